@@ -0,0 +1,217 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/pkg/serve/backup"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/extension"
+	"github.com/antgroup/hugescm/pkg/serve/httpserver"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+)
+
+type backupEnv struct {
+	sc     *httpserver.ServerConfig
+	db     database.DB
+	bucket oss.Bucket
+	cold   oss.Bucket // cold tier, nil when tiering is disabled
+}
+
+// newBackupEnv: sshd config has the same repositories, database and oss fields as httpd config. The reference key
+// signs the reference logs of imported references.
+func newBackupEnv(config string, expandEnv bool) (*backupEnv, error) {
+	sc, err := httpserver.NewServerConfig(config, expandEnv)
+	if err != nil {
+		return nil, fmt.Errorf("load server config: %w", err)
+	}
+	if sc.DB == nil {
+		return nil, errors.New("missing database config")
+	}
+	cfg, err := sc.DB.MakeConfig()
+	if err != nil {
+		return nil, err
+	}
+	e := &backupEnv{sc: sc}
+	if e.db, err = database.NewDB(cfg, database.WithReferenceKey(sc.ReferenceSigner)); err != nil {
+		return nil, err
+	}
+	if sc.PersistentOSS == nil {
+		return e, nil
+	}
+	if e.bucket, err = oss.NewBucket(&oss.NewBucketOptions{
+		Endpoint:        sc.PersistentOSS.Endpoint,
+		SharedEndpoint:  sc.PersistentOSS.SharedEndpoint,
+		AccessKeyID:     sc.PersistentOSS.AccessKeyID,
+		AccessKeySecret: sc.PersistentOSS.AccessKeySecret,
+		Bucket:          sc.PersistentOSS.Bucket,
+	}); err != nil {
+		_ = e.db.Close()
+		return nil, err
+	}
+	if sc.Tiering == nil || sc.Tiering.ColdOSS == nil {
+		return e, nil
+	}
+	if e.cold, err = oss.NewBucket(&oss.NewBucketOptions{
+		Endpoint:        sc.Tiering.ColdOSS.Endpoint,
+		SharedEndpoint:  sc.Tiering.ColdOSS.SharedEndpoint,
+		AccessKeyID:     sc.Tiering.ColdOSS.AccessKeyID,
+		AccessKeySecret: sc.Tiering.ColdOSS.AccessKeySecret,
+		Bucket:          sc.Tiering.ColdOSS.Bucket,
+	}); err != nil {
+		_ = e.db.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// repositories opens the repositories with the extensions of the server.
+func (e *backupEnv) repositories() (repo.Repositories, error) {
+	ext, err := extension.Load(e.sc.Extensions)
+	if err != nil {
+		return nil, fmt.Errorf("load extensions: %w", err)
+	}
+	return repo.NewRepositories(e.sc.Repositories, e.sc.PersistentOSS, e.sc.Tiering, e.sc.Cache, e.sc.CommitPolicy, e.sc.PathPolicy, e.db, ext)
+}
+
+func (e *backupEnv) Close() error {
+	return e.db.Close()
+}
+
+// resolve returns the repository ID of namespace/repo or a repository ID, a repository ID is required to restore
+// a deleted repository.
+func (e *backupEnv) resolve(ctx context.Context, repoPath string) (int64, *database.Namespace, *database.Repository, error) {
+	if rid, err := strconv.ParseInt(repoPath, 10, 64); err == nil {
+		n, r, err := e.db.FindRepositoryByID(ctx, int(rid))
+		if errors.Is(err, sql.ErrNoRows) {
+			return rid, nil, nil, nil
+		}
+		return rid, n, r, err
+	}
+	namespacePath, repoName, ok := strings.Cut(strings.Trim(repoPath, "/"), "/")
+	if !ok {
+		return 0, nil, nil, fmt.Errorf("bad repository '%s', expected namespace/repo or repository ID", repoPath)
+	}
+	n, r, err := e.db.FindRepositoryByPath(ctx, namespacePath, strings.TrimSuffix(repoName, ".zeta"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil, nil, fmt.Errorf("repository '%s' not found, use the repository ID to restore deleted repositories", repoPath)
+	}
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return r.ID, n, r, nil
+}
+
+func progress(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+}
+
+type Backup struct {
+	Repository string `arg:"" name:"repository" help:"Repository to back up, namespace/repo or repository ID"`
+	To         string `name:"to" required:"" help:"Backup location, local directory or s3://bucket/prefix"`
+	Full       bool   `name:"full" help:"Create a full backup instead of an incremental backup based on the last backup"`
+	Config     string `short:"c" name:"config" help:"Location of server config file" default:"~/config/zeta-serve-httpd.toml" type:"path"`
+}
+
+func (c *Backup) Run(globals *Globals) error {
+	ctx := context.Background()
+	e, err := newBackupEnv(c.Config, globals.ExpandEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve backup: %v\n", err)
+		return err
+	}
+	defer e.Close() // nolint
+	rid, n, r, err := e.resolve(ctx, c.Repository)
+	if err == nil && r == nil {
+		err = fmt.Errorf("repository '%d' not found", rid)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve backup: resolve repository: %v\n", err)
+		return err
+	}
+	storage, err := backup.NewStorage(c.To, e.sc.PersistentOSS)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve backup: open backup location: %v\n", err)
+		return err
+	}
+	m, err := backup.Backup(ctx, &backup.Options{
+		RID:       rid,
+		Namespace: n.Path,
+		Path:      r.Path,
+		Root:      e.sc.Repositories,
+		DB:        e.db.Database(),
+		Bucket:    e.bucket,
+		Cold:      e.cold,
+		Storage:   storage,
+		Full:      c.Full,
+		Progress:  progress,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve backup: %v\n", err)
+		return err
+	}
+	if len(m.Parent) != 0 {
+		fmt.Fprintf(os.Stdout, "backup %s/%s snapshot %s (incremental, parent %s) size: %s\n", m.Namespace, m.Path, m.ID, m.Parent, strengthen.FormatSize(m.Size()))
+		return nil
+	}
+	fmt.Fprintf(os.Stdout, "backup %s/%s snapshot %s (full) size: %s\n", m.Namespace, m.Path, m.ID, strengthen.FormatSize(m.Size()))
+	return nil
+}
+
+type Restore struct {
+	Repository string `arg:"" name:"repository" help:"Repository to restore, namespace/repo or repository ID"`
+	From       string `name:"from" required:"" help:"Backup location, local directory or s3://bucket/prefix"`
+	Snapshot   string `name:"snapshot" help:"Snapshot to restore, defaults to the latest snapshot"`
+	VerifyOnly bool   `name:"verify-only" help:"Only verify the integrity of the snapshot"`
+	Config     string `short:"c" name:"config" help:"Location of server config file" default:"~/config/zeta-serve-httpd.toml" type:"path"`
+}
+
+func (c *Restore) Run(globals *Globals) error {
+	ctx := context.Background()
+	e, err := newBackupEnv(c.Config, globals.ExpandEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve restore: %v\n", err)
+		return err
+	}
+	defer e.Close() // nolint
+	rid, _, _, err := e.resolve(ctx, c.Repository)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve restore: resolve repository: %v\n", err)
+		return err
+	}
+	storage, err := backup.NewStorage(c.From, e.sc.PersistentOSS)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve restore: open backup location: %v\n", err)
+		return err
+	}
+	m, err := backup.Restore(ctx, &backup.RestoreOptions{
+		RID:        rid,
+		Snapshot:   c.Snapshot,
+		Root:       e.sc.Repositories,
+		DB:         e.db.Database(),
+		Bucket:     e.bucket,
+		Storage:    storage,
+		VerifyOnly: c.VerifyOnly,
+		Progress:   progress,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve restore: %v\n", err)
+		return err
+	}
+	if c.VerifyOnly {
+		fmt.Fprintf(os.Stdout, "snapshot %s of %s/%s is intact, size: %s\n", m.ID, m.Namespace, m.Path, strengthen.FormatSize(m.Size()))
+		return nil
+	}
+	fmt.Fprintf(os.Stdout, "restored %s/%s to snapshot %s created at %s\n", m.Namespace, m.Path, m.ID, m.CreatedAt.Format("2006-01-02 15:04:05 -0700"))
+	return nil
+}
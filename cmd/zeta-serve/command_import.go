@@ -0,0 +1,153 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/importer"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/transport/client"
+)
+
+type Import struct {
+	Repository  string `arg:"" name:"repository" help:"Repository to create, namespace/repo"`
+	From        string `name:"from" required:"" help:"Source repository, URL of another HugeSCM server or path of a local zeta repository"`
+	Owner       string `name:"owner" required:"" help:"Username or email of the repository owner"`
+	Description string `name:"description" help:"Description of the repository"`
+	Public      bool   `name:"public" help:"Create a public repository"`
+	Config      string `short:"c" name:"config" help:"Location of server config file" default:"~/config/zeta-serve-httpd.toml" type:"path"`
+}
+
+func (c *Import) newSource(ctx context.Context, verbose bool) (importer.Source, error) {
+	if !transport.IsRemoteEndpoint(c.From) {
+		return importer.NewLocalSource(c.From)
+	}
+	endpoint, err := transport.NewEndpoint(c.From, nil)
+	if err != nil {
+		return nil, err
+	}
+	t, err := client.NewTransport(ctx, endpoint, transport.DOWNLOAD, verbose)
+	if err != nil {
+		return nil, err
+	}
+	return importer.NewRemoteSource(t, progress), nil
+}
+
+// provision returns the repository to import into. A repository is created with its owner, a repository left by an
+// interrupted import has the staging directory and is resumed, any other existing repository is rejected.
+func (c *Import) provision(ctx context.Context, e *backupEnv, hub repo.Repositories, u *database.User, defaultBranch string) (*database.Repository, error) {
+	namespacePath, repoPath, ok := strings.Cut(strings.Trim(c.Repository, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("bad repository '%s', expected namespace/repo", c.Repository)
+	}
+	repoPath = strings.TrimSuffix(repoPath, ".zeta")
+	_, r, err := e.db.FindRepositoryByPath(ctx, namespacePath, repoPath)
+	if err == nil {
+		if _, err := os.Stat(importer.StagingPath(e.sc.Repositories, r.ID)); err != nil {
+			return nil, fmt.Errorf("repository '%s' already exists", c.Repository)
+		}
+		progress("Resuming the import of %s", c.Repository)
+		return r, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	n, err := e.db.FindNamespaceByPath(ctx, namespacePath)
+	if err != nil {
+		return nil, fmt.Errorf("find namespace '%s': %w", namespacePath, err)
+	}
+	newRepo := &database.Repository{
+		NamespaceID:   n.ID,
+		Name:          repoPath,
+		Path:          repoPath,
+		Description:   c.Description,
+		DefaultBranch: defaultBranch,
+	}
+	if c.Public {
+		newRepo.VisibleLevel = database.PublicRepository
+	}
+	if r, err = hub.New(ctx, newRepo, u, true); err != nil {
+		return nil, err
+	}
+	// the staging directory marks the repository as being imported
+	if err := os.MkdirAll(importer.StagingPath(e.sc.Repositories, r.ID), 0755); err != nil {
+		return nil, err
+	}
+	progress("Created repository %s (%d)", c.Repository, r.ID)
+	return r, nil
+}
+
+func (c *Import) Run(globals *Globals) error {
+	ctx := context.Background()
+	e, err := newBackupEnv(c.Config, globals.ExpandEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve import: %v\n", err)
+		return err
+	}
+	defer e.Close() // nolint
+	if e.sc.PersistentOSS == nil {
+		err = errors.New("missing oss config")
+		fmt.Fprintf(os.Stderr, "zeta-serve import: %v\n", err)
+		return err
+	}
+	hub, err := e.repositories()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve import: %v\n", err)
+		return err
+	}
+	cdb, err := odb.NewCacheDB(e.sc.Cache.NumCounters, e.sc.Cache.MaxCost, e.sc.Cache.BufferItems)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve import: %v\n", err)
+		return err
+	}
+	source, err := c.newSource(ctx, globals.Verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve import: open source: %v\n", err)
+		return err
+	}
+	defer source.Close() // nolint
+	refs, defaultBranch, err := source.References(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve import: list references: %v\n", err)
+		return err
+	}
+	owner, err := e.db.SearchUser(ctx, c.Owner)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve import: search owner '%s': %v\n", c.Owner, err)
+		return err
+	}
+	r, err := c.provision(ctx, e, hub, owner, defaultBranch)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve import: %v\n", err)
+		return err
+	}
+	result, err := importer.Import(ctx, &importer.Options{
+		RID:             r.ID,
+		UID:             owner.ID,
+		Root:            e.sc.Repositories,
+		CompressionAlgo: r.CompressionAlgo,
+		DB:              e.db,
+		Cache:           cdb,
+		Bucket:          e.bucket,
+		Source:          source,
+		References:      refs,
+		Progress:        progress,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve import: %v\nrun the same command again to resume the import\n", err)
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "imported %s (%d): %d references, %d commits, %d trees, %d fragments and tags, %d objects\n",
+		c.Repository, r.ID, result.References, result.Commits, result.Trees, result.MetaObjects, result.Objects)
+	return nil
+}
@@ -0,0 +1,252 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/jobs"
+)
+
+const (
+	followInterval = time.Second
+)
+
+type Jobs struct {
+	List   ListJobs  `cmd:"list" help:"List the latest jobs"`
+	Run    RunJob    `cmd:"run" help:"Run a maintenance job of a repository and show its progress"`
+	Status JobStatus `cmd:"status" help:"Show the status and progress of a job"`
+	Cancel CancelJob `cmd:"cancel" help:"Cancel a running job"`
+}
+
+func jobProgress(j *database.Job) string {
+	return jobs.Progress{Stage: j.Stage, Current: j.Current, Total: j.Total}.String()
+}
+
+func jobStatus(j *database.Job) string {
+	if j.Orphaned(time.Now()) {
+		return "orphaned"
+	}
+	if j.Status == database.JobRunning && j.CancelRequested {
+		return "canceling"
+	}
+	return string(j.Status)
+}
+
+func printJob(j *database.Job) {
+	fmt.Fprintf(os.Stdout, "job %d: %s of repository %d, %s\n", j.ID, j.Kind, j.RID, jobStatus(j))
+	if len(j.Params) != 0 {
+		fmt.Fprintf(os.Stdout, "  params:   %s\n", j.Params)
+	}
+	fmt.Fprintf(os.Stdout, "  runner:   %s\n", j.Runner)
+	fmt.Fprintf(os.Stdout, "  started:  %s\n", j.CreatedAt.Format(time.RFC3339))
+	if j.Finished() {
+		fmt.Fprintf(os.Stdout, "  finished: %s (%v)\n", j.FinishedAt.Format(time.RFC3339), j.FinishedAt.Sub(j.CreatedAt).Round(time.Second))
+	} else {
+		fmt.Fprintf(os.Stdout, "  updated:  %s\n", j.UpdatedAt.Format(time.RFC3339))
+	}
+	if progress := jobProgress(j); len(progress) != 0 {
+		fmt.Fprintf(os.Stdout, "  progress: %s\n", progress)
+	}
+	if len(j.Message) != 0 {
+		fmt.Fprintf(os.Stdout, "  result:   %s\n", j.Message)
+	}
+}
+
+func jobError(j *database.Job) error {
+	switch j.Status {
+	case database.JobFailed, database.JobCanceled:
+		return fmt.Errorf("job %d %s: %s", j.ID, j.Status, j.Message)
+	}
+	return nil
+}
+
+type ListJobs struct {
+	Repository string `arg:"" optional:"" name:"repository" help:"List the jobs of the repository, namespace/repo or repository ID"`
+	Limit      int    `name:"limit" short:"n" help:"Maximum number of jobs to list" default:"20"`
+	Config     string `short:"c" name:"config" help:"Location of server config file" default:"~/config/zeta-serve-httpd.toml" type:"path"`
+}
+
+func (c *ListJobs) Run(globals *Globals) error {
+	ctx := context.Background()
+	e, err := newBackupEnv(c.Config, globals.ExpandEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve jobs list: %v\n", err)
+		return err
+	}
+	defer e.Close() // nolint
+	var rid int64
+	if len(c.Repository) != 0 {
+		if rid, _, _, err = e.resolve(ctx, c.Repository); err != nil {
+			fmt.Fprintf(os.Stderr, "zeta-serve jobs list: resolve repository: %v\n", err)
+			return err
+		}
+	}
+	items, err := e.db.ListJobs(ctx, rid, max(c.Limit, 1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve jobs list: %v\n", err)
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tRID\tKIND\tSTATUS\tSTARTED\tPROGRESS")
+	for _, j := range items {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\n", j.ID, j.RID, j.Kind, jobStatus(j), j.CreatedAt.Format(time.DateTime), jobProgress(j))
+	}
+	return w.Flush()
+}
+
+type RunJob struct {
+	Kind       string `arg:"" name:"kind" help:"Kind of the job: fsck, gc, backup or tier"`
+	Repository string `arg:"" name:"repository" help:"Repository, namespace/repo or repository ID"`
+	To         string `name:"to" help:"Backup location of backup jobs, local directory or s3://bucket/prefix"`
+	Full       bool   `name:"full" help:"Create a full backup instead of an incremental backup"`
+	DryRun     bool   `name:"dry-run" help:"Report the large objects a tier job would move without moving them"`
+	Config     string `short:"c" name:"config" help:"Location of server config file" default:"~/config/zeta-serve-httpd.toml" type:"path"`
+}
+
+func (c *RunJob) params() (string, error) {
+	var v any
+	switch c.Kind {
+	case jobs.KindBackup:
+		v = &jobs.BackupParams{To: c.To, Full: c.Full}
+	case jobs.KindTier:
+		v = &jobs.TierParams{DryRun: c.DryRun}
+	default:
+		return "", nil
+	}
+	params, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(params), nil
+}
+
+// Run runs the job in this process, interrupting the command cancels the job.
+func (c *RunJob) Run(globals *Globals) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	e, err := newBackupEnv(c.Config, globals.ExpandEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve jobs run: %v\n", err)
+		return err
+	}
+	defer e.Close() // nolint
+	if e.sc.PersistentOSS == nil {
+		err = errors.New("missing oss config")
+		fmt.Fprintf(os.Stderr, "zeta-serve jobs run: %v\n", err)
+		return err
+	}
+	rid, n, r, err := e.resolve(ctx, c.Repository)
+	if err == nil && r == nil {
+		err = fmt.Errorf("repository '%d' not found", rid)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve jobs run: resolve repository: %v\n", err)
+		return err
+	}
+	hub, err := e.repositories()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve jobs run: %v\n", err)
+		return err
+	}
+	params, err := c.params()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve jobs run: %v\n", err)
+		return err
+	}
+	task, err := jobs.NewTask(&jobs.Env{DB: e.db, Hub: hub, Root: e.sc.Repositories, Bucket: e.bucket, Cold: e.cold, OSS: e.sc.PersistentOSS, Tiering: e.sc.Tiering}, c.Kind, params, n, r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve jobs run: %v\n", err)
+		return err
+	}
+	var last jobs.Progress
+	j, err := jobs.NewRunner(e.db).Run(ctx, &database.Job{RID: r.ID, Kind: c.Kind, Params: params}, task, func(id int64, p jobs.Progress) {
+		if last == (jobs.Progress{}) {
+			progress("job %d started, cancel it with 'zeta-serve jobs cancel %d'", id, id)
+		}
+		if p != last {
+			progress("%s", p)
+			last = p
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve jobs run: %v\n", err)
+		return err
+	}
+	printJob(j)
+	return jobError(j)
+}
+
+type JobStatus struct {
+	ID     int64  `arg:"" name:"id" help:"Job ID"`
+	Follow bool   `name:"follow" short:"f" help:"Show the progress until the job finishes"`
+	Config string `short:"c" name:"config" help:"Location of server config file" default:"~/config/zeta-serve-httpd.toml" type:"path"`
+}
+
+func (c *JobStatus) Run(globals *Globals) error {
+	ctx := context.Background()
+	e, err := newBackupEnv(c.Config, globals.ExpandEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve jobs status: %v\n", err)
+		return err
+	}
+	defer e.Close() // nolint
+	var last string
+	for {
+		j, err := e.db.FindJob(ctx, c.ID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "zeta-serve jobs status: %v\n", err)
+			return err
+		}
+		if !c.Follow || j.Finished() || j.Orphaned(time.Now()) {
+			printJob(j)
+			return jobError(j)
+		}
+		if p := jobProgress(j); p != last {
+			progress("%s", p)
+			last = p
+		}
+		time.Sleep(followInterval)
+	}
+}
+
+type CancelJob struct {
+	ID     int64  `arg:"" name:"id" help:"Job ID"`
+	Wait   bool   `name:"wait" short:"w" help:"Wait until the job stops"`
+	Config string `short:"c" name:"config" help:"Location of server config file" default:"~/config/zeta-serve-httpd.toml" type:"path"`
+}
+
+// Run asks the runner of the job to cancel it, the runner checks the request on its next progress update.
+func (c *CancelJob) Run(globals *Globals) error {
+	ctx := context.Background()
+	e, err := newBackupEnv(c.Config, globals.ExpandEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve jobs cancel: %v\n", err)
+		return err
+	}
+	defer e.Close() // nolint
+	j, err := e.db.CancelJob(ctx, c.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve jobs cancel: %v\n", err)
+		return err
+	}
+	for c.Wait && !j.Finished() && !j.Orphaned(time.Now()) {
+		time.Sleep(followInterval)
+		if j, err = e.db.FindJob(ctx, c.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "zeta-serve jobs cancel: %v\n", err)
+			return err
+		}
+	}
+	printJob(j)
+	return nil
+}
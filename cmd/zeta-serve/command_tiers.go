@@ -0,0 +1,68 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+)
+
+type Tiers struct {
+	Repository string `arg:"" name:"repository" help:"Repository, namespace/repo or repository ID"`
+	JSON       bool   `name:"json" short:"j" help:"Data will be returned in JSON format"`
+	Config     string `short:"c" name:"config" help:"Location of server config file" default:"~/config/zeta-serve-httpd.toml" type:"path"`
+}
+
+func printTier(name string, s *odb.TierStat) {
+	fmt.Fprintf(os.Stdout, "%-8s %d objects, %s\n", name, s.Objects, strengthen.FormatSize(s.Size))
+}
+
+// Run shows the distribution of the large objects of the repository between the hot and the cold tier.
+func (c *Tiers) Run(globals *Globals) error {
+	ctx := context.Background()
+	e, err := newBackupEnv(c.Config, globals.ExpandEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve tiers: %v\n", err)
+		return err
+	}
+	defer e.Close() // nolint
+	if e.bucket == nil {
+		err = errors.New("missing oss config")
+		fmt.Fprintf(os.Stderr, "zeta-serve tiers: %v\n", err)
+		return err
+	}
+	rid, _, r, err := e.resolve(ctx, c.Repository)
+	if err == nil && r == nil {
+		err = fmt.Errorf("repository '%d' not found", rid)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve tiers: resolve repository: %v\n", err)
+		return err
+	}
+	report, err := odb.StatTiers(ctx, e.bucket, e.cold, odb.NewMetadataDB(e.db.Database(), rid), rid, time.Now().Add(-e.sc.Tiering.ColdAfter()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve tiers: %v\n", err)
+		return err
+	}
+	if c.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printTier("hot:", &report.Hot)
+	if report.Cold == nil {
+		fmt.Fprintln(os.Stdout, "cold:    tiering is not configured")
+	} else {
+		printTier("cold:", report.Cold)
+	}
+	fmt.Fprintf(os.Stdout, "unused:  %d hot objects (%s) not uploaded or accessed since %s\n", report.Unused.Objects, strengthen.FormatSize(report.Unused.Size), report.UnusedSince.Format(time.DateOnly))
+	return nil
+}
@@ -18,6 +18,11 @@ type App struct {
 	SSHD    SSHD    `cmd:"sshd" help:"start zeta-serve sshd server"`
 	Keygen  Keygen  `cmd:"keygen" help:"Generates a random private key"`
 	Encrypt Encrypt `cmd:"encrypt" help:"Encrypting Data Using RSA Key"`
+	Backup  Backup  `cmd:"backup" help:"Create a consistent snapshot of a repository"`
+	Restore Restore `cmd:"restore" help:"Verify and restore a repository snapshot"`
+	Import  Import  `cmd:"import" help:"Create a repository from another HugeSCM server or a local zeta repository"`
+	Jobs    Jobs    `cmd:"jobs" help:"Run, list and cancel maintenance jobs of repositories"`
+	Tiers   Tiers   `cmd:"tiers" help:"Show the distribution of large objects between the hot and the cold tier"`
 }
 
 func main() {
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/antgroup/hugescm/modules/trace"
 	"github.com/antgroup/hugescm/pkg/command"
 	"github.com/antgroup/hugescm/pkg/kong"
+	"github.com/antgroup/hugescm/pkg/migrate"
 	"github.com/antgroup/hugescm/pkg/tr"
 	"github.com/antgroup/hugescm/pkg/version"
 	"github.com/antgroup/hugescm/pkg/zeta"
@@ -23,45 +25,72 @@ import (
 
 type App struct {
 	command.Globals
-	Checkout    command.Checkout    `cmd:"checkout" aliases:"co" help:"Checkout remote, switch branches, or restore worktree files"`
-	Switch      command.Switch      `cmd:"switch" help:"Switch branches"`
-	Add         command.Add         `cmd:"add" help:"Add file contents to the index"`
-	Status      command.Status      `cmd:"status" help:"Show the working tree status"`
-	Restore     command.Restore     `cmd:"restore" help:"Restore working tree files"`
-	Fetch       command.Fetch       `cmd:"fetch" help:"Download objects and reference from remote"`
-	Commit      command.Commit      `cmd:"commit" help:"Record changes to the repository"`
-	Push        command.Push        `cmd:"push" help:"Update remote refs along with associated objects"`
-	Branch      command.Branch      `cmd:"branch" help:"List, create, or delete branches"`
-	Tag         command.Tag         `cmd:"tag" help:"List, create, or delete tags"`
-	Pull        command.Pull        `cmd:"pull" help:"Fetch from and integrate with remote"`
-	Merge       command.Merge       `cmd:"merge" help:"Join two development histories together"`
-	Rebase      command.Rebase      `cmd:"rebase" help:"Reapply commits on top of another base tip"`
-	Config      command.Config      `cmd:"config" help:"Get and set repository or global options"`
-	CatFile     command.Cat         `cmd:"cat-file" aliases:"cat" help:"Provide contents or details of repository objects"`
-	Log         command.Log         `cmd:"log" help:"Show commit logs"`
-	GC          command.GC          `cmd:"gc" help:"Cleanup unnecessary files and optimize the local repository"`
-	Reset       command.Reset       `cmd:"reset" help:"Reset current HEAD to the specified state"`
-	Diff        command.Diff        `cmd:"diff" help:"Show changes between commits, commit and working tree, etc"`
-	Clean       command.Clean       `cmd:"clean" help:"Remove untracked files from the working tree"`
-	LsTree      command.LsTree      `cmd:"ls-tree" help:"List the contents of a tree object"`
-	MergeTree   command.MergeTree   `cmd:"merge-tree" help:"Perform merge without touching index or working tree"`
-	RM          command.Remove      `cmd:"rm" help:"Remove files from the working tree and from the index"`
-	Stash       command.Stash       `cmd:"stash" help:"Stash the changes in a dirty working directory away"`
-	RevParse    command.RevParse    `cmd:"rev-parse" help:"Pick out and massage parameters"`
-	ForEachRef  command.ForEachRef  `cmd:"for-each-ref" help:"Output information on each ref"`
-	Remote      command.Remote      `cmd:"remote" help:"Manage of tracked repository"`
-	CheckIgnore command.CheckIgnore `cmd:"check-ignore" help:"Debug zetaignore / exclude files"`
-	Init        command.Init        `cmd:"init" help:"Create an empty zeta repository"`
-	MergeBase   command.MergeBase   `cmd:"merge-base" help:"Find optimal common ancestors for merge"`
-	LsFiles     command.LsFiles     `cmd:"ls-files" help:"Show information about files in the index and the working tree"`
-	HashObject  command.HashObject  `cmd:"hash-object" help:"Compute hash or create object"`
-	MergeFile   command.MergeFile   `cmd:"merge-file" help:"Run a three-way file merge"`
-	Show        command.Show        `cmd:"show" help:"Show various types of objects"`
-	Version     command.Version     `cmd:"version" help:"Display version information"`
-	CherryPick  command.CherryPick  `cmd:"cherry-pick" help:"EXPERIMENTAL: Apply the changes introduced by some existing commit"`
-	Revert      command.Revert      `cmd:"revert" help:"EXPERIMENTAL: Revert commit"`
-	Rename      command.Rename      `cmd:"rename" help:"EXPERIMENTAL: Rename a file"`
-	Debug       bool                `name:"debug" help:"Enable debug mode; analyze timing"`
+	Checkout       command.Checkout       `cmd:"checkout" aliases:"co" help:"Checkout remote, switch branches, or restore worktree files"`
+	Switch         command.Switch         `cmd:"switch" help:"Switch branches"`
+	Add            command.Add            `cmd:"add" help:"Add file contents to the index"`
+	Status         command.Status         `cmd:"status" help:"Show the working tree status"`
+	Restore        command.Restore        `cmd:"restore" help:"Restore working tree files"`
+	Fetch          command.Fetch          `cmd:"fetch" help:"Download objects and reference from remote"`
+	Commit         command.Commit         `cmd:"commit" help:"Record changes to the repository"`
+	Push           command.Push           `cmd:"push" help:"Update remote refs along with associated objects"`
+	Branch         command.Branch         `cmd:"branch" help:"List, create, or delete branches"`
+	Tag            command.Tag            `cmd:"tag" help:"List, create, or delete tags"`
+	Pull           command.Pull           `cmd:"pull" help:"Fetch from and integrate with remote"`
+	Merge          command.Merge          `cmd:"merge" help:"Join two development histories together"`
+	Rebase         command.Rebase         `cmd:"rebase" help:"Reapply commits on top of another base tip"`
+	Config         command.Config         `cmd:"config" help:"Get and set repository or global options"`
+	Setup          command.Setup          `cmd:"setup" help:"Interactively configure user, editor, credentials, proxy and transfers"`
+	CatFile        command.Cat            `cmd:"cat-file" aliases:"cat" help:"Provide contents or details of repository objects"`
+	Log            command.Log            `cmd:"log" help:"Show commit logs"`
+	Shortlog       command.Shortlog       `cmd:"shortlog" help:"Summarize commit logs grouped by author"`
+	Blame          command.Blame          `cmd:"blame" help:"Show what revision and author last modified each line of a file"`
+	GC             command.GC             `cmd:"gc" help:"Cleanup unnecessary files and optimize the local repository"`
+	Reset          command.Reset          `cmd:"reset" help:"Reset current HEAD to the specified state"`
+	Diff           command.Diff           `cmd:"diff" help:"Show changes between commits, commit and working tree, etc"`
+	Clean          command.Clean          `cmd:"clean" help:"Remove untracked files from the working tree"`
+	LsTree         command.LsTree         `cmd:"ls-tree" help:"List the contents of a tree object"`
+	SizeReport     command.SizeReport     `cmd:"size-report" help:"Report the largest files, directories and extensions of a tree"`
+	SharedGC       command.SharedGC       `cmd:"shared-gc" help:"Remove objects of the sharing root no longer used by any registered clone"`
+	Cache          command.Cache          `cmd:"cache" help:"Show or clear the blob cache of the repository"`
+	MergeTree      command.MergeTree      `cmd:"merge-tree" help:"Perform merge without touching index or working tree"`
+	RM             command.Remove         `cmd:"rm" help:"Remove files from the working tree and from the index"`
+	Stash          command.Stash          `cmd:"stash" help:"Stash the changes in a dirty working directory away"`
+	RevParse       command.RevParse       `cmd:"rev-parse" help:"Pick out and massage parameters"`
+	ForEachRef     command.ForEachRef     `cmd:"for-each-ref" help:"Output information on each ref"`
+	Remote         command.Remote         `cmd:"remote" help:"Manage of tracked repository"`
+	VerifyRemote   command.VerifyRemote   `cmd:"verify-remote" help:"Compare local refs and objects against the remote"`
+	TransferLog    command.TransferLog    `cmd:"transfer-log" help:"Inspect the transport requests made by this repository"`
+	CheckIgnore    command.CheckIgnore    `cmd:"check-ignore" help:"Debug zetaignore / exclude files"`
+	Init           command.Init           `cmd:"init" help:"Create an empty zeta repository"`
+	MergeBase      command.MergeBase      `cmd:"merge-base" help:"Find optimal common ancestors for merge"`
+	LsFiles        command.LsFiles        `cmd:"ls-files" help:"Show information about files in the index and the working tree"`
+	Grep           command.Grep           `cmd:"grep" help:"Print lines matching a pattern"`
+	HashObject     command.HashObject     `cmd:"hash-object" help:"Compute hash or create object"`
+	CommitTree     command.CommitTree     `cmd:"commit-tree" help:"Create a commit from a tree and changes without touching the index or the worktree"`
+	MergeFile      command.MergeFile      `cmd:"merge-file" help:"Run a three-way file merge"`
+	Show           command.Show           `cmd:"show" help:"Show various types of objects"`
+	FastExport     command.FastExport     `cmd:"fast-export" help:"Export zeta repository as a git fast-import stream"`
+	ImportTar      command.ImportTar      `cmd:"import-tar" help:"Create a commit from a tarball"`
+	RewriteHistory command.RewriteHistory `cmd:"rewrite-history" help:"Rewrite the history of branches and tags: remove paths and large files, replace blobs, messages and identities"`
+	Version        command.Version        `cmd:"version" help:"Display version information"`
+	UpdateSelf     command.UpdateSelf     `cmd:"update-self" help:"Update zeta to the latest release"`
+	Telemetry      command.Telemetry      `cmd:"telemetry" help:"Show or upload opt-in command telemetry"`
+	CherryPick     command.CherryPick     `cmd:"cherry-pick" help:"EXPERIMENTAL: Apply the changes introduced by some existing commit"`
+	Revert         command.Revert         `cmd:"revert" help:"EXPERIMENTAL: Revert commit"`
+	Rename         command.Rename         `cmd:"rename" help:"EXPERIMENTAL: Rename a file"`
+	SplitCommit    command.SplitCommit    `cmd:"split-commit" help:"EXPERIMENTAL: Split an unpushed commit into several commits"`
+	Debug          bool                   `name:"debug" help:"Enable debug mode; analyze timing"`
+}
+
+// commandName: the subcommand path without positional arguments
+func commandName(ctx *kong.Context) string {
+	var names []string
+	for _, trace := range ctx.Path {
+		if trace.Command != nil {
+			names = append(names, trace.Command.Name)
+		}
+	}
+	return strings.Join(names, " ")
 }
 
 func main() {
@@ -69,6 +98,8 @@ func main() {
 	// initialize locale
 	_ = tr.Initialize()
 	kong.BindW(tr.W) // replace W
+	// zeta checkout/fetch git+https://...: read-through translation of git remotes
+	zeta.RegisterTranslator(migrate.NewGitTranslator(), migrate.GitSchemes...)
 
 	// A cancellable root context lets long-running operations (fetch, push,
 	// rebase, etc.) react to Ctrl+C / SIGTERM. stop() detaches the signal
@@ -77,7 +108,7 @@ func main() {
 	defer stop()
 
 	var app App
-	ctx := kong.Parse(&app,
+	parser, err := kong.New(&app,
 		kong.NamedMapper("size", command.SizeDecoder()),
 		kong.NamedMapper("expire", command.ExpireDecoder()),
 		kong.Name("zeta"),
@@ -94,15 +125,26 @@ func main() {
 		// receives the cancellable context automatically.
 		kong.BindTo(rootCtx, (*context.Context)(nil)),
 	)
+	if err != nil {
+		panic(err)
+	}
+	// help.autocorrect: suggest or run the most similar command when the subcommand is mistyped
+	ctx, err := parser.Parse(command.Autocorrect(parser, os.Args[1:]))
+	parser.FatalIfErrorf(err)
 	now := time.Now()
 	m := strengthen.NewMeasurer("zeta", app.Debug)
 	if app.Verbose {
 		trace.EnableDebugMode()
 	}
-	err := ctx.Run(&app.Globals)
+	err = ctx.Run(&app.Globals)
 	m.Close()
+	spent := time.Since(now)
 	if app.Verbose {
-		trace.DbgPrint("time spent: %v", time.Since(now))
+		trace.DbgPrint("time spent: %v", spent)
+	}
+	if name := commandName(ctx); !strings.HasPrefix(name, "telemetry") {
+		zeta.RecordTelemetry(rootCtx, app.CWD, app.Values, name, spent, err)
+		zeta.RecordMetrics(app.CWD, app.Values, name, spent, err)
 	}
 	if err == nil {
 		return
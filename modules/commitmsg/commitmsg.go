@@ -0,0 +1,209 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package commitmsg validates and enriches commit messages with built-in policies. The same rules are used by
+// zeta commit on the client and by the push checks of zeta-serve.
+//
+// Policies:
+//
+//	issue           the message must reference an issue, eg: JIRA-123
+//	conventional    the subject must follow Conventional Commits, eg: fix(scope): description
+//	subject-length  the subject must not exceed the length limit
+//	branch-footer   a footer derived from the branch name is appended to the message, eg: Issue: JIRA-123
+package commitmsg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	PolicyIssue         = "issue"
+	PolicyConventional  = "conventional"
+	PolicySubjectLength = "subject-length"
+	PolicyBranchFooter  = "branch-footer"
+)
+
+const (
+	DefaultIssuePattern  = `\b[A-Z][A-Z0-9]+-[0-9]+\b`
+	DefaultSubjectLength = 72
+	// DefaultBranchPattern captures the issue key of branches like JIRA-123, feature/JIRA-123 or JIRA-123-fix-crash.
+	DefaultBranchPattern = `^(?:[^/]+/)*([A-Z][A-Z0-9]+-[0-9]+)`
+	DefaultFooter        = "Issue: $1"
+)
+
+var (
+	conventionalRE = regexp.MustCompile(`^(build|chore|ci|docs|feat|fix|perf|refactor|revert|style|test)(\([^()\s]+\))?!?: \S`)
+)
+
+type Options struct {
+	// Policies: enabled policies, see the package documentation.
+	Policies []string
+	// IssuePattern: regular expression matching an issue reference, DefaultIssuePattern when empty.
+	IssuePattern string
+	// SubjectLength: maximum characters of the subject, DefaultSubjectLength when zero.
+	SubjectLength int
+	// BranchPattern: regular expression matched against the branch name, DefaultBranchPattern when empty.
+	BranchPattern string
+	// Footer: template of the footer expanded with the submatches of BranchPattern, DefaultFooter when empty.
+	Footer string
+}
+
+// Rules: compiled policies. A nil *Rules has no policy.
+type Rules struct {
+	issue         *regexp.Regexp
+	conventional  bool
+	subjectLength int
+	branch        *regexp.Regexp
+	footer        string
+}
+
+// New compiles the policies, it returns nil when no policy is enabled.
+func New(opts *Options) (*Rules, error) {
+	if opts == nil || len(opts.Policies) == 0 {
+		return nil, nil
+	}
+	r := &Rules{}
+	for _, p := range opts.Policies {
+		switch p = strings.TrimSpace(p); p {
+		case PolicyIssue:
+			pattern := opts.IssuePattern
+			if len(pattern) == 0 {
+				pattern = DefaultIssuePattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("bad issue pattern '%s': %w", pattern, err)
+			}
+			r.issue = re
+		case PolicyConventional:
+			r.conventional = true
+		case PolicySubjectLength:
+			if r.subjectLength = opts.SubjectLength; r.subjectLength <= 0 {
+				r.subjectLength = DefaultSubjectLength
+			}
+		case PolicyBranchFooter:
+			pattern := opts.BranchPattern
+			if len(pattern) == 0 {
+				pattern = DefaultBranchPattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("bad branch pattern '%s': %w", pattern, err)
+			}
+			r.branch = re
+			if r.footer = opts.Footer; len(r.footer) == 0 {
+				r.footer = DefaultFooter
+			}
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown commit message policy '%s'", p)
+		}
+	}
+	return r, nil
+}
+
+// Violation: a policy not satisfied by the message. Format and Args allow callers to translate the description.
+type Violation struct {
+	Policy string
+	Format string
+	Args   []any
+}
+
+func (v *Violation) String() string {
+	return fmt.Sprintf(v.Format, v.Args...)
+}
+
+// Subject returns the first line of the message.
+func Subject(message string) string {
+	if i := strings.IndexAny(message, "\r\n"); i != -1 {
+		return message[:i]
+	}
+	return message
+}
+
+// Check returns the policies not satisfied by the message. branch-footer only enriches messages and is not checked.
+func (r *Rules) Check(message string) []*Violation {
+	if r == nil {
+		return nil
+	}
+	var violations []*Violation
+	subject := Subject(message)
+	if r.issue != nil && !r.issue.MatchString(message) {
+		violations = append(violations, &Violation{Policy: PolicyIssue, Format: "no issue reference matching '%s'", Args: []any{r.issue.String()}})
+	}
+	// merge commits are generated by zeta, their subjects are not checked
+	if r.conventional && !strings.HasPrefix(subject, "Merge ") && !conventionalRE.MatchString(subject) {
+		violations = append(violations, &Violation{Policy: PolicyConventional, Format: "subject does not follow Conventional Commits 'type(scope): description'"})
+	}
+	if r.subjectLength > 0 {
+		if n := utf8.RuneCountInString(subject); n > r.subjectLength {
+			violations = append(violations, &Violation{Policy: PolicySubjectLength, Format: "subject has %d characters, limit is %d", Args: []any{n, r.subjectLength}})
+		}
+	}
+	return violations
+}
+
+// Enrich appends the footer derived from the branch name, the message is returned unchanged when the branch does
+// not match, the message is empty or the footer is already present.
+func (r *Rules) Enrich(message, branch string) string {
+	if r == nil || r.branch == nil || len(branch) == 0 || len(strings.TrimSpace(message)) == 0 {
+		return message
+	}
+	m := r.branch.FindStringSubmatchIndex(branch)
+	if m == nil {
+		return message
+	}
+	footer := strings.TrimSpace(string(r.branch.ExpandString(nil, r.footer, branch, m)))
+	if len(footer) == 0 {
+		return message
+	}
+	for _, t := range ParseTrailers(message) {
+		if strings.EqualFold(t, footer) {
+			return message
+		}
+	}
+	return AppendTrailer(message, footer)
+}
+
+// ParseTrailers returns the trailer lines of the last paragraph of the commit message.
+// The last paragraph is a trailer block only if every line is 'Token: value' or a continuation line.
+func ParseTrailers(message string) []string {
+	message = strings.TrimRight(strings.ReplaceAll(message, "\r\n", "\n"), "\n \t")
+	i := strings.LastIndex(message, "\n\n")
+	if i == -1 {
+		// subject only
+		return nil
+	}
+	var trailers []string
+	for line := range strings.SplitSeq(message[i+2:], "\n") {
+		if len(line) != 0 && (line[0] == ' ' || line[0] == '\t') {
+			if len(trailers) == 0 {
+				return nil
+			}
+			trailers[len(trailers)-1] += " " + strings.TrimSpace(line)
+			continue
+		}
+		token, value, ok := strings.Cut(line, ":")
+		if !ok || len(token) == 0 || strings.ContainsAny(token, " \t") || len(strings.TrimSpace(value)) == 0 {
+			return nil
+		}
+		trailers = append(trailers, token+": "+strings.TrimSpace(value))
+	}
+	return trailers
+}
+
+// AppendTrailer appends the trailer to the trailer block of the message, a new block is started when the last
+// paragraph is not a trailer block.
+func AppendTrailer(message, trailer string) string {
+	trimmed := strings.TrimRight(message, "\r\n \t")
+	if len(trimmed) == 0 {
+		return trailer + "\n"
+	}
+	if len(ParseTrailers(trimmed)) != 0 {
+		return trimmed + "\n" + trailer + "\n"
+	}
+	return trimmed + "\n\n" + trailer + "\n"
+}
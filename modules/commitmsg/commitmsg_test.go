@@ -0,0 +1,65 @@
+package commitmsg
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	r, err := New(&Options{Policies: []string{PolicyIssue, PolicyConventional, PolicySubjectLength}, SubjectLength: 20})
+	if err != nil {
+		t.Fatalf("new rules error: %v", err)
+	}
+	tests := []struct {
+		message string
+		want    []string
+	}{
+		{"fix(core): crash\n\nIssue: JIRA-123\n", nil},
+		{"feat!: JIRA-1 drop", nil},
+		{"Merge branch 'dev'\n\nJIRA-9", nil},
+		{"fix crash", []string{PolicyIssue, PolicyConventional}},
+		{"fix: JIRA-123 handle the crash on startup", []string{PolicySubjectLength}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, v := range r.Check(tt.message) {
+			got = append(got, v.Policy)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Check(%q) = %v; want %v", tt.message, got, tt.want)
+		}
+	}
+	if _, err := New(&Options{Policies: []string{"unknown"}}); err == nil {
+		t.Errorf("unknown policy should fail")
+	}
+	var none *Rules
+	if v := none.Check("anything"); len(v) != 0 {
+		t.Errorf("nil rules should not check")
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	r, err := New(&Options{Policies: []string{PolicyBranchFooter, PolicyIssue}})
+	if err != nil {
+		t.Fatalf("new rules error: %v", err)
+	}
+	tests := []struct {
+		message string
+		branch  string
+		want    string
+	}{
+		{"fix crash", "feature/JIRA-123-crash", "fix crash\n\nIssue: JIRA-123\n"},
+		{"fix crash\n\nSigned-off-by: Jane <jane@example.com>\n", "JIRA-7", "fix crash\n\nSigned-off-by: Jane <jane@example.com>\nIssue: JIRA-7\n"},
+		{"fix crash\n\nIssue: JIRA-7\n", "JIRA-7", "fix crash\n\nIssue: JIRA-7\n"},
+		{"fix crash", "mainline", "fix crash"},
+	}
+	for _, tt := range tests {
+		got := r.Enrich(tt.message, tt.branch)
+		if got != tt.want {
+			t.Errorf("Enrich(%q, %q) = %q; want %q", tt.message, tt.branch, got, tt.want)
+		}
+		if tt.branch != "mainline" && len(r.Check(got)) != 0 {
+			t.Errorf("enriched message %q should reference the issue", got)
+		}
+	}
+}
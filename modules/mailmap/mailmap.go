@@ -0,0 +1,164 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mailmap implements git-compatible .mailmap parsing.
+//
+// Supported forms (see gitmailmap(5)):
+//
+//	Proper Name <commit@email.xx>
+//	<proper@email.xx> <commit@email.xx>
+//	Proper Name <proper@email.xx> <commit@email.xx>
+//	Proper Name <proper@email.xx> Commit Name <commit@email.xx>
+//
+// Email and name matching is case-insensitive.
+package mailmap
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+type identity struct {
+	name  string
+	email string
+}
+
+type entry struct {
+	identity
+	// names: replacement keyed by lower-case commit name
+	names map[string]identity
+}
+
+// Mailmap maps commit identities to canonical identities.
+type Mailmap struct {
+	entries map[string]*entry
+}
+
+// New returns an empty mailmap.
+func New() *Mailmap {
+	return &Mailmap{entries: make(map[string]*entry)}
+}
+
+// Len returns the number of commit emails mapped.
+func (m *Mailmap) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.entries)
+}
+
+// parseIdent parses "Name <email>" from the start of s and returns the rest.
+func parseIdent(s string) (name, email, rest string, ok bool) {
+	left := strings.IndexByte(s, '<')
+	if left == -1 {
+		return "", "", s, false
+	}
+	right := strings.IndexByte(s[left+1:], '>')
+	if right == -1 {
+		return "", "", s, false
+	}
+	right += left + 1
+	return strings.TrimSpace(s[:left]), strings.TrimSpace(s[left+1 : right]), s[right+1:], true
+}
+
+func (m *Mailmap) add(newName, newEmail, oldName, oldEmail string) {
+	if len(oldEmail) == 0 {
+		oldEmail = newEmail
+		newEmail = ""
+	}
+	key := strings.ToLower(oldEmail)
+	e, ok := m.entries[key]
+	if !ok {
+		e = &entry{}
+		m.entries[key] = e
+	}
+	if len(oldName) == 0 {
+		if len(newName) != 0 {
+			e.name = newName
+		}
+		if len(newEmail) != 0 {
+			e.email = newEmail
+		}
+		return
+	}
+	if e.names == nil {
+		e.names = make(map[string]identity)
+	}
+	e.names[strings.ToLower(oldName)] = identity{name: newName, email: newEmail}
+}
+
+// ParseLine parses one mailmap line, blank lines and comments are ignored.
+func (m *Mailmap) ParseLine(line string) {
+	if i := strings.IndexByte(line, '#'); i != -1 {
+		line = line[:i]
+	}
+	line = strings.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	newName, newEmail, rest, ok := parseIdent(line)
+	if !ok {
+		return
+	}
+	oldName, oldEmail, _, ok := parseIdent(rest)
+	if !ok {
+		m.add(newName, newEmail, "", "")
+		return
+	}
+	m.add(newName, newEmail, oldName, oldEmail)
+}
+
+// Parse reads mailmap entries from r, later entries override earlier ones.
+func (m *Mailmap) Parse(r io.Reader) error {
+	br := bufio.NewScanner(r)
+	br.Buffer(make([]byte, 0, 4096), 1<<20)
+	for br.Scan() {
+		m.ParseLine(br.Text())
+	}
+	return br.Err()
+}
+
+// ParseFile reads mailmap entries from a file, a missing file is not an error.
+func (m *Mailmap) ParseFile(name string) error {
+	fd, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer fd.Close() // nolint
+	return m.Parse(fd)
+}
+
+// Lookup returns the canonical name and email for the given identity.
+// Identities not present in the mailmap are returned unchanged.
+func (m *Mailmap) Lookup(name, email string) (string, string) {
+	if m.Len() == 0 {
+		return name, email
+	}
+	e, ok := m.entries[strings.ToLower(email)]
+	if !ok {
+		return name, email
+	}
+	if len(e.names) != 0 {
+		if i, ok := e.names[strings.ToLower(name)]; ok {
+			if len(i.name) != 0 {
+				name = i.name
+			}
+			if len(i.email) != 0 {
+				email = i.email
+			}
+			return name, email
+		}
+	}
+	if len(e.name) != 0 {
+		name = e.name
+	}
+	if len(e.email) != 0 {
+		email = e.email
+	}
+	return name, email
+}
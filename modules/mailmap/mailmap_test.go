@@ -0,0 +1,49 @@
+package mailmap
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testMailmap = `# comment
+Proper Name <proper@example.com>
+<canonical@example.com> <old@example.com>
+Jane Doe <jane@example.com> <jane@OLD.example.com>
+Joe Smith <joe@example.com> Joe <bot@example.com>
+Other Joe <other@example.com> joe bot <bot@example.com> # trailing comment
+broken line without email
+`
+)
+
+func TestMailmapLookup(t *testing.T) {
+	m := New()
+	if err := m.Parse(strings.NewReader(testMailmap)); err != nil {
+		t.Fatalf("parse mailmap error: %v", err)
+	}
+	tests := []struct {
+		name, email         string
+		wantName, wantEmail string
+	}{
+		{"proper", "proper@example.com", "Proper Name", "proper@example.com"},
+		{"Old", "OLD@example.com", "Old", "canonical@example.com"},
+		{"jane", "jane@old.example.com", "Jane Doe", "jane@example.com"},
+		{"Joe", "bot@example.com", "Joe Smith", "joe@example.com"},
+		{"Joe Bot", "bot@example.com", "Other Joe", "other@example.com"},
+		{"Someone", "bot@example.com", "Someone", "bot@example.com"},
+		{"Unknown", "unknown@example.com", "Unknown", "unknown@example.com"},
+	}
+	for _, tt := range tests {
+		name, email := m.Lookup(tt.name, tt.email)
+		if name != tt.wantName || email != tt.wantEmail {
+			t.Errorf("Lookup(%q, %q) = %q, %q; want %q, %q", tt.name, tt.email, name, email, tt.wantName, tt.wantEmail)
+		}
+	}
+}
+
+func TestMailmapEmpty(t *testing.T) {
+	var m *Mailmap
+	if name, email := m.Lookup("a", "b"); name != "a" || email != "b" {
+		t.Fatalf("nil mailmap changed identity: %s <%s>", name, email)
+	}
+}
@@ -52,9 +52,10 @@ type ListObjectsResultV2 struct {
 }
 
 type Object struct {
-	Key  string `json:"key"`
-	Size int64  `json:"size"`
-	ETag string `json:"etag"`
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
 }
 
 const (
@@ -99,7 +100,7 @@ func (b *bucket) ListObjects(ctx context.Context, prefix, continuationToken stri
 	}
 	objects := make([]*Object, 0, len(result.Objects))
 	for _, o := range result.Objects {
-		objects = append(objects, &Object{Key: o.Key, Size: o.Size, ETag: o.ETag})
+		objects = append(objects, &Object{Key: o.Key, Size: o.Size, ETag: o.ETag, LastModified: o.LastModified})
 	}
 	return objects, result.NextContinuationToken, nil
 }
@@ -0,0 +1,222 @@
+// Package delta encodes and applies binary deltas in the format of git packfiles: the header holds the sizes of the
+// source and the target as varints, followed by instructions that copy a range of the source or insert literal bytes.
+package delta
+
+import (
+	"errors"
+)
+
+const (
+	// blockSize: the source is indexed by blocks of blockSize bytes, shorter matches are inserted
+	blockSize = 32
+	// maxCopySize: the largest copy of one instruction
+	maxCopySize = 0x10000
+	// maxInsertSize: the largest insert of one instruction
+	maxInsertSize = 0x7f
+	// MaxSourceSize: copy offsets are 32 bits
+	MaxSourceSize = 1<<32 - 1
+
+	prime = 16777619
+)
+
+var (
+	ErrInvalidDelta = errors.New("invalid delta data")
+	ErrSourceSize   = errors.New("delta source size mismatch")
+)
+
+func appendVarint(b []byte, n uint64) []byte {
+	for n >= 0x80 {
+		b = append(b, byte(n)|0x80)
+		n >>= 7
+	}
+	return append(b, byte(n))
+}
+
+func readVarint(b []byte, pos int) (uint64, int, error) {
+	var n uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		if pos >= len(b) {
+			return 0, 0, ErrInvalidDelta
+		}
+		c := b[pos]
+		pos++
+		n |= uint64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			return n, pos, nil
+		}
+	}
+	return 0, 0, ErrInvalidDelta
+}
+
+func appendInsert(b []byte, data []byte) []byte {
+	for len(data) > 0 {
+		n := min(len(data), maxInsertSize)
+		b = append(b, byte(n))
+		b = append(b, data[:n]...)
+		data = data[n:]
+	}
+	return b
+}
+
+func appendCopy(b []byte, offset, size int) []byte {
+	for size > 0 {
+		n := min(size, maxCopySize)
+		pos := len(b)
+		op := byte(0x80)
+		b = append(b, 0)
+		for i := range 4 {
+			if c := byte(offset >> (8 * i)); c != 0 {
+				op |= 1 << i
+				b = append(b, c)
+			}
+		}
+		// 0x10000 is encoded as size 0
+		if n != maxCopySize {
+			for i := range 3 {
+				if c := byte(n >> (8 * i)); c != 0 {
+					op |= 0x10 << i
+					b = append(b, c)
+				}
+			}
+		}
+		b[pos] = op
+		offset += n
+		size -= n
+	}
+	return b
+}
+
+func hashBlock(b []byte) uint32 {
+	var h uint32
+	for _, c := range b {
+		h = h*prime + uint32(c)
+	}
+	return h
+}
+
+// Diff returns the delta turning src into dst. The source is indexed by fixed blocks and dst is scanned with a rolling
+// hash, so the cost is linear in the sizes; unchanged ranges of big files become copies and the delta stays small.
+func Diff(src, dst []byte) []byte {
+	b := appendVarint(nil, uint64(len(src)))
+	b = appendVarint(b, uint64(len(dst)))
+	if len(src) < blockSize || len(dst) < blockSize || uint64(len(src)) > MaxSourceSize {
+		return appendInsert(b, dst)
+	}
+	index := make(map[uint32]int, len(src)/blockSize)
+	for i := 0; i+blockSize <= len(src); i += blockSize {
+		h := hashBlock(src[i : i+blockSize])
+		if _, ok := index[h]; !ok {
+			index[h] = i
+		}
+	}
+	// pow = prime^(blockSize-1), removes the leading byte of the window
+	pow := uint32(1)
+	for range blockSize - 1 {
+		pow *= prime
+	}
+	var insertFrom, i int
+	h := hashBlock(dst[:blockSize])
+	for i+blockSize <= len(dst) {
+		if offset, ok := index[h]; ok && string(src[offset:offset+blockSize]) == string(dst[i:i+blockSize]) {
+			// extend the match backwards over the pending insert and forwards
+			start, srcStart := i, offset
+			for start > insertFrom && srcStart > 0 && src[srcStart-1] == dst[start-1] {
+				start--
+				srcStart--
+			}
+			end, srcEnd := i+blockSize, offset+blockSize
+			for end < len(dst) && srcEnd < len(src) && src[srcEnd] == dst[end] {
+				end++
+				srcEnd++
+			}
+			b = appendInsert(b, dst[insertFrom:start])
+			b = appendCopy(b, srcStart, end-start)
+			insertFrom, i = end, end
+			if i+blockSize <= len(dst) {
+				h = hashBlock(dst[i : i+blockSize])
+			}
+			continue
+		}
+		if i+blockSize < len(dst) {
+			h = (h-uint32(dst[i])*pow)*prime + uint32(dst[i+blockSize])
+		}
+		i++
+	}
+	return appendInsert(b, dst[insertFrom:])
+}
+
+// Header returns the sizes of the source and the target recorded in the delta.
+func Header(delta []byte) (srcSize, dstSize uint64, err error) {
+	var pos int
+	if srcSize, pos, err = readVarint(delta, 0); err != nil {
+		return
+	}
+	dstSize, _, err = readVarint(delta, pos)
+	return
+}
+
+// Patch applies the delta to src and returns the target, malformed deltas are rejected without reading out of bounds.
+func Patch(src, delta []byte) ([]byte, error) {
+	srcSize, pos, err := readVarint(delta, 0)
+	if err != nil {
+		return nil, err
+	}
+	if srcSize != uint64(len(src)) {
+		return nil, ErrSourceSize
+	}
+	dstSize, pos, err := readVarint(delta, pos)
+	if err != nil {
+		return nil, err
+	}
+	// every instruction produces at least one byte from at most 8 bytes, bigger targets come from a bad header
+	if dstSize > uint64(len(delta)-pos)*maxCopySize {
+		return nil, ErrInvalidDelta
+	}
+	dst := make([]byte, 0, dstSize)
+	for pos < len(delta) {
+		op := delta[pos]
+		pos++
+		switch {
+		case op&0x80 != 0:
+			var offset, size uint64
+			for i := range 4 {
+				if op&(1<<i) != 0 {
+					if pos >= len(delta) {
+						return nil, ErrInvalidDelta
+					}
+					offset |= uint64(delta[pos]) << (8 * i)
+					pos++
+				}
+			}
+			for i := range 3 {
+				if op&(0x10<<i) != 0 {
+					if pos >= len(delta) {
+						return nil, ErrInvalidDelta
+					}
+					size |= uint64(delta[pos]) << (8 * i)
+					pos++
+				}
+			}
+			if size == 0 {
+				size = maxCopySize
+			}
+			if offset+size > uint64(len(src)) || uint64(len(dst))+size > dstSize {
+				return nil, ErrInvalidDelta
+			}
+			dst = append(dst, src[offset:offset+size]...)
+		case op != 0:
+			n := int(op)
+			if pos+n > len(delta) || uint64(len(dst)+n) > dstSize {
+				return nil, ErrInvalidDelta
+			}
+			dst = append(dst, delta[pos:pos+n]...)
+			pos += n
+		default:
+			return nil, ErrInvalidDelta
+		}
+	}
+	if uint64(len(dst)) != dstSize {
+		return nil, ErrInvalidDelta
+	}
+	return dst, nil
+}
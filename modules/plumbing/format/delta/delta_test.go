@@ -0,0 +1,63 @@
+package delta
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestDiffPatch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	base := make([]byte, 1<<20)
+	_, _ = rng.Read(base)
+	modified := bytes.Clone(base)
+	copy(modified[4096:], []byte("changed in the middle"))
+	inserted := append(append(bytes.Clone(base[:300000]), []byte("inserted")...), base[300000:]...)
+	for name, c := range map[string]struct {
+		src, dst []byte
+		small    bool
+	}{
+		"same":     {src: base, dst: base, small: true},
+		"modified": {src: base, dst: modified, small: true},
+		"inserted": {src: base, dst: inserted, small: true},
+		"truncate": {src: base, dst: base[100:70000], small: true},
+		"empty":    {src: base, dst: nil},
+		"new":      {src: nil, dst: []byte("hello world")},
+		"short":    {src: []byte("abc"), dst: []byte("abcd")},
+	} {
+		d := Diff(c.src, c.dst)
+		if c.small && len(d) > 1024 {
+			t.Errorf("%s: delta too large: %d", name, len(d))
+		}
+		got, err := Patch(c.src, d)
+		if err != nil {
+			t.Fatalf("%s: patch error: %v", name, err)
+		}
+		if !bytes.Equal(got, c.dst) {
+			t.Fatalf("%s: patch result mismatch", name)
+		}
+		srcSize, dstSize, err := Header(d)
+		if err != nil || srcSize != uint64(len(c.src)) || dstSize != uint64(len(c.dst)) {
+			t.Fatalf("%s: bad header %d %d %v", name, srcSize, dstSize, err)
+		}
+	}
+}
+
+func TestPatchInvalid(t *testing.T) {
+	src := []byte("0123456789abcdef0123456789abcdef0123456789")
+	d := Diff(src, src)
+	if _, err := Patch(src[1:], d); err != ErrSourceSize {
+		t.Fatalf("expected source size error, got %v", err)
+	}
+	for _, bad := range [][]byte{
+		d[:len(d)-1],
+		append(bytes.Clone(d), 0),
+		{byte(len(src)), 4, 0x91, 0x40, 0x04}, // copy out of the source
+		{byte(len(src)), 4, 0x05, 'a'},        // truncated insert
+		{byte(len(src)), 0xff},                // truncated header
+	} {
+		if _, err := Patch(src, bad); err == nil {
+			t.Fatalf("invalid delta %x accepted", bad)
+		}
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/modules/vfs"
 )
 
@@ -19,15 +18,31 @@ const (
 	infoExcludeFile = zetaDir + "/info/exclude"
 )
 
-// readIgnoreFile reads a specific git ignore file.
-func readIgnoreFile(fs vfs.VFS, path []string, ignoreFile string) (ps []Pattern, err error) {
-	ignoreFile = strengthen.ExpandPath(ignoreFile)
-	f, err := os.Open(fs.Join(append(path, ignoreFile)...))
+// Compat selects the ignore files of other tools read besides .zetaignore.
+type Compat int
+
+const (
+	CompatNone Compat = iota
+	// CompatGit reads .gitignore in directories without .zetaignore, easing the migration of git repositories.
+	CompatGit
+)
+
+// ParseCompat parses core.ignoreCompat, unknown values read .zetaignore only.
+func ParseCompat(s string) Compat {
+	if strings.EqualFold(s, "git") {
+		return CompatGit
+	}
+	return CompatNone
+}
+
+// readIgnoreFile reads a specific git ignore file, found is false when the file does not exist.
+func readIgnoreFile(fs vfs.VFS, path []string, ignoreFile string) (ps []Pattern, found bool, err error) {
+	f, err := fs.Open(fs.Join(append(path, ignoreFile)...))
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, nil
+			return nil, false, nil
 		}
-		return nil, err
+		return nil, false, err
 	}
 	defer f.Close() // nolint
 
@@ -39,22 +54,24 @@ func readIgnoreFile(fs vfs.VFS, path []string, ignoreFile string) (ps []Pattern,
 		}
 	}
 	if err := scanner.Err(); err != nil {
-		return nil, err
+		return nil, true, err
 	}
 
-	return
+	return ps, true, nil
 }
 
 // ReadPatterns reads the .zeta/info/exclude and then the zetaignore patterns
 // recursively traversing through the directory structure. The result is in
 // the ascending order of priority (last higher).
-func ReadPatterns(fs vfs.VFS, path []string) (ps []Pattern, err error) {
-	ps, _ = readIgnoreFile(fs, path, infoExcludeFile)
+func ReadPatterns(fs vfs.VFS, path []string, compat Compat) (ps []Pattern, err error) {
+	ps, _, _ = readIgnoreFile(fs, path, infoExcludeFile)
 
-	subps, _ := readIgnoreFile(fs, path, zetaignoreFile)
-	ps = append(ps, subps...)
-	subps, _ = readIgnoreFile(fs, path, gitignoreFile)
+	subps, found, _ := readIgnoreFile(fs, path, zetaignoreFile)
 	ps = append(ps, subps...)
+	if !found && compat == CompatGit {
+		subps, _, _ = readIgnoreFile(fs, path, gitignoreFile)
+		ps = append(ps, subps...)
+	}
 
 	dirs, err := fs.ReadDir(filepath.Join(path...))
 	if err != nil {
@@ -67,7 +84,7 @@ func ReadPatterns(fs vfs.VFS, path []string) (ps []Pattern, err error) {
 				continue
 			}
 			var subps []Pattern
-			subps, err = ReadPatterns(fs, append(path, d.Name()))
+			subps, err = ReadPatterns(fs, append(path, d.Name()), compat)
 			if err != nil {
 				return
 			}
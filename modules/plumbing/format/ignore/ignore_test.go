@@ -1,7 +1,11 @@
 package ignore
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/antgroup/hugescm/modules/vfs"
 )
 
 // ---------------------------------------------------------------------------
@@ -606,6 +610,58 @@ func TestOriginalTestcase(t *testing.T) {
 	}
 }
 
+// TestReadPatternsCompat: .gitignore is read only in git compat mode, and only where .zetaignore is absent.
+func TestReadPatternsCompat(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":      "*.log\n",
+		"a/.zetaignore":   "*.tmp\n",
+		"a/.gitignore":    "*.bak\n",
+		"b/.gitignore":    "*.o\n",
+		"b/c/.zetaignore": "# nothing ignored\n",
+		"b/c/.gitignore":  "*.a\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		path []string
+		none bool
+		git  bool
+	}{
+		{[]string{"x.log"}, false, true},
+		{[]string{"a", "x.tmp"}, true, true},
+		{[]string{"a", "x.bak"}, false, false},
+		{[]string{"b", "x.o"}, false, true},
+		{[]string{"b", "c", "x.a"}, false, false},
+	}
+	for _, compat := range []Compat{CompatNone, CompatGit} {
+		ps, err := ReadPatterns(vfs.NewVFS(root), nil, compat)
+		if err != nil {
+			t.Fatalf("read patterns error: %v", err)
+		}
+		m := NewMatcher(ps)
+		for _, tt := range tests {
+			want := tt.none
+			if compat == CompatGit {
+				want = tt.git
+			}
+			if got := m.Match(tt.path, false); got != want {
+				t.Errorf("compat %d: Match(%v) = %v, want %v", compat, tt.path, got, want)
+			}
+		}
+	}
+	if ParseCompat("Git") != CompatGit || ParseCompat("") != CompatNone || ParseCompat("hg") != CompatNone {
+		t.Errorf("unexpected ParseCompat result")
+	}
+}
+
 // Benchmarks
 
 func BenchmarkWildmatchLiteral(b *testing.B) {
@@ -0,0 +1,116 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package statsd sends metrics in the statsd line protocol with DogStatsD tags over UDP. The protocol is accepted by
+// statsd, Datadog agents, Telegraf and the statsd receiver of the OpenTelemetry collector. Metrics are buffered and
+// sent on Flush, losing a packet never blocks or fails the caller.
+package statsd
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPacketSize: stays below the common MTU so packets are not fragmented
+	maxPacketSize = 1432
+	dialTimeout   = time.Second
+)
+
+type Client struct {
+	conn   net.Conn
+	prefix string
+	tags   []string
+	buf    bytes.Buffer
+}
+
+// New connects to addr (host:port), prefix is prepended to metric names, tags are added to every metric.
+func New(addr, prefix string, tags []string) (*Client, error) {
+	conn, err := net.DialTimeout("udp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, prefix: strings.TrimSuffix(prefix, "."), tags: make([]string, 0, len(tags))}
+	for _, t := range tags {
+		if len(t) != 0 {
+			c.tags = append(c.tags, sanitize(t))
+		}
+	}
+	return c, nil
+}
+
+// Tag formats a tag, characters reserved by the protocol are replaced.
+func Tag(key, value string) string {
+	return sanitize(key) + ":" + sanitize(value)
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '|', ',', '#', '\n', '@':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func (c *Client) add(name, value, kind string, tags []string) {
+	var line bytes.Buffer
+	if len(c.prefix) != 0 {
+		_, _ = line.WriteString(c.prefix)
+		_ = line.WriteByte('.')
+	}
+	_, _ = line.WriteString(sanitize(name))
+	_ = line.WriteByte(':')
+	_, _ = line.WriteString(value)
+	_ = line.WriteByte('|')
+	_, _ = line.WriteString(kind)
+	if len(c.tags)+len(tags) != 0 {
+		_, _ = line.WriteString("|#")
+		_, _ = line.WriteString(strings.Join(append(append([]string{}, c.tags...), tags...), ","))
+	}
+	if c.buf.Len() != 0 && c.buf.Len()+1+line.Len() > maxPacketSize {
+		_ = c.Flush()
+	}
+	if c.buf.Len() != 0 {
+		_ = c.buf.WriteByte('\n')
+	}
+	_, _ = c.buf.Write(line.Bytes())
+}
+
+// Timing records a duration in milliseconds.
+func (c *Client) Timing(name string, d time.Duration, tags ...string) {
+	c.add(name, strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64), "ms", tags)
+}
+
+// Count adds n to a counter.
+func (c *Client) Count(name string, n int64, tags ...string) {
+	c.add(name, strconv.FormatInt(n, 10), "c", tags)
+}
+
+// Gauge sets a gauge.
+func (c *Client) Gauge(name string, v int64, tags ...string) {
+	c.add(name, strconv.FormatInt(v, 10), "g", tags)
+}
+
+// Flush sends the buffered metrics.
+func (c *Client) Flush() error {
+	if c.buf.Len() == 0 {
+		return nil
+	}
+	defer c.buf.Reset()
+	_, err := c.conn.Write(c.buf.Bytes())
+	return err
+}
+
+// Close flushes the buffered metrics and closes the connection.
+func (c *Client) Close() error {
+	err := c.Flush()
+	if e := c.conn.Close(); err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+package statsd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen udp: %v", err)
+	}
+	defer pc.Close() // nolint
+	c, err := New(pc.LocalAddr().String(), "zeta.", []string{"ci:true", ""})
+	if err != nil {
+		t.Fatalf("new client error: %v", err)
+	}
+	c.Timing("command.duration", 1500*time.Microsecond, Tag("command", "fetch"), Tag("repo", "group/a|b"))
+	c.Count("transfer.bytes", 42)
+	if err := c.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, maxPacketSize)
+	n, _, err := pc.ReadFrom(b)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	lines := strings.Split(string(b[:n]), "\n")
+	expected := []string{
+		"zeta.command.duration:1.5|ms|#ci:true,command:fetch,repo:group/a_b",
+		"zeta.transfer.bytes:42|c|#ci:true",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected packet: %q", b[:n])
+	}
+	for i, line := range lines {
+		if line != expected[i] {
+			t.Fatalf("line %d: got %q, expected %q", i, line, expected[i])
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package strengthen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	dateUnits = map[string]func(t time.Time, n int) time.Time{
+		"second": func(t time.Time, n int) time.Time { return t.Add(-time.Duration(n) * time.Second) },
+		"minute": func(t time.Time, n int) time.Time { return t.Add(-time.Duration(n) * time.Minute) },
+		"hour":   func(t time.Time, n int) time.Time { return t.Add(-time.Duration(n) * time.Hour) },
+		"day":    func(t time.Time, n int) time.Time { return t.AddDate(0, 0, -n) },
+		"week":   func(t time.Time, n int) time.Time { return t.AddDate(0, 0, -7*n) },
+		"month":  func(t time.Time, n int) time.Time { return t.AddDate(0, -n, 0) },
+		"year":   func(t time.Time, n int) time.Time { return t.AddDate(-n, 0, 0) },
+	}
+	weekdays = map[string]time.Weekday{
+		"sunday":    time.Sunday,
+		"monday":    time.Monday,
+		"tuesday":   time.Tuesday,
+		"wednesday": time.Wednesday,
+		"thursday":  time.Thursday,
+		"friday":    time.Friday,
+		"saturday":  time.Saturday,
+	}
+	// dateLayouts: dates without a zone are in the local time zone
+	dateLayouts = []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04 -0700",
+		"2006-01-02 15:04",
+		"2006-01-02",
+		time.RFC1123Z,
+		time.RFC1123,
+	}
+)
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// parseRelativeDate: '2.weeks.ago', '3 days ago', '1 hour', 'yesterday', 'last tuesday'
+func parseRelativeDate(s string, now time.Time) (time.Time, bool) {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == '.' || r == ' ' || r == '_'
+	})
+	if n := len(fields); n > 0 && fields[n-1] == "ago" {
+		fields = fields[:n-1]
+	}
+	switch len(fields) {
+	case 1:
+		switch fields[0] {
+		case "now":
+			return now, true
+		case "today", "midnight":
+			return startOfDay(now), true
+		case "yesterday":
+			return now.AddDate(0, 0, -1), true
+		}
+		if wd, ok := weekdays[fields[0]]; ok {
+			return lastWeekday(now, wd), true
+		}
+	case 2:
+		if fields[0] == "last" {
+			if wd, ok := weekdays[fields[1]]; ok {
+				return lastWeekday(now, wd), true
+			}
+			if fn, ok := dateUnits[strings.TrimSuffix(fields[1], "s")]; ok {
+				return fn(now, 1), true
+			}
+			return time.Time{}, false
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 0 {
+			return time.Time{}, false
+		}
+		if fn, ok := dateUnits[strings.TrimSuffix(fields[1], "s")]; ok {
+			return fn(now, n), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// lastWeekday returns the start of the latest weekday before today.
+func lastWeekday(now time.Time, wd time.Weekday) time.Time {
+	days := int(now.Weekday()-wd+7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return startOfDay(now.AddDate(0, 0, -days))
+}
+
+// ParseDate parses absolute and relative dates, relative dates are relative to now:
+//
+//	2024-05-01, 2024-05-01 10:30, 2024-05-01T10:30:00+08:00, @1714530600 (unix time)
+//	now, today, yesterday, tuesday, last tuesday, 2.weeks.ago, 3 days ago, 1.hour.ago
+//
+// Dates without a time zone are in the time zone of now.
+func ParseDate(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+	if unix, ok := strings.CutPrefix(s, "@"); ok {
+		sec, err := strconv.ParseInt(unix, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("bad date '%s'", s)
+		}
+		return time.Unix(sec, 0).In(now.Location()), nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if t, ok := parseRelativeDate(s, now); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("bad date '%s'", s)
+}
@@ -0,0 +1,47 @@
+package strengthen
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	// Wednesday
+	now := time.Date(2024, 5, 15, 14, 30, 0, 0, loc)
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"now", now},
+		{"yesterday", time.Date(2024, 5, 14, 14, 30, 0, 0, loc)},
+		{"today", time.Date(2024, 5, 15, 0, 0, 0, 0, loc)},
+		{"2.weeks.ago", time.Date(2024, 5, 1, 14, 30, 0, 0, loc)},
+		{"3 days ago", time.Date(2024, 5, 12, 14, 30, 0, 0, loc)},
+		{"1.hour.ago", time.Date(2024, 5, 15, 13, 30, 0, 0, loc)},
+		{"2 months", time.Date(2024, 3, 15, 14, 30, 0, 0, loc)},
+		{"last tuesday", time.Date(2024, 5, 14, 0, 0, 0, 0, loc)},
+		{"Wednesday", time.Date(2024, 5, 8, 0, 0, 0, 0, loc)},
+		{"last week", time.Date(2024, 5, 8, 14, 30, 0, 0, loc)},
+		{"2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, loc)},
+		{"2024-05-01 10:30", time.Date(2024, 5, 1, 10, 30, 0, 0, loc)},
+		{"2024-05-01T10:30:00Z", time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)},
+		{"2024-05-01 10:30:00 -0700", time.Date(2024, 5, 1, 10, 30, 0, 0, time.FixedZone("", -7*3600))},
+		{"@1714530600", time.Unix(1714530600, 0)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.input, now)
+		if err != nil {
+			t.Errorf("ParseDate(%q) error: %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+	for _, s := range []string{"", "tomorrowish", "3.fortnights.ago", "last", "@abc", "-1 days ago"} {
+		if _, err := ParseDate(s, now); err == nil {
+			t.Errorf("ParseDate(%q) expected error", s)
+		}
+	}
+}
@@ -1,10 +1,12 @@
-package kong
+package strengthen
 
 import "unicode/utf8"
 
+// Levenshtein returns the edit distance between a and b in runes.
+//
 // https://en.wikibooks.org/wiki/Algorithm_Implementation/Strings/Levenshtein_distance#Go
 // License: https://creativecommons.org/licenses/by-sa/3.0/
-func levenshtein(a, b string) int {
+func Levenshtein(a, b string) int {
 	f := make([]int, utf8.RuneCountInString(b)+1)
 
 	for j := range f {
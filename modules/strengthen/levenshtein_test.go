@@ -0,0 +1,23 @@
+package strengthen
+
+import "testing"
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"commit", "commit", 0},
+		{"comit", "commit", 1},
+		{"stauts", "status", 2},
+		{"", "abc", 3},
+		{"分支", "分支名", 1},
+		{"café", "cafe", 1},
+	}
+	for _, tt := range tests {
+		if got := Levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d; want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
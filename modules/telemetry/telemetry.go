@@ -0,0 +1,255 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package telemetry aggregates command timings and error categories. Only the command name (without arguments), the
+// duration and a coarse error category are recorded: paths, repository names, remotes and error messages are never
+// stored. Aggregates are kept in a local file and only uploaded when the caller asks for it.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	// BucketCount: duration histogram buckets, bucket i holds durations in [2^(i-1), 2^i) milliseconds, the last
+	// bucket holds everything longer than ~2.3 hours.
+	BucketCount = 24
+)
+
+const (
+	CategoryCanceled = "canceled"
+	CategoryTimeout  = "timeout"
+	CategoryNetwork  = "network"
+	CategoryError    = "error"
+)
+
+// Command: aggregates of one command.
+type Command struct {
+	Count   int64            `json:"count"`
+	TotalMs int64            `json:"total_ms"`
+	MaxMs   int64            `json:"max_ms"`
+	Buckets []int64          `json:"buckets"`
+	Errors  map[string]int64 `json:"errors,omitempty"`
+}
+
+func bucketOf(ms int64) int {
+	if ms <= 0 {
+		return 0
+	}
+	return min(bits.Len64(uint64(ms)), BucketCount-1)
+}
+
+// Average returns the mean duration.
+func (c *Command) Average() time.Duration {
+	if c.Count == 0 {
+		return 0
+	}
+	return time.Duration(c.TotalMs/c.Count) * time.Millisecond
+}
+
+// Percentile returns the upper bound of the bucket holding the p-th (0-100) percentile, capped by the maximum.
+func (c *Command) Percentile(p float64) time.Duration {
+	var total int64
+	for _, n := range c.Buckets {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := int64(float64(total)*p/100 + 0.5)
+	var seen int64
+	for i, n := range c.Buckets {
+		if seen += n; seen >= max(rank, 1) {
+			return time.Duration(min(int64(1)<<i, c.MaxMs)) * time.Millisecond
+		}
+	}
+	return time.Duration(c.MaxMs) * time.Millisecond
+}
+
+// Failures returns the number of failed runs.
+func (c *Command) Failures() int64 {
+	var n int64
+	for _, v := range c.Errors {
+		n += v
+	}
+	return n
+}
+
+// Stats: aggregates since the last upload, or since the first record when nothing was uploaded.
+type Stats struct {
+	Since     time.Time           `json:"since"`
+	Uploaded  time.Time           `json:"uploaded,omitzero"`
+	Attempted time.Time           `json:"attempted,omitzero"`
+	Commands  map[string]*Command `json:"commands"`
+}
+
+func New() *Stats {
+	return &Stats{Since: time.Now(), Commands: make(map[string]*Command)}
+}
+
+// Record adds a run of the command, category is empty when the command succeeded.
+func (s *Stats) Record(name string, d time.Duration, category string) {
+	c, ok := s.Commands[name]
+	if !ok {
+		c = &Command{}
+		s.Commands[name] = c
+	}
+	if len(c.Buckets) != BucketCount {
+		c.Buckets = append(c.Buckets, make([]int64, BucketCount-len(c.Buckets))...)
+	}
+	ms := d.Milliseconds()
+	c.Count++
+	c.TotalMs += ms
+	c.MaxMs = max(c.MaxMs, ms)
+	c.Buckets[bucketOf(ms)]++
+	if len(category) != 0 {
+		if c.Errors == nil {
+			c.Errors = make(map[string]int64)
+		}
+		c.Errors[category]++
+	}
+}
+
+// Names returns the command names sorted by total time, slowest first.
+func (s *Stats) Names() []string {
+	names := make([]string, 0, len(s.Commands))
+	for k := range s.Commands {
+		names = append(names, k)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if x, y := s.Commands[a].TotalMs, s.Commands[b].TotalMs; x != y {
+			if x > y {
+				return -1
+			}
+			return 1
+		}
+		if a < b {
+			return -1
+		}
+		return 1
+	})
+	return names
+}
+
+// Reset drops the aggregates after they were uploaded.
+func (s *Stats) Reset(now time.Time) {
+	s.Since = now
+	s.Uploaded = now
+	s.Commands = make(map[string]*Command)
+}
+
+// DefaultPath: ~/.config/zeta/telemetry.json
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", "zeta", "telemetry.json"), nil
+}
+
+// Load reads the aggregates, a missing or corrupted file starts new aggregates.
+func Load(p string) *Stats {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return New()
+	}
+	s := &Stats{}
+	if err := json.Unmarshal(data, s); err != nil || s.Since.IsZero() {
+		return New()
+	}
+	if s.Commands == nil {
+		s.Commands = make(map[string]*Command)
+	}
+	return s
+}
+
+// Save replaces the file atomically. Concurrent zeta processes may overwrite each other's record, which is
+// acceptable for statistics.
+func (s *Stats) Save(p string) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	fd, err := os.CreateTemp(filepath.Dir(p), ".telemetry-*")
+	if err != nil {
+		return err
+	}
+	tempName := fd.Name()
+	if _, err = fd.Write(data); err == nil {
+		err = fd.Close()
+	} else {
+		_ = fd.Close()
+	}
+	if err == nil {
+		err = os.Rename(tempName, p)
+	}
+	if err != nil {
+		_ = os.Remove(tempName)
+	}
+	return err
+}
+
+// Categorize maps an error to a coarse category, error messages may contain paths and are never recorded.
+func Categorize(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return CategoryCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CategoryTimeout
+	}
+	if _, ok := errors.AsType[net.Error](err); ok {
+		return CategoryNetwork
+	}
+	return CategoryError
+}
+
+// Report: the upload payload.
+type Report struct {
+	Version  string              `json:"version"`
+	OS       string              `json:"os"`
+	Arch     string              `json:"arch"`
+	Since    time.Time           `json:"since"`
+	Until    time.Time           `json:"until"`
+	Commands map[string]*Command `json:"commands"`
+}
+
+// Upload posts the report as JSON to the endpoint.
+func Upload(ctx context.Context, client *http.Client, endpoint, userAgent string, r *Report) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("upload telemetry: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	s := New()
+	for i := range 10 {
+		s.Record("status", time.Duration(i+1)*100*time.Millisecond, "")
+	}
+	s.Record("status", 5*time.Second, CategoryError)
+	s.Record("fetch", time.Second, CategoryNetwork)
+	c := s.Commands["status"]
+	if c.Count != 11 || c.MaxMs != 5000 || c.Failures() != 1 {
+		t.Fatalf("unexpected aggregates: %+v", c)
+	}
+	if p := c.Percentile(50); p != 1024*time.Millisecond {
+		t.Fatalf("p50 = %v", p)
+	}
+	if p := c.Percentile(100); p != 5*time.Second {
+		t.Fatalf("p100 = %v", p)
+	}
+	if names := s.Names(); len(names) != 2 || names[0] != "status" {
+		t.Fatalf("unexpected order: %v", names)
+	}
+	p := filepath.Join(t.TempDir(), "zeta", "telemetry.json")
+	if err := s.Save(p); err != nil {
+		t.Fatalf("save error: %v", err)
+	}
+	if got := Load(p); got.Commands["fetch"].Errors[CategoryNetwork] != 1 || got.Commands["status"].Count != 11 {
+		t.Fatalf("unexpected loaded aggregates: %+v", got.Commands)
+	}
+}
+
+func TestCategorize(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("fetch: %w", context.Canceled), CategoryCanceled},
+		{context.DeadlineExceeded, CategoryTimeout},
+		{&net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")}, CategoryNetwork},
+		{fmt.Errorf("open /home/zeta/secret: permission denied"), CategoryError},
+	}
+	for _, tt := range tests {
+		if got := Categorize(tt.err); got != tt.want {
+			t.Errorf("Categorize(%v) = %q; want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestUpload(t *testing.T) {
+	var got Report
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	s := New()
+	s.Record("push", time.Second, "")
+	if err := Upload(t.Context(), srv.Client(), srv.URL, "Zeta/test", &Report{Version: "test", Since: s.Since, Until: time.Now(), Commands: s.Commands}); err != nil {
+		t.Fatalf("upload error: %v", err)
+	}
+	if got.Version != "test" || got.Commands["push"].Count != 1 {
+		t.Fatalf("unexpected report: %+v", got)
+	}
+	if err := Upload(t.Context(), srv.Client(), srv.URL+"/missing\x7f", "Zeta/test", &Report{}); err == nil {
+		t.Fatalf("bad endpoint should fail")
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package transferlog appends one JSON line per transport request to a local log, so that slow or failing transfers
+// can be inspected after the fact. The log is rotated when it grows beyond MaxSize, one previous file is kept.
+package transferlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxSize: the log is rotated to <name>.1 once it grows beyond 4MB
+	MaxSize = 4 << 20
+)
+
+const (
+	StatusOK = "ok"
+)
+
+// Record: one transport request. Bytes are the bytes sent for uploads and received for downloads, duration covers
+// reading the response body.
+type Record struct {
+	Time       time.Time `json:"time"`
+	Endpoint   string    `json:"endpoint"`
+	Operation  string    `json:"operation"`
+	Objects    int       `json:"objects,omitempty"`
+	Bytes      int64     `json:"bytes"`
+	DurationMs int64     `json:"duration_ms"`
+	Retries    int       `json:"retries,omitempty"`
+	// Status: ok, the HTTP status code or an error category
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Proxy  string `json:"proxy,omitempty"`
+}
+
+func (r *Record) Duration() time.Duration {
+	return time.Duration(r.DurationMs) * time.Millisecond
+}
+
+// Logger appends records to the log, it is safe for concurrent use.
+type Logger struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+}
+
+func New(path string) *Logger {
+	return &Logger{path: path, maxSize: MaxSize}
+}
+
+func (l *Logger) Path() string {
+	return l.path
+}
+
+func (l *Logger) rotate() {
+	si, err := os.Stat(l.path)
+	if err != nil || si.Size() < l.maxSize {
+		return
+	}
+	// concurrent zeta processes may rotate at the same time, losing a few records is acceptable
+	_ = os.Rename(l.path, l.path+".1")
+}
+
+// Append writes the record as a single line, O_APPEND keeps lines of concurrent processes apart.
+func (l *Logger) Append(r *Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	l.rotate()
+	fd, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write(line); err != nil {
+		_ = fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+func readFile(p string, since time.Time, records []*Record) ([]*Record, error) {
+	fd, err := os.Open(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return records, nil
+		}
+		return nil, err
+	}
+	defer fd.Close() // nolint
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		r := &Record{}
+		// a line may be truncated by a crash, skip it
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			continue
+		}
+		if r.Time.Before(since) {
+			continue
+		}
+		records = append(records, r)
+	}
+	return records, scanner.Err()
+}
+
+// Read returns the records written since the given time, oldest first, including the rotated file.
+func Read(p string, since time.Time) ([]*Record, error) {
+	records, err := readFile(p+".1", since, nil)
+	if err != nil {
+		return nil, err
+	}
+	return readFile(p, since, records)
+}
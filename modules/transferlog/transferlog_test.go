@@ -0,0 +1,50 @@
+package transferlog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendRotateRead(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "logs", "transfer.log")
+	l := New(p)
+	l.maxSize = 256
+	start := time.Now().Add(-2 * time.Hour)
+	for i := range 8 {
+		r := &Record{Time: start.Add(time.Duration(i) * 20 * time.Minute), Endpoint: "https://zeta.example.com/group/mono", Operation: "batch-objects", Objects: i, Bytes: 1024, Status: StatusOK}
+		if err := l.Append(r); err != nil {
+			t.Fatalf("append error: %v", err)
+		}
+	}
+	if _, err := os.Stat(p + ".1"); err != nil {
+		t.Fatalf("log not rotated: %v", err)
+	}
+	// a line truncated by a crash must not hide the others
+	fd, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fd.WriteString("{\"time\":")
+	_ = fd.Close()
+	records, err := Read(p, time.Time{})
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if len(records) == 0 || records[len(records)-1].Objects != 7 {
+		t.Fatalf("unexpected records %d", len(records))
+	}
+	for i := 1; i < len(records); i++ {
+		if records[i].Time.Before(records[i-1].Time) {
+			t.Fatalf("records out of order")
+		}
+	}
+	records, err = Read(p, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("records within the last hour = %d, want 4", len(records))
+	}
+}
@@ -43,3 +43,11 @@ func AskConfirm(confirm *bool, format string, a ...any) error {
 	c := huh.NewConfirm().Title(askTitle(format, a...)).Inline(true).Value(confirm).WithTheme(baseTheme())
 	return c.RunAccessible(os.Stderr, os.Stdin)
 }
+
+// AskSelect prompts for one of the options using huh library, value holds the default and receives the choice.
+//
+// Note: Output goes to stderr to avoid interfering with stdout piping.
+func AskSelect(value *string, options []string, format string, a ...any) error {
+	s := huh.NewSelect[string]().Title(askTitle(format, a...)).Options(huh.NewOptions(options...)...).Value(value).WithTheme(baseTheme())
+	return s.RunAccessible(os.Stderr, os.Stdin)
+}
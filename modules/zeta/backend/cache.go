@@ -0,0 +1,144 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+const (
+	// cacheTouchInterval: the modification time of a blob is the time of its last use, it is only updated when
+	// older than the interval so that reads do not turn into metadata writes.
+	cacheTouchInterval = time.Hour
+)
+
+var (
+	ErrSharedCache = errors.New("blobs of the sharing root are used by other clones")
+)
+
+// Cache: blobs of a repository are a cache of the remote, blobs missing locally are fetched again on demand. Budget
+// limits the size of the loose blobs (0 is unlimited), the least recently used blobs are evicted first. Metadata
+// objects are also cached in memory when a cache is set.
+type Cache struct {
+	Budget      int64
+	hits        atomic.Int64
+	misses      atomic.Int64
+	evictions   atomic.Int64
+	evictedSize atomic.Int64
+}
+
+// CacheMetrics: blob reads found locally (hits) and missing (misses), blobs evicted to stay within the budget.
+type CacheMetrics struct {
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Evictions   int64 `json:"evictions"`
+	EvictedSize int64 `json:"evicted_size"`
+}
+
+func (m *CacheMetrics) Add(o *CacheMetrics) {
+	m.Hits += o.Hits
+	m.Misses += o.Misses
+	m.Evictions += o.Evictions
+	m.EvictedSize += o.EvictedSize
+}
+
+func NewCache(budget int64) *Cache {
+	return &Cache{Budget: max(budget, 0)}
+}
+
+func (c *Cache) Metrics() *CacheMetrics {
+	return &CacheMetrics{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		EvictedSize: c.evictedSize.Load(),
+	}
+}
+
+// WithCache: count blob reads, keep the last use of loose blobs for eviction and cache metadata objects in memory.
+func WithCache(c *Cache) Option {
+	return func(d *Database) {
+		d.cache = c
+	}
+}
+
+// access records the read of a blob, err is the error of opening it.
+func (d *Database) access(oid plumbing.Hash, err error) {
+	if d.cache == nil {
+		return
+	}
+	if err != nil {
+		if plumbing.IsNoSuchObject(err) {
+			d.cache.misses.Add(1)
+		}
+		return
+	}
+	d.cache.hits.Add(1)
+	// blobs of the sharing root are never evicted by a clone, see EvictBlobs
+	if d.cache.Budget == 0 || len(d.sharingRoot) != 0 {
+		return
+	}
+	p := Join(d.blobRoot, oid)
+	si, err := os.Stat(p)
+	if err != nil || time.Since(si.ModTime()) < cacheTouchInterval {
+		// packed blobs are not evicted
+		return
+	}
+	now := time.Now()
+	_ = os.Chtimes(p, now, now)
+}
+
+// CacheMetrics returns the metrics of the cache, nil when no cache is set.
+func (d *Database) CacheMetrics() *CacheMetrics {
+	if d.cache == nil {
+		return nil
+	}
+	return d.cache.Metrics()
+}
+
+// CacheUsage: loose blobs and the loose blobs retained by eviction, packed blobs are not counted.
+type CacheUsage struct {
+	Objects    int
+	Size       int64
+	Pinned     int
+	PinnedSize int64
+}
+
+// CacheUsage returns the usage of the loose blobs, pinned reports the blobs retained by eviction and may be nil.
+func (d *Database) CacheUsage(pinned func(oid plumbing.Hash) bool) (*CacheUsage, error) {
+	objects, err := (&fileStorer{root: d.blobRoot}).looseObjects(math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	u := &CacheUsage{Objects: len(objects)}
+	for _, o := range objects {
+		u.Size += o.Size
+		if pinned != nil && pinned(o.Hash) {
+			u.Pinned++
+			u.PinnedSize += o.Size
+		}
+	}
+	return u, nil
+}
+
+// EvictBlobs removes the least recently used loose blobs until the loose blobs are not larger than limit, blobs for
+// which keep returns true are retained. Blobs of the sharing root are shared with other clones and are not evicted.
+func (d *Database) EvictBlobs(ctx context.Context, limit int64, keep func(oid plumbing.Hash) bool) ([]plumbing.Hash, int64, error) {
+	if len(d.sharingRoot) != 0 {
+		return nil, 0, ErrSharedCache
+	}
+	oids, size, err := d.rw.EvictObjects(ctx, limit, keep)
+	if d.cache != nil {
+		d.cache.evictions.Add(int64(len(oids)))
+		d.cache.evictedSize.Add(size)
+	}
+	return oids, size, err
+}
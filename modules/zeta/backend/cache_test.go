@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+func TestEvictObjects(t *testing.T) {
+	root := t.TempDir()
+	fo := newFileStorer(root, filepath.Join(root, "incoming"), "zstd")
+	oids := make([]plumbing.Hash, 0, 4)
+	now := time.Now()
+	// objects are used from the oldest to the newest, the second is pinned
+	for i, s := range []string{"oldest object", "pinned object", "older object", "newest object"} {
+		h := plumbing.NewHasher()
+		_, _ = h.Write([]byte(s))
+		oid := h.Sum()
+		p := fo.path(oid)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+		used := now.Add(time.Duration(i-4) * time.Hour)
+		if err := os.Chtimes(p, used, used); err != nil {
+			t.Fatal(err)
+		}
+		oids = append(oids, oid)
+	}
+	keep := func(oid plumbing.Hash) bool {
+		return oid == oids[1]
+	}
+	// 51 bytes, the two least recently used unpinned objects are evicted
+	evicted, size, err := fo.EvictObjects(t.Context(), 26, keep)
+	if err != nil {
+		t.Fatalf("evict objects error: %v", err)
+	}
+	if !slices.Equal(evicted, []plumbing.Hash{oids[0], oids[2]}) || size != 25 {
+		t.Fatalf("evicted %v size %d", evicted, size)
+	}
+	if evicted, _, err = fo.EvictObjects(t.Context(), 0, keep); err != nil || !slices.Equal(evicted, []plumbing.Hash{oids[3]}) {
+		t.Fatalf("evict all: %v %v", evicted, err)
+	}
+	if _, err := os.Stat(fo.path(oids[1])); err != nil {
+		t.Fatalf("pinned object evicted: %v", err)
+	}
+}
+
+func TestCacheMetrics(t *testing.T) {
+	d, err := NewDatabase(filepath.Join(t.TempDir(), ".zeta"), WithCache(NewCache(1<<20)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close() // nolint
+	oid, err := d.HashTo(t.Context(), strings.NewReader("cached blob"), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Join(d.blobRoot, oid)
+	old := time.Now().Add(-2 * cacheTouchInterval)
+	if err := os.Chtimes(p, old, old); err != nil {
+		t.Fatal(err)
+	}
+	br, err := d.Blob(t.Context(), oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = br.Close()
+	// the last use of the blob is kept for eviction
+	if si, err := os.Stat(p); err != nil || si.ModTime().Before(time.Now().Add(-cacheTouchInterval)) {
+		t.Fatalf("blob not touched: %v", err)
+	}
+	if _, err := d.Blob(t.Context(), plumbing.NewHash("bad2e8ac27ccf6cdf3b61e4b8c3b9ef8b1a1b8b7a0e8c1d2e3f405162738495a")); !plumbing.IsNoSuchObject(err) {
+		t.Fatalf("missing blob: %v", err)
+	}
+	u, err := d.CacheUsage(func(plumbing.Hash) bool { return true })
+	if err != nil || u.Objects != 1 || u.Pinned != 1 || u.Size != u.PinnedSize {
+		t.Fatalf("usage %v: %v", u, err)
+	}
+	if _, _, err := d.EvictBlobs(t.Context(), 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	m := d.CacheMetrics()
+	if m.Hits != 1 || m.Misses != 1 || m.Evictions != 1 || m.EvictedSize != u.Size {
+		t.Fatalf("metrics %+v", m)
+	}
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Fatalf("blob not evicted: %v", err)
+	}
+}
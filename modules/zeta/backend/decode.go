@@ -31,7 +31,7 @@ var (
 )
 
 func (d *Database) store(a any) error {
-	if !d.enableLRU {
+	if d.cache == nil {
 		return nil
 	}
 	switch v := a.(type) {
@@ -84,14 +84,22 @@ func (d *Database) Exists(oid plumbing.Hash, metadata bool) error {
 }
 
 // Object: find object and set backend
-// decode and set backend
-func (d *Database) Object(_ context.Context, oid plumbing.Hash) (any, error) {
+// decode and set backend, corrupt objects are healed and read again
+func (d *Database) Object(ctx context.Context, oid plumbing.Hash) (any, error) {
 	if oid == plumbing.EmptyTree {
 		return object.NewEmptyTree(d.backend), nil
 	}
+	a, err := d.object(oid)
+	if err == nil || !d.heal(ctx, err) {
+		return a, err
+	}
+	return d.object(oid)
+}
+
+func (d *Database) object(oid plumbing.Hash) (any, error) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
-	if d.enableLRU {
+	if d.cache != nil {
 		if a, err := d.fromCache(oid); err == nil {
 			return a, nil
 		}
@@ -101,7 +109,7 @@ func (d *Database) Object(_ context.Context, oid plumbing.Hash) (any, error) {
 		return nil, err
 	}
 	defer rc.Close() // nolint
-	a, err := object.Decode(rc, oid, d.backend)
+	a, err := d.decodeVerified(rc, oid)
 	if err == nil {
 		_ = d.store(a)
 	}
@@ -175,20 +183,37 @@ func (d *Database) Tag(ctx context.Context, oid plumbing.Hash) (*object.Tag, err
 	return nil, NewErrMismatchedObjectType(oid, "tag")
 }
 
-func (d *Database) Blob(_ context.Context, oid plumbing.Hash) (br *object.Blob, err error) {
+func (d *Database) Blob(ctx context.Context, oid plumbing.Hash) (*object.Blob, error) {
 	if oid == BLANK_BLOB_HASH {
 		return &object.Blob{Contents: strings.NewReader("")}, nil
 	}
+	br, err := d.blob(ctx, oid)
+	if err == nil || !d.heal(ctx, err) {
+		return br, err
+	}
+	return d.blob(ctx, oid)
+}
+
+func (d *Database) blob(ctx context.Context, oid plumbing.Hash) (*object.Blob, error) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
-	var rc io.ReadCloser
-	if rc, err = d.ro.Open(oid); err != nil {
+	rc, err := d.ro.Open(oid)
+	d.access(oid, err)
+	if err != nil {
 		return nil, err
 	}
-	if br, err = object.NewBlob(rc); err != nil {
+	br, err := object.NewBlob(rc)
+	if err != nil {
 		_ = rc.Close()
+		if serr := sealedError(d.ro, oid); serr != nil {
+			return nil, serr
+		}
+		return nil, newCorruptObject(oid, false, err)
 	}
-	return
+	if !d.blobVerified(oid) {
+		br.Contents = &verifiedReader{Reader: br.Contents, ctx: ctx, d: d, oid: oid, h: plumbing.NewHasher()}
+	}
+	return br, nil
 }
 
 type SizeReader interface {
@@ -258,6 +283,8 @@ func (d *Database) metaSizeReader(oid plumbing.Hash) (SizeReader, error) {
 		return &sizeReader{Reader: reader, closer: v, size: si.Size()}, nil
 	case *pack.SizeReader:
 		return &sizeReader{Reader: reader, closer: v, size: v.Size()}, nil
+	case SizeReader:
+		return &sizeReader{Reader: reader, closer: v, size: v.Size()}, nil
 	default:
 	}
 	_ = rc.Close()
@@ -281,6 +308,7 @@ func (d *Database) SizeReader(oid plumbing.Hash, meta bool) (SizeReader, error)
 		return d.metaSizeReader(oid)
 	}
 	rc, err := d.ro.Open(oid)
+	d.access(oid, err)
 	if err != nil {
 		return nil, err
 	}
@@ -294,6 +322,8 @@ func (d *Database) SizeReader(oid plumbing.Hash, meta bool) (SizeReader, error)
 		return &sizeReader{Reader: v, closer: v, size: si.Size()}, nil
 	case *pack.SizeReader:
 		return &sizeReader{Reader: v, closer: v, size: v.Size()}, nil
+	case SizeReader:
+		return v, nil
 	default:
 	}
 	_ = rc.Close()
@@ -316,7 +346,9 @@ func (d *Database) OpenReader(oid plumbing.Hash, meta bool) (io.ReadCloser, erro
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 	if !meta {
-		return d.ro.Open(oid)
+		rc, err := d.ro.Open(oid)
+		d.access(oid, err)
+		return rc, err
 	}
 	rc, err := d.metaRO.Open(oid)
 	if err != nil {
@@ -109,6 +109,10 @@ func (d *Database) ValidateFD(fd *os.File, oid plumbing.Hash) error {
 		_ = os.Remove(name)
 		return err
 	}
+	if d.sealer != nil {
+		// partial downloads stay in plaintext so they can be resumed, the validated blob is sealed.
+		return d.sealPart(name, oid)
+	}
 	if err := os.MkdirAll(filepath.Dir(saveTo), 0755); err != nil {
 		_ = os.Remove(name)
 		return err
@@ -119,3 +123,15 @@ func (d *Database) ValidateFD(fd *os.File, oid plumbing.Hash) error {
 	}
 	return nil
 }
+
+func (d *Database) sealPart(name string, oid plumbing.Hash) error {
+	defer os.Remove(name) // nolint
+	fd, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer fd.Close() // nolint
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.rw.Unpack(oid, fd)
+}
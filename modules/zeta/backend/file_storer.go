@@ -6,13 +6,16 @@ package backend
 import (
 	"errors"
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/antgroup/hugescm/modules/mime"
@@ -77,6 +80,8 @@ type fileStorer struct {
 	// temp directory, defaults to os.TempDir
 	incoming       string
 	selectedMethod CompressMethod
+	// sealer encrypts objects written to disk, nil when encryption is disabled
+	sealer *Sealer
 }
 
 var (
@@ -177,22 +182,8 @@ func compress(r io.Reader, w ExtendWriter, method CompressMethod) (written int64
 // 8 byte uncompressed length
 // N bytes raw or compressed data
 func (fo *fileStorer) hashToInternal(fd *os.File, r io.Reader, size int64, compressed bool) error {
-	var err error
-	// 4 byte magic
-	if _, err := fd.Write(BLOB_MAGIC[:]); err != nil {
-		return err
-	}
-	// 2 byte version
-	if err := binary.Write(fd, binary.BigEndian, DEFAULT_BLOB_VERSION); err != nil {
-		return err
-	}
-	// 2 byte method
 	method := fo.method(compressed)
-	if err := binary.Write(fd, binary.BigEndian, method); err != nil {
-		return err
-	}
-	// 8 byte uncompressed length
-	if err = binary.Write(fd, binary.BigEndian, size); err != nil {
+	if err := writeBlobHeader(fd, method, size); err != nil {
 		return err
 	}
 	bytes, err := compress(r, fd, method)
@@ -217,6 +208,92 @@ func (fo *fileStorer) hashToInternal(fd *os.File, r io.Reader, size int64, compr
 	return nil
 }
 
+func writeBlobHeader(w io.Writer, method CompressMethod, size int64) error {
+	// 4 byte magic
+	if _, err := w.Write(BLOB_MAGIC[:]); err != nil {
+		return err
+	}
+	// 2 byte version
+	if err := binary.Write(w, binary.BigEndian, DEFAULT_BLOB_VERSION); err != nil {
+		return err
+	}
+	// 2 byte method
+	if err := binary.Write(w, binary.BigEndian, method); err != nil {
+		return err
+	}
+	// 8 byte uncompressed length
+	return binary.Write(w, binary.BigEndian, size)
+}
+
+// hashToSealed: like hashToInternal, but the blob is sealed before it is written to disk. Sealed blobs cannot be
+// patched in place, so contents of unknown size are spooled to a sealed temporary file first.
+func (fo *fileStorer) hashToSealed(fd *os.File, r io.Reader, size int64, compressed bool) error {
+	if size < 0 {
+		spooled, n, err := fo.spool(r)
+		if err != nil {
+			return err
+		}
+		defer spooled.Close() // nolint
+		r, size = spooled, n
+	}
+	sw, err := fo.sealer.newWriter(fd)
+	if err != nil {
+		return err
+	}
+	method := fo.method(compressed)
+	if err := writeBlobHeader(sw, method, size); err != nil {
+		return err
+	}
+	bytes, err := compress(r, sw, method)
+	if err != nil {
+		return err
+	}
+	if size != bytes {
+		return fmt.Errorf("blob size not match expected, actual size %d, expected size %d", bytes, size)
+	}
+	return sw.Close()
+}
+
+// spool saves the contents to a sealed temporary file, and returns the reader of the contents and the size.
+func (fo *fileStorer) spool(r io.Reader) (io.ReadCloser, int64, error) {
+	fd, err := os.CreateTemp(fo.incoming, "spool")
+	if err != nil {
+		return nil, 0, err
+	}
+	closeFn := func() error {
+		_ = fd.Close()
+		return os.Remove(fd.Name())
+	}
+	sw, err := fo.sealer.newWriter(fd)
+	if err != nil {
+		_ = closeFn()
+		return nil, 0, err
+	}
+	n, err := io.Copy(sw, r)
+	if err == nil {
+		err = sw.Close()
+	}
+	if err != nil {
+		_ = closeFn()
+		return nil, 0, err
+	}
+	header := make([]byte, sealedHeaderSize)
+	if _, err := fd.ReadAt(header, 0); err != nil {
+		_ = closeFn()
+		return nil, 0, err
+	}
+	if _, err := fd.Seek(sealedHeaderSize, io.SeekStart); err != nil {
+		_ = closeFn()
+		return nil, 0, err
+	}
+	contents, err := fo.sealer.open(header, fd)
+	if err != nil {
+		_ = closeFn()
+		return nil, 0, err
+	}
+	return &readCloser{Reader: contents, closeFn: closeFn}, n, nil
+}
+
 func mkdir(paths ...string) error {
 	for _, path := range paths {
 		// os.MkdirAll check dir exists
@@ -261,7 +338,11 @@ func (fo *fileStorer) HashTo(ctx context.Context, r io.Reader, size int64) (oid
 		return oid, err
 	}
 	incomingPath := fd.Name()
-	if err = fo.hashToInternal(fd, io.TeeReader(contents, hasher), size, compressed); err != nil {
+	hashTo := fo.hashToInternal
+	if fo.sealer != nil {
+		hashTo = fo.hashToSealed
+	}
+	if err = hashTo(fd, io.TeeReader(contents, hasher), size, compressed); err != nil {
 		_ = fd.Close()
 		_ = os.Remove(incomingPath)
 		return
@@ -281,6 +362,21 @@ func (fo *fileStorer) HashTo(ctx context.Context, r io.Reader, size int64) (oid
 	return
 }
 
+// writeSealed: fn writes the object to fd, the object is sealed when encryption is enabled.
+func (fo *fileStorer) writeSealed(fd *os.File, fn func(w io.Writer) error) error {
+	if fo.sealer == nil {
+		return fn(fd)
+	}
+	sw, err := fo.sealer.newWriter(fd)
+	if err != nil {
+		return err
+	}
+	if err := fn(sw); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
 func (fo *fileStorer) WriteEncoded(e object.Encoder) (oid plumbing.Hash, err error) {
 	var fd *os.File
 	if err = mkdir(fo.incoming); err != nil {
@@ -291,7 +387,9 @@ func (fo *fileStorer) WriteEncoded(e object.Encoder) (oid plumbing.Hash, err err
 	}
 	incomingPath := fd.Name()
 	hasher := plumbing.NewHasher()
-	if err = e.Encode(io.MultiWriter(hasher, fd)); err != nil {
+	if err = fo.writeSealed(fd, func(w io.Writer) error {
+		return e.Encode(io.MultiWriter(hasher, w))
+	}); err != nil {
 		_ = fd.Close()
 		_ = os.Remove(incomingPath)
 		return
@@ -415,7 +513,10 @@ func (fo *fileStorer) Unpack(oid plumbing.Hash, r io.Reader) (err error) {
 		return
 	}
 	incomingPath := fd.Name()
-	if _, err = fd.ReadFrom(r); err != nil {
+	if err = fo.writeSealed(fd, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	}); err != nil {
 		_ = fd.Close()
 		_ = os.Remove(incomingPath)
 		return
@@ -530,7 +631,8 @@ func (fo *fileStorer) PruneObject(ctx context.Context, oid plumbing.Hash) error
 	return nil
 }
 
-func (fo *fileStorer) PruneObjects(ctx context.Context, largeSize int64) ([]plumbing.Hash, int64, error) {
+// PruneObjects removes loose objects not smaller than largeSize, objects for which keep returns true are retained.
+func (fo *fileStorer) PruneObjects(ctx context.Context, largeSize int64, keep func(oid plumbing.Hash) bool) ([]plumbing.Hash, int64, error) {
 	oids := make([]plumbing.Hash, 0, 100)
 	var totalSize int64
 	err := filepath.WalkDir(fo.root, func(path string, d fs.DirEntry, err error) error {
@@ -554,10 +656,13 @@ func (fo *fileStorer) PruneObjects(ctx context.Context, largeSize int64) ([]plum
 		size := si.Size()
 		if size < largeSize {
 			return nil
-
 		}
-		if err = os.Remove(filepath.Join(path, name)); err == nil {
-			oids = append(oids, plumbing.NewHash(name))
+		oid := plumbing.NewHash(name)
+		if keep != nil && keep(oid) {
+			return nil
+		}
+		if err = os.Remove(path); err == nil {
+			oids = append(oids, oid)
 			totalSize += size
 			return nil
 		}
@@ -568,3 +673,43 @@ func (fo *fileStorer) PruneObjects(ctx context.Context, largeSize int64) ([]plum
 	})
 	return oids, totalSize, err
 }
+
+// EvictObjects removes the least recently modified loose objects until the loose objects are not larger than limit,
+// objects for which keep returns true are retained but counted.
+func (fo *fileStorer) EvictObjects(ctx context.Context, limit int64, keep func(oid plumbing.Hash) bool) ([]plumbing.Hash, int64, error) {
+	objects, err := fo.looseObjects(math.MaxInt64)
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int64
+	candidates := make([]*LooseObject, 0, len(objects))
+	for _, o := range objects {
+		total += o.Size
+		if keep == nil || !keep(o.Hash) {
+			candidates = append(candidates, o)
+		}
+	}
+	slices.SortFunc(candidates, func(a, b *LooseObject) int {
+		if c := cmp.Compare(a.Modification, b.Modification); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.Hash[:], b.Hash[:])
+	})
+	oids := make([]plumbing.Hash, 0, 100)
+	var evictedSize int64
+	for _, o := range candidates {
+		if total <= limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return oids, evictedSize, err
+		}
+		if err := os.Remove(fo.path(o.Hash)); err != nil && !os.IsNotExist(err) {
+			return oids, evictedSize, err
+		}
+		total -= o.Size
+		oids = append(oids, o.Hash)
+		evictedSize += o.Size
+	}
+	return oids, evictedSize, nil
+}
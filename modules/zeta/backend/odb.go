@@ -35,10 +35,14 @@ type Database struct {
 	// closed is a uint32 managed by sync/atomic's <X>Uint32 methods. It
 	// yields a value of 0 if the *Database it is stored upon is open,
 	// and a value of 1 if it is closed.
-	closed    uint32
-	mu        sync.RWMutex
-	backend   object.Backend
-	enableLRU bool
+	closed  uint32
+	mu      sync.RWMutex
+	backend object.Backend
+	// cache: blob reads and evictions, metadata objects are cached in metaLRU when set
+	cache    *Cache
+	blobRoot string
+	sealer   *Sealer
+	healer   Healer
 }
 
 type Option func(*Database)
@@ -51,15 +55,16 @@ func WithSharingRoot(sharingRoot string) Option {
 	}
 }
 
-func WithEnableLRU(enableLRU bool) Option {
+func WithAbstractBackend(backend object.Backend) Option {
 	return func(d *Database) {
-		d.enableLRU = enableLRU
+		d.backend = backend
 	}
 }
 
-func WithAbstractBackend(backend object.Backend) Option {
+// WithSealer: objects written to the database are encrypted, sealed objects are decrypted on read.
+func WithSealer(s *Sealer) Option {
 	return func(d *Database) {
-		d.backend = backend
+		d.sealer = s
 	}
 }
 
@@ -121,12 +126,14 @@ func (d *Database) initializeBlobStorage() error {
 		return err
 	}
 	fo := newFileStorer(root, incoming, d.compressionALGO)
+	fo.sealer = d.sealer
 	packs, err := pack.NewStorage(root)
 	if err != nil {
 		return err
 	}
-	d.ro = storage.MultiStorage(fo, packs)
+	d.ro = d.sealer.wrap(storage.MultiStorage(fo, packs))
 	d.rw = fo
+	d.blobRoot = root
 	return nil
 }
 
@@ -145,13 +152,14 @@ func (d *Database) initializeMetadataStorage() error {
 		return err
 	}
 	fo := newFileStorer(root, incoming, d.compressionALGO)
+	fo.sealer = d.sealer
 	packs, err := pack.NewStorage(root)
 	if err != nil {
 		return err
 	}
-	d.metaRO = storage.MultiStorage(fo, packs)
+	d.metaRO = d.sealer.wrap(storage.MultiStorage(fo, packs))
 	d.metaRW = fo
-	if !d.enableLRU {
+	if d.cache == nil {
 		return nil
 	}
 	if d.metaLRU != nil {
@@ -198,3 +206,7 @@ func (d *Database) CompressionALGO() string {
 func (d *Database) Root() string {
 	return d.root
 }
+
+func (d *Database) SharingRoot() string {
+	return d.sharingRoot
+}
@@ -7,6 +7,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -83,7 +85,39 @@ func openObject(ro storage.Storage, oid plumbing.Hash, o *packedObject) (SizeRea
 	return nil, 0, errors.New("unable detect reader size")
 }
 
-func repackMetaObjects(ctx context.Context, ro storage.Storage, objects packedObjects, quarantine string, bar Indicators) error {
+// openPackedObject opens the object to be repacked, objects are sealed with the current key when resealing.
+func openPackedObject(opts *PackOptions, ro storage.Storage, oid plumbing.Hash, o *packedObject) (SizeReader, int64, bool, error) {
+	if !opts.Reseal {
+		sr, modification, err := openObject(ro, oid, o)
+		return sr, modification, false, err
+	}
+	sr, resealed, err := opts.Sealer.resealObject(ro, oid)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return sr, o.modification, resealed, nil
+}
+
+// rewriteObject replaces the loose object with the resealed object, the old object is closed before it is replaced.
+func rewriteObject(fo *fileStorer, oid plumbing.Hash, sr SizeReader, quarantine string) error {
+	fd, err := os.CreateTemp(quarantine, "object")
+	if err != nil {
+		_ = sr.Close()
+		return err
+	}
+	_, err = io.Copy(fd, sr)
+	_ = sr.Close()
+	_ = fd.Close()
+	if err == nil {
+		err = finalizeObject(fd.Name(), fo.path(oid))
+	}
+	if err != nil {
+		_ = os.Remove(fd.Name())
+	}
+	return err
+}
+
+func repackMetaObjects(ctx context.Context, opts *PackOptions, ro storage.Storage, objects packedObjects, quarantine string, bar Indicators) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -96,7 +130,7 @@ func repackMetaObjects(ctx context.Context, ro storage.Storage, objects packedOb
 	defer w.Close() // nolint
 	for oid, po := range objects {
 		bar.Add(1)
-		sr, modification, err := openObject(ro, oid, po)
+		sr, modification, _, err := openPackedObject(opts, ro, oid, po)
 		if err != nil {
 			return err
 		}
@@ -115,11 +149,14 @@ func repackObjects(ctx context.Context, opts *PackOptions, ro storage.Storage, f
 		return ctx.Err()
 	default:
 	}
-	unpack := func(oid plumbing.Hash, po *packedObject, sr SizeReader) error {
-		defer sr.Close() // nolint
+	unpack := func(oid plumbing.Hash, po *packedObject, sr SizeReader, resealed bool) error {
 		if !po.packed {
-			return nil
+			if resealed {
+				return rewriteObject(fo, oid, sr, quarantine)
+			}
+			return sr.Close()
 		}
+		defer sr.Close() // nolint
 		return fo.Unpack(oid, sr)
 	}
 	w, err := pack.NewWriter(quarantine, 0)
@@ -129,12 +166,12 @@ func repackObjects(ctx context.Context, opts *PackOptions, ro storage.Storage, f
 	defer w.Close() // nolint
 	for oid, po := range objects {
 		bar.Add(1)
-		sr, modification, err := openObject(ro, oid, po)
+		sr, modification, resealed, err := openPackedObject(opts, ro, oid, po)
 		if err != nil {
 			return err
 		}
 		if sr.Size() > opts.PackThreshold {
-			if err := unpack(oid, po, sr); err != nil {
+			if err := unpack(oid, po, sr, resealed); err != nil {
 				return err
 			}
 			objects[oid] = nil
@@ -155,7 +192,7 @@ func repackObjectsEx(ctx context.Context, opts *PackOptions, ro storage.Storage,
 	bar.Run(newCtx)
 
 	if meta {
-		err = repackMetaObjects(ctx, ro, objects, quarantine, bar)
+		err = repackMetaObjects(ctx, opts, ro, objects, quarantine, bar)
 	} else {
 		err = repackObjects(ctx, opts, ro, fo, objects, quarantine, bar)
 	}
@@ -229,7 +266,7 @@ func hasTidyPacks(root string) bool {
 }
 
 func packObjectsInternal(ctx context.Context, opts *PackOptions, root string, meta bool) error {
-	fo := newFileStorer(root, "", opts.CompressionALGO)
+	fo := newFileStorer(root, filepath.Join(filepath.Dir(root), "incoming"), opts.CompressionALGO)
 	packs, err := pack.NewScanner(root)
 	if err != nil {
 		return fmt.Errorf("new scanner error: %w", err)
@@ -242,7 +279,12 @@ func packObjectsInternal(ctx context.Context, opts *PackOptions, root string, me
 		}
 	}()
 	objects := make(packedObjects)
-	looseObjects, err := fo.looseObjects(opts.PackThreshold)
+	sizeMax := opts.PackThreshold
+	if opts.Reseal {
+		// large loose objects are not packed, but they are sealed again in place
+		sizeMax = math.MaxInt64
+	}
+	looseObjects, err := fo.looseObjects(sizeMax)
 	if err != nil {
 		return err
 	}
@@ -251,7 +293,7 @@ func packObjectsInternal(ctx context.Context, opts *PackOptions, root string, me
 		step = "metadata"
 	}
 
-	if len(looseObjects) == 0 && !hasTidyPacks(root) {
+	if len(looseObjects) == 0 && !hasTidyPacks(root) && !opts.Reseal {
 		// no small loose objects, skipped.
 		opts.Printf("Pack %s objects: no smaller loose object, skipping packing.\n", step)
 		return nil
@@ -277,9 +319,19 @@ func packObjectsInternal(ctx context.Context, opts *PackOptions, root string, me
 	}()
 
 	opts.Printf("Pack %s objects: loose object %d packed objects %d\n", step, len(looseObjects), packedEntries)
+	if opts.Reseal {
+		opts.Printf("Encrypt %s objects with key %s\n", step, opts.Sealer.KeyID())
+	}
 	if err := repackObjectsEx(ctx, opts, ro, fo, objects, quarantineDir, meta); err != nil {
 		return fmt.Errorf("repack objects [metadata: %v] %w", meta, err)
 	}
+	// the new pack has the same name as an old pack when nothing changed, it must not be removed
+	preserved := make(map[string]bool)
+	if entries, err := os.ReadDir(quarantineDir); err == nil {
+		for _, e := range entries {
+			preserved[e.Name()] = true
+		}
+	}
 	if err := preservePack(root, quarantineDir); err != nil {
 		return err
 	}
@@ -287,6 +339,9 @@ func packObjectsInternal(ctx context.Context, opts *PackOptions, root string, me
 	_ = ro.Close()
 	closed = true
 	for _, p := range names {
+		if preserved[filepath.Base(p)] {
+			continue
+		}
 		_ = os.Remove(p)                                          // PACK
 		_ = os.Remove(strings.TrimSuffix(p, ".pack") + ".idx")    // PACK INDEX
 		_ = os.Remove(strings.TrimSuffix(p, ".pack") + ".mtimes") // PACK INDEX
@@ -306,8 +361,12 @@ type PackOptions struct {
 	Quiet           bool
 	CompressionALGO string
 	PackThreshold   int64
-	Logger          func(format string, a ...any)
-	NewIndicators   NewIndicators
+	// Sealer: encrypts objects written by repack, required by Reseal.
+	Sealer *Sealer
+	// Reseal: plaintext objects and objects sealed with a retired key are sealed with the current key.
+	Reseal        bool
+	Logger        func(format string, a ...any)
+	NewIndicators NewIndicators
 }
 
 const (
@@ -336,6 +395,9 @@ func (opts *PackOptions) Printf(format string, a ...any) {
 
 func PackObjects(ctx context.Context, opts *PackOptions) error {
 	opts.checkInit()
+	if opts.Reseal && opts.Sealer == nil {
+		return errors.New("reseal objects requires object encryption key")
+	}
 	metaRoot := filepath.Join(opts.ZetaDir, "metadata")
 	if err := packObjectsInternal(ctx, opts, metaRoot, true); err != nil {
 		return err
@@ -6,6 +6,7 @@ package pack
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
@@ -130,6 +131,29 @@ func (e *Encoder) Write(oid plumbing.Hash, size uint32, r io.Reader, modificatio
 	return
 }
 
+// DiscardLast removes the last written object, only supported when the number of entries is unknown.
+func (e *Encoder) DiscardLast() error {
+	if e.hasher.Hash != nil {
+		return errors.New("discard object is not supported when the number of entries is known")
+	}
+	if len(e.objects) == 0 {
+		return nil
+	}
+	last := e.objects[len(e.objects)-1]
+	if err := e.bw.Flush(); err != nil {
+		return err
+	}
+	if err := e.fd.Truncate(int64(last.Offset)); err != nil {
+		return err
+	}
+	if _, err := e.fd.Seek(int64(last.Offset), io.SeekStart); err != nil {
+		return err
+	}
+	e.objects = e.objects[:len(e.objects)-1]
+	e.offset = last.Offset
+	return nil
+}
+
 func (e *Encoder) Name() string {
 	return e.sum.String()
 }
@@ -260,6 +284,10 @@ func (w *Writer) Write(oid plumbing.Hash, size uint32, r io.Reader, modification
 	return w.e.Write(oid, size, r, modification)
 }
 
+func (w *Writer) DiscardLast() error {
+	return w.e.DiscardLast()
+}
+
 func (w *Writer) WriteTrailer() error {
 	if err := w.e.WriteTrailer(); err != nil {
 		return err
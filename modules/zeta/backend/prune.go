@@ -16,6 +16,6 @@ func (d *Database) PruneObject(ctx context.Context, oid plumbing.Hash, metadata
 	return d.rw.PruneObject(ctx, oid)
 }
 
-func (d *Database) PruneObjects(ctx context.Context, largeSize int64) ([]plumbing.Hash, int64, error) {
-	return d.rw.PruneObjects(ctx, largeSize)
+func (d *Database) PruneObjects(ctx context.Context, largeSize int64, keep func(oid plumbing.Hash) bool) ([]plumbing.Hash, int64, error) {
+	return d.rw.PruneObjects(ctx, largeSize, keep)
 }
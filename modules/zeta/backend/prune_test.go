@@ -0,0 +1,91 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+func TestPruneObjects(t *testing.T) {
+	root := t.TempDir()
+	fo := newFileStorer(root, filepath.Join(root, "incoming"), "zstd")
+	oids := make([]plumbing.Hash, 0, 3)
+	for _, s := range []string{"small", "large object one", "large object two"} {
+		h := plumbing.NewHasher()
+		_, _ = h.Write([]byte(s))
+		oid := h.Sum()
+		p := fo.path(oid)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+		oids = append(oids, oid)
+	}
+	pruned, size, err := fo.PruneObjects(t.Context(), int64(len("large object one")), func(oid plumbing.Hash) bool {
+		return oid == oids[2]
+	})
+	if err != nil {
+		t.Fatalf("prune objects error: %v", err)
+	}
+	if !slices.Equal(pruned, []plumbing.Hash{oids[1]}) || size != int64(len("large object one")) {
+		t.Fatalf("pruned %v size %d", pruned, size)
+	}
+	for i, oid := range oids {
+		_, err := os.Stat(fo.path(oid))
+		if exists := err == nil; exists != (i != 1) {
+			t.Errorf("object %s exists: %v", oid.String()[:8], exists)
+		}
+	}
+}
+
+func TestPruneSharedObjects(t *testing.T) {
+	root := t.TempDir()
+	fo := newFileStorer(filepath.Join(root, "blob"), filepath.Join(root, "incoming"), "zstd")
+	oids := make([]plumbing.Hash, 0, 3)
+	old := time.Now().Add(-time.Hour)
+	for i, s := range []string{"referenced", "unreferenced", "recent"} {
+		h := plumbing.NewHasher()
+		_, _ = h.Write([]byte(s))
+		oid := h.Sum()
+		p := fo.path(oid)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if i != 2 {
+			if err := os.Chtimes(p, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+		oids = append(oids, oid)
+	}
+	keep := func(oid plumbing.Hash) bool {
+		return oid == oids[0]
+	}
+	expire := time.Now().Add(-time.Minute)
+	pruned, _, err := PruneSharedObjects(t.Context(), root, expire, true, keep)
+	if err != nil || !slices.Equal(pruned, []plumbing.Hash{oids[1]}) {
+		t.Fatalf("dry run pruned %v: %v", pruned, err)
+	}
+	if _, err := os.Stat(fo.path(oids[1])); err != nil {
+		t.Fatalf("dry run removed object: %v", err)
+	}
+	pruned, size, err := PruneSharedObjects(t.Context(), root, expire, false, keep)
+	if err != nil || !slices.Equal(pruned, []plumbing.Hash{oids[1]}) || size != int64(len("unreferenced")) {
+		t.Fatalf("pruned %v size %d: %v", pruned, size, err)
+	}
+	for i, oid := range oids {
+		_, err := os.Stat(fo.path(oid))
+		if exists := err == nil; exists != (i != 1) {
+			t.Errorf("object %s exists: %v", oid.String()[:8], exists)
+		}
+	}
+}
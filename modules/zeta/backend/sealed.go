@@ -0,0 +1,477 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/backend/pack"
+	"github.com/antgroup/hugescm/modules/zeta/backend/storage"
+)
+
+// Sealed object format, objects are encrypted with AES-256-GCM in 64K chunks:
+//
+//	4 byte magic
+//	8 byte key id
+//	32 byte random salt
+//	N chunks: ciphertext + 16 byte tag
+//
+// Every object is encrypted with its own key, derived from the configured key and the salt with HKDF-SHA256, so the
+// number of objects sealed with one configured key is not bounded by nonce collisions. The nonce of a chunk is 7 zero
+// bytes + 4 byte counter + 1 byte last chunk flag, the header is the additional data of every chunk, so reordered,
+// truncated or extended objects fail to decrypt. Every chunk except the last one is full, the plaintext size is derived
+// from the sealed size.
+const (
+	SealedKeySize    = 32
+	sealedHeaderSize = 44
+	sealedChunkSize  = 64 * 1024
+	sealedTagSize    = 16
+	sealedKeyIDSize  = 8
+	sealedSaltSize   = 32
+)
+
+var (
+	SEALED_MAGIC = [4]byte{'Z', 'S', 0x00, 0x02}
+)
+
+type sealedKeyID [sealedKeyIDSize]byte
+
+func (id sealedKeyID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// ErrSealedObject: the object is encrypted, but no key is configured or the key is unknown.
+type ErrSealedObject struct {
+	keyID sealedKeyID
+	known bool
+}
+
+func (e *ErrSealedObject) Error() string {
+	if e.known {
+		return fmt.Sprintf("object is encrypted with key %s, but the key is not available", e.keyID)
+	}
+	return "object is encrypted, but object encryption is not enabled"
+}
+
+func IsErrSealedObject(err error) bool {
+	var e *ErrSealedObject
+	return errors.As(err, &e)
+}
+
+// Sealer encrypts objects at rest. The first key is used to encrypt objects, all keys can decrypt objects, so
+// objects sealed with a retired key stay readable until gc re-encrypts them.
+type Sealer struct {
+	current sealedKeyID
+	keys    map[sealedKeyID][]byte
+}
+
+func sealedKeyIDOf(key []byte) (id sealedKeyID) {
+	h := plumbing.NewHasher()
+	_, _ = h.Write(key)
+	sum := h.Sum()
+	copy(id[:], sum[:])
+	return
+}
+
+// NewSealer creates a sealer, keys must be 32 bytes (AES-256), the first key is the current key.
+func NewSealer(keys ...[]byte) (*Sealer, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no object encryption key")
+	}
+	s := &Sealer{keys: make(map[sealedKeyID][]byte, len(keys))}
+	for i, key := range keys {
+		if len(key) != SealedKeySize {
+			return nil, fmt.Errorf("bad object encryption key size %d, expected %d", len(key), SealedKeySize)
+		}
+		id := sealedKeyIDOf(key)
+		if i == 0 {
+			s.current = id
+		}
+		s.keys[id] = bytes.Clone(key)
+	}
+	return s, nil
+}
+
+// objectAEAD returns the cipher of the object, the object key is derived from the key and the salt in the header.
+func objectAEAD(key []byte, header []byte) (cipher.AEAD, error) {
+	objectKey, err := hkdf.Key(sha256.New, key, header[12:12+sealedSaltSize], "zeta sealed object", SealedKeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(objectKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// KeyID returns the id of the current key.
+func (s *Sealer) KeyID() string {
+	return s.current.String()
+}
+
+func isSealed(header []byte) bool {
+	return len(header) >= sealedHeaderSize && bytes.Equal(header[:4], SEALED_MAGIC[:])
+}
+
+func sealedNonce(counter uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce[7:], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// sealedSize returns the size of the sealed object.
+func sealedSize(size int64) int64 {
+	chunks := (size + sealedChunkSize - 1) / sealedChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return sealedHeaderSize + size + chunks*sealedTagSize
+}
+
+// openedSize returns the plaintext size of the sealed object.
+func openedSize(size int64) int64 {
+	size -= sealedHeaderSize
+	chunks := (size + sealedChunkSize + sealedTagSize - 1) / (sealedChunkSize + sealedTagSize)
+	if chunks == 0 {
+		chunks = 1
+	}
+	return size - chunks*sealedTagSize
+}
+
+type sealWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	counter uint32
+}
+
+// newWriter returns a writer sealing the object, Close must be called to write the last chunk.
+func (s *Sealer) newWriter(w io.Writer) (*sealWriter, error) {
+	header := make([]byte, sealedHeaderSize)
+	copy(header, SEALED_MAGIC[:])
+	copy(header[4:12], s.current[:])
+	if _, err := rand.Read(header[12:]); err != nil {
+		return nil, err
+	}
+	aead, err := objectAEAD(s.keys[s.current], header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &sealWriter{w: w, aead: aead, header: header, buf: make([]byte, 0, sealedChunkSize+sealedTagSize)}, nil
+}
+
+func (sw *sealWriter) seal(last bool) error {
+	if sw.counter == ^uint32(0) {
+		return errors.New("object too large to seal")
+	}
+	out := sw.aead.Seal(sw.buf[:0], sealedNonce(sw.counter, last), sw.buf, sw.header)
+	sw.counter++
+	if _, err := sw.w.Write(out); err != nil {
+		return err
+	}
+	sw.buf = sw.buf[:0]
+	return nil
+}
+
+func (sw *sealWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) != 0 {
+		// a full chunk is sealed only when more data follows, the last chunk is sealed by Close
+		if len(sw.buf) == sealedChunkSize {
+			if err := sw.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := min(sealedChunkSize-len(sw.buf), len(p))
+		sw.buf = append(sw.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (sw *sealWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{sw}, r)
+}
+
+func (sw *sealWriter) Close() error {
+	return sw.seal(true)
+}
+
+type openReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	chunk   []byte
+	counter uint32
+	done    bool
+}
+
+// open returns the plaintext reader of the sealed object, header is the first sealedHeaderSize bytes of the object.
+func (s *Sealer) open(header []byte, r io.Reader) (io.Reader, error) {
+	var id sealedKeyID
+	copy(id[:], header[4:12])
+	if s == nil {
+		return nil, &ErrSealedObject{keyID: id}
+	}
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, &ErrSealedObject{keyID: id, known: true}
+	}
+	aead, err := objectAEAD(key, header)
+	if err != nil {
+		return nil, err
+	}
+	return &openReader{
+		r:      bufio.NewReaderSize(r, sealedChunkSize+sealedTagSize+1),
+		aead:   aead,
+		header: bytes.Clone(header[:sealedHeaderSize]),
+		buf:    make([]byte, sealedChunkSize+sealedTagSize),
+	}, nil
+}
+
+func (or *openReader) next() error {
+	n, err := io.ReadFull(or.r, or.buf)
+	last := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err := or.r.Peek(1); err == io.EOF {
+			last = true
+		}
+	}
+	chunk, err := or.aead.Open(or.buf[:0], sealedNonce(or.counter, last), or.buf[:n], or.header)
+	if err != nil {
+		return fmt.Errorf("decrypt object chunk %d: %w", or.counter, err)
+	}
+	or.counter++
+	or.chunk = chunk
+	or.done = last
+	return nil
+}
+
+func (or *openReader) Read(p []byte) (int, error) {
+	for len(or.chunk) == 0 {
+		if or.done {
+			return 0, io.EOF
+		}
+		if err := or.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, or.chunk)
+	or.chunk = or.chunk[n:]
+	return n, nil
+}
+
+func rawSize(rc io.ReadCloser) (int64, error) {
+	switch v := rc.(type) {
+	case *os.File:
+		si, err := v.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return si.Size(), nil
+	case *pack.SizeReader:
+		return v.Size(), nil
+	case SizeReader:
+		return v.Size(), nil
+	default:
+	}
+	return 0, errors.New("unable detect reader size")
+}
+
+type rawObject struct {
+	io.ReadCloser // contents after the header
+	header        []byte
+	size          int64
+}
+
+func (o *rawObject) sealed() bool {
+	return isSealed(o.header)
+}
+
+// raw returns the reader of the stored object, including the header.
+func (o *rawObject) raw() SizeReader {
+	return &sizeReader{Reader: io.MultiReader(bytes.NewReader(o.header), o.ReadCloser), closer: o.ReadCloser, size: o.size}
+}
+
+// open returns the reader of the decrypted object.
+func (o *rawObject) open(s *Sealer, oid plumbing.Hash) (SizeReader, error) {
+	r, err := s.open(o.header, o.ReadCloser)
+	if err != nil {
+		return nil, fmt.Errorf("open object %s: %w", oid, err)
+	}
+	return &sizeReader{Reader: r, closer: o.ReadCloser, size: openedSize(o.size)}, nil
+}
+
+func openRaw(ro storage.Storage, oid plumbing.Hash) (*rawObject, error) {
+	rc, err := ro.Open(oid)
+	if err != nil {
+		return nil, err
+	}
+	size, err := rawSize(rc)
+	if err != nil {
+		_ = rc.Close()
+		return nil, err
+	}
+	header := make([]byte, sealedHeaderSize)
+	n, err := io.ReadFull(rc, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		_ = rc.Close()
+		return nil, err
+	}
+	return &rawObject{ReadCloser: rc, header: header[:n], size: size}, nil
+}
+
+// openSized opens the object in the storage, sealed objects are decrypted.
+func (s *Sealer) openSized(ro storage.Storage, oid plumbing.Hash) (SizeReader, error) {
+	o, err := openRaw(ro, oid)
+	if err != nil {
+		return nil, err
+	}
+	if !o.sealed() {
+		return o.raw(), nil
+	}
+	sr, err := o.open(s, oid)
+	if err != nil {
+		_ = o.Close()
+		return nil, err
+	}
+	return sr, nil
+}
+
+// resealObject opens the object in the storage, plaintext objects and objects sealed with a retired key are sealed
+// with the current key. The second result reports whether the object was sealed again.
+func (s *Sealer) resealObject(ro storage.Storage, oid plumbing.Hash) (SizeReader, bool, error) {
+	o, err := openRaw(ro, oid)
+	if err != nil {
+		return nil, false, err
+	}
+	if o.sealed() && s.current == sealedKeyID(o.header[4:12]) {
+		return o.raw(), false, nil
+	}
+	plain := o.raw()
+	if o.sealed() {
+		if plain, err = o.open(s, oid); err != nil {
+			_ = o.Close()
+			return nil, false, err
+		}
+	}
+	resealed, err := s.reseal(plain)
+	if err != nil {
+		_ = o.Close()
+		return nil, false, err
+	}
+	return resealed, true, nil
+}
+
+type sealReader struct {
+	r    io.Reader
+	sw   *sealWriter
+	out  bytes.Buffer
+	done bool
+}
+
+// newReader returns a reader sealing the contents of r, the plaintext is never buffered beyond one chunk.
+func (s *Sealer) newReader(r io.Reader) (*sealReader, error) {
+	sr := &sealReader{r: r}
+	sw, err := s.newWriter(&sr.out)
+	if err != nil {
+		return nil, err
+	}
+	sr.sw = sw
+	return sr, nil
+}
+
+func (sr *sealReader) fill() error {
+	// a full chunk is sealed by the next write, so at most one chunk is sealed per fill
+	if _, err := io.CopyN(sr.sw, sr.r, sealedChunkSize); err != io.EOF {
+		return err
+	}
+	sr.done = true
+	return sr.sw.Close()
+}
+
+func (sr *sealReader) Read(p []byte) (int, error) {
+	for sr.out.Len() == 0 {
+		if sr.done {
+			return 0, io.EOF
+		}
+		if err := sr.fill(); err != nil {
+			return 0, err
+		}
+	}
+	return sr.out.Read(p)
+}
+
+// reseal returns the object sealed with the current key.
+func (s *Sealer) reseal(sr SizeReader) (SizeReader, error) {
+	r, err := s.newReader(sr)
+	if err != nil {
+		return nil, err
+	}
+	return &sizeReader{Reader: r, closer: sr, size: sealedSize(sr.Size())}, nil
+}
+
+// sealedError returns ErrSealedObject when the object is sealed, it is called when an object cannot be decoded while
+// encryption is not enabled, since the storage is not wrapped in that case.
+func sealedError(ro storage.Storage, oid plumbing.Hash) error {
+	o, err := openRaw(ro, oid)
+	if err != nil {
+		return nil
+	}
+	defer o.Close() // nolint
+	if !o.sealed() {
+		return nil
+	}
+	return &ErrSealedObject{keyID: sealedKeyID(o.header[4:12])}
+}
+
+// wrap returns the storage decrypting sealed objects, without a sealer the storage is returned as is.
+func (s *Sealer) wrap(ro storage.Storage) storage.Storage {
+	if s == nil {
+		return ro
+	}
+	return &sealedStorage{Storage: ro, s: s}
+}
+
+// sealedStorage decrypts sealed objects on read, plaintext objects are returned as is.
+type sealedStorage struct {
+	storage.Storage
+	s *Sealer
+}
+
+func (ss *sealedStorage) Open(oid plumbing.Hash) (io.ReadCloser, error) {
+	sr, err := ss.s.openSized(ss.Storage, oid)
+	if err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
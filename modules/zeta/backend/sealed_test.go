@@ -0,0 +1,153 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+func newTestKey(t *testing.T) []byte {
+	key := make([]byte, SealedKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSealRoundTrip(t *testing.T) {
+	s, err := NewSealer(newTestKey(t))
+	if err != nil {
+		t.Fatalf("new sealer error: %v", err)
+	}
+	for _, size := range []int{0, 1, sealedChunkSize - 1, sealedChunkSize, sealedChunkSize + 1, 3*sealedChunkSize + 7} {
+		plain := make([]byte, size)
+		_, _ = rand.Read(plain)
+		var sealed bytes.Buffer
+		sw, err := s.newWriter(&sealed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sw.Write(plain); err != nil {
+			t.Fatal(err)
+		}
+		if err := sw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if int64(sealed.Len()) != sealedSize(int64(size)) || openedSize(int64(sealed.Len())) != int64(size) {
+			t.Fatalf("size %d: sealed size %d, expected %d", size, sealed.Len(), sealedSize(int64(size)))
+		}
+		sr, err := s.newReader(bytes.NewReader(plain))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pulled, err := io.ReadAll(sr)
+		if err != nil || len(pulled) != sealed.Len() {
+			t.Fatalf("size %d: sealed reader returns %d bytes: %v", size, len(pulled), err)
+		}
+		for _, b := range [][]byte{sealed.Bytes(), pulled} {
+			r, err := s.open(b, bytes.NewReader(b[sealedHeaderSize:]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil || !bytes.Equal(got, plain) {
+				t.Fatalf("size %d: decrypt error: %v", size, err)
+			}
+		}
+		if size < sealedChunkSize {
+			continue
+		}
+		// truncated at a chunk boundary
+		b := sealed.Bytes()[:sealedHeaderSize+sealedChunkSize+sealedTagSize]
+		r, _ := s.open(b, bytes.NewReader(b[sealedHeaderSize:]))
+		if _, err := io.ReadAll(r); err == nil && size > sealedChunkSize {
+			t.Fatalf("size %d: truncated object should fail", size)
+		}
+	}
+	other, _ := NewSealer(newTestKey(t))
+	var sealed bytes.Buffer
+	sw, _ := s.newWriter(&sealed)
+	_ = sw.Close()
+	// every object has its own salt, so the same plaintext is sealed with a different object key
+	var again bytes.Buffer
+	sw, _ = s.newWriter(&again)
+	_ = sw.Close()
+	if bytes.Equal(sealed.Bytes()[12:sealedHeaderSize], again.Bytes()[12:sealedHeaderSize]) || bytes.Equal(sealed.Bytes(), again.Bytes()) {
+		t.Fatalf("objects should be sealed with different salts")
+	}
+	if _, err := other.open(sealed.Bytes(), bytes.NewReader(nil)); !IsErrSealedObject(err) {
+		t.Fatalf("unknown key should fail, got %v", err)
+	}
+	if _, err := NewSealer([]byte("short")); err == nil {
+		t.Fatalf("short key should fail")
+	}
+}
+
+func TestSealedDatabase(t *testing.T) {
+	zetaDir := filepath.Join(t.TempDir(), ".zeta")
+	oldKey, newKey := newTestKey(t), newTestKey(t)
+	s, _ := NewSealer(oldKey)
+	d, err := NewDatabase(zetaDir, WithSealer(s))
+	if err != nil {
+		t.Fatalf("new database error: %v", err)
+	}
+	content := strings.Repeat("secret source code\n", 10000)
+	oid, err := d.HashTo(t.Context(), strings.NewReader(content), -1)
+	if err != nil {
+		t.Fatalf("hash to error: %v", err)
+	}
+	commit := &object.Commit{Tree: plumbing.EmptyTree, Message: "sealed commit\n"}
+	cid, err := d.WriteEncoded(commit)
+	if err != nil {
+		t.Fatalf("write encoded error: %v", err)
+	}
+	for _, p := range []string{Join(filepath.Join(zetaDir, "blob"), oid), Join(filepath.Join(zetaDir, "metadata"), cid)} {
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isSealed(raw) || bytes.Contains(raw, []byte("secret source code")) {
+			t.Fatalf("object %s is not sealed", p)
+		}
+	}
+	checkBlob := func(d *Database) {
+		t.Helper()
+		br, err := d.Blob(t.Context(), oid)
+		if err != nil {
+			t.Fatalf("open blob error: %v", err)
+		}
+		defer br.Close() // nolint
+		got, _ := io.ReadAll(br.Contents)
+		if string(got) != content {
+			t.Fatalf("blob content mismatch")
+		}
+		if _, err := d.Commit(t.Context(), cid); err != nil {
+			t.Fatalf("open commit error: %v", err)
+		}
+	}
+	checkBlob(d)
+	_ = d.Close()
+
+	plain, _ := NewDatabase(zetaDir)
+	if _, err := plain.Blob(t.Context(), oid); !IsErrSealedObject(err) {
+		t.Fatalf("reading sealed object without key should fail, got %v", err)
+	}
+	_ = plain.Close()
+
+	// rotate: objects sealed with the old key are sealed with the new key by gc, the blob stays loose
+	rotated, _ := NewSealer(newKey, oldKey)
+	if err := PackObjects(t.Context(), &PackOptions{ZetaDir: zetaDir, Sealer: rotated, Reseal: true, PackThreshold: 64}); err != nil {
+		t.Fatalf("pack objects error: %v", err)
+	}
+	onlyNew, _ := NewSealer(newKey)
+	d, _ = NewDatabase(zetaDir, WithSealer(onlyNew))
+	checkBlob(d)
+	_ = d.Close()
+}
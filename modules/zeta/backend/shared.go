@@ -0,0 +1,66 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+// PruneSharedObjects removes loose blobs of the sharing root modified before expire, objects for which keep returns
+// true are retained. Packed objects are never removed. When dryRun is true, the objects are reported only.
+func PruneSharedObjects(ctx context.Context, sharingRoot string, expire time.Time, dryRun bool, keep func(oid plumbing.Hash) bool) ([]plumbing.Hash, int64, error) {
+	oids := make([]plumbing.Hash, 0, 100)
+	var totalSize int64
+	err := filepath.WalkDir(filepath.Join(sharingRoot, "blob"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if ignoreDir[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		if !plumbing.ValidateHashHex(name) {
+			return nil
+		}
+		oid := plumbing.NewHash(name)
+		if keep != nil && keep(oid) {
+			return nil
+		}
+		si, err := d.Info()
+		if err != nil {
+			return err
+		}
+		// objects written recently may belong to a fetch or a commit in progress
+		if si.ModTime().After(expire) {
+			return nil
+		}
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+		}
+		oids = append(oids, oid)
+		totalSize += si.Size()
+		return nil
+	})
+	return oids, totalSize, err
+}
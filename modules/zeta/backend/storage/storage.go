@@ -32,7 +32,8 @@ type WritableStorage interface {
 	WriteEncoded(e object.Encoder) (oid plumbing.Hash, err error)
 	LooseObjects() ([]plumbing.Hash, error)
 	PruneObject(ctx context.Context, oid plumbing.Hash) error
-	PruneObjects(ctx context.Context, largeSize int64) ([]plumbing.Hash, int64, error)
+	PruneObjects(ctx context.Context, largeSize int64, keep func(oid plumbing.Hash) bool) ([]plumbing.Hash, int64, error)
+	EvictObjects(ctx context.Context, limit int64, keep func(oid plumbing.Hash) bool) ([]plumbing.Hash, int64, error)
 }
 
 // Storage implements an interface for reading, but not writing, objects in an
@@ -23,6 +23,7 @@ type Unpacker struct {
 	root           string
 	quarantineDir  string
 	selectedMethod CompressMethod
+	sealer         *Sealer
 }
 
 func (u *Unpacker) method(compressed bool) CompressMethod {
@@ -77,6 +78,18 @@ func (u *Unpacker) HashTo(r io.Reader, size int64, modification int64) (oid plum
 	return
 }
 
+// Write writes the encoded object to the pack, the object is sealed when encryption is enabled.
+func (u *Unpacker) Write(oid plumbing.Hash, size uint32, r io.Reader, modification int64) error {
+	if u.sealer == nil {
+		return u.Writer.Write(oid, size, r, modification)
+	}
+	sr, err := u.sealer.newReader(r)
+	if err != nil {
+		return err
+	}
+	return u.Writer.Write(oid, uint32(sealedSize(int64(size))), sr, modification)
+}
+
 func (u *Unpacker) WriteEncoded(e object.Encoder, squeeze bool, modification int64) (plumbing.Hash, error) {
 	buffer := streamio.GetBytesBuffer()
 	defer streamio.PutBytesBuffer(buffer)
@@ -134,7 +147,7 @@ func (d *Database) NewUnpackerEx(entries uint32, metadata bool, method CompressM
 		_ = os.RemoveAll(quarantineDir)
 		return nil, err
 	}
-	return &Unpacker{Writer: w, root: root, quarantineDir: quarantineDir, selectedMethod: method}, nil
+	return &Unpacker{Writer: w, root: root, quarantineDir: quarantineDir, selectedMethod: method, sealer: d.sealer}, nil
 }
 
 func (d *Database) NewUnpacker(entries uint32, metadata bool) (*Unpacker, error) {
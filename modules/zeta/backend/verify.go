@@ -0,0 +1,187 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/streamio"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+const (
+	// quarantineDir: corrupt loose objects are moved here, the next read no longer finds them
+	quarantineDir = "quarantine"
+	// verifiedPrefix: blobs read to the end with a matching hash are remembered in the LRU, later reads skip hashing
+	verifiedPrefix = "verified:"
+)
+
+// ErrCorruptObject: the object does not match its hash, or cannot be decompressed or decoded.
+type ErrCorruptObject struct {
+	OID  plumbing.Hash
+	Meta bool
+	Err  error
+}
+
+func (e *ErrCorruptObject) Error() string {
+	return fmt.Sprintf("object %s is corrupt: %v", e.OID, e.Err)
+}
+
+func (e *ErrCorruptObject) Unwrap() error {
+	return e.Err
+}
+
+func IsErrCorruptObject(err error) bool {
+	var e *ErrCorruptObject
+	return errors.As(err, &e)
+}
+
+// Healer is called after a corrupt object is quarantined, Heal downloads the object again. The corrupt object is
+// read again once Heal succeeds.
+type Healer interface {
+	Heal(ctx context.Context, e *ErrCorruptObject, quarantined bool) error
+}
+
+// WithHealer: corrupt objects are quarantined and downloaded again by h instead of failing the read.
+func WithHealer(h Healer) Option {
+	return func(d *Database) {
+		d.healer = h
+	}
+}
+
+// newCorruptObject: sealed objects without the key and missing objects are not corrupt.
+func newCorruptObject(oid plumbing.Hash, meta bool, err error) error {
+	if IsErrSealedObject(err) || plumbing.IsNoSuchObject(err) || errors.Is(err, context.Canceled) {
+		return err
+	}
+	return &ErrCorruptObject{OID: oid, Meta: meta, Err: err}
+}
+
+// decodeVerified decodes the metadata object and checks the hash of its contents.
+func (d *Database) decodeVerified(r io.Reader, oid plumbing.Hash) (any, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, newCorruptObject(oid, true, err)
+	}
+	if magic == SEALED_MAGIC {
+		// encryption is not enabled, the sealed object is not corrupt
+		var id sealedKeyID
+		_, _ = io.ReadFull(r, id[:])
+		return nil, &ErrSealedObject{keyID: id}
+	}
+	contents := io.MultiReader(bytes.NewReader(magic[:]), r)
+	if isZstdMagic(magic) {
+		zr, err := streamio.GetZstdReader(contents)
+		if err != nil {
+			return nil, newCorruptObject(oid, true, err)
+		}
+		defer streamio.PutZstdReader(zr)
+		contents = zr
+	}
+	h := plumbing.NewHasher()
+	tr := io.TeeReader(contents, h)
+	a, err := object.Decode(tr, oid, d.backend)
+	if err != nil {
+		return nil, newCorruptObject(oid, true, err)
+	}
+	if _, err := io.Copy(io.Discard, tr); err != nil {
+		return nil, newCorruptObject(oid, true, err)
+	}
+	if got := h.Sum(); got != oid {
+		return nil, newCorruptObject(oid, true, fmt.Errorf("hash mismatch, got %s", got))
+	}
+	return a, nil
+}
+
+// quarantine moves the loose object to the quarantine dir, packed objects stay in place, the loose object written by
+// the healer takes precedence over them.
+func (d *Database) quarantine(oid plumbing.Hash, meta bool) bool {
+	zetaDir, storer := d.root, d.rw
+	if meta {
+		storer = d.metaRW
+	} else if len(d.sharingRoot) != 0 {
+		zetaDir = d.sharingRoot
+	}
+	fo, ok := storer.(*fileStorer)
+	if !ok {
+		return false
+	}
+	p := fo.path(oid)
+	if _, err := os.Stat(p); err != nil {
+		return false
+	}
+	dest := filepath.Join(zetaDir, quarantineDir, oid.String())
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return false
+	}
+	return os.Rename(p, dest) == nil
+}
+
+// heal quarantines the corrupt object and asks the healer to download it again, it reports whether the object can
+// be read again.
+func (d *Database) heal(ctx context.Context, err error) bool {
+	var e *ErrCorruptObject
+	if !errors.As(err, &e) {
+		return false
+	}
+	if d.cache != nil {
+		d.metaLRU.Del(verifiedPrefix + e.OID.String())
+	}
+	quarantined := d.quarantine(e.OID, e.Meta)
+	if d.healer == nil {
+		return false
+	}
+	return d.healer.Heal(ctx, e, quarantined) == nil
+}
+
+func (d *Database) blobVerified(oid plumbing.Hash) bool {
+	if d.cache == nil {
+		return false
+	}
+	_, ok := d.metaLRU.Get(verifiedPrefix + oid.String())
+	return ok
+}
+
+// verifiedReader hashes the blob contents, a mismatch is reported at the end of the contents, the object is
+// quarantined and healed, so it is intact the next time it is read.
+type verifiedReader struct {
+	io.Reader
+	ctx  context.Context
+	d    *Database
+	oid  plumbing.Hash
+	h    plumbing.Hasher
+	done bool
+}
+
+func (r *verifiedReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	_, _ = r.h.Write(p[:n])
+	if r.done {
+		return n, err
+	}
+	switch {
+	case err == io.EOF:
+		r.done = true
+		if got := r.h.Sum(); got != r.oid {
+			err = &ErrCorruptObject{OID: r.oid, Err: fmt.Errorf("hash mismatch, got %s", got)}
+			r.d.heal(r.ctx, err)
+			return n, err
+		}
+		if r.d.cache != nil {
+			_ = r.d.metaLRU.Set(verifiedPrefix+r.oid.String(), true, 1)
+		}
+	case err != nil && !errors.Is(err, context.Canceled):
+		r.done = true
+		err = &ErrCorruptObject{OID: r.oid, Err: err}
+		r.d.heal(r.ctx, err)
+	}
+	return n, err
+}
@@ -0,0 +1,120 @@
+package backend
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+type rewriteHealer struct {
+	d      *Database
+	commit *object.Commit
+	blob   string
+	healed []plumbing.Hash
+}
+
+func (h *rewriteHealer) Heal(ctx context.Context, e *ErrCorruptObject, quarantined bool) error {
+	if !quarantined {
+		return e
+	}
+	h.healed = append(h.healed, e.OID)
+	if e.Meta {
+		_, err := h.d.WriteEncoded(h.commit)
+		return err
+	}
+	_, err := h.d.HashTo(ctx, strings.NewReader(h.blob), -1)
+	return err
+}
+
+// corrupt replaces the loose object of oid with the contents of the loose object of other.
+func corrupt(t *testing.T, root string, oid, other plumbing.Hash) {
+	t.Helper()
+	raw, err := os.ReadFile(Join(root, other))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Join(root, oid)
+	_ = os.Chmod(p, 0644)
+	if err := os.WriteFile(p, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCorruptObjectHeal(t *testing.T) {
+	zetaDir := filepath.Join(t.TempDir(), ".zeta")
+	d, err := NewDatabase(zetaDir)
+	if err != nil {
+		t.Fatalf("new database error: %v", err)
+	}
+	content := strings.Repeat("intact content\n", 100)
+	oid, err := d.HashTo(t.Context(), strings.NewReader(content), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := d.HashTo(t.Context(), strings.NewReader("other content\n"), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := &object.Commit{Tree: plumbing.EmptyTree, Message: "intact commit\n"}
+	cid, err := d.WriteEncoded(commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCid, err := d.WriteEncoded(&object.Commit{Tree: plumbing.EmptyTree, Message: "other commit\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = d.Close()
+	corrupt(t, filepath.Join(zetaDir, "metadata"), cid, otherCid)
+	corrupt(t, filepath.Join(zetaDir, "blob"), oid, other)
+
+	// without healer: the read fails, the corrupt object is quarantined
+	d, _ = NewDatabase(zetaDir)
+	if _, err := d.Commit(t.Context(), cid); !IsErrCorruptObject(err) {
+		t.Fatalf("corrupt commit should fail, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(zetaDir, quarantineDir, cid.String())); err != nil {
+		t.Fatalf("corrupt commit not quarantined: %v", err)
+	}
+	if _, err := d.Commit(t.Context(), cid); !plumbing.IsNoSuchObject(err) {
+		t.Fatalf("quarantined commit should be missing, got %v", err)
+	}
+	_ = d.Close()
+
+	// with healer: the object is written again and the read succeeds
+	h := &rewriteHealer{commit: commit, blob: content}
+	d, _ = NewDatabase(zetaDir, WithHealer(h), WithCache(NewCache(0)))
+	defer d.Close() // nolint
+	h.d = d
+	corrupt(t, filepath.Join(zetaDir, "metadata"), cid, otherCid)
+	cc, err := d.Commit(t.Context(), cid)
+	if err != nil || cc.Message != commit.Message {
+		t.Fatalf("healed commit error: %v", err)
+	}
+	br, err := d.Blob(t.Context(), oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(br.Contents); !IsErrCorruptObject(err) {
+		t.Fatalf("corrupt blob should fail at the end, got %v", err)
+	}
+	_ = br.Close()
+	br, err = d.Blob(t.Context(), oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(br.Contents)
+	_ = br.Close()
+	if err != nil || string(got) != content {
+		t.Fatalf("healed blob error: %v", err)
+	}
+	if len(h.healed) != 2 {
+		t.Fatalf("unexpected healed objects: %v", h.healed)
+	}
+}
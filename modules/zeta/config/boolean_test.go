@@ -130,3 +130,29 @@ func TestBooleanUnmarshalText(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeLogUnmarshal(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected MergeLog
+		wantErr  bool
+	}{
+		{true, DefaultMergeLog, false},
+		{false, 0, false},
+		{int64(5), 5, false},
+		{int64(-1), 0, false},
+		{"on", DefaultMergeLog, false},
+		{"12", 12, false},
+		{"many", 0, true},
+	}
+	for _, tt := range tests {
+		var m MergeLog
+		err := m.UnmarshalTOML(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("UnmarshalTOML(%v) error = %v", tt.input, err)
+		}
+		if err == nil && m != tt.expected {
+			t.Fatalf("UnmarshalTOML(%v) = %d, want %d", tt.input, m, tt.expected)
+		}
+	}
+}
@@ -6,6 +6,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"maps"
 
 	"github.com/antgroup/hugescm/modules/strengthen"
 )
@@ -60,12 +61,29 @@ type Core struct {
 	Remote              string      `toml:"remote,omitempty"`
 	Snapshot            bool        `toml:"snapshot,omitempty"`
 	SparseDirs          StringArray `toml:"sparse,omitempty"`
+	NarrowDirs          StringArray `toml:"narrow,omitempty"` // zeta checkout --narrow <dir>: nothing outside these dirs is fetched
 	HashALGO            string      `toml:"hash-algo,omitempty"`
 	CompressionALGO     string      `toml:"compression-algo,omitempty"`
 	Editor              string      `toml:"editor,omitempty"`
 	OptimizeStrategy    Strategy    `toml:"optimizeStrategy,omitempty"`   // zeta config core.optimizeStrategy eager OR ZETA_CORE_OPTIMIZE_STRATEGY="eager"
 	Accelerator         Accelerator `toml:"accelerator,omitempty"`        // zeta config core.accelerator dragonfly OR ZETA_CORE_ACCELERATOR="dragonfly"
 	ConcurrentTransfers int         `toml:"concurrenttransfers,omitzero"` // zeta config core.concurrenttransfers 8 OR ZETA_CORE_CONCURRENT_TRANSFERS=8
+	// EncryptObjects: encrypt objects stored under .zeta and core.sharingRoot, zeta config core.encryptObjects true OR ZETA_CORE_ENCRYPT_OBJECTS=true
+	EncryptObjects Boolean `toml:"encryptObjects,omitempty"`
+	// EncryptionKeyCommand: command printing the object encryption keys (eg: a KMS client), keys are kept in the credential storage when empty
+	EncryptionKeyCommand string `toml:"encryptionKeyCommand,omitempty"`
+	// IgnoreCompat: 'git' also reads .gitignore in directories without .zetaignore, zeta config core.ignoreCompat git OR ZETA_CORE_IGNORE_COMPAT=git
+	IgnoreCompat string `toml:"ignoreCompat,omitempty"`
+	// OrgTemplates: 'auto' downloads the organization-wide ignore and attribute templates of the server on checkout and fetch,
+	// they are layered beneath the repository files, zeta config core.orgTemplates auto OR ZETA_CORE_ORG_TEMPLATES=auto
+	OrgTemplates string `toml:"orgTemplates,omitempty"`
+	// FileMode: false ignores the executable bit of worktree files (FAT, SMB and other mounts without POSIX permissions), detected by zeta init/checkout
+	FileMode Boolean `toml:"filemode,omitempty"`
+	// Symlinks: false checks out symlinks as plain files containing the link target, detected by zeta init/checkout
+	Symlinks Boolean `toml:"symlinks,omitempty"`
+	// CacheSize: size budget of the loose blobs, the least recently used blobs fetched from the remote are evicted by
+	// zeta gc and after checkout, zeta config core.cacheSize 20G OR ZETA_CORE_CACHE_SIZE=20G
+	CacheSize Size `toml:"cacheSize,omitempty"`
 }
 
 func (c *Core) Overwrite(o *Core) {
@@ -84,10 +102,22 @@ func (c *Core) Overwrite(o *Core) {
 	}
 	c.CompressionALGO = overwrite(c.CompressionALGO, o.CompressionALGO)
 	c.Editor = overwrite(c.Editor, o.Editor)
+	c.EncryptObjects.Merge(&o.EncryptObjects)
+	c.EncryptionKeyCommand = overwrite(c.EncryptionKeyCommand, o.EncryptionKeyCommand)
+	c.IgnoreCompat = overwrite(c.IgnoreCompat, o.IgnoreCompat)
+	c.OrgTemplates = overwrite(c.OrgTemplates, o.OrgTemplates)
+	c.FileMode.Merge(&o.FileMode)
+	c.Symlinks.Merge(&o.Symlinks)
+	if o.CacheSize > 0 {
+		c.CacheSize = o.CacheSize
+	}
 	// merge sparse dirs
 	if len(o.SparseDirs) != 0 {
 		c.SparseDirs = o.SparseDirs
 	}
+	if len(o.NarrowDirs) != 0 {
+		c.NarrowDirs = o.NarrowDirs
+	}
 }
 
 // IsExtreme: Extreme cleanup strategy to delete large object snapshots in the repository. Typically used in AI scenarios, it is no longer necessary to save blobs when downloading models.
@@ -128,6 +158,7 @@ func (f Fragment) Size() int64 {
 type HTTP struct {
 	ExtraHeader StringArray `toml:"extraHeader,omitempty"`
 	SSLVerify   Boolean     `toml:"sslVerify,omitempty"`
+	Cache       Boolean     `toml:"cache,omitempty"` // cache references and metadata responses, revalidated with ETag
 }
 
 func (h *HTTP) Overwrite(o *HTTP) {
@@ -135,6 +166,7 @@ func (h *HTTP) Overwrite(o *HTTP) {
 		h.ExtraHeader = append(h.ExtraHeader, o.ExtraHeader...)
 	}
 	h.SSLVerify.Merge(&o.SSLVerify)
+	h.Cache.Merge(&o.Cache)
 }
 
 type SSH struct {
@@ -185,10 +217,36 @@ func (d *Diff) Overwrite(o *Diff) {
 
 type Merge struct {
 	ConflictStyle string `toml:"conflictStyle,omitempty"`
+	// Log: merged commits listed in merge messages, see MergeLog
+	Log MergeLog `toml:"log,omitempty"`
+	// MessageTemplate: text/template of merge messages, the default message is used when empty
+	MessageTemplate string `toml:"messageTemplate,omitempty"`
 }
 
 func (m *Merge) Overwrite(o *Merge) {
 	m.ConflictStyle = overwrite(m.ConflictStyle, o.ConflictStyle)
+	if o.Log != 0 {
+		m.Log = o.Log
+	}
+	m.MessageTemplate = overwrite(m.MessageTemplate, o.MessageTemplate)
+}
+
+// Branch: per branch settings, keys are '<branch>.<name>', eg: branch.main.description
+type Branch map[string]string
+
+func (b *Branch) Overwrite(o Branch) {
+	if len(o) == 0 {
+		return
+	}
+	if *b == nil {
+		*b = make(Branch)
+	}
+	maps.Copy(*b, o)
+}
+
+// Description returns branch.<name>.description.
+func (b Branch) Description(name string) string {
+	return b[name+".description"]
 }
 
 // Credential configures credential storage behavior.
@@ -256,6 +314,124 @@ func (c *Credential) Overwrite(o *Credential) {
 	c.StoragePath = overwrite(c.StoragePath, o.StoragePath)
 }
 
+// Mailmap configures identity canonicalization for log, shortlog and blame.
+type Mailmap struct {
+	// File is an additional mailmap file read after the worktree .mailmap.
+	File string `toml:"file,omitempty"`
+}
+
+func (m *Mailmap) Overwrite(o *Mailmap) {
+	m.File = overwrite(m.File, o.File)
+}
+
+// Help configures how mistyped commands are handled.
+type Help struct {
+	// Autocorrect: "0" or empty shows suggestions, a positive number runs the
+	// only suggestion after that many deciseconds, "immediate" runs it at once,
+	// "prompt" asks before running it and "never" disables suggestions.
+	Autocorrect string `toml:"autocorrect,omitempty"`
+}
+
+func (h *Help) Overwrite(o *Help) {
+	h.Autocorrect = overwrite(h.Autocorrect, o.Autocorrect)
+}
+
+// Commit configures the built-in commit message policies, see modules/commitmsg.
+type Commit struct {
+	// Policies: issue, conventional, subject-length and branch-footer.
+	Policies      StringArray `toml:"policies,omitempty"`
+	IssuePattern  string      `toml:"issuePattern,omitempty"`
+	SubjectLength int         `toml:"subjectLength,omitzero"`
+	// BranchPattern is matched against the current branch, its submatches expand Footer, eg: 'Issue: $1'
+	BranchPattern string `toml:"branchPattern,omitempty"`
+	Footer        string `toml:"footer,omitempty"`
+}
+
+func (c *Commit) Overwrite(o *Commit) {
+	if len(o.Policies) != 0 {
+		c.Policies = o.Policies
+	}
+	c.IssuePattern = overwrite(c.IssuePattern, o.IssuePattern)
+	if o.SubjectLength > 0 {
+		c.SubjectLength = o.SubjectLength
+	}
+	c.BranchPattern = overwrite(c.BranchPattern, o.BranchPattern)
+	c.Footer = overwrite(c.Footer, o.Footer)
+}
+
+// SelfUpdate configures zeta update-self, the toml section is [update].
+type SelfUpdate struct {
+	// Endpoint: release endpoint, manifest.json and its signature manifest.json.sig are fetched from it
+	Endpoint string `toml:"endpoint,omitempty"`
+	// PublicKeys: ed25519 public keys in authorized_keys format, the manifest must be signed by one of them
+	PublicKeys StringArray `toml:"publicKeys,omitempty"`
+}
+
+func (u *SelfUpdate) Overwrite(o *SelfUpdate) {
+	u.Endpoint = overwrite(u.Endpoint, o.Endpoint)
+	if len(o.PublicKeys) != 0 {
+		u.PublicKeys = o.PublicKeys
+	}
+}
+
+// Telemetry: opt-in, anonymous command timings and error categories are aggregated locally.
+type Telemetry struct {
+	Enabled Boolean `toml:"enabled,omitempty"`
+	// Endpoint: aggregates are uploaded to it once a day, nothing leaves the machine when it is empty
+	Endpoint string `toml:"endpoint,omitempty"`
+}
+
+func (t *Telemetry) Overwrite(o *Telemetry) {
+	t.Enabled.Merge(&o.Enabled)
+	t.Endpoint = overwrite(t.Endpoint, o.Endpoint)
+}
+
+// Metrics: opt-in statsd exporter, durations and transfer bytes of every command are sent when Statsd is set.
+type Metrics struct {
+	// Statsd: host:port of a statsd (DogStatsD tags) or OpenTelemetry collector statsd receiver
+	Statsd string `toml:"statsd,omitempty"`
+	// Prefix: prepended to metric names, defaults to 'zeta'
+	Prefix string `toml:"prefix,omitempty"`
+	// Tags: key:value tags added to every metric, for example the CI job or runner pool
+	Tags StringArray `toml:"tags,omitempty"`
+}
+
+func (m *Metrics) Overwrite(o *Metrics) {
+	m.Statsd = overwrite(m.Statsd, o.Statsd)
+	m.Prefix = overwrite(m.Prefix, o.Prefix)
+	if len(o.Tags) != 0 {
+		m.Tags = o.Tags
+	}
+}
+
+// Safe: only trusted in the system and global config, repository config cannot allow itself.
+type Safe struct {
+	// Directories: repositories owned by other users which are allowed to be opened, '*' allows all, 'dir/*' allows
+	// the repositories under dir, an empty value clears the directories listed before it
+	Directories StringArray `toml:"directory,omitempty"`
+}
+
+func (s *Safe) Overwrite(o *Safe) {
+	if len(o.Directories) > 0 {
+		s.Directories = append(s.Directories, o.Directories...)
+	}
+}
+
+// Status configures the branch information of zeta status.
+type Status struct {
+	// AheadBehind: count the commits between the branch and its upstream, defaults to true
+	AheadBehind Boolean `toml:"aheadBehind,omitempty"`
+	// AheadBehindLimit: commits walked before the counts are reported as approximate, eg: 1000+
+	AheadBehindLimit int `toml:"aheadBehindLimit,omitzero"`
+}
+
+func (s *Status) Overwrite(o *Status) {
+	s.AheadBehind.Merge(&o.AheadBehind)
+	if o.AheadBehindLimit > 0 {
+		s.AheadBehindLimit = o.AheadBehindLimit
+	}
+}
+
 type Config struct {
 	Core       Core       `toml:"core,omitempty"`
 	User       User       `toml:"user,omitempty"`
@@ -266,6 +442,15 @@ type Config struct {
 	Diff       Diff       `toml:"diff,omitempty"`
 	Merge      Merge      `toml:"merge,omitempty"`
 	Credential Credential `toml:"credential,omitempty"`
+	Mailmap    Mailmap    `toml:"mailmap,omitempty"`
+	Help       Help       `toml:"help,omitempty"`
+	Commit     Commit     `toml:"commit,omitempty"`
+	SelfUpdate SelfUpdate `toml:"update,omitempty"`
+	Telemetry  Telemetry  `toml:"telemetry,omitempty"`
+	Metrics    Metrics    `toml:"metrics,omitempty"`
+	Safe       Safe       `toml:"safe,omitempty"`
+	Branch     Branch     `toml:"branch,omitempty"`
+	Status     Status     `toml:"status,omitempty"`
 }
 
 // Overwrite: use local config overwrite config
@@ -279,4 +464,13 @@ func (c *Config) Overwrite(other *Config) {
 	c.Diff.Overwrite(&other.Diff)
 	c.Merge.Overwrite(&other.Merge)
 	c.Credential.Overwrite(&other.Credential)
+	c.Mailmap.Overwrite(&other.Mailmap)
+	c.Help.Overwrite(&other.Help)
+	c.Commit.Overwrite(&other.Commit)
+	c.SelfUpdate.Overwrite(&other.SelfUpdate)
+	c.Telemetry.Overwrite(&other.Telemetry)
+	c.Metrics.Overwrite(&other.Metrics)
+	c.Safe.Overwrite(&other.Safe)
+	c.Branch.Overwrite(other.Branch)
+	c.Status.Overwrite(&other.Status)
 }
@@ -8,6 +8,11 @@ import (
 	"strings"
 )
 
+const (
+	// BranchSection: keys are '<branch>.<name>', branch names may contain dots
+	BranchSection = "branch"
+)
+
 // Key represents a parsed configuration key with format "section.name".
 type Key struct {
 	Section string
@@ -15,7 +20,8 @@ type Key struct {
 }
 
 // ParseKey parses a configuration key string into a Key struct.
-// The key must be in the format "section.name".
+// The key must be in the format "section.name", names of the branch section
+// start with the branch name (e.g., "branch.main.description").
 // Returns ErrBadConfigKey for invalid formats.
 func ParseKey(s string) (Key, error) {
 	section, name, ok := strings.Cut(s, ".")
@@ -26,7 +32,7 @@ func ParseKey(s string) (Key, error) {
 		return Key{}, &ErrBadConfigKey{key: s}
 	}
 	// Check for nested dots (e.g., "a.b.c")
-	if strings.Contains(name, ".") {
+	if strings.Contains(name, ".") && section != BranchSection {
 		return Key{}, &ErrBadConfigKey{key: s}
 	}
 	return Key{Section: section, Name: name}, nil
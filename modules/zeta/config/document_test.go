@@ -48,6 +48,12 @@ func TestParseKey(t *testing.T) {
 			input:     "a.b.c",
 			wantError: true,
 		},
+		{
+			name:        "branch key with dotted branch name",
+			input:       "branch.release.1.description",
+			wantSection: "branch",
+			wantName:    "release.1.description",
+		},
 		{
 			name:      "empty string",
 			input:     "",
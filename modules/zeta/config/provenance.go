@@ -0,0 +1,260 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/antgroup/hugescm/modules/strengthen"
+)
+
+// Scopes of config values, from the lowest to the highest priority.
+const (
+	ScopeSystem  = "system"
+	ScopeGlobal  = "global"
+	ScopeLocal   = "local"
+	ScopeEnv     = "env"
+	ScopeCommand = "command"
+)
+
+// Entry: a config value and where it was read, origin is 'file:<path>', 'env:<NAME>' or 'command line:-X'.
+type Entry struct {
+	Key    string
+	Value  Value
+	Scope  string
+	Origin string
+}
+
+func (e *Entry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Key    string `json:"key"`
+		Value  any    `json:"value"`
+		Scope  string `json:"scope"`
+		Origin string `json:"origin"`
+	}{Key: e.Key, Value: e.Value.ToAny(), Scope: e.Scope, Origin: e.Origin})
+}
+
+// Prefix returns the scope and the origin columns shown before the value, eg: 'local\tfile:.zeta/zeta.toml\t'.
+func (e *Entry) Prefix(showScope, showOrigin bool) string {
+	var b strings.Builder
+	if showScope {
+		b.WriteString(e.Scope)
+		b.WriteByte('\t')
+	}
+	if showOrigin {
+		b.WriteString(e.Origin)
+		b.WriteByte('\t')
+	}
+	return b.String()
+}
+
+// FileEntries returns the values of the config file sorted by key, a missing file has no values.
+func FileEntries(scope, path string) ([]*Entry, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	doc, err := LoadDocumentFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*Entry, 0, 16)
+	for section, values := range doc {
+		for name, value := range values {
+			entries = append(entries, &Entry{Key: section + "." + name, Value: value, Scope: scope, Origin: "file:" + path})
+		}
+	}
+	slices.SortFunc(entries, func(a, b *Entry) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return entries, nil
+}
+
+// LoadEntries returns the values of the system, global and local (when zetaDir is not empty) config files, values
+// of a later file override the values of the former ones.
+func LoadEntries(zetaDir string) ([]*Entry, error) {
+	files := []struct {
+		scope, path string
+	}{
+		{ScopeSystem, configSystemPath()},
+		{ScopeGlobal, strengthen.ExpandPath("~/.zeta.toml")},
+	}
+	if len(zetaDir) != 0 {
+		files = append(files, struct{ scope, path string }{ScopeLocal, filepath.Join(zetaDir, "zeta.toml")})
+	}
+	var entries []*Entry
+	for _, f := range files {
+		fileEntries, err := FileEntries(f.scope, f.path)
+		if err != nil {
+			return nil, fmt.Errorf("load %s config %s: %w", f.scope, f.path, err)
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+var (
+	knownKeys = collectKnownKeys()
+)
+
+// collectKnownKeys collects the keys of the Config fields, lower case keys map to their canonical spelling.
+func collectKnownKeys() map[string]string {
+	keys := make(map[string]string)
+	ct := reflect.TypeFor[Config]()
+	for i := range ct.NumField() {
+		sf := ct.Field(i)
+		section, _, _ := strings.Cut(sf.Tag.Get("toml"), ",")
+		if sf.Type.Kind() != reflect.Struct {
+			continue
+		}
+		for j := range sf.Type.NumField() {
+			name, _, _ := strings.Cut(sf.Type.Field(j).Tag.Get("toml"), ",")
+			if len(name) != 0 && name != "-" {
+				keys[strings.ToLower(section+"."+name)] = section + "." + name
+			}
+		}
+	}
+	return keys
+}
+
+// KnownKey returns the canonical spelling of the key, keys are matched case-insensitively like the decoder does.
+// Keys of the branch section are free-form.
+func KnownKey(key string) (string, bool) {
+	if section, _, _ := strings.Cut(key, "."); strings.EqualFold(section, BranchSection) {
+		return key, true
+	}
+	canonical, ok := knownKeys[strings.ToLower(key)]
+	return canonical, ok
+}
+
+// SuggestKey returns the known key closest to the unknown key, empty when none is close enough.
+func SuggestKey(key string) string {
+	lower := strings.ToLower(key)
+	var suggestion string
+	best := max(2, utf8.RuneCountInString(lower)/4) + 1
+	for k, canonical := range knownKeys {
+		if d := strengthen.Levenshtein(lower, k); d < best || (d == best && canonical < suggestion) {
+			best, suggestion = d, canonical
+		}
+	}
+	return suggestion
+}
+
+// Audit finding kinds.
+const (
+	// FindingUnknown: the key is not a zeta setting, it is a typo or a removed setting and has no effect
+	FindingUnknown = "unknown"
+	// FindingDeprecated: the key is a renamed setting or the git spelling of a setting, it has no effect
+	FindingDeprecated = "deprecated"
+	// FindingInvalid: the value does not have the type of the setting, loading the config fails
+	FindingInvalid = "invalid"
+	// FindingIgnored: the setting is not read from this scope
+	FindingIgnored = "ignored"
+	// FindingOverridden: the setting is set to different values, only the value of the highest scope is used
+	FindingOverridden = "overridden"
+	// FindingSecret: a secret is stored in plain text
+	FindingSecret = "secret"
+)
+
+type Finding struct {
+	Kind  string `json:"kind"`
+	Entry *Entry `json:"entry"`
+	// Winner: the entry overriding Entry, FindingOverridden only
+	Winner *Entry `json:"winner,omitempty"`
+	// Suggestion: the known key close to an unknown key
+	Suggestion string `json:"suggestion,omitempty"`
+	// Replacement: the key replacing a deprecated key, FindingDeprecated only
+	Replacement string `json:"replacement,omitempty"`
+	Err         error  `json:"-"`
+}
+
+var (
+	// trustedOnlyKeys: read from the system and global config and the command line, never from the repository config
+	trustedOnlyKeys = map[string]bool{
+		"safe.directory": true,
+	}
+	// deprecatedKeys: renamed keys and the git and git-lfs spellings of settings, mapped to the key replacing them
+	deprecatedKeys = map[string]string{
+		"core.hashalgo":           "core.hash-algo",
+		"core.compressionalgo":    "core.compression-algo",
+		"fragment.enablecdc":      "fragment.enable_cdc",
+		"lfs.concurrenttransfers": "core.concurrenttransfers",
+	}
+	// secretKeys: better kept in the environment or the credential storage than in config files
+	secretKeys = map[string]bool{
+		"credential.encryptionkey": true,
+	}
+)
+
+func isFileScope(scope string) bool {
+	return scope == ScopeSystem || scope == ScopeGlobal || scope == ScopeLocal
+}
+
+// sameValue compares the text of the values, values of the environment and the command line are strings.
+func sameValue(a, b Value) bool {
+	return fmt.Sprint(a.ToAny()) == fmt.Sprint(b.ToAny())
+}
+
+// Audit checks the entries ordered from the lowest to the highest priority: unknown and deprecated keys, values of the
+// wrong type, settings ignored in their scope, scalar settings overridden by a different value and secrets in config
+// files.
+func Audit(entries []*Entry) []*Finding {
+	var findings []*Finding
+	winners := make(map[string]*Entry)
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		lower := strings.ToLower(e.Key)
+		canonical, known := KnownKey(e.Key)
+		if replacement, ok := deprecatedKeys[lower]; ok && !known {
+			findings = append(findings, &Finding{Kind: FindingDeprecated, Entry: e, Replacement: replacement})
+			continue
+		}
+		if !known {
+			findings = append(findings, &Finding{Kind: FindingUnknown, Entry: e, Suggestion: SuggestKey(e.Key)})
+			continue
+		}
+		if isFileScope(e.Scope) {
+			doc := make(Document)
+			if _, err := doc.Set(canonical, e.Value.ToAny()); err == nil {
+				if err := ValidateDocument(doc); err != nil {
+					findings = append(findings, &Finding{Kind: FindingInvalid, Entry: e, Err: err})
+					continue
+				}
+			}
+		}
+		if e.Scope == ScopeLocal && trustedOnlyKeys[lower] {
+			findings = append(findings, &Finding{Kind: FindingIgnored, Entry: e})
+			continue
+		}
+		if isFileScope(e.Scope) && secretKeys[lower] {
+			findings = append(findings, &Finding{Kind: FindingSecret, Entry: e})
+		}
+		// list values are merged or replaced as a whole depending on the setting, only scalars are compared
+		if !e.Value.isScalar() {
+			continue
+		}
+		if w, ok := winners[lower]; ok {
+			// eg: ZETA_AUTHOR_NAME and ZETA_COMMITTER_NAME set user.name of different signatures
+			if w.Scope == ScopeEnv && e.Scope == ScopeEnv {
+				continue
+			}
+			if !sameValue(w.Value, e.Value) {
+				findings = append(findings, &Finding{Kind: FindingOverridden, Entry: e, Winner: w})
+			}
+			continue
+		}
+		winners[lower] = e
+	}
+	slices.Reverse(findings)
+	return findings
+}
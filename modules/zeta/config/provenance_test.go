@@ -0,0 +1,109 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKnownKey(t *testing.T) {
+	tests := []struct {
+		key       string
+		canonical string
+		known     bool
+	}{
+		{"core.editor", "core.editor", true},
+		{"CORE.SHARINGROOT", "core.sharingRoot", true},
+		{"core.cachesize", "core.cacheSize", true},
+		{"branch.main.remote", "branch.main.remote", true},
+		{"core.editer", "", false},
+		{"nosuch.key", "", false},
+	}
+	for _, tt := range tests {
+		canonical, known := KnownKey(tt.key)
+		if known != tt.known || canonical != tt.canonical {
+			t.Errorf("KnownKey(%q) = %q, %v, want %q, %v", tt.key, canonical, known, tt.canonical, tt.known)
+		}
+	}
+}
+
+func TestSuggestKey(t *testing.T) {
+	if s := SuggestKey("core.editer"); s != "core.editor" {
+		t.Errorf("SuggestKey(core.editer) = %q", s)
+	}
+	if s := SuggestKey("completely.different"); s != "" {
+		t.Errorf("SuggestKey(completely.different) = %q", s)
+	}
+}
+
+func TestFileEntries(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "zeta.toml")
+	if err := os.WriteFile(name, []byte("[user]\nname = \"zeta\"\nemail = \"zeta@example.io\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := FileEntries(ScopeGlobal, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Key != "user.email" || entries[1].Key != "user.name" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+	if entries[1].Scope != ScopeGlobal || entries[1].Origin != "file:"+name {
+		t.Errorf("unexpected entry %v", entries[1])
+	}
+	if entries, err = FileEntries(ScopeLocal, filepath.Join(t.TempDir(), "missing.toml")); err != nil || entries != nil {
+		t.Errorf("missing file: %v %v", entries, err)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	entries := []*Entry{
+		{Key: "user.name", Value: NewStringValue("global"), Scope: ScopeGlobal, Origin: "file:global"},
+		{Key: "core.editer", Value: NewStringValue("vim"), Scope: ScopeGlobal, Origin: "file:global"},
+		{Key: "lfs.concurrentTransfers", Value: NewInt64Value(8), Scope: ScopeGlobal, Origin: "file:global"},
+		{Key: "credential.encryptionKey", Value: NewStringValue("secret"), Scope: ScopeGlobal, Origin: "file:global"},
+		{Key: "core.concurrenttransfers", Value: NewStringValue("many"), Scope: ScopeLocal, Origin: "file:local"},
+		{Key: "safe.directory", Value: NewStringSliceValue([]string{"/tmp"}), Scope: ScopeLocal, Origin: "file:local"},
+		{Key: "user.name", Value: NewStringValue("local"), Scope: ScopeLocal, Origin: "file:local"},
+		{Key: "user.email", Value: NewStringValue("zeta@example.io"), Scope: ScopeLocal, Origin: "file:local"},
+		{Key: "user.name", Value: NewStringValue("author"), Scope: ScopeEnv, Origin: "env:ZETA_AUTHOR_NAME"},
+		{Key: "user.name", Value: NewStringValue("committer"), Scope: ScopeEnv, Origin: "env:ZETA_COMMITTER_NAME"},
+		{Key: "user.email", Value: NewStringValue("zeta@example.io"), Scope: ScopeCommand, Origin: "command line:-X"},
+	}
+	want := []struct {
+		kind   string
+		origin string
+	}{
+		{FindingOverridden, "file:global"},
+		{FindingUnknown, "file:global"},
+		{FindingDeprecated, "file:global"},
+		{FindingSecret, "file:global"},
+		{FindingInvalid, "file:local"},
+		{FindingIgnored, "file:local"},
+		{FindingOverridden, "file:local"},
+	}
+	findings := Audit(entries)
+	if len(findings) != len(want) {
+		for _, f := range findings {
+			t.Logf("%s %s %s", f.Kind, f.Entry.Key, f.Entry.Origin)
+		}
+		t.Fatalf("got %d findings, want %d", len(findings), len(want))
+	}
+	for i, f := range findings {
+		if f.Kind != want[i].kind || f.Entry.Origin != want[i].origin {
+			t.Errorf("finding %d: got %s %s, want %s %s", i, f.Kind, f.Entry.Origin, want[i].kind, want[i].origin)
+		}
+	}
+	if findings[1].Suggestion != "core.editor" {
+		t.Errorf("suggestion %q", findings[1].Suggestion)
+	}
+	if findings[2].Replacement != "core.concurrenttransfers" || len(findings[2].Suggestion) != 0 {
+		t.Errorf("replacement %q suggestion %q", findings[2].Replacement, findings[2].Suggestion)
+	}
+	if w := findings[6].Winner; w == nil || w.Origin != "env:ZETA_COMMITTER_NAME" {
+		t.Errorf("winner %v", w)
+	}
+}
@@ -6,6 +6,7 @@ package config
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/antgroup/hugescm/modules/strengthen"
@@ -164,6 +165,51 @@ func (s *Size) UnmarshalText(text []byte) error {
 	return err
 }
 
+// MergeLog: number of merged commits listed in merge messages, true lists DefaultMergeLog commits, false disables.
+type MergeLog int64
+
+const (
+	DefaultMergeLog = 20
+)
+
+func (m *MergeLog) UnmarshalTOML(a any) error {
+	switch data := a.(type) {
+	case bool:
+		*m = 0
+		if data {
+			*m = DefaultMergeLog
+		}
+		return nil
+	case int64:
+		*m = MergeLog(max(data, 0))
+		return nil
+	case int:
+		*m = MergeLog(max(data, 0))
+		return nil
+	case string:
+		return m.UnmarshalText([]byte(data))
+	}
+	return fmt.Errorf("invalid merge log value: %T", a)
+}
+
+func (m *MergeLog) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	switch s {
+	case "true", "yes", "on":
+		*m = DefaultMergeLog
+		return nil
+	case "false", "no", "off", "":
+		*m = 0
+		return nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid merge log value: %q", string(text))
+	}
+	*m = MergeLog(max(n, 0))
+	return nil
+}
+
 type Accelerator string
 
 const (
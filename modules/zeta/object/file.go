@@ -91,6 +91,10 @@ func (f *File) UnifiedText(ctx context.Context, codecvt bool) (content string, e
 		// NO CONTENT DELETE OR NEWFILE
 		return "", nil
 	}
+	if f.Mode == filemode.Submodule {
+		// like git, the pointer change is shown as text, the commit lives in another repository
+		return "Subproject commit " + f.Hash.String() + "\n", nil
+	}
 	r, _, err := f.OriginReader(ctx)
 	if err != nil {
 		return "", err
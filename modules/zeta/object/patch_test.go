@@ -1,9 +1,15 @@
 package object
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
+
+	"github.com/antgroup/hugescm/modules/diferenco"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
 )
 
 func TestPathRenameCombine(t *testing.T) {
@@ -28,3 +34,27 @@ func TestPathRenameCombine(t *testing.T) {
 		fmt.Fprintf(os.Stderr, "%s => %s|%s\n", i.A, i.B, d)
 	}
 }
+
+func TestSubmodulePatch(t *testing.T) {
+	from := &File{Path: "vendor/lib", Mode: filemode.Submodule, Hash: plumbing.NewHash(strings.Repeat("a", 64))}
+	to := &File{Path: "vendor/lib", Mode: filemode.Submodule, Hash: plumbing.NewHash(strings.Repeat("b", 64))}
+	s1, err := from.UnifiedText(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := to.UnifiedText(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := diferenco.Unified(context.Background(), &diferenco.Options{From: from.asFile(), To: to.asFile(), S1: s1, S2: s2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.IsBinary || len(p.Hunks) != 1 {
+		t.Fatalf("unexpected submodule patch: %+v", p)
+	}
+	lines := p.Hunks[0].Lines
+	if len(lines) != 2 || lines[0].Kind != diferenco.Delete || lines[0].Content != s1 || lines[1].Kind != diferenco.Insert || lines[1].Content != s2 {
+		t.Fatalf("unexpected submodule patch lines: %+v", lines)
+	}
+}
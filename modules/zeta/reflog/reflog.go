@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/zeta/object"
@@ -82,6 +83,20 @@ func (o *Reflog) Push(oid plumbing.Hash, committer *object.Signature, message st
 	o.Entries = newEntries
 }
 
+// At returns the entry which set the value of the reference at the time, entries are ordered from the newest to the
+// oldest. When the time is older than the oldest entry, the oldest entry is returned and ok is false.
+func (o *Reflog) At(t time.Time) (e *Entry, ok bool) {
+	if o.Empty() {
+		return nil, false
+	}
+	for _, e := range o.Entries {
+		if !e.Committer.When.After(t) {
+			return e, true
+		}
+	}
+	return o.Entries[len(o.Entries)-1], false
+}
+
 type DB struct {
 	root string
 }
@@ -145,3 +145,35 @@ func TestReflogPush(t *testing.T) {
 	}, "PushE")
 	_ = d.serialize(os.Stderr, log.Entries)
 }
+
+func TestReflogAt(t *testing.T) {
+	m := `0000000000000000000000000000000000000000000000000000000000000000 7d93f7dad4160ce2a30e7083e1fbe189b68142bcefd029fdc376f892eedb250a LBW <dev@zeta.io> 1706772738 +0800	commit (initial): A
+7d93f7dad4160ce2a30e7083e1fbe189b68142bcefd029fdc376f892eedb250a 46ec16b743c9020366a11f9cb3ea61f1ec04ca6d588132eff4c5028a2a49a815 LBW <dev@zeta.io> 1706772760 +0800	commit: B
+46ec16b743c9020366a11f9cb3ea61f1ec04ca6d588132eff4c5028a2a49a815 c0869060ede3e208c464cac81fd78e6f31cecb572a3450b9a7dce4784c6dab5f LBW <dev@zeta.io> 1706773202 +0800	commit: C
+`
+	d := &DB{}
+	entries, err := d.parse(strings.NewReader(m))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	log := &Reflog{name: "refs/heads/master", Entries: entries}
+	tests := []struct {
+		at   int64
+		want string
+		ok   bool
+	}{
+		{1706773202, "c0869060ede3e208c464cac81fd78e6f31cecb572a3450b9a7dce4784c6dab5f", true},
+		{1706773000, "46ec16b743c9020366a11f9cb3ea61f1ec04ca6d588132eff4c5028a2a49a815", true},
+		{1706772738, "7d93f7dad4160ce2a30e7083e1fbe189b68142bcefd029fdc376f892eedb250a", true},
+		{1706770000, "7d93f7dad4160ce2a30e7083e1fbe189b68142bcefd029fdc376f892eedb250a", false},
+	}
+	for _, tt := range tests {
+		e, ok := log.At(time.Unix(tt.at, 0))
+		if e == nil || e.N.String() != tt.want || ok != tt.ok {
+			t.Errorf("At(%d) = %v %v, want %s %v", tt.at, e, ok, tt.want, tt.ok)
+		}
+	}
+	if e, ok := (&Reflog{}).At(time.Now()); e != nil || ok {
+		t.Errorf("empty reflog At = %v %v", e, ok)
+	}
+}
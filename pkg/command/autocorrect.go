@@ -0,0 +1,190 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/env"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/term"
+	"github.com/antgroup/hugescm/pkg/kong"
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+const (
+	autocorrectShow = iota
+	autocorrectNever
+	autocorrectImmediate
+	autocorrectPrompt
+	autocorrectDelay
+)
+
+// parseAutocorrect: https://git-scm.com/docs/git-config#Documentation/git-config.txt-helpautocorrect
+func parseAutocorrect(s string) (int, time.Duration) {
+	switch strings.ToLower(s) {
+	case "", "0", "false", "off", "no", "show":
+		return autocorrectShow, 0
+	case "1", "true", "on", "yes", "immediate":
+		return autocorrectImmediate, 0
+	case "never":
+		return autocorrectNever, 0
+	case "prompt":
+		return autocorrectPrompt, 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return autocorrectShow, 0
+	}
+	if n < 0 {
+		return autocorrectImmediate, 0
+	}
+	return autocorrectDelay, time.Duration(n) * 100 * time.Millisecond
+}
+
+// similarCommands returns the visible commands closest to name, best matches first.
+func similarCommands(node *kong.Node, name string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	for _, c := range node.Children {
+		if c.Hidden || c.Type != kong.CommandNode {
+			continue
+		}
+		best := -1
+		for _, n := range append([]string{c.Name}, c.Aliases...) {
+			d := strengthen.Levenshtein(n, name)
+			if d > 2 && (len(name) < 2 || !strings.HasPrefix(n, name)) {
+				continue
+			}
+			if best < 0 || d < best {
+				best = d
+			}
+		}
+		if best >= 0 {
+			candidates = append(candidates, candidate{name: c.Name, distance: best})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return cmp.Or(cmp.Compare(a.distance, b.distance), cmp.Compare(a.name, b.name))
+	})
+	names := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		// only keep the best matches
+		if c.distance != candidates[0].distance {
+			break
+		}
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// commandIndex returns the index of the subcommand in args, skipping global flags, and the --cwd value.
+func commandIndex(args []string) (int, string) {
+	var cwd string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-X" || a == "--cwd":
+			if i+1 < len(args) && a == "--cwd" {
+				cwd = args[i+1]
+			}
+			i++
+		case strings.HasPrefix(a, "--cwd="):
+			cwd = strings.TrimPrefix(a, "--cwd=")
+		case strings.HasPrefix(a, "-X"), a == "-V", a == "--verbose", a == "--debug":
+		case strings.HasPrefix(a, "-"):
+			// --, --help, --version and unknown flags are handled by kong
+			return -1, cwd
+		default:
+			return i, cwd
+		}
+	}
+	return -1, cwd
+}
+
+func autocorrectConfig(cwd string) string {
+	cfg, err := zeta.LoadConfig(cwd, nil)
+	if err != nil {
+		return ""
+	}
+	return cfg.Help.Autocorrect
+}
+
+func promptAutocorrect(name string) bool {
+	if !term.IsTerminal(os.Stdin.Fd()) || !env.ZETA_TERMINAL_PROMPT.SimpleAtob(true) {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, W("Run '%s' instead [y/N]? "), name)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
+
+// Autocorrect handles mistyped subcommands according to help.autocorrect: suggest the most similar
+// commands and exit, or rewrite args to run the only suggestion.
+func Autocorrect(app *kong.Kong, args []string) []string {
+	i, cwd := commandIndex(args)
+	if i < 0 {
+		return args
+	}
+	name := args[i]
+	for _, c := range app.Model.Children {
+		if c.Name == name || slices.Contains(c.Aliases, name) {
+			return args
+		}
+	}
+	mode, delay := parseAutocorrect(autocorrectConfig(cwd))
+	if mode == autocorrectNever {
+		return args
+	}
+	candidates := similarCommands(app.Model.Node, name)
+	if len(candidates) == 0 {
+		return args
+	}
+	if len(candidates) == 1 && mode != autocorrectShow {
+		fmt.Fprintf(os.Stderr, W("WARNING: You called a zeta command named '%s', which does not exist.\n"), name)
+		ok := true
+		switch mode {
+		case autocorrectImmediate:
+			fmt.Fprintf(os.Stderr, W("Continuing under the assumption that you meant '%s'.\n"), candidates[0])
+		case autocorrectDelay:
+			fmt.Fprintf(os.Stderr, W("Continuing in %0.1f seconds, assuming that you meant '%s'.\n"), delay.Seconds(), candidates[0])
+			time.Sleep(delay)
+		case autocorrectPrompt:
+			ok = promptAutocorrect(candidates[0])
+		}
+		if ok {
+			newArgs := slices.Clone(args)
+			newArgs[i] = candidates[0]
+			return newArgs
+		}
+	}
+	fmt.Fprintf(os.Stderr, W("zeta: '%s' is not a zeta command. See 'zeta --help'.\n"), name)
+	if len(candidates) == 1 {
+		fmt.Fprintf(os.Stderr, "\n%s\n", W("The most similar command is"))
+	} else {
+		fmt.Fprintf(os.Stderr, "\n%s\n", W("The most similar commands are"))
+	}
+	for _, c := range candidates {
+		fmt.Fprintf(os.Stderr, "\t%s\n", c)
+	}
+	app.Exit(1)
+	return args
+}
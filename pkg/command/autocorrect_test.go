@@ -0,0 +1,89 @@
+package command
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/pkg/kong"
+)
+
+func TestParseAutocorrect(t *testing.T) {
+	for _, c := range []struct {
+		value string
+		mode  int
+		delay time.Duration
+	}{
+		{"", autocorrectShow, 0},
+		{"0", autocorrectShow, 0},
+		{"false", autocorrectShow, 0},
+		{"show", autocorrectShow, 0},
+		{"1", autocorrectImmediate, 0},
+		{"true", autocorrectImmediate, 0},
+		{"Immediate", autocorrectImmediate, 0},
+		{"-1", autocorrectImmediate, 0},
+		{"never", autocorrectNever, 0},
+		{"prompt", autocorrectPrompt, 0},
+		{"2", autocorrectDelay, 200 * time.Millisecond},
+		{"15", autocorrectDelay, 1500 * time.Millisecond},
+		{"bad", autocorrectShow, 0},
+	} {
+		mode, delay := parseAutocorrect(c.value)
+		if mode != c.mode || delay != c.delay {
+			t.Fatalf("parseAutocorrect(%q) = %d, %v, expected %d, %v", c.value, mode, delay, c.mode, c.delay)
+		}
+	}
+}
+
+func TestSimilarCommands(t *testing.T) {
+	node := &kong.Node{}
+	for _, c := range []*kong.Node{
+		{Name: "status", Aliases: []string{"st"}},
+		{Name: "stash"},
+		{Name: "switch", Aliases: []string{"sw"}},
+		{Name: "commit", Aliases: []string{"ci"}},
+		{Name: "checkout", Aliases: []string{"co"}},
+		{Name: "cherry-pick"},
+		{Name: "internal-status", Hidden: true},
+	} {
+		c.Type = kong.CommandNode
+		node.Children = append(node.Children, c)
+	}
+	for _, c := range []struct {
+		name     string
+		expected []string
+	}{
+		{"stauts", []string{"status"}},
+		{"comit", []string{"commit"}},
+		{"swtich", []string{"switch"}},
+		{"stat", []string{"stash", "status"}},
+		{"cherry", []string{"cherry-pick"}},
+		{"ct", []string{"checkout", "commit", "status"}}, // aliases co, ci and st
+		{"internal-statu", nil},
+		{"unknown", nil},
+	} {
+		if got := similarCommands(node, c.name); !slices.Equal(got, c.expected) {
+			t.Fatalf("similarCommands(%q) = %v, expected %v", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestCommandIndex(t *testing.T) {
+	for _, c := range []struct {
+		args  []string
+		index int
+		cwd   string
+	}{
+		{[]string{"stauts"}, 0, ""},
+		{[]string{"--cwd", "/tmp/repo", "stauts"}, 2, "/tmp/repo"},
+		{[]string{"--cwd=/tmp/repo", "-V", "stauts"}, 2, "/tmp/repo"},
+		{[]string{"-X", "core.sharingRoot=/tmp", "stauts"}, 2, ""},
+		{[]string{"--help"}, -1, ""},
+		{nil, -1, ""},
+	} {
+		index, cwd := commandIndex(c.args)
+		if index != c.index || cwd != c.cwd {
+			t.Fatalf("commandIndex(%q) = %d, %q, expected %d, %q", c.args, index, cwd, c.index, c.cwd)
+		}
+	}
+}
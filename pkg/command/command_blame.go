@@ -0,0 +1,52 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type Blame struct {
+	Args    []string `arg:"" name:"args" help:"[<rev>] <file>"`
+	JSON    bool     `name:"json" short:"j" help:"Data will be returned in JSON format"`
+	Mailmap bool     `name:"mailmap" negatable:"" default:"true" help:"Use mailmap file to map author and committer names and email addresses to canonical ones"`
+}
+
+const (
+	blameSummaryFormat = `%szeta blame [<options>] [<rev>] [--] <file>`
+)
+
+func (c *Blame) Summary() string {
+	return fmt.Sprintf(blameSummaryFormat, W("Usage: "))
+}
+
+func (c *Blame) Run(ctx context.Context, g *Globals) error {
+	opts := &zeta.BlameOptions{
+		JSON:      c.JSON,
+		NoMailmap: !c.Mailmap,
+	}
+	switch len(c.Args) {
+	case 1:
+		opts.Path = cleanPath(c.Args[0])
+	case 2:
+		opts.Revision = c.Args[0]
+		opts.Path = cleanPath(c.Args[1])
+	default:
+		die("blame requires [<rev>] <file>")
+		return ErrArgRequired
+	}
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	return r.Blame(ctx, opts)
+}
@@ -0,0 +1,51 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type Cache struct {
+	Stats CacheStats `cmd:"stats" help:"Show the size budget, the usage and the hit/miss/eviction counts of the blob cache" default:"1"`
+	Clear ClearCache `cmd:"clear" help:"Evict all blobs that are not pinned, they are fetched again when needed"`
+}
+
+type CacheStats struct {
+	JSON bool `name:"json" short:"j" help:"Data will be returned in JSON format"`
+}
+
+func (c *CacheStats) Run(ctx context.Context, g *Globals) error {
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	return r.CacheStats(ctx, &zeta.CacheStatsOptions{JSON: c.JSON})
+}
+
+type ClearCache struct {
+	ResetStats bool `name:"reset-stats" help:"Also reset the hit/miss/eviction counts"`
+	Quiet      bool `name:"quiet" short:"q" help:"Operate quietly. Progress is not reported to the standard error stream"`
+}
+
+func (c *ClearCache) Run(ctx context.Context, g *Globals) error {
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+		Quiet:    c.Quiet,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	return r.ClearCache(ctx, &zeta.ClearCacheOptions{ResetStats: c.ResetStats})
+}
@@ -19,6 +19,7 @@ type Cat struct {
 	Direct   bool   `name:"direct" help:"View files directly"`
 	Limit    int64  `name:"limit" short:"L" help:"Omits blobs larger than n bytes or units. n may be zero. Supported units: KB, MB, GB, K, M, G" default:"-1" type:"size"`
 	Output   string `name:"output" help:"Output to a specific file instead of stdout" placeholder:"<file>"`
+	Save     bool   `name:"save" help:"Save objects resolved from the remote to the local repository"`
 }
 
 func (c *Cat) Run(ctx context.Context, g *Globals) error {
@@ -41,5 +42,6 @@ func (c *Cat) Run(ctx context.Context, g *Globals) error {
 		PrintJSON: c.JSON,
 		Verify:    c.Verify,
 		Output:    c.Output,
+		Save:      c.Save,
 	})
 }
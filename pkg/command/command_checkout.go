@@ -21,6 +21,7 @@ type Checkout struct {
 	Refname         string   `name:"refname" help:"Direct the new HEAD to the <name> ref's commit after checkout" placeholder:"<tag>"`
 	Commit          string   `name:"commit" help:"Direct the new HEAD to the <commit> branch after checkout" placeholder:"<commit>"`
 	Sparse          []string `name:"sparse" short:"s" help:"A subset of repository files, all files are checked out by default" placeholder:"<dir>"`
+	Narrow          []string `name:"narrow" help:"Only fetch trees and files under these dirs, the server filters out the rest" placeholder:"<dir>"`
 	Limit           int64    `name:"limit" short:"L" help:"Omits blobs larger than n bytes or units. n may be zero. Supported units: KB, MB, GB, K, M, G" default:"-1" type:"size"`
 	Batch           bool     `name:"batch" help:"Get and checkout files for each provided on stdin"`
 	Snapshot        bool     `name:"snapshot" help:"Checkout a non-editable snapshot"`
@@ -32,7 +33,7 @@ type Checkout struct {
 }
 
 const (
-	coSummaryFormat = `%szeta checkout (co) [--branch|--tag] [--commit] [--sparse] [--limit] <url> [<destination>]
+	coSummaryFormat = `%szeta checkout (co) [--branch|--tag] [--commit] [--sparse] [--narrow] [--limit] <url> [<destination>]
 %szeta checkout (co) <branch>
 %szeta checkout (co) [<branch>] -- <file>...
 %szeta checkout (co) --batch [<branch>]
@@ -66,6 +67,7 @@ func (c *Checkout) doRemote(ctx context.Context, g *Globals, remote, destination
 		Commit:      c.Commit,
 		Destination: destination,
 		SparseDirs:  c.Sparse,
+		NarrowDirs:  c.Narrow,
 		Snapshot:    c.Snapshot,
 		SizeLimit:   c.Limit,
 		Values:      g.Values,
@@ -20,6 +20,7 @@ type Commit struct {
 	AllowEmpty        bool     `name:"allow-empty" help:"Allow creating a commit with the exact same tree structure as its parent commit"`
 	AllowEmptyMessage bool     `name:"allow-empty-message" help:"Like --allow-empty this command is primarily for use by foreign SCM interface scripts"`
 	Amend             bool     `name:"amend" help:"Replace the tip of the current branch by creating a new commit"`
+	NoVerify          bool     `name:"no-verify" short:"n" help:"Bypass the commit message policies"`
 }
 
 func (c *Commit) Run(ctx context.Context, g *Globals) error {
@@ -40,6 +41,7 @@ func (c *Commit) Run(ctx context.Context, g *Globals) error {
 		Amend:             c.Amend,
 		Message:           c.Message,
 		File:              c.File,
+		NoVerify:          c.NoVerify,
 	}
 	oid, err := w.Commit(ctx, opts)
 	if err != nil {
@@ -66,7 +68,10 @@ func (c *Commit) Run(ctx context.Context, g *Globals) error {
 		} else if errors.Is(err, zeta.ErrNoChanges) {
 			fmt.Fprintln(os.Stderr, W("nothing to commit, working tree clean"))
 			return err
-		} else if errors.Is(err, zeta.ErrNothingToCommit) {
+		} else if errors.Is(err, zeta.ErrNothingToCommit) || errors.Is(err, zeta.ErrHasConflicts) || errors.Is(err, zeta.ErrAborting) || zeta.IsErrLocked(err) {
+			return err
+		} else if errors.Is(err, zeta.ErrCommitMessagePolicy) {
+			fmt.Fprintln(os.Stderr, W("Aborting commit, use --no-verify to bypass the commit message policies."))
 			return err
 		} else {
 			fmt.Fprintf(os.Stderr, "zeta commit error: %v\n", err)
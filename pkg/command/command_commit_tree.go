@@ -0,0 +1,54 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+// Create a commit from a tree and changes without touching the index or the worktree
+type CommitTree struct {
+	Tree      string   `arg:"" optional:"" name:"tree-ish" help:"Base tree of the commit, defaults to the tree of the first parent"`
+	Parents   []string `name:"parent" short:"p" sep:"none" help:"Parent commit of the new commit" placeholder:"<parent>"`
+	Message   []string `name:"message" short:"m" sep:"none" help:"Use the given message as the commit message. Concatenate multiple -m options as separate paragraphs" placeholder:"<message>"`
+	File      string   `name:"file" short:"F" help:"Take the commit message from the given file. Use - to read the message from the standard input" placeholder:"<file>"`
+	CacheInfo []string `name:"cacheinfo" sep:"none" help:"Add the object to the tree at the path, mode 0 removes the path" placeholder:"<mode>,<object>,<path>"`
+	Remove    []string `name:"remove" sep:"none" help:"Remove the path from the tree" placeholder:"<path>"`
+	IndexInfo bool     `name:"index-info" help:"Read '<mode> <object>\\t<path>' lines from the standard input, mode 0 removes the path"`
+	UpdateRef string   `name:"update-ref" help:"Update the reference to the new commit if it still points to the first parent" placeholder:"<refname>"`
+}
+
+const (
+	commitTreeSummaryFormat = `%szeta commit-tree [<tree-ish>] [-p <parent>]... [-m <message>]... [-F <file>]
+       [--cacheinfo <mode>,<object>,<path>]... [--remove <path>]... [--index-info] [--update-ref <refname>]`
+)
+
+func (c *CommitTree) Summary() string {
+	return fmt.Sprintf(commitTreeSummaryFormat, W("Usage: "))
+}
+
+func (c *CommitTree) Run(ctx context.Context, g *Globals) error {
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	return r.BuildCommit(ctx, &zeta.BuildCommitOptions{
+		Tree:      c.Tree,
+		Parents:   c.Parents,
+		Message:   c.Message,
+		File:      c.File,
+		CacheInfo: c.CacheInfo,
+		Remove:    c.Remove,
+		IndexInfo: c.IndexInfo,
+		UpdateRef: c.UpdateRef,
+	})
+}
@@ -12,59 +12,79 @@ import (
 )
 
 type Config struct {
-	Args   []string `arg:"" name:"args" optional:"" help:"Name and value, support: <name value> appears in pairs or <name=value ...>, eg: zeta config K1=V1 K2=V2"`
-	System bool     `name:"system" help:"Use system config file"`
-	Global bool     `name:"global" help:"Only read or write to global ~/.zeta.toml"`
-	Local  bool     `name:"local" help:"Only read or write to repository .zeta/zeta.toml, which is the default behavior when writing"`
-	Unset  bool     `name:"unset" short:"u" help:"Remove the line matching the key from config file"`
-	List   bool     `name:"list" short:"l" help:"List all variables set in config file, along with their values"`
-	Get    bool     `name:"get" help:"Get the value for a given Key"`
-	GetALL bool     `name:"get-all" help:"Get all values for a given Key"`
-	Add    bool     `name:"add" help:"Add a new variable: name value"`
-	JSON   bool     `name:"json" short:"j" help:"Data will be returned in JSON format"`
-	Z      bool     `short:"z" shortonly:"" help:"Terminate values with NUL byte"`
-	Type   string   `name:"type" short:"T" help:"zeta config will ensure that any input or output is valid under the given type constraint(s), support: bool, int, float, date" placeholder:"<type>"`
+	Args       []string `arg:"" name:"args" optional:"" help:"Name and value, support: <name value> appears in pairs or <name=value ...>, eg: zeta config K1=V1 K2=V2"`
+	System     bool     `name:"system" help:"Use system config file"`
+	Global     bool     `name:"global" help:"Only read or write to global ~/.zeta.toml"`
+	Local      bool     `name:"local" help:"Only read or write to repository .zeta/zeta.toml, which is the default behavior when writing"`
+	Unset      bool     `name:"unset" short:"u" help:"Remove the line matching the key from config file"`
+	List       bool     `name:"list" short:"l" help:"List all variables set in config file, along with their values"`
+	Get        bool     `name:"get" help:"Get the value for a given Key"`
+	GetALL     bool     `name:"get-all" help:"Get all values for a given Key"`
+	Add        bool     `name:"add" help:"Add a new variable: name value"`
+	JSON       bool     `name:"json" short:"j" help:"Data will be returned in JSON format"`
+	Z          bool     `short:"z" shortonly:"" help:"Terminate values with NUL byte"`
+	Type       string   `name:"type" short:"T" help:"zeta config will ensure that any input or output is valid under the given type constraint(s), support: bool, int, float, date" placeholder:"<type>"`
+	ShowScope  bool     `name:"show-scope" help:"Augment the output of all queried config options with the scope of that value (system, global, local, env, command)"`
+	ShowOrigin bool     `name:"show-origin" help:"Augment the output of all queried config options with the origin type (file, env, command line) and the actual origin"`
+	Audit      bool     `name:"audit" help:"Report unknown, deprecated, invalid, ignored and overridden settings and secrets stored in config files"`
 }
 
 func (c *Config) Run(ctx context.Context, g *Globals) error {
+	if c.Audit {
+		if len(c.Args) != 0 || c.List || c.Get || c.GetALL || c.Unset || c.Add {
+			die("--audit cannot be used with other actions")
+			return ErrFlagsIncompatible
+		}
+		return zeta.AuditConfig(&zeta.AuditConfigOptions{
+			JSON:   c.JSON,
+			CWD:    g.CWD,
+			Values: g.Values,
+		})
+	}
 	if c.List {
 		if len(c.Args) != 0 {
 			die("wrong number of arguments, should be 0")
 			return errors.New("wrong number of arguments, should be 0")
 		}
 		return zeta.ListConfig(&zeta.ListConfigOptions{
-			System: c.System,
-			Global: c.Global,
-			Local:  c.Local,
-			Z:      c.Z,
-			JSON:   c.JSON,
-			CWD:    g.CWD,
-			Values: g.Values,
+			System:     c.System,
+			Global:     c.Global,
+			Local:      c.Local,
+			Z:          c.Z,
+			JSON:       c.JSON,
+			ShowScope:  c.ShowScope,
+			ShowOrigin: c.ShowOrigin,
+			CWD:        g.CWD,
+			Values:     g.Values,
 		})
 	}
 	if c.Get {
 		return zeta.GetConfig(&zeta.GetConfigOptions{
-			System: c.System,
-			Global: c.Global,
-			Local:  c.Local,
-			Z:      c.Z,
-			JSON:   c.JSON,
-			Keys:   c.Args,
-			CWD:    g.CWD,
-			Values: g.Values,
+			System:     c.System,
+			Global:     c.Global,
+			Local:      c.Local,
+			Z:          c.Z,
+			JSON:       c.JSON,
+			ShowScope:  c.ShowScope,
+			ShowOrigin: c.ShowOrigin,
+			Keys:       c.Args,
+			CWD:        g.CWD,
+			Values:     g.Values,
 		})
 	}
 	if c.GetALL {
 		return zeta.GetConfig(&zeta.GetConfigOptions{
-			System: c.System,
-			Global: c.Global,
-			Local:  c.Local,
-			ALL:    true,
-			Z:      c.Z,
-			JSON:   c.JSON,
-			Keys:   c.Args,
-			CWD:    g.CWD,
-			Values: g.Values,
+			System:     c.System,
+			Global:     c.Global,
+			Local:      c.Local,
+			ALL:        true,
+			Z:          c.Z,
+			JSON:       c.JSON,
+			ShowScope:  c.ShowScope,
+			ShowOrigin: c.ShowOrigin,
+			Keys:       c.Args,
+			CWD:        g.CWD,
+			Values:     g.Values,
 		})
 	}
 	if c.Unset {
@@ -79,13 +99,15 @@ func (c *Config) Run(ctx context.Context, g *Globals) error {
 		kv := c.Args[0]
 		if strings.IndexByte(kv, '=') == -1 {
 			return zeta.GetConfig(&zeta.GetConfigOptions{
-				System: c.System,
-				Global: c.Global,
-				Local:  c.Local,
-				Z:      c.Z,
-				Keys:   c.Args,
-				CWD:    g.CWD,
-				Values: g.Values,
+				System:     c.System,
+				Global:     c.Global,
+				Local:      c.Local,
+				Z:          c.Z,
+				ShowScope:  c.ShowScope,
+				ShowOrigin: c.ShowOrigin,
+				Keys:       c.Args,
+				CWD:        g.CWD,
+				Values:     g.Values,
 			})
 		}
 	}
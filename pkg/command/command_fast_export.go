@@ -0,0 +1,50 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type FastExport struct {
+	Revisions    []string `arg:"" optional:"" name:"revision" help:"References to export, defaults to all branches and tags"`
+	ImportMarks  string   `name:"import-marks" help:"Before processing any input, load the marks specified in <file>; a missing file is treated as empty" placeholder:"<file>"`
+	ExportMarks  string   `name:"export-marks" help:"Dumps the internal marks table to <file> when complete" placeholder:"<file>"`
+	LFS          bool     `name:"lfs" help:"Emit Git LFS pointers for fragmented files instead of reassembling them"`
+	LFSThreshold int64    `name:"lfs-threshold" help:"With --lfs, also emit Git LFS pointers for blobs larger than n bytes or units. Supported units: KB, MB, GB, K, M, G" default:"-1" type:"size"`
+	LFSObjects   string   `name:"lfs-objects" help:"With --lfs, store the contents of LFS objects in <dir> using the Git LFS storage layout" placeholder:"<dir>"`
+	Output       string   `name:"output" help:"Output to a specific file instead of stdout" placeholder:"<file>"`
+}
+
+const (
+	fastExportSummaryFormat = `%szeta fast-export [<revision>...] [--export-marks=<file>] [--import-marks=<file>] [--lfs]`
+)
+
+func (c *FastExport) Summary() string {
+	return fmt.Sprintf(fastExportSummaryFormat, W("Usage: "))
+}
+
+func (c *FastExport) Run(ctx context.Context, g *Globals) error {
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	return r.FastExport(ctx, &zeta.FastExportOptions{
+		Revisions:    c.Revisions,
+		ImportMarks:  c.ImportMarks,
+		ExportMarks:  c.ExportMarks,
+		LFS:          c.LFS,
+		LFSThreshold: c.LFSThreshold,
+		LFSObjects:   c.LFSObjects,
+		Output:       c.Output,
+	})
+}
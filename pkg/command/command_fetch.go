@@ -44,6 +44,7 @@ func (c *Fetch) Run(ctx context.Context, g *Globals) error {
 		Unshallow:   c.Unshallow,
 		Limit:       c.Limit,
 		Tag:         c.Tag,
+		Force:       c.Force,
 		FetchAlways: true,
 	})
 	return err
@@ -12,8 +12,10 @@ import (
 )
 
 type GC struct {
-	Prune time.Duration `name:"prune" help:"Pruning objects older than specified date (default is 2 weeks ago, configurable with gc.pruneExpire)" type:"expire" default:"2.weeks.ago"`
-	Quiet bool          `name:"quiet" help:"Operate quietly. Progress is not reported to the standard error stream"`
+	Prune     time.Duration `name:"prune" help:"Pruning objects older than specified date (default is 2 weeks ago, configurable with gc.pruneExpire)" type:"expire" default:"2.weeks.ago"`
+	Quiet     bool          `name:"quiet" help:"Operate quietly. Progress is not reported to the standard error stream"`
+	Reencrypt bool          `name:"reencrypt" help:"Encrypt plaintext objects and re-encrypt objects with the current key (requires core.encryptObjects)"`
+	RotateKey bool          `name:"rotate-key" help:"Generate a new object encryption key and re-encrypt all objects with it"`
 }
 
 func (c *GC) Run(ctx context.Context, g *Globals) error {
@@ -28,5 +30,5 @@ func (c *GC) Run(ctx context.Context, g *Globals) error {
 		return err
 	}
 	defer r.Close() // nolint
-	return r.Gc(ctx, &zeta.GcOptions{Prune: c.Prune})
+	return r.Gc(ctx, &zeta.GcOptions{Prune: c.Prune, Reencrypt: c.Reencrypt, RotateKey: c.RotateKey})
 }
@@ -0,0 +1,147 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/antgroup/hugescm/pkg/kong"
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+// https://git-scm.com/docs/git-grep
+// Print lines matching a pattern
+
+// grepTermSeq: kong decodes the flags in command line order, the sequence keeps the order of -e, --and, --or and
+// --not across the flags.
+var grepTermSeq int
+
+type grepTerm struct {
+	seq  int
+	term zeta.GrepTerm
+}
+
+type grepPatterns []grepTerm
+
+func (p *grepPatterns) Decode(ctx *kong.DecodeContext) error {
+	var pattern string
+	if err := ctx.Scan.PopValueInto("pattern", &pattern); err != nil {
+		return err
+	}
+	grepTermSeq++
+	*p = append(*p, grepTerm{seq: grepTermSeq, term: zeta.GrepTerm{Kind: zeta.GrepTermPattern, Pattern: pattern}})
+	return nil
+}
+
+// grepOperators: --and, --or and --not, the operator is the name of the flag.
+type grepOperators []grepTerm
+
+func (o *grepOperators) Decode(ctx *kong.DecodeContext) error {
+	kind := zeta.GrepTermOr
+	switch ctx.Value.Name {
+	case "and":
+		kind = zeta.GrepTermAnd
+	case "not":
+		kind = zeta.GrepTermNot
+	}
+	grepTermSeq++
+	*o = append(*o, grepTerm{seq: grepTermSeq, term: zeta.GrepTerm{Kind: kind}})
+	return nil
+}
+
+func (o *grepOperators) IsBool() bool {
+	return true
+}
+
+type Grep struct {
+	Patterns        grepPatterns  `name:"regexp" short:"e" help:"Match <pattern>, patterns are combined with --or by default" placeholder:"<pattern>"`
+	And             grepOperators `name:"and" help:"Lines must match the patterns on both sides"`
+	Or              grepOperators `name:"or" help:"Lines may match either of the patterns"`
+	Not             grepOperators `name:"not" help:"Lines must not match the next pattern"`
+	Cached          bool          `name:"cached" help:"Search the files registered in the index instead of the worktree"`
+	FixedStrings    bool          `name:"fixed-strings" short:"F" help:"Interpret patterns as fixed strings, not regular expressions"`
+	IgnoreCase      bool          `name:"ignore-case" short:"i" help:"Ignore case differences between the patterns and the files"`
+	InvertMatch     bool          `name:"invert-match" help:"Select non-matching lines"`
+	Text            bool          `name:"text" short:"a" help:"Process binary files as if they were text"`
+	LineNumber      bool          `name:"line-number" short:"n" help:"Prefix the line number to matching lines"`
+	NameOnly        bool          `name:"files-with-matches" short:"l" help:"Show only the names of files that contain matches"`
+	Count           bool          `name:"count" short:"c" help:"Show the number of matching lines of each file"`
+	JSON            bool          `name:"json" short:"j" help:"Data will be returned in JSON format"`
+	Args            []string      `arg:"" optional:"" name:"args" help:"<pattern> when no -e is given, followed by the revisions to search"`
+	passthroughArgs []string      `kong:"-"`
+}
+
+const (
+	grepSummaryFormat = `%szeta grep [<options>] [-e] <pattern> [<revision>...] [[--] <path>...]`
+)
+
+func (c *Grep) Summary() string {
+	return fmt.Sprintf(grepSummaryFormat, W("Usage: "))
+}
+
+func (c *Grep) Passthrough(paths []string) {
+	c.passthroughArgs = append(c.passthroughArgs, paths...)
+}
+
+// terms returns the terms of the expression in command line order and the revisions.
+func (c *Grep) terms() ([]zeta.GrepTerm, []string) {
+	all := slices.Concat([]grepTerm(c.Patterns), []grepTerm(c.And), []grepTerm(c.Or), []grepTerm(c.Not))
+	slices.SortFunc(all, func(a, b grepTerm) int {
+		return a.seq - b.seq
+	})
+	terms := make([]zeta.GrepTerm, 0, len(all)+1)
+	for _, t := range all {
+		terms = append(terms, t.term)
+	}
+	revisions := c.Args
+	if len(c.Patterns) == 0 && len(revisions) != 0 {
+		// like git grep, the first argument is the pattern when no -e is given
+		terms = append(terms, zeta.GrepTerm{Kind: zeta.GrepTermPattern, Pattern: revisions[0]})
+		revisions = revisions[1:]
+	}
+	return terms, revisions
+}
+
+func (c *Grep) Run(ctx context.Context, g *Globals) error {
+	terms, revisions := c.terms()
+	expr, err := zeta.CompileGrepExpr(terms, &zeta.GrepPatternOptions{FixedStrings: c.FixedStrings, IgnoreCase: c.IgnoreCase})
+	if err != nil {
+		diev("zeta grep: %v", err)
+		return err
+	}
+	if c.Cached && len(revisions) != 0 {
+		die("--cached cannot be used with revisions")
+		return ErrFlagsIncompatible
+	}
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	w := r.Worktree()
+	if err := w.DoGrep(ctx, &zeta.GrepCommandOptions{
+		Revisions:   revisions,
+		Cached:      c.Cached,
+		Paths:       slashPaths(c.passthroughArgs),
+		Expr:        expr,
+		InvertMatch: c.InvertMatch,
+		Text:        c.Text,
+		LineNumber:  c.LineNumber,
+		NameOnly:    c.NameOnly,
+		Count:       c.Count,
+		JSON:        c.JSON,
+	}); err != nil {
+		if !zeta.IsExitCode(err, 1) {
+			diev("zeta grep error: %v", err)
+		}
+		return err
+	}
+	return nil
+}
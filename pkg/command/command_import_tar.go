@@ -0,0 +1,52 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+// Create a commit from a tarball
+type ImportTar struct {
+	File            string `arg:"" name:"file" help:"Tarball to import, gzip compressed tarballs are detected, '-' reads stdin"`
+	Prefix          string `name:"prefix" help:"Replace the dir with the contents of the tarball instead of the whole tree" placeholder:"<dir>/"`
+	StripComponents int    `name:"strip-components" help:"Strip the number of leading components from file names" placeholder:"<n>"`
+	Branch          string `name:"branch" short:"b" help:"Commit to the branch instead of the current branch" placeholder:"<branch>"`
+	Message         string `name:"message" short:"m" help:"Use the given message as the commit message" placeholder:"<message>"`
+}
+
+const (
+	importTarSummaryFormat = `%szeta import-tar <file> [--prefix=<dir>/] [--strip-components=<n>] [-b <branch>] [-m <message>]`
+)
+
+func (c *ImportTar) Summary() string {
+	return fmt.Sprintf(importTarSummaryFormat, W("Usage: "))
+}
+
+func (c *ImportTar) Run(ctx context.Context, g *Globals) error {
+	if c.StripComponents < 0 {
+		die("--strip-components must not be negative")
+		return ErrArgRequired
+	}
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	w := r.Worktree()
+	return w.ImportTar(ctx, &zeta.ImportTarOptions{
+		File:            c.File,
+		Prefix:          c.Prefix,
+		StripComponents: c.StripComponents,
+		Branch:          c.Branch,
+		Message:         c.Message,
+	})
+}
@@ -35,6 +35,7 @@ func (c *Init) Run(ctx context.Context, g *Globals) error {
 		Branch:    c.Branch,
 		Worktree:  c.Directory,
 		MustEmpty: false,
+		Values:    g.Values,
 		Verbose:   g.Verbose})
 	if err != nil {
 		return err
@@ -6,7 +6,9 @@ package command
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/pkg/zeta"
 )
 
@@ -31,6 +33,9 @@ type Log struct {
 	FirstParent     bool     `name:"first-parent" help:"Follow only the first parent commit upon seeing a merge commit"`
 	JSON            bool     `name:"json" short:"j" help:"Data will be returned in JSON format"`
 	Limit           int      `name:"limit" short:"L" help:"Limit number of commits in JSON output (-1 or 0 means unlimited)" default:"-1"`
+	Mailmap         bool     `name:"mailmap" negatable:"" default:"true" help:"Use mailmap file to map author and committer names and email addresses to canonical ones"`
+	Since           string   `name:"since" aliases:"after" help:"Show commits more recent than a specific date, eg: 2024-05-01, yesterday, 2.weeks.ago" placeholder:"<date>"`
+	Until           string   `name:"until" aliases:"before" help:"Show commits older than a specific date" placeholder:"<date>"`
 	paths           []string `kong:"-"`
 }
 
@@ -47,6 +52,24 @@ func (c *Log) Passthrough(paths []string) {
 }
 
 func (c *Log) Run(ctx context.Context, g *Globals) error {
+	var since, until *time.Time
+	now := time.Now()
+	if len(c.Since) != 0 {
+		t, err := strengthen.ParseDate(c.Since, now)
+		if err != nil {
+			diev("invalid --since: %v", err)
+			return err
+		}
+		since = &t
+	}
+	if len(c.Until) != 0 {
+		t, err := strengthen.ParseDate(c.Until, now)
+		if err != nil {
+			diev("invalid --until: %v", err)
+			return err
+		}
+		until = &t
+	}
 	r, err := zeta.Open(ctx, &zeta.OpenOptions{
 		Worktree: g.CWD,
 		Values:   g.Values,
@@ -65,6 +88,9 @@ func (c *Log) Run(ctx context.Context, g *Globals) error {
 		Reverse:              c.Reverse,
 		FormatJSON:           c.JSON,
 		JSONLimit:            c.Limit,
+		NoMailmap:            !c.Mailmap,
+		Since:                since,
+		Until:                until,
 	}
 	switch {
 	case c.DateOrder || c.AuthorDateOrder:
@@ -10,14 +10,16 @@ import (
 )
 
 type LsFiles struct {
-	Cached   bool     `name:"cached" short:"c" help:"Show cached files in the output (default)"`
-	Deleted  bool     `name:"deleted" short:"d" help:"Show deleted files in the output"`
-	Modified bool     `name:"modified" short:"m" help:"Show modified files in the output"`
-	Others   bool     `name:"others" short:"o" help:"Show other files in the output"`
-	Stage    bool     `name:"stage" short:"s" help:"Show staged contents' object name in the output"`
-	Z        bool     `short:"z" shortonly:"" help:"Terminate entries with NUL byte"`
-	JSON     bool     `name:"json" short:"j" help:"Data will be returned in JSON format"`
-	Paths    []string `arg:"" name:"path" optional:"" help:"Given paths, show as match patterns; else, use root as sole argument"`
+	Cached          bool     `name:"cached" short:"c" help:"Show cached files in the output (default)"`
+	Deleted         bool     `name:"deleted" short:"d" help:"Show deleted files in the output"`
+	Modified        bool     `name:"modified" short:"m" help:"Show modified files in the output"`
+	Others          bool     `name:"others" short:"o" help:"Show other files in the output"`
+	Ignored         bool     `name:"ignored" short:"i" help:"Show only ignored files in the output, must be used with either -o or -c"`
+	ExcludeStandard bool     `name:"exclude-standard" help:"Add the standard zeta exclusions: .zetaignore"`
+	Stage           bool     `name:"stage" short:"s" help:"Show staged contents' object name in the output"`
+	Z               bool     `short:"z" shortonly:"" help:"Terminate entries with NUL byte"`
+	JSON            bool     `name:"json" short:"j" help:"Data will be returned in JSON format"`
+	Paths           []string `arg:"" name:"path" optional:"" help:"Given paths, show as match patterns; else, use root as sole argument"`
 }
 
 func (c *LsFiles) Run(ctx context.Context, g *Globals) error {
@@ -32,19 +34,25 @@ func (c *LsFiles) Run(ctx context.Context, g *Globals) error {
 	defer r.Close() // nolint
 	w := r.Worktree()
 	opts := &zeta.LsFilesOptions{
-		Z:     c.Z,
-		JSON:  c.JSON,
-		Paths: slashPaths(c.Paths),
+		ExcludeStandard: c.ExcludeStandard,
+		Z:               c.Z,
+		JSON:            c.JSON,
+		Paths:           slashPaths(c.Paths),
 	}
-	switch {
-	case c.Stage:
-		opts.Mode = zeta.ListFilesStage
-	case c.Deleted:
-		opts.Mode = zeta.ListFilesDeleted
-	case c.Modified:
-		opts.Mode = zeta.ListFilesModified
-	case c.Others:
-		opts.Mode = zeta.ListFilesOthers
+	for _, m := range []struct {
+		set  bool
+		mode zeta.ListFilesMode
+	}{
+		{c.Cached, zeta.ListFilesCached},
+		{c.Deleted, zeta.ListFilesDeleted},
+		{c.Modified, zeta.ListFilesModified},
+		{c.Others, zeta.ListFilesOthers},
+		{c.Ignored, zeta.ListFilesIgnored},
+		{c.Stage, zeta.ListFilesStage},
+	} {
+		if m.set {
+			opts.Mode |= m.mode
+		}
 	}
 	if err := w.LsFiles(ctx, opts); err != nil {
 		diev("zeta ls-files error: %v", err)
@@ -21,6 +21,10 @@ type Merge struct {
 	Message                 []string `name:"message" short:"m" help:"Merge commit message (for a non-fast-forward merge)" placeholder:"<message>"`
 	File                    string   `name:"file" short:"F" help:"Read message from file" placeholder:"<file>"`
 	Signoff                 bool     `name:"signoff" negatable:"" help:"Add a Signed-off-by trailer" default:"false"`
+	NoVerify                bool     `name:"no-verify" help:"Bypass the commit message policies"`
+	LabelOurs               string   `name:"label-ours" help:"Label of our side in conflict markers, defaults to HEAD" placeholder:"<label>"`
+	LabelTheirs             string   `name:"label-theirs" help:"Label of their side in conflict markers, defaults to the revision" placeholder:"<label>"`
+	LabelBase               string   `name:"label-base" help:"Label of the merge base in diff3 conflict markers" placeholder:"<label>"`
 	Abort                   bool     `name:"abort" help:"Abort a conflicting merge"`
 	Continue                bool     `name:"continue" help:"Continue a merge with resolved conflicts"`
 }
@@ -67,6 +71,10 @@ func (c *Merge) Run(ctx context.Context, g *Globals) error {
 		Textconv:                c.Textconv,
 		Abort:                   c.Abort,
 		Continue:                c.Continue,
+		NoVerify:                c.NoVerify,
+		LabelOurs:               c.LabelOurs,
+		LabelTheirs:             c.LabelTheirs,
+		LabelBase:               c.LabelBase,
 	}); err != nil {
 		return err
 	}
@@ -15,6 +15,8 @@ type Push struct {
 	PushOptions []string `name:"push-option" short:"o" help:"Option to transmit" placeholder:"<option>"`
 	Tag         bool     `name:"tag" short:"t" help:"Update remote tag reference"`
 	Force       bool     `name:"force" short:"f" help:"force updates"`
+	NoVerify    bool     `name:"no-verify" help:"Bypass the commit message policies"`
+	DryRun      bool     `name:"dry-run" short:"n" help:"Validate the push on the server and show what would be uploaded, without updating the remote"`
 }
 
 func (c *Push) Run(ctx context.Context, g *Globals) error {
@@ -36,6 +38,8 @@ func (c *Push) Run(ctx context.Context, g *Globals) error {
 		PushOptions: c.PushOptions,
 		Tag:         c.Tag,
 		Force:       c.Force,
+		NoVerify:    c.NoVerify,
+		DryRun:      c.DryRun,
 	}); err != nil {
 		return err
 	}
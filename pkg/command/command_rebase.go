@@ -5,15 +5,29 @@ package command
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/antgroup/hugescm/pkg/zeta"
 )
 
 type Rebase struct {
-	Args     []string `arg:"" help:"Upstream and branch to rebase (upstream branch to compare against and branch to rebase)"`
-	Onto     string   `name:"onto" help:"Rebase onto given branch" placeholder:"<revision>"`
-	Abort    bool     `name:"abort" help:"Abort and checkout the original branch"`
-	Continue bool     `name:"continue" help:"Continue"`
+	Args                      []string `arg:"" optional:"" help:"Upstream and branch to rebase (upstream branch to compare against and branch to rebase)"`
+	Onto                      string   `name:"onto" help:"Rebase onto given branch" placeholder:"<revision>"`
+	CommitterDateIsAuthorDate bool     `name:"committer-date-is-author-date" help:"Use the author date of the rebased commit as the committer date"`
+	RebaseMerges              bool     `name:"rebase-merges" short:"r" help:"Try to rebase merges instead of skipping them"`
+	Abort                     bool     `name:"abort" help:"Abort and checkout the original branch"`
+	Continue                  bool     `name:"continue" help:"Continue"`
+}
+
+const (
+	rebaseSummaryFormat = `%szeta rebase [<options>] [--onto <newbase>] <upstream> [<branch>]
+%szeta rebase --continue
+%szeta rebase --abort`
+)
+
+func (c *Rebase) Summary() string {
+	or := W("   or: ")
+	return fmt.Sprintf(rebaseSummaryFormat, W("Usage: "), or, or)
 }
 
 func (c *Rebase) Run(ctx context.Context, g *Globals) error {
@@ -38,10 +52,12 @@ func (c *Rebase) Run(ctx context.Context, g *Globals) error {
 	w := r.Worktree()
 
 	opts := &zeta.RebaseOptions{
-		Branch:   "HEAD",
-		Onto:     c.Onto,
-		Abort:    c.Abort,
-		Continue: c.Continue,
+		Branch:                    "HEAD",
+		Onto:                      c.Onto,
+		CommitterDateIsAuthorDate: c.CommitterDateIsAuthorDate,
+		RebaseMerges:              c.RebaseMerges,
+		Abort:                     c.Abort,
+		Continue:                  c.Continue,
 	}
 	if len(c.Args) > 0 {
 		opts.Upstream = c.Args[0]
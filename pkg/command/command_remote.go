@@ -14,8 +14,9 @@ import (
 )
 
 type Remote struct {
-	Show ShowRemote `cmd:"show" help:"Gives some information about the remote" default:"1"`
-	Set  SetRemote  `cmd:"set" help:"Set URL for the remote"`
+	Show             ShowRemote       `cmd:"show" help:"Gives some information about the remote" default:"1"`
+	Set              SetRemote        `cmd:"set" help:"Set URL for the remote"`
+	SetDefaultBranch SetDefaultBranch `cmd:"set-default-branch" help:"Change the default branch of the remote repository"`
 }
 
 type ShowRemote struct {
@@ -67,3 +68,21 @@ func (c *SetRemote) Run(ctx context.Context, g *Globals) error {
 	_, _ = fmt.Fprintf(os.Stdout, "remote: %s\n", newRemote)
 	return nil
 }
+
+// Change default branch of the remote
+type SetDefaultBranch struct {
+	Branch string `arg:"" name:"branch" help:"Branch to be the default branch (HEAD) of the remote repository"`
+}
+
+func (c *SetDefaultBranch) Run(ctx context.Context, g *Globals) error {
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	return r.SetRemoteDefaultBranch(ctx, c.Branch)
+}
@@ -0,0 +1,63 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+// https://github.com/newren/git-filter-repo
+// Rewrite the history of branches and tags
+type RewriteHistory struct {
+	RemovePaths      []string `name:"remove-path" help:"Remove the files and dirs matching the path from the history, support wildcards" placeholder:"<path>"`
+	StripBlobsBigger int64    `name:"strip-blobs-bigger-than" help:"Remove the files larger than n bytes or units from the history. Supported units: KB, MB, GB, K, M, G" default:"-1" type:"size" placeholder:"<size>"`
+	ReplaceBlobs     []string `name:"replace-blob" help:"Replace the blob with the contents of the file, remove the files of the blob when no file is given" placeholder:"<blob>[=<file>]"`
+	ReplaceMessage   string   `name:"replace-message" help:"Rewrite commit and tag messages with the 'old==>new' expressions of the file" placeholder:"<file>"`
+	Mailmap          string   `name:"mailmap" help:"Rewrite authors, committers and taggers with the mailmap file" placeholder:"<file>"`
+	PruneEmpty       bool     `name:"prune-empty" negatable:"" default:"true" help:"Drop the commits which become empty"`
+	DryRun           bool     `name:"dry-run" short:"n" help:"Write the commit map without updating the references"`
+	Push             bool     `name:"push" help:"Force push the rewritten references to the remote"`
+}
+
+const (
+	rewriteHistorySummaryFormat = `%szeta rewrite-history [--remove-path=<path>...] [--strip-blobs-bigger-than=<size>] [--replace-blob=<blob>[=<file>]...] [--replace-message=<file>] [--mailmap=<file>] [--push]`
+)
+
+func (c *RewriteHistory) Summary() string {
+	return fmt.Sprintf(rewriteHistorySummaryFormat, W("Usage: "))
+}
+
+func (c *RewriteHistory) Run(ctx context.Context, g *Globals) error {
+	if len(c.RemovePaths) == 0 && c.StripBlobsBigger <= 0 && len(c.ReplaceBlobs) == 0 && len(c.ReplaceMessage) == 0 && len(c.Mailmap) == 0 {
+		die("nothing to rewrite, specify paths, blobs, messages or a mailmap")
+		return ErrArgRequired
+	}
+	if c.DryRun && c.Push {
+		die("--dry-run and --push cannot be used together")
+		return ErrFlagsIncompatible
+	}
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	w := r.Worktree()
+	return w.RewriteHistory(ctx, &zeta.RewriteHistoryOptions{
+		RemovePaths:      slashPaths(c.RemovePaths),
+		StripBlobsBigger: c.StripBlobsBigger,
+		ReplaceBlobs:     c.ReplaceBlobs,
+		ReplaceMessage:   c.ReplaceMessage,
+		Mailmap:          c.Mailmap,
+		PruneEmpty:       c.PruneEmpty,
+		DryRun:           c.DryRun,
+		Push:             c.Push,
+	})
+}
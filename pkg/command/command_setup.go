@@ -0,0 +1,17 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type Setup struct{}
+
+// Ask for the common settings and write them to the global config
+func (c *Setup) Run(ctx context.Context, g *Globals) error {
+	return zeta.Setup(ctx, &zeta.SetupOptions{Verbose: g.Verbose})
+}
@@ -0,0 +1,34 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"time"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type SharedGC struct {
+	Root   string        `name:"root" help:"Sharing root to collect, default core.sharingRoot" placeholder:"<dir>"`
+	Prune  time.Duration `name:"prune" help:"Only remove objects older than specified date" type:"expire" default:"2.weeks.ago"`
+	DryRun bool          `name:"dry-run" short:"n" help:"Only report the objects that would be removed"`
+	Force  bool          `name:"force" short:"f" help:"Remove unused objects even if no clone is registered"`
+	Quiet  bool          `name:"quiet" help:"Operate quietly. Progress is not reported to the standard error stream"`
+}
+
+// Remove objects of the sharing root no longer used by any registered clone
+func (c *SharedGC) Run(ctx context.Context, g *Globals) error {
+	root := c.Root
+	if len(root) == 0 {
+		root = zeta.ResolveSharingRoot(g.CWD, g.Values)
+	}
+	return zeta.SharedGC(ctx, &zeta.SharedGCOptions{
+		SharingRoot: root,
+		Prune:       c.Prune,
+		DryRun:      c.DryRun,
+		Force:       c.Force,
+		Quiet:       c.Quiet,
+	})
+}
@@ -0,0 +1,54 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type Shortlog struct {
+	Revision    string   `arg:"" optional:"" name:"revision-range" help:"Revision range"`
+	SummaryOnly bool     `name:"summary" short:"s" help:"Suppress commit description and provide a commit count summary only"`
+	Numbered    bool     `name:"numbered" short:"n" help:"Sort output according to the number of commits per author"`
+	Email       bool     `name:"email" short:"e" help:"Show the email address of each author"`
+	Committer   bool     `name:"committer" short:"c" help:"Collect and show committer identities instead of authors"`
+	Mailmap     bool     `name:"mailmap" negatable:"" default:"true" help:"Use mailmap file to map author and committer names and email addresses to canonical ones"`
+	paths       []string `kong:"-"`
+}
+
+const (
+	shortlogSummaryFormat = `%szeta shortlog [<options>] [<revision-range>] [[--] <path>...]`
+)
+
+func (c *Shortlog) Summary() string {
+	return fmt.Sprintf(shortlogSummaryFormat, W("Usage: "))
+}
+
+func (c *Shortlog) Passthrough(paths []string) {
+	c.paths = append(c.paths, paths...)
+}
+
+func (c *Shortlog) Run(ctx context.Context, g *Globals) error {
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	return r.Shortlog(ctx, &zeta.ShortlogOptions{
+		Revision:  c.Revision,
+		Paths:     slashPaths(c.paths),
+		Summary:   c.SummaryOnly,
+		Numbered:  c.Numbered,
+		Email:     c.Email,
+		Committer: c.Committer,
+		NoMailmap: !c.Mailmap,
+	})
+}
@@ -26,6 +26,7 @@ type Show struct {
 	Minimal       bool     `name:"minimal" help:"Spend extra time to make sure the smallest possible diff is produced"`
 	DiffAlgorithm string   `name:"diff-algorithm" help:"Choose a diff algorithm, supported: histogram|onp|myers|patience|minimal" placeholder:"<algorithm>"`
 	Limit         int64    `name:"limit" short:"L" help:"Omits blobs larger than n bytes or units. n may be zero. Supported units: KB, MB, GB, K, M, G" default:"-1" type:"size"`
+	Save          bool     `name:"save" help:"Save objects resolved from the remote to the local repository"`
 	Objects       []string `arg:"" optional:"" name:"object" help:""`
 }
 
@@ -81,5 +82,6 @@ func (c *Show) Run(ctx context.Context, g *Globals) error {
 		Textconv:  c.Textconv,
 		Limit:     c.Limit,
 		Algorithm: a,
+		Save:      c.Save,
 	})
 }
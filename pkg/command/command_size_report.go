@@ -0,0 +1,36 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type SizeReport struct {
+	Revision string `arg:"" optional:"" name:"tree-ish" help:"ID of a tree-ish, default HEAD"`
+	Base     string `name:"base" short:"b" help:"Compare sizes with the base revision" placeholder:"<rev>"`
+	Limit    int    `name:"limit" short:"n" default:"20" help:"Number of entries shown in each section, 0 means unlimited" placeholder:"<n>"`
+	JSON     bool   `name:"json" short:"j" help:"Data will be returned in JSON format"`
+}
+
+// Report the largest files, directories and extensions of a tree
+func (c *SizeReport) Run(ctx context.Context, g *Globals) error {
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	return r.SizeReport(ctx, &zeta.SizeReportOptions{
+		Revision: c.Revision,
+		Base:     c.Base,
+		Limit:    c.Limit,
+		JSON:     c.JSON,
+	})
+}
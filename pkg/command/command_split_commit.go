@@ -0,0 +1,39 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+// Split an unpushed commit into several commits
+type SplitCommit struct {
+	Revision string   `arg:"" optional:"" name:"commit" help:"The unpushed commit to split, default HEAD" default:"HEAD" placeholder:"<commit>"`
+	Paths    []string `name:"path" short:"p" help:"Move the changes under the path to a new commit before it instead of picking hunks" placeholder:"<path>"`
+	Message  []string `name:"message" short:"m" help:"Message of the new commit, required with --path" placeholder:"<message>"`
+}
+
+func (c *SplitCommit) Run(ctx context.Context, g *Globals) error {
+	if len(c.Paths) != 0 && len(c.Message) == 0 {
+		die("--path requires --message")
+		return ErrArgRequired
+	}
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	w := r.Worktree()
+	return w.SplitCommit(ctx, &zeta.SplitCommitOptions{
+		Commit:  c.Revision,
+		Paths:   c.Paths,
+		Message: c.Message,
+	})
+}
@@ -15,6 +15,9 @@ type Status struct {
 	Short bool `name:"short" short:"s" help:"Give the output in the short-format"`
 	Z     bool `short:"z" shortonly:"" help:"Terminate entries with NUL byte"`
 	JSON  bool `name:"json" short:"j" help:"Data will be returned in JSON format"`
+	// status.aheadBehind is overridden by the flags
+	AheadBehind   bool `name:"ahead-behind" help:"Count the commits the branch is ahead and behind its upstream"`
+	NoAheadBehind bool `name:"no-ahead-behind" help:"Only show whether the branch and its upstream differ"`
 }
 
 func (s *Status) NewLine() byte {
@@ -27,9 +30,20 @@ func (s *Status) NewLine() byte {
 // --show-stash
 
 func (s *Status) Run(ctx context.Context, g *Globals) error {
+	if s.AheadBehind && s.NoAheadBehind {
+		die("--ahead-behind and --no-ahead-behind cannot be used together")
+		return ErrFlagsIncompatible
+	}
+	values := g.Values
+	switch {
+	case s.AheadBehind:
+		values = append(values, "status.aheadBehind=true")
+	case s.NoAheadBehind:
+		values = append(values, "status.aheadBehind=false")
+	}
 	r, err := zeta.Open(ctx, &zeta.OpenOptions{
 		Worktree: g.CWD,
-		Values:   g.Values,
+		Values:   values,
 		Verbose:  g.Verbose,
 	})
 	if err != nil {
@@ -45,7 +59,7 @@ func (s *Status) Run(ctx context.Context, g *Globals) error {
 		return err
 	}
 	if s.JSON {
-		return w.ShowStatusJSON(status)
+		return w.ShowStatusJSON(ctx, status)
 	}
 	if shortFormat {
 		w.ShowStatus(status, true, s.Z)
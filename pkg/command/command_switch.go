@@ -19,7 +19,8 @@ type Switch struct {
 	Orphan         bool     `name:"orphan" help:"Create a new orphan branch, named <new-branch>. All tracked files are removed"`
 	DiscardChanges bool     `name:"discard-changes" help:"Proceed even if the index or the working tree differs from HEAD"`
 	Force          bool     `name:"force" short:"f" help:"An alias for --discard-changes"`
-	Merge          bool     `name:"merge" short:"m" negatable:"" default:"true" help:"Perform a 3-way merge with the new branch"`
+	Merge          bool     `name:"merge" short:"m" help:"Perform a 3-way merge of local changes with the new branch"`
+	Conflict       string   `name:"conflict" help:"Conflict style for --merge: merge, diff3 or zdiff3, implies --merge" placeholder:"<style>"`
 	Remote         bool     `name:"remote" help:"Attempt to checkout from remote when branch is absent"`
 	Limit          int64    `name:"limit" short:"L" help:"Omits blobs larger than n bytes or units. n may be zero. Supported units: KB, MB, GB, K, M, G" default:"-1" type:"size"`
 	Quiet          bool     `name:"quiet" help:"Operate quietly. Progress is not reported to the standard error stream"`
@@ -53,16 +54,29 @@ func (s *Switch) Run(ctx context.Context, g *Globals) error {
 		return err
 	}
 	defer r.Close() // nolint
+	if len(s.Args) == 0 && s.Detach {
+		s.Args = append(s.Args, "HEAD")
+	}
 	if len(s.Args) == 0 {
 		die("missing branch or commit argument")
 		return ErrArgRequired
 	}
+	switch s.Conflict {
+	case "", "merge", "diff3", "zdiff3":
+	default:
+		diev("unknown conflict style '%s'", s.Conflict)
+		return ErrArgRequired
+	}
+	if s.Orphan && len(s.Args) > 1 {
+		die("--orphan does not take a start-point")
+		return ErrArgRequired
+	}
 	branchOrBasePoint := s.Args[0]
 	basePoint := "HEAD"
 	if len(s.Args) >= 2 {
 		basePoint = s.Args[1]
 	}
-	so := &zeta.SwitchOptions{Force: s.Discard(), Merge: s.Merge, ForceCreate: s.ForceCreate, Remote: s.Remote, Limit: s.Limit}
+	so := &zeta.SwitchOptions{Force: s.Discard(), Merge: s.Merge || len(s.Conflict) != 0, ConflictStyle: s.Conflict, ForceCreate: s.ForceCreate, Remote: s.Remote, Limit: s.Limit}
 	if err := so.Validate(); err != nil {
 		fmt.Fprintf(os.Stderr, "zeta switch to '%s' error: %v\n", basePoint, err)
 		return err
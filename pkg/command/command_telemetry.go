@@ -0,0 +1,36 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type Telemetry struct {
+	Show   ShowTelemetry   `cmd:"show" help:"Show command timings and error categories collected on this machine" default:"1"`
+	Upload UploadTelemetry `cmd:"upload" help:"Upload the aggregates to telemetry.endpoint now"`
+	Clear  ClearTelemetry  `cmd:"clear" help:"Remove the aggregates collected on this machine"`
+}
+
+type ShowTelemetry struct {
+	JSON bool `name:"json" short:"j" help:"Data will be returned in JSON format"`
+}
+
+func (c *ShowTelemetry) Run(ctx context.Context, g *Globals) error {
+	return zeta.ShowTelemetry(ctx, &zeta.TelemetryOptions{CWD: g.CWD, Values: g.Values, JSON: c.JSON, Verbose: g.Verbose})
+}
+
+type UploadTelemetry struct{}
+
+func (c *UploadTelemetry) Run(ctx context.Context, g *Globals) error {
+	return zeta.UploadTelemetry(ctx, &zeta.TelemetryOptions{CWD: g.CWD, Values: g.Values, Verbose: g.Verbose})
+}
+
+type ClearTelemetry struct{}
+
+func (c *ClearTelemetry) Run(ctx context.Context, g *Globals) error {
+	return zeta.ClearTelemetry(ctx, &zeta.TelemetryOptions{CWD: g.CWD, Values: g.Values, Verbose: g.Verbose})
+}
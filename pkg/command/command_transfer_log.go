@@ -0,0 +1,33 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"time"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type TransferLog struct {
+	Show ShowTransferLog `cmd:"show" help:"Show the transport requests recorded in .zeta/logs/transfer.log" default:"1"`
+}
+
+type ShowTransferLog struct {
+	Last time.Duration `name:"last" help:"Only show requests within this period, eg: 30m, 1h, 2d" type:"expire" default:"all"`
+	JSON bool          `name:"json" short:"j" help:"Data will be returned in JSON format"`
+}
+
+func (c *ShowTransferLog) Run(ctx context.Context, g *Globals) error {
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	return r.ShowTransferLog(ctx, &zeta.TransferLogOptions{Last: c.Last, JSON: c.JSON})
+}
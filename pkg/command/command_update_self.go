@@ -0,0 +1,28 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type UpdateSelf struct {
+	Check    bool `name:"check" help:"Only check whether a newer version is available"`
+	Force    bool `name:"force" short:"f" help:"Install the published version even if it is not newer"`
+	Rollback bool `name:"rollback" help:"Restore the version replaced by the last update"`
+}
+
+// Replace the running zeta with the release published to update.endpoint
+func (c *UpdateSelf) Run(ctx context.Context, g *Globals) error {
+	return zeta.UpdateSelf(ctx, &zeta.UpdateSelfOptions{
+		CWD:      g.CWD,
+		Values:   g.Values,
+		Check:    c.Check,
+		Force:    c.Force,
+		Rollback: c.Rollback,
+		Verbose:  g.Verbose,
+	})
+}
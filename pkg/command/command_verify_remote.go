@@ -0,0 +1,28 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type VerifyRemote struct {
+	JSON bool `name:"json" short:"j" help:"Data will be returned in JSON format"`
+}
+
+// Compare local branches, tags and remote-tracking refs against the remote
+func (c *VerifyRemote) Run(ctx context.Context, g *Globals) error {
+	r, err := zeta.Open(ctx, &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint
+	return r.VerifyRemote(ctx, &zeta.VerifyRemoteOptions{JSON: c.JSON})
+}
@@ -0,0 +1,252 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/command"
+	"github.com/antgroup/hugescm/modules/git"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/tr"
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+// Read-through translation of git remotes: zeta checkout/fetch git+https://host/repo.git mirrors the git
+// repository into .zeta/git-bridge/mirror.git and converts the new commits with the zeta-mc conversion, the
+// mapping of git objects to zeta objects is kept in .zeta/git-bridge/object-map so every fetch only converts the
+// commits the previous fetches have not seen.
+
+const (
+	bridgeDir      = "git-bridge"
+	bridgeMirror   = "mirror.git"
+	bridgeMapName  = "object-map"
+	bridgeStepEnd  = 2
+	gitSchemPrefix = "git+"
+)
+
+var (
+	// GitSchemes: remotes using these schemes are translated by GitTranslator
+	GitSchemes = []string{"git+http", "git+https", "git+ssh", "git+file"}
+)
+
+type GitTranslator struct{}
+
+// NewGitTranslator returns the translator of git remotes, see zeta.RegisterTranslator.
+func NewGitTranslator() zeta.Translator {
+	return &GitTranslator{}
+}
+
+func (t *GitTranslator) Translate(ctx context.Context, r *zeta.Repository, opts *zeta.TranslateOptions) (*zeta.Translation, error) {
+	remote := opts.Remote[len(gitSchemPrefix):]
+	root := filepath.Join(r.ZetaDir(), bridgeDir)
+	mirror := filepath.Join(root, bridgeMirror)
+	if err := syncMirror(ctx, mirror, remote, opts.Quiet); err != nil {
+		return nil, err
+	}
+	m, err := newBridgeMigrator(r, mirror, opts.Quiet || opts.Verbose)
+	if err != nil {
+		return nil, err
+	}
+	defer m.gitODB.Close() // nolint
+	mapPath := filepath.Join(root, bridgeMapName)
+	if err := m.loadObjectMap(mapPath); err != nil {
+		return nil, fmt.Errorf("load object map: %w", err)
+	}
+	refs, err := m.translate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.saveObjectMap(mapPath); err != nil {
+		return nil, fmt.Errorf("save object map: %w", err)
+	}
+	return &zeta.Translation{References: refs, DefaultBranch: remoteDefaultBranch(ctx, mirror, remote)}, nil
+}
+
+// syncMirror fetches the branches and tags of the remote into the bare mirror, deleted branches and tags are pruned.
+func syncMirror(ctx context.Context, mirror, remote string, quiet bool) error {
+	if _, err := os.Stat(mirror); os.IsNotExist(err) {
+		if err := command.New(ctx, command.NoDir, "git", "init", "--bare", "--quiet", mirror).RunEx(); err != nil {
+			return fmt.Errorf("git init %s error: %w", mirror, err)
+		}
+	}
+	args := []string{"--git-dir", mirror, "fetch", "--prune", "--force", "--no-tags"}
+	if quiet {
+		args = append(args, "--quiet")
+	}
+	args = append(args, remote, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*")
+	if err := command.NewFromOptions(ctx, &command.RunOpts{Stderr: os.Stderr}, "git", args...).Run(); err != nil {
+		return fmt.Errorf("git fetch %s error: %w", remote, err)
+	}
+	return nil
+}
+
+// remoteDefaultBranch returns the branch HEAD of the remote points to, empty when the remote does not tell.
+func remoteDefaultBranch(ctx context.Context, mirror, remote string) string {
+	line, err := command.New(ctx, command.NoDir, "git", "--git-dir", mirror, "ls-remote", "--symref", remote, "HEAD").OneLine()
+	if err != nil {
+		return ""
+	}
+	// ref: refs/heads/master	HEAD
+	for l := range strings.SplitSeq(line, "\n") {
+		if target, ok := strings.CutPrefix(l, "ref: "); ok {
+			target, _, _ = strings.Cut(target, "\t")
+			return plumbing.ReferenceName(target).BranchName()
+		}
+	}
+	return ""
+}
+
+func newBridgeMigrator(r *zeta.Repository, mirror string, noProgress bool) (*Migrator, error) {
+	odb, err := git.NewODB(mirror, git.HashFormatOK(mirror))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		from:         mirror,
+		to:           r.BaseDir(),
+		mu:           new(sync.Mutex),
+		metadata:     make(map[string]plumbing.Hash),
+		blobs:        make(map[string]*blob),
+		gitODB:       odb,
+		r:            r,
+		modification: time.Now().Unix(),
+		stepEnd:      bridgeStepEnd,
+		stepCurrent:  1,
+		verbose:      noProgress,
+	}, nil
+}
+
+// loadObjectMap loads the objects converted by the previous fetches, each line of the map is:
+//
+//	<git-oid> <zeta-oid>                          commits, trees and tags
+//	<git-oid> <zeta-oid> <size> <fragments>       blobs
+func (m *Migrator) loadObjectMap(p string) error {
+	fd, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer fd.Close() // nolint
+	sr := bufio.NewScanner(fd)
+	for sr.Scan() {
+		fields := strings.Fields(sr.Text())
+		if len(fields) < 2 || !plumbing.ValidateHashHex(fields[1]) {
+			continue
+		}
+		if len(fields) < 4 {
+			m.metadata[fields[0]] = plumbing.NewHash(fields[1])
+			continue
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		m.blobs[fields[0]] = &blob{oid: plumbing.NewHash(fields[1]), size: size, fragments: fields[3] == "1"}
+	}
+	return sr.Err()
+}
+
+func (m *Migrator) saveObjectMap(p string) error {
+	var b strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(m.metadata)) {
+		fmt.Fprintf(&b, "%s %s\n", k, m.metadata[k])
+	}
+	for _, k := range slices.Sorted(maps.Keys(m.blobs)) {
+		e := m.blobs[k]
+		fragments := 0
+		if e.fragments {
+			fragments = 1
+		}
+		fmt.Fprintf(&b, "%s %s %d %d\n", k, e.oid, e.size, fragments)
+	}
+	// write then rename, an interrupted save keeps the previous map
+	tmp := p + ".lock"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// commitsToTranslate returns the commits of the mirror which have not been converted, parents first.
+func (m *Migrator) commitsToTranslate(ctx context.Context) ([][]byte, error) {
+	commits, err := m.commitsToMigrate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(commits, func(oid []byte) bool {
+		_, ok := m.metadata[hex.EncodeToString(oid)]
+		return ok
+	}), nil
+}
+
+// translate converts the new commits and returns the converted branches and tags of the mirror.
+func (m *Migrator) translate(ctx context.Context) (map[plumbing.ReferenceName]plumbing.Hash, error) {
+	commits, err := m.commitsToTranslate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("commits to translate error: %w", err)
+	}
+	ur, err := m.r.ODB().NewUnpacker(0, true)
+	if err != nil {
+		return nil, err
+	}
+	defer ur.Close() // nolint
+	bar := NewBar(tr.W("Rewrite commits"), len(commits), m.stepCurrent, m.stepEnd, m.verbose || len(commits) == 0)
+	m.stepCurrent++
+	for _, oid := range commits {
+		if err := m.migrateCommit(ctx, ur, oid); err != nil {
+			return nil, fmt.Errorf("rewrite commit %s error: %w", hex.EncodeToString(oid), err)
+		}
+		bar.Add(1)
+	}
+	bar.Done()
+	refs, err := m.refsToMigrate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bar = NewBar(tr.W("Rewrite references"), len(refs), m.stepCurrent, m.stepEnd, m.verbose)
+	m.stepCurrent++
+	result := make(map[plumbing.ReferenceName]plumbing.Hash, len(refs))
+	for _, ref := range refs {
+		name := plumbing.ReferenceName(ref.Name)
+		if !name.IsBranch() && !name.IsTag() {
+			continue
+		}
+		oid, err := m.rewriteOneRef(ur, ref)
+		if err != nil {
+			return nil, fmt.Errorf("rewrite one ref '%s' error: %w", ref.Name, err)
+		}
+		bar.Add(1)
+		if oid.IsZero() {
+			// tags of trees and blobs
+			continue
+		}
+		if ref.ObjectType != git.CommitObject {
+			// annotated tags are converted once
+			m.metadata[ref.Target] = oid
+		}
+		result[name] = oid
+	}
+	bar.Done()
+	if err := ur.Preserve(); err != nil {
+		return nil, err
+	}
+	if err := m.r.ODB().Reload(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
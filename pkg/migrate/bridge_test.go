@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestGitTranslator(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	src := t.TempDir()
+	runGit(t, src, "init", "--quiet", "--initial-branch=mainline")
+	runGit(t, src, "commit", "--quiet", "--allow-empty", "-m", "first")
+	runGit(t, src, "tag", "-a", "v1.0", "-m", "release v1.0")
+
+	r, err := zeta.Init(t.Context(), &zeta.InitOptions{Worktree: t.TempDir(), MustEmpty: true, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close() // nolint
+	translator := NewGitTranslator()
+	opts := &zeta.TranslateOptions{Remote: "git+file://" + filepath.ToSlash(src), Quiet: true}
+	result, err := translator.Translate(t.Context(), r, opts)
+	if err != nil {
+		t.Fatalf("translate error: %v", err)
+	}
+	if result.DefaultBranch != "mainline" {
+		t.Fatalf("unexpected default branch %q", result.DefaultBranch)
+	}
+	first, ok := result.References[plumbing.NewBranchReferenceName("mainline")]
+	if !ok {
+		t.Fatalf("branch mainline not translated: %v", result.References)
+	}
+	tagOID, ok := result.References[plumbing.NewTagReferenceName("v1.0")]
+	if !ok {
+		t.Fatalf("tag v1.0 not translated: %v", result.References)
+	}
+	tag, err := r.ODB().Tag(t.Context(), tagOID)
+	if err != nil {
+		t.Fatalf("read tag error: %v", err)
+	}
+	if tag.Object != first {
+		t.Fatalf("tag points to %s, want %s", tag.Object, first)
+	}
+
+	// the second translation converts the new commit only, its parent is the commit converted before
+	runGit(t, src, "commit", "--quiet", "--allow-empty", "-m", "second")
+	if result, err = translator.Translate(t.Context(), r, opts); err != nil {
+		t.Fatalf("translate again error: %v", err)
+	}
+	cc, err := r.ODB().Commit(t.Context(), result.References[plumbing.NewBranchReferenceName("mainline")])
+	if err != nil {
+		t.Fatalf("read commit error: %v", err)
+	}
+	if cc.Message != "second\n" || len(cc.Parents) != 1 || cc.Parents[0] != first {
+		t.Fatalf("unexpected commit %q parents %v, want parent %s", cc.Message, cc.Parents, first)
+	}
+	if result.References[plumbing.NewTagReferenceName("v1.0")] != tagOID {
+		t.Fatalf("tag v1.0 converted again")
+	}
+}
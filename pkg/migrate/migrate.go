@@ -408,6 +408,69 @@ func (m *Migrator) migrateTrees(ctx context.Context, ur *backend.Unpacker, treeO
 	return ur.WriteEncoded(&object.Tree{Entries: entries}, m.squeeze, m.modification)
 }
 
+// migrateCommit converts the commit oid, the parents must be converted first.
+func (m *Migrator) migrateCommit(ctx context.Context, ur *backend.Unpacker, oid []byte) error {
+	oc, err := m.gitODB.Commit(oid)
+	if err != nil {
+		return err
+	}
+	var newTree plumbing.Hash
+	var ok bool
+	if newTree, ok = m.uncacheMD(oc.TreeID); !ok {
+		if newTree, err = m.migrateTrees(ctx, ur, oc.TreeID, ""); err != nil {
+			return err
+		}
+		m.cacheMD(oc.TreeID, newTree)
+	}
+	// Create a new list of parents from the original commit to
+	// point at the rewritten parents in order to create a
+	// topologically equivalent DAG.
+	//
+	// This operation is safe since we are visiting the commits in
+	// reverse topological order and therefore have seen all parents
+	// before children (in other words, r.uncacheCommit(...) will
+	// always return a value, if the prospective parent is a part of
+	// the migration).
+	parents := make([]plumbing.Hash, 0, len(oc.ParentIDs))
+	for _, sha1Parent := range oc.ParentIDs {
+		rewrittenParent, ok := m.uncacheMD(sha1Parent)
+		if !ok {
+			// If we haven't seen the parent before, this
+			// means that we're doing a partial migration
+			// and the parent that we're looking for isn't
+			// included.
+			//
+			// Use the original parent to properly link
+			// history across the migration boundary.
+			continue
+		}
+
+		parents = append(parents, rewrittenParent)
+	}
+
+	// Construct a new commit using the original header information,
+	// but the rewritten set of parents as well as root tree.
+	nc := &object.Commit{
+		Message: oc.Message,
+		Parents: parents,
+		Tree:    newTree,
+	}
+	nc.Author.Decode([]byte(oc.Author))
+	nc.Committer.Decode([]byte(oc.Committer))
+	for _, e := range oc.ExtraHeaders {
+		nc.ExtraHeaders = append(nc.ExtraHeaders, &object.ExtraHeader{K: e.K, V: e.V})
+	}
+
+	var newOID plumbing.Hash
+	if newOID, err = ur.WriteEncoded(nc, m.squeeze, m.modification); err != nil {
+		return err
+	}
+	// Cache that commit so that we can reassign children of this
+	// commit.
+	m.cacheMD(oid, newOID)
+	return nil
+}
+
 func (m *Migrator) migrateCommits(ctx context.Context, ur *backend.Unpacker) error {
 	commits, err := m.commitsToMigrate(ctx)
 	if err != nil {
@@ -417,64 +480,9 @@ func (m *Migrator) migrateCommits(ctx context.Context, ur *backend.Unpacker) err
 	m.stepCurrent++
 	trace.DbgPrint("commits: %v", len(commits))
 	for _, oid := range commits {
-		oc, err := m.gitODB.Commit(oid)
-		if err != nil {
-			return err
-		}
-		var newTree plumbing.Hash
-		var ok bool
-		if newTree, ok = m.uncacheMD(oc.TreeID); !ok {
-			if newTree, err = m.migrateTrees(ctx, ur, oc.TreeID, ""); err != nil {
-				return err
-			}
-			m.cacheMD(oc.TreeID, newTree)
-		}
-		// Create a new list of parents from the original commit to
-		// point at the rewritten parents in order to create a
-		// topologically equivalent DAG.
-		//
-		// This operation is safe since we are visiting the commits in
-		// reverse topological order and therefore have seen all parents
-		// before children (in other words, r.uncacheCommit(...) will
-		// always return a value, if the prospective parent is a part of
-		// the migration).
-		parents := make([]plumbing.Hash, 0, len(oc.ParentIDs))
-		for _, sha1Parent := range oc.ParentIDs {
-			rewrittenParent, ok := m.uncacheMD(sha1Parent)
-			if !ok {
-				// If we haven't seen the parent before, this
-				// means that we're doing a partial migration
-				// and the parent that we're looking for isn't
-				// included.
-				//
-				// Use the original parent to properly link
-				// history across the migration boundary.
-				continue
-			}
-
-			parents = append(parents, rewrittenParent)
-		}
-
-		// Construct a new commit using the original header information,
-		// but the rewritten set of parents as well as root tree.
-		nc := &object.Commit{
-			Message: oc.Message,
-			Parents: parents,
-			Tree:    newTree,
-		}
-		nc.Author.Decode([]byte(oc.Author))
-		nc.Committer.Decode([]byte(oc.Committer))
-		for _, e := range oc.ExtraHeaders {
-			nc.ExtraHeaders = append(nc.ExtraHeaders, &object.ExtraHeader{K: e.K, V: e.V})
-		}
-
-		var newOID plumbing.Hash
-		if newOID, err = ur.WriteEncoded(nc, m.squeeze, m.modification); err != nil {
+		if err := m.migrateCommit(ctx, ur, oid); err != nil {
 			return err
 		}
-		// Cache that commit so that we can reassign children of this
-		// commit.
-		m.cacheMD(oid, newOID)
 		bar.Add(1)
 	}
 	bar.Done()
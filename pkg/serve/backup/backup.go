@@ -0,0 +1,295 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package backup implements snapshots of server repositories: metadata rows in the database, local repository
+// files and large objects in the oss bucket. Files are stored by checksum, so unchanged files are shared by
+// incremental backups.
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	incomingDir = "incoming" // quarantine of pushes in progress
+	parallel    = 8
+)
+
+type Options struct {
+	RID       int64
+	Namespace string
+	Path      string
+	Root      string // repositories root
+	DB        *sql.DB
+	Bucket    oss.Bucket
+	Cold      oss.Bucket // cold tier of large objects, may be nil
+	Storage   Storage
+	Full      bool // do not reuse the previous backup
+	// Progress: called after each table, local files and oss objects are saved
+	Progress func(format string, a ...any)
+}
+
+func (opts *Options) progress(format string, a ...any) {
+	if opts.Progress != nil {
+		opts.Progress(format, a...)
+	}
+}
+
+// Backup creates a snapshot of the repository. Metadata is read in a single read-only transaction before
+// files and objects, everything referenced by the snapshot refs is written before the refs are updated.
+func Backup(ctx context.Context, opts *Options) (*Manifest, error) {
+	var prev *Manifest
+	if !opts.Full {
+		var err error
+		if prev, err = LoadManifest(ctx, opts.Storage, opts.RID, ""); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("load last backup manifest: %w", err)
+		}
+	}
+	m := &Manifest{
+		Version:   ManifestVersion,
+		ID:        newSnapshotID(time.Now()),
+		RID:       opts.RID,
+		Namespace: opts.Namespace,
+		Path:      opts.Path,
+		CreatedAt: time.Now(),
+	}
+	if prev != nil {
+		if prev.ID == m.ID {
+			return nil, fmt.Errorf("snapshot '%s' already exists", m.ID)
+		}
+		m.Parent = prev.ID
+	}
+	if err := backupTables(ctx, opts, prev, m); err != nil {
+		return nil, err
+	}
+	files, err := backupFiles(ctx, opts.Storage, filepath.FromSlash(repo.RepositoryPath(opts.Root, opts.RID)), prev)
+	if err != nil {
+		return nil, fmt.Errorf("backup repository files: %w", err)
+	}
+	m.Files = files
+	opts.progress("files: %d", len(files))
+	if opts.Bucket != nil {
+		if m.Objects, err = backupObjects(ctx, opts.Storage, opts.Bucket, odb.OssPrefix(opts.RID), prev); err != nil {
+			return nil, fmt.Errorf("backup oss objects: %w", err)
+		}
+		if opts.Cold != nil {
+			cold, err := backupObjects(ctx, opts.Storage, opts.Cold, odb.OssPrefix(opts.RID), prev)
+			if err != nil {
+				return nil, fmt.Errorf("backup oss objects of the cold tier: %w", err)
+			}
+			m.Objects = mergeObjects(m.Objects, cold)
+		}
+		opts.progress("objects: %d", len(m.Objects))
+	}
+	if err := storeManifest(ctx, opts.Storage, m); err != nil {
+		return nil, fmt.Errorf("store manifest: %w", err)
+	}
+	return m, nil
+}
+
+func backupTables(ctx context.Context, opts *Options, prev *Manifest, m *Manifest) error {
+	tx, err := opts.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint
+	// taken before any row is read, rows committed after the snapshot of the transaction are updated later
+	var now int64
+	if err := tx.QueryRowContext(ctx, "select UNIX_TIMESTAMP()").Scan(&now); err != nil {
+		return err
+	}
+	prevTables := make(map[string]*Table)
+	if prev != nil {
+		for _, t := range prev.Tables {
+			prevTables[t.Name] = t
+		}
+	}
+	for _, spec := range tableSpecs {
+		t := &Table{Name: spec.name, DumpedAt: now}
+		var since int64
+		if p, ok := prevTables[spec.name]; ok && spec.appendOnly && p.DumpedAt != 0 {
+			t.Segments = append(t.Segments, p.Segments...)
+			since = max(p.DumpedAt-int64(incrementalOverlap/time.Second), 1)
+		}
+		pr, pw := io.Pipe()
+		var count int
+		done := make(chan error, 1)
+		go func() {
+			var err error
+			count, err = dumpTable(ctx, tx, spec, opts.RID, since, pw)
+			_ = pw.CloseWithError(err)
+			done <- err
+		}()
+		e, err := putBlob(ctx, opts.Storage, spec.name, pr)
+		_ = pr.CloseWithError(err)
+		if dumpErr := <-done; err == nil {
+			err = dumpErr
+		}
+		if err != nil {
+			return fmt.Errorf("dump table %s: %w", spec.name, err)
+		}
+		if count != 0 || len(t.Segments) == 0 {
+			t.Segments = append(t.Segments, e)
+		}
+		m.Tables = append(m.Tables, t)
+		opts.progress("table %s: %d rows", spec.name, count)
+	}
+	return tx.Commit()
+}
+
+func relativePath(root, p string) (string, error) {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// backupFiles saves files of the local repository, files with the same size and mtime as in the previous backup
+// are not read again.
+func backupFiles(ctx context.Context, s Storage, root string, prev *Manifest) ([]*Entry, error) {
+	prevFiles := make(map[string]*Entry)
+	if prev != nil {
+		for _, e := range prev.Files {
+			prevFiles[e.Name] = e
+		}
+	}
+	var files []*Entry
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root && errors.Is(err, os.ErrNotExist) {
+				// empty repository
+				return filepath.SkipAll
+			}
+			return err
+		}
+		name, err := relativePath(root, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name == incomingDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		si, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if e, ok := prevFiles[name]; ok && e.Size == si.Size() && e.ModTime == si.ModTime().UnixNano() {
+			files = append(files, e)
+			return nil
+		}
+		fd, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer fd.Close() // nolint
+		e, err := putBlob(ctx, s, name, fd)
+		if err != nil {
+			return err
+		}
+		e.Mode = uint32(si.Mode().Perm())
+		e.ModTime = si.ModTime().UnixNano()
+		files = append(files, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// mergeObjects merges the objects of the cold tier, an object being moved between the tiers is in both.
+func mergeObjects(objects, cold []*Entry) []*Entry {
+	names := make(map[string]bool, len(objects))
+	for _, e := range objects {
+		names[e.Name] = true
+	}
+	for _, e := range cold {
+		if !names[e.Name] {
+			objects = append(objects, e)
+		}
+	}
+	slices.SortFunc(objects, func(a, b *Entry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return objects
+}
+
+// backupObjects saves oss objects under prefix, objects are immutable, those in the previous backup are not
+// downloaded again.
+func backupObjects(ctx context.Context, s Storage, bucket oss.Bucket, prefix string, prev *Manifest) ([]*Entry, error) {
+	prevObjects := make(map[string]*Entry)
+	if prev != nil {
+		for _, e := range prev.Objects {
+			prevObjects[e.Name] = e
+		}
+	}
+	var mu sync.Mutex
+	var objects []*Entry
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(parallel)
+	var continuationToken string
+	for {
+		list, nextContinuationToken, err := bucket.ListObjects(ctx, prefix, continuationToken)
+		if err != nil {
+			_ = g.Wait()
+			return nil, err
+		}
+		for _, o := range list {
+			name := strings.TrimPrefix(o.Key, prefix)
+			if e, ok := prevObjects[name]; ok && e.Size == o.Size {
+				mu.Lock()
+				objects = append(objects, e)
+				mu.Unlock()
+				continue
+			}
+			g.Go(func() error {
+				rc, err := bucket.Open(gctx, o.Key, 0, 0)
+				if err != nil {
+					return err
+				}
+				defer rc.Close() // nolint
+				e, err := putBlob(gctx, s, name, rc)
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				objects = append(objects, e)
+				mu.Unlock()
+				return nil
+			})
+		}
+		if continuationToken = nextContinuationToken; len(continuationToken) == 0 {
+			break
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(objects, func(a, b *Entry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return objects, nil
+}
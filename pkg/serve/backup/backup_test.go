@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, p string, content string) {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackupRestoreFiles(t *testing.T) {
+	ctx := context.Background()
+	repoDir := filepath.Join(t.TempDir(), "1.zeta")
+	writeFile(t, filepath.Join(repoDir, "blob", "pack", "pack-1.pack"), "pack content")
+	writeFile(t, filepath.Join(repoDir, "metadata", "ab", "cd"), "metadata object")
+	writeFile(t, filepath.Join(repoDir, "incoming", "quarantine-1", "blob"), "pushing")
+	s, err := newDirStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := backupFiles(ctx, s, repoDir, nil)
+	if err != nil {
+		t.Fatalf("backup files error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	m := &Manifest{Version: ManifestVersion, ID: newSnapshotID(time.Now()), RID: 1, Files: files}
+	if err := storeManifest(ctx, s, m); err != nil {
+		t.Fatalf("store manifest error: %v", err)
+	}
+	// incremental: unchanged files keep their entries, changed files are saved again
+	writeFile(t, filepath.Join(repoDir, "metadata", "ab", "cd"), "metadata object changed")
+	prev, err := LoadManifest(ctx, s, 1, "")
+	if err != nil {
+		t.Fatalf("load manifest error: %v", err)
+	}
+	files2, err := backupFiles(ctx, s, repoDir, prev)
+	if err != nil {
+		t.Fatalf("incremental backup files error: %v", err)
+	}
+	if files2[0] != prev.Files[0] || files2[1].Checksum == prev.Files[1].Checksum {
+		t.Fatalf("unexpected incremental entries: %v %v", files2[0], files2[1])
+	}
+	restored := filepath.Join(t.TempDir(), "restored")
+	if err := restoreFiles(ctx, s, files2, restored); err != nil {
+		t.Fatalf("restore files error: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(restored, "metadata", "ab", "cd"))
+	if err != nil || string(b) != "metadata object changed" {
+		t.Fatalf("unexpected restored file: %q %v", b, err)
+	}
+	// corrupt a blob
+	if err := os.WriteFile(filepath.Join(s.root, blobName(files2[0].Checksum)), []byte("pack contenT"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = Verify(ctx, s, &Manifest{Files: files2})
+	if e, ok := errors.AsType[*ErrCorrupted](err); !ok || e.Name != files2[0].Name {
+		t.Fatalf("expected corrupted error, got: %v", err)
+	}
+	if _, err := LoadManifest(ctx, s, 2, ""); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected not exist error, got: %v", err)
+	}
+}
+
+func TestTableSQL(t *testing.T) {
+	spec := &tableSpec{name: "trees", columns: []string{"rid", "hash", "bindata"}, times: timestamps, where: "rid = ?", appendOnly: true}
+	if got, want := spec.selectSQL(false), "select rid, hash, bindata, UNIX_TIMESTAMP(created_at), UNIX_TIMESTAMP(updated_at) from trees where rid = ? order by id"; got != want {
+		t.Fatalf("select sql:\n%s\nwant:\n%s", got, want)
+	}
+	if got, want := spec.selectSQL(true), "select rid, hash, bindata, UNIX_TIMESTAMP(created_at), UNIX_TIMESTAMP(updated_at) from trees where rid = ? and updated_at >= FROM_UNIXTIME(?) order by id"; got != want {
+		t.Fatalf("incremental select sql:\n%s\nwant:\n%s", got, want)
+	}
+	if got, want := spec.insertSQL(2), "insert into trees(rid, hash, bindata, created_at, updated_at) values (?, ?, ?, FROM_UNIXTIME(?), FROM_UNIXTIME(?)), (?, ?, ?, FROM_UNIXTIME(?), FROM_UNIXTIME(?)) ON DUPLICATE KEY UPDATE rid = rid"; got != want {
+		t.Fatalf("insert sql:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestReplaceDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "1.zeta")
+	staging := dir + ".restore"
+	writeFile(t, filepath.Join(dir, "HEAD"), "current")
+	writeFile(t, filepath.Join(staging, "HEAD"), "restored")
+	old, err := replaceDir(staging, dir)
+	if err != nil {
+		t.Fatalf("replace dir error: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "HEAD")); err != nil || string(b) != "restored" {
+		t.Fatalf("unexpected replaced file: %q %v", b, err)
+	}
+	// the metadata transaction failed to commit
+	if err := restoreDir(old, dir); err != nil {
+		t.Fatalf("restore dir error: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "HEAD")); err != nil || string(b) != "current" {
+		t.Fatalf("unexpected put back file: %q %v", b, err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatalf("old dir should be moved back: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/streamio"
+)
+
+const (
+	ManifestVersion = 1
+	latestName      = "LATEST"
+	maxPointerSize  = 4096
+)
+
+// Entry: a file in the backup, its content is stored as blobs/xx/yy/<BLAKE3 checksum>.
+type Entry struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+	Mode     uint32 `json:"mode,omitempty"`
+	ModTime  int64  `json:"mtime,omitempty"` // local files only, unchanged files are not read again in incremental mode
+}
+
+// Table: dump of a metadata table, append-only tables are dumped incrementally, one segment per backup.
+type Table struct {
+	Name string `json:"name"`
+	// DumpedAt: database unix time when the table was dumped, the next incremental dump starts from it, tables of
+	// manifests without it are dumped in full
+	DumpedAt int64    `json:"dumped_at,omitempty"`
+	Segments []*Entry `json:"segments"`
+}
+
+// Manifest: a consistent snapshot of a repository, it references all files of the snapshot including those
+// stored by previous backups.
+type Manifest struct {
+	Version   int       `json:"version"`
+	ID        string    `json:"id"`
+	Parent    string    `json:"parent,omitempty"` // previous backup of incremental backups
+	RID       int64     `json:"rid"`
+	Namespace string    `json:"namespace"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+	Tables    []*Table  `json:"tables"`
+	Files     []*Entry  `json:"files"`   // local repository files, relative path
+	Objects   []*Entry  `json:"objects"` // oss objects, key relative to the repository prefix
+}
+
+func (m *Manifest) Size() int64 {
+	var size int64
+	for _, t := range m.Tables {
+		for _, e := range t.Segments {
+			size += e.Size
+		}
+	}
+	for _, e := range m.Files {
+		size += e.Size
+	}
+	for _, e := range m.Objects {
+		size += e.Size
+	}
+	return size
+}
+
+func newSnapshotID(now time.Time) string {
+	return now.UTC().Format("20060102T150405Z")
+}
+
+func manifestDir(rid int64) string {
+	return path.Join("manifests", strconv.FormatInt(rid, 10))
+}
+
+// putPointer stores a small text file, pointers are overwritten.
+func putPointer(ctx context.Context, s Storage, name string, content string) error {
+	fd, err := os.CreateTemp(s.TempDir(), "pointer-")
+	if err != nil {
+		return err
+	}
+	if _, err := fd.WriteString(content + "\n"); err != nil {
+		_ = fd.Close()
+		_ = os.Remove(fd.Name())
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		_ = os.Remove(fd.Name())
+		return err
+	}
+	if err := s.Put(ctx, name, fd.Name()); err != nil {
+		_ = os.Remove(fd.Name())
+		return err
+	}
+	return nil
+}
+
+func readPointer(ctx context.Context, s Storage, name string) (string, error) {
+	rc, err := s.Open(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close() // nolint
+	b, err := streamio.ReadMax(rc, maxPointerSize)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// storeManifest stores the manifest as a blob, manifests/<rid>/<id> records its checksum and LATEST its id.
+func storeManifest(ctx context.Context, s Storage, m *Manifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	e, err := putBlob(ctx, s, m.ID, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	dir := manifestDir(m.RID)
+	if err := putPointer(ctx, s, path.Join(dir, m.ID), fmt.Sprintf("%s %d", e.Checksum, e.Size)); err != nil {
+		return err
+	}
+	return putPointer(ctx, s, path.Join(dir, latestName), m.ID)
+}
+
+// LoadManifest loads the manifest of snapshot id of repository rid and verifies its checksum, empty id
+// means the latest snapshot. Returns os.ErrNotExist when there is no such snapshot.
+func LoadManifest(ctx context.Context, s Storage, rid int64, id string) (*Manifest, error) {
+	dir := manifestDir(rid)
+	if len(id) == 0 {
+		latest, err := readPointer(ctx, s, path.Join(dir, latestName))
+		if err != nil {
+			return nil, err
+		}
+		id = latest
+	}
+	if strings.ContainsAny(id, "/\\") || id == latestName {
+		return nil, fmt.Errorf("bad snapshot id '%s'", id)
+	}
+	pointer, err := readPointer(ctx, s, path.Join(dir, id))
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{Name: "manifest " + id}
+	if _, err := fmt.Sscanf(pointer, "%s %d", &e.Checksum, &e.Size); err != nil {
+		return nil, fmt.Errorf("bad manifest pointer of snapshot '%s': %w", id, err)
+	}
+	rc, err := openBlob(ctx, s, e)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close() // nolint
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("decode manifest of snapshot '%s': %w", id, err)
+	}
+	if m.Version > ManifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", m.Version)
+	}
+	if m.RID != rid || m.ID != id {
+		return nil, errors.New("manifest does not match the snapshot")
+	}
+	return &m, nil
+}
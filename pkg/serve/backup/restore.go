@@ -0,0 +1,267 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+	"golang.org/x/sync/errgroup"
+)
+
+type RestoreOptions struct {
+	RID        int64
+	Snapshot   string // empty means the latest snapshot
+	Root       string // repositories root
+	DB         *sql.DB
+	Bucket     oss.Bucket
+	Storage    Storage
+	VerifyOnly bool // only verify the integrity of the snapshot
+	Progress   func(format string, a ...any)
+}
+
+func (opts *RestoreOptions) progress(format string, a ...any) {
+	if opts.Progress != nil {
+		opts.Progress(format, a...)
+	}
+}
+
+// Verify reads all files of the snapshot and checks their size and checksum.
+func Verify(ctx context.Context, s Storage, m *Manifest) error {
+	entries := make([]*Entry, 0, len(m.Files)+len(m.Objects))
+	for _, t := range m.Tables {
+		entries = append(entries, t.Segments...)
+	}
+	entries = append(entries, m.Files...)
+	entries = append(entries, m.Objects...)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(parallel)
+	for _, e := range entries {
+		g.Go(func() error {
+			rc, err := openBlob(gctx, s, e)
+			if err != nil {
+				return err
+			}
+			defer rc.Close() // nolint
+			_, err = io.Copy(io.Discard, rc)
+			return err
+		})
+	}
+	return g.Wait()
+}
+
+// Restore restores the repository to the snapshot. Every file is verified before anything is modified: files
+// are restored to a staging directory, metadata is restored in a single transaction, the staging directory
+// replaces the repository and the transaction is committed at last, so the restored references never point to
+// objects missing from the repository. Repository writes should be stopped while restoring.
+func Restore(ctx context.Context, opts *RestoreOptions) (*Manifest, error) {
+	m, err := LoadManifest(ctx, opts.Storage, opts.RID, opts.Snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("load backup manifest: %w", err)
+	}
+	if opts.VerifyOnly {
+		return m, Verify(ctx, opts.Storage, m)
+	}
+	specs := make(map[string]*tableSpec)
+	for _, spec := range tableSpecs {
+		specs[spec.name] = spec
+	}
+	for _, t := range m.Tables {
+		if _, ok := specs[t.Name]; !ok {
+			return nil, fmt.Errorf("unsupported table '%s' in snapshot", t.Name)
+		}
+	}
+	repoPath := filepath.FromSlash(repo.RepositoryPath(opts.Root, opts.RID))
+	staging := repoPath + ".restore"
+	if err := os.RemoveAll(staging); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging) // nolint
+	if err := restoreFiles(ctx, opts.Storage, m.Files, staging); err != nil {
+		return nil, fmt.Errorf("restore repository files: %w", err)
+	}
+	opts.progress("files: %d", len(m.Files))
+	dumps := make(map[*Entry]string)
+	defer func() {
+		for _, p := range dumps {
+			_ = os.Remove(p)
+		}
+	}()
+	for _, t := range m.Tables {
+		for _, e := range t.Segments {
+			p, err := spoolBlob(ctx, opts.Storage, e)
+			if err != nil {
+				return nil, fmt.Errorf("read table %s dump: %w", t.Name, err)
+			}
+			dumps[e] = p
+		}
+	}
+	if len(m.Objects) != 0 {
+		if opts.Bucket == nil {
+			return nil, errors.New("snapshot has oss objects but oss is not configured")
+		}
+		// objects are immutable and keyed by hash, restoring them before metadata is harmless
+		if err := restoreObjects(ctx, opts.Storage, opts.Bucket, odb.OssPrefix(opts.RID), m.Objects); err != nil {
+			return nil, fmt.Errorf("restore oss objects: %w", err)
+		}
+		opts.progress("objects: %d", len(m.Objects))
+	}
+	if err := restoreTables(ctx, opts, m, specs, dumps, staging, repoPath); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// restoreTables restores metadata in a transaction, the staging directory replaces the repository before the
+// transaction is committed and the repository is put back when the commit fails.
+func restoreTables(ctx context.Context, opts *RestoreOptions, m *Manifest, specs map[string]*tableSpec, dumps map[*Entry]string, staging, repoPath string) error {
+	tx, err := opts.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint
+	for _, t := range m.Tables {
+		spec := specs[t.Name]
+		if err := spec.clear(ctx, tx, opts.RID); err != nil {
+			return fmt.Errorf("clear table %s: %w", t.Name, err)
+		}
+		for _, e := range t.Segments {
+			if err := restoreSegment(ctx, tx, spec, dumps[e]); err != nil {
+				return fmt.Errorf("restore table %s: %w", t.Name, err)
+			}
+		}
+		opts.progress("table %s: %d segments", t.Name, len(t.Segments))
+	}
+	old, err := replaceDir(staging, repoPath)
+	if err != nil {
+		return fmt.Errorf("replace repository files: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		if rerr := restoreDir(old, repoPath); rerr != nil {
+			return fmt.Errorf("commit restored metadata: %w, put back repository files: %v", err, rerr)
+		}
+		return fmt.Errorf("commit restored metadata: %w", err)
+	}
+	return os.RemoveAll(old)
+}
+
+func restoreFiles(ctx context.Context, s Storage, files []*Entry, root string) error {
+	for _, e := range files {
+		name := filepath.FromSlash(e.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("bad file name '%s' in snapshot", e.Name)
+		}
+		if err := restoreFile(ctx, s, e, filepath.Join(root, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func restoreFile(ctx context.Context, s Storage, e *Entry, p string) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	rc, err := openBlob(ctx, s, e)
+	if err != nil {
+		return err
+	}
+	defer rc.Close() // nolint
+	mode := fs.FileMode(e.Mode)
+	if mode == 0 {
+		mode = 0644
+	}
+	fd, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fd, rc); err != nil {
+		_ = fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+	if e.ModTime != 0 {
+		// keep mtime so the next incremental backup does not read the file again
+		mtime := time.Unix(0, e.ModTime)
+		return os.Chtimes(p, mtime, mtime)
+	}
+	return nil
+}
+
+func restoreObjects(ctx context.Context, s Storage, bucket oss.Bucket, prefix string, objects []*Entry) error {
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(parallel)
+	for _, e := range objects {
+		g.Go(func() error {
+			if strings.Contains(e.Name, "..") {
+				return fmt.Errorf("bad object name '%s' in snapshot", e.Name)
+			}
+			key := prefix + e.Name
+			if si, err := bucket.Stat(gctx, key); err == nil && si.Size == e.Size {
+				// unchanged, still verify the backup
+				rc, err := openBlob(gctx, s, e)
+				if err != nil {
+					return err
+				}
+				defer rc.Close() // nolint
+				_, err = io.Copy(io.Discard, rc)
+				return err
+			}
+			p, err := spoolBlob(gctx, s, e)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(p) // nolint
+			return bucket.StartUpload(gctx, key, p, odb.OSS_ZETA_BLOB_MIME)
+		})
+	}
+	return g.Wait()
+}
+
+// replaceDir replaces dir with the staging directory and returns the old directory, it is removed by the caller
+// or put back by restoreDir.
+func replaceDir(staging, dir string) (string, error) {
+	if _, err := os.Stat(staging); os.IsNotExist(err) {
+		// empty snapshot
+		if err := os.MkdirAll(staging, 0755); err != nil {
+			return "", err
+		}
+	}
+	old := dir + ".old"
+	if err := os.RemoveAll(old); err != nil {
+		return "", err
+	}
+	if err := os.Rename(dir, old); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		_ = os.Rename(old, dir)
+		return "", err
+	}
+	return old, nil
+}
+
+// restoreDir puts back the old directory replaced by replaceDir, dir did not exist when old is missing.
+func restoreDir(old, dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.Rename(old, dir); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve"
+)
+
+const (
+	blobMIME = "application/octet-stream"
+)
+
+// Storage: where backups are kept, a local directory or an object storage bucket.
+type Storage interface {
+	Exists(ctx context.Context, name string) (bool, error)
+	// Put moves the local file to name.
+	Put(ctx context.Context, name string, filePath string) error
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// TempDir: temporary files are created here before Put.
+	TempDir() string
+}
+
+// NewStorage opens a backup location: a local directory or s3://bucket/prefix, buckets use the endpoint and
+// credentials of ossConfig.
+func NewStorage(location string, ossConfig *serve.OSS) (Storage, error) {
+	if !strings.HasPrefix(location, "s3://") {
+		return newDirStorage(location)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if len(u.Host) == 0 {
+		return nil, fmt.Errorf("bad backup location '%s': missing bucket", location)
+	}
+	if ossConfig == nil {
+		return nil, fmt.Errorf("backup to '%s' requires oss config", location)
+	}
+	bucket, err := oss.NewBucket(&oss.NewBucketOptions{
+		Endpoint:        ossConfig.Endpoint,
+		AccessKeyID:     ossConfig.AccessKeyID,
+		AccessKeySecret: ossConfig.AccessKeySecret,
+		Bucket:          u.Host,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &bucketStorage{bucket: bucket, prefix: strings.Trim(u.Path, "/")}, nil
+}
+
+type dirStorage struct {
+	root string
+}
+
+func newDirStorage(root string) (*dirStorage, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	// temporary files are kept under root so that Put is a rename
+	if err := os.MkdirAll(filepath.Join(root, "tmp"), 0755); err != nil {
+		return nil, err
+	}
+	return &dirStorage{root: root}, nil
+}
+
+func (s *dirStorage) Exists(ctx context.Context, name string) (bool, error) {
+	_, err := os.Stat(filepath.Join(s.root, name))
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *dirStorage) Put(ctx context.Context, name string, filePath string) error {
+	p := filepath.Join(s.root, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.Rename(filePath, p)
+}
+
+func (s *dirStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.root, name))
+}
+
+func (s *dirStorage) TempDir() string {
+	return filepath.Join(s.root, "tmp")
+}
+
+type bucketStorage struct {
+	bucket oss.Bucket
+	prefix string
+}
+
+func (s *bucketStorage) join(name string) string {
+	return path.Join(s.prefix, name)
+}
+
+func (s *bucketStorage) Exists(ctx context.Context, name string) (bool, error) {
+	_, err := s.bucket.Stat(ctx, s.join(name))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *bucketStorage) Put(ctx context.Context, name string, filePath string) error {
+	defer os.Remove(filePath) // nolint
+	return s.bucket.StartUpload(ctx, s.join(name), filePath, blobMIME)
+}
+
+func (s *bucketStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.bucket.Open(ctx, s.join(name), 0, 0)
+}
+
+func (s *bucketStorage) TempDir() string {
+	return os.TempDir()
+}
+
+func blobName(checksum string) string {
+	return path.Join("blobs", checksum[0:2], checksum[2:4], checksum)
+}
+
+// spool copies r to a temporary file and returns its size and BLAKE3 checksum.
+func spool(s Storage, r io.Reader) (string, int64, string, error) {
+	fd, err := os.CreateTemp(s.TempDir(), "blob-")
+	if err != nil {
+		return "", 0, "", err
+	}
+	h := plumbing.NewHasher()
+	size, err := io.Copy(io.MultiWriter(fd, h), r)
+	if closeErr := fd.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(fd.Name())
+		return "", 0, "", err
+	}
+	return fd.Name(), size, hex.EncodeToString(h.Hash.Sum(nil)), nil
+}
+
+// putBlob stores the content of r by checksum, content already in the backup is not stored again.
+func putBlob(ctx context.Context, s Storage, name string, r io.Reader) (*Entry, error) {
+	tempPath, size, checksum, err := spool(s, r)
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{Name: name, Size: size, Checksum: checksum}
+	exists, err := s.Exists(ctx, blobName(checksum))
+	if err != nil || exists {
+		_ = os.Remove(tempPath)
+		return e, err
+	}
+	if err := s.Put(ctx, blobName(checksum), tempPath); err != nil {
+		_ = os.Remove(tempPath)
+		return nil, err
+	}
+	return e, nil
+}
+
+// ErrCorrupted: the content of a backup file does not match the manifest.
+type ErrCorrupted struct {
+	Name     string
+	Checksum string
+}
+
+func (e *ErrCorrupted) Error() string {
+	return fmt.Sprintf("backup file '%s' (blob %s) is corrupted", e.Name, e.Checksum)
+}
+
+type verifyReader struct {
+	io.ReadCloser
+	e *Entry
+	h plumbing.Hasher
+	n int64
+}
+
+func (r *verifyReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	_, _ = r.h.Write(p[:n])
+	r.n += int64(n)
+	if err == io.EOF && (r.n != r.e.Size || hex.EncodeToString(r.h.Hash.Sum(nil)) != r.e.Checksum) {
+		return n, &ErrCorrupted{Name: r.e.Name, Checksum: r.e.Checksum}
+	}
+	return n, err
+}
+
+// openBlob opens the content of e, reading to EOF fails with ErrCorrupted when the content does not match e.
+func openBlob(ctx context.Context, s Storage, e *Entry) (io.ReadCloser, error) {
+	if len(e.Checksum) < 4 {
+		return nil, &ErrCorrupted{Name: e.Name, Checksum: e.Checksum}
+	}
+	rc, err := s.Open(ctx, blobName(e.Checksum))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, &ErrCorrupted{Name: e.Name, Checksum: e.Checksum}
+		}
+		return nil, err
+	}
+	return &verifyReader{ReadCloser: rc, e: e, h: plumbing.NewHasher()}, nil
+}
+
+// spoolBlob verifies the content of e and copies it to a temporary file.
+func spoolBlob(ctx context.Context, s Storage, e *Entry) (string, error) {
+	rc, err := openBlob(ctx, s, e)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close() // nolint
+	tempPath, _, _, err := spool(s, rc)
+	return tempPath, err
+}
@@ -0,0 +1,208 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/streamio"
+)
+
+const (
+	restoreBatchRows = 64
+	// incrementalOverlap: rows are timestamped when inserted but visible when committed, incremental dumps
+	// start incrementalOverlap before the previous dump so rows of transactions committed after it are not
+	// missed, transactions writing metadata must be shorter
+	incrementalOverlap = time.Hour
+)
+
+type tableSpec struct {
+	name    string
+	columns []string
+	times   []string // timestamp columns, dumped as unix time
+	where   string   // rows of the repository, the only argument is rid
+	// appendOnly: rows are keyed by hash and never modified, dumped incrementally by updated_at and restored
+	// without removing existing rows, rows dumped again by overlapping dumps are ignored
+	appendOnly bool
+}
+
+var (
+	timestamps = []string{"created_at", "updated_at"}
+	tableSpecs = []*tableSpec{
+		{
+			name:    "repositories",
+			columns: []string{"id", "name", "path", "namespace_id", "description", "default_branch", "hash_algo", "compression_algo", "visible_level", "deleted_at"},
+			times:   timestamps,
+			where:   "id = ?",
+		},
+		{
+			name:    "members",
+			columns: []string{"rid", "uid", "access_level", "source_type"},
+			times:   []string{"expires_at", "created_at", "updated_at"},
+			where:   "rid = ? and source_type = 2",
+		},
+		{name: "path_permissions", columns: []string{"rid", "uid", "path"}, times: timestamps, where: "rid = ?"},
+		{name: "branches", columns: []string{"rid", "name", "hash", "protection_level"}, times: timestamps, where: "rid = ?"},
+		{name: "refs", columns: []string{"rid", "name", "hash"}, times: timestamps, where: "rid = ?"},
+		{name: "tags", columns: []string{"rid", "uid", "name", "hash", "subject", "description"}, times: timestamps, where: "rid = ?"},
+		// the reference log is restored along with the references, entries after the snapshot are removed
+		{name: "reference_logs", columns: []string{"rid", "seq", "name", "old_rev", "new_rev", "old_target", "new_target", "uid", "prev_hash", "hash", "signature"}, times: []string{"created_at"}, where: "rid = ?"},
+		{name: "commits", columns: []string{"rid", "hash", "author", "committer", "bindata"}, times: timestamps, where: "rid = ?", appendOnly: true},
+		{name: "trees", columns: []string{"rid", "hash", "bindata"}, times: timestamps, where: "rid = ?", appendOnly: true},
+		{name: "objects", columns: []string{"rid", "hash", "bindata"}, times: timestamps, where: "rid = ?", appendOnly: true},
+	}
+)
+
+// selectSQL returns the query of rows, incremental queries have a second argument, the unix time rows are
+// updated since.
+func (t *tableSpec) selectSQL(incremental bool) string {
+	var b strings.Builder
+	b.WriteString("select ")
+	b.WriteString(strings.Join(t.columns, ", "))
+	for _, c := range t.times {
+		fmt.Fprintf(&b, ", UNIX_TIMESTAMP(%s)", c)
+	}
+	fmt.Fprintf(&b, " from %s where %s", t.name, t.where)
+	if incremental {
+		b.WriteString(" and updated_at >= FROM_UNIXTIME(?)")
+	}
+	b.WriteString(" order by id")
+	return b.String()
+}
+
+func (t *tableSpec) insertSQL(rows int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "insert into %s(%s", t.name, strings.Join(t.columns, ", "))
+	for _, c := range t.times {
+		b.WriteString(", ")
+		b.WriteString(c)
+	}
+	b.WriteString(") values")
+	placeholders := strings.Repeat("?, ", len(t.columns)) + strings.Repeat("FROM_UNIXTIME(?), ", len(t.times))
+	placeholders = "(" + strings.TrimSuffix(placeholders, ", ") + ")"
+	for i := range rows {
+		if i != 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(" ")
+		b.WriteString(placeholders)
+	}
+	if t.appendOnly {
+		b.WriteString(" ON DUPLICATE KEY UPDATE rid = rid")
+	}
+	return b.String()
+}
+
+// dumpTable writes rows of the repository as zstd compressed json lines, each row is an array of columns, NULL
+// is null. When since is not zero only rows updated since the unix time are written. Returns the number of rows.
+func dumpTable(ctx context.Context, tx *sql.Tx, t *tableSpec, rid int64, since int64, w io.Writer) (int, error) {
+	args := []any{rid}
+	if since != 0 {
+		args = append(args, since)
+	}
+	rows, err := tx.QueryContext(ctx, t.selectSQL(since != 0), args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close() // nolint
+	zw := streamio.GetZstdWriter(w)
+	defer streamio.PutZstdWriter(zw)
+	enc := json.NewEncoder(zw)
+	n := len(t.columns) + len(t.times)
+	values := make([]sql.NullString, n)
+	dest := make([]any, n)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	var count int
+	row := make([]*string, n)
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return 0, err
+		}
+		for i, v := range values {
+			row[i] = nil
+			if v.Valid {
+				row[i] = &v.String
+			}
+		}
+		if err := enc.Encode(row); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (t *tableSpec) clear(ctx context.Context, tx *sql.Tx, rid int64) error {
+	if t.appendOnly {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("delete from %s where %s", t.name, t.where), rid)
+	return err
+}
+
+// restoreSegment inserts rows of a verified dump.
+func restoreSegment(ctx context.Context, tx *sql.Tx, t *tableSpec, dumpPath string) error {
+	fd, err := os.Open(dumpPath)
+	if err != nil {
+		return err
+	}
+	defer fd.Close() // nolint
+	zr, err := streamio.GetZstdReader(fd)
+	if err != nil {
+		return err
+	}
+	defer streamio.PutZstdReader(zr)
+	n := len(t.columns) + len(t.times)
+	flush := func(args []any) error {
+		if len(args) == 0 {
+			return nil
+		}
+		_, err := tx.ExecContext(ctx, t.insertSQL(len(args)/n), args...)
+		return err
+	}
+	dec := json.NewDecoder(bufio.NewReader(zr))
+	args := make([]any, 0, n*restoreBatchRows)
+	for {
+		var row []*string
+		if err := dec.Decode(&row); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("decode %s dump: %w", t.name, err)
+		}
+		if len(row) != n {
+			return fmt.Errorf("decode %s dump: expected %d columns, got %d", t.name, n, len(row))
+		}
+		for _, v := range row {
+			if v == nil {
+				args = append(args, nil)
+				continue
+			}
+			args = append(args, *v)
+		}
+		if len(args) == n*restoreBatchRows {
+			if err := flush(args); err != nil {
+				return err
+			}
+			args = args[:0]
+		}
+	}
+	return flush(args)
+}
@@ -4,8 +4,12 @@
 package serve
 
 import (
+	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -92,6 +96,47 @@ func (o *OSS) Decrypt(d *Decrypter) {
 	}
 }
 
+const (
+	defaultColdAfterDays = 90
+	defaultRecentDays    = 30
+)
+
+// Tiering describes the cold tier of large objects. Tier jobs move large objects which are neither referenced by
+// recent commits nor fetched recently to the cold bucket, objects of the cold tier are moved back on access.
+type Tiering struct {
+	// ColdOSS is the bucket of the cold tier, usually an infrequent access bucket. Tiering is disabled without it.
+	ColdOSS *OSS `toml:"cold_oss,omitempty"`
+	// ColdAfterDays: objects not fetched or uploaded within the days are cold, 90 by default.
+	ColdAfterDays int `toml:"cold_after_days,omitempty"`
+	// RecentDays: objects of the reference tips and of commits committed within the days stay hot, 30 by default.
+	RecentDays int `toml:"recent_days,omitempty"`
+	// MinSize: smaller objects stay hot, cold storage usually bills a minimum object size.
+	MinSize int64 `toml:"min_size,omitempty"`
+}
+
+func (t *Tiering) Decrypt(d *Decrypter) {
+	if t == nil || t.ColdOSS == nil {
+		return
+	}
+	t.ColdOSS.Decrypt(d)
+}
+
+// ColdAfter returns the duration after which unused objects are cold, t may be nil.
+func (t *Tiering) ColdAfter() time.Duration {
+	if t == nil || t.ColdAfterDays <= 0 {
+		return defaultColdAfterDays * 24 * time.Hour
+	}
+	return time.Duration(t.ColdAfterDays) * 24 * time.Hour
+}
+
+// Recent returns the age of the oldest commits whose objects stay hot.
+func (t *Tiering) Recent() time.Duration {
+	if t == nil || t.RecentDays <= 0 {
+		return defaultRecentDays * 24 * time.Hour
+	}
+	return time.Duration(t.RecentDays) * 24 * time.Hour
+}
+
 type Cache struct {
 	NumCounters int64 `toml:"num_counters"`
 	MaxCost     int64 `toml:"max_cost"`
@@ -118,3 +163,108 @@ func NewExpandReader(file string, expandEnv bool) (io.ReadCloser, error) {
 	b := strings.NewReader(os.ExpandEnv(string(buf)))
 	return io.NopCloser(b), nil
 }
+
+// CommitPolicy describes the trailers and the message policies required on commits pushed to protected branches.
+type CommitPolicy struct {
+	// SignOff requires a Signed-off-by trailer matching the commit author (DCO).
+	SignOff bool `toml:"signoff,omitempty"`
+	// Trailers are regular expressions, each one must match at least one trailer of every commit, eg: '^Change-Id: I[0-9a-f]{40}$'
+	Trailers []string `toml:"trailers,omitempty"`
+	// Branches are glob patterns of branches the policy applies to, in addition to protected branches.
+	Branches []string `toml:"branches,omitempty"`
+	// MessagePolicies are built-in commit message policies shared with zeta commit: issue, conventional, subject-length.
+	MessagePolicies []string `toml:"message_policies,omitempty"`
+	// IssuePattern is the regular expression of the issue policy, eg: '\b[A-Z][A-Z0-9]+-[0-9]+\b'
+	IssuePattern string `toml:"issue_pattern,omitempty"`
+	// SubjectLength is the maximum characters of the subject, 72 by default.
+	SubjectLength int `toml:"subject_length,omitempty"`
+}
+
+// PathPolicy describes the tree entries checked on trees received by a push. Clients refuse to check out some of
+// them, but the server does not trust the clients.
+type PathPolicy struct {
+	// Action is reject (default) or warn, warnings are reported to the client and the push is accepted.
+	Action string `toml:"action,omitempty"`
+	// Checks are enabled checks, dotdir by default:
+	//   dotdir: .zeta and .git path components, including the NTFS and 8.3 short name aliases
+	//   symlink: symlinks with absolute targets, targets escaping the worktree or pointing into .zeta/.git
+	//   ntfs: alternate data streams, backslashes, trailing dots or spaces and reserved device names
+	//   case: entries of the same tree which only differ in case
+	Checks []string `toml:"checks,omitempty"`
+}
+
+// Extension enables a server extension, Name is an extension registered with extension.Register or "sidecar".
+type Extension struct {
+	Name string `toml:"name"`
+	// Endpoint of the sidecar: http(s)://host:port or unix:///path/to/socket
+	Endpoint string   `toml:"endpoint,omitempty"`
+	Timeout  Duration `toml:"timeout,omitempty"`
+	// FailOpen ignores the extension when it fails, by default a failed check rejects the push or the access.
+	FailOpen bool           `toml:"fail_open,omitempty"`
+	Options  map[string]any `toml:"options,omitempty"`
+}
+
+// Backup restricts the locations of backup jobs started by the management API, jobs run by the command line are not
+// restricted.
+type Backup struct {
+	// Root: local directory of backups, the location of a job is a directory below it.
+	Root string `toml:"root,omitempty"`
+	// Targets: allowed OSS locations such as s3://zeta-backup/prod, the location of a job is one of them or below them.
+	Targets []string `toml:"targets,omitempty"`
+}
+
+// ErrBackupLocation: the backup location is not below the backup root or a backup target.
+type ErrBackupLocation struct {
+	Location string
+}
+
+func (e *ErrBackupLocation) Error() string {
+	return fmt.Sprintf("backup location '%s' is not allowed, it must be below the configured backup root or targets", e.Location)
+}
+
+func cleanBackupTarget(location string) (string, bool) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != "s3" || len(u.Host) == 0 || u.User != nil || len(u.RawQuery) != 0 || len(u.Fragment) != 0 {
+		return "", false
+	}
+	for p := range strings.SplitSeq(u.Path, "/") {
+		if p == ".." {
+			return "", false
+		}
+	}
+	return "s3://" + u.Host + "/" + strings.Trim(path.Clean("/"+u.Path), "/"), true
+}
+
+// Location returns the location of a backup job, relative local locations are relative to the root. b may be nil,
+// all locations are rejected then.
+func (b *Backup) Location(to string) (string, error) {
+	if b == nil {
+		return "", &ErrBackupLocation{Location: to}
+	}
+	if strings.HasPrefix(to, "s3://") {
+		location, ok := cleanBackupTarget(to)
+		if !ok {
+			return "", &ErrBackupLocation{Location: to}
+		}
+		for _, target := range b.Targets {
+			if t, ok := cleanBackupTarget(target); ok && (location == t || strings.HasPrefix(location, strings.TrimSuffix(t, "/")+"/")) {
+				return location, nil
+			}
+		}
+		return "", &ErrBackupLocation{Location: to}
+	}
+	if len(b.Root) == 0 {
+		return "", &ErrBackupLocation{Location: to}
+	}
+	rel := to
+	if filepath.IsAbs(to) {
+		var err error
+		if rel, err = filepath.Rel(b.Root, to); err != nil {
+			return "", &ErrBackupLocation{Location: to}
+		}
+	}
+	if !filepath.IsLocal(rel) || filepath.Clean(rel) == "." {
+		return "", &ErrBackupLocation{Location: to}
+	}
+	return filepath.Join(b.Root, rel), nil
+}
@@ -0,0 +1,53 @@
+package serve
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBackupLocation(t *testing.T) {
+	b := &Backup{Root: "/backup", Targets: []string{"s3://zeta-backup/prod", "s3://archive"}}
+	for _, c := range []struct {
+		to   string
+		want string
+	}{
+		{"daily", "/backup/daily"},
+		{"daily/group", "/backup/daily/group"},
+		{"/backup/daily", "/backup/daily"},
+		{"s3://zeta-backup/prod", "s3://zeta-backup/prod"},
+		{"s3://zeta-backup/prod/daily/", "s3://zeta-backup/prod/daily"},
+		{"s3://archive/2026", "s3://archive/2026"},
+	} {
+		got, err := b.Location(c.to)
+		if err != nil {
+			t.Fatalf("location %q: %v", c.to, err)
+		}
+		if got != c.want {
+			t.Fatalf("location %q: got %q, want %q", c.to, got, c.want)
+		}
+	}
+	for _, to := range []string{
+		"/etc/zeta",
+		"/backup",
+		"/backup-other/daily",
+		".",
+		"../etc",
+		"daily/../../etc",
+		"s3://zeta-backup/production",
+		"s3://zeta-backup/prod/../other",
+		"s3://other/prod",
+		"s3://user@zeta-backup/prod",
+	} {
+		var le *ErrBackupLocation
+		if _, err := b.Location(to); !errors.As(err, &le) {
+			t.Fatalf("location %q: want ErrBackupLocation, got %v", to, err)
+		}
+	}
+	var empty *Backup
+	if _, err := empty.Location("daily"); err == nil {
+		t.Fatalf("location without backup config accepted")
+	}
+	if _, err := (&Backup{}).Location("/backup/daily"); err == nil {
+		t.Fatalf("location without backup root accepted")
+	}
+}
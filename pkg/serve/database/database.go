@@ -5,6 +5,7 @@ package database
 
 import (
 	"context"
+	"crypto/ed25519"
 	"database/sql"
 	"fmt"
 	"time"
@@ -22,25 +23,56 @@ type DB interface {
 	AddMember(ctx context.Context, m *Member) error
 	FindKey(ctx context.Context, id int64) (*Key, error)
 	AddKey(ctx context.Context, k *Key) (*Key, error)
-	IsDeployKeyEnabled(ctx context.Context, rid int64, kid int64) (bool, error)
+	DeleteKey(ctx context.Context, id int64) error
+	TouchKey(ctx context.Context, id int64) error
+	FindDeployKeyRepository(ctx context.Context, rid int64, kid int64) (*DeployKeyRepository, error)
+	EnableDeployKey(ctx context.Context, kid, rid int64, commands []string) (*DeployKeyRepository, error)
 	FindNamespaceByID(ctx context.Context, namespaceID int64) (*Namespace, error)
 	FindNamespaceByPath(ctx context.Context, namespacePath string) (*Namespace, error)
 	FindRepositoryByID(ctx context.Context, rid int) (*Namespace, *Repository, error)
 	FindRepositoryByPath(ctx context.Context, namespacePath, repoPath string) (*Namespace, *Repository, error)
 	NewRepository(ctx context.Context, r *Repository) (*Repository, error)
+	SetDefaultBranch(ctx context.Context, rid, uid int64, branchName string) (string, error)
 	RepoAccessLevel(ctx context.Context, r *Repository, u *User) (AccessLevel, AccessLevel, error)
+	PathPermissions(ctx context.Context, rid, uid int64) ([]string, error)
+	SetPathPermissions(ctx context.Context, rid, uid int64, paths []string) error
+	UserSettings(ctx context.Context, uid int64) (*UserSettings, error)
+	SetUserSettings(ctx context.Context, s *UserSettings) (*UserSettings, error)
+	Templates(ctx context.Context, namespaceID int64) ([]*Template, error)
+	ListTemplates(ctx context.Context) ([]*Template, error)
+	SetTemplate(ctx context.Context, t *Template) (*Template, error)
+	DeleteTemplate(ctx context.Context, namespaceID int64, kind string) error
 	FindBranchForPrefix(ctx context.Context, rid int64, prefix string) (*Branch, error)
 	FindTagForPrefix(ctx context.Context, rid int64, prefix string) (*Tag, error)
 	FindBranch(ctx context.Context, rid int64, branchName string) (*Branch, error)
 	FindTag(ctx context.Context, rid int64, tagName string) (*Tag, error)
 	FindOrdinaryReference(ctx context.Context, rid int64, refname plumbing.ReferenceName) (*Reference, error)
+	ListReferences(ctx context.Context, rid int64) ([]*Reference, error)
 	DoBranchUpdate(ctx context.Context, cmd *Command) (*Branch, error)
 	DoReferenceUpdate(ctx context.Context, cmd *Command) (*Reference, error)
+	ReferenceLogs(ctx context.Context, rid int64, after int64, limit int) ([]*ReferenceLog, error)
+	ReferencePublicKey() ed25519.PublicKey
+	NewJob(ctx context.Context, j *Job) (*Job, error)
+	FindJob(ctx context.Context, id int64) (*Job, error)
+	ListJobs(ctx context.Context, rid int64, limit int) ([]*Job, error)
+	UpdateJobProgress(ctx context.Context, id int64, stage string, current, total int64) (bool, error)
+	FinishJob(ctx context.Context, id int64, status JobStatus, message string) error
+	CancelJob(ctx context.Context, id int64) (*Job, error)
 	Close() error
 }
 
 type database struct {
 	*sql.DB
+	referenceKey ed25519.PrivateKey
+}
+
+type Option func(*database)
+
+// WithReferenceKey signs reference log entries with key.
+func WithReferenceKey(key ed25519.PrivateKey) Option {
+	return func(d *database) {
+		d.referenceKey = key
+	}
 }
 
 func (d *database) Database() *sql.DB {
@@ -55,7 +87,7 @@ var (
 	_ DB = &database{}
 )
 
-func NewDB(cfg *mysql.Config) (DB, error) {
+func NewDB(cfg *mysql.Config, opts ...Option) (DB, error) {
 	connector, err := mysql.NewConnector(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("new connector: %w", err)
@@ -65,5 +97,9 @@ func NewDB(cfg *mysql.Config) (DB, error) {
 	db.SetMaxIdleConns(25)
 	db.SetMaxOpenConns(50)
 	db.SetConnMaxLifetime(5 * time.Minute)
-	return &database{DB: db}, nil
+	d := &database{DB: db}
+	for _, o := range opts {
+		o(d)
+	}
+	return d, nil
 }
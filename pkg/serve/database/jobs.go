@@ -0,0 +1,102 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	sqlJobColumns = "id, rid, uid, kind, params, status, stage, current, total, message, cancel_requested, runner, created_at, updated_at, finished_at"
+)
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row rowScanner) (*Job, error) {
+	j := &Job{}
+	var finishedAt sql.NullTime
+	if err := row.Scan(&j.ID, &j.RID, &j.UID, &j.Kind, &j.Params, &j.Status, &j.Stage, &j.Current, &j.Total, &j.Message,
+		&j.CancelRequested, &j.Runner, &j.CreatedAt, &j.UpdatedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	j.FinishedAt = finishedAt.Time
+	return j, nil
+}
+
+// NewJob records a running job.
+func (d *database) NewJob(ctx context.Context, j *Job) (*Job, error) {
+	now := time.Now()
+	result, err := d.ExecContext(ctx, "insert into jobs(rid, uid, kind, params, status, stage, message, runner, created_at, updated_at) values(?,?,?,?,?,?,?,?,?,?)",
+		j.RID, j.UID, j.Kind, j.Params, JobRunning, j.Stage, "", j.Runner, now, now)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return d.FindJob(ctx, id)
+}
+
+func (d *database) FindJob(ctx context.Context, id int64) (*Job, error) {
+	return scanJob(d.QueryRowContext(ctx, "select "+sqlJobColumns+" from jobs where id = ?", id))
+}
+
+// ListJobs returns the latest jobs of the repository, newest first, rid 0 lists the jobs of all repositories.
+func (d *database) ListJobs(ctx context.Context, rid int64, limit int) ([]*Job, error) {
+	var rows *sql.Rows
+	var err error
+	if rid == 0 {
+		rows, err = d.QueryContext(ctx, "select "+sqlJobColumns+" from jobs order by id desc limit ?", limit)
+	} else {
+		rows, err = d.QueryContext(ctx, "select "+sqlJobColumns+" from jobs where rid = ? order by id desc limit ?", rid, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint
+	jobs := make([]*Job, 0, limit)
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// UpdateJobProgress saves the progress of a running job and refreshes its heartbeat, it returns whether the job
+// was asked to cancel.
+func (d *database) UpdateJobProgress(ctx context.Context, id int64, stage string, current, total int64) (bool, error) {
+	if _, err := d.ExecContext(ctx, "update jobs set stage = ?, current = ?, total = ?, updated_at = ? where id = ? and status = ?",
+		stage, current, total, time.Now(), id, JobRunning); err != nil {
+		return false, err
+	}
+	var cancelRequested bool
+	if err := d.QueryRowContext(ctx, "select cancel_requested from jobs where id = ?", id).Scan(&cancelRequested); err != nil {
+		return false, err
+	}
+	return cancelRequested, nil
+}
+
+func (d *database) FinishJob(ctx context.Context, id int64, status JobStatus, message string) error {
+	now := time.Now()
+	_, err := d.ExecContext(ctx, "update jobs set status = ?, message = ?, updated_at = ?, finished_at = ? where id = ? and status = ?",
+		status, message, now, now, id, JobRunning)
+	return err
+}
+
+// CancelJob asks the runner of a running job to cancel it, the job stops at the runner's next progress update.
+// Finished jobs are returned unchanged.
+func (d *database) CancelJob(ctx context.Context, id int64) (*Job, error) {
+	if _, err := d.ExecContext(ctx, "update jobs set cancel_requested = 1 where id = ? and status = ?", id, JobRunning); err != nil {
+		return nil, err
+	}
+	return d.FindJob(ctx, id)
+}
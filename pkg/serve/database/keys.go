@@ -6,32 +6,42 @@ package database
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
-func (d *database) SearchKey(ctx context.Context, fingerprint string) (*Key, error) {
+const (
+	keyColumns = "id, uid, content, title, type, fingerprint, expires_at, last_used_at, created_at, updated_at"
+)
+
+func scanKey(row *sql.Row) (*Key, error) {
 	var k Key
-	if err := d.QueryRowContext(ctx, "select id, uid, content, title, type, fingerprint, created_at, updated_at from ssh_keys where fingerprint =?",
-		fingerprint).Scan(&k.ID, &k.UID, &k.Content, &k.Title, &k.Type, &k.Fingerprint, &k.CreatedAt, &k.UpdatedAt); err != nil {
+	var expiresAt, lastUsedAt sql.NullTime
+	if err := row.Scan(&k.ID, &k.UID, &k.Content, &k.Title, &k.Type, &k.Fingerprint, &expiresAt, &lastUsedAt, &k.CreatedAt, &k.UpdatedAt); err != nil {
 		return nil, err
 	}
+	k.ExpiresAt = expiresAt.Time
+	k.LastUsedAt = lastUsedAt.Time
 	return &k, nil
 }
 
+func (d *database) SearchKey(ctx context.Context, fingerprint string) (*Key, error) {
+	return scanKey(d.QueryRowContext(ctx, "select "+keyColumns+" from ssh_keys where fingerprint =?", fingerprint))
+}
+
 func (d *database) FindKey(ctx context.Context, id int64) (*Key, error) {
-	var k Key
-	if err := d.QueryRowContext(ctx, "select id, uid, content, title, type, fingerprint, created_at, updated_at from ssh_keys where id =?",
-		id).Scan(&k.ID, &k.UID, &k.Content, &k.Title, &k.Type, &k.Fingerprint, &k.CreatedAt, &k.UpdatedAt); err != nil {
-		return nil, err
-	}
-	return &k, nil
+	return scanKey(d.QueryRowContext(ctx, "select "+keyColumns+" from ssh_keys where id =?", id))
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
 }
 
 func (d *database) AddKey(ctx context.Context, k *Key) (*Key, error) {
 	now := time.Now()
-	_, err := d.ExecContext(ctx, "insert into ssh_keys(uid, content, title, type, fingerprint, created_at, updated_at) values(?,?,?,?,?,?,?)",
-		k.UID, k.Content, k.Title, k.Type, k.Fingerprint, now, now)
+	_, err := d.ExecContext(ctx, "insert into ssh_keys(uid, content, title, type, fingerprint, expires_at, created_at, updated_at) values(?,?,?,?,?,?,?,?)",
+		k.UID, k.Content, k.Title, k.Type, k.Fingerprint, nullTime(k.ExpiresAt), now, now)
 	if IsDupEntry(err) {
 		return nil, &ErrExist{message: "key already exists"}
 	}
@@ -41,13 +51,58 @@ func (d *database) AddKey(ctx context.Context, k *Key) (*Key, error) {
 	return d.SearchKey(ctx, k.Fingerprint)
 }
 
-func (d *database) IsDeployKeyEnabled(ctx context.Context, rid int64, kid int64) (bool, error) {
-	var id int64
-	if err := d.QueryRowContext(ctx, "select id from deploy_keys_repositories where rid=? and kid=?", rid, kid).Scan(&id); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return false, nil
-		}
-		return false, err
+// DeleteKey removes the key and the repositories it is enabled for.
+func (d *database) DeleteKey(ctx context.Context, id int64) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("new tx error: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "delete from deploy_keys_repositories where kid = ?", id); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	result, err := tx.ExecContext(ctx, "delete from ssh_keys where id = ?", id)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		_ = tx.Rollback()
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// TouchKey records the key was used, updated_at is kept.
+func (d *database) TouchKey(ctx context.Context, id int64) error {
+	_, err := d.ExecContext(ctx, "update ssh_keys set last_used_at = ?, updated_at = updated_at where id = ?", time.Now(), id)
+	return err
+}
+
+func splitCommands(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func (d *database) FindDeployKeyRepository(ctx context.Context, rid int64, kid int64) (*DeployKeyRepository, error) {
+	var dk DeployKeyRepository
+	var commands string
+	if err := d.QueryRowContext(ctx, "select id, kid, rid, commands, created_at, updated_at from deploy_keys_repositories where rid=? and kid=?", rid, kid).
+		Scan(&dk.ID, &dk.KID, &dk.RID, &commands, &dk.CreatedAt, &dk.UpdatedAt); err != nil {
+		return nil, err
+	}
+	dk.Commands = splitCommands(commands)
+	return &dk, nil
+}
+
+// EnableDeployKey enables the deploy key for the repository, commands of an enabled key are replaced.
+func (d *database) EnableDeployKey(ctx context.Context, kid, rid int64, commands []string) (*DeployKeyRepository, error) {
+	now := time.Now()
+	if _, err := d.ExecContext(ctx, "insert into deploy_keys_repositories(kid, rid, commands, created_at, updated_at) values(?,?,?,?,?) on duplicate key update commands = values(commands), updated_at = values(updated_at)",
+		kid, rid, strings.Join(commands, ","), now, now); err != nil {
+		return nil, err
 	}
-	return true, nil
+	return d.FindDeployKeyRepository(ctx, rid, kid)
 }
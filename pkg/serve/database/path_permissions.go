@@ -0,0 +1,49 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PathPermissions returns the dirs the user is allowed to see and modify in the repository, no rows means the user
+// is not restricted.
+func (d *database) PathPermissions(ctx context.Context, rid, uid int64) ([]string, error) {
+	rows, err := d.QueryContext(ctx, "select path from path_permissions where rid = ? and uid = ? order by path", rid, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// SetPathPermissions replaces the dirs of the user, empty paths removes the restriction.
+func (d *database) SetPathPermissions(ctx context.Context, rid, uid int64, paths []string) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("new tx error: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "delete from path_permissions where rid = ? and uid = ?", rid, uid); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	now := time.Now()
+	for _, p := range paths {
+		if _, err := tx.ExecContext(ctx, "insert into path_permissions(rid, uid, path, created_at, updated_at) values(?,?,?,?,?)", rid, uid, p, now, now); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -24,3 +24,40 @@ func (d *database) FindOrdinaryReference(ctx context.Context, rid int64, refname
 	}
 	return nil, err
 }
+
+// ListReferences returns the branches, tags and other references of the repository, sorted by name.
+func (d *database) ListReferences(ctx context.Context, rid int64) ([]*Reference, error) {
+	queries := []struct {
+		query  string
+		prefix string
+	}{
+		{"select id, name, hash, protection_level, created_at, updated_at from branches where rid = ? order by name", plumbing.ReferencePrefix + "heads/"},
+		{"select id, name, hash, 0, created_at, updated_at from tags where rid = ? order by name", plumbing.ReferencePrefix + "tags/"},
+		{"select id, name, hash, 0, created_at, updated_at from refs where rid = ? order by name", ""},
+	}
+	refs := make([]*Reference, 0, 16)
+	for _, q := range queries {
+		rows, err := d.QueryContext(ctx, q.query, rid)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			ref := &Reference{RID: rid}
+			var name string
+			if err := rows.Scan(&ref.ID, &name, &ref.Hash, &ref.ProtectionLevel, &ref.CreatedAt, &ref.UpdatedAt); err != nil {
+				_ = rows.Close()
+				return nil, err
+			}
+			ref.Name = plumbing.ReferenceName(q.prefix + name)
+			ref.CreatedAt = ref.CreatedAt.Local()
+			ref.UpdatedAt = ref.UpdatedAt.Local()
+			refs = append(refs, ref)
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,143 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+// ReferenceLog: an entry of the append-only reference log of a repository. Each entry contains the hash of the
+// previous one, the first entry of a repository follows ZERO_OID. When the server has a reference key, the entry
+// hash is signed with ed25519. Updates of the symbolic reference HEAD record the old and new branch in OldTarget and
+// NewTarget, OldRev and NewRev are the commits of these branches.
+type ReferenceLog struct {
+	RID       int64                  `json:"rid"`
+	Seq       int64                  `json:"seq"` // 1, 2, 3 ... in each repository
+	Name      plumbing.ReferenceName `json:"name"`
+	OldRev    string                 `json:"old_rev"`
+	NewRev    string                 `json:"new_rev"`
+	OldTarget string                 `json:"old_target,omitempty"`
+	NewTarget string                 `json:"new_target,omitempty"`
+	UID       int64                  `json:"uid"` // actor
+	PrevHash  string                 `json:"prev_hash"`
+	Hash      string                 `json:"hash"`
+	Signature string                 `json:"signature,omitempty"` // base64 ed25519 signature of the raw hash
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Sum returns the hash of the entry, CreatedAt has a precision of seconds. Targets are hashed only when set, so the
+// hash of entries of ordinary references does not change.
+func (e *ReferenceLog) Sum() plumbing.Hash {
+	h := plumbing.NewHasher()
+	_, _ = fmt.Fprintf(h, "rid %d\nseq %d\nname %s\nold %s\nnew %s\nuid %d\ntime %d\nprev %s\n",
+		e.RID, e.Seq, e.Name, e.OldRev, e.NewRev, e.UID, e.CreatedAt.Unix(), e.PrevHash)
+	if len(e.OldTarget) != 0 || len(e.NewTarget) != 0 {
+		_, _ = fmt.Fprintf(h, "old-target %s\nnew-target %s\n", e.OldTarget, e.NewTarget)
+	}
+	return h.Sum()
+}
+
+type ErrReferenceLogBroken struct {
+	Seq    int64
+	Reason string
+}
+
+func (e *ErrReferenceLogBroken) Error() string {
+	return fmt.Sprintf("reference log broken at %d: %s", e.Seq, e.Reason)
+}
+
+// VerifyReferenceLogs verifies that entries continue the chain after the entry (seq, hash); use 0 and ZERO_OID
+// for the first entry of a repository. When pub is not nil, entries after the first signed entry must be signed,
+// signed reports whether the chain is signed after entries.
+func VerifyReferenceLogs(seq int64, hash string, signed bool, entries []*ReferenceLog, pub ed25519.PublicKey) (bool, error) {
+	for _, e := range entries {
+		if e.Seq != seq+1 {
+			return signed, &ErrReferenceLogBroken{Seq: e.Seq, Reason: fmt.Sprintf("expected seq %d", seq+1)}
+		}
+		if e.PrevHash != hash {
+			return signed, &ErrReferenceLogBroken{Seq: e.Seq, Reason: fmt.Sprintf("previous hash mismatch, expected %s", hash)}
+		}
+		sum := e.Sum()
+		if sum.String() != e.Hash {
+			return signed, &ErrReferenceLogBroken{Seq: e.Seq, Reason: "hash mismatch"}
+		}
+		if pub != nil {
+			switch {
+			case len(e.Signature) != 0:
+				sig, err := base64.StdEncoding.DecodeString(e.Signature)
+				if err != nil || !ed25519.Verify(pub, sum[:], sig) {
+					return signed, &ErrReferenceLogBroken{Seq: e.Seq, Reason: "bad signature"}
+				}
+				signed = true
+			case signed:
+				return signed, &ErrReferenceLogBroken{Seq: e.Seq, Reason: "signature missing"}
+			}
+		}
+		seq, hash = e.Seq, e.Hash
+	}
+	return signed, nil
+}
+
+// appendReferenceLog appends the reference update to the log in the transaction of the update. Appends of a
+// repository are serialized by locking the repository row.
+func (d *database) appendReferenceLog(ctx context.Context, tx *sql.Tx, e *ReferenceLog) error {
+	var id int64
+	if err := tx.QueryRowContext(ctx, "select id from repositories where id = ? for update", e.RID).Scan(&id); err != nil {
+		return fmt.Errorf("lock repository error: %w", err)
+	}
+	e.Seq, e.PrevHash = 1, plumbing.ZERO_OID
+	var seq int64
+	var prevHash string
+	switch err := tx.QueryRowContext(ctx, "select seq, hash from reference_logs where rid = ? order by seq desc limit 1", e.RID).Scan(&seq, &prevHash); {
+	case err == nil:
+		e.Seq, e.PrevHash = seq+1, prevHash
+	case err != sql.ErrNoRows:
+		return err
+	}
+	e.CreatedAt = time.Now().Truncate(time.Second)
+	sum := e.Sum()
+	e.Hash = sum.String()
+	if d.referenceKey != nil {
+		e.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(d.referenceKey, sum[:]))
+	}
+	if _, err := tx.ExecContext(ctx, "insert into reference_logs(rid, seq, name, old_rev, new_rev, old_target, new_target, uid, prev_hash, hash, signature, created_at) values(?,?,?,?,?,?,?,?,?,?,?,?)",
+		e.RID, e.Seq, e.Name, e.OldRev, e.NewRev, e.OldTarget, e.NewTarget, e.UID, e.PrevHash, e.Hash, e.Signature, e.CreatedAt); err != nil {
+		return fmt.Errorf("append reference log error: %w", err)
+	}
+	return nil
+}
+
+// ReferenceLogs returns at most limit entries of the repository after seq.
+func (d *database) ReferenceLogs(ctx context.Context, rid int64, after int64, limit int) ([]*ReferenceLog, error) {
+	rows, err := d.QueryContext(ctx, "select seq, name, old_rev, new_rev, old_target, new_target, uid, prev_hash, hash, signature, created_at from reference_logs where rid = ? and seq > ? order by seq limit ?",
+		rid, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint
+	entries := make([]*ReferenceLog, 0, min(limit, 100))
+	for rows.Next() {
+		e := &ReferenceLog{RID: rid}
+		if err := rows.Scan(&e.Seq, &e.Name, &e.OldRev, &e.NewRev, &e.OldTarget, &e.NewTarget, &e.UID, &e.PrevHash, &e.Hash, &e.Signature, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = e.CreatedAt.Local()
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (d *database) ReferencePublicKey() ed25519.PublicKey {
+	if d.referenceKey == nil {
+		return nil
+	}
+	return d.referenceKey.Public().(ed25519.PublicKey)
+}
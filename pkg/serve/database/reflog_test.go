@@ -0,0 +1,115 @@
+package database
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+func hashString(s string) string {
+	h := plumbing.NewHasher()
+	_, _ = h.Write([]byte(s))
+	return h.Sum().String()
+}
+
+func newReferenceLogs(key ed25519.PrivateKey, n int) []*ReferenceLog {
+	entries := make([]*ReferenceLog, 0, n)
+	prev := plumbing.ZERO_OID
+	now := time.Now().Truncate(time.Second)
+	for i := range n {
+		e := &ReferenceLog{
+			RID:       1,
+			Seq:       int64(i + 1),
+			Name:      plumbing.NewBranchReferenceName("mainline"),
+			OldRev:    prev,
+			NewRev:    hashString(string(rune('a' + i))),
+			UID:       2,
+			PrevHash:  prev,
+			CreatedAt: now.Add(time.Duration(i) * time.Second),
+		}
+		sum := e.Sum()
+		e.Hash = sum.String()
+		if key != nil {
+			e.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, sum[:]))
+		}
+		prev = e.Hash
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func brokenAt(err error) int64 {
+	if e, ok := errors.AsType[*ErrReferenceLogBroken](err); ok {
+		return e.Seq
+	}
+	return -1
+}
+
+func TestVerifyReferenceLogs(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := newReferenceLogs(key, 5)
+	if signed, err := VerifyReferenceLogs(0, plumbing.ZERO_OID, false, entries, pub); err != nil || !signed {
+		t.Fatalf("verify signed chain: %v %v", signed, err)
+	}
+	// verify by pages
+	signed, err := VerifyReferenceLogs(0, plumbing.ZERO_OID, false, entries[:2], pub)
+	if err == nil {
+		_, err = VerifyReferenceLogs(2, entries[1].Hash, signed, entries[2:], pub)
+	}
+	if err != nil {
+		t.Fatalf("verify pages: %v", err)
+	}
+	if _, err := VerifyReferenceLogs(0, plumbing.ZERO_OID, false, newReferenceLogs(nil, 3), nil); err != nil {
+		t.Fatalf("verify unsigned chain: %v", err)
+	}
+
+	tampered := newReferenceLogs(key, 5)
+	tampered[2].NewRev = hashString("evil")
+	if _, err := VerifyReferenceLogs(0, plumbing.ZERO_OID, false, tampered, pub); brokenAt(err) != 3 {
+		t.Fatalf("tampered entry: %v", err)
+	}
+	removed := newReferenceLogs(key, 5)
+	removed = append(removed[:1], removed[2:]...)
+	if _, err := VerifyReferenceLogs(0, plumbing.ZERO_OID, false, removed, pub); brokenAt(err) != 3 {
+		t.Fatalf("removed entry: %v", err)
+	}
+	other, otherKey, _ := ed25519.GenerateKey(nil)
+	if _, err := VerifyReferenceLogs(0, plumbing.ZERO_OID, false, newReferenceLogs(otherKey, 2), pub); brokenAt(err) != 1 {
+		t.Fatalf("bad signature: %v", err)
+	}
+	if _, err := VerifyReferenceLogs(0, plumbing.ZERO_OID, false, entries, other); brokenAt(err) != 1 {
+		t.Fatalf("other public key: %v", err)
+	}
+	unsigned := newReferenceLogs(key, 3)
+	unsigned[2].Signature = ""
+	if _, err := VerifyReferenceLogs(0, plumbing.ZERO_OID, false, unsigned, pub); brokenAt(err) != 3 {
+		t.Fatalf("signature missing: %v", err)
+	}
+}
+
+func TestReferenceLogTargets(t *testing.T) {
+	e := &ReferenceLog{RID: 1, Seq: 1, Name: plumbing.HEAD, OldRev: hashString("a"), NewRev: hashString("b"), UID: 2,
+		PrevHash: plumbing.ZERO_OID, CreatedAt: time.Now().Truncate(time.Second)}
+	plain := e.Sum()
+	e.OldTarget, e.NewTarget = "refs/heads/mainline", "refs/heads/"+strings.Repeat("long-branch-name/", 8)
+	targeted := e.Sum()
+	if plain == targeted {
+		t.Fatalf("targets should be part of the hash")
+	}
+	e.Hash = targeted.String()
+	if _, err := VerifyReferenceLogs(0, plumbing.ZERO_OID, false, []*ReferenceLog{e}, nil); err != nil {
+		t.Fatalf("verify symbolic entry: %v", err)
+	}
+	e.NewTarget = "refs/heads/evil"
+	if _, err := VerifyReferenceLogs(0, plumbing.ZERO_OID, false, []*ReferenceLog{e}, nil); brokenAt(err) != 1 {
+		t.Fatalf("tampered target: %v", err)
+	}
+}
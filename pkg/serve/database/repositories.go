@@ -7,7 +7,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
 )
 
 const (
@@ -135,3 +138,48 @@ func (d *database) NewRepository(ctx context.Context, r *Repository) (*Repositor
 		CreatedAt:       now,
 	}, nil
 }
+
+// SetDefaultBranch changes the default branch of the repository, the branch must exist. The change is recorded in
+// the reference log as an update of HEAD from the old branch reference to the new one, the revisions are the commits
+// of the branches, the old branch may no longer exist.
+func (d *database) SetDefaultBranch(ctx context.Context, rid, uid int64, branchName string) (string, error) {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("new tx error: %w", err)
+	}
+	var oldBranch string
+	if err := tx.QueryRowContext(ctx, "select default_branch from repositories where id = ? for update", rid).Scan(&oldBranch); err != nil {
+		_ = tx.Rollback()
+		return "", err
+	}
+	var newRev string
+	if err := tx.QueryRowContext(ctx, "select hash from branches where rid = ? and name = ?", rid, branchName).Scan(&newRev); err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", &ErrRevisionNotFound{Revision: string(plumbing.NewBranchReferenceName(branchName))}
+		}
+		return "", err
+	}
+	if oldBranch == branchName {
+		_ = tx.Rollback()
+		return oldBranch, nil
+	}
+	oldRev := plumbing.ZERO_OID
+	if err := tx.QueryRowContext(ctx, "select hash from branches where rid = ? and name = ?", rid, oldBranch).Scan(&oldRev); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, "update repositories set default_branch = ?, updated_at = ? where id = ?", branchName, time.Now(), rid); err != nil {
+		_ = tx.Rollback()
+		return "", err
+	}
+	if err := d.appendReferenceLog(ctx, tx, &ReferenceLog{RID: rid, Name: plumbing.HEAD, OldRev: oldRev, NewRev: newRev,
+		OldTarget: string(plumbing.NewBranchReferenceName(oldBranch)), NewTarget: string(plumbing.NewBranchReferenceName(branchName)), UID: uid}); err != nil {
+		_ = tx.Rollback()
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return oldBranch, nil
+}
@@ -0,0 +1,82 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	templateColumns = "t.id, t.namespace_id, coalesce(n.path, ''), t.kind, t.content, t.version, t.created_at, t.updated_at"
+	templateTables  = "templates t left join namespaces n on n.id = t.namespace_id"
+)
+
+func scanTemplates(rows *sql.Rows) ([]*Template, error) {
+	defer rows.Close() // nolint
+	templates := make([]*Template, 0, 4)
+	for rows.Next() {
+		var t Template
+		if err := rows.Scan(&t.ID, &t.NamespaceID, &t.Namespace, &t.Kind, &t.Content, &t.Version, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, &t)
+	}
+	return templates, rows.Err()
+}
+
+// Templates returns the templates of all namespaces followed by the templates of the namespace.
+func (d *database) Templates(ctx context.Context, namespaceID int64) ([]*Template, error) {
+	rows, err := d.QueryContext(ctx, "select "+templateColumns+" from "+templateTables+" where t.namespace_id in (0, ?) order by t.namespace_id, t.kind", namespaceID)
+	if err != nil {
+		return nil, err
+	}
+	return scanTemplates(rows)
+}
+
+// ListTemplates returns all templates, used to export them.
+func (d *database) ListTemplates(ctx context.Context) ([]*Template, error) {
+	rows, err := d.QueryContext(ctx, "select "+templateColumns+" from "+templateTables+" order by t.namespace_id, t.kind")
+	if err != nil {
+		return nil, err
+	}
+	return scanTemplates(rows)
+}
+
+// SetTemplate creates or replaces the template of (namespace, kind), the version is increased only when the content
+// changes so importing the same templates again does not make clients download them.
+func (d *database) SetTemplate(ctx context.Context, t *Template) (*Template, error) {
+	now := time.Now()
+	// version is assigned before content, it still compares with the old content
+	if _, err := d.ExecContext(ctx, `insert into templates(namespace_id, kind, content, version, created_at, updated_at) values(?,?,?,1,?,?)
+on duplicate key update version = version + (content <> values(content)), content = values(content), updated_at = values(updated_at)`,
+		t.NamespaceID, t.Kind, t.Content, now, now); err != nil {
+		return nil, err
+	}
+	rows, err := d.QueryContext(ctx, "select "+templateColumns+" from "+templateTables+" where t.namespace_id = ? and t.kind = ?", t.NamespaceID, t.Kind)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := scanTemplates(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return templates[0], nil
+}
+
+// DeleteTemplate removes the template of (namespace, kind).
+func (d *database) DeleteTemplate(ctx context.Context, namespaceID int64, kind string) error {
+	result, err := d.ExecContext(ctx, "delete from templates where namespace_id = ? and kind = ?", namespaceID, kind)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -47,6 +47,80 @@ type User struct {
 	UpdatedAt      time.Time `json:"updated_at"`
 	Password       string    `json:"-"`
 	SignatureToken string    `json:"-"`
+	// Language: preferred language of the messages, from user_settings, empty when not set
+	Language string `json:"language,omitempty"`
+}
+
+// UserSettings: per-user preferences, the notification endpoints receive push failures on protected branches.
+type UserSettings struct {
+	UID           int64     `json:"uid"`
+	Language      string    `json:"language"`
+	NotifyWebhook string    `json:"notify_webhook"`
+	NotifyEmail   string    `json:"notify_email"`
+	CreatedAt     time.Time `json:"created_at,omitzero"`
+	UpdatedAt     time.Time `json:"updated_at,omitzero"`
+}
+
+const (
+	TemplateIgnore     = "ignore"
+	TemplateAttributes = "attributes"
+)
+
+// Template: organization-wide ignore or attribute template, clients layer it beneath the repository files. Templates
+// of namespace 0 apply to all namespaces.
+type Template struct {
+	ID          int64     `json:"id"`
+	NamespaceID int64     `json:"namespace_id"`
+	Namespace   string    `json:"namespace"`
+	Kind        string    `json:"kind"`
+	Content     string    `json:"content"`
+	Version     int64     `json:"version"`
+	CreatedAt   time.Time `json:"created_at,omitzero"`
+	UpdatedAt   time.Time `json:"updated_at,omitzero"`
+}
+
+// JobStatus: status of a maintenance job.
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// JobHeartbeat: running jobs are refreshed at least once per JobHeartbeat, a running job not refreshed for
+// longer lost its runner.
+const JobHeartbeat = time.Minute
+
+// Job: a maintenance job of a repository, see package jobs.
+type Job struct {
+	ID              int64     `json:"id"`
+	RID             int64     `json:"rid"`
+	UID             int64     `json:"uid"`
+	Kind            string    `json:"kind"`
+	Params          string    `json:"params,omitempty"`
+	Status          JobStatus `json:"status"`
+	Stage           string    `json:"stage,omitempty"`
+	Current         int64     `json:"current"`
+	Total           int64     `json:"total"`
+	Message         string    `json:"message,omitempty"`
+	CancelRequested bool      `json:"cancel_requested"`
+	Runner          string    `json:"runner,omitempty"`
+	CreatedAt       time.Time `json:"created_at,omitzero"`
+	UpdatedAt       time.Time `json:"updated_at,omitzero"`
+	FinishedAt      time.Time `json:"finished_at,omitzero"`
+}
+
+// Finished reports whether the job is no longer running.
+func (j *Job) Finished() bool {
+	return j.Status != JobRunning
+}
+
+// Orphaned reports whether the job is still running but its runner stopped refreshing it, the process running
+// it probably exited without finishing it.
+func (j *Job) Orphaned(now time.Time) bool {
+	return j.Status == JobRunning && now.Sub(j.UpdatedAt) > 2*JobHeartbeat
 }
 
 func (u *User) Guard() {
@@ -195,10 +269,29 @@ type Key struct {
 	Title       string    `json:"title"`
 	Type        KeyType   `json:"type"`
 	Fingerprint string    `json:"fingerprint"`
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`   // zero: never expires
+	LastUsedAt  time.Time `json:"last_used_at,omitzero"` // zero: never used
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// Expired: the key is rejected when it expired.
+func (k *Key) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// DeployKeyRepository: a deploy key is only usable in the repositories it is enabled for, and only runs the
+// commands allowed there.
+type DeployKeyRepository struct {
+	ID  int64 `json:"id"`
+	KID int64 `json:"kid"`
+	RID int64 `json:"rid"`
+	// Commands: SSH commands allowed, empty allows only the download commands
+	Commands  []string  `json:"commands,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 type MemberType int
 
 const (
@@ -9,7 +9,7 @@ import (
 	"github.com/antgroup/hugescm/modules/plumbing"
 )
 
-func (d *database) doRemoveBranch(ctx context.Context, rid int64, branchName string) (*Branch, error) {
+func (d *database) doRemoveBranch(ctx context.Context, rid, uid int64, branchName string) (*Branch, error) {
 	tx, err := d.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("new tx error: %w", err)
@@ -38,13 +38,17 @@ func (d *database) doRemoveBranch(ctx context.Context, rid int64, branchName str
 		_ = tx.Rollback()
 		return nil, &ErrAlreadyLocked{Reference: string(plumbing.NewBranchReferenceName(branchName))}
 	}
+	if err := d.appendReferenceLog(ctx, tx, &ReferenceLog{RID: rid, Name: plumbing.NewBranchReferenceName(branchName), OldRev: oldRev, NewRev: plumbing.ZERO_OID, UID: uid}); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 	return &Branch{Name: branchName, ID: branchID, RID: rid, Hash: oldRev, CreatedAt: createdAt.Local(), UpdatedAt: updateAt.Local()}, nil
 }
 
-func (d *database) doCreateBranch(ctx context.Context, rid int64, branchName string, newRev string) (*Branch, error) {
+func (d *database) doCreateBranch(ctx context.Context, rid, uid int64, branchName string, newRev string) (*Branch, error) {
 	now := time.Now()
 	tx, err := d.BeginTx(ctx, nil)
 	if err != nil {
@@ -68,6 +72,10 @@ func (d *database) doCreateBranch(ctx context.Context, rid int64, branchName str
 		_ = tx.Rollback()
 		return nil, &ErrAlreadyLocked{Reference: string(plumbing.NewBranchReferenceName(branchName))}
 	}
+	if err := d.appendReferenceLog(ctx, tx, &ReferenceLog{RID: rid, Name: plumbing.NewBranchReferenceName(branchName), OldRev: plumbing.ZERO_OID, NewRev: newRev, UID: uid}); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -78,10 +86,10 @@ func (d *database) doCreateBranch(ctx context.Context, rid int64, branchName str
 func (d *database) DoBranchUpdate(ctx context.Context, cmd *Command) (*Branch, error) {
 	branchName := cmd.ReferenceName.BranchName()
 	if cmd.OldRev == plumbing.ZERO_OID {
-		return d.doCreateBranch(ctx, cmd.RID, branchName, cmd.NewRev)
+		return d.doCreateBranch(ctx, cmd.RID, cmd.UID, branchName, cmd.NewRev)
 	}
 	if cmd.NewRev == plumbing.ZERO_OID {
-		return d.doRemoveBranch(ctx, cmd.RID, branchName)
+		return d.doRemoveBranch(ctx, cmd.RID, cmd.UID, branchName)
 	}
 	tx, err := d.BeginTx(ctx, nil)
 	if err != nil {
@@ -112,6 +120,10 @@ func (d *database) DoBranchUpdate(ctx context.Context, cmd *Command) (*Branch, e
 		_ = tx.Rollback()
 		return nil, &ErrAlreadyLocked{Reference: string(plumbing.NewBranchReferenceName(branchName))}
 	}
+	if err := d.appendReferenceLog(ctx, tx, &ReferenceLog{RID: cmd.RID, Name: plumbing.NewBranchReferenceName(branchName), OldRev: cmd.OldRev, NewRev: cmd.NewRev, UID: cmd.UID}); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -124,7 +136,7 @@ func (d *database) doCreateTag(ctx context.Context, rid, uid int64, tagName stri
 		return nil, fmt.Errorf("new tx error: %w", err)
 	}
 	now := time.Now()
-	result, err := d.ExecContext(ctx, "insert into tags(name, rid, uid, hash, subject, description, created_at, updated_at) values(?,?,?,?,?,?,?,?)",
+	result, err := tx.ExecContext(ctx, "insert into tags(name, rid, uid, hash, subject, description, created_at, updated_at) values(?,?,?,?,?,?,?,?)",
 		tagName, rid, uid, newRev, subject, description, now, now)
 	if IsDupEntry(err) {
 		_ = tx.Rollback()
@@ -143,13 +155,17 @@ func (d *database) doCreateTag(ctx context.Context, rid, uid int64, tagName stri
 		_ = tx.Rollback()
 		return nil, &ErrAlreadyLocked{Reference: string(plumbing.NewTagReferenceName(tagName))}
 	}
+	if err := d.appendReferenceLog(ctx, tx, &ReferenceLog{RID: rid, Name: plumbing.NewTagReferenceName(tagName), OldRev: plumbing.ZERO_OID, NewRev: newRev, UID: uid}); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 	return &Tag{Name: tagName, RID: rid, Hash: newRev, Subject: subject, Description: description, CreatedAt: now, UpdatedAt: now}, nil
 }
 
-func (d *database) doRemoveTag(ctx context.Context, rid int64, tagName string) (*Tag, error) {
+func (d *database) doRemoveTag(ctx context.Context, rid, uid int64, tagName string) (*Tag, error) {
 	tx, err := d.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("new tx error: %w", err)
@@ -177,6 +193,10 @@ func (d *database) doRemoveTag(ctx context.Context, rid int64, tagName string) (
 		_ = tx.Rollback()
 		return nil, &ErrAlreadyLocked{Reference: string(plumbing.NewTagReferenceName(tagName))}
 	}
+	if err := d.appendReferenceLog(ctx, tx, &ReferenceLog{RID: rid, Name: plumbing.NewTagReferenceName(tagName), OldRev: t.Hash, NewRev: plumbing.ZERO_OID, UID: uid}); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -189,7 +209,7 @@ func (d *database) doTagUpdate(ctx context.Context, cmd *Command) (*Tag, error)
 		return d.doCreateTag(ctx, cmd.RID, cmd.UID, tagName, cmd.NewRev, cmd.Subject, cmd.Description)
 	}
 	if cmd.NewRev == plumbing.ZERO_OID {
-		return d.doRemoveTag(ctx, cmd.RID, tagName)
+		return d.doRemoveTag(ctx, cmd.RID, cmd.UID, tagName)
 	}
 	tx, err := d.BeginTx(ctx, nil)
 	if err != nil {
@@ -220,19 +240,23 @@ func (d *database) doTagUpdate(ctx context.Context, cmd *Command) (*Tag, error)
 		_ = tx.Rollback()
 		return nil, &ErrAlreadyLocked{Reference: string(plumbing.NewTagReferenceName(tagName))}
 	}
+	if err := d.appendReferenceLog(ctx, tx, &ReferenceLog{RID: cmd.RID, Name: plumbing.NewTagReferenceName(tagName), OldRev: cmd.OldRev, NewRev: cmd.NewRev, UID: cmd.UID}); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 	return d.FindTag(ctx, cmd.RID, tagName)
 }
 
-func (d *database) doCreateOrdinaryRef(ctx context.Context, rid int64, refname plumbing.ReferenceName, newRev string) (*Reference, error) {
+func (d *database) doCreateOrdinaryRef(ctx context.Context, rid, uid int64, refname plumbing.ReferenceName, newRev string) (*Reference, error) {
 	tx, err := d.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("new tx error: %w", err)
 	}
 	now := time.Now()
-	result, err := d.ExecContext(ctx, "insert into refs(name, rid, hash, created_at, updated_at) values(?,?,?,?,?)", refname, rid, newRev, now, now)
+	result, err := tx.ExecContext(ctx, "insert into refs(name, rid, hash, created_at, updated_at) values(?,?,?,?,?)", refname, rid, newRev, now, now)
 	if IsDupEntry(err) {
 		_ = tx.Rollback()
 		return nil, &ErrAlreadyLocked{Reference: string(refname)}
@@ -250,13 +274,17 @@ func (d *database) doCreateOrdinaryRef(ctx context.Context, rid int64, refname p
 		_ = tx.Rollback()
 		return nil, &ErrAlreadyLocked{Reference: string(refname)}
 	}
+	if err := d.appendReferenceLog(ctx, tx, &ReferenceLog{RID: rid, Name: refname, OldRev: plumbing.ZERO_OID, NewRev: newRev, UID: uid}); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 	return &Reference{Name: refname, RID: rid, Hash: newRev, CreatedAt: now, UpdatedAt: now}, nil
 }
 
-func (d *database) doRemoveOrdinaryRef(ctx context.Context, rid int64, refname plumbing.ReferenceName) (*Reference, error) {
+func (d *database) doRemoveOrdinaryRef(ctx context.Context, rid, uid int64, refname plumbing.ReferenceName) (*Reference, error) {
 	tx, err := d.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("new tx error: %w", err)
@@ -283,6 +311,10 @@ func (d *database) doRemoveOrdinaryRef(ctx context.Context, rid int64, refname p
 		_ = tx.Rollback()
 		return nil, &ErrAlreadyLocked{Reference: string(refname)}
 	}
+	if err := d.appendReferenceLog(ctx, tx, &ReferenceLog{RID: rid, Name: refname, OldRev: ref.Hash, NewRev: plumbing.ZERO_OID, UID: uid}); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -291,10 +323,10 @@ func (d *database) doRemoveOrdinaryRef(ctx context.Context, rid int64, refname p
 
 func (d *database) doOrdinaryRefUpdate(ctx context.Context, cmd *Command) (*Reference, error) {
 	if cmd.OldRev == plumbing.ZERO_OID {
-		return d.doCreateOrdinaryRef(ctx, cmd.RID, cmd.ReferenceName, cmd.NewRev)
+		return d.doCreateOrdinaryRef(ctx, cmd.RID, cmd.UID, cmd.ReferenceName, cmd.NewRev)
 	}
 	if cmd.NewRev == plumbing.ZERO_OID {
-		return d.doRemoveOrdinaryRef(ctx, cmd.RID, cmd.ReferenceName)
+		return d.doRemoveOrdinaryRef(ctx, cmd.RID, cmd.UID, cmd.ReferenceName)
 	}
 
 	tx, err := d.BeginTx(ctx, nil)
@@ -325,6 +357,10 @@ func (d *database) doOrdinaryRefUpdate(ctx context.Context, cmd *Command) (*Refe
 		_ = tx.Rollback()
 		return nil, &ErrAlreadyLocked{Reference: string(cmd.ReferenceName)}
 	}
+	if err := d.appendReferenceLog(ctx, tx, &ReferenceLog{RID: cmd.RID, Name: cmd.ReferenceName, OldRev: cmd.OldRev, NewRev: cmd.NewRev, UID: cmd.UID}); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -12,31 +12,35 @@ import (
 )
 
 const (
-	sqlFindUser = `SELECT    username,
-          name,
-          admin,
-          email,
-		  type,
-          password,
-          signature_token,
-          locked_at,
-          created_at,
-          updated_at
-FROM      users
-WHERE     id = ?`
-	sqlSearchUserByName = `SELECT    id,
-          username,
-          name,
-          admin,
-          email,
-		  type,
-          password,
-          signature_token,
-          locked_at,
-          created_at,
-          updated_at
-FROM      users
-WHERE     username = ?`
+	sqlFindUser = `SELECT    u.username,
+          u.name,
+          u.admin,
+          u.email,
+		  u.type,
+          u.password,
+          u.signature_token,
+          u.locked_at,
+          u.created_at,
+          u.updated_at,
+          IFNULL(s.language, '')
+FROM      users AS u
+LEFT      JOIN user_settings AS s ON s.uid = u.id
+WHERE     u.id = ?`
+	sqlSearchUserByName = `SELECT    u.id,
+          u.username,
+          u.name,
+          u.admin,
+          u.email,
+		  u.type,
+          u.password,
+          u.signature_token,
+          u.locked_at,
+          u.created_at,
+          u.updated_at,
+          IFNULL(s.language, '')
+FROM      users AS u
+LEFT      JOIN user_settings AS s ON s.uid = u.id
+WHERE     u.username = ?`
 	sqlSearchUserByEmail = `SELECT    u.id,
           u.username,
           u.name,
@@ -47,9 +51,11 @@ WHERE     username = ?`
           u.signature_token,
           u.locked_at,
           u.created_at,
-          u.updated_at
+          u.updated_at,
+          IFNULL(s.language, '')
 FROM      users AS u
 INNER     JOIN emails AS e
+LEFT      JOIN user_settings AS s ON s.uid = u.id
 WHERE     e.email = ?
 AND       e.confirmed_at IS NOT NULL
 AND       u.id = e.uid`
@@ -65,7 +71,7 @@ func (d *database) FindUser(ctx context.Context, uid int64) (*User, error) {
 	}
 	var lockedAt sql.NullTime
 	if err := d.QueryRowContext(ctx, sqlFindUser, uid).Scan(
-		&u.UserName, &u.Name, &u.Administrator, &u.Email, &u.Type, &u.Password, &u.SignatureToken, &lockedAt, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		&u.UserName, &u.Name, &u.Administrator, &u.Email, &u.Type, &u.Password, &u.SignatureToken, &lockedAt, &u.CreatedAt, &u.UpdatedAt, &u.Language); err != nil {
 		return nil, err
 	}
 	u.LockedAt = lockedAt.Time
@@ -77,7 +83,7 @@ func (d *database) SearchUser(ctx context.Context, emailOrName string) (*User, e
 	if strings.Contains(emailOrName, "@") {
 		var u User
 		if err := d.QueryRowContext(ctx, sqlSearchUserByEmail, emailOrName).Scan(
-			&u.ID, &u.UserName, &u.Name, &u.Administrator, &u.Email, &u.Type, &u.Password, &u.SignatureToken, &lockedAt, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			&u.ID, &u.UserName, &u.Name, &u.Administrator, &u.Email, &u.Type, &u.Password, &u.SignatureToken, &lockedAt, &u.CreatedAt, &u.UpdatedAt, &u.Language); err != nil {
 			return nil, err
 		}
 		u.LockedAt = lockedAt.Time
@@ -85,7 +91,7 @@ func (d *database) SearchUser(ctx context.Context, emailOrName string) (*User, e
 	}
 	var u User
 	if err := d.QueryRowContext(ctx, sqlSearchUserByName, emailOrName).Scan(
-		&u.ID, &u.UserName, &u.Name, &u.Administrator, &u.Email, &u.Type, &u.Password, &u.SignatureToken, &lockedAt, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		&u.ID, &u.UserName, &u.Name, &u.Administrator, &u.Email, &u.Type, &u.Password, &u.SignatureToken, &lockedAt, &u.CreatedAt, &u.UpdatedAt, &u.Language); err != nil {
 		return nil, err
 	}
 	u.LockedAt = lockedAt.Time
@@ -0,0 +1,36 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// UserSettings returns the settings of the user, users without settings get empty settings.
+func (d *database) UserSettings(ctx context.Context, uid int64) (*UserSettings, error) {
+	s := &UserSettings{UID: uid}
+	err := d.QueryRowContext(ctx, "select language, notify_webhook, notify_email, created_at, updated_at from user_settings where uid = ?", uid).
+		Scan(&s.Language, &s.NotifyWebhook, &s.NotifyEmail, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetUserSettings creates or replaces the settings of the user.
+func (d *database) SetUserSettings(ctx context.Context, s *UserSettings) (*UserSettings, error) {
+	now := time.Now()
+	if _, err := d.ExecContext(ctx, `insert into user_settings(uid, language, notify_webhook, notify_email, created_at, updated_at) values(?,?,?,?,?,?)
+on duplicate key update language = values(language), notify_webhook = values(notify_webhook), notify_email = values(notify_email), updated_at = values(updated_at)`,
+		s.UID, s.Language, s.NotifyWebhook, s.NotifyEmail, now, now); err != nil {
+		return nil, err
+	}
+	return d.UserSettings(ctx, s.UID)
+}
@@ -25,6 +25,25 @@ CREATE TABLE
         KEY `idx_refs_rid` (`rid`) LOCAL
     ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '引用表';
 
+CREATE TABLE
+    `reference_logs` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `rid` bigint (20) unsigned NOT NULL comment '存储库 ID',
+        `seq` bigint (20) unsigned NOT NULL comment '存储库内的序号，从 1 开始',
+        `name` varchar(4096) NOT NULL DEFAULT '' comment '引用全名',
+        `old_rev` char(64) NOT NULL DEFAULT '' comment '更新前的提交',
+        `new_rev` char(64) NOT NULL DEFAULT '' comment '更新后的提交',
+        `old_target` varchar(4096) NOT NULL DEFAULT '' comment '符号引用更新前指向的引用',
+        `new_target` varchar(4096) NOT NULL DEFAULT '' comment '符号引用更新后指向的引用',
+        `uid` bigint (20) unsigned NOT NULL DEFAULT '0' comment '操作用户 ID',
+        `prev_hash` char(64) NOT NULL DEFAULT '' comment '上一条日志的哈希值',
+        `hash` char(64) NOT NULL DEFAULT '' comment '日志哈希值',
+        `signature` varchar(256) NOT NULL DEFAULT '' comment 'ed25519 签名（base64）',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        PRIMARY KEY (`id`),
+        UNIQUE KEY `uk_reference_logs_rid_seq` (`rid`, `seq`) LOCAL
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '引用日志表';
+
 CREATE TABLE
     `objects` (
         `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
@@ -152,6 +171,80 @@ CREATE TABLE
         KEY `idx_members_uid` (`uid`) LOCAL
     ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '仓库成员表';
 
+CREATE TABLE
+    `path_permissions` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `rid` bigint (20) unsigned NOT NULL comment '存储库 ID',
+        `uid` bigint (20) unsigned NOT NULL comment '用户 ID',
+        `path` varchar(1024) NOT NULL comment '允许访问的目录，用户没有任何记录时不受限制',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP comment '修改时间',
+        PRIMARY KEY (`id`),
+        UNIQUE KEY `uk_path_permissions_rid_uid_path` (`rid`, `uid`, `path`) LOCAL,
+        KEY `idx_path_permissions_rid` (`rid`) LOCAL
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '目录级访问权限表';
+
+CREATE TABLE
+    `user_settings` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `uid` bigint (20) unsigned NOT NULL comment '用户 ID',
+        `language` varchar(64) NOT NULL DEFAULT '' comment '错误信息等消息的首选语言，为空时根据请求头选择，eg: zh-CN',
+        `notify_webhook` varchar(1024) NOT NULL DEFAULT '' comment '受保护分支推送失败时通知的 Webhook 地址',
+        `notify_email` varchar(255) NOT NULL DEFAULT '' comment '受保护分支推送失败时通知的邮箱，随 Webhook 一并发送',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP comment '修改时间',
+        PRIMARY KEY (`id`),
+        UNIQUE KEY `uk_user_settings_uid` (`uid`) LOCAL
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '用户设置表';
+
+-- templates table
+CREATE TABLE
+    `templates` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `namespace_id` bigint (20) unsigned NOT NULL DEFAULT '0' comment '命名空间 ID，0 表示所有命名空间',
+        `kind` varchar(32) NOT NULL comment '模板类型，ignore 或 attributes',
+        `content` mediumtext NOT NULL comment '模板内容',
+        `version` bigint (20) unsigned NOT NULL DEFAULT '1' comment '版本，内容每次修改加一',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP comment '修改时间',
+        PRIMARY KEY (`id`),
+        UNIQUE KEY `uk_templates_namespace_id_kind` (`namespace_id`, `kind`) LOCAL
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '组织级忽略和属性模板表';
+
+-- jobs table
+CREATE TABLE
+    `jobs` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `rid` bigint (20) unsigned NOT NULL comment '存储库 ID',
+        `uid` bigint (20) unsigned NOT NULL DEFAULT '0' comment '发起任务的用户 ID，命令行发起时为 0',
+        `kind` varchar(64) NOT NULL comment '任务类型，eg: fsck, gc, backup',
+        `params` text NOT NULL comment '任务参数，JSON 格式',
+        `status` varchar(32) NOT NULL DEFAULT 'running' comment '任务状态，running, succeeded, failed, canceled',
+        `stage` varchar(255) NOT NULL DEFAULT '' comment '当前阶段',
+        `current` bigint (20) NOT NULL DEFAULT '0' comment '当前阶段已完成的数量',
+        `total` bigint (20) NOT NULL DEFAULT '0' comment '当前阶段的总数量，为 0 时未知',
+        `message` text NOT NULL comment '任务结果或错误信息',
+        `cancel_requested` tinyint (1) NOT NULL DEFAULT '0' comment '是否已请求取消，执行者定期检查并停止任务',
+        `runner` varchar(255) NOT NULL DEFAULT '' comment '执行者，主机名:进程 ID',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP comment '修改时间，运行中的任务定期刷新',
+        `finished_at` timestamp NULL DEFAULT NULL comment '结束时间',
+        PRIMARY KEY (`id`),
+        KEY `idx_jobs_rid` (`rid`) LOCAL
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '维护任务表';
+
+-- object_access table
+CREATE TABLE
+    `object_access` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `rid` bigint (20) unsigned NOT NULL comment '存储库 ID',
+        `oid` char(64) NOT NULL comment '大文件哈希值',
+        `accessed_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '最后访问时间，每个进程每天最多更新一次',
+        PRIMARY KEY (`id`),
+        UNIQUE KEY `uk_object_access_rid_oid` (`rid`, `oid`) LOCAL,
+        KEY `idx_object_access_rid_accessed_at` (`rid`, `accessed_at`) LOCAL
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '大文件访问记录，用于分层存储';
+
 -- emails table
 CREATE TABLE
     `emails` (
@@ -176,6 +269,8 @@ CREATE TABLE
         `title` varchar(255) NOT NULL COMMENT '标题',
         `type` tinyint (4) NOT NULL DEFAULT '0' COMMENT '公钥类型，0 用户公钥，1 部署公钥',
         `fingerprint` varchar(255) NOT NULL COMMENT '指纹',
+        `expires_at` timestamp NULL DEFAULT NULL COMMENT '过期时间，为空时永不过期',
+        `last_used_at` timestamp NULL DEFAULT NULL COMMENT '最后使用时间',
         `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
         `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP comment '修改时间',
         PRIMARY KEY (`id`),
@@ -188,6 +283,7 @@ CREATE TABLE
         `id` bigint (20) NOT NULL AUTO_INCREMENT COMMENT '主键',
         `kid` bigint (20) unsigned NOT NULL COMMENT '公钥 ID',
         `rid` bigint (20) unsigned NOT NULL COMMENT '存储库 ID',
+        `commands` varchar(255) NOT NULL DEFAULT '' COMMENT '允许执行的 SSH 命令，逗号分隔，为空时只允许下载命令',
         `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
         `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP comment '修改时间',
         PRIMARY KEY (`id`),
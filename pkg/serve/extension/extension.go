@@ -0,0 +1,147 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package extension is the plugin API of zeta-serve. An extension observes or vetoes pushes, augments
+// authorization decisions and reacts to repository lifecycle events. Extensions are compiled into the server
+// with Register and enabled by the [[extensions]] tables of the configuration, or run out of process as a
+// sidecar speaking JSON over HTTP.
+package extension
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+)
+
+// Extension is implemented by every extension, it also implements one or more of PushChecker, PushObserver,
+// Authorizer and RepositoryObserver.
+type Extension interface {
+	Name() string
+}
+
+type PushEvent struct {
+	RID           int64    `json:"rid"`
+	UID           int64    `json:"uid"`
+	ReferenceName string   `json:"reference_name"`
+	OldRev        string   `json:"old_rev"`
+	NewRev        string   `json:"new_rev"`
+	Commits       []string `json:"commits,omitempty"` // commits received by the push, empty when deleting
+	DryRun        bool     `json:"dry_run,omitempty"` // zeta push --dry-run, the reference is not updated
+}
+
+// PushChecker checks a push before the reference is updated, a non-nil error rejects the push. Return a
+// *Veto to reject it with a message shown to the user.
+type PushChecker interface {
+	CheckPush(ctx context.Context, e *PushEvent) error
+}
+
+// PushObserver is notified after the reference is updated.
+type PushObserver interface {
+	OnPush(ctx context.Context, e *PushEvent)
+}
+
+type AccessRequest struct {
+	RID         int64              `json:"rid"`
+	UID         int64              `json:"uid"`
+	UserName    string             `json:"user_name"`
+	Repository  string             `json:"repository"` // namespace/repo
+	Operation   protocol.Operation `json:"operation"`
+	AccessLevel int                `json:"access_level"` // access level of the user in the repository
+	Allowed     bool               `json:"allowed"`      // decision of the built-in rules
+}
+
+type Decision int
+
+const (
+	Abstain Decision = iota // keep the built-in decision
+	Allow
+	Deny
+)
+
+func (d Decision) String() string {
+	switch d {
+	case Allow:
+		return "allow"
+	case Deny:
+		return "deny"
+	}
+	return "abstain"
+}
+
+// Authorizer augments the built-in authorization, Deny from any authorizer wins over Allow. Administrators and
+// deploy keys are not subject to authorizers.
+type Authorizer interface {
+	Authorize(ctx context.Context, req *AccessRequest) (Decision, error)
+}
+
+type RepositoryEventType string
+
+const (
+	RepositoryCreated              RepositoryEventType = "created"
+	RepositoryDefaultBranchChanged RepositoryEventType = "default-branch-changed"
+)
+
+type RepositoryEvent struct {
+	Type             RepositoryEventType `json:"type"`
+	RID              int64               `json:"rid"`
+	NamespaceID      int64               `json:"namespace_id"`
+	Path             string              `json:"path"`
+	UID              int64               `json:"uid"` // actor
+	DefaultBranch    string              `json:"default_branch,omitempty"`
+	OldDefaultBranch string              `json:"old_default_branch,omitempty"` // default-branch-changed only
+}
+
+// RepositoryObserver reacts to repository lifecycle events.
+type RepositoryObserver interface {
+	OnRepositoryEvent(ctx context.Context, e *RepositoryEvent)
+}
+
+// Veto rejects a push with a message shown to the user.
+type Veto struct {
+	Extension string
+	Message   string
+}
+
+func (v *Veto) Error() string {
+	return fmt.Sprintf("rejected by %s: %s", v.Extension, v.Message)
+}
+
+// Factory creates an extension from the [[extensions]] table of the configuration.
+type Factory func(cfg *serve.Extension) (Extension, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{
+		sidecarName: newSidecar,
+	}
+)
+
+// Register makes an extension available by name, it is usually called in the init function of the package
+// implementing the extension. Register panics if the name is registered twice.
+func Register(name string, f Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if f == nil {
+		panic("extension: Register factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("extension: Register called twice for " + name)
+	}
+	factories[name] = f
+}
+
+// Registered returns the names of the registered extensions.
+func Registered() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
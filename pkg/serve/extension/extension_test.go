@@ -0,0 +1,111 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+)
+
+type testExtension struct {
+	name     string
+	decision Decision
+	err      error
+}
+
+func (x *testExtension) Name() string {
+	return x.name
+}
+
+func (x *testExtension) CheckPush(ctx context.Context, e *PushEvent) error {
+	return x.err
+}
+
+func (x *testExtension) Authorize(ctx context.Context, req *AccessRequest) (Decision, error) {
+	return x.decision, x.err
+}
+
+func TestSet(t *testing.T) {
+	ctx := t.Context()
+	var s *Set
+	if err := s.CheckPush(ctx, &PushEvent{}); err != nil || !s.Authorize(ctx, &AccessRequest{Allowed: true}) {
+		t.Fatalf("nil set should keep the built-in decision")
+	}
+	s = NewSet(&testExtension{name: "abstain"}, &testExtension{name: "allow", decision: Allow})
+	if !s.Authorize(ctx, &AccessRequest{}) {
+		t.Errorf("allow should grant access")
+	}
+	s = NewSet(&testExtension{name: "allow", decision: Allow}, &testExtension{name: "deny", decision: Deny})
+	if s.Authorize(ctx, &AccessRequest{Allowed: true}) {
+		t.Errorf("deny should win over allow")
+	}
+	s = NewSet(&testExtension{name: "broken", err: errors.New("unavailable")})
+	if s.Authorize(ctx, &AccessRequest{Allowed: true}) {
+		t.Errorf("failed authorizer should deny")
+	}
+	err := s.CheckPush(ctx, &PushEvent{})
+	if v, ok := errors.AsType[*Veto](err); !ok || v.Extension != "broken" {
+		t.Errorf("failed checker should reject: %v", err)
+	}
+	s.entries[0].failOpen = true
+	if err := s.CheckPush(ctx, &PushEvent{}); err != nil || !s.Authorize(ctx, &AccessRequest{Allowed: true}) {
+		t.Errorf("fail open extension should be ignored: %v", err)
+	}
+	if _, err := Load([]*serve.Extension{{Name: "not-registered"}}); err == nil {
+		t.Errorf("unknown extension should fail")
+	}
+}
+
+func TestSidecar(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(sidecarCheckPush, func(w http.ResponseWriter, r *http.Request) {
+		var e PushEvent
+		_ = json.NewDecoder(r.Body).Decode(&e)
+		resp := &CheckPushResponse{Allow: e.ReferenceName != "refs/heads/frozen", Message: "branch is frozen"}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc(sidecarAuthorize, func(w http.ResponseWriter, r *http.Request) {
+		var req AccessRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		decision := "abstain"
+		if req.Operation == protocol.UPLOAD && req.UserName == "bot" {
+			decision = "deny"
+		}
+		_ = json.NewEncoder(w).Encode(&AuthorizeResponse{Decision: decision})
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	s, err := Load([]*serve.Extension{{Name: sidecarName, Endpoint: ts.URL}})
+	if err != nil {
+		t.Fatalf("load sidecar: %v", err)
+	}
+	ctx := t.Context()
+	if err := s.CheckPush(ctx, &PushEvent{ReferenceName: "refs/heads/mainline"}); err != nil {
+		t.Errorf("push to mainline: %v", err)
+	}
+	err = s.CheckPush(ctx, &PushEvent{ReferenceName: "refs/heads/frozen"})
+	if v, ok := errors.AsType[*Veto](err); !ok || v.Message != "branch is frozen" {
+		t.Errorf("push to frozen branch: %v", err)
+	}
+	if s.Authorize(ctx, &AccessRequest{UserName: "bot", Operation: protocol.UPLOAD, Allowed: true}) {
+		t.Errorf("bot upload should be denied")
+	}
+	if !s.Authorize(ctx, &AccessRequest{UserName: "bot", Operation: protocol.DOWNLOAD, Allowed: true}) {
+		t.Errorf("bot download should keep the built-in decision")
+	}
+	// events not handled by the sidecar
+	mux.HandleFunc(sidecarNotifyPush, http.NotFound)
+	sc, _ := newSidecar(&serve.Extension{Endpoint: ts.URL})
+	if err := sc.(*sidecar).post(ctx, sidecarNotifyPush, &PushEvent{}, nil); !errors.Is(err, errNotHandled) {
+		t.Errorf("not found should be not handled: %v", err)
+	}
+	if _, err := newSidecar(&serve.Extension{Endpoint: "ftp://example.com"}); err == nil {
+		t.Errorf("unsupported endpoint should fail")
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultTimeout = 5 * time.Second
+	notifyTimeout  = 30 * time.Second
+)
+
+type entry struct {
+	Extension
+	timeout  time.Duration
+	failOpen bool
+}
+
+// Set: the enabled extensions in configuration order. A nil *Set has no extension.
+type Set struct {
+	entries []*entry
+}
+
+// Load creates the extensions enabled by the configuration.
+func Load(cfgs []*serve.Extension) (*Set, error) {
+	s := &Set{}
+	for _, cfg := range cfgs {
+		factoriesMu.RLock()
+		f, ok := factories[cfg.Name]
+		factoriesMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown extension '%s'", cfg.Name)
+		}
+		ext, err := f(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("load extension '%s' error: %w", cfg.Name, err)
+		}
+		e := &entry{Extension: ext, timeout: cfg.Timeout.Duration, failOpen: cfg.FailOpen}
+		if e.timeout <= 0 {
+			e.timeout = defaultTimeout
+		}
+		s.entries = append(s.entries, e)
+	}
+	return s, nil
+}
+
+// NewSet returns a set of extensions created by the caller, mainly for tests and embedding.
+func NewSet(exts ...Extension) *Set {
+	s := &Set{}
+	for _, ext := range exts {
+		s.entries = append(s.entries, &entry{Extension: ext, timeout: defaultTimeout})
+	}
+	return s
+}
+
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.entries)
+}
+
+// CheckPush runs the push checkers in order, the first rejection stops the push.
+func (s *Set) CheckPush(ctx context.Context, e *PushEvent) error {
+	if s == nil {
+		return nil
+	}
+	for _, x := range s.entries {
+		c, ok := x.Extension.(PushChecker)
+		if !ok {
+			continue
+		}
+		err := func() error {
+			ctx, cancel := context.WithTimeout(ctx, x.timeout)
+			defer cancel()
+			return c.CheckPush(ctx, e)
+		}()
+		if err == nil {
+			continue
+		}
+		if _, ok := errors.AsType[*Veto](err); ok {
+			return err
+		}
+		if x.failOpen {
+			logrus.Errorf("extension %s check push error: %v", x.Name(), err)
+			continue
+		}
+		return &Veto{Extension: x.Name(), Message: err.Error()}
+	}
+	return nil
+}
+
+// Authorize returns the final decision of the access request: any Deny rejects, any Allow grants, otherwise the
+// built-in decision is kept.
+func (s *Set) Authorize(ctx context.Context, req *AccessRequest) bool {
+	if s == nil {
+		return req.Allowed
+	}
+	allowed := req.Allowed
+	for _, x := range s.entries {
+		a, ok := x.Extension.(Authorizer)
+		if !ok {
+			continue
+		}
+		d, err := func() (Decision, error) {
+			ctx, cancel := context.WithTimeout(ctx, x.timeout)
+			defer cancel()
+			return a.Authorize(ctx, req)
+		}()
+		if err != nil {
+			logrus.Errorf("extension %s authorize error: %v", x.Name(), err)
+			if x.failOpen {
+				continue
+			}
+			return false
+		}
+		switch d {
+		case Deny:
+			return false
+		case Allow:
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// NotifyPush notifies the push observers in the background.
+func (s *Set) NotifyPush(e *PushEvent) {
+	if s == nil {
+		return
+	}
+	for _, x := range s.entries {
+		if o, ok := x.Extension.(PushObserver); ok {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+				defer cancel()
+				o.OnPush(ctx, e)
+			}()
+		}
+	}
+}
+
+// NotifyRepository notifies the repository observers in the background.
+func (s *Set) NotifyRepository(e *RepositoryEvent) {
+	if s == nil {
+		return
+	}
+	for _, x := range s.entries {
+		if o, ok := x.Extension.(RepositoryObserver); ok {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+				defer cancel()
+				o.OnRepositoryEvent(ctx, e)
+			}()
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package extension
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	sidecarName = "sidecar"
+	// sidecar endpoints, a sidecar answers 404 for events it does not handle
+	sidecarCheckPush        = "/v1/push/check"
+	sidecarNotifyPush       = "/v1/push/notify"
+	sidecarAuthorize        = "/v1/authorize"
+	sidecarNotifyRepository = "/v1/repository/notify"
+)
+
+var (
+	errNotHandled = errors.New("not handled by sidecar")
+)
+
+type CheckPushResponse struct {
+	Allow   bool   `json:"allow"`
+	Message string `json:"message,omitempty"`
+}
+
+type AuthorizeResponse struct {
+	Decision string `json:"decision"` // allow, deny or abstain
+}
+
+// sidecar: an extension running out of process, events are posted as JSON to the endpoint.
+type sidecar struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newSidecar(cfg *serve.Extension) (Extension, error) {
+	if len(cfg.Endpoint) == 0 {
+		return nil, errors.New("sidecar endpoint not set")
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("bad sidecar endpoint: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &sidecar{baseURL: strings.TrimSuffix(cfg.Endpoint, "/"), client: &http.Client{}}, nil
+	case "unix":
+		socketPath := u.Path
+		dialer := &net.Dialer{}
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		}
+		return &sidecar{baseURL: "http://sidecar", client: &http.Client{Transport: transport}}, nil
+	}
+	return nil, fmt.Errorf("unsupported sidecar endpoint '%s'", cfg.Endpoint)
+}
+
+func (s *sidecar) Name() string {
+	return sidecarName
+}
+
+func (s *sidecar) post(ctx context.Context, endpoint string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotHandled
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("sidecar %s: %s %s", endpoint, resp.Status, bytes.TrimSpace(message))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (s *sidecar) CheckPush(ctx context.Context, e *PushEvent) error {
+	var resp CheckPushResponse
+	if err := s.post(ctx, sidecarCheckPush, e, &resp); err != nil {
+		if errors.Is(err, errNotHandled) {
+			return nil
+		}
+		return err
+	}
+	if !resp.Allow {
+		return &Veto{Extension: sidecarName, Message: resp.Message}
+	}
+	return nil
+}
+
+func (s *sidecar) OnPush(ctx context.Context, e *PushEvent) {
+	if err := s.post(ctx, sidecarNotifyPush, e, nil); err != nil && !errors.Is(err, errNotHandled) {
+		logrus.Errorf("notify sidecar push error: %v", err)
+	}
+}
+
+func (s *sidecar) Authorize(ctx context.Context, req *AccessRequest) (Decision, error) {
+	var resp AuthorizeResponse
+	if err := s.post(ctx, sidecarAuthorize, req, &resp); err != nil {
+		if errors.Is(err, errNotHandled) {
+			return Abstain, nil
+		}
+		return Abstain, err
+	}
+	switch resp.Decision {
+	case "allow":
+		return Allow, nil
+	case "deny":
+		return Deny, nil
+	case "abstain", "":
+		return Abstain, nil
+	}
+	return Abstain, fmt.Errorf("bad sidecar decision '%s'", resp.Decision)
+}
+
+func (s *sidecar) OnRepositoryEvent(ctx context.Context, e *RepositoryEvent) {
+	if err := s.post(ctx, sidecarNotifyRepository, e, nil); err != nil && !errors.Is(err, errNotHandled) {
+		logrus.Errorf("notify sidecar repository event error: %v", err)
+	}
+}
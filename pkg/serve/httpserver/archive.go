@@ -0,0 +1,81 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+	"github.com/sirupsen/logrus"
+)
+
+// archiveName: <repo>-<ref>.<format>, characters not allowed in file names are replaced.
+func archiveName(repoName, rev, format string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', '"', ':', '*', '?', '<', '>', '|':
+			return '-'
+		}
+		return r
+	}, repoName+"-"+rev)
+	return name + "." + format
+}
+
+// GET /{namespace}/{repo}/archive?ref=...&format=tar.gz&prefix=...
+func (s *Server) Archive(w http.ResponseWriter, r *Request) {
+	q := r.URL.Query()
+	rev := q.Get("ref")
+	if len(rev) == 0 {
+		rev = protocol.HEAD
+	}
+	opts := &repo.ArchiveOptions{
+		Format: q.Get("format"),
+		Prefix: q.Get("prefix"),
+		Repo:   r.N.Path + "/" + r.R.Path,
+		Ref:    rev,
+		Paths:  r.Paths,
+	}
+	if len(opts.Format) == 0 {
+		opts.Format = repo.ArchiveTarGz
+	}
+	if err := opts.Validate(); err != nil {
+		renderFailure(w, r.Request, http.StatusBadRequest, err.Error())
+		return
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		return
+	}
+	defer rr.Close() // nolint
+	ro, err := rr.ParseRev(r.Context(), rev)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	if ro.Target == nil {
+		renderFailureFormat(w, r.Request, http.StatusNotFound, "rev %s target not commit", rev)
+		return
+	}
+	contentType := "application/x-tar"
+	if opts.Format == repo.ArchiveTarGz {
+		contentType = "application/gzip"
+	}
+	if rev == protocol.HEAD {
+		rev = ro.Target.Hash.String()[:12]
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", archiveName(r.R.Path, rev, opts.Format)))
+	// the archive of a commit never changes
+	w.Header().Set("ETag", fmt.Sprintf("\"%s-%s-%s\"", ro.Target.Hash, opts.Format, strings.Trim(opts.Prefix, "/")))
+	w.WriteHeader(http.StatusOK)
+	if err := rr.Archive(r.Context(), ro.Target, opts, w); err != nil {
+		if _, ok := errors.AsType[*repo.ErrBadArchiveRequest](err); !ok {
+			logrus.Errorf("archive %s %s error: %v", opts.Repo, ro.Target.Hash, err)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/antgroup/hugescm/pkg/serve/argon2id"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/extension"
 	"github.com/antgroup/hugescm/pkg/serve/protocol"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
@@ -123,7 +124,12 @@ func (s *Server) basicAuth(w http.ResponseWriter, r *http.Request, operation pro
 		renderFailureFormat(w, r, http.StatusInternalServerError, "search repo '%s/%s' error: %v", namespacePath, repoPath, err)
 		return nil, ErrStop
 	}
-	if _, err = s.checkAccess(w, r, operation, repo, u); err != nil {
+	accessLevel, err := s.checkAccess(w, r, operation, ns, repo, u)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := s.pathPermissions(w, r, repo, u, accessLevel)
+	if err != nil {
 		return nil, err
 	}
 	return &Request{
@@ -131,6 +137,7 @@ func (s *Server) basicAuth(w http.ResponseWriter, r *http.Request, operation pro
 		U:       u,
 		N:       ns,
 		R:       repo,
+		Paths:   paths,
 	}, nil
 }
 
@@ -159,7 +166,12 @@ func (s *Server) doAuth(w http.ResponseWriter, r *http.Request, operation protoc
 		renderFailureFormat(w, r, http.StatusInternalServerError, "search repo '%s/%s' error: %v", namespacePath, repoPath, err)
 		return nil, ErrStop
 	}
-	if _, err = s.checkAccess(w, r, operation, repo, u); err != nil {
+	accessLevel, err := s.checkAccess(w, r, operation, ns, repo, u)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := s.pathPermissions(w, r, repo, u, accessLevel)
+	if err != nil {
 		return nil, err
 	}
 	return &Request{
@@ -167,9 +179,24 @@ func (s *Server) doAuth(w http.ResponseWriter, r *http.Request, operation protoc
 		U:       u,
 		N:       ns,
 		R:       repo,
+		Paths:   paths,
 	}, nil
 }
 
+// pathPermissions returns the dirs the user is restricted to, masters and administrators are never restricted.
+func (s *Server) pathPermissions(w http.ResponseWriter, r *http.Request, repo *database.Repository, u *database.User, accessLevel database.AccessLevel) ([]string, error) {
+	if u.Administrator || accessLevel.Sudo() {
+		return nil, nil
+	}
+	paths, err := s.db.PathPermissions(r.Context(), repo.ID, u.ID)
+	if err != nil {
+		logrus.Errorf("%s check path permissions error: %v", r.RequestURI, err)
+		renderFailureFormat(w, r, http.StatusInternalServerError, "check user's path permissions error: %v", err)
+		return nil, err
+	}
+	return paths, nil
+}
+
 func (s *Server) OnFunc(fn HandlerFunc, operation protocol.Operation) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		req, err := s.doAuth(w, r, operation)
@@ -187,7 +214,19 @@ func checkRepoReadable(u *database.User, repo *database.Repository, accessLevel
 	return repo.IsPublic() || (repo.IsInternal() && u.Type != database.UserTypeRemoteUser)
 }
 
-func (s *Server) checkAccess(w http.ResponseWriter, r *http.Request, operation protocol.Operation, repo *database.Repository, u *database.User) (database.AccessLevel, error) {
+func newAccessRequest(operation protocol.Operation, namespacePath string, repo *database.Repository, u *database.User, accessLevel database.AccessLevel, allowed bool) *extension.AccessRequest {
+	return &extension.AccessRequest{
+		RID:         repo.ID,
+		UID:         u.ID,
+		UserName:    u.UserName,
+		Repository:  namespacePath + "/" + repo.Path,
+		Operation:   operation,
+		AccessLevel: int(accessLevel),
+		Allowed:     allowed,
+	}
+}
+
+func (s *Server) checkAccess(w http.ResponseWriter, r *http.Request, operation protocol.Operation, ns *database.Namespace, repo *database.Repository, u *database.User) (database.AccessLevel, error) {
 	if u.Administrator {
 		return database.OwnerAccess, nil
 	}
@@ -197,25 +236,21 @@ func (s *Server) checkAccess(w http.ResponseWriter, r *http.Request, operation p
 		renderFailureFormat(w, r, http.StatusInternalServerError, "check user's access for repository error: %v", err)
 		return database.NoneAccess, err
 	}
+	var allowed bool
 	switch operation {
 	case protocol.DOWNLOAD:
-		if !checkRepoReadable(u, repo, accessLevel) {
-			renderFailureFormat(w, r, http.StatusForbidden, "[DOWNLOAD] access denied, current user: %s", u.UserName)
-			return accessLevel, ErrAccessDenied
-		}
+		allowed = checkRepoReadable(u, repo, accessLevel)
 	case protocol.UPLOAD:
-		if !accessLevel.Writeable() {
-			renderFailureFormat(w, r, http.StatusForbidden, "[UPLOAD] access denied, current user: %s", u.UserName)
-			return accessLevel, ErrAccessDenied
-		}
+		allowed = accessLevel.Writeable()
 	case protocol.SUDO:
-		if !accessLevel.Sudo() {
-			renderFailureFormat(w, r, http.StatusForbidden, "[SUDO] access denied, current user: %s", u.UserName)
-			return accessLevel, ErrAccessDenied
-		}
+		allowed = accessLevel.Sudo()
 	default:
 		renderFailureFormat(w, r, http.StatusBadRequest, "bad operation name '%s'", operation)
 		return accessLevel, fmt.Errorf("bad operation name '%s'", operation)
 	}
+	if !s.ext.Authorize(r.Context(), newAccessRequest(operation, ns.Path, repo, u, accessLevel, allowed)) {
+		renderFailureFormat(w, r, http.StatusForbidden, "[%s] access denied, current user: %s", strings.ToUpper(string(operation)), u.UserName)
+		return accessLevel, ErrAccessDenied
+	}
 	return accessLevel, nil
 }
@@ -6,6 +6,7 @@ package httpserver
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -38,6 +39,15 @@ func (t *BearerMD) Match(op protocol.Operation) bool {
 	return false
 }
 
+// skewHint reports the token validity and server time, tokens rejected right after being issued usually mean the client clock is off.
+func (t *BearerMD) skewHint(now time.Time) string {
+	if t == nil || t.IssuedAt == nil || t.ExpiresAt == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (issued at %s, expires at %s, server time %s; if these do not match your local time, check the system clock)",
+		t.IssuedAt.UTC().Format(time.RFC3339), t.ExpiresAt.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+}
+
 func GenerateJWT(u *database.User, rid int64, op protocol.Operation, expiresAt time.Time) (string, error) {
 	now := time.Now()
 	claims := BearerMD{
@@ -69,7 +79,7 @@ func (s *Server) ParseJWT(w http.ResponseWriter, r *http.Request, bearerToken st
 			return nil, sqlErr
 		}
 		return []byte(u.SignatureToken), nil
-	})
+	}, jwt.WithLeeway(s.ClockSkew.Duration))
 	if err != nil {
 		switch {
 		case errors.Is(err, jwt.ErrTokenMalformed):
@@ -77,7 +87,7 @@ func (s *Server) ParseJWT(w http.ResponseWriter, r *http.Request, bearerToken st
 		case errors.Is(err, jwt.ErrTokenSignatureInvalid):
 			renderFailureFormat(w, r, http.StatusForbidden, "invalid token: %s", err)
 		case errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, jwt.ErrTokenNotValidYet):
-			renderFailureFormat(w, r, http.StatusForbidden, "expired token: %s", err)
+			renderFailureFormat(w, r, http.StatusForbidden, "expired token: %s%s", err, claims.skewHint(time.Now()))
 		case errors.Is(err, sql.ErrNoRows):
 			renderFailureFormat(w, r, http.StatusNotFound, "user not found: %v", err)
 		default:
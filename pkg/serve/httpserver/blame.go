@@ -0,0 +1,41 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+)
+
+// GET /{namespace}/{repo}/blame?path=...&ref=...
+func (s *Server) Blame(w http.ResponseWriter, r *Request) {
+	q := r.URL.Query()
+	rev := q.Get("ref")
+	if len(rev) == 0 {
+		rev = protocol.HEAD
+	}
+	if len(r.Paths) != 0 && !protocol.PathVisible(r.Paths, q.Get("path")) {
+		renderFailure(w, r.Request, http.StatusForbidden, r.W("no access to the requested paths"))
+		return
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		return
+	}
+	defer rr.Close() // nolint
+	resp, err := rr.Blame(r.Context(), rev, q.Get("path"))
+	if err != nil {
+		if e, ok := errors.AsType[*repo.ErrBadBlameRequest](err); ok {
+			renderFailure(w, r.Request, http.StatusBadRequest, e.Error())
+			return
+		}
+		s.renderError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	JsonEncode(w, resp)
+}
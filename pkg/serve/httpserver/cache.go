@@ -0,0 +1,103 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	CacheControl = "Cache-Control"
+	// references may be updated at any time, clients must revalidate with If-None-Match
+	cacheControlRevalidate = "no-cache"
+	// metadata addressed by commit id never changes
+	cacheControlImmutable = "max-age=31536000, immutable"
+)
+
+// makeETag returns a strong entity tag of the parts.
+func makeETag(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		_, _ = h.Write([]byte(p))
+		_, _ = h.Write([]byte{0})
+	}
+	return "\"" + hex.EncodeToString(h.Sum(nil)[:20]) + "\""
+}
+
+// etagMatch: https://www.rfc-editor.org/rfc/rfc9110#name-if-none-match, weak comparison
+func etagMatch(ifNoneMatch, etag string) bool {
+	if len(ifNoneMatch) == 0 {
+		return false
+	}
+	for v := range strings.SplitSeq(ifNoneMatch, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNotModified sets ETag and Cache-Control, and responds 304 when the client copy is fresh.
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag, cacheControl string) bool {
+	h := w.Header()
+	h.Set(ETag, etag)
+	h.Set(CacheControl, cacheControl)
+	h.Add("Vary", "Accept, Authorization")
+	if !etagMatch(r.Header.Get(IfNoneMatch), etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// metadataCacheControl: metadata addressed by object id is immutable, only anonymous repositories may be stored by shared caches.
+func metadataCacheControl(r *Request, revision string) string {
+	if !plumbing.ValidateHashHex(revision) {
+		return cacheControlRevalidate
+	}
+	if r.R.VisibleLevel == database.AnonymousRepository {
+		return "public, " + cacheControlImmutable
+	}
+	return "private, " + cacheControlImmutable
+}
+
+// metadataETag: metadata depends on the resolved objects, query (depth, deepen, have) and Accept (compression).
+func metadataETag(r *Request, ro *repo.RevObjects, extra ...string) string {
+	parts := make([]string, 0, len(ro.Objects)+len(extra)+3)
+	parts = append(parts, ro.Target.Hash.String(), r.URL.RawQuery, r.Header.Get("Accept"))
+	parts = append(parts, slices.Sorted(maps.Keys(ro.Objects))...)
+	parts = append(parts, extra...)
+	return makeETag(parts...)
+}
+
+// ZetaEncodeVNDConditional is ZetaEncodeVND with ETag validation.
+func ZetaEncodeVNDConditional(w http.ResponseWriter, r *http.Request, a any) {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(a); err != nil {
+		logrus.Errorf("encode response error: %v", err)
+		renderFailure(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if checkNotModified(w, r, makeETag(b.String()), cacheControlRevalidate) {
+		return
+	}
+	w.Header().Set("Content-Type", ZETA_MIME_VND_JSON)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(b.Bytes()); err != nil {
+		logrus.Errorf("write response error: %v", err)
+	}
+}
@@ -4,6 +4,7 @@
 package httpserver
 
 import (
+	"crypto/ed25519"
 	"time"
 
 	"github.com/antgroup/hugescm/pkg/serve"
@@ -15,19 +16,29 @@ const (
 	DefaultReadTimeout  = 2 * time.Hour
 	DefaultWriteTimeout = 2 * time.Hour
 	DefaultIdleTimeout  = 5 * time.Minute
+	DefaultClockSkew    = 5 * time.Minute
 )
 
 type ServerConfig struct {
-	Listen        string          `toml:"listen"`
-	Repositories  string          `toml:"repositories"`
-	IdleTimeout   serve.Duration  `toml:"idle_timeout,omitempty"`
-	ReadTimeout   serve.Duration  `toml:"read_timeout,omitempty"`
-	WriteTimeout  serve.Duration  `toml:"write_timeout,omitempty"`
-	BannerVersion string          `toml:"banner_version,omitempty"`
-	X25519Key     string          `toml:"x25519_key,omitempty"`
-	Cache         *serve.Cache    `toml:"cache,omitempty"`
-	DB            *serve.Database `toml:"database,omitempty"`
-	PersistentOSS *serve.OSS      `toml:"oss,omitempty"` // Persistent storage
+	Listen          string              `toml:"listen"`
+	Repositories    string              `toml:"repositories"`
+	IdleTimeout     serve.Duration      `toml:"idle_timeout,omitempty"`
+	ReadTimeout     serve.Duration      `toml:"read_timeout,omitempty"`
+	WriteTimeout    serve.Duration      `toml:"write_timeout,omitempty"`
+	ClockSkew       serve.Duration      `toml:"clock_skew,omitempty"` // leeway of token validation and share link expiry
+	BannerVersion   string              `toml:"banner_version,omitempty"`
+	X25519Key       string              `toml:"x25519_key,omitempty"`
+	ReferenceKey    string              `toml:"reference_key,omitempty"` // ed25519 key signing the reference log
+	ReferenceSigner ed25519.PrivateKey  `toml:"-"`
+	Cache           *serve.Cache        `toml:"cache,omitempty"`
+	DB              *serve.Database     `toml:"database,omitempty"`
+	PersistentOSS   *serve.OSS          `toml:"oss,omitempty"` // Persistent storage
+	CommitPolicy    *serve.CommitPolicy `toml:"commit_policy,omitempty"`
+	PathPolicy      *serve.PathPolicy   `toml:"path_policy,omitempty"`
+	Tiering         *serve.Tiering      `toml:"tiering,omitempty"` // cold tier of large objects
+	Backup          *serve.Backup       `toml:"backup,omitempty"`  // locations of backup jobs
+	BodyLimits      *serve.BodyLimits   `toml:"body_limits,omitempty"`
+	Extensions      []*serve.Extension  `toml:"extensions,omitempty"`
 }
 
 func NewServerConfig(file string, expandEnv bool) (*ServerConfig, error) {
@@ -47,6 +58,10 @@ func NewServerConfig(file string, expandEnv bool) (*ServerConfig, error) {
 		WriteTimeout: serve.Duration{
 			Duration: DefaultWriteTimeout,
 		},
+		ClockSkew: serve.Duration{
+			Duration: DefaultClockSkew,
+		},
+		BodyLimits:    serve.NewBodyLimits(), // fields absent from config keep the defaults
 		BannerVersion: version.GetServerVersion(),
 	}
 	if err := toml.NewDecoder(r).Decode(sc); err != nil {
@@ -60,6 +75,12 @@ func NewServerConfig(file string, expandEnv bool) (*ServerConfig, error) {
 	}
 	sc.DB.Decrypt(d)
 	sc.PersistentOSS.Decrypt(d)
+	sc.Tiering.Decrypt(d)
+	if len(sc.ReferenceKey) != 0 {
+		if sc.ReferenceSigner, err = serve.NewReferenceKey(sc.ReferenceKey, d); err != nil {
+			return nil, err
+		}
+	}
 	if sc.Cache == nil {
 		sc.Cache = &serve.Cache{
 			NumCounters: 1000000000,
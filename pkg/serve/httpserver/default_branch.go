@@ -0,0 +1,41 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+)
+
+// PUT /{namespace}/{repo}/default-branch
+func (s *Server) SetDefaultBranch(w http.ResponseWriter, r *Request) {
+	if !limitBody(w, r.Request, s.BodyLimits.Management.Size) {
+		return
+	}
+	var req protocol.DefaultBranchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		renderRequestError(w, r.Request, err, "input body error: %v")
+		return
+	}
+	branchName := strings.TrimPrefix(req.Branch, protocol.BRANCH_PREFIX)
+	if !plumbing.ValidateBranchName([]byte(branchName)) {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, r.W("bad branch name '%s'"), req.Branch)
+		return
+	}
+	oldBranch, err := s.hub.SetDefaultBranch(r.Context(), r.R, r.U, branchName)
+	if err != nil {
+		if database.IsErrRevisionNotFound(err) {
+			renderFailureFormat(w, r.Request, http.StatusNotFound, r.W("branch '%s' not exist"), branchName)
+			return
+		}
+		s.renderError(w, r, err)
+		return
+	}
+	JsonEncode(w, &protocol.DefaultBranchResponse{DefaultBranch: branchName, OldDefaultBranch: oldBranch})
+}
@@ -0,0 +1,57 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+)
+
+// GET /{namespace}/{repo}/history?path=...&ref=...&follow=true&limit=...&cursor=...
+func (s *Server) History(w http.ResponseWriter, r *Request) {
+	limit, err := s.checkLimit(w, r)
+	if err != nil {
+		return
+	}
+	q := r.URL.Query()
+	opts := &repo.HistoryOptions{
+		Path:   q.Get("path"),
+		Limit:  limit,
+		Cursor: q.Get("cursor"),
+	}
+	if f := q.Get("follow"); len(f) != 0 {
+		if opts.Follow, err = strconv.ParseBool(f); err != nil {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad follow value '%s'", f)
+			return
+		}
+	}
+	rev := q.Get("ref")
+	if len(rev) == 0 {
+		rev = protocol.HEAD
+	}
+	if len(r.Paths) != 0 && !protocol.PathVisible(r.Paths, opts.Path) {
+		renderFailure(w, r.Request, http.StatusForbidden, r.W("no access to the requested paths"))
+		return
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		return
+	}
+	defer rr.Close() // nolint
+	resp, err := rr.History(r.Context(), rev, opts)
+	if err != nil {
+		if e, ok := errors.AsType[*repo.ErrBadHistoryRequest](err); ok {
+			renderFailure(w, r.Request, http.StatusBadRequest, e.Error())
+			return
+		}
+		s.renderError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	JsonEncode(w, resp)
+}
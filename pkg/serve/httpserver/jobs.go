@@ -0,0 +1,131 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/jobs"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultJobsLimit = 20
+	maxJobsLimit     = 100
+)
+
+type NewJob struct {
+	NamespacePath string          `json:"namespace_path"`
+	RepoPath      string          `json:"repo_path"`
+	Kind          string          `json:"kind"`             // fsck, gc, backup or tier
+	Params        json.RawMessage `json:"params,omitempty"` // eg: backup {"to": "daily", "full": false}
+	UID           int64           `json:"uid,omitempty"`    // user starting the job
+}
+
+func (s *Server) jobEnv() *jobs.Env {
+	backup := s.Backup
+	if backup == nil {
+		// backups of the management API are disabled without backup root and targets
+		backup = &serve.Backup{}
+	}
+	return &jobs.Env{DB: s.db, Hub: s.hub, Root: s.Repositories, Bucket: s.hub.Bucket(), Cold: s.hub.ColdBucket(), OSS: s.PersistentOSS, Tiering: s.Tiering, Backup: backup}
+}
+
+// NewJob: start a maintenance job in the background, the job is returned at once, its progress is read by GetJob.
+func (s *Server) NewJob(w http.ResponseWriter, r *http.Request) {
+	var newJob NewJob
+	if !limitBody(w, r, s.BodyLimits.Management.Size) {
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&newJob); err != nil {
+		renderRequestError(w, r, err, "input body error: %v")
+		return
+	}
+	n, repo, err := s.db.FindRepositoryByPath(r.Context(), newJob.NamespacePath, newJob.RepoPath)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	params := string(newJob.Params)
+	task, err := jobs.NewTask(s.jobEnv(), newJob.Kind, params, n, repo)
+	if err != nil {
+		renderFailure(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	j, err := s.jobs.Start(r.Context(), &database.Job{RID: repo.ID, UID: newJob.UID, Kind: newJob.Kind, Params: params}, task)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, j)
+}
+
+// ListJobs: latest jobs, newest first, namespace_path and repo_path select the jobs of a repository.
+func (s *Server) ListJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := defaultJobsLimit
+	if v := query.Get("limit"); len(v) != 0 {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			renderFailureFormat(w, r, http.StatusBadRequest, "bad limit '%s'", v)
+			return
+		}
+		limit = min(n, maxJobsLimit)
+	}
+	var rid int64
+	if namespacePath := query.Get("namespace_path"); len(namespacePath) != 0 {
+		_, repo, err := s.db.FindRepositoryByPath(r.Context(), namespacePath, query.Get("repo_path"))
+		if err != nil {
+			s.renderErrorRaw(w, r, err)
+			return
+		}
+		rid = repo.ID
+	}
+	items, err := s.db.ListJobs(r.Context(), rid, limit)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, items)
+}
+
+func jobID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+}
+
+// GetJob: status and progress of the job.
+func (s *Server) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := jobID(r)
+	if err != nil {
+		renderFailureFormat(w, r, http.StatusBadRequest, "bad job id: %v", err)
+		return
+	}
+	j, err := s.db.FindJob(r.Context(), id)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, j)
+}
+
+// CancelJob: ask the runner of the job to cancel it, the job is canceled when its status becomes canceled. Jobs of
+// this server are canceled at once, jobs of other processes at their next progress update.
+func (s *Server) CancelJob(w http.ResponseWriter, r *http.Request) {
+	id, err := jobID(r)
+	if err != nil {
+		renderFailureFormat(w, r, http.StatusBadRequest, "bad job id: %v", err)
+		return
+	}
+	j, err := s.db.CancelJob(r.Context(), id)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	s.jobs.Cancel(id)
+	JsonEncode(w, j)
+}
@@ -6,13 +6,24 @@ package httpserver
 // WARING: The management API is mainly used for testing and adding users. Do not use it in a production environment.
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/mail"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/argon2id"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
 	"github.com/gorilla/mux"
 	"golang.org/x/crypto/ssh"
 )
@@ -27,9 +38,12 @@ type NewUser struct {
 
 func (s *Server) NewUser(w http.ResponseWriter, r *http.Request) {
 	var newUser NewUser
+	if !limitBody(w, r, s.BodyLimits.Management.Size) {
+		return
+	}
 	if err := json.NewDecoder(r.Body).Decode(&newUser); err != nil {
 		fmt.Println(err)
-		renderFailureFormat(w, r, http.StatusBadRequest, "input body error: %v", err)
+		renderRequestError(w, r, err, "input body error: %v")
 		return
 	}
 	if len(newUser.UserName) == 0 || len(newUser.Password) == 0 {
@@ -74,9 +88,12 @@ type NewRepo struct {
 
 func (s *Server) NewRepo(w http.ResponseWriter, r *http.Request) {
 	var newRepo NewRepo
+	if !limitBody(w, r, s.BodyLimits.Management.Size) {
+		return
+	}
 	if err := json.NewDecoder(r.Body).Decode(&newRepo); err != nil {
 		fmt.Println(err)
-		renderFailureFormat(w, r, http.StatusBadRequest, "input body error: %v", err)
+		renderRequestError(w, r, err, "input body error: %v")
 		return
 	}
 	var u *database.User
@@ -128,17 +145,21 @@ func (s *Server) NewRepo(w http.ResponseWriter, r *http.Request) {
 }
 
 type NewKey struct {
-	UserName string `json:"username,omitempty"`
-	UID      int64  `json:"uid,omitempty"`
-	Title    string `json:"title"`
-	Content  string `json:"content"`
+	UserName  string    `json:"username,omitempty"`
+	UID       int64     `json:"uid,omitempty"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
 }
 
 func (s *Server) NewKey(w http.ResponseWriter, r *http.Request) {
 	var newKey NewKey
+	if !limitBody(w, r, s.BodyLimits.Management.Size) {
+		return
+	}
 	if err := json.NewDecoder(r.Body).Decode(&newKey); err != nil {
 		fmt.Println(err)
-		renderFailureFormat(w, r, http.StatusBadRequest, "input body error: %v", err)
+		renderRequestError(w, r, err, "input body error: %v")
 		return
 	}
 	var u *database.User
@@ -169,6 +190,7 @@ func (s *Server) NewKey(w http.ResponseWriter, r *http.Request) {
 		Title:       newKey.Title,
 		Type:        database.BasicKey,
 		Fingerprint: ssh.FingerprintSHA256(pk),
+		ExpiresAt:   newKey.ExpiresAt,
 	})
 	if err != nil {
 		s.renderErrorRaw(w, r, err)
@@ -177,8 +199,264 @@ func (s *Server) NewKey(w http.ResponseWriter, r *http.Request) {
 	JsonEncode(w, k)
 }
 
+type NewPathPermissions struct {
+	UserName      string   `json:"username,omitempty"`
+	UID           int64    `json:"uid,omitempty"`
+	NamespacePath string   `json:"namespace_path"`
+	RepoPath      string   `json:"repo_path"`
+	Paths         []string `json:"paths"` // empty: remove the restriction
+}
+
+// SetPathPermissions: restrict the user to some dirs of the repository, masters are never restricted.
+func (s *Server) SetPathPermissions(w http.ResponseWriter, r *http.Request) {
+	var newPerms NewPathPermissions
+	if !limitBody(w, r, s.BodyLimits.Management.Size) {
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&newPerms); err != nil {
+		renderRequestError(w, r, err, "input body error: %v")
+		return
+	}
+	paths := make([]string, 0, len(newPerms.Paths))
+	for _, p := range newPerms.Paths {
+		if strings.HasPrefix(p, "/") || path.Clean(p) != p || p == "." || p == ".." || strings.HasPrefix(p, "../") {
+			renderFailureFormat(w, r, http.StatusBadRequest, "bad path '%s'", p)
+			return
+		}
+		if !slices.Contains(paths, p) {
+			paths = append(paths, p)
+		}
+	}
+	var u *database.User
+	var err error
+	switch {
+	case len(newPerms.UserName) != 0:
+		if u, err = s.db.SearchUser(r.Context(), newPerms.UserName); err != nil {
+			s.renderErrorRaw(w, r, err)
+			return
+		}
+	case newPerms.UID != 0:
+		if u, err = s.db.FindUser(r.Context(), newPerms.UID); err != nil {
+			s.renderErrorRaw(w, r, err)
+			return
+		}
+	default:
+		renderFailure(w, r, http.StatusBadRequest, "username or uid not given")
+		return
+	}
+	_, repo, err := s.db.FindRepositoryByPath(r.Context(), newPerms.NamespacePath, newPerms.RepoPath)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	if err := s.db.SetPathPermissions(r.Context(), repo.ID, u.ID, paths); err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, &NewPathPermissions{UserName: u.UserName, UID: u.ID, NamespacePath: newPerms.NamespacePath, RepoPath: repo.Path, Paths: paths})
+}
+
+type NewDeployKey struct {
+	NamespacePath string    `json:"namespace_path"`
+	RepoPath      string    `json:"repo_path"`
+	Title         string    `json:"title"`
+	Content       string    `json:"content"`
+	ExpiresAt     time.Time `json:"expires_at,omitzero"`
+	Commands      []string  `json:"commands,omitempty"` // SSH commands allowed, empty: download commands only
+}
+
+type DeployKey struct {
+	*database.Key
+	Repository *database.DeployKeyRepository `json:"repository"`
+}
+
+// NewDeployKey: add a deploy key or enable an existing deploy key for the repository.
+func (s *Server) NewDeployKey(w http.ResponseWriter, r *http.Request) {
+	var newKey NewDeployKey
+	if !limitBody(w, r, s.BodyLimits.Management.Size) {
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&newKey); err != nil {
+		renderRequestError(w, r, err, "input body error: %v")
+		return
+	}
+	commands := make([]string, 0, len(newKey.Commands))
+	for _, c := range newKey.Commands {
+		if _, ok := protocol.SSHCommands[c]; !ok {
+			renderFailureFormat(w, r, http.StatusBadRequest, "unknown command '%s'", c)
+			return
+		}
+		if !slices.Contains(commands, c) {
+			commands = append(commands, c)
+		}
+	}
+	pk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(newKey.Content))
+	if err != nil {
+		renderFailureFormat(w, r, http.StatusBadRequest, "bad public key: %v", err)
+		return
+	}
+	_, repo, err := s.db.FindRepositoryByPath(r.Context(), newKey.NamespacePath, newKey.RepoPath)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	fingerprint := ssh.FingerprintSHA256(pk)
+	k, err := s.db.SearchKey(r.Context(), fingerprint)
+	switch {
+	case err == nil:
+		if k.Type != database.DeployKey {
+			renderFailure(w, r, http.StatusConflict, "key already exists and is not a deploy key")
+			return
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		if k, err = s.db.AddKey(r.Context(), &database.Key{
+			Content:     newKey.Content,
+			Title:       newKey.Title,
+			Type:        database.DeployKey,
+			Fingerprint: fingerprint,
+			ExpiresAt:   newKey.ExpiresAt,
+		}); err != nil {
+			s.renderErrorRaw(w, r, err)
+			return
+		}
+	default:
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	dk, err := s.db.EnableDeployKey(r.Context(), k.ID, repo.ID, commands)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, &DeployKey{Key: k, Repository: dk})
+}
+
+func keyID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+}
+
+// GetKey: key metadata, eg: expires_at and last_used_at.
+func (s *Server) GetKey(w http.ResponseWriter, r *http.Request) {
+	id, err := keyID(r)
+	if err != nil {
+		renderFailureFormat(w, r, http.StatusBadRequest, "bad key id: %v", err)
+		return
+	}
+	k, err := s.db.FindKey(r.Context(), id)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, k)
+}
+
+func (s *Server) DeleteKey(w http.ResponseWriter, r *http.Request) {
+	id, err := keyID(r)
+	if err != nil {
+		renderFailureFormat(w, r, http.StatusBadRequest, "bad key id: %v", err)
+		return
+	}
+	if err := s.db.DeleteKey(r.Context(), id); err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func userID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+}
+
+// GetUserSettings: preferred language and notification endpoints of the user.
+func (s *Server) GetUserSettings(w http.ResponseWriter, r *http.Request) {
+	uid, err := userID(r)
+	if err != nil {
+		renderFailureFormat(w, r, http.StatusBadRequest, "bad user id: %v", err)
+		return
+	}
+	if _, err := s.db.FindUser(r.Context(), uid); err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	settings, err := s.db.UserSettings(r.Context(), uid)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, settings)
+}
+
+// checkUserSettings: the language must be supported by the server, the webhook must be a http(s) URL.
+func checkUserSettings(settings *database.UserSettings) error {
+	if len(settings.Language) != 0 {
+		lang, ok := serve.SupportedLanguage(settings.Language)
+		if !ok {
+			return fmt.Errorf("unsupported language '%s'", settings.Language)
+		}
+		settings.Language = lang
+	}
+	if len(settings.NotifyWebhook) != 0 {
+		u, err := url.Parse(settings.NotifyWebhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return fmt.Errorf("bad notify webhook '%s'", settings.NotifyWebhook)
+		}
+	}
+	if len(settings.NotifyEmail) != 0 {
+		if _, err := mail.ParseAddress(settings.NotifyEmail); err != nil {
+			return fmt.Errorf("bad notify email '%s'", settings.NotifyEmail)
+		}
+	}
+	return nil
+}
+
+// SetUserSettings: replace the settings of the user, empty values clear the settings.
+func (s *Server) SetUserSettings(w http.ResponseWriter, r *http.Request) {
+	uid, err := userID(r)
+	if err != nil {
+		renderFailureFormat(w, r, http.StatusBadRequest, "bad user id: %v", err)
+		return
+	}
+	var settings database.UserSettings
+	if !limitBody(w, r, s.BodyLimits.Management.Size) {
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		renderRequestError(w, r, err, "input body error: %v")
+		return
+	}
+	if err := checkUserSettings(&settings); err != nil {
+		renderFailure(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := s.db.FindUser(r.Context(), uid); err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	settings.UID = uid
+	newSettings, err := s.db.SetUserSettings(r.Context(), &settings)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, newSettings)
+}
+
 func (s *Server) ManagementRouter(r *mux.Router) {
 	r.HandleFunc("/api/v1/user", s.NewUser).Methods("POST")
+	r.HandleFunc("/api/v1/user/{id:[0-9]+}/settings", s.GetUserSettings).Methods("GET")
+	r.HandleFunc("/api/v1/user/{id:[0-9]+}/settings", s.SetUserSettings).Methods("PUT")
 	r.HandleFunc("/api/v1/key", s.NewKey).Methods("POST")
+	r.HandleFunc("/api/v1/key/{id:[0-9]+}", s.GetKey).Methods("GET")
+	r.HandleFunc("/api/v1/key/{id:[0-9]+}", s.DeleteKey).Methods("DELETE")
+	r.HandleFunc("/api/v1/deploy-key", s.NewDeployKey).Methods("POST")
 	r.HandleFunc("/api/v1/repo", s.NewRepo).Methods("POST")
+	r.HandleFunc("/api/v1/path-permissions", s.SetPathPermissions).Methods("POST")
+	r.HandleFunc("/api/v1/templates", s.ExportTemplates).Methods("GET")
+	r.HandleFunc("/api/v1/templates", s.ImportTemplates).Methods("PUT")
+	r.HandleFunc("/api/v1/templates/{kind}", s.DeleteTemplate).Methods("DELETE")
+	r.HandleFunc("/api/v1/jobs", s.NewJob).Methods("POST")
+	r.HandleFunc("/api/v1/jobs", s.ListJobs).Methods("GET")
+	r.HandleFunc("/api/v1/jobs/{id:[0-9]+}", s.GetJob).Methods("GET")
+	r.HandleFunc("/api/v1/jobs/{id:[0-9]+}/cancel", s.CancelJob).Methods("POST")
+	r.HandleFunc("/api/v1/tiers", s.GetTiers).Methods("GET")
 }
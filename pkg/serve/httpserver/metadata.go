@@ -19,6 +19,7 @@ const (
 	DeepenFrom = "deepen-from" // shallow base
 	Deepen     = "deepen"      // deepen <depth>
 	Have       = "have"        // local have
+	Limit      = "limit"       // max trees and fragments per response, the rest is returned as cursor
 )
 
 // checkDeepen: check deepen and deepen-from, if deepen-from is set, ignore deepen
@@ -63,12 +64,30 @@ func (s *Server) checkDepth(w http.ResponseWriter, r *Request) (int, error) {
 	return depth, nil
 }
 
+// checkLimit: 0 means no limit
+func (s *Server) checkLimit(w http.ResponseWriter, r *Request) (int, error) {
+	l := r.URL.Query().Get(Limit)
+	if l == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(l)
+	if err != nil || limit < 0 {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad limit value '%s'", l)
+		return 0, ErrStop
+	}
+	return limit, nil
+}
+
 func (s *Server) FetchMetadata(w http.ResponseWriter, r *Request) {
 	depth, err := s.checkDepth(w, r)
 	if err != nil {
 		return
 	}
-	deepen, have, deepenFrom, err := s.checkDeepen(w, r)
+	limit, err := s.checkLimit(w, r)
+	if err != nil {
+		return
+	}
+	deepen, deepenFrom, have, err := s.checkDeepen(w, r)
 	if err != nil {
 		return
 	}
@@ -87,7 +106,14 @@ func (s *Server) FetchMetadata(w http.ResponseWriter, r *Request) {
 		renderFailureFormat(w, r.Request, http.StatusNotFound, "rev %s target not commit", rev)
 		return
 	}
-	p, err := protocol.NewHttpPacker(rr.ODB(), w, r.Request, depth)
+	if checkNotModified(w, r.Request, metadataETag(r, ro, r.Paths...), metadataCacheControl(r, rev)) {
+		return
+	}
+	if len(r.Paths) != 0 {
+		// restricted users receive the allowed dirs only, cursors are based on the full tree
+		limit = 0
+	}
+	p, err := protocol.NewHttpPacker(rr.ODB(), w, r.Request, depth, limit)
 	if err != nil {
 		logrus.Errorf("new packer error %v", err)
 		return
@@ -99,7 +125,12 @@ func (s *Server) FetchMetadata(w http.ResponseWriter, r *Request) {
 			return
 		}
 	}
-	if err := p.WriteDeepenMetadata(r.Context(), ro.Target, deepenFrom, have, deepen); err != nil {
+	if len(r.Paths) != 0 {
+		err = p.WriteDeepenSparseMetadata(r.Context(), ro.Target, deepenFrom, have, deepen, r.Paths)
+	} else {
+		err = p.WriteDeepenMetadata(r.Context(), ro.Target, deepenFrom, have, deepen)
+	}
+	if err != nil {
 		logrus.Errorf("write commits error %v", err)
 		return
 	}
@@ -121,11 +152,25 @@ func (s *Server) GetSparseMetadata(w http.ResponseWriter, r *Request) {
 	if err != nil {
 		return
 	}
+	limit, err := s.checkLimit(w, r)
+	if err != nil {
+		return
+	}
+	if !limitBody(w, r.Request, s.BodyLimits.SparseMetadata.Size) {
+		return
+	}
 	paths, err := protocol.ReadInputPaths(r.Body)
 	if err != nil {
-		renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad input paths: %v", err)
+		renderRequestError(w, r.Request, err, "bad input paths: %v")
 		return
 	}
+	if len(r.Paths) != 0 {
+		if paths = protocol.RestrictPaths(r.Paths, paths); len(paths) == 0 {
+			renderFailure(w, r.Request, http.StatusForbidden, r.W("no access to the requested paths"))
+			return
+		}
+		limit = 0
+	}
 
 	deepen, deepenFrom, have, err := s.checkDeepen(w, r)
 	if err != nil {
@@ -145,8 +190,11 @@ func (s *Server) GetSparseMetadata(w http.ResponseWriter, r *Request) {
 		renderFailureFormat(w, r.Request, http.StatusNotFound, "rev %s target not commit", rev)
 		return
 	}
+	if checkNotModified(w, r.Request, metadataETag(r, ro, paths...), metadataCacheControl(r, rev)) {
+		return
+	}
 	cc := ro.Target
-	p, err := protocol.NewHttpPacker(rr.ODB(), w, r.Request, depth)
+	p, err := protocol.NewHttpPacker(rr.ODB(), w, r.Request, depth, limit)
 	if err != nil {
 		logrus.Errorf("new packer error %v", err)
 		return
@@ -169,13 +217,25 @@ func (s *Server) GetSparseMetadata(w http.ResponseWriter, r *Request) {
 }
 
 func (s *Server) BatchMetadata(w http.ResponseWriter, r *Request) {
+	if len(r.Paths) != 0 {
+		// trees requested by oid cannot be checked against the allowed dirs
+		renderFailure(w, r.Request, http.StatusForbidden, r.W("batch metadata is not available to users restricted to some dirs"))
+		return
+	}
 	depth, err := s.checkDepth(w, r)
 	if err != nil {
 		return
 	}
+	limit, err := s.checkLimit(w, r)
+	if err != nil {
+		return
+	}
+	if !limitBody(w, r.Request, s.BodyLimits.BatchMetadata.Size) {
+		return
+	}
 	oids, err := protocol.ReadInputOIDs(r.Body)
 	if err != nil {
-		renderFailureFormat(w, r.Request, http.StatusBadRequest, "batch metadata: %v", err)
+		renderRequestError(w, r.Request, err, "batch metadata: %v")
 		return
 	}
 	rr, err := s.open(w, r)
@@ -193,7 +253,7 @@ func (s *Server) BatchMetadata(w http.ResponseWriter, r *Request) {
 		}
 		objects = append(objects, a)
 	}
-	p, err := protocol.NewHttpPacker(rr.ODB(), w, r.Request, depth)
+	p, err := protocol.NewHttpPacker(rr.ODB(), w, r.Request, depth, limit)
 	if err != nil {
 		logrus.Errorf("new packer error %v", err)
 		return
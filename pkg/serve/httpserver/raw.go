@@ -0,0 +1,212 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/streamio"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// sniffLen: http.DetectContentType considers at most the first 512 bytes
+	sniffLen = 512
+	// rawTextPlain: text of any kind is served as plain text, browsers never render or execute it
+	rawTextPlain = "text/plain; charset=utf-8"
+	// rawSecurityPolicy: a document opened directly still cannot run scripts or load anything
+	rawSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; sandbox"
+)
+
+var (
+	// rawArchiveTypes: archives the content sniffing does not know
+	rawArchiveTypes = map[string]string{
+		".7z":  "application/x-7z-compressed",
+		".bz2": "application/x-bzip2",
+		".jar": "application/java-archive",
+		".tar": "application/x-tar",
+		".tgz": "application/gzip",
+		".xz":  "application/x-xz",
+		".zst": "application/zstd",
+	}
+)
+
+// rawContentType returns the content type of a file and whether browsers should show it inline. The type comes from
+// the content, the extension only names the archives sniffing misses. Types a browser would render as a document
+// (html, xml, svg) are served as plain text.
+func rawContentType(name string, head []byte, symlink bool) (string, bool) {
+	if symlink {
+		return rawTextPlain, true
+	}
+	contentType := http.DetectContentType(head)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "text/plain":
+		return contentType, true
+	case strings.HasPrefix(mediaType, "text/"), strings.HasSuffix(mediaType, "xml"):
+		return rawTextPlain, true
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "audio/"), strings.HasPrefix(mediaType, "video/"), mediaType == "application/pdf":
+		return contentType, true
+	case mediaType == "application/octet-stream":
+		if t, ok := rawArchiveTypes[strings.ToLower(path.Ext(name))]; ok {
+			return t, false
+		}
+	}
+	return contentType, false
+}
+
+// rawDisposition: ?inline=true and ?download=true override the disposition chosen by the content type.
+func rawDisposition(r *http.Request) (inline bool, forced bool, err error) {
+	q := r.URL.Query()
+	var in, download bool
+	if v := q.Get("inline"); len(v) != 0 {
+		if in, err = strconv.ParseBool(v); err != nil {
+			return false, false, fmt.Errorf("bad inline value '%s'", v)
+		}
+	}
+	if v := q.Get("download"); len(v) != 0 {
+		if download, err = strconv.ParseBool(v); err != nil {
+			return false, false, fmt.Errorf("bad download value '%s'", v)
+		}
+	}
+	switch {
+	case in && download:
+		return false, false, errors.New("inline and download cannot be used together")
+	case in:
+		return true, true, nil
+	case download:
+		return false, true, nil
+	}
+	return false, false, nil
+}
+
+// rawRange returns the requested range, nil for the whole file. Like net/http, invalid ranges and multiple ranges
+// are ignored and the whole file is sent, If-Range with another ETag also asks for the whole file.
+func rawRange(r *http.Request, size int64, etag string) (*protocol.Range, error) {
+	rangeHdr := r.Header.Get("Range")
+	if len(rangeHdr) == 0 {
+		return nil, nil
+	}
+	if ir := r.Header.Get("If-Range"); len(ir) != 0 && ir != etag {
+		return nil, nil
+	}
+	ranges, err := protocol.ParseRange(rangeHdr, size)
+	if errors.Is(err, protocol.ErrNoOverlap) {
+		return nil, err
+	}
+	if err != nil || len(ranges) != 1 {
+		return nil, nil
+	}
+	return &ranges[0], nil
+}
+
+// GET /{namespace}/{repo}/raw?path=...&ref=...&inline=true|download=true
+func (s *Server) Raw(w http.ResponseWriter, r *Request) {
+	q := r.URL.Query()
+	rev := q.Get("ref")
+	if len(rev) == 0 {
+		rev = protocol.HEAD
+	}
+	inline, forced, err := rawDisposition(r.Request)
+	if err != nil {
+		renderFailure(w, r.Request, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(r.Paths) != 0 && !protocol.PathVisible(r.Paths, q.Get("path")) {
+		renderFailure(w, r.Request, http.StatusForbidden, r.W("no access to the requested paths"))
+		return
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		return
+	}
+	defer rr.Close() // nolint
+	f, err := rr.Raw(r.Context(), rev, q.Get("path"))
+	if err != nil {
+		if e, ok := errors.AsType[*repo.ErrBadRawRequest](err); ok {
+			renderFailure(w, r.Request, http.StatusBadRequest, e.Error())
+			return
+		}
+		s.renderError(w, r, err)
+		return
+	}
+	// the content of a file is identified by its object
+	etag := fmt.Sprintf("\"%s\"", f.Entry.Hash)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	rg, err := rawRange(r.Request, f.Size, etag)
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", f.Size))
+		renderFailure(w, r.Request, http.StatusRequestedRangeNotSatisfiable, err.Error())
+		return
+	}
+	rc, err := f.Open(r.Context(), 0)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(rc, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		_ = rc.Close()
+		s.renderError(w, r, err)
+		return
+	}
+	head = head[:n]
+	contentType, inlineByType := rawContentType(f.Name, head, f.IsSymlink())
+	if !forced {
+		inline = inlineByType
+	}
+	var body io.Reader = io.MultiReader(bytes.NewReader(head), rc)
+	length := f.Size
+	statusCode := http.StatusOK
+	if rg != nil {
+		if rg.Start != 0 {
+			// fragments before the range are not read
+			_ = rc.Close()
+			if rc, err = f.Open(r.Context(), rg.Start); err != nil {
+				s.renderError(w, r, err)
+				return
+			}
+			body = rc
+		}
+		body = io.LimitReader(body, rg.Length)
+		length = rg.Length
+		statusCode = http.StatusPartialContent
+		w.Header().Set("Content-Range", rg.ContentRange(f.Size))
+	}
+	defer rc.Close() // nolint
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": f.Name}))
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", rawSecurityPolicy)
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(statusCode)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := streamio.Copy(w, body); err != nil {
+		logrus.Errorf("raw %s/%s %s error: %v", r.N.Path, r.R.Path, q.Get("path"), err)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+)
+
+const (
+	referenceLogsLimit    = 100
+	referenceLogsMaxLimit = 1000
+)
+
+type ReferenceLogsResponse struct {
+	Entries   []*database.ReferenceLog `json:"entries"`
+	PublicKey string                   `json:"public_key,omitempty"` // base64 ed25519 public key
+	Next      int64                    `json:"next,omitempty"`       // resume with ?after=, zero when no more entries
+}
+
+type ReferenceLogVerification struct {
+	Verified   bool              `json:"verified"`
+	Signed     bool              `json:"signed"`
+	Entries    int64             `json:"entries"`
+	Hash       string            `json:"hash"` // hash of the last verified entry
+	PublicKey  string            `json:"public_key,omitempty"`
+	BrokenAt   int64             `json:"broken_at,omitempty"`
+	Reason     string            `json:"reason,omitempty"`
+	References map[string]string `json:"references"` // references replayed from the log, HEAD maps to its branch
+}
+
+func (s *Server) referencePublicKey() string {
+	if pub := s.db.ReferencePublicKey(); pub != nil {
+		return base64.StdEncoding.EncodeToString(pub)
+	}
+	return ""
+}
+
+// GET /{namespace}/{repo}/reference-logs?after=...&limit=...
+func (s *Server) ReferenceLogs(w http.ResponseWriter, r *Request) {
+	limit, err := s.checkLimit(w, r)
+	if err != nil {
+		return
+	}
+	if limit == 0 {
+		limit = referenceLogsLimit
+	}
+	limit = min(limit, referenceLogsMaxLimit)
+	var after int64
+	if a := r.URL.Query().Get("after"); len(a) != 0 {
+		if after, err = strconv.ParseInt(a, 10, 64); err != nil || after < 0 {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad after value '%s'", a)
+			return
+		}
+	}
+	entries, err := s.db.ReferenceLogs(r.Context(), r.R.ID, after, limit)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	resp := &ReferenceLogsResponse{Entries: entries, PublicKey: s.referencePublicKey()}
+	if len(entries) == limit {
+		resp.Next = entries[len(entries)-1].Seq
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	JsonEncode(w, resp)
+}
+
+// GET /{namespace}/{repo}/reference-logs/verify
+func (s *Server) VerifyReferenceLogs(w http.ResponseWriter, r *Request) {
+	pub := s.db.ReferencePublicKey()
+	v := &ReferenceLogVerification{Hash: plumbing.ZERO_OID, PublicKey: s.referencePublicKey(), References: make(map[string]string)}
+	for {
+		entries, err := s.db.ReferenceLogs(r.Context(), r.R.ID, v.Entries, referenceLogsMaxLimit)
+		if err != nil {
+			s.renderError(w, r, err)
+			return
+		}
+		if v.Signed, err = database.VerifyReferenceLogs(v.Entries, v.Hash, v.Signed, entries, pub); err != nil {
+			if e, ok := errors.AsType[*database.ErrReferenceLogBroken](err); ok {
+				v.BrokenAt, v.Reason = e.Seq, e.Reason
+				break
+			}
+			s.renderError(w, r, err)
+			return
+		}
+		for _, e := range entries {
+			if len(e.NewTarget) != 0 {
+				// symbolic reference: HEAD points to the branch
+				v.References[string(e.Name)] = e.NewTarget
+				continue
+			}
+			if e.NewRev == plumbing.ZERO_OID {
+				delete(v.References, string(e.Name))
+			} else {
+				v.References[string(e.Name)] = e.NewRev
+			}
+		}
+		if len(entries) != 0 {
+			last := entries[len(entries)-1]
+			v.Entries, v.Hash = last.Seq, last.Hash
+		}
+		if len(entries) < referenceLogsMaxLimit {
+			v.Verified = true
+			break
+		}
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	JsonEncode(w, v)
+}
@@ -4,9 +4,12 @@
 package httpserver
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
+	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/database"
 )
@@ -16,10 +19,20 @@ type Request struct {
 	U *database.User
 	N *database.Namespace
 	R *database.Repository
+	// Paths: dirs the user is restricted to, empty when not restricted
+	Paths []string
+}
+
+// Language returns the language of the messages, the user's stored language wins over the request headers.
+func (r *Request) Language() string {
+	if r.U != nil {
+		return serve.PreferredLanguage(r.Request, r.U.Language)
+	}
+	return serve.Language(r.Request)
 }
 
 func (r *Request) W(message string) string {
-	return serve.W(r.Request, message)
+	return serve.Translate(r.Language(), message)
 }
 
 func resolveScheme(r *http.Request) string {
@@ -38,3 +51,32 @@ func resolveScheme(r *http.Request) string {
 func (r *Request) makeRemoteURL() string {
 	return fmt.Sprintf("%s://%s/%s/%s", resolveScheme(r.Request), r.Host, r.N.Path, r.R.Path)
 }
+
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// limitBody rejects the request when Content-Length exceeds limit, chunked bodies fail with ErrBodyTooLarge while reading.
+func limitBody(w http.ResponseWriter, r *http.Request, limit int64) bool {
+	if limit > 0 && r.ContentLength > limit {
+		renderBodyTooLarge(w, r, limit)
+		return false
+	}
+	r.Body = &limitedBody{Reader: serve.NewLimitedReader(r.Body, limit), Closer: r.Body}
+	return true
+}
+
+func renderBodyTooLarge(w http.ResponseWriter, r *http.Request, limit int64) {
+	renderFailureFormat(w, r, http.StatusRequestEntityTooLarge,
+		serve.W(r, "request body exceeds the limit of %s, please split it into smaller batches, eg: lower 'transport.maxEntries'"), strengthen.FormatSize(limit))
+}
+
+// renderRequestError renders 413 when the body exceeds the limit, otherwise 400.
+func renderRequestError(w http.ResponseWriter, r *http.Request, err error, format string) {
+	if e, ok := errors.AsType[*serve.ErrBodyTooLarge](err); ok {
+		renderBodyTooLarge(w, r, e.Limit)
+		return
+	}
+	renderFailureFormat(w, r, http.StatusBadRequest, format, err)
+}
@@ -15,6 +15,8 @@ import (
 
 	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/extension"
+	"github.com/antgroup/hugescm/pkg/serve/jobs"
 	"github.com/antgroup/hugescm/pkg/serve/protocol"
 	"github.com/antgroup/hugescm/pkg/serve/repo"
 	"github.com/gorilla/mux"
@@ -29,6 +31,8 @@ type Server struct {
 	r          *mux.Router
 	db         database.DB
 	hub        repo.Repositories
+	ext        *extension.Set
+	jobs       *jobs.Runner
 	serverName string
 }
 
@@ -53,12 +57,23 @@ func (s *Server) ProtocolZ1Router(r *mux.Router) {
 	r.HandleFunc("/{namespace}/{repo}/authorization", s.ShareAuthorization).Methods("POST").MatcherFunc(Z1Matcher) // AUTH: shard signature auth
 	// Zeta Protocol: FETCH APIs
 	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}", s.OnFunc(s.LsReference, protocol.DOWNLOAD)).Methods("GET").MatcherFunc(Z1Matcher)        // CHECKOUT: fetch reference
+	r.HandleFunc("/{namespace}/{repo}/references", s.OnFunc(s.LsReferences, protocol.DOWNLOAD)).Methods("GET").MatcherFunc(Z1Matcher)                   // ENHANCED: list all references, required to import repositories
 	r.HandleFunc("/{namespace}/{repo}/metadata/batch", s.OnFunc(s.BatchMetadata, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher)             // CHECKOUT: batch metadata for FUSE
 	r.HandleFunc("/{namespace}/{repo}/metadata/{revision:.*}", s.OnFunc(s.FetchMetadata, protocol.DOWNLOAD)).Methods("GET").MatcherFunc(Z1Matcher)      // CHECKOUT: download commit and tree/subtrees metadata ...
 	r.HandleFunc("/{namespace}/{repo}/metadata/{revision:.*}", s.OnFunc(s.GetSparseMetadata, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher) // CHECKOUT: sparse checkout
 	r.HandleFunc("/{namespace}/{repo}/objects/batch", s.OnFunc(s.BatchObjects, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher)               // ENHANCED: batch objects Required to migrate from zeta to git
 	r.HandleFunc("/{namespace}/{repo}/objects/share", s.OnFunc(s.ShareObjects, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher)               // CHECKOUT: shared signed oss urls
+	r.HandleFunc("/{namespace}/{repo}/objects/exists", s.OnFunc(s.ObjectsExists, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher)             // ENHANCED: bulk query objects existence
 	r.HandleFunc("/{namespace}/{repo}/objects/{oid}", s.OnFunc(s.GetObject, protocol.DOWNLOAD)).Methods("GET").MatcherFunc(Z1Matcher)                   // ENHANCED: download object Required to migrate from zeta to git
+	r.HandleFunc("/{namespace}/{repo}/templates", s.OnFunc(s.Templates, protocol.DOWNLOAD)).Methods("GET").MatcherFunc(Z1Matcher)                       // CHECKOUT: organization-wide ignore and attribute templates
+	r.HandleFunc("/{namespace}/{repo}/history", s.OnFunc(s.History, protocol.DOWNLOAD)).Methods("GET")                                                  // WEB: file history, Z1 header not required
+	r.HandleFunc("/{namespace}/{repo}/blame", s.OnFunc(s.Blame, protocol.DOWNLOAD)).Methods("GET")                                                      // WEB: blame of a file, Z1 header not required
+	r.HandleFunc("/{namespace}/{repo}/archive", s.OnFunc(s.Archive, protocol.DOWNLOAD)).Methods("GET")                                                  // WEB: reproducible source archive, Z1 header not required
+	r.HandleFunc("/{namespace}/{repo}/raw", s.OnFunc(s.Raw, protocol.DOWNLOAD)).Methods("GET", "HEAD")                                                  // WEB: raw file content, Z1 header not required
+	r.HandleFunc("/{namespace}/{repo}/reference-logs", s.OnFunc(s.ReferenceLogs, protocol.DOWNLOAD)).Methods("GET")                                     // AUDIT: signed reference log, Z1 header not required
+	r.HandleFunc("/{namespace}/{repo}/reference-logs/verify", s.OnFunc(s.VerifyReferenceLogs, protocol.DOWNLOAD)).Methods("GET")                        // AUDIT: verify the reference log chain
+	// Zeta Protocol: MANAGEMENT APIs
+	r.HandleFunc("/{namespace}/{repo}/default-branch", s.OnFunc(s.SetDefaultBranch, protocol.SUDO)).Methods("PUT").MatcherFunc(Z1Matcher) // MANAGE: change default branch (HEAD)
 	// Zeta Protocol: PUSH APIs
 	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}/objects/batch", s.OnFunc(s.BatchCheck, protocol.UPLOAD)).Methods("POST").MatcherFunc(NewZ1AcceptMatcher(ZETA_MIME_VND_JSON)) // PUSH: batch check large objects
 	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}/objects/{oid}", s.OnFunc(s.PutObject, protocol.UPLOAD)).Methods("PUT").MatcherFunc(Z1Matcher)                                // PUSH: PUT one large object
@@ -96,13 +111,18 @@ func NewServer(sc *ServerConfig) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	if srv.db, err = database.NewDB(cfg); err != nil {
+	if srv.db, err = database.NewDB(cfg, database.WithReferenceKey(sc.ReferenceSigner)); err != nil {
 		return nil, err
 	}
-	if srv.hub, err = repo.NewRepositories(sc.Repositories, sc.PersistentOSS, sc.Cache, srv.db); err != nil {
+	if srv.ext, err = extension.Load(sc.Extensions); err != nil {
 		_ = srv.db.Close()
 		return nil, err
 	}
+	if srv.hub, err = repo.NewRepositories(sc.Repositories, sc.PersistentOSS, sc.Tiering, sc.Cache, sc.CommitPolicy, sc.PathPolicy, srv.db, srv.ext); err != nil {
+		_ = srv.db.Close()
+		return nil, err
+	}
+	srv.jobs = jobs.NewRunner(srv.db)
 	return srv, nil
 }
 
@@ -163,6 +183,10 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	if err := s.srv.Shutdown(ctx); err != nil {
 		logrus.Errorf("shutdown ssh server %v", err)
 	}
+	if s.jobs != nil {
+		// running jobs are recorded as canceled
+		s.jobs.Shutdown()
+	}
 	if s.db != nil {
 		_ = s.db.Close()
 	}
@@ -0,0 +1,117 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxTemplateSize: templates are downloaded by every checkout and fetch, keep them small
+	maxTemplateSize = 256 << 10
+)
+
+// GET /{namespace}/{repo}/templates
+func (s *Server) Templates(w http.ResponseWriter, r *Request) {
+	templates, err := s.hub.Templates(r.Context(), r.N.ID)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	// the version of the templates is the entity tag, clients revalidate with If-None-Match
+	if checkNotModified(w, r.Request, "\""+templates.Version+"\"", cacheControlRevalidate) {
+		return
+	}
+	ZetaEncodeVND(w, templates)
+}
+
+type NewTemplate struct {
+	NamespacePath string `json:"namespace_path,omitempty"` // empty: global template
+	Kind          string `json:"kind"`                     // ignore or attributes
+	Content       string `json:"content"`
+}
+
+func validateTemplateKind(kind string) bool {
+	return kind == database.TemplateIgnore || kind == database.TemplateAttributes
+}
+
+// ExportTemplates: all templates, the response can be imported by ImportTemplates.
+func (s *Server) ExportTemplates(w http.ResponseWriter, r *http.Request) {
+	items, err := s.db.ListTemplates(r.Context())
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, items)
+}
+
+// ImportTemplates: create or replace templates, the version of a template is increased only when its content changes.
+func (s *Server) ImportTemplates(w http.ResponseWriter, r *http.Request) {
+	var newTemplates []*NewTemplate
+	if !limitBody(w, r, s.BodyLimits.Management.Size) {
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&newTemplates); err != nil {
+		renderRequestError(w, r, err, "input body error: %v")
+		return
+	}
+	templates := make([]*database.Template, 0, len(newTemplates))
+	for _, t := range newTemplates {
+		if !validateTemplateKind(t.Kind) {
+			renderFailureFormat(w, r, http.StatusBadRequest, "bad template kind '%s'", t.Kind)
+			return
+		}
+		if len(t.Content) > maxTemplateSize {
+			renderFailureFormat(w, r, http.StatusBadRequest, "template '%s' of '%s' too large", t.Kind, t.NamespacePath)
+			return
+		}
+		nt := &database.Template{Kind: t.Kind, Content: t.Content}
+		if len(t.NamespacePath) != 0 {
+			n, err := s.db.FindNamespaceByPath(r.Context(), t.NamespacePath)
+			if err != nil {
+				s.renderErrorRaw(w, r, err)
+				return
+			}
+			nt.NamespaceID = n.ID
+		}
+		templates = append(templates, nt)
+	}
+	items := make([]*database.Template, 0, len(templates))
+	for _, t := range templates {
+		item, err := s.db.SetTemplate(r.Context(), t)
+		if err != nil {
+			s.renderErrorRaw(w, r, err)
+			return
+		}
+		items = append(items, item)
+	}
+	JsonEncode(w, items)
+}
+
+// DeleteTemplate: remove the template of kind, namespace_path selects the template of a namespace.
+func (s *Server) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	kind := mux.Vars(r)["kind"]
+	if !validateTemplateKind(kind) {
+		renderFailureFormat(w, r, http.StatusBadRequest, "bad template kind '%s'", kind)
+		return
+	}
+	var namespaceID int64
+	if namespacePath := r.URL.Query().Get("namespace_path"); len(namespacePath) != 0 {
+		n, err := s.db.FindNamespaceByPath(r.Context(), namespacePath)
+		if err != nil {
+			s.renderErrorRaw(w, r, err)
+			return
+		}
+		namespaceID = n.ID
+	}
+	if err := s.db.DeleteTemplate(r.Context(), namespaceID, kind); err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,27 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+)
+
+// GetTiers: distribution of the large objects of a repository between the hot and the cold tier.
+func (s *Server) GetTiers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	_, repo, err := s.db.FindRepositoryByPath(r.Context(), query.Get("namespace_path"), query.Get("repo_path"))
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	report, err := odb.StatTiers(r.Context(), s.hub.Bucket(), s.hub.ColdBucket(), odb.NewMetadataDB(s.db.Database(), repo.ID), repo.ID, time.Now().Add(-s.Tiering.ColdAfter()))
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, report)
+}
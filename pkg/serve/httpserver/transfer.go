@@ -8,6 +8,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"os"
@@ -20,8 +21,8 @@ import (
 	"github.com/antgroup/hugescm/modules/streamio"
 	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/modules/zeta"
-	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/notify"
 	"github.com/antgroup/hugescm/pkg/serve/protocol"
 	"github.com/antgroup/hugescm/pkg/serve/repo"
 	"github.com/gorilla/mux"
@@ -37,9 +38,12 @@ const (
 	ZETA_PROTOCOL        = "Zeta-Protocol"
 	ZETA_COMMAND_OLDREV  = "X-Zeta-Command-OldRev"
 	ZETA_COMMAND_NEWREV  = "X-Zeta-Command-NewRev"
+	ZETA_COMMAND_DRY_RUN = "X-Zeta-Command-Dry-Run"
 	ZETA_TERMINAL        = "X-Zeta-Terminal"
 	ZETA_OBJECTS_STATS   = "X-Zeta-Objects-Stats"
 	ZETA_COMPRESSED_SIZE = "X-Zeta-Compressed-Size"
+	ZETA_BATCH_INTEGRITY = "X-Zeta-Batch-Integrity"
+	ZETA_DELTA_BASE      = "X-Zeta-Delta-Base"
 	// ZETA Protocol Content Type
 	ZETA_MIME_BLOB          = "application/x-zeta-blob"
 	ZETA_MIME_BLOBS         = "application/x-zeta-blobs"
@@ -53,9 +57,12 @@ const (
 
 // ShareAuthorization: POST /{namespace}/{repo}/authorization
 func (s *Server) ShareAuthorization(w http.ResponseWriter, r *http.Request) {
+	if !limitBody(w, r, s.BodyLimits.Authorization.Size) {
+		return
+	}
 	var sa protocol.SASHandshake
 	if err := json.NewDecoder(r.Body).Decode(&sa); err != nil {
-		renderFailureFormat(w, r, http.StatusBadRequest, "decode handshake error: %v", err)
+		renderRequestError(w, r, err, "decode handshake error: %v")
 		return
 	}
 	req, err := s.basicAuth(w, r, sa.Operation, r.Header.Get(AUTHORIZATION))
@@ -72,7 +79,8 @@ func (s *Server) ShareAuthorization(w http.ResponseWriter, r *http.Request) {
 		Header: protocol.PayloadHeader{
 			Authorization: BearerPrefix + token,
 		},
-		ExpiresAt: expiresAt,
+		ExpiresAt:  expiresAt,
+		ServerTime: time.Now(),
 	})
 }
 
@@ -95,8 +103,9 @@ func (s *Server) LsBranchReference(w http.ResponseWriter, r *Request, branchName
 		Agent:           s.serverName,
 		HashAlgo:        r.R.HashAlgo,
 		CompressionAlgo: r.R.CompressionAlgo,
+		Capabilities:    protocol.Capabilities,
 	}
-	ZetaEncodeVND(w, branch)
+	ZetaEncodeVNDConditional(w, r.Request, branch)
 }
 
 func (s *Server) LsTagReference(w http.ResponseWriter, r *Request, tagName string) {
@@ -120,8 +129,9 @@ func (s *Server) LsTagReference(w http.ResponseWriter, r *Request, tagName strin
 		Agent:           s.serverName,
 		HashAlgo:        r.R.HashAlgo,
 		CompressionAlgo: r.R.CompressionAlgo,
+		Capabilities:    protocol.Capabilities,
 	}
-	ZetaEncodeVND(w, branch)
+	ZetaEncodeVNDConditional(w, r.Request, branch)
 }
 
 func (s *Server) LsOrdinaryReference(w http.ResponseWriter, r *Request, refname plumbing.ReferenceName) {
@@ -143,8 +153,9 @@ func (s *Server) LsOrdinaryReference(w http.ResponseWriter, r *Request, refname
 		Agent:           s.serverName,
 		HashAlgo:        r.R.HashAlgo,
 		CompressionAlgo: r.R.CompressionAlgo,
+		Capabilities:    protocol.Capabilities,
 	}
-	ZetaEncodeVND(w, branch)
+	ZetaEncodeVNDConditional(w, r.Request, branch)
 }
 
 // GET /{namespace}/{repo}/reference/{refname:.*}
@@ -173,11 +184,39 @@ func (s *Server) LsReference(w http.ResponseWriter, r *Request) {
 	s.LsBranchReference(w, r, refname)
 }
 
+// GET /{namespace}/{repo}/references
+func (s *Server) LsReferences(w http.ResponseWriter, r *Request) {
+	rr, err := s.open(w, r)
+	if err != nil {
+		return
+	}
+	defer rr.Close() // nolint
+	items, err := rr.LsReferences(r.Context())
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	refs := &protocol.References{
+		Remote:          r.makeRemoteURL(),
+		HEAD:            protocol.BRANCH_PREFIX + r.R.DefaultBranch,
+		Version:         int(protocol.PROTOCOL_VERSION),
+		Agent:           s.serverName,
+		HashAlgo:        r.R.HashAlgo,
+		CompressionAlgo: r.R.CompressionAlgo,
+		Capabilities:    protocol.Capabilities,
+		References:      items,
+	}
+	ZetaEncodeVNDConditional(w, r.Request, refs)
+}
+
 // POST /{namespace}/{repo}/objects/batch
 func (s *Server) BatchObjects(w http.ResponseWriter, r *Request) {
+	if !limitBody(w, r.Request, s.BodyLimits.BatchObjects.Size) {
+		return
+	}
 	oids, err := protocol.ReadInputOIDs(r.Body)
 	if err != nil {
-		renderFailureFormat(w, r.Request, http.StatusBadRequest, "batch-oids: %v", err)
+		renderRequestError(w, r.Request, err, "batch-oids: %v")
 		return
 	}
 	rr, err := s.open(w, r)
@@ -195,7 +234,13 @@ func (s *Server) BatchObjects(w http.ResponseWriter, r *Request) {
 		streamio.PutBufferWriter(buffedWriter)
 	}()
 	cw := crc.NewCrc64Writer(buffedWriter)
-	if err := protocol.WriteBatchObjectsHeader(cw); err != nil {
+	var flags byte
+	writeItem := protocol.WriteObjectsItem
+	if r.Header.Get(ZETA_BATCH_INTEGRITY) == protocol.BATCH_INTEGRITY_CRC64 {
+		flags |= protocol.BATCH_FLAG_ITEM_CRC64
+		writeItem = protocol.WriteObjectsItemWithChecksum
+	}
+	if err := protocol.WriteBatchObjectsHeader(cw, flags); err != nil {
 		logrus.Errorf("write blob header error: %v", err)
 		return
 	}
@@ -213,7 +258,7 @@ func (s *Server) BatchObjects(w http.ResponseWriter, r *Request) {
 			return nil
 		}
 		defer sr.Close() // nolint
-		return protocol.WriteObjectsItem(cw, sr, oid.String(), sr.Size())
+		return writeItem(cw, sr, oid.String(), sr.Size())
 	}
 	for _, oid := range oids {
 		if err := writeFunc(oid); err != nil {
@@ -229,9 +274,12 @@ func (s *Server) BatchObjects(w http.ResponseWriter, r *Request) {
 
 // POST /{namespace}/{repo}/objects/share
 func (s *Server) ShareObjects(w http.ResponseWriter, r *Request) {
-	var request protocol.BatchShareObjectsRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		renderFailureFormat(w, r.Request, http.StatusBadRequest, "decode request body error: %v", err)
+	if !limitBody(w, r.Request, s.BodyLimits.ShareObjects.Size) {
+		return
+	}
+	request, err := protocol.DecodeBatchShareObjectsRequest(r.Body)
+	if err != nil {
+		renderRequestError(w, r.Request, err, "decode request body error: %v")
 		return
 	}
 	rr, err := s.open(w, r)
@@ -240,12 +288,15 @@ func (s *Server) ShareObjects(w http.ResponseWriter, r *Request) {
 	}
 	defer rr.Close() // nolint
 
+	now := time.Now()
 	response := &protocol.BatchShareObjectsResponse{
-		Objects: make([]*protocol.Representation, 0, len(request.Objects)),
+		Objects:    make([]*protocol.Representation, 0, len(request.Objects)),
+		ServerTime: now,
 	}
 	odb := rr.ODB()
-	ExpiresAt := time.Now().Add(time.Hour * 2)
-	expiresAt := ExpiresAt.Unix()
+	ExpiresAt := now.Add(time.Hour * 2)
+	// links stay valid a little longer than reported, tolerating clients whose clock is behind
+	expiresAt := ExpiresAt.Add(s.ClockSkew.Duration).Unix()
 	for _, o := range request.Objects {
 		if o == nil {
 			renderFailureFormat(w, r.Request, http.StatusBadRequest, "require object is nil")
@@ -268,6 +319,29 @@ func (s *Server) ShareObjects(w http.ResponseWriter, r *Request) {
 	ZetaEncodeVND(w, response)
 }
 
+// POST /{namespace}/{repo}/objects/exists
+func (s *Server) ObjectsExists(w http.ResponseWriter, r *Request) {
+	if !limitBody(w, r.Request, s.BodyLimits.BatchObjects.Size) {
+		return
+	}
+	oids, err := protocol.ReadExistsOIDs(r.Body)
+	if err != nil {
+		renderRequestError(w, r.Request, err, "exists-oids: %v")
+		return
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		return
+	}
+	defer rr.Close() // nolint
+	exists, err := rr.ODB().Exists(r.Context(), oids)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	ZetaEncodeVND(w, protocol.NewObjectsExistsResponse(exists))
+}
+
 // GET /{namespace}/{repo}/objects/{oid}
 func (s *Server) GetObject(w http.ResponseWriter, r *Request) {
 	rg, err := protocol.ParseRangeEx(r.Request)
@@ -353,9 +427,12 @@ func (s *Server) updateReferenceDryRun(w http.ResponseWriter, r *Request) bool {
 
 // POST /{namespace}/{repo}/reference/{refname:.*}/objects/batch
 func (s *Server) BatchCheck(w http.ResponseWriter, r *Request) {
-	var request protocol.BatchCheckRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		renderFailureFormat(w, r.Request, http.StatusBadRequest, "decode request body error: %v", err)
+	if !limitBody(w, r.Request, s.BodyLimits.BatchCheck.Size) {
+		return
+	}
+	request, err := protocol.DecodeBatchCheckRequest(r.Body)
+	if err != nil {
+		renderRequestError(w, r.Request, err, "decode request body error: %v")
 		return
 	}
 	if !s.updateReferenceDryRun(w, r) {
@@ -412,6 +489,7 @@ func (s *Server) PutObject(w http.ResponseWriter, r *Request) {
 	if us := r.Header.Get(ZETA_COMPRESSED_SIZE); len(us) != 0 {
 		if uploadSize, err = strconv.ParseInt(us, 10, 64); err != nil {
 			renderFailureFormat(w, r.Request, http.StatusBadRequest, "'x-zeta-compressed-size' value not valid number: '%s'", us)
+			return
 		}
 	}
 	sid := mux.Vars(r.Request)["oid"]
@@ -429,9 +507,23 @@ func (s *Server) PutObject(w http.ResponseWriter, r *Request) {
 	}
 	defer rr.Close() // nolint
 
+	if baseID := r.Header.Get(ZETA_DELTA_BASE); len(baseID) != 0 {
+		if !plumbing.ValidateHashHex(baseID) {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "invalid delta base: %s", baseID)
+			return
+		}
+		size, err := rr.ODB().WriteDelta(r.Context(), oid, plumbing.NewHash(baseID), r.Body, uploadSize)
+		if err != nil {
+			renderFailureFormat(w, r.Request, http.StatusConflict, "upload object '%s' error: %v", sid, err)
+			return
+		}
+		logrus.Infof("%s upload large object %s [size: %s, delta: %s] to %s [refname: %s] success", checkName(r), sid, strengthen.FormatSize(size), strengthen.FormatSize(uploadSize), r.makeRemoteURL(), mux.Vars(r.Request)["refname"])
+		ZetaEncodeVND(w, &protocol.ErrorCode{Code: 200, Message: "OK"})
+		return
+	}
 	size, err := rr.ODB().WriteDirect(r.Context(), oid, r.Body, uploadSize)
 	if err != nil {
-		renderFailureFormat(w, r.Request, http.StatusConflict, "upload object '%s' error: %v", err, sid)
+		renderFailureFormat(w, r.Request, http.StatusConflict, "upload object '%s' error: %v", sid, err)
 		return
 	}
 	logrus.Infof("%s upload large object %s [size: %s] to %s [refname: %s] success", checkName(r), sid, strengthen.FormatSize(size), r.makeRemoteURL(), mux.Vars(r.Request)["refname"])
@@ -467,7 +559,9 @@ func (s *Server) checkBranchCanUpdate(ctx context.Context, w http.ResponseWriter
 		return nil, ErrStop
 	case ProtectedBranch:
 		if !r.U.Administrator {
-			renderFailureFormat(w, r.Request, http.StatusForbidden, r.W("'%s' is protected branch, cannot be modified"), branchName)
+			message := fmt.Sprintf(r.W("'%s' is protected branch, cannot be modified"), branchName)
+			s.notifyPushFailure(r, branchName, http.StatusForbidden, message)
+			renderFailure(w, r.Request, http.StatusForbidden, message)
 			return nil, ErrStop
 		}
 		return branch, nil
@@ -476,6 +570,29 @@ func (s *Server) checkBranchCanUpdate(ctx context.Context, w http.ResponseWriter
 	return branch, nil
 }
 
+// notifyPushFailure notifies the user of a rejected push to a protected branch, see the user's settings. Dry-run
+// pushes are not notified.
+func (s *Server) notifyPushFailure(r *Request, branchName string, code int, message string) {
+	if isDryRun(r) {
+		return
+	}
+	notify.PushFailed(s.db, &notify.PushFailure{
+		UID:           r.U.ID,
+		UserName:      r.U.UserName,
+		Repository:    r.N.Path + "/" + r.R.Path,
+		ReferenceName: string(plumbing.NewBranchReferenceName(branchName)),
+		OldRev:        r.Header.Get(ZETA_COMMAND_OLDREV),
+		NewRev:        r.Header.Get(ZETA_COMMAND_NEWREV),
+		Status:        code,
+		Message:       message,
+	})
+}
+
+// isDryRun: zeta push --dry-run, the push is validated but the reference is not updated
+func isDryRun(r *Request) bool {
+	return len(r.Header.Get(ZETA_COMMAND_DRY_RUN)) != 0
+}
+
 // POST /{namespace}/{repo}/reference/{refname:.*}
 func (s *Server) Push(w http.ResponseWriter, r *Request) {
 	escapedRefname := mux.Vars(r.Request)["refname"]
@@ -524,7 +641,9 @@ func (s *Server) TagPush(w http.ResponseWriter, r *Request, tagName string) {
 		OldRev:        r.Header.Get("X-Zeta-Command-OldRev"),
 		NewRev:        r.Header.Get("X-Zeta-Command-NewRev"),
 		Terminal:      r.Header.Get("X-Zeta-Terminal"),
-		Language:      serve.Language(r.Request),
+		Language:      r.Language(),
+		Paths:         r.Paths,
+		DryRun:        isDryRun(r),
 	}
 	if !plumbing.ValidateHashHex(command.NewRev) {
 		renderFailureFormat(w, r.Request, http.StatusBadRequest, "NewRev '%s' is bad commit", command.NewRev)
@@ -569,7 +688,10 @@ func (s *Server) BranchPush(w http.ResponseWriter, r *Request, branchName string
 		OldRev:        r.Header.Get("X-Zeta-Command-OldRev"),
 		NewRev:        r.Header.Get("X-Zeta-Command-NewRev"),
 		Terminal:      r.Header.Get("X-Zeta-Terminal"),
-		Language:      serve.Language(r.Request),
+		Language:      r.Language(),
+		Paths:         r.Paths,
+		Protected:     oldBranch != nil && oldBranch.ProtectionLevel == ProtectedBranch,
+		DryRun:        isDryRun(r),
 	}
 	if !plumbing.ValidateHashHex(command.NewRev) {
 		renderFailureFormat(w, r.Request, http.StatusBadRequest, "NewRev '%s' is bad commit", command.NewRev)
@@ -598,6 +720,13 @@ func (s *Server) BranchPush(w http.ResponseWriter, r *Request, branchName string
 		if errors.As(err, &es) {
 			renderFailure(w, r.Request, es.Code, es.Message)
 		}
+		if command.Protected {
+			code := http.StatusForbidden
+			if es != nil {
+				code = es.Code
+			}
+			s.notifyPushFailure(r, branchName, code, command.Reason(err))
+		}
 		return
 	}
 }
@@ -0,0 +1,331 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package importer provisions a hosted repository from another HugeSCM server or from a local zeta repository.
+package importer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+)
+
+const (
+	// largeSize: objects larger than largeSize are downloaded one by one and resumed from the last byte, same
+	// as the default transport.largeSize of the client
+	largeSize = 5 << 20
+	// existsBatchSize: max objects of one existence query
+	existsBatchSize = 1000
+)
+
+// Reference: a reference of the source repository.
+type Reference struct {
+	Name plumbing.ReferenceName
+	Hash plumbing.Hash
+}
+
+// Source: the repository to import from.
+type Source interface {
+	// References returns the references to import and the default branch of the source.
+	References(ctx context.Context) ([]*Reference, string, error)
+	// Open returns a database holding every object reachable from refs. Remote sources download the objects to
+	// staging, the objects already in staging are not downloaded again, which makes an interrupted import
+	// resumable.
+	Open(ctx context.Context, refs []*Reference, staging string) (*backend.Database, error)
+	// Close closes the database returned by Open, Close can be called more than once.
+	Close() error
+}
+
+type Options struct {
+	RID             int64
+	UID             int64  // recorded in the reference logs
+	Root            string // repositories root
+	CompressionAlgo string
+	DB              database.DB
+	Cache           odb.CacheDB
+	Bucket          oss.Bucket
+	Source          Source
+	References      []*Reference
+	Progress        func(format string, a ...any)
+}
+
+func (opts *Options) progress(format string, a ...any) {
+	if opts.Progress != nil {
+		opts.Progress(format, a...)
+	}
+}
+
+// StagingPath returns the staging directory of an import of repository rid, it exists until the import succeeds.
+func StagingPath(root string, rid int64) string {
+	return repo.RepositoryPath(root, rid) + ".import"
+}
+
+type Result struct {
+	Commits     int
+	Trees       int
+	MetaObjects int
+	Objects     int
+	References  int
+}
+
+// Import copies every object reachable from opts.References into the repository and creates the references.
+// Objects and references the repository already has are skipped, running Import again after a failure resumes
+// the import. The staging directory is removed when the import succeeds.
+func Import(ctx context.Context, opts *Options) (*Result, error) {
+	staging := StagingPath(opts.Root, opts.RID)
+	src, err := opts.Source.Open(ctx, opts.References, staging)
+	if err != nil {
+		return nil, err
+	}
+	o, err := odb.NewODB(opts.RID, repo.RepositoryPath(opts.Root, opts.RID), opts.CompressionAlgo, opts.Cache, odb.NewMetadataDB(opts.DB.Database(), opts.RID), opts.Bucket, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer o.Close() // nolint
+	c := newCollector(src)
+	for _, ref := range opts.References {
+		opts.progress("Counting objects of %s", ref.Name)
+		if err := c.collect(ctx, ref.Hash); err != nil {
+			if plumbing.IsNoSuchObject(err) {
+				return nil, fmt.Errorf("source repository is incomplete, %s: %w", ref.Name, err)
+			}
+			return nil, err
+		}
+	}
+	if err := c.skipExisting(ctx, o); err != nil {
+		return nil, err
+	}
+	result := &Result{Commits: len(c.commits), Trees: len(c.trees), MetaObjects: len(c.metaObjects), Objects: len(c.objects)}
+	opts.progress("Storing %d commits, %d trees, %d fragments and tags, %d objects", result.Commits, result.Trees, result.MetaObjects, result.Objects)
+	// objects before metadata, metadata before references: the repository never references a missing object
+	if err := o.BatchObjects(ctx, src, c.objects, 50); err != nil {
+		return nil, fmt.Errorf("store objects: %w", err)
+	}
+	if err := o.BatchMetaObjects(ctx, src, c.metaObjects); err != nil {
+		return nil, fmt.Errorf("store fragments and tags: %w", err)
+	}
+	if err := o.BatchTrees(ctx, src, c.trees); err != nil {
+		return nil, fmt.Errorf("store trees: %w", err)
+	}
+	if err := o.BatchCommits(ctx, src, c.commits); err != nil {
+		return nil, fmt.Errorf("store commits: %w", err)
+	}
+	if result.References, err = updateReferences(ctx, opts, src); err != nil {
+		return nil, err
+	}
+	if err := opts.Source.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(staging); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func messageSplit(message string) (string, string) {
+	if i := strings.IndexAny(message, "\r\n"); i != -1 {
+		return message[0:i], message[i+1:]
+	}
+	return message, ""
+}
+
+func updateReferences(ctx context.Context, opts *Options, src *backend.Database) (int, error) {
+	current, err := opts.DB.ListReferences(ctx, opts.RID)
+	if err != nil {
+		return 0, err
+	}
+	existing := make(map[plumbing.ReferenceName]string, len(current))
+	for _, ref := range current {
+		existing[ref.Name] = ref.Hash
+	}
+	var updated int
+	for _, ref := range opts.References {
+		cmd := &database.Command{
+			ReferenceName: ref.Name,
+			OldRev:        plumbing.ZERO_OID,
+			NewRev:        ref.Hash.String(),
+			RID:           opts.RID,
+			UID:           opts.UID,
+		}
+		if oldRev, ok := existing[ref.Name]; ok {
+			if oldRev == cmd.NewRev {
+				continue
+			}
+			cmd.OldRev = oldRev
+		}
+		if ref.Name.IsTag() {
+			if to, err := src.Tag(ctx, ref.Hash); err == nil {
+				message, _ := to.Extract()
+				cmd.Subject, cmd.Description = messageSplit(message)
+			}
+		}
+		if _, err := opts.DB.DoReferenceUpdate(ctx, cmd); err != nil {
+			return updated, fmt.Errorf("update reference %s: %w", ref.Name, err)
+		}
+		opts.progress("%s -> %s", ref.Name, ref.Hash)
+		updated++
+	}
+	return updated, nil
+}
+
+type collector struct {
+	src         *backend.Database
+	seen        map[plumbing.Hash]bool
+	commits     []plumbing.Hash
+	trees       []plumbing.Hash
+	metaObjects []plumbing.Hash
+	objects     []plumbing.Hash
+}
+
+func newCollector(src *backend.Database) *collector {
+	return &collector{src: src, seen: make(map[plumbing.Hash]bool)}
+}
+
+// collect walks the tags, commits, trees and fragments reachable from oid.
+func (c *collector) collect(ctx context.Context, oid plumbing.Hash) error {
+	for !c.seen[oid] {
+		a, err := c.src.Object(ctx, oid)
+		if err != nil {
+			return err
+		}
+		switch v := a.(type) {
+		case *object.Tag:
+			c.seen[oid] = true
+			c.metaObjects = append(c.metaObjects, oid)
+			oid = v.Object
+		case *object.Commit:
+			return c.collectCommits(ctx, v)
+		default:
+			return backend.NewErrMismatchedObjectType(oid, "commit")
+		}
+	}
+	return nil
+}
+
+func (c *collector) collectCommits(ctx context.Context, cc *object.Commit) error {
+	c.seen[cc.Hash] = true
+	pending := []*object.Commit{cc}
+	for len(pending) != 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		cc = pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		c.commits = append(c.commits, cc.Hash)
+		if err := c.collectTreeOID(ctx, cc.Tree); err != nil {
+			return err
+		}
+		for _, p := range cc.Parents {
+			if c.seen[p] {
+				continue
+			}
+			c.seen[p] = true
+			parent, err := c.src.Commit(ctx, p)
+			if err != nil {
+				return err
+			}
+			pending = append(pending, parent)
+		}
+	}
+	return nil
+}
+
+func (c *collector) collectTreeOID(ctx context.Context, oid plumbing.Hash) error {
+	if c.seen[oid] {
+		return nil
+	}
+	t, err := c.src.Tree(ctx, oid)
+	if err != nil {
+		return err
+	}
+	return c.collectTree(ctx, t)
+}
+
+func (c *collector) collectTree(ctx context.Context, t *object.Tree) error {
+	c.seen[t.Hash] = true
+	if t.Hash == plumbing.EmptyTree {
+		return nil
+	}
+	c.trees = append(c.trees, t.Hash)
+	for _, e := range t.Entries {
+		switch e.Type() {
+		case object.TreeObject:
+			if err := c.collectTreeOID(ctx, e.Hash); err != nil {
+				return err
+			}
+		case object.FragmentsObject:
+			if err := c.collectFragments(ctx, e.Hash); err != nil {
+				return err
+			}
+		case object.BlobObject:
+			if len(e.Payload) == 0 {
+				c.addObject(e.Hash)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *collector) collectFragments(ctx context.Context, oid plumbing.Hash) error {
+	if c.seen[oid] {
+		return nil
+	}
+	c.seen[oid] = true
+	f, err := c.src.Fragments(ctx, oid)
+	if err != nil {
+		return err
+	}
+	c.metaObjects = append(c.metaObjects, oid)
+	for _, e := range f.Entries {
+		c.addObject(e.Hash)
+	}
+	return nil
+}
+
+func (c *collector) addObject(oid plumbing.Hash) {
+	if c.seen[oid] || oid == backend.BLANK_BLOB_HASH {
+		return
+	}
+	c.seen[oid] = true
+	c.objects = append(c.objects, oid)
+}
+
+func filterExisting(ctx context.Context, o *odb.ODB, oids []plumbing.Hash) ([]plumbing.Hash, error) {
+	missing := make([]plumbing.Hash, 0, len(oids))
+	for len(oids) > 0 {
+		n := min(len(oids), existsBatchSize)
+		exists, err := o.Exists(ctx, oids[:n])
+		if err != nil {
+			return nil, err
+		}
+		for i, ok := range exists {
+			if !ok {
+				missing = append(missing, oids[i])
+			}
+		}
+		oids = oids[n:]
+	}
+	return missing, nil
+}
+
+// skipExisting drops the objects the repository already has, they were stored by an interrupted import.
+func (c *collector) skipExisting(ctx context.Context, o *odb.ODB) error {
+	var err error
+	for _, oids := range []*[]plumbing.Hash{&c.commits, &c.trees, &c.metaObjects, &c.objects} {
+		if *oids, err = filterExisting(ctx, o, *oids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+func writeObject(t *testing.T, d *backend.Database, e object.Encoder) plumbing.Hash {
+	oid, err := d.WriteEncoded(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return oid
+}
+
+func writeBlob(t *testing.T, d *backend.Database, content string) plumbing.Hash {
+	oid, err := d.HashTo(t.Context(), strings.NewReader(content), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return oid
+}
+
+func writeRef(t *testing.T, zetaDir, name, content string) {
+	p := filepath.Join(zetaDir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalSource(t *testing.T) {
+	worktree := t.TempDir()
+	zetaDir := filepath.Join(worktree, ".zeta")
+	d, err := backend.NewDatabase(zetaDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readme := writeBlob(t, d, "readme\n")
+	chunk := writeBlob(t, d, "chunk\n")
+	fragments := writeObject(t, d, &object.Fragments{Size: 6, Entries: []*object.Fragment{{Index: 0, Size: 6, Hash: chunk}}})
+	sub := writeObject(t, d, &object.Tree{Entries: []*object.TreeEntry{{Name: "large.bin", Mode: filemode.Regular | filemode.Fragments, Hash: fragments, Size: 6}}})
+	root := writeObject(t, d, &object.Tree{Entries: []*object.TreeEntry{
+		{Name: "README", Mode: filemode.Regular, Hash: readme, Size: 7},
+		{Name: "sub", Mode: filemode.Dir, Hash: sub},
+	}})
+	first := writeObject(t, d, &object.Commit{Tree: plumbing.EmptyTree, Message: "first\n"})
+	second := writeObject(t, d, &object.Commit{Tree: root, Parents: []plumbing.Hash{first}, Message: "second\n"})
+	tag := writeObject(t, d, &object.Tag{Object: first, ObjectType: object.CommitObject, Name: "v1.0", Content: "release v1.0\n"})
+	_ = d.Close()
+
+	writeRef(t, zetaDir, "HEAD", "ref: refs/heads/mainline")
+	writeRef(t, zetaDir, "refs/heads/mainline", second.String())
+	writeRef(t, zetaDir, "refs/tags/v1.0", tag.String())
+	writeRef(t, zetaDir, "refs/remotes/origin/mainline", first.String())
+
+	s, err := NewLocalSource(worktree)
+	if err != nil {
+		t.Fatalf("new local source error: %v", err)
+	}
+	defer s.Close() // nolint
+	refs, defaultBranch, err := s.References(t.Context())
+	if err != nil {
+		t.Fatalf("references error: %v", err)
+	}
+	if defaultBranch != "mainline" {
+		t.Fatalf("unexpected default branch %q", defaultBranch)
+	}
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		names = append(names, string(ref.Name))
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"refs/heads/mainline", "refs/tags/v1.0"}) {
+		t.Fatalf("unexpected references %v", names)
+	}
+
+	src, err := s.Open(t.Context(), refs, "")
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	c := newCollector(src)
+	for _, ref := range refs {
+		if err := c.collect(t.Context(), ref.Hash); err != nil {
+			t.Fatalf("collect %s error: %v", ref.Name, err)
+		}
+	}
+	for _, e := range []struct {
+		name string
+		got  []plumbing.Hash
+		want []plumbing.Hash
+	}{
+		{"commits", c.commits, []plumbing.Hash{first, second}},
+		{"trees", c.trees, []plumbing.Hash{root, sub}},
+		{"meta objects", c.metaObjects, []plumbing.Hash{fragments, tag}},
+		{"objects", c.objects, []plumbing.Hash{readme, chunk}},
+	} {
+		got := slices.Clone(e.got)
+		slices.SortFunc(got, func(a, b plumbing.Hash) int { return strings.Compare(a.String(), b.String()) })
+		want := slices.Clone(e.want)
+		slices.SortFunc(want, func(a, b plumbing.Hash) int { return strings.Compare(a.String(), b.String()) })
+		if !slices.Equal(got, want) {
+			t.Errorf("unexpected %s: %v, want %v", e.name, got, want)
+		}
+	}
+
+	if _, err := NewLocalSource(t.TempDir()); err == nil {
+		t.Fatalf("expected error for a directory without a zeta repository")
+	}
+}
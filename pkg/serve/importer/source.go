@@ -0,0 +1,249 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/refs"
+	"github.com/antgroup/hugescm/pkg/transport"
+	zodb "github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+const (
+	// metadataLimit: max trees and fragments per metadata response, the rest is fetched by cursors
+	metadataLimit = 100000
+	// batchLimit: max objects per batch objects request
+	batchLimit = 1000
+)
+
+// ErrUnsupportedSource: the source server can not list all references.
+var ErrUnsupportedSource = errors.New("the source server does not support listing references, upgrade it first")
+
+// importable: branches, tags and other references, remote-tracking references are not imported.
+func importable(name plumbing.ReferenceName) bool {
+	return name.HasReferencePrefix() && !name.IsRemote()
+}
+
+type localSource struct {
+	zetaDir string
+	db      *backend.Database
+}
+
+// NewLocalSource returns a source reading a local zeta repository, p is the worktree or the .zeta directory
+// of the repository. The repository must have the complete history and all objects.
+func NewLocalSource(p string) (Source, error) {
+	zetaDir := p
+	if filepath.Base(p) != ".zeta" {
+		zetaDir = filepath.Join(p, ".zeta")
+	}
+	if _, err := os.Stat(filepath.Join(zetaDir, "HEAD")); err != nil {
+		return nil, fmt.Errorf("'%s' is not a zeta repository", p)
+	}
+	return &localSource{zetaDir: zetaDir}, nil
+}
+
+func (s *localSource) References(ctx context.Context) ([]*Reference, string, error) {
+	rdb, err := refs.ReferencesDB(s.zetaDir)
+	if err != nil {
+		return nil, "", err
+	}
+	items := make([]*Reference, 0, len(rdb.References()))
+	for _, ref := range rdb.References() {
+		if ref.Type() != plumbing.HashReference || !importable(ref.Name()) {
+			continue
+		}
+		items = append(items, &Reference{Name: ref.Name(), Hash: ref.Hash()})
+	}
+	var defaultBranch string
+	if head := rdb.HEAD(); head != nil && head.Type() == plumbing.SymbolicReference && head.Target().IsBranch() {
+		defaultBranch = head.Target().BranchName()
+	}
+	return items, defaultBranch, nil
+}
+
+// Open opens the database of the local repository, nothing is staged.
+func (s *localSource) Open(ctx context.Context, _ []*Reference, _ string) (*backend.Database, error) {
+	db, err := backend.NewDatabase(s.zetaDir)
+	if err != nil {
+		return nil, err
+	}
+	s.db = db
+	return db, nil
+}
+
+func (s *localSource) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+type remoteSource struct {
+	t               transport.Transport
+	o               *zodb.ODB
+	compressionAlgo string
+	progress        func(format string, a ...any)
+}
+
+// NewRemoteSource returns a source downloading the repository from another HugeSCM server.
+func NewRemoteSource(t transport.Transport, progress func(format string, a ...any)) Source {
+	if progress == nil {
+		progress = func(format string, a ...any) {}
+	}
+	return &remoteSource{t: t, progress: progress}
+}
+
+func (s *remoteSource) References(ctx context.Context) ([]*Reference, string, error) {
+	ref, err := s.t.FetchReference(ctx, plumbing.HEAD)
+	if err != nil && !errors.Is(err, transport.ErrReferenceNotExist) {
+		return nil, "", err
+	}
+	if ref != nil && !ref.HasCapability(transport.CAPABILITY_LIST_REFERENCES) {
+		return nil, "", ErrUnsupportedSource
+	}
+	rr, err := s.t.ListReferences(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	s.compressionAlgo = rr.CompressionALGO
+	items := make([]*Reference, 0, len(rr.References))
+	for _, r := range rr.References {
+		if !importable(r.Name) {
+			continue
+		}
+		items = append(items, &Reference{Name: r.Name, Hash: plumbing.NewHash(r.Hash)})
+	}
+	return items, strings.TrimPrefix(rr.HEAD, plumbing.ReferencePrefix+"heads/"), nil
+}
+
+func (s *remoteSource) unpackMetadata(rc transport.SessionReader) ([]plumbing.Hash, error) {
+	cursor, err := s.o.MetadataUnpackCursor(rc, true)
+	if err != nil {
+		_ = rc.Close()
+		if lastErr := rc.LastError(); lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, err
+	}
+	_ = rc.Close()
+	return cursor, nil
+}
+
+// fetchMetadata downloads the full history of target, large histories are continued with cursors.
+func (s *remoteSource) fetchMetadata(ctx context.Context, target plumbing.Hash) error {
+	rc, err := s.t.FetchMetadata(ctx, target, &transport.MetadataOptions{
+		Deepen: transport.AnyDeepen,
+		Depth:  transport.AnyDepth,
+		Limit:  metadataLimit,
+	})
+	if err != nil {
+		return err
+	}
+	cursor, err := s.unpackMetadata(rc)
+	if err != nil {
+		return err
+	}
+	for len(cursor) != 0 {
+		n := min(len(cursor), metadataLimit)
+		if rc, err = s.t.BatchMetadata(ctx, cursor[:n], transport.AnyDepth, metadataLimit); err != nil {
+			return err
+		}
+		next, err := s.unpackMetadata(rc)
+		if err != nil {
+			return err
+		}
+		cursor = append(next, cursor[n:]...)
+	}
+	return nil
+}
+
+func (s *remoteSource) batch(ctx context.Context, oids []plumbing.Hash) error {
+	for len(oids) > 0 {
+		n := min(len(oids), batchLimit)
+		rc, err := s.t.BatchObjects(ctx, oids[:n])
+		if err != nil {
+			return err
+		}
+		if err := s.o.Unpack(rc, n, true); err != nil {
+			_ = rc.Close()
+			if lastErr := rc.LastError(); lastErr != nil {
+				return lastErr
+			}
+			return err
+		}
+		_ = rc.Close()
+		oids = oids[n:]
+	}
+	return s.o.Reload()
+}
+
+// Open downloads the metadata and the objects of refs to staging. Metadata is downloaded again on every run,
+// objects already in staging are skipped and large objects are resumed from the last byte.
+func (s *remoteSource) Open(ctx context.Context, refs []*Reference, staging string) (*backend.Database, error) {
+	o, err := zodb.NewODB(staging, backend.WithCompressionALGO(s.compressionAlgo))
+	if err != nil {
+		return nil, err
+	}
+	s.o = o
+	for _, ref := range refs {
+		s.progress("Fetching metadata of %s", ref.Name)
+		if err := s.fetchMetadata(ctx, ref.Hash); err != nil {
+			return nil, fmt.Errorf("fetch metadata of %s: %w", ref.Name, err)
+		}
+	}
+	if err := o.Reload(); err != nil {
+		return nil, err
+	}
+	larges := make([]*zodb.Entry, 0, 100)
+	seen := make(map[plumbing.Hash]bool)
+	for _, ref := range refs {
+		s.progress("Fetching objects of %s", ref.Name)
+		if err := o.CountingObjects(ctx, ref.Hash, plumbing.ZeroHash, 0, func(ctx context.Context, entries zodb.Entries) error {
+			smalls := make([]plumbing.Hash, 0, len(entries))
+			for _, e := range entries {
+				if e.Hash == backend.BLANK_BLOB_HASH || seen[e.Hash] {
+					continue
+				}
+				seen[e.Hash] = true
+				if e.Size > largeSize {
+					larges = append(larges, e)
+					continue
+				}
+				smalls = append(smalls, e.Hash)
+			}
+			return s.batch(ctx, smalls)
+		}); err != nil {
+			return nil, fmt.Errorf("fetch objects of %s: %w", ref.Name, err)
+		}
+	}
+	for i, e := range larges {
+		s.progress("Downloading large object %s (%s) [%d/%d]", e.Hash, strengthen.FormatSize(e.Size), i+1, len(larges))
+		if err := o.DoTransfer(ctx, e.Hash, func(offset int64) (transport.SizeReader, error) {
+			return s.t.GetObject(ctx, e.Hash, offset)
+		}, nil, zodb.NO_BAR); err != nil {
+			return nil, fmt.Errorf("download large object %s: %w", e.Hash, err)
+		}
+	}
+	return o.Database, nil
+}
+
+func (s *remoteSource) Close() error {
+	if s.o == nil {
+		return nil
+	}
+	err := s.o.Close()
+	s.o = nil
+	return err
+}
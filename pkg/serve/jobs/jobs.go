@@ -0,0 +1,213 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package jobs runs maintenance jobs of hosted repositories. Jobs are recorded in the jobs table, their progress
+// is saved periodically, a job is canceled by setting cancel_requested, which its runner checks on every progress
+// update, so any zeta-serve process can cancel a job of any other process.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/antgroup/hugescm/pkg/serve/database"
+)
+
+const (
+	// updateInterval: progress of running jobs is saved and cancel requests are checked once per updateInterval
+	updateInterval = 2 * time.Second
+)
+
+var (
+	ErrCanceled = errors.New("job canceled")
+)
+
+// Store: the tables of jobs, database.DB implements Store.
+type Store interface {
+	NewJob(ctx context.Context, j *database.Job) (*database.Job, error)
+	FindJob(ctx context.Context, id int64) (*database.Job, error)
+	UpdateJobProgress(ctx context.Context, id int64, stage string, current, total int64) (bool, error)
+	FinishJob(ctx context.Context, id int64, status database.JobStatus, message string) error
+}
+
+// Progress: progress of the current stage of a job, Total is 0 when unknown.
+type Progress struct {
+	Stage   string
+	Current int64
+	Total   int64
+}
+
+func (p Progress) String() string {
+	if p.Total > 0 {
+		return fmt.Sprintf("%s: %d%% (%d/%d)", p.Stage, p.Current*100/p.Total, p.Current, p.Total)
+	}
+	if p.Current > 0 {
+		return fmt.Sprintf("%s: %d", p.Stage, p.Current)
+	}
+	return p.Stage
+}
+
+// Reporter: tasks report their progress to the reporter, the runner saves it.
+type Reporter struct {
+	mu sync.Mutex
+	p  Progress
+}
+
+// Stage starts a new stage of total steps, total is 0 when unknown.
+func (r *Reporter) Stage(stage string, total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.p = Progress{Stage: stage, Total: total}
+}
+
+// Add completes n steps of the current stage.
+func (r *Reporter) Add(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.p.Current += n
+}
+
+func (r *Reporter) Progress() Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.p
+}
+
+// Task: the work of a job, it returns the result message. Tasks must return soon after ctx is canceled.
+type Task func(ctx context.Context, r *Reporter) (string, error)
+
+type Runner struct {
+	store    Store
+	name     string
+	interval time.Duration
+	mu       sync.Mutex
+	running  map[int64]context.CancelCauseFunc
+	wg       sync.WaitGroup
+}
+
+func NewRunner(store Store) *Runner {
+	hostname, _ := os.Hostname()
+	return &Runner{
+		store:    store,
+		name:     fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		interval: updateInterval,
+		running:  make(map[int64]context.CancelCauseFunc),
+	}
+}
+
+// Start records the job and runs task in the background, it returns the recorded job.
+func (r *Runner) Start(ctx context.Context, job *database.Job, task Task) (*database.Job, error) {
+	job.Runner = r.name
+	j, err := r.store.NewJob(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	jobCtx := r.register(context.Background(), j.ID)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		_, _ = r.run(jobCtx, j.ID, task, nil)
+	}()
+	return j, nil
+}
+
+// Run records the job and runs task, it returns the finished job. watch is called with the progress of the job
+// after each update, canceling ctx cancels the job.
+func (r *Runner) Run(ctx context.Context, job *database.Job, task Task, watch func(id int64, p Progress)) (*database.Job, error) {
+	job.Runner = r.name
+	j, err := r.store.NewJob(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	return r.run(r.register(ctx, j.ID), j.ID, task, watch)
+}
+
+// Cancel cancels a running job of this runner immediately, it returns false when the job does not run here. Jobs
+// of other runners are canceled by database.DB.CancelJob.
+func (r *Runner) Cancel(id int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancel, ok := r.running[id]
+	if ok {
+		cancel(ErrCanceled)
+	}
+	return ok
+}
+
+// Shutdown cancels the running jobs and waits for them to be recorded as canceled.
+func (r *Runner) Shutdown() {
+	r.mu.Lock()
+	for _, cancel := range r.running {
+		cancel(ErrCanceled)
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+func (r *Runner) register(ctx context.Context, id int64) context.Context {
+	ctx, cancel := context.WithCancelCause(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running[id] = cancel
+	return ctx
+}
+
+func (r *Runner) unregister(id int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cancel, ok := r.running[id]; ok {
+		cancel(nil)
+		delete(r.running, id)
+	}
+}
+
+func (r *Runner) run(ctx context.Context, id int64, task Task, watch func(id int64, p Progress)) (*database.Job, error) {
+	defer r.unregister(id)
+	// the job must be recorded after ctx is canceled
+	storeCtx := context.WithoutCancel(ctx)
+	rep := &Reporter{}
+	var result string
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		result, err = task(ctx, rep)
+	}()
+	update := func() {
+		p := rep.Progress()
+		cancelRequested, updateErr := r.store.UpdateJobProgress(storeCtx, id, p.Stage, p.Current, p.Total)
+		if updateErr == nil && cancelRequested {
+			r.Cancel(id)
+		}
+		if watch != nil {
+			watch(id, p)
+		}
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		case <-ticker.C:
+			update()
+		}
+	}
+	update()
+	status := database.JobSucceeded
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		status, result = database.JobCanceled, context.Cause(ctx).Error()
+	default:
+		status, result = database.JobFailed, err.Error()
+	}
+	if err := r.store.FinishJob(storeCtx, id, status, result); err != nil {
+		return nil, err
+	}
+	return r.store.FindJob(storeCtx, id)
+}
@@ -0,0 +1,226 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+)
+
+type memoryStore struct {
+	mu   sync.Mutex
+	jobs map[int64]*database.Job
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{jobs: make(map[int64]*database.Job)}
+}
+
+func (s *memoryStore) NewJob(ctx context.Context, j *database.Job) (*database.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nj := *j
+	nj.ID = int64(len(s.jobs) + 1)
+	nj.Status = database.JobRunning
+	s.jobs[nj.ID] = &nj
+	cj := nj
+	return &cj, nil
+}
+
+func (s *memoryStore) FindJob(ctx context.Context, id int64) (*database.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	cj := *j
+	return &cj, nil
+}
+
+func (s *memoryStore) UpdateJobProgress(ctx context.Context, id int64, stage string, current, total int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := s.jobs[id]
+	j.Stage, j.Current, j.Total = stage, current, total
+	return j.CancelRequested, nil
+}
+
+func (s *memoryStore) FinishJob(ctx context.Context, id int64, status database.JobStatus, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := s.jobs[id]
+	j.Status, j.Message = status, message
+	return nil
+}
+
+func (s *memoryStore) requestCancel(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[id].CancelRequested = true
+}
+
+func newTestRunner(store Store) *Runner {
+	r := NewRunner(store)
+	r.interval = 10 * time.Millisecond
+	return r
+}
+
+func TestRunner(t *testing.T) {
+	store := newMemoryStore()
+	r := newTestRunner(store)
+	j, err := r.Run(t.Context(), &database.Job{RID: 1, Kind: KindGC}, func(ctx context.Context, rep *Reporter) (string, error) {
+		rep.Stage("Counting", 3)
+		rep.Add(3)
+		return "done", nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Status != database.JobSucceeded || j.Message != "done" || j.Stage != "Counting" || j.Current != 3 || j.Total != 3 {
+		t.Fatalf("unexpected job %+v", j)
+	}
+	if j.Runner != r.name {
+		t.Fatalf("unexpected runner %q", j.Runner)
+	}
+
+	j, err = r.Run(t.Context(), &database.Job{RID: 1, Kind: KindFsck}, func(ctx context.Context, rep *Reporter) (string, error) {
+		return "", errors.New("2 missing objects")
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Status != database.JobFailed || j.Message != "2 missing objects" {
+		t.Fatalf("unexpected job %+v", j)
+	}
+}
+
+func TestRunnerCancel(t *testing.T) {
+	store := newMemoryStore()
+	r := newTestRunner(store)
+	var watched bool
+	j, err := r.Run(t.Context(), &database.Job{RID: 1, Kind: KindFsck}, func(ctx context.Context, rep *Reporter) (string, error) {
+		rep.Stage("Checking commits", 0)
+		// another process asks to cancel through the store
+		store.requestCancel(1)
+		<-ctx.Done()
+		return "", ctx.Err()
+	}, func(id int64, p Progress) {
+		watched = p.Stage == "Checking commits"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Status != database.JobCanceled || j.Message != ErrCanceled.Error() {
+		t.Fatalf("unexpected job %+v", j)
+	}
+	if !watched {
+		t.Fatalf("progress not watched")
+	}
+
+	started := make(chan struct{})
+	j, err = r.Start(t.Context(), &database.Job{RID: 1, Kind: KindGC}, func(ctx context.Context, rep *Reporter) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	r.Shutdown()
+	if j, err = store.FindJob(t.Context(), j.ID); err != nil {
+		t.Fatal(err)
+	}
+	if j.Status != database.JobCanceled {
+		t.Fatalf("unexpected job %+v", j)
+	}
+}
+
+func TestGC(t *testing.T) {
+	root := t.TempDir()
+	r := &database.Repository{ID: 7}
+	incoming := filepath.Join(repo.RepositoryPath(root, r.ID), "incoming")
+	stale := filepath.Join(incoming, "quarantine-stale")
+	fresh := filepath.Join(incoming, "quarantine-fresh")
+	for _, dir := range []string{stale, fresh} {
+		if err := os.MkdirAll(filepath.Join(dir, "blob"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "blob", "object"), []byte("object"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * quarantineExpiry)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+	rep := &Reporter{}
+	result, err := gc(t.Context(), &Env{Root: root}, r, rep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(result, "removed 1 stale quarantine directories") {
+		t.Fatalf("unexpected result %q", result)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("stale quarantine directory not removed: %v", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Fatalf("fresh quarantine directory removed: %v", err)
+	}
+	if p := rep.Progress(); p.Current != 2 || p.Total != 2 {
+		t.Fatalf("unexpected progress %+v", p)
+	}
+}
+
+func TestTierPolicy(t *testing.T) {
+	now := time.Now()
+	recent := plumbing.NewHash("1111111111111111111111111111111111111111111111111111111111111111")
+	accessed := plumbing.NewHash("2222222222222222222222222222222222222222222222222222222222222222")
+	unused := plumbing.NewHash("3333333333333333333333333333333333333333333333333333333333333333")
+	p := &tierPolicy{
+		recent:     map[plumbing.Hash]bool{recent: true},
+		accessed:   map[plumbing.Hash]bool{accessed: true},
+		coldBefore: now.Add(-24 * time.Hour),
+		minSize:    1024,
+	}
+	old := now.Add(-48 * time.Hour)
+	tests := []struct {
+		name string
+		oid  plumbing.Hash
+		o    *oss.Object
+		cold bool
+	}{
+		{"unused", unused, &oss.Object{Size: 4096, LastModified: old}, true},
+		{"recent commits", recent, &oss.Object{Size: 4096, LastModified: old}, false},
+		{"accessed", accessed, &oss.Object{Size: 4096, LastModified: old}, false},
+		{"uploaded recently", unused, &oss.Object{Size: 4096, LastModified: now}, false},
+		{"small", unused, &oss.Object{Size: 512, LastModified: old}, false},
+	}
+	for _, tt := range tests {
+		if got := p.cold(tt.oid, tt.o); got != tt.cold {
+			t.Errorf("%s: cold = %v, want %v", tt.name, got, tt.cold)
+		}
+	}
+}
+
+func TestNewTierTask(t *testing.T) {
+	r := &database.Repository{ID: 1}
+	if _, err := NewTask(&Env{}, KindTier, "", nil, r); err == nil {
+		t.Fatal("tier job without a cold tier should fail")
+	}
+	if _, err := NewTask(&Env{}, KindTier, "{bad", nil, r); err == nil {
+		t.Fatal("tier job with bad params should fail")
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/antgroup/hugescm/pkg/serve/backup"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+)
+
+const (
+	KindFsck   = "fsck"
+	KindGC     = "gc"
+	KindBackup = "backup"
+	KindTier   = "tier"
+)
+
+const (
+	// existsBatchSize: max objects of one existence query
+	existsBatchSize = 1000
+	// maxReportedMissing: max missing objects listed in the result of fsck
+	maxReportedMissing = 10
+)
+
+var (
+	// quarantineExpiry: quarantine directories are removed by pushes when they finish, a directory older than
+	// quarantineExpiry was left by a push interrupted by a crash
+	quarantineExpiry = 24 * time.Hour
+)
+
+// Kinds returns the supported kinds of jobs.
+func Kinds() []string {
+	return []string{KindFsck, KindGC, KindBackup, KindTier}
+}
+
+// Env: what tasks need to access the repositories.
+type Env struct {
+	DB      database.DB
+	Hub     repo.Repositories
+	Root    string // repositories root
+	Bucket  oss.Bucket
+	Cold    oss.Bucket // cold tier, nil when tiering is disabled
+	OSS     *serve.OSS
+	Tiering *serve.Tiering
+	Backup  *serve.Backup // allowed backup locations, nil: not restricted (command line)
+}
+
+// BackupParams: params of backup jobs.
+type BackupParams struct {
+	To   string `json:"to"`   // backup location, local directory or s3://bucket/prefix
+	Full bool   `json:"full"` // do not reuse the previous backup
+}
+
+// NewTask returns the task of a job of kind, params is the JSON encoded params of the kind.
+func NewTask(env *Env, kind string, params string, n *database.Namespace, r *database.Repository) (Task, error) {
+	switch kind {
+	case KindFsck:
+		return func(ctx context.Context, rep *Reporter) (string, error) {
+			return fsck(ctx, env, r, rep)
+		}, nil
+	case KindGC:
+		return func(ctx context.Context, rep *Reporter) (string, error) {
+			return gc(ctx, env, r, rep)
+		}, nil
+	case KindBackup:
+		var p BackupParams
+		if len(params) != 0 {
+			if err := json.Unmarshal([]byte(params), &p); err != nil {
+				return nil, fmt.Errorf("bad backup params: %w", err)
+			}
+		}
+		if len(p.To) == 0 {
+			return nil, errors.New("backup params: missing 'to'")
+		}
+		if env.Backup != nil {
+			to, err := env.Backup.Location(p.To)
+			if err != nil {
+				return nil, err
+			}
+			p.To = to
+		}
+		return func(ctx context.Context, rep *Reporter) (string, error) {
+			return runBackup(ctx, env, n, r, &p, rep)
+		}, nil
+	case KindTier:
+		var p TierParams
+		if len(params) != 0 {
+			if err := json.Unmarshal([]byte(params), &p); err != nil {
+				return nil, fmt.Errorf("bad tier params: %w", err)
+			}
+		}
+		if env.Cold == nil || env.Tiering == nil {
+			return nil, odb.ErrTieringDisabled
+		}
+		return func(ctx context.Context, rep *Reporter) (string, error) {
+			return tier(ctx, env, r, &p, rep)
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported job kind '%s', supported: %s", kind, strings.Join(Kinds(), ", "))
+}
+
+type checker struct {
+	o       odb.DB
+	rep     *Reporter
+	since   time.Time // parents committed before since are not walked, zero walks the whole history
+	seen    map[plumbing.Hash]bool
+	commits int
+	trees   int
+	objects []plumbing.Hash
+	missing []string
+}
+
+func (c *checker) addMissing(kind string, oid plumbing.Hash) {
+	c.missing = append(c.missing, fmt.Sprintf("%s %s", kind, oid))
+}
+
+// check walks the tags, commits, trees and fragments reachable from oid, missing metadata is recorded instead of
+// failing the walk.
+func (c *checker) check(ctx context.Context, oid plumbing.Hash) error {
+	for !c.seen[oid] {
+		c.seen[oid] = true
+		a, err := c.o.Objects(ctx, oid)
+		if plumbing.IsNoSuchObject(err) {
+			c.addMissing("object", oid)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch v := a.(type) {
+		case *object.Tag:
+			oid = v.Object
+		case *object.Commit:
+			return c.checkCommits(ctx, v)
+		default:
+			return backend.NewErrMismatchedObjectType(oid, "commit")
+		}
+	}
+	return nil
+}
+
+func (c *checker) checkCommits(ctx context.Context, cc *object.Commit) error {
+	pending := []*object.Commit{cc}
+	for len(pending) != 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		cc = pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		c.commits++
+		c.rep.Add(1)
+		if err := c.checkTree(ctx, cc.Tree); err != nil {
+			return err
+		}
+		for _, p := range cc.Parents {
+			if c.seen[p] {
+				continue
+			}
+			c.seen[p] = true
+			parent, err := c.o.Commit(ctx, p)
+			if plumbing.IsNoSuchObject(err) {
+				c.addMissing("commit", p)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if parent.Committer.When.Before(c.since) {
+				continue
+			}
+			pending = append(pending, parent)
+		}
+	}
+	return nil
+}
+
+func (c *checker) checkTree(ctx context.Context, oid plumbing.Hash) error {
+	if c.seen[oid] {
+		return nil
+	}
+	c.seen[oid] = true
+	if oid == plumbing.EmptyTree {
+		return nil
+	}
+	t, err := c.o.Tree(ctx, oid)
+	if plumbing.IsNoSuchObject(err) {
+		c.addMissing("tree", oid)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.trees++
+	for _, e := range t.Entries {
+		switch e.Type() {
+		case object.TreeObject:
+			if err := c.checkTree(ctx, e.Hash); err != nil {
+				return err
+			}
+		case object.FragmentsObject:
+			if err := c.checkFragments(ctx, e.Hash); err != nil {
+				return err
+			}
+		case object.BlobObject:
+			if len(e.Payload) == 0 {
+				c.addObject(e.Hash)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *checker) checkFragments(ctx context.Context, oid plumbing.Hash) error {
+	if c.seen[oid] {
+		return nil
+	}
+	c.seen[oid] = true
+	f, err := c.o.Fragments(ctx, oid)
+	if plumbing.IsNoSuchObject(err) {
+		c.addMissing("fragments", oid)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range f.Entries {
+		c.addObject(e.Hash)
+	}
+	return nil
+}
+
+func (c *checker) addObject(oid plumbing.Hash) {
+	if c.seen[oid] || oid == backend.BLANK_BLOB_HASH {
+		return
+	}
+	c.seen[oid] = true
+	c.objects = append(c.objects, oid)
+}
+
+// checkObjects checks that the blobs exist locally, in the metadata database or in oss.
+func (c *checker) checkObjects(ctx context.Context) error {
+	c.rep.Stage("Checking objects", int64(len(c.objects)))
+	for oids := c.objects; len(oids) > 0; {
+		n := min(len(oids), existsBatchSize)
+		exists, err := c.o.Exists(ctx, oids[:n])
+		if err != nil {
+			return err
+		}
+		for i, ok := range exists {
+			if !ok {
+				c.addMissing("blob", oids[i])
+			}
+		}
+		c.rep.Add(int64(n))
+		oids = oids[n:]
+	}
+	return nil
+}
+
+// fsck checks that every object reachable from the references of the repository exists.
+func fsck(ctx context.Context, env *Env, r *database.Repository, rep *Reporter) (string, error) {
+	rr, err := env.Hub.Open(ctx, r.ID, r.CompressionAlgo, r.DefaultBranch)
+	if err != nil {
+		return "", err
+	}
+	defer rr.Close() // nolint
+	refs, err := env.DB.ListReferences(ctx, r.ID)
+	if err != nil {
+		return "", err
+	}
+	c := &checker{o: rr.ODB(), rep: rep, seen: make(map[plumbing.Hash]bool)}
+	rep.Stage("Checking commits", 0)
+	for _, ref := range refs {
+		if err := c.check(ctx, plumbing.NewHash(ref.Hash)); err != nil {
+			return "", fmt.Errorf("check %s: %w", ref.Name, err)
+		}
+	}
+	if err := c.checkObjects(ctx); err != nil {
+		return "", err
+	}
+	if len(c.missing) != 0 {
+		reported := c.missing[:min(len(c.missing), maxReportedMissing)]
+		return "", fmt.Errorf("%d missing objects: %s", len(c.missing), strings.Join(reported, ", "))
+	}
+	return fmt.Sprintf("checked %d references, %d commits, %d trees, %d objects", len(refs), c.commits, c.trees, len(c.objects)), nil
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// gc removes the quarantine directories left by interrupted pushes.
+func gc(ctx context.Context, env *Env, r *database.Repository, rep *Reporter) (string, error) {
+	incoming := filepath.Join(repo.RepositoryPath(env.Root, r.ID), "incoming")
+	entries, err := os.ReadDir(incoming)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	rep.Stage("Removing stale quarantine directories", int64(len(entries)))
+	var removed int
+	var freed int64
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		rep.Add(1)
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), "quarantine-") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < quarantineExpiry {
+			continue
+		}
+		dir := filepath.Join(incoming, e.Name())
+		size := dirSize(dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return "", err
+		}
+		removed++
+		freed += size
+	}
+	return fmt.Sprintf("removed %d stale quarantine directories, freed %s", removed, strengthen.FormatSize(freed)), nil
+}
+
+func runBackup(ctx context.Context, env *Env, n *database.Namespace, r *database.Repository, p *BackupParams, rep *Reporter) (string, error) {
+	storage, err := backup.NewStorage(p.To, env.OSS)
+	if err != nil {
+		return "", fmt.Errorf("open backup location: %w", err)
+	}
+	m, err := backup.Backup(ctx, &backup.Options{
+		RID:       r.ID,
+		Namespace: n.Path,
+		Path:      r.Path,
+		Root:      env.Root,
+		DB:        env.DB.Database(),
+		Bucket:    env.Bucket,
+		Cold:      env.Cold,
+		Storage:   storage,
+		Full:      p.Full,
+		Progress: func(format string, a ...any) {
+			rep.Stage(fmt.Sprintf(format, a...), 0)
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(m.Parent) != 0 {
+		return fmt.Sprintf("snapshot %s (incremental, parent %s) size: %s", m.ID, m.Parent, strengthen.FormatSize(m.Size())), nil
+	}
+	return fmt.Sprintf("snapshot %s (full) size: %s", m.ID, strengthen.FormatSize(m.Size())), nil
+}
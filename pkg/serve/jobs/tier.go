@@ -0,0 +1,103 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+)
+
+// TierParams: params of tier jobs.
+type TierParams struct {
+	DryRun bool `json:"dry_run"` // report the objects to move without moving them
+}
+
+// tierPolicy: large objects which are referenced by recent commits or were accessed recently stay hot, objects
+// uploaded before coldBefore and smaller than minSize stay hot as well.
+type tierPolicy struct {
+	recent     map[plumbing.Hash]bool
+	accessed   map[plumbing.Hash]bool
+	coldBefore time.Time
+	minSize    int64
+}
+
+func (p *tierPolicy) cold(oid plumbing.Hash, o *oss.Object) bool {
+	return !p.recent[oid] && !p.accessed[oid] && o.LastModified.Before(p.coldBefore) && o.Size >= p.minSize
+}
+
+// recentObjects returns the objects of the reference tips and of the commits committed since since.
+func recentObjects(ctx context.Context, o odb.DB, refs []*database.Reference, since time.Time, rep *Reporter) (map[plumbing.Hash]bool, error) {
+	c := &checker{o: o, rep: rep, seen: make(map[plumbing.Hash]bool), since: since}
+	for _, ref := range refs {
+		if err := c.check(ctx, plumbing.NewHash(ref.Hash)); err != nil {
+			return nil, fmt.Errorf("walk %s: %w", ref.Name, err)
+		}
+	}
+	recent := make(map[plumbing.Hash]bool, len(c.objects))
+	for _, oid := range c.objects {
+		recent[oid] = true
+	}
+	return recent, nil
+}
+
+// tier moves the cold large objects of the repository to the cold tier.
+func tier(ctx context.Context, env *Env, r *database.Repository, p *TierParams, rep *Reporter) (string, error) {
+	rr, err := env.Hub.Open(ctx, r.ID, r.CompressionAlgo, r.DefaultBranch)
+	if err != nil {
+		return "", err
+	}
+	defer rr.Close() // nolint
+	refs, err := env.DB.ListReferences(ctx, r.ID)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	policy := &tierPolicy{coldBefore: now.Add(-env.Tiering.ColdAfter()), minSize: env.Tiering.MinSize}
+	rep.Stage("Collecting objects of recent commits", 0)
+	if policy.recent, err = recentObjects(ctx, rr.ODB(), refs, now.Add(-env.Tiering.Recent()), rep); err != nil {
+		return "", err
+	}
+	if policy.accessed, err = odb.NewMetadataDB(env.DB.Database(), r.ID).AccessedSince(ctx, policy.coldBefore); err != nil {
+		return "", fmt.Errorf("load object access: %w", err)
+	}
+	rep.Stage("Moving cold objects", 0)
+	var moved, kept int
+	var movedSize, keptSize int64
+	if err := odb.WalkObjects(ctx, env.Bucket, r.ID, func(oid plumbing.Hash, o *oss.Object) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rep.Add(1)
+		if !policy.cold(oid, o) {
+			kept++
+			keptSize += o.Size
+			return nil
+		}
+		size := o.Size
+		if !p.DryRun {
+			var err error
+			if size, err = rr.ODB().Archive(ctx, oid); err != nil {
+				return fmt.Errorf("move %s: %w", oid, err)
+			}
+		}
+		moved++
+		movedSize += size
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	if p.DryRun {
+		return fmt.Sprintf("dry run: %d objects (%s) would be moved to the cold tier, %d objects (%s) stay hot",
+			moved, strengthen.FormatSize(movedSize), kept, strengthen.FormatSize(keptSize)), nil
+	}
+	return fmt.Sprintf("moved %d objects (%s) to the cold tier, %d objects (%s) stay hot",
+		moved, strengthen.FormatSize(movedSize), kept, strengthen.FormatSize(keptSize)), nil
+}
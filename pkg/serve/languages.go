@@ -114,21 +114,44 @@ func RegisterLanguageMatcher() error {
 }
 
 func Language(r *http.Request) string {
+	return PreferredLanguage(r, "")
+}
+
+// PreferredLanguage returns the language of the messages, the language stored in the user's settings wins over the
+// lang cookie and Accept-Language.
+func PreferredLanguage(r *http.Request, preferred string) string {
 	if languageMatcher == nil {
 		return "en-US"
 	}
+	if lang, ok := SupportedLanguage(preferred); ok {
+		return lang
+	}
 	lang, _ := r.Cookie("lang")
 	accept := r.Header.Get("Accept-Language")
 	tag, _ := language.MatchStrings(languageMatcher, lang.String(), accept)
 	return tag.String()
 }
 
+// SupportedLanguage returns the canonical name of lang when the server has messages in the language.
+func SupportedLanguage(lang string) (string, bool) {
+	if len(lang) == 0 {
+		return "", false
+	}
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return "", false
+	}
+	for _, s := range languagesSupported {
+		if t, err := language.Parse(s); err == nil && t == tag {
+			return s, true
+		}
+	}
+	return "", false
+}
+
 func W(r *http.Request, message string) string {
 	if languageMatcher == nil {
 		return message
 	}
-	lang, _ := r.Cookie("lang")
-	accept := r.Header.Get("Accept-Language")
-	tag, _ := language.MatchStrings(languageMatcher, lang.String(), accept)
-	return Translate(tag.String(), message)
+	return Translate(Language(r), message)
 }
@@ -2,6 +2,7 @@ package serve
 
 import (
 	"fmt"
+	"net/http/httptest"
 	"os"
 	"testing"
 
@@ -20,3 +21,18 @@ func TestAcceptLanguages(t *testing.T) {
 	tag, _ := language.MatchStrings(languageMatcher, "", accept)
 	fmt.Fprintf(os.Stderr, "accept-language: %s\n", tag.String())
 }
+
+func TestPreferredLanguage(t *testing.T) {
+	_ = RegisterLanguageMatcher()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	if lang := PreferredLanguage(r, "zh-cn"); lang != "zh-CN" {
+		t.Fatalf("stored language should win over Accept-Language: %s", lang)
+	}
+	if lang := PreferredLanguage(r, "xx"); lang != "en-US" {
+		t.Fatalf("unsupported language should fall back to Accept-Language: %s", lang)
+	}
+	if _, ok := SupportedLanguage("fr-FR"); ok {
+		t.Fatalf("fr-FR is not supported")
+	}
+}
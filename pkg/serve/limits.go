@@ -0,0 +1,90 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package serve
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/antgroup/hugescm/modules/strengthen"
+)
+
+const (
+	DefaultJSONBodyLimit  = 1 * MiByte
+	DefaultBatchBodyLimit = 64 * MiByte // about 1M object ids
+	DefaultPathsBodyLimit = 16 * MiByte
+)
+
+// Size: toml size, eg: '64MB'
+type Size struct {
+	Size int64
+}
+
+func (s *Size) UnmarshalText(text []byte) error {
+	var err error
+	s.Size, err = strengthen.ParseSize(string(text))
+	return err
+}
+
+// BodyLimits: maximum request body size of each endpoint, push and large object uploads are not limited.
+type BodyLimits struct {
+	Authorization  Size `toml:"authorization,omitempty"`
+	Management     Size `toml:"management,omitempty"`
+	BatchObjects   Size `toml:"batch_objects,omitempty"`
+	ShareObjects   Size `toml:"share_objects,omitempty"`
+	BatchCheck     Size `toml:"batch_check,omitempty"`
+	BatchMetadata  Size `toml:"batch_metadata,omitempty"`
+	SparseMetadata Size `toml:"sparse_metadata,omitempty"`
+}
+
+func NewBodyLimits() *BodyLimits {
+	return &BodyLimits{
+		Authorization:  Size{Size: DefaultJSONBodyLimit},
+		Management:     Size{Size: DefaultJSONBodyLimit},
+		BatchObjects:   Size{Size: DefaultBatchBodyLimit},
+		ShareObjects:   Size{Size: DefaultBatchBodyLimit},
+		BatchCheck:     Size{Size: DefaultBatchBodyLimit},
+		BatchMetadata:  Size{Size: DefaultBatchBodyLimit},
+		SparseMetadata: Size{Size: DefaultPathsBodyLimit},
+	}
+}
+
+// ErrBodyTooLarge: request body exceeds the limit of the endpoint.
+type ErrBodyTooLarge struct {
+	Limit int64
+}
+
+func (e *ErrBodyTooLarge) Error() string {
+	return fmt.Sprintf("request body too large, limit: %s", strengthen.FormatSize(e.Limit))
+}
+
+type limitedReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+}
+
+// NewLimitedReader returns a reader which fails with ErrBodyTooLarge once more than limit bytes are read, limit <= 0 means no limit.
+func NewLimitedReader(r io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, limit: limit}
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.n > r.limit {
+		return 0, &ErrBodyTooLarge{Limit: r.limit}
+	}
+	// read one more byte than the limit to detect oversized bodies
+	if remaining := r.limit - r.n + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if r.n > r.limit {
+		return n - int(r.n-r.limit), &ErrBodyTooLarge{Limit: r.limit}
+	}
+	return n, err
+}
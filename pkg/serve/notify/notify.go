@@ -0,0 +1,99 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notify delivers notifications to the endpoints stored in the user's settings.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	EventPushFailed = "push-failed"
+	notifyTimeout   = 30 * time.Second
+)
+
+var (
+	client   = &http.Client{Timeout: notifyTimeout}
+	colorsRE = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+// PushFailure: a push to a protected branch was rejected. The server has no mailer, NotifyEmail is sent along so that
+// the webhook can forward the notification.
+type PushFailure struct {
+	Event         string    `json:"event"`
+	UID           int64     `json:"uid"`
+	UserName      string    `json:"username"`
+	NotifyEmail   string    `json:"notify_email,omitempty"`
+	Repository    string    `json:"repository"` // namespace/repo
+	ReferenceName string    `json:"reference_name"`
+	OldRev        string    `json:"old_rev,omitempty"`
+	NewRev        string    `json:"new_rev,omitempty"`
+	Status        int       `json:"status"`
+	Message       string    `json:"message"`
+	Time          time.Time `json:"time"`
+}
+
+func post(ctx context.Context, endpoint string, in any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Zeta-Event", EventPushFailed)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+func pushFailed(ctx context.Context, db database.DB, e *PushFailure) error {
+	s, err := db.UserSettings(ctx, e.UID)
+	if err != nil {
+		return err
+	}
+	if len(s.NotifyWebhook) == 0 {
+		return nil
+	}
+	e.Event = EventPushFailed
+	e.NotifyEmail = s.NotifyEmail
+	e.Message = colorsRE.ReplaceAllString(e.Message, "")
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	return post(ctx, s.NotifyWebhook, e)
+}
+
+// PushFailed notifies the user in the background, users without a webhook are not notified.
+func PushFailed(db database.DB, e *PushFailure) {
+	if e.UID == 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		if err := pushFailed(ctx, db, e); err != nil {
+			logrus.Errorf("notify user %d push to %s %s failed error: %v", e.UID, e.Repository, e.ReferenceName, err)
+		}
+	}()
+}
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/antgroup/hugescm/pkg/serve/database"
+)
+
+type settingsDB struct {
+	database.DB
+	settings map[int64]*database.UserSettings
+}
+
+func (d *settingsDB) UserSettings(ctx context.Context, uid int64) (*database.UserSettings, error) {
+	if s, ok := d.settings[uid]; ok {
+		return s, nil
+	}
+	return &database.UserSettings{UID: uid}, nil
+}
+
+func TestPushFailed(t *testing.T) {
+	received := make(chan *PushFailure, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e PushFailure
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- &e
+	}))
+	defer ts.Close()
+	db := &settingsDB{settings: map[int64]*database.UserSettings{
+		1: {UID: 1, NotifyWebhook: ts.URL, NotifyEmail: "zeta@example.io"},
+	}}
+	e := &PushFailure{UID: 1, UserName: "zeta", Repository: "group/mono", ReferenceName: "refs/heads/mainline", Status: 403,
+		Message: "\x1b[31merror\x1b[0m: push rejected"}
+	if err := pushFailed(t.Context(), db, e); err != nil {
+		t.Fatalf("notify error: %v", err)
+	}
+	got := <-received
+	if got.Event != EventPushFailed || got.NotifyEmail != "zeta@example.io" || got.Message != "error: push rejected" || got.Time.IsZero() {
+		t.Fatalf("unexpected notification: %+v", got)
+	}
+	// users without a webhook are not notified
+	if err := pushFailed(t.Context(), db, &PushFailure{UID: 2}); err != nil {
+		t.Fatalf("notify error: %v", err)
+	}
+	select {
+	case got := <-received:
+		t.Fatalf("unexpected notification: %+v", got)
+	default:
+	}
+}
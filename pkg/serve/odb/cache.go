@@ -27,6 +27,8 @@ type CacheDB interface {
 	Store(ctx context.Context, rid int64, a any) error
 	Mark(rid int64, oid plumbing.Hash)
 	Exist(rid int64, oid plumbing.Hash) bool
+	// Touch reports whether the access of the large object must be recorded, see accessInterval.
+	Touch(rid int64, oid plumbing.Hash) bool
 }
 
 type cacheDB struct {
@@ -118,3 +120,12 @@ func (d *cacheDB) Exist(rid int64, oid plumbing.Hash) bool {
 	_, ok := d.Get(cacheKey(rid, oid))
 	return ok
 }
+
+func (d *cacheDB) Touch(rid int64, oid plumbing.Hash) bool {
+	key := "access/" + cacheKey(rid, oid)
+	if _, ok := d.Get(key); ok {
+		return false
+	}
+	d.SetWithTTL(key, true, 1, accessInterval)
+	return true
+}
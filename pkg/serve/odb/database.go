@@ -165,6 +165,48 @@ func (d *MetadataDB) Tag(ctx context.Context, oid plumbing.Hash, b object.Backen
 	return nil, backend.NewErrMismatchedObjectType(oid, "tag")
 }
 
+// Exists returns the objects found in the commits, trees and objects tables.
+func (d *MetadataDB) Exists(ctx context.Context, oids []plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	found := make(map[plumbing.Hash]bool)
+	batchFn := func(table string, hs []plumbing.Hash) error {
+		args := make([]any, 0, len(hs)+1)
+		args = append(args, d.rid)
+		for _, h := range hs {
+			args = append(args, h.String())
+		}
+		query := "select hash from " + table + " where rid = ? and hash in (?" + strings.Repeat(", ?", len(hs)-1) + ")"
+		rows, err := d.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() // nolint
+		for rows.Next() {
+			var h string
+			if err := rows.Scan(&h); err != nil {
+				return err
+			}
+			found[plumbing.NewHash(h)] = true
+		}
+		return rows.Err()
+	}
+	for _, table := range []string{"commits", "trees", "objects"} {
+		unknown := make([]plumbing.Hash, 0, len(oids))
+		for _, oid := range oids {
+			if !found[oid] {
+				unknown = append(unknown, oid)
+			}
+		}
+		for len(unknown) > 0 {
+			g := min(len(unknown), 500)
+			if err := batchFn(table, unknown[0:g]); err != nil {
+				return nil, err
+			}
+			unknown = unknown[g:]
+		}
+	}
+	return found, nil
+}
+
 func (d *MetadataDB) Encode(ctx context.Context, oid plumbing.Hash, e object.Encoder) error {
 	bindata, err := object.Base64Encode(e)
 	if err != nil {
@@ -238,10 +280,10 @@ func (d *MetadataDB) BatchEncodeTags(ctx context.Context, tags []*object.Tag) er
 	return nil
 }
 
-func (o *ODB) batchCommits(ctx context.Context, oids []plumbing.Hash) error {
+func (o *ODB) batchCommits(ctx context.Context, src *backend.Database, oids []plumbing.Hash) error {
 	commits := make([]*object.Commit, 0, len(oids))
 	for _, oid := range oids {
-		cc, err := o.odb.Commit(ctx, oid)
+		cc, err := src.Commit(ctx, oid)
 		if err != nil {
 			return err
 		}
@@ -258,10 +300,11 @@ func (o *ODB) batchCommits(ctx context.Context, oids []plumbing.Hash) error {
 	return nil
 }
 
-func (o *ODB) BatchCommits(ctx context.Context, oids []plumbing.Hash) error {
+// BatchCommits: store the commits read from src in the metadata database.
+func (o *ODB) BatchCommits(ctx context.Context, src *backend.Database, oids []plumbing.Hash) error {
 	for len(oids) > 0 {
 		batchSize := min(len(oids), 1000)
-		if err := o.batchCommits(ctx, oids[:batchSize]); err != nil {
+		if err := o.batchCommits(ctx, src, oids[:batchSize]); err != nil {
 			return err
 		}
 		oids = oids[batchSize:]
@@ -269,10 +312,10 @@ func (o *ODB) BatchCommits(ctx context.Context, oids []plumbing.Hash) error {
 	return nil
 }
 
-func (o *ODB) batchTrees(ctx context.Context, oids []plumbing.Hash) error {
+func (o *ODB) batchTrees(ctx context.Context, src *backend.Database, oids []plumbing.Hash) error {
 	trees := make([]*object.Tree, 0, len(oids))
 	for _, oid := range oids {
-		cc, err := o.odb.Tree(ctx, oid)
+		cc, err := src.Tree(ctx, oid)
 		if err != nil {
 			return err
 		}
@@ -289,10 +332,11 @@ func (o *ODB) batchTrees(ctx context.Context, oids []plumbing.Hash) error {
 	return nil
 }
 
-func (o *ODB) BatchTrees(ctx context.Context, oids []plumbing.Hash) error {
+// BatchTrees: store the trees read from src in the metadata database.
+func (o *ODB) BatchTrees(ctx context.Context, src *backend.Database, oids []plumbing.Hash) error {
 	for len(oids) > 0 {
 		batchSize := min(len(oids), 1000)
-		if err := o.batchTrees(ctx, oids[:batchSize]); err != nil {
+		if err := o.batchTrees(ctx, src, oids[:batchSize]); err != nil {
 			return err
 		}
 		oids = oids[batchSize:]
@@ -300,11 +344,11 @@ func (o *ODB) BatchTrees(ctx context.Context, oids []plumbing.Hash) error {
 	return nil
 }
 
-func (o *ODB) batchMetaObjects(ctx context.Context, oids []plumbing.Hash) error {
+func (o *ODB) batchMetaObjects(ctx context.Context, src *backend.Database, oids []plumbing.Hash) error {
 	fragments := make([]*object.Fragments, 0, 100)
 	tags := make([]*object.Tag, 0, 100)
 	for _, oid := range oids {
-		a, err := o.odb.Object(ctx, oid)
+		a, err := src.Object(ctx, oid)
 		if err != nil {
 			return err
 		}
@@ -336,10 +380,11 @@ func (o *ODB) batchMetaObjects(ctx context.Context, oids []plumbing.Hash) error
 	return nil
 }
 
-func (o *ODB) BatchMetaObjects(ctx context.Context, oids []plumbing.Hash) error {
+// BatchMetaObjects: store the fragments and tags read from src in the metadata database.
+func (o *ODB) BatchMetaObjects(ctx context.Context, src *backend.Database, oids []plumbing.Hash) error {
 	for len(oids) > 0 {
 		batchSize := min(len(oids), 1000)
-		if err := o.batchMetaObjects(ctx, oids[:batchSize]); err != nil {
+		if err := o.batchMetaObjects(ctx, src, oids[:batchSize]); err != nil {
 			return err
 		}
 		oids = oids[batchSize:]
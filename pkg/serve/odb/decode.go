@@ -300,9 +300,10 @@ func (o *ODB) Open(ctx context.Context, oid plumbing.Hash, start int64) (sr back
 	if !plumbing.IsNoSuchObject(err) {
 		return nil, err
 	}
-	if sr, err = o.bucket.Open(ctx, ossJoin(o.rid, oid), start, -1); err != nil {
+	if sr, err = o.openHot(ctx, oid, start); err != nil {
 		return
 	}
+	o.touch(oid)
 	if sr.Size() < cachedThreshold && start == 0 {
 		return o.newBufferedReader(ctx, oid, sr)
 	}
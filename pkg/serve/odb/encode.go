@@ -72,7 +72,7 @@ func (o *ODB) HashTo(ctx context.Context, r io.Reader, size int64) (oid plumbing
 		return
 	}
 	resourcePath := ossJoin(o.rid, oid)
-	if _, err = o.bucket.Stat(ctx, resourcePath); err == nil {
+	if _, err = o.stat(ctx, oid); err == nil {
 		return oid, nil
 	}
 	if !os.IsNotExist(err) {
@@ -0,0 +1,70 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package odb
+
+import (
+	"context"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	existsStatConcurrency = 8
+)
+
+// Exists reports whether each object is in the repository. The local cache is checked first, the metadata left are
+// looked up in batches in the database and the blobs left are checked in OSS concurrently.
+func (o *ODB) Exists(ctx context.Context, oids []plumbing.Hash) ([]bool, error) {
+	exists := make([]bool, len(oids))
+	pending := make(map[plumbing.Hash][]int)
+	for i, oid := range oids {
+		if o.odb.Exists(oid, false) == nil || o.odb.Exists(oid, true) == nil {
+			exists[i] = true
+			continue
+		}
+		pending[oid] = append(pending[oid], i)
+	}
+	if len(pending) != 0 && o.mdb != nil {
+		unknown := make([]plumbing.Hash, 0, len(pending))
+		for oid := range pending {
+			unknown = append(unknown, oid)
+		}
+		found, err := o.mdb.Exists(ctx, unknown)
+		if err != nil {
+			return nil, err
+		}
+		for oid := range found {
+			for _, i := range pending[oid] {
+				exists[i] = true
+			}
+			delete(pending, oid)
+		}
+	}
+	if len(pending) == 0 || o.bucket == nil {
+		return exists, nil
+	}
+	g, newCtx := errgroup.WithContext(ctx)
+	g.SetLimit(existsStatConcurrency)
+	for oid, indexes := range pending {
+		g.Go(func() error {
+			err := o.ossExists(newCtx, oid)
+			if plumbing.IsNoSuchObject(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			// each object owns its indexes, no lock required
+			for _, i := range indexes {
+				exists[i] = true
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return exists, nil
+}
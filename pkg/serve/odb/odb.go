@@ -23,8 +23,11 @@ type DB interface {
 	Blob(ctx context.Context, oid plumbing.Hash) (b *object.Blob, err error)
 	Push(ctx context.Context, oid plumbing.Hash) error // Push object to OSS
 	WriteDirect(ctx context.Context, oid plumbing.Hash, r io.Reader, size int64) (int64, error)
+	WriteDelta(ctx context.Context, oid, base plumbing.Hash, r io.Reader, size int64) (int64, error) // rebuild a large object from a delta
 	Stat(ctx context.Context, oid plumbing.Hash) (*oss.Stat, error)
 	Share(ctx context.Context, oid plumbing.Hash, expiresAt int64) (*Representation, error)
+	Exists(ctx context.Context, oids []plumbing.Hash) ([]bool, error)
+	Archive(ctx context.Context, oid plumbing.Hash) (int64, error) // move a large object to the cold tier
 }
 
 type ODB struct {
@@ -32,14 +35,16 @@ type ODB struct {
 	cdb    CacheDB
 	mdb    *MetadataDB
 	bucket oss.Bucket
+	cold   oss.Bucket // cold tier of large objects, nil when tiering is disabled
 	rid    int64
 }
 
-func NewODB(rid int64, root string, compressionALGO string, cdb CacheDB, mdb *MetadataDB, bucket oss.Bucket) (*ODB, error) {
+func NewODB(rid int64, root string, compressionALGO string, cdb CacheDB, mdb *MetadataDB, bucket, cold oss.Bucket) (*ODB, error) {
 	o := &ODB{
 		cdb:    cdb,
 		mdb:    mdb,
 		bucket: bucket,
+		cold:   cold,
 		rid:    rid,
 	}
 	odb, err := backend.NewDatabase(root, backend.WithCompressionALGO(compressionALGO), backend.WithAbstractBackend(o))
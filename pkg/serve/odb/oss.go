@@ -4,6 +4,7 @@
 package odb
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -16,6 +17,8 @@ import (
 
 	"github.com/antgroup/hugescm/modules/oss"
 	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/format/delta"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
 	"github.com/antgroup/hugescm/modules/zeta/object"
 	"golang.org/x/sync/errgroup"
 )
@@ -24,23 +27,31 @@ const (
 	OSS_ZETA_BLOB_MIME       = "application/vnd.zeta-blob"
 	MiByte             int64 = 1048576
 	defaultThreshold         = 100 * MiByte
+	// MaxDeltaObjectSize: the largest base and object of a delta upload
+	MaxDeltaObjectSize = 256 * MiByte
 )
 
+// OssPrefix returns the prefix of all oss objects of repository rid.
+func OssPrefix(rid int64) string {
+	return fmt.Sprintf("zeta/%03d/%d/", rid%1000, rid)
+}
+
 func ossJoin(rid int64, oid plumbing.Hash) string {
 	h := oid.String()
-	return fmt.Sprintf("zeta/%03d/%d/%s/%s/%s", rid%1000, rid, h[0:2], h[2:4], h)
+	return fmt.Sprintf("%s%s/%s/%s", OssPrefix(rid), h[0:2], h[2:4], h)
 }
 
 func (o *ODB) ossExists(ctx context.Context, oid plumbing.Hash) error {
-	_, err := o.bucket.Stat(ctx, ossJoin(o.rid, oid))
+	_, err := o.stat(ctx, oid)
 	if errors.Is(err, os.ErrNotExist) {
 		return plumbing.NoSuchObject(oid)
 	}
 	return err
 }
 
+// Stat returns the stat of the large object in either tier.
 func (o *ODB) Stat(ctx context.Context, oid plumbing.Hash) (*oss.Stat, error) {
-	return o.bucket.Stat(ctx, ossJoin(o.rid, oid))
+	return o.stat(ctx, oid)
 }
 
 type Representation struct {
@@ -51,13 +62,14 @@ type Representation struct {
 
 func (o *ODB) Share(ctx context.Context, oid plumbing.Hash, expiresAt int64) (*Representation, error) {
 	resourcePath := ossJoin(o.rid, oid)
-	si, err := o.bucket.Stat(ctx, resourcePath)
+	si, err := o.hotStat(ctx, oid)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil, plumbing.NoSuchObject(oid)
 		}
 		return nil, err
 	}
+	o.touch(oid)
 	href := o.bucket.Share(ctx, resourcePath, expiresAt)
 	return &Representation{
 		Href:      href,
@@ -71,7 +83,7 @@ func (o *ODB) Share(ctx context.Context, oid plumbing.Hash, expiresAt int64) (*R
 // Typically used for uploading larger binary files.
 func (o *ODB) WriteDirect(ctx context.Context, oid plumbing.Hash, r io.Reader, size int64) (int64, error) {
 	resourcePath := ossJoin(o.rid, oid)
-	si, err := o.bucket.Stat(ctx, resourcePath)
+	si, err := o.stat(ctx, oid)
 	if err == nil {
 		return si.Size, nil
 	}
@@ -118,12 +130,63 @@ func (o *ODB) WriteDirect(ctx context.Context, oid plumbing.Hash, r io.Reader, s
 	return size, nil
 }
 
+// WriteDelta: the large object oid is uploaded as a delta against base, the object is rebuilt from the base, verified
+// and stored like HashTo. The base and the object are loaded in memory, both are limited to MaxDeltaObjectSize.
+func (o *ODB) WriteDelta(ctx context.Context, oid, base plumbing.Hash, r io.Reader, size int64) (int64, error) {
+	if si, err := o.stat(ctx, oid); err == nil {
+		return si.Size, nil
+	}
+	if size <= 0 || size > MaxDeltaObjectSize {
+		return 0, fmt.Errorf("bad delta size %d", size)
+	}
+	d := make([]byte, size)
+	if _, err := io.ReadFull(r, d); err != nil {
+		return 0, fmt.Errorf("read delta: %w", err)
+	}
+	srcSize, dstSize, err := delta.Header(d)
+	if err != nil {
+		return 0, err
+	}
+	if srcSize > uint64(MaxDeltaObjectSize) || dstSize > uint64(MaxDeltaObjectSize) {
+		return 0, fmt.Errorf("delta base or object too large: %d/%d", srcSize, dstSize)
+	}
+	br, err := o.Blob(ctx, base)
+	if err != nil {
+		return 0, fmt.Errorf("open delta base %s: %w", base, err)
+	}
+	defer br.Close() // nolint
+	if br.Size != int64(srcSize) {
+		return 0, delta.ErrSourceSize
+	}
+	src, err := io.ReadAll(br.Contents)
+	if err != nil {
+		return 0, fmt.Errorf("read delta base %s: %w", base, err)
+	}
+	contents, err := delta.Patch(src, d)
+	if err != nil {
+		return 0, err
+	}
+	hasher := plumbing.NewHasher()
+	_, _ = hasher.Write(contents)
+	if got := hasher.Sum(); got != oid {
+		return 0, fmt.Errorf("unexpected blob oid got '%s' want '%s'", got, oid)
+	}
+	if _, err := o.HashTo(ctx, bytes.NewReader(contents), int64(len(contents))); err != nil {
+		return 0, err
+	}
+	return int64(len(contents)), nil
+}
+
 func (o *ODB) Push(ctx context.Context, oid plumbing.Hash) error {
+	return o.push(ctx, o.odb, oid)
+}
+
+func (o *ODB) push(ctx context.Context, src *backend.Database, oid plumbing.Hash) error {
 	resourcePath := ossJoin(o.rid, oid)
-	if _, err := o.bucket.Stat(ctx, resourcePath); err == nil {
+	if _, err := o.stat(ctx, oid); err == nil {
 		return nil
 	}
-	sr, err := o.odb.SizeReader(oid, false)
+	sr, err := src.SizeReader(oid, false)
 	if err != nil {
 		return err
 	}
@@ -137,6 +200,7 @@ func (o *ODB) Push(ctx context.Context, oid plumbing.Hash) error {
 }
 
 type uploadGroup struct {
+	src    *backend.Database
 	ch     chan plumbing.Hash
 	errors chan error
 	wg     sync.WaitGroup
@@ -178,7 +242,7 @@ func (g *uploadGroup) upload(ctx context.Context, o *ODB) error {
 			return context.Canceled
 		default:
 		}
-		if err := o.Push(ctx, oid); err != nil {
+		if err := o.push(ctx, g.src, oid); err != nil {
 			return err
 		}
 	}
@@ -192,12 +256,13 @@ func (g *uploadGroup) run(ctx context.Context, o *ODB) {
 	})
 }
 
-// BatchObjects: batch upload objects
-func (o *ODB) BatchObjects(ctx context.Context, oids []plumbing.Hash, batchLimit int) error {
+// BatchObjects: batch upload objects read from src
+func (o *ODB) BatchObjects(ctx context.Context, src *backend.Database, oids []plumbing.Hash, batchLimit int) error {
 	if len(oids) == 0 {
 		return nil
 	}
 	g := &uploadGroup{
+		src:    src,
 		ch:     make(chan plumbing.Hash, batchLimit),
 		errors: make(chan error, batchLimit),
 	}
@@ -224,7 +289,7 @@ func (o *ODB) BatchObjects(ctx context.Context, oids []plumbing.Hash, batchLimit
 }
 
 func OssRemoveFiles(ctx context.Context, b oss.Bucket, rid int64) error {
-	prefix := fmt.Sprintf("zeta/%03d/%d/", rid%1000, rid)
+	prefix := OssPrefix(rid)
 	var continuationToken string
 	for {
 		objects, nextContinuationToken, err := b.ListObjects(ctx, prefix, continuationToken)
@@ -258,7 +323,7 @@ type StatObjectsResult struct {
 }
 
 func StatObjects(ctx context.Context, b oss.Bucket, rid int64, threshold int64) (*StatObjectsResult, error) {
-	prefix := fmt.Sprintf("zeta/%03d/%d/", rid%1000, rid)
+	prefix := OssPrefix(rid)
 	var result StatObjectsResult
 	var continuationToken string
 	if threshold == 0 {
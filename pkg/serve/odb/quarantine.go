@@ -5,30 +5,92 @@ package odb
 
 import (
 	"context"
+	"os"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/zeta/backend"
 	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
 )
 
+// QuarantineDB: objects received by a push, staged in incoming/quarantine-XXXX. Lookups see the quarantined objects
+// first and then the repository, but the repository never sees the quarantined objects until Migrate, which is called
+// after the integrity check, the policies, the extensions and the reference update succeed. Close drops everything
+// not migrated, so a rejected push leaves nothing in the repository.
 type QuarantineDB struct {
-	o *ODB
-	q *backend.Database
+	o       *ODB
+	q       *backend.Database
+	dir     string
+	Objects *Objects
 }
 
-func NewQuarantineDB(o *ODB, quarantineDir string) (*QuarantineDB, error) {
+func newQuarantineDB(o *ODB, quarantineDir string, recvObjects *Objects) (*QuarantineDB, error) {
 	q, err := backend.NewDatabase(quarantineDir, backend.WithCompressionALGO(o.odb.CompressionALGO()), backend.WithAbstractBackend(o))
 	if err != nil {
 		return nil, err
 	}
-	return &QuarantineDB{o: o, q: q}, nil
+	return &QuarantineDB{o: o, q: q, dir: quarantineDir, Objects: recvObjects}, nil
 }
 
+// Close closes the quarantine and removes the quarantine dir.
 func (q *QuarantineDB) Close() error {
+	var err error
 	if q.q != nil {
-		return q.q.Close()
+		err = q.q.Close()
+		q.q = nil
 	}
-	return nil
+	_ = os.RemoveAll(q.dir)
+	return err
+}
+
+// Publish uploads the blobs to OSS and stores the metadata objects in the metadata database, both are content
+// addressed and must be complete before the reference points to the new commits.
+func (q *QuarantineDB) Publish(ctx context.Context) error {
+	var g errgroup.Group
+	g.Go(func() error {
+		if err := q.o.BatchObjects(ctx, q.q, q.Objects.Objects, 50); err != nil {
+			logrus.Errorf("batch upload blobs error: %v", err)
+			return err
+		}
+		return nil
+	})
+	g.Go(func() error {
+		if err := q.o.BatchMetaObjects(ctx, q.q, q.Objects.MetaObjects); err != nil {
+			logrus.Errorf("batch encode metadata objects error: %v", err)
+			return err
+		}
+		return nil
+	})
+	g.Go(func() error {
+		if err := q.o.BatchTrees(ctx, q.q, q.Objects.Trees); err != nil {
+			logrus.Errorf("batch encode trees error: %v", err)
+			return err
+		}
+		return nil
+	})
+	g.Go(func() error {
+		if err := q.o.BatchCommits(ctx, q.q, q.Objects.Commits); err != nil {
+			logrus.Errorf("batch encode commits error: %v", err)
+			return err
+		}
+		return nil
+	})
+	return g.Wait()
+}
+
+// Migrate moves the quarantined objects into the repository and reloads the repository odb.
+func (q *QuarantineDB) Migrate() error {
+	if q.q != nil {
+		if err := q.q.Close(); err != nil {
+			return err
+		}
+		q.q = nil
+	}
+	if err := q.o.moveFromQuarantineDir(q.dir); err != nil {
+		return err
+	}
+	return q.o.Reload()
 }
 
 func (q *QuarantineDB) parseRev(ctx context.Context, oid plumbing.Hash) (a any, isolated bool, err error) {
@@ -92,6 +154,13 @@ func (q *QuarantineDB) Tag(ctx context.Context, oid plumbing.Hash) (tag *object.
 	return q.o.Tag(ctx, oid)
 }
 
+func (q *QuarantineDB) Blob(ctx context.Context, oid plumbing.Hash) (br *object.Blob, err error) {
+	if br, err = q.q.Blob(ctx, oid); !plumbing.IsNoSuchObject(err) {
+		return
+	}
+	return q.o.Blob(ctx, oid)
+}
+
 func (q *QuarantineDB) Exists(ctx context.Context, oid plumbing.Hash, meta bool) error {
 	if err := q.q.Exists(oid, meta); !plumbing.IsNoSuchObject(err) {
 		return err
@@ -0,0 +1,100 @@
+package odb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/binary"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+// newPushStream encodes a commit and a blob written by a client odb as a push stream.
+func newPushStream(t *testing.T) (*bytes.Buffer, plumbing.Hash, plumbing.Hash) {
+	clientDir := filepath.Join(t.TempDir(), ".zeta")
+	d, err := backend.NewDatabase(clientDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close() // nolint
+	oid, err := d.HashTo(t.Context(), strings.NewReader("quarantined blob\n"), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cid, err := d.WriteEncoded(&object.Commit{Tree: plumbing.EmptyTree, Message: "quarantined commit\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	_ = binary.Write(&b, PUSH_STREAM_MAGIC[:])
+	_ = binary.WriteUint32(&b, supportedVersion)
+	_ = binary.Write(&b, make([]byte, 16))
+	for _, e := range []struct {
+		p        string
+		oid      plumbing.Hash
+		metadata bool
+	}{{"metadata", cid, true}, {"blob", oid, false}} {
+		raw, err := os.ReadFile(backend.Join(filepath.Join(clientDir, e.p), e.oid))
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := int64(len(raw) + plumbing.HASH_HEX_SIZE)
+		if e.metadata {
+			size = -size
+		}
+		_ = binary.WriteUint64(&b, uint64(size))
+		_ = binary.Write(&b, []byte(e.oid.String()), raw)
+	}
+	_ = binary.WriteUint64(&b, 0)
+	return &b, cid, oid
+}
+
+func TestQuarantine(t *testing.T) {
+	o, err := NewODB(1, filepath.Join(t.TempDir(), "1.zeta"), "zstd", nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("new odb error: %v", err)
+	}
+	defer o.Close() // nolint
+	incoming := filepath.Join(o.odb.Root(), "incoming")
+	for _, migrate := range []bool{false, true} {
+		stream, cid, oid := newPushStream(t)
+		q, err := o.Unpack(t.Context(), stream, &OStats{M: 1, B: 1})
+		if err != nil {
+			t.Fatalf("unpack error: %v", err)
+		}
+		if len(q.Objects.Commits) != 1 || len(q.Objects.Objects) != 1 {
+			t.Fatalf("unexpected objects: %v", q.Objects)
+		}
+		if _, err := q.Commit(t.Context(), cid); err != nil {
+			t.Fatalf("quarantined commit not found: %v", err)
+		}
+		if err := o.odb.Exists(cid, true); !plumbing.IsNoSuchObject(err) {
+			t.Fatalf("quarantined commit is visible in the repository: %v", err)
+		}
+		if migrate {
+			if err := q.Migrate(); err != nil {
+				t.Fatalf("migrate error: %v", err)
+			}
+		}
+		_ = q.Close()
+		if entries, _ := os.ReadDir(incoming); len(entries) != 0 {
+			t.Fatalf("quarantine dir not removed")
+		}
+		for _, e := range []struct {
+			oid  plumbing.Hash
+			meta bool
+		}{{cid, true}, {oid, false}} {
+			err := o.odb.Exists(e.oid, e.meta)
+			if migrate && err != nil {
+				t.Fatalf("object %s not migrated: %v", e.oid, err)
+			}
+			if !migrate && !plumbing.IsNoSuchObject(err) {
+				t.Fatalf("object %s of the rejected push is visible: %v", e.oid, err)
+			}
+		}
+	}
+}
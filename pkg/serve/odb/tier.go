@@ -0,0 +1,249 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package odb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// Large objects are stored in the hot tier (the persistent bucket) or in the cold tier (the cold bucket of the tiering
+// config) under the same key. Tier jobs move cold objects to the cold tier, reading an object of the cold tier moves
+// it back to the hot tier, so share links always point to the hot tier.
+
+const (
+	// accessInterval: each process records the access of a large object at most once per accessInterval
+	accessInterval = 24 * time.Hour
+	// recordTimeout: max duration of recording an access in the background
+	recordTimeout = 10 * time.Second
+)
+
+var (
+	ErrTieringDisabled = errors.New("tiering is not configured")
+)
+
+var (
+	// recalls: concurrent reads of an object of the cold tier recall it once
+	recalls singleflight.Group
+)
+
+// moveObject copies the object from src to dst, verifies the copy and removes the object from src. A copy left by an
+// interrupted move is reused when it matches.
+func moveObject(ctx context.Context, src, dst oss.Bucket, resourcePath string) (int64, error) {
+	si, err := src.Stat(ctx, resourcePath)
+	if err != nil {
+		return 0, err
+	}
+	matches := func(di *oss.Stat) bool {
+		return di.Size == si.Size && (len(si.Crc64) == 0 || len(di.Crc64) == 0 || si.Crc64 == di.Crc64)
+	}
+	if di, err := dst.Stat(ctx, resourcePath); err != nil || !matches(di) {
+		rr, err := src.Open(ctx, resourcePath, 0, -1)
+		if err != nil {
+			return 0, err
+		}
+		err = dst.LinearUpload(ctx, resourcePath, rr, si.Size, OSS_ZETA_BLOB_MIME)
+		_ = rr.Close()
+		if err != nil {
+			return 0, err
+		}
+		if di, err = dst.Stat(ctx, resourcePath); err != nil {
+			return 0, err
+		}
+		if !matches(di) {
+			_ = dst.Delete(ctx, resourcePath)
+			return 0, fmt.Errorf("copy of '%s' does not match: size %d crc64 '%s', want size %d crc64 '%s'", resourcePath, di.Size, di.Crc64, si.Size, si.Crc64)
+		}
+	}
+	if err := src.Delete(ctx, resourcePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+	return si.Size, nil
+}
+
+// stat returns the stat of the large object in the hot tier or in the cold tier, objects are not recalled.
+func (o *ODB) stat(ctx context.Context, oid plumbing.Hash) (*oss.Stat, error) {
+	resourcePath := ossJoin(o.rid, oid)
+	si, err := o.bucket.Stat(ctx, resourcePath)
+	if o.cold == nil || !errors.Is(err, os.ErrNotExist) {
+		return si, err
+	}
+	return o.cold.Stat(ctx, resourcePath)
+}
+
+// recall moves the object back to the hot tier, it returns os.ErrNotExist when the object is not in the cold tier.
+func (o *ODB) recall(ctx context.Context, oid plumbing.Hash) error {
+	if o.cold == nil {
+		return os.ErrNotExist
+	}
+	resourcePath := ossJoin(o.rid, oid)
+	_, err, _ := recalls.Do(resourcePath, func() (any, error) {
+		size, err := moveObject(ctx, o.cold, o.bucket, resourcePath)
+		if err == nil {
+			logrus.Infof("recall %s (%d bytes) of repository %d from the cold tier", oid, size, o.rid)
+		}
+		return nil, err
+	})
+	return err
+}
+
+// hotStat returns the stat of the large object in the hot tier, objects of the cold tier are recalled first.
+func (o *ODB) hotStat(ctx context.Context, oid plumbing.Hash) (*oss.Stat, error) {
+	resourcePath := ossJoin(o.rid, oid)
+	si, err := o.bucket.Stat(ctx, resourcePath)
+	if o.cold == nil || !errors.Is(err, os.ErrNotExist) {
+		return si, err
+	}
+	if err := o.recall(ctx, oid); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	// recalled by this process or by another one
+	return o.bucket.Stat(ctx, resourcePath)
+}
+
+// openHot opens the large object in the hot tier, objects of the cold tier are recalled first.
+func (o *ODB) openHot(ctx context.Context, oid plumbing.Hash, start int64) (oss.RangeReader, error) {
+	resourcePath := ossJoin(o.rid, oid)
+	rr, err := o.bucket.Open(ctx, resourcePath, start, -1)
+	if o.cold == nil || !errors.Is(err, os.ErrNotExist) {
+		return rr, err
+	}
+	if err := o.recall(ctx, oid); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return o.bucket.Open(ctx, resourcePath, start, -1)
+}
+
+// touch records the access of the large object for tier jobs, in the background to keep reads fast.
+func (o *ODB) touch(oid plumbing.Hash) {
+	if o.mdb == nil || o.cdb == nil || !o.cdb.Touch(o.rid, oid) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+		defer cancel()
+		if err := o.mdb.TouchObject(ctx, oid, time.Now()); err != nil {
+			logrus.Errorf("record access of %s in repository %d error: %v", oid, o.rid, err)
+		}
+	}()
+}
+
+// Archive moves the large object to the cold tier. The object is copied and verified before it is removed from the
+// hot tier, share links created before stop working.
+func (o *ODB) Archive(ctx context.Context, oid plumbing.Hash) (int64, error) {
+	if o.cold == nil {
+		return 0, ErrTieringDisabled
+	}
+	return moveObject(ctx, o.bucket, o.cold, ossJoin(o.rid, oid))
+}
+
+// TouchObject records that the large object oid was accessed at t.
+func (d *MetadataDB) TouchObject(ctx context.Context, oid plumbing.Hash, t time.Time) error {
+	_, err := d.ExecContext(ctx, "insert into object_access(rid, oid, accessed_at) values(?, ?, ?) ON DUPLICATE KEY UPDATE accessed_at = GREATEST(accessed_at, VALUES(accessed_at))",
+		d.rid, oid.String(), t)
+	return err
+}
+
+// AccessedSince returns the large objects accessed since t.
+func (d *MetadataDB) AccessedSince(ctx context.Context, t time.Time) (map[plumbing.Hash]bool, error) {
+	rows, err := d.QueryContext(ctx, "select oid from object_access where rid = ? and accessed_at >= ?", d.rid, t)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint
+	accessed := make(map[plumbing.Hash]bool)
+	for rows.Next() {
+		var oid string
+		if err := rows.Scan(&oid); err != nil {
+			return nil, err
+		}
+		accessed[plumbing.NewHash(oid)] = true
+	}
+	return accessed, rows.Err()
+}
+
+// WalkObjects calls fn with each large object of repository rid in the bucket.
+func WalkObjects(ctx context.Context, b oss.Bucket, rid int64, fn func(oid plumbing.Hash, o *oss.Object) error) error {
+	prefix := OssPrefix(rid)
+	var continuationToken string
+	for {
+		objects, nextContinuationToken, err := b.ListObjects(ctx, prefix, continuationToken)
+		if err != nil {
+			return err
+		}
+		for _, o := range objects {
+			name := path.Base(o.Key)
+			if !plumbing.ValidateHashHex(name) {
+				continue
+			}
+			if err := fn(plumbing.NewHash(name), o); err != nil {
+				return err
+			}
+		}
+		if continuationToken = nextContinuationToken; len(continuationToken) == 0 {
+			return nil
+		}
+	}
+}
+
+type TierStat struct {
+	Objects int   `json:"objects"`
+	Size    int64 `json:"size"`
+}
+
+func (s *TierStat) add(o *oss.Object) {
+	s.Objects++
+	s.Size += o.Size
+}
+
+// TierReport: distribution of the large objects of a repository, Cold is nil when tiering is disabled. Unused counts
+// the hot objects not uploaded or accessed since UnusedSince, which the next tier job would move unless they are
+// referenced by recent commits.
+type TierReport struct {
+	Hot         TierStat  `json:"hot"`
+	Cold        *TierStat `json:"cold,omitempty"`
+	Unused      TierStat  `json:"unused"`
+	UnusedSince time.Time `json:"unused_since"`
+}
+
+// StatTiers lists the large objects of repository rid in both tiers, cold and mdb may be nil.
+func StatTiers(ctx context.Context, hot, cold oss.Bucket, mdb *MetadataDB, rid int64, unusedSince time.Time) (*TierReport, error) {
+	var accessed map[plumbing.Hash]bool
+	if mdb != nil {
+		var err error
+		if accessed, err = mdb.AccessedSince(ctx, unusedSince); err != nil {
+			return nil, err
+		}
+	}
+	r := &TierReport{UnusedSince: unusedSince}
+	if err := WalkObjects(ctx, hot, rid, func(oid plumbing.Hash, o *oss.Object) error {
+		r.Hot.add(o)
+		if !accessed[oid] && o.LastModified.Before(unusedSince) {
+			r.Unused.add(o)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if cold == nil {
+		return r, nil
+	}
+	r.Cold = &TierStat{}
+	if err := WalkObjects(ctx, cold, rid, func(_ plumbing.Hash, o *oss.Object) error {
+		r.Cold.add(o)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
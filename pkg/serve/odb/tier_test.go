@@ -0,0 +1,161 @@
+package odb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+type memoryBucket struct {
+	oss.Bucket
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemoryBucket() *memoryBucket {
+	return &memoryBucket{objects: make(map[string][]byte)}
+}
+
+func (b *memoryBucket) Stat(ctx context.Context, resourcePath string) (*oss.Stat, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.objects[resourcePath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return &oss.Stat{Size: int64(len(data))}, nil
+}
+
+func (b *memoryBucket) Open(ctx context.Context, resourcePath string, start, length int64) (oss.RangeReader, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.objects[resourcePath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return oss.NewRangeReader(io.NopCloser(bytes.NewReader(data[start:])), int64(len(data)), ""), nil
+}
+
+func (b *memoryBucket) Delete(ctx context.Context, resourcePath string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.objects, resourcePath)
+	return nil
+}
+
+func (b *memoryBucket) LinearUpload(ctx context.Context, resourcePath string, r io.Reader, size int64, mime string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[resourcePath] = data
+	return nil
+}
+
+func (b *memoryBucket) ListObjects(ctx context.Context, prefix, continuationToken string) ([]*oss.Object, string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var objects []*oss.Object
+	for k, data := range b.objects {
+		if strings.HasPrefix(k, prefix) {
+			objects = append(objects, &oss.Object{Key: k, Size: int64(len(data)), LastModified: time.Now()})
+		}
+	}
+	return objects, "", nil
+}
+
+func (b *memoryBucket) Share(ctx context.Context, resourcePath string, expiresAt int64) string {
+	return "https://oss.example.com/" + resourcePath
+}
+
+func (b *memoryBucket) has(oid plumbing.Hash) bool {
+	_, err := b.Stat(context.Background(), ossJoin(1, oid))
+	return err == nil
+}
+
+func TestTiering(t *testing.T) {
+	ctx := t.Context()
+	hot, cold := newMemoryBucket(), newMemoryBucket()
+	o, err := NewODB(1, filepath.Join(t.TempDir(), "1.zeta"), "zstd", nil, nil, hot, cold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close() // nolint
+	contents := strings.Repeat("large object\n", 100)
+	oid := plumbing.NewHash("6b7a1e6f2a33b1bd2c2a4a0b9b3f0c9a2a7f3c6e8c1f5d1b2e3a4c5d6e7f8091")
+	hot.objects[ossJoin(1, oid)] = []byte(contents)
+
+	size, err := o.Archive(ctx, oid)
+	if err != nil {
+		t.Fatalf("archive error: %v", err)
+	}
+	if size != int64(len(contents)) || hot.has(oid) || !cold.has(oid) {
+		t.Fatalf("archive: size %d, hot %v, cold %v", size, hot.has(oid), cold.has(oid))
+	}
+	// stat does not recall
+	si, err := o.Stat(ctx, oid)
+	if err != nil || si.Size != size || hot.has(oid) {
+		t.Fatalf("stat of a cold object: %v %v, hot %v", si, err, hot.has(oid))
+	}
+	report, err := StatTiers(ctx, hot, cold, nil, 1, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Hot.Objects != 0 || report.Cold == nil || report.Cold.Objects != 1 || report.Cold.Size != size {
+		t.Fatalf("unexpected tier report: %+v %+v", report, report.Cold)
+	}
+
+	// share recalls the object
+	rep, err := o.Share(ctx, oid, time.Now().Add(time.Hour).Unix())
+	if err != nil {
+		t.Fatalf("share a cold object error: %v", err)
+	}
+	if rep.Size != size || !hot.has(oid) || cold.has(oid) {
+		t.Fatalf("share: size %d, hot %v, cold %v", rep.Size, hot.has(oid), cold.has(oid))
+	}
+
+	// open recalls the object
+	if _, err := o.Archive(ctx, oid); err != nil {
+		t.Fatal(err)
+	}
+	sr, err := o.Open(ctx, oid, 0)
+	if err != nil {
+		t.Fatalf("open a cold object error: %v", err)
+	}
+	defer sr.Close() // nolint
+	got, err := io.ReadAll(sr)
+	if err != nil || string(got) != contents {
+		t.Fatalf("open a cold object: %d bytes, %v", len(got), err)
+	}
+	if !hot.has(oid) || cold.has(oid) {
+		t.Fatalf("open: hot %v, cold %v", hot.has(oid), cold.has(oid))
+	}
+
+	missing := plumbing.NewHash("0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0")
+	if _, err := o.Share(ctx, missing, 0); !plumbing.IsNoSuchObject(err) {
+		t.Fatalf("share a missing object: %v", err)
+	}
+}
+
+func TestTieringDisabled(t *testing.T) {
+	o, err := NewODB(1, filepath.Join(t.TempDir(), "1.zeta"), "zstd", nil, nil, newMemoryBucket(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close() // nolint
+	if _, err := o.Archive(t.Context(), plumbing.ZeroHash); !errors.Is(err, ErrTieringDisabled) {
+		t.Fatalf("archive without a cold tier: %v", err)
+	}
+}
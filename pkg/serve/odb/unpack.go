@@ -45,12 +45,10 @@ type Objects struct {
 	Larges      []plumbing.Hash
 }
 
-type Validator func(ctx context.Context, quarantineDir string, o *Objects) error
-
-// Unpack:
+// Unpack: unpack the pushed objects into a quarantine dir, the caller must close the returned QuarantineDB.
 //
 //	FIXME: CRC64 verification has been temporarily stopped and may need to be restored later.
-func (o *ODB) Unpack(ctx context.Context, r io.Reader, ss *OStats, validator Validator) (*Objects, error) {
+func (o *ODB) Unpack(ctx context.Context, r io.Reader, ss *OStats) (q *QuarantineDB, err error) {
 	now := time.Now()
 	incoming := filepath.Join(o.odb.Root(), "incoming")
 	if err := os.MkdirAll(incoming, 0755); err != nil {
@@ -61,7 +59,9 @@ func (o *ODB) Unpack(ctx context.Context, r io.Reader, ss *OStats, validator Val
 		return nil, zeta.NewErrStatusCode(http.StatusInternalServerError, "create quarantine dir error: %v", err)
 	}
 	defer func() {
-		_ = os.RemoveAll(quarantineDir)
+		if q == nil {
+			_ = os.RemoveAll(quarantineDir)
+		}
 	}()
 	blobDir := filepath.Join(quarantineDir, "blob")
 	metadataDir := filepath.Join(quarantineDir, "metadata")
@@ -158,14 +158,7 @@ func (o *ODB) Unpack(ctx context.Context, r io.Reader, ss *OStats, validator Val
 		return nil, err
 	}
 	logrus.Infof("[RID-%d] objects unpacking consumption: %v", o.rid, time.Since(now))
-	if err := validator(ctx, quarantineDir, recvObjects); err != nil {
-		return nil, err
-	}
-
-	if err := o.moveFromQuarantineDir(quarantineDir); err != nil {
-		return nil, err
-	}
-	return recvObjects, nil
+	return newQuarantineDB(o, quarantineDir, recvObjects)
 }
 
 // /home/zeta/repositories/a.zeta/tmp/quarantine-XXXX/metadata/8d/8d0607257a2ee5a4c85d287c70900c14c2380f55cd49179f2db6228edee7db25
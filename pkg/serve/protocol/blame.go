@@ -0,0 +1,25 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package protocol
+
+import (
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+// BlameRange: consecutive lines of the file last modified by the same commit, line numbers are 1-based.
+type BlameRange struct {
+	Start         int           `json:"start"`          // first line in the blamed file
+	Lines         int           `json:"lines"`          // number of lines
+	Commit        plumbing.Hash `json:"commit"`         // commit which introduced the lines
+	OriginalStart int           `json:"original_start"` // first line in the file of the commit
+}
+
+type BlameResponse struct {
+	Path    string                    `json:"path"`
+	Commit  plumbing.Hash             `json:"commit"` // blamed commit resolved from ref
+	Ranges  []*BlameRange             `json:"ranges"`
+	Commits map[string]*object.Commit `json:"commits"`           // commits referenced by ranges
+	Partial bool                      `json:"partial,omitempty"` // the walk was stopped early, older commits may be blamed on boundary commits
+}
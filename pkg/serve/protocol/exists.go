@@ -0,0 +1,69 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package protocol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+const (
+	// MAX_EXISTS_OBJECTS: maximum number of object ids of one objects exists query
+	MAX_EXISTS_OBJECTS = 100000
+)
+
+// ReadExistsOIDs reads the object ids of an objects exists query, one per line. Unlike ReadInputOIDs the order and the
+// duplicates are kept, the i-th bit of the answer belongs to the i-th line.
+func ReadExistsOIDs(r io.Reader) ([]plumbing.Hash, error) {
+	br := bufio.NewScanner(r)
+	oids := make([]plumbing.Hash, 0, 100)
+	for br.Scan() {
+		sid := strings.TrimSpace(br.Text())
+		if len(sid) == 0 {
+			break
+		}
+		if !plumbing.ValidateHashHex(sid) {
+			if err := br.Err(); err != nil {
+				return nil, err // truncated by read error
+			}
+			return nil, fmt.Errorf("invalid hash '%s'", sid)
+		}
+		if len(oids) == MAX_EXISTS_OBJECTS {
+			return nil, fmt.Errorf("too many objects, limit: %d", MAX_EXISTS_OBJECTS)
+		}
+		oids = append(oids, plumbing.NewHash(sid))
+	}
+	if br.Err() != nil {
+		return nil, br.Err()
+	}
+	return oids, nil
+}
+
+// ObjectsExistsResponse: the i-th bit of Bitmap, least significant bit first, is set when the i-th object exists.
+type ObjectsExistsResponse struct {
+	Objects int    `json:"objects"`
+	Bitmap  []byte `json:"bitmap"`
+}
+
+func NewObjectsExistsResponse(exists []bool) *ObjectsExistsResponse {
+	r := &ObjectsExistsResponse{Objects: len(exists), Bitmap: make([]byte, (len(exists)+7)/8)}
+	for i, ok := range exists {
+		if ok {
+			r.Bitmap[i/8] |= 1 << (i % 8)
+		}
+	}
+	return r
+}
+
+// Exists reports whether the i-th object exists.
+func (r *ObjectsExistsResponse) Exists(i int) bool {
+	if i < 0 || i >= r.Objects || i/8 >= len(r.Bitmap) {
+		return false
+	}
+	return r.Bitmap[i/8]&(1<<(i%8)) != 0
+}
@@ -0,0 +1,29 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package protocol
+
+import (
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+const (
+	HISTORY_ADDED    = "added"
+	HISTORY_MODIFIED = "modified"
+	HISTORY_DELETED  = "deleted"
+	HISTORY_RENAMED  = "renamed"
+)
+
+// HistoryCommit: a commit that touched the path, path is the name of the file in this commit when following renames.
+type HistoryCommit struct {
+	*object.Commit
+	Path    string `json:"path"`
+	OldPath string `json:"old_path,omitempty"` // renamed from
+	Status  string `json:"status"`
+}
+
+type HistoryResponse struct {
+	Path    string           `json:"path"`
+	Commits []*HistoryCommit `json:"commits"`
+	Cursor  string           `json:"cursor,omitempty"` // resume the walk with ?cursor=, empty when the history is complete
+}
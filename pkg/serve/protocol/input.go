@@ -5,6 +5,8 @@ package protocol
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -38,6 +40,9 @@ func ReadInputOIDs(r io.Reader) ([]plumbing.Hash, error) {
 			break
 		}
 		if !plumbing.ValidateHashHex(sid) {
+			if err := br.Err(); err != nil {
+				return nil, err // truncated by read error
+			}
 			return nil, fmt.Errorf("invalid hash '%s'", sid)
 		}
 		if seen[sid] {
@@ -54,3 +59,97 @@ func ReadInputOIDs(r io.Reader) ([]plumbing.Hash, error) {
 	}
 	return oids, nil
 }
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected '%s' but got '%v'", delim, t)
+	}
+	return nil
+}
+
+// decodeObjects decodes '{"objects": [...]}' one object at a time, each object is validated as soon as
+// it is decoded, so malformed requests are rejected without reading the whole body.
+func decodeObjects[T any](r io.Reader, validate func(*T) error) ([]*T, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	var objects []*T
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if key, _ := t.(string); key != "objects" {
+			var unknown json.RawMessage
+			if err := dec.Decode(&unknown); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if t, err = dec.Token(); err != nil {
+			return nil, err
+		}
+		if t == nil {
+			continue // "objects": null
+		}
+		if d, ok := t.(json.Delim); !ok || d != '[' {
+			return nil, fmt.Errorf("objects: expected '[' but got '%v'", t)
+		}
+		for dec.More() {
+			var o *T
+			if err := dec.Decode(&o); err != nil {
+				return nil, err
+			}
+			if o == nil {
+				return nil, errors.New("require object is nil")
+			}
+			if err := validate(o); err != nil {
+				return nil, err
+			}
+			objects = append(objects, o)
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return nil, err
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// DecodeBatchCheckRequest decodes BatchCheckRequest in streaming mode.
+func DecodeBatchCheckRequest(r io.Reader) (*BatchCheckRequest, error) {
+	objects, err := decodeObjects(r, func(o *HaveObject) error {
+		if !plumbing.ValidateHashHex(o.OID) {
+			return fmt.Errorf("invalid hash '%s'", o.OID)
+		}
+		if o.CompressedSize < 0 {
+			return fmt.Errorf("invalid compressed size %d of '%s'", o.CompressedSize, o.OID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BatchCheckRequest{Objects: objects}, nil
+}
+
+// DecodeBatchShareObjectsRequest decodes BatchShareObjectsRequest in streaming mode.
+func DecodeBatchShareObjectsRequest(r io.Reader) (*BatchShareObjectsRequest, error) {
+	objects, err := decodeObjects(r, func(o *WantObject) error {
+		if !plumbing.ValidateHashHex(o.OID) {
+			return fmt.Errorf("invalid hash '%s'", o.OID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BatchShareObjectsRequest{Objects: objects}, nil
+}
@@ -0,0 +1,83 @@
+package protocol
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/pkg/serve"
+)
+
+const testOID = "e5b3a5a9bf8754e338162e69fd6820ed65e0b3f306e651c0f7cb6540e6a8f7a1"
+
+func TestDecodeBatchCheckRequest(t *testing.T) {
+	request, err := DecodeBatchCheckRequest(strings.NewReader(`{"unknown":{"a":[1,2]},"objects":[{"oid":"` + testOID + `","compressed_size":10}]}`))
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(request.Objects) != 1 || request.Objects[0].OID != testOID || request.Objects[0].CompressedSize != 10 {
+		t.Fatalf("unexpected objects: %v", request.Objects)
+	}
+	if request, err = DecodeBatchCheckRequest(strings.NewReader(`{"objects":null}`)); err != nil || len(request.Objects) != 0 {
+		t.Fatalf("decode null objects: %v %v", request, err)
+	}
+	bad := []string{
+		`[]`,
+		`{"objects":{}}`,
+		`{"objects":[null]}`,
+		`{"objects":[{"oid":"bad"}]}`,
+		`{"objects":[{"oid":"` + testOID + `","compressed_size":-1}]}`,
+		`{"objects":[{"oid":"` + testOID + `"}`,
+	}
+	for _, s := range bad {
+		if _, err := DecodeBatchCheckRequest(strings.NewReader(s)); err == nil {
+			t.Errorf("decode %s: expected error", s)
+		}
+	}
+}
+
+func TestDecodeBodyTooLarge(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`{"objects":[`)
+	for i := range 100 {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(`{"oid":"` + testOID + `"}`)
+	}
+	b.WriteString(`]}`)
+	_, err := DecodeBatchShareObjectsRequest(serve.NewLimitedReader(strings.NewReader(b.String()), 1024))
+	if e, ok := errors.AsType[*serve.ErrBodyTooLarge](err); !ok || e.Limit != 1024 {
+		t.Fatalf("expected body too large error, got: %v", err)
+	}
+	request, err := DecodeBatchShareObjectsRequest(serve.NewLimitedReader(strings.NewReader(b.String()), int64(b.Len())))
+	if err != nil || len(request.Objects) != 100 {
+		t.Fatalf("decode error: %v", err)
+	}
+	if _, err := ReadInputOIDs(serve.NewLimitedReader(strings.NewReader(strings.Repeat(testOID+"\n", 100)), 1024)); !errors.As(err, new(*serve.ErrBodyTooLarge)) {
+		t.Fatalf("expected body too large error, got: %v", err)
+	}
+}
+
+func TestObjectsExistsResponse(t *testing.T) {
+	exists := []bool{true, false, false, true, false, false, false, false, true}
+	r := NewObjectsExistsResponse(exists)
+	if len(r.Bitmap) != 2 || r.Bitmap[0] != 0x09 || r.Bitmap[1] != 0x01 {
+		t.Fatalf("unexpected bitmap: %x", r.Bitmap)
+	}
+	for i, want := range exists {
+		if r.Exists(i) != want {
+			t.Errorf("exists(%d) = %v, want %v", i, !want, want)
+		}
+	}
+	if r.Exists(len(exists)) || r.Exists(-1) {
+		t.Fatalf("out of range object exists")
+	}
+	oids, err := ReadExistsOIDs(strings.NewReader(testOID + "\n" + testOID + "\n"))
+	if err != nil || len(oids) != 2 {
+		t.Fatalf("duplicate objects should be kept: %v %v", oids, err)
+	}
+	if _, err := ReadExistsOIDs(strings.NewReader(strings.Repeat(testOID+"\n", MAX_EXISTS_OBJECTS+1))); err == nil {
+		t.Fatalf("expected too many objects error")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"context"
 	"fmt"
+	"hash/crc64"
 	"io"
 	"math"
 	"net/http"
@@ -20,8 +21,10 @@ import (
 	"github.com/antgroup/hugescm/pkg/serve/odb"
 )
 
-func writeMetadataHeader(w io.Writer) error {
-	if err := binary.Write(w, metaTransportMagic[:], PROTOCOL_VERSION, reserved[:]); err != nil {
+func writeMetadataHeader(w io.Writer, flags byte) error {
+	header := reserved
+	header[0] = flags
+	if err := binary.Write(w, metaTransportMagic[:], PROTOCOL_VERSION, header[:]); err != nil {
 		return fmt.Errorf("write metadata magic error: %w", err)
 	}
 	return nil
@@ -53,8 +56,11 @@ func writeMetadataItem(w io.Writer, e object.Encoder, oid string) error {
 	return nil
 }
 
-func WriteBatchObjectsHeader(w io.Writer) error {
-	if err := binary.Write(w, objectsTransportMagic[:], PROTOCOL_VERSION, reserved[:]); err != nil {
+// WriteBatchObjectsHeader: flags is stored in the first reserved byte, eg: BATCH_FLAG_ITEM_CRC64
+func WriteBatchObjectsHeader(w io.Writer, flags byte) error {
+	header := reserved
+	header[0] = flags
+	if err := binary.Write(w, objectsTransportMagic[:], PROTOCOL_VERSION, header[:]); err != nil {
 		return fmt.Errorf("write batch-objects magic error: %w", err)
 	}
 	return nil
@@ -82,6 +88,31 @@ func WriteObjectsItem(w io.Writer, r io.Reader, oid string, size int64) error {
 	return nil
 }
 
+// WriteObjectsItemWithChecksum: same as WriteObjectsItem, followed by an 8 byte big-endian CRC64 (ISO) of oid and data,
+// the client can verify every item and re-request only the corrupted ones.
+func WriteObjectsItemWithChecksum(w io.Writer, r io.Reader, oid string, size int64) error {
+	if r == nil {
+		return WriteObjectsItem(w, nil, "", 0)
+	}
+	h := crc64.New(crc64.MakeTable(crc64.ISO))
+	if err := binary.WriteUint32(w, uint32(size+plumbing.HASH_HEX_SIZE)); err != nil {
+		return err
+	}
+	if err := binary.Write(io.MultiWriter(w, h), []byte(oid)); err != nil {
+		return err
+	}
+	bytesBuffer := streamio.GetByteSlice()
+	defer streamio.PutByteSlice(bytesBuffer)
+	n, err := io.CopyBuffer(io.MultiWriter(w, h), r, *bytesBuffer)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("failed to write data, tried to write %d bytes, actual %d bytes", size, n)
+	}
+	return binary.WriteUint64(w, h.Sum64())
+}
+
 func WriteSingleObjectsHeader(w io.Writer, contentLength, compressedSize int64) error {
 	if err := binary.Write(w, objectsTransportMagic[:], PROTOCOL_VERSION, contentLength, compressedSize); err != nil {
 		return fmt.Errorf("write object magic error: %w", err)
@@ -136,10 +167,31 @@ type Packer struct {
 	treeMaxDepth int
 	seen         map[plumbing.Hash]bool
 	closeFn      func() error
+	// limit: when the number of trees and fragments written reaches the limit, the remaining
+	// objects are deferred to cursor, clients continue with batch metadata.
+	limit  int
+	cursor []plumbing.Hash
+}
+
+// newPacker: limit only takes effect when tree depth is unlimited, the cursor does not carry depth.
+func newPacker(o odb.DB, cw *crc.Crc64Writer, treeMaxDepth int, limit int, closeFn func() error) (*Packer, error) {
+	if treeMaxDepth != math.MaxInt || limit < 0 {
+		limit = 0
+	}
+	p := &Packer{DB: o, w: cw, Finisher: cw, treeMaxDepth: treeMaxDepth, closeFn: closeFn, seen: make(map[plumbing.Hash]bool), limit: limit}
+	var flags byte
+	if limit > 0 {
+		flags |= METADATA_FLAG_CURSOR
+	}
+	if err := writeMetadataHeader(cw, flags); err != nil {
+		_ = p.Close()
+		return nil, err
+	}
+	return p, nil
 }
 
 // NewPipePacker: SSH protocol
-func NewPipePacker(o odb.DB, w io.Writer, treeMaxDepth int, useZSTD bool) (*Packer, error) {
+func NewPipePacker(o odb.DB, w io.Writer, treeMaxDepth int, useZSTD bool, limit int) (*Packer, error) {
 	if treeMaxDepth == -1 {
 		treeMaxDepth = math.MaxInt
 	}
@@ -165,16 +217,10 @@ func NewPipePacker(o odb.DB, w io.Writer, treeMaxDepth int, useZSTD bool) (*Pack
 		}
 		bodyWriter = buffedWriter
 	}
-	cw := crc.NewCrc64Writer(bodyWriter)
-	p := &Packer{DB: o, w: cw, Finisher: cw, treeMaxDepth: treeMaxDepth, closeFn: closeFn, seen: make(map[plumbing.Hash]bool)}
-	if err := writeMetadataHeader(cw); err != nil {
-		_ = p.Close()
-		return nil, err
-	}
-	return p, nil
+	return newPacker(o, crc.NewCrc64Writer(bodyWriter), treeMaxDepth, limit, closeFn)
 }
 
-func NewHttpPacker(o odb.DB, w http.ResponseWriter, r *http.Request, treeMaxDepth int) (*Packer, error) {
+func NewHttpPacker(o odb.DB, w http.ResponseWriter, r *http.Request, treeMaxDepth int, limit int) (*Packer, error) {
 	if treeMaxDepth == -1 {
 		treeMaxDepth = math.MaxInt
 	}
@@ -204,16 +250,12 @@ func NewHttpPacker(o odb.DB, w http.ResponseWriter, r *http.Request, treeMaxDept
 		bodyWriter = buffedWriter
 		w.Header().Set("Content-Type", ZETA_MIME_MD)
 	}
-	w.Header().Set("Cache-Control", "no-cache")
+	if len(w.Header().Get("Cache-Control")) == 0 {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
 	w.Header().Set("X-Accel-Buffering", "no")
 	w.WriteHeader(http.StatusOK)
-	cw := crc.NewCrc64Writer(bodyWriter)
-	p := &Packer{DB: o, w: cw, Finisher: cw, treeMaxDepth: treeMaxDepth, closeFn: closeFn, seen: make(map[plumbing.Hash]bool)}
-	if err := writeMetadataHeader(cw); err != nil {
-		_ = p.Close()
-		return nil, err
-	}
-	return p, nil
+	return newPacker(o, crc.NewCrc64Writer(bodyWriter), treeMaxDepth, limit, closeFn)
 }
 
 func (p *Packer) Close() error {
@@ -223,14 +265,35 @@ func (p *Packer) Close() error {
 	return nil
 }
 
+// Done: when limit is set, the cursor is written after the end item: 4 byte count and count * 64 byte hash.
 func (p *Packer) Done() (err error) {
 	if err = writeMetadataItem(p.w, nil, ""); err != nil {
 		return err
 	}
+	if p.limit > 0 {
+		if err = binary.WriteUint32(p.w, uint32(len(p.cursor))); err != nil {
+			return err
+		}
+		for _, oid := range p.cursor {
+			if err = binary.Write(p.w, []byte(oid.String())); err != nil {
+				return err
+			}
+		}
+	}
 	_, err = p.Finish()
 	return err
 }
 
+// deferred: reached the limit, oid is deferred to the cursor.
+func (p *Packer) deferred(oid plumbing.Hash) bool {
+	if p.limit <= 0 || p.count < p.limit {
+		return false
+	}
+	p.cursor = append(p.cursor, oid)
+	p.seen[oid] = true
+	return true
+}
+
 func (p *Packer) WriteAny(ctx context.Context, e object.Encoder, oid string) error {
 	return writeMetadataItem(p.w, e, oid)
 }
@@ -247,7 +310,7 @@ func (p *Packer) WriteTree(ctx context.Context, oid plumbing.Hash, depth int) er
 	if depth > p.treeMaxDepth {
 		return nil
 	}
-	if p.seen[oid] {
+	if p.seen[oid] || p.deferred(oid) {
 		return nil
 	}
 	tree, err := p.Tree(ctx, oid)
@@ -265,7 +328,7 @@ func (p *Packer) WriteTree(ctx context.Context, oid plumbing.Hash, depth int) er
 				return err
 			}
 		case object.FragmentsObject:
-			if !p.seen[e.Hash] {
+			if !p.seen[e.Hash] && !p.deferred(e.Hash) {
 				ff, err := p.Fragments(ctx, e.Hash)
 				if err != nil {
 					return err
@@ -0,0 +1,76 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package protocol
+
+import (
+	"path"
+	"strings"
+)
+
+func cleanDir(p string) string {
+	p = path.Clean("/" + p)
+	return strings.TrimPrefix(p, "/")
+}
+
+// PathAllowed reports whether p is one of the allowed dirs or below one of them.
+func PathAllowed(allowed []string, p string) bool {
+	p = cleanDir(p)
+	for _, a := range allowed {
+		a = cleanDir(a)
+		if len(a) == 0 || p == a || strings.HasPrefix(p, a+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// PathVisible reports whether p can be seen by a user restricted to the allowed dirs. Like sparse checkout, the
+// trees on the way to an allowed dir are sent as they are, so the files and dirs directly in them are visible.
+func PathVisible(allowed []string, p string) bool {
+	p = cleanDir(p)
+	if PathAllowed(allowed, p) {
+		return true
+	}
+	parent := path.Dir(p)
+	if parent == "." {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.HasPrefix(cleanDir(a)+"/", parent+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// RestrictPaths returns the sparse dirs a restricted user receives: requested dirs inside an allowed dir are kept,
+// allowed dirs below a requested dir replace it, other requested dirs are dropped. Without requested dirs, the user
+// receives all allowed dirs.
+func RestrictPaths(allowed, requested []string) []string {
+	if len(requested) == 0 {
+		requested = []string{""}
+	}
+	seen := make(map[string]bool)
+	paths := make([]string, 0, len(allowed))
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	for _, r := range requested {
+		r = cleanDir(r)
+		if PathAllowed(allowed, r) && len(r) != 0 {
+			add(r)
+			continue
+		}
+		for _, a := range allowed {
+			a = cleanDir(a)
+			if len(r) == 0 || strings.HasPrefix(a, r+"/") {
+				add(a)
+			}
+		}
+	}
+	return paths
+}
@@ -0,0 +1,47 @@
+package protocol
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRestrictPaths(t *testing.T) {
+	allowed := []string{"team-a", "libs/common"}
+	tests := []struct {
+		requested []string
+		want      []string
+	}{
+		{nil, []string{"team-a", "libs/common"}},
+		{[]string{"team-a/docs"}, []string{"team-a/docs"}},
+		{[]string{"libs"}, []string{"libs/common"}},
+		{[]string{"/libs/common/x/", "team-a"}, []string{"libs/common/x", "team-a"}},
+		{[]string{"team-b", "team-ab"}, []string{}},
+	}
+	for _, tt := range tests {
+		if got := RestrictPaths(allowed, tt.requested); !slices.Equal(got, tt.want) {
+			t.Errorf("RestrictPaths(%v) = %v; want %v", tt.requested, got, tt.want)
+		}
+	}
+}
+
+func TestPathVisible(t *testing.T) {
+	allowed := []string{"team-a", "libs/common"}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"README.md", true},
+		{"team-a/src/main.go", true},
+		{"libs/README.md", true},
+		{"libs/common", true},
+		{"libs/other/x.go", false},
+		{"team-b/main.go", false},
+		{"team-ab", true},
+		{"team-ab/x", false},
+	}
+	for _, tt := range tests {
+		if got := PathVisible(allowed, tt.path); got != tt.want {
+			t.Errorf("PathVisible(%q) = %v; want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,9 @@
 package protocol
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"math"
 	"time"
 )
@@ -21,8 +24,30 @@ const (
 	ZETA_MIME_COMPRESS_MD = "application/x-zeta-compress-metadata"
 	// other
 	MAX_BATCH_BLOB_SIZE = math.MaxUint32 - 64
+	// batch objects integrity: client requests per-item checksums, see WriteObjectsItemWithChecksum
+	BATCH_INTEGRITY_CRC64 = "crc64"
+	// batch objects header flags, stored in the first reserved byte
+	BATCH_FLAG_ITEM_CRC64 byte = 0x01
+	// metadata header flags, stored in the first reserved byte: the stream ends with a resumable cursor, see Packer.Done
+	METADATA_FLAG_CURSOR byte = 0x01
 )
 
+const (
+	// CAPABILITY_OBJECTS_EXISTS: objects exists queries are supported, clients skip the objects the server already has
+	CAPABILITY_OBJECTS_EXISTS = "objects-exists"
+	// CAPABILITY_DELTA_OBJECTS: large objects can be uploaded as a delta against a base object
+	CAPABILITY_DELTA_OBJECTS = "delta-objects"
+	// CAPABILITY_LIST_REFERENCES: all references of the repository can be listed at once
+	CAPABILITY_LIST_REFERENCES = "list-references"
+	// CAPABILITY_TEMPLATES: organization-wide ignore and attribute templates can be downloaded
+	CAPABILITY_TEMPLATES = "templates"
+	// CAPABILITY_PUSH_DRY_RUN: pushes can be validated without updating the reference
+	CAPABILITY_PUSH_DRY_RUN = "push-dry-run"
+)
+
+// Capabilities: advertised by the references responses.
+var Capabilities = []string{CAPABILITY_OBJECTS_EXISTS, CAPABILITY_DELTA_OBJECTS, CAPABILITY_LIST_REFERENCES, CAPABILITY_TEMPLATES, CAPABILITY_PUSH_DRY_RUN}
+
 var (
 	metaTransportMagic    = [4]byte{'Z', 'M', '\x00', '\x01'}
 	objectsTransportMagic = [4]byte{'Z', 'B', '\x00', '\x02'}
@@ -38,6 +63,17 @@ const (
 	SUDO     Operation = "sudo"
 )
 
+// SSHCommands: operations of the zeta-serve SSH commands, deploy keys are limited to the commands allowed for the
+// repository, DOWNLOAD commands by default.
+var SSHCommands = map[string]Operation{
+	"ls-remote":      DOWNLOAD,
+	"metadata":       DOWNLOAD,
+	"objects":        DOWNLOAD,
+	"push":           UPLOAD,
+	"default-branch": SUDO,
+	"templates":      DOWNLOAD,
+}
+
 type SASHandshake struct {
 	Operation Operation `json:"operation"`
 	Version   string    `json:"version,omitempty"`
@@ -48,9 +84,10 @@ type PayloadHeader struct {
 }
 
 type SASPayload struct {
-	Header    PayloadHeader `json:"header"`
-	Notice    string        `json:"notice,omitempty"`
-	ExpiresAt time.Time     `json:"expires_at,omitzero"`
+	Header     PayloadHeader `json:"header"`
+	Notice     string        `json:"notice,omitempty"`
+	ExpiresAt  time.Time     `json:"expires_at,omitzero"`
+	ServerTime time.Time     `json:"server_time,omitzero"` // lets clients detect clock skew
 }
 
 type ErrorCode struct {
@@ -75,6 +112,24 @@ type Reference struct {
 	Capabilities    []string `json:"capabilities"`
 }
 
+type ReferenceItem struct {
+	Name   string `json:"name"`
+	Hash   string `json:"hash"`
+	Peeled string `json:"peeled,omitempty"`
+}
+
+// References: all branches, tags and other references of the repository.
+type References struct {
+	Remote          string           `json:"remote"`
+	HEAD            string           `json:"head"`
+	Version         int              `json:"version"`
+	Agent           string           `json:"agent"`
+	HashAlgo        string           `json:"hash-algo"`
+	CompressionAlgo string           `json:"compression-algo"`
+	Capabilities    []string         `json:"capabilities"`
+	References      []*ReferenceItem `json:"references"`
+}
+
 type Branch struct {
 	Remote          string   `json:"remote"`
 	Branch          string   `json:"branch"`
@@ -87,6 +142,46 @@ type Branch struct {
 	Capabilities    []string `json:"capabilities"`
 }
 
+// DefaultBranchRequest: change the default branch (HEAD) of the repository, the branch must exist.
+type DefaultBranchRequest struct {
+	Branch string `json:"branch"`
+}
+
+type DefaultBranchResponse struct {
+	DefaultBranch    string `json:"default_branch"`
+	OldDefaultBranch string `json:"old_default_branch"`
+}
+
+const (
+	// TEMPLATE_SCOPE_GLOBAL: templates of all namespaces, other templates are scoped to the namespace path
+	TEMPLATE_SCOPE_GLOBAL = "global"
+)
+
+// Template: organization-wide ignore or attribute template, clients layer it beneath the repository files.
+type Template struct {
+	Kind    string `json:"kind"`
+	Scope   string `json:"scope"`
+	Version int64  `json:"version"`
+	Content string `json:"content"`
+}
+
+// Templates: templates of the namespace of the repository, global templates first. Version changes whenever a
+// template changes, clients send it back and get NotModified when nothing changed.
+type Templates struct {
+	Version     string      `json:"version"`
+	NotModified bool        `json:"not_modified,omitempty"`
+	Templates   []*Template `json:"templates,omitempty"`
+}
+
+// NewTemplates computes the version of the templates from their kinds, scopes and versions.
+func NewTemplates(templates []*Template) *Templates {
+	h := sha256.New()
+	for _, t := range templates {
+		_, _ = fmt.Fprintf(h, "%s %s %d\n", t.Kind, t.Scope, t.Version)
+	}
+	return &Templates{Version: hex.EncodeToString(h.Sum(nil))[:16], Templates: templates}
+}
+
 type Tag struct {
 	Remote          string   `json:"remote"`
 	Tag             string   `json:"tag"`
@@ -116,7 +211,8 @@ type Representation struct {
 }
 
 type BatchShareObjectsResponse struct {
-	Objects []*Representation `json:"objects"`
+	Objects    []*Representation `json:"objects"`
+	ServerTime time.Time         `json:"server_time,omitzero"` // lets clients detect clock skew
 }
 
 type HaveObject struct {
@@ -0,0 +1,34 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package serve
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"reflect"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// NewReferenceKey parses the ed25519 private key signing the reference log, OpenSSH (zeta-serve keygen -t ed25519)
+// and PKCS#8 PEM are supported. Encrypted keys are decrypted with d.
+func NewReferenceKey(key string, d *Decrypter) (ed25519.PrivateKey, error) {
+	if d != nil {
+		var err error
+		if key, err = d.Decrypt(key); err != nil {
+			return nil, err
+		}
+	}
+	k, err := ssh.ParseRawPrivateKey([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("parse reference key: %w", err)
+	}
+	switch a := k.(type) {
+	case ed25519.PrivateKey:
+		return a, nil
+	case *ed25519.PrivateKey:
+		return *a, nil
+	}
+	return nil, fmt.Errorf("reference key type not supported: %s", reflect.TypeOf(k))
+}
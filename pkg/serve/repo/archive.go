@@ -0,0 +1,251 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+)
+
+const (
+	ArchiveTar   = "tar"
+	ArchiveTarGz = "tar.gz"
+	// ArchivalFile: version metadata embedded in the archive, like .hg_archival.txt
+	ArchivalFile = ".zeta_archival.txt"
+)
+
+type ArchiveOptions struct {
+	Format string   // tar or tar.gz
+	Prefix string   // prepended to the paths of the archive, eg: repo-v1.0/
+	Repo   string   // namespace/repo, recorded in the archival file
+	Ref    string   // requested revision, recorded in the archival file
+	Paths  []string // dirs the user is restricted to, empty when not restricted
+}
+
+// ErrBadArchiveRequest: unsupported format or bad prefix.
+type ErrBadArchiveRequest struct {
+	message string
+}
+
+func (e *ErrBadArchiveRequest) Error() string {
+	return e.message
+}
+
+type archiveDB interface {
+	Tree(ctx context.Context, oid plumbing.Hash) (*object.Tree, error)
+	Fragments(ctx context.Context, oid plumbing.Hash) (*object.Fragments, error)
+	Blob(ctx context.Context, oid plumbing.Hash) (*object.Blob, error)
+}
+
+// archiver writes a reproducible snapshot of a commit: entries are sorted, owners are dropped and every entry has the
+// commit time, so the same commit always produces the same bytes.
+type archiver struct {
+	db     archiveDB
+	tw     *tar.Writer
+	opts   *ArchiveOptions
+	prefix string
+	when   time.Time
+}
+
+func (a *archiver) header(name string, typeflag byte, mode int64, size int64) *tar.Header {
+	return &tar.Header{
+		Typeflag: typeflag,
+		Name:     name,
+		Mode:     mode,
+		Size:     size,
+		ModTime:  a.when,
+		Uname:    "root",
+		Gname:    "root",
+		Format:   tar.FormatPAX,
+	}
+}
+
+func (a *archiver) writeArchival(cc *object.Commit) error {
+	var b strings.Builder
+	if len(a.opts.Repo) != 0 {
+		fmt.Fprintf(&b, "repo: %s\n", a.opts.Repo)
+	}
+	if len(a.opts.Ref) != 0 {
+		fmt.Fprintf(&b, "ref: %s\n", a.opts.Ref)
+	}
+	fmt.Fprintf(&b, "commit: %s\ntree: %s\ndate: %s\n", cc.Hash, cc.Tree, cc.Committer.When.UTC().Format(time.RFC3339))
+	content := b.String()
+	if err := a.tw.WriteHeader(a.header(a.prefix+ArchivalFile, tar.TypeReg, 0644, int64(len(content)))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(a.tw, content)
+	return err
+}
+
+func (a *archiver) writeBlob(ctx context.Context, oid plumbing.Hash) error {
+	b, err := a.db.Blob(ctx, oid)
+	if err != nil {
+		return err
+	}
+	defer b.Close() // nolint
+	_, err = io.Copy(a.tw, b.Contents)
+	return err
+}
+
+func (a *archiver) writeFile(ctx context.Context, name string, e *object.TreeEntry) error {
+	mode := int64(0644)
+	if e.Mode&^filemode.Fragments == filemode.Executable {
+		mode = 0755
+	}
+	if e.Type() == object.FragmentsObject {
+		ff, err := a.db.Fragments(ctx, e.Hash)
+		if err != nil {
+			return err
+		}
+		if err := a.tw.WriteHeader(a.header(name, tar.TypeReg, mode, int64(ff.Size))); err != nil {
+			return err
+		}
+		for _, f := range ff.Entries {
+			if err := a.writeBlob(ctx, f.Hash); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	b, err := a.db.Blob(ctx, e.Hash)
+	if err != nil {
+		return err
+	}
+	defer b.Close() // nolint
+	if e.Mode == filemode.Symlink {
+		var target strings.Builder
+		if _, err := io.Copy(&target, io.LimitReader(b.Contents, 32*1024)); err != nil {
+			return err
+		}
+		h := a.header(name, tar.TypeSymlink, 0777, 0)
+		h.Linkname = target.String()
+		return a.tw.WriteHeader(h)
+	}
+	if err := a.tw.WriteHeader(a.header(name, tar.TypeReg, mode, b.Size)); err != nil {
+		return err
+	}
+	_, err = io.Copy(a.tw, b.Contents)
+	return err
+}
+
+// visible: restricted users get the files they can see, dirs are only archived when something in them is visible.
+func (a *archiver) visible(p string, isDir bool) bool {
+	if len(a.opts.Paths) == 0 {
+		return true
+	}
+	if isDir {
+		// the files directly in the dir are visible when the dir is allowed or on the way to an allowed dir
+		return protocol.PathVisible(a.opts.Paths, p+"/_")
+	}
+	return protocol.PathVisible(a.opts.Paths, p)
+}
+
+func (a *archiver) writeTree(ctx context.Context, oid plumbing.Hash, parent string) error {
+	t, err := a.db.Tree(ctx, oid)
+	if err != nil {
+		return err
+	}
+	for _, e := range t.Entries {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		p := path.Join(parent, e.Name)
+		if !a.visible(p, e.Mode == filemode.Dir) {
+			continue
+		}
+		switch {
+		case e.Mode == filemode.Dir:
+			if err := a.tw.WriteHeader(a.header(a.prefix+p+"/", tar.TypeDir, 0755, 0)); err != nil {
+				return err
+			}
+			if err := a.writeTree(ctx, e.Hash, p); err != nil {
+				return err
+			}
+		case e.Mode == filemode.Submodule:
+			// submodules are not archived
+		default:
+			if err := a.writeFile(ctx, a.prefix+p, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func cleanArchivePrefix(prefix string) (string, error) {
+	if len(prefix) == 0 {
+		return "", nil
+	}
+	p := strings.Trim(path.Clean("/"+prefix), "/")
+	if len(p) == 0 || strings.Contains(prefix, "..") {
+		return "", &ErrBadArchiveRequest{message: fmt.Sprintf("bad prefix '%s'", prefix)}
+	}
+	return p + "/", nil
+}
+
+// Validate checks the format and the prefix before the response is written.
+func (o *ArchiveOptions) Validate() error {
+	switch o.Format {
+	case "", ArchiveTar, ArchiveTarGz:
+	default:
+		return &ErrBadArchiveRequest{message: fmt.Sprintf("unsupported archive format '%s'", o.Format)}
+	}
+	_, err := cleanArchivePrefix(o.Prefix)
+	return err
+}
+
+func archive(ctx context.Context, db archiveDB, cc *object.Commit, opts *ArchiveOptions, w io.Writer) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	prefix, _ := cleanArchivePrefix(opts.Prefix)
+	var zw *gzip.Writer
+	if opts.Format == ArchiveTarGz {
+		// the gzip header has neither a name nor a modification time
+		zw = gzip.NewWriter(w)
+		w = zw
+	}
+	a := &archiver{db: db, tw: tar.NewWriter(w), opts: opts, prefix: prefix, when: cc.Committer.When.UTC().Truncate(time.Second)}
+	// like git archive, the commit is recorded in the pax global header
+	if err := a.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: map[string]string{"comment": cc.Hash.String()}, Format: tar.FormatPAX}); err != nil {
+		return err
+	}
+	if len(prefix) != 0 {
+		if err := a.tw.WriteHeader(a.header(prefix, tar.TypeDir, 0755, 0)); err != nil {
+			return err
+		}
+	}
+	if err := a.writeArchival(cc); err != nil {
+		return err
+	}
+	if err := a.writeTree(ctx, cc.Tree, ""); err != nil {
+		return err
+	}
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	if zw != nil {
+		return zw.Close()
+	}
+	return nil
+}
+
+// Archive writes a snapshot of the commit to w. The archive is reproducible and embeds the version metadata in
+// .zeta_archival.txt.
+func (r *repository) Archive(ctx context.Context, cc *object.Commit, opts *ArchiveOptions, w io.Writer) error {
+	return archive(ctx, r.odb, cc, opts, w)
+}
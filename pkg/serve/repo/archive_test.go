@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+type archiveMemoryDB struct {
+	*memoryDB
+}
+
+func (d *archiveMemoryDB) Fragments(ctx context.Context, oid plumbing.Hash) (*object.Fragments, error) {
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func (d *archiveMemoryDB) Blob(ctx context.Context, oid plumbing.Hash) (*object.Blob, error) {
+	content, ok := d.blobs[oid]
+	if !ok {
+		return nil, plumbing.NoSuchObject(oid)
+	}
+	return &object.Blob{Contents: strings.NewReader(content), Size: int64(len(content))}, nil
+}
+
+func TestArchive(t *testing.T) {
+	ctx := context.Background()
+	d := &memoryDB{commits: make(map[plumbing.Hash]*object.Commit), trees: make(map[plumbing.Hash]*object.Tree), blobs: make(map[plumbing.Hash]string)}
+	db := &archiveMemoryDB{memoryDB: d}
+	c := d.commit("add", map[string]string{"src/a.txt": "a\n", "secret/key": "k", "README": "r"}, time.Unix(1700000000, 0))
+
+	opts := &ArchiveOptions{Format: ArchiveTarGz, Prefix: "repo-v1/", Repo: "group/repo", Ref: "v1"}
+	var first, second bytes.Buffer
+	if err := archive(ctx, db, c, opts, &first); err != nil {
+		t.Fatalf("archive error: %v", err)
+	}
+	if err := archive(ctx, db, c, opts, &second); err != nil {
+		t.Fatalf("archive error: %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Fatalf("archive is not reproducible")
+	}
+
+	var b bytes.Buffer
+	opts = &ArchiveOptions{Format: ArchiveTar, Prefix: "repo-v1", Repo: "group/repo", Ref: "v1", Paths: []string{"src"}}
+	if err := archive(ctx, db, c, opts, &b); err != nil {
+		t.Fatalf("archive error: %v", err)
+	}
+	tr := tar.NewReader(&b)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read tar error: %v", err)
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			if hdr.PAXRecords["comment"] != c.Hash.String() {
+				t.Fatalf("unexpected pax global header %v", hdr.PAXRecords)
+			}
+			continue
+		}
+		if !hdr.ModTime.Equal(time.Unix(1700000000, 0)) {
+			t.Fatalf("%s: unexpected mtime %v", hdr.Name, hdr.ModTime)
+		}
+		names = append(names, hdr.Name)
+		if hdr.Name == "repo-v1/"+ArchivalFile {
+			content, _ := io.ReadAll(tr)
+			if !strings.Contains(string(content), "commit: "+c.Hash.String()) || !strings.Contains(string(content), "ref: v1") {
+				t.Fatalf("unexpected archival file: %s", content)
+			}
+		}
+	}
+	expected := []string{"repo-v1/", "repo-v1/" + ArchivalFile, "repo-v1/README", "repo-v1/src/", "repo-v1/src/a.txt"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected entries %v, expected %v", names, expected)
+	}
+
+	for _, bad := range []*ArchiveOptions{{Format: "zip"}, {Prefix: "../x"}} {
+		if err := archive(ctx, db, c, bad, io.Discard); err == nil {
+			t.Fatalf("bad options %+v should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/diferenco"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+	"github.com/dgraph-io/ristretto/v2"
+)
+
+const (
+	// maxBlameScan: commits examined per request, lines not resolved yet are blamed on the boundary commits
+	maxBlameScan = 100000
+	// blameCacheMaxLines: cache cost is the number of lines
+	blameCacheMaxLines = 4 << 20
+)
+
+// ErrBadBlameRequest: bad path, or the path is not a text file.
+type ErrBadBlameRequest struct {
+	message string
+}
+
+func (e *ErrBadBlameRequest) Error() string {
+	return e.message
+}
+
+type blameDB interface {
+	historyDB
+	Blob(ctx context.Context, oid plumbing.Hash) (*object.Blob, error)
+}
+
+// blameOrigin: the commit which introduced the line and the 0-based line number in the file of that commit.
+type blameOrigin struct {
+	commit plumbing.Hash
+	line   int
+}
+
+// blameCache: complete blame results keyed by (repository, commit, path). Results of ancestor commits are reused
+// when the walk reaches them, so blaming a branch tip after a few pushes only examines the new commits.
+type blameCache struct {
+	*ristretto.Cache[string, []blameOrigin]
+}
+
+func newBlameCache() (*blameCache, error) {
+	c, err := ristretto.NewCache(&ristretto.Config[string, []blameOrigin]{
+		NumCounters: blameCacheMaxLines / 100,
+		MaxCost:     blameCacheMaxLines,
+		BufferItems: 64,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable initialize blame cache, error: %w", err)
+	}
+	return &blameCache{Cache: c}, nil
+}
+
+func blameCacheKey(rid int64, oid plumbing.Hash, p string) string {
+	return fmt.Sprintf("%d/%s/%s", rid, oid, p)
+}
+
+func (c *blameCache) get(rid int64, oid plumbing.Hash, p string) ([]blameOrigin, bool) {
+	if c == nil {
+		return nil, false
+	}
+	return c.Get(blameCacheKey(rid, oid, p))
+}
+
+func (c *blameCache) set(rid int64, oid plumbing.Hash, p string, lines []blameOrigin) {
+	if c == nil {
+		return
+	}
+	_ = c.Set(blameCacheKey(rid, oid, p), lines, int64(len(lines))+1)
+}
+
+type blameLine struct {
+	final int // line number in the blamed file
+	line  int // line number in the file of the commit being examined
+}
+
+type blameItem struct {
+	commit *object.Commit
+	lines  []blameLine
+}
+
+// blameQueue: newest commits first, so a commit is examined after its children passed their lines to it
+type blameQueue []*blameItem
+
+func (q blameQueue) Len() int { return len(q) }
+func (q blameQueue) Less(i, j int) bool {
+	if !q[i].commit.Committer.When.Equal(q[j].commit.Committer.When) {
+		return q[i].commit.Committer.When.After(q[j].commit.Committer.When)
+	}
+	return q[i].commit.Hash.String() < q[j].commit.Hash.String()
+}
+func (q blameQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *blameQueue) Push(x any)   { *q = append(*q, x.(*blameItem)) }
+func (q *blameQueue) Pop() any {
+	old := *q
+	n := len(old)
+	it := old[n-1]
+	*q = old[:n-1]
+	return it
+}
+
+type blamer struct {
+	db       blameDB
+	cache    *blameCache
+	rid      int64
+	path     string
+	queue    blameQueue
+	pending  map[plumbing.Hash]*blameItem
+	contents map[plumbing.Hash][]string
+	origins  []blameOrigin
+	partial  bool
+}
+
+// pass hands the lines over to the parent, lines passed by several children are examined together.
+func (b *blamer) pass(c *object.Commit, lines []blameLine) {
+	if it, ok := b.pending[c.Hash]; ok {
+		it.lines = append(it.lines, lines...)
+		return
+	}
+	it := &blameItem{commit: c, lines: lines}
+	b.pending[c.Hash] = it
+	heap.Push(&b.queue, it)
+}
+
+func (b *blamer) blameOn(c plumbing.Hash, lines []blameLine) {
+	for _, l := range lines {
+		b.origins[l.final] = blameOrigin{commit: c, line: l.line}
+	}
+}
+
+func splitLines(content string) []string {
+	lines := strings.Split(content, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func (b *blamer) content(ctx context.Context, e *object.TreeEntry) ([]string, error) {
+	if lines, ok := b.contents[e.Hash]; ok {
+		return lines, nil
+	}
+	if !e.Mode.IsFile() || e.Mode.IsFragments() {
+		return nil, &ErrBadBlameRequest{message: fmt.Sprintf("'%s' is not a regular file", b.path)}
+	}
+	br, err := b.db.Blob(ctx, e.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer br.Close() // nolint
+	if br.Size > diferenco.MAX_DIFF_SIZE {
+		return nil, &ErrBadBlameRequest{message: fmt.Sprintf("'%s' is too large to blame", b.path)}
+	}
+	content, _, err := diferenco.ReadUnifiedText(br.Contents, br.Size, false)
+	if err != nil {
+		if errors.Is(err, diferenco.ErrNonText) {
+			return nil, &ErrBadBlameRequest{message: fmt.Sprintf("'%s' is a binary file", b.path)}
+		}
+		return nil, err
+	}
+	lines := splitLines(content)
+	b.contents[e.Hash] = lines
+	return lines, nil
+}
+
+// lineMapping maps the lines of the new file to the lines of the old file, -1 for the inserted lines.
+func lineMapping(changes []diferenco.Change, n int) []int {
+	m := make([]int, n)
+	i1, i2 := 0, 0
+	for _, ch := range changes {
+		for ; i2 < ch.P2; i1, i2 = i1+1, i2+1 {
+			m[i2] = i1
+		}
+		for k := 0; k < ch.Ins; k, i2 = k+1, i2+1 {
+			m[i2] = -1
+		}
+		i1 += ch.Del
+	}
+	for ; i2 < n; i1, i2 = i1+1, i2+1 {
+		m[i2] = i1
+	}
+	return m
+}
+
+// next examines one commit. Like git blame, when the file is the same in a parent all lines are passed to that
+// parent; otherwise unchanged lines are passed to the parents in order and the rest are blamed on the commit.
+func (b *blamer) next(ctx context.Context, start plumbing.Hash) error {
+	it := heap.Pop(&b.queue).(*blameItem)
+	delete(b.pending, it.commit.Hash)
+	c := it.commit
+	if c.Hash != start {
+		if origins, ok := b.cache.get(b.rid, c.Hash, b.path); ok {
+			for _, l := range it.lines {
+				b.origins[l.final] = origins[l.line]
+			}
+			return nil
+		}
+	}
+	entry, err := lookupPath(ctx, b.db, c.Tree, b.path)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("path '%s' vanished in commit %s", b.path, c.Hash)
+	}
+	parents := make([]*object.Commit, 0, len(c.Parents))
+	parentEntries := make([]*object.TreeEntry, 0, len(c.Parents))
+	for _, p := range c.Parents {
+		pc, err := b.db.Commit(ctx, p)
+		if err != nil {
+			return err
+		}
+		pe, err := lookupPath(ctx, b.db, pc.Tree, b.path)
+		if err != nil {
+			return err
+		}
+		if sameEntry(entry, pe) {
+			b.pass(pc, it.lines)
+			return nil
+		}
+		parents = append(parents, pc)
+		parentEntries = append(parentEntries, pe)
+	}
+	remaining := it.lines
+	for i, pc := range parents {
+		pe := parentEntries[i]
+		if len(remaining) == 0 {
+			break
+		}
+		if pe == nil || !pe.Mode.IsFile() || pe.Mode.IsFragments() {
+			continue
+		}
+		parentLines, err := b.content(ctx, pe)
+		if err != nil {
+			if _, ok := errors.AsType[*ErrBadBlameRequest](err); ok {
+				// binary in the parent, lines start here
+				continue
+			}
+			return err
+		}
+		lines, err := b.content(ctx, entry)
+		if err != nil {
+			return err
+		}
+		changes, err := diferenco.DiffSlices(ctx, parentLines, lines, diferenco.Unspecified)
+		if err != nil {
+			return err
+		}
+		m := lineMapping(changes, len(lines))
+		var passed, rest []blameLine
+		for _, l := range remaining {
+			if pl := m[l.line]; pl >= 0 {
+				passed = append(passed, blameLine{final: l.final, line: pl})
+				continue
+			}
+			rest = append(rest, l)
+		}
+		if len(passed) != 0 {
+			b.pass(pc, passed)
+		}
+		remaining = rest
+	}
+	b.blameOn(c.Hash, remaining)
+	return nil
+}
+
+func blame(ctx context.Context, db blameDB, cache *blameCache, rid int64, start *object.Commit, p string) (*protocol.BlameResponse, error) {
+	p, err := CleanHistoryPath(p)
+	if err != nil {
+		return nil, &ErrBadBlameRequest{message: err.Error()}
+	}
+	if len(p) == 0 {
+		return nil, &ErrBadBlameRequest{message: "path is required"}
+	}
+	b := &blamer{
+		db:       db,
+		cache:    cache,
+		rid:      rid,
+		path:     p,
+		pending:  make(map[plumbing.Hash]*blameItem),
+		contents: make(map[plumbing.Hash][]string),
+	}
+	origins, ok := cache.get(rid, start.Hash, p)
+	if !ok {
+		entry, err := lookupPath(ctx, db, start.Tree, p)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, plumbing.NewErrRevNotFound("path '%s' does not exist in commit %s", p, start.Hash)
+		}
+		lines, err := b.content(ctx, entry)
+		if err != nil {
+			return nil, err
+		}
+		b.origins = make([]blameOrigin, len(lines))
+		if len(lines) != 0 {
+			items := make([]blameLine, len(lines))
+			for i := range items {
+				items[i] = blameLine{final: i, line: i}
+			}
+			b.pass(start, items)
+		}
+		for scanned := 0; b.queue.Len() > 0; scanned++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if scanned >= maxBlameScan {
+				// blame the unresolved lines on the boundary commits
+				b.partial = true
+				for _, it := range b.queue {
+					b.blameOn(it.commit.Hash, it.lines)
+				}
+				break
+			}
+			if err := b.next(ctx, start.Hash); err != nil {
+				return nil, err
+			}
+		}
+		origins = b.origins
+		if !b.partial {
+			cache.set(rid, start.Hash, p, origins)
+		}
+	}
+	resp := &protocol.BlameResponse{
+		Path:    p,
+		Commit:  start.Hash,
+		Ranges:  make([]*protocol.BlameRange, 0, 10),
+		Commits: make(map[string]*object.Commit),
+		Partial: b.partial,
+	}
+	var last *protocol.BlameRange
+	for i, o := range origins {
+		if last != nil && last.Commit == o.commit && last.OriginalStart+last.Lines == o.line+1 {
+			last.Lines++
+			continue
+		}
+		last = &protocol.BlameRange{Start: i + 1, Lines: 1, Commit: o.commit, OriginalStart: o.line + 1}
+		resp.Ranges = append(resp.Ranges, last)
+		key := o.commit.String()
+		if _, ok := resp.Commits[key]; ok {
+			continue
+		}
+		c, err := db.Commit(ctx, o.commit)
+		if err != nil {
+			return nil, err
+		}
+		resp.Commits[key] = c
+	}
+	return resp, nil
+}
+
+// Blame returns the commit which last modified each line of the file at rev. Results are cached per (commit, path)
+// and reused by later requests whose history reaches the cached commit.
+func (r *repository) Blame(ctx context.Context, rev string, p string) (*protocol.BlameResponse, error) {
+	ro, err := r.ParseRev(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+	if ro.Target == nil {
+		return nil, plumbing.NewErrRevNotFound("rev %s target not commit", rev)
+	}
+	return blame(ctx, r.odb, r.blames, r.rid, ro.Target, p)
+}
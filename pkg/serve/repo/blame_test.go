@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+type blobCounter struct {
+	*memoryDB
+	reads int
+}
+
+func (d *blobCounter) Blob(ctx context.Context, oid plumbing.Hash) (*object.Blob, error) {
+	content, ok := d.blobs[oid]
+	if !ok {
+		return nil, plumbing.NoSuchObject(oid)
+	}
+	d.reads++
+	return &object.Blob{Contents: strings.NewReader(content), Size: int64(len(content))}, nil
+}
+
+func TestBlame(t *testing.T) {
+	ctx := context.Background()
+	d := &memoryDB{commits: make(map[plumbing.Hash]*object.Commit), trees: make(map[plumbing.Hash]*object.Tree), blobs: make(map[plumbing.Hash]string)}
+	db := &blobCounter{memoryDB: d}
+	now := time.Now()
+	c1 := d.commit("add", map[string]string{"src/a.txt": "a\nb\nc\n", "bin": "\x00\x01"}, now)
+	c2 := d.commit("modify", map[string]string{"src/a.txt": "a\nB\nc\n", "bin": "\x00\x01"}, now.Add(time.Minute), c1)
+	c3 := d.commit("append", map[string]string{"src/a.txt": "a\nb\nc\nd\n", "bin": "\x00\x01"}, now.Add(2*time.Minute), c1)
+	c4 := d.commit("merge", map[string]string{"src/a.txt": "a\nB\nc\nd\n", "bin": "\x00\x01"}, now.Add(3*time.Minute), c2, c3)
+	c5 := d.commit("readme", map[string]string{"src/a.txt": "a\nB\nc\nd\n", "bin": "\x00\x01", "README": "r"}, now.Add(4*time.Minute), c4)
+
+	cache, err := newBlameCache()
+	if err != nil {
+		t.Fatalf("new blame cache error: %v", err)
+	}
+	defer cache.Close()
+	names := map[plumbing.Hash]string{c1.Hash: "add", c2.Hash: "modify", c3.Hash: "append", c4.Hash: "merge", c5.Hash: "readme"}
+	format := func(c *object.Commit) []string {
+		resp, err := blame(ctx, db, cache, 1, c, "/src/a.txt")
+		if err != nil {
+			t.Fatalf("blame error: %v", err)
+		}
+		var got []string
+		for _, r := range resp.Ranges {
+			if _, ok := resp.Commits[r.Commit.String()]; !ok {
+				t.Fatalf("commit %s of range not returned", r.Commit)
+			}
+			got = append(got, fmt.Sprintf("%d+%d:%s:%d", r.Start, r.Lines, names[r.Commit], r.OriginalStart))
+		}
+		return got
+	}
+	want := []string{"1+1:add:1", "2+1:modify:2", "3+1:add:3", "4+1:append:4"}
+	if got := format(c4); !slices.Equal(got, want) {
+		t.Fatalf("blame = %q; want %q", got, want)
+	}
+	cache.Wait()
+	// the walk stops at the cached merge commit, only the file at c5 is read
+	db.reads = 0
+	if got := format(c5); !slices.Equal(got, want) || db.reads != 1 {
+		t.Fatalf("incremental blame = %q reads %d; want %q", got, db.reads, want)
+	}
+	if got := format(c2); !slices.Equal(got, []string{"1+1:add:1", "2+1:modify:2", "3+1:add:3"}) {
+		t.Fatalf("blame of c2 = %q", got)
+	}
+	for _, p := range []string{"", "src", "bin", "missing", "a/../b"} {
+		if _, err := blame(ctx, db, cache, 1, c5, p); err == nil {
+			t.Errorf("blame of %q: expected error", p)
+		}
+	}
+}
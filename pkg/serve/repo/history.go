@@ -0,0 +1,365 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"container/heap"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+)
+
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 500
+	// maxHistoryScan: commits examined per request, the rest of the walk is returned as cursor
+	maxHistoryScan = 10000
+	maxCursorItems = 1000
+)
+
+type HistoryOptions struct {
+	Path   string
+	Follow bool // follow renames, exact renames only
+	Limit  int
+	Cursor string
+}
+
+// ErrBadHistoryRequest: bad path or cursor.
+type ErrBadHistoryRequest struct {
+	message string
+}
+
+func (e *ErrBadHistoryRequest) Error() string {
+	return e.message
+}
+
+// CleanHistoryPath: empty path means the whole repository.
+func CleanHistoryPath(p string) (string, error) {
+	p = strings.Trim(p, "/")
+	if len(p) == 0 {
+		return "", nil
+	}
+	for s := range strings.SplitSeq(p, "/") {
+		if s == "" || s == "." || s == ".." {
+			return "", &ErrBadHistoryRequest{message: fmt.Sprintf("bad path '%s'", p)}
+		}
+	}
+	return p, nil
+}
+
+type historyDB interface {
+	Commit(ctx context.Context, oid plumbing.Hash) (*object.Commit, error)
+	Tree(ctx context.Context, oid plumbing.Hash) (*object.Tree, error)
+}
+
+type historyItem struct {
+	commit *object.Commit
+	path   string
+}
+
+// historyQueue: newest commits first, like git log
+type historyQueue []*historyItem
+
+func (q historyQueue) Len() int { return len(q) }
+func (q historyQueue) Less(i, j int) bool {
+	if !q[i].commit.Committer.When.Equal(q[j].commit.Committer.When) {
+		return q[i].commit.Committer.When.After(q[j].commit.Committer.When)
+	}
+	return q[i].commit.Hash.String() < q[j].commit.Hash.String()
+}
+func (q historyQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *historyQueue) Push(x any)   { *q = append(*q, x.(*historyItem)) }
+func (q *historyQueue) Pop() any {
+	old := *q
+	n := len(old)
+	it := old[n-1]
+	*q = old[:n-1]
+	return it
+}
+
+type historyCursorItem struct {
+	Hash string `json:"h"`
+	Path string `json:"p"`
+}
+
+func encodeHistoryCursor(q historyQueue) string {
+	items := make([]historyCursorItem, 0, len(q))
+	for _, it := range q {
+		items = append(items, historyCursorItem{Hash: it.commit.Hash.String(), Path: it.path})
+	}
+	b, _ := json.Marshal(items)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeHistoryCursor(cursor string) ([]historyCursorItem, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, &ErrBadHistoryRequest{message: "bad cursor"}
+	}
+	var items []historyCursorItem
+	if err := json.Unmarshal(b, &items); err != nil || len(items) == 0 || len(items) > maxCursorItems {
+		return nil, &ErrBadHistoryRequest{message: "bad cursor"}
+	}
+	for _, it := range items {
+		if !plumbing.ValidateHashHex(it.Hash) {
+			return nil, &ErrBadHistoryRequest{message: "bad cursor"}
+		}
+		if _, err := CleanHistoryPath(it.Path); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func readTree(ctx context.Context, db historyDB, oid plumbing.Hash) (*object.Tree, error) {
+	if oid.IsZero() {
+		return &object.Tree{}, nil
+	}
+	return db.Tree(ctx, oid)
+}
+
+// lookupPath returns the entry of p in the tree, nil when p does not exist.
+func lookupPath(ctx context.Context, db historyDB, root plumbing.Hash, p string) (*object.TreeEntry, error) {
+	if len(p) == 0 {
+		return &object.TreeEntry{Hash: root, Mode: filemode.Dir}, nil
+	}
+	oid := root
+	parts := strings.Split(p, "/")
+	for i, name := range parts {
+		t, err := db.Tree(ctx, oid)
+		if err != nil {
+			return nil, err
+		}
+		e, err := t.Entry(name)
+		if err != nil {
+			return nil, nil
+		}
+		if i == len(parts)-1 {
+			return e, nil
+		}
+		if !e.IsDir() {
+			return nil, nil
+		}
+		oid = e.Hash
+	}
+	return nil, nil
+}
+
+func sameEntry(a, b *object.TreeEntry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Hash == b.Hash && a.Mode == b.Mode
+}
+
+// findRename returns the path in from of target which was removed in to, exact renames only. Subtrees with the
+// same hash are skipped.
+func findRename(ctx context.Context, db historyDB, from, to plumbing.Hash, target *object.TreeEntry) (string, error) {
+	var candidates []string
+	var walk func(from, to plumbing.Hash, prefix string) error
+	walk = func(from, to plumbing.Hash, prefix string) error {
+		if from == to {
+			return nil
+		}
+		ft, err := readTree(ctx, db, from)
+		if err != nil {
+			return err
+		}
+		tt, err := readTree(ctx, db, to)
+		if err != nil {
+			return err
+		}
+		for _, e := range ft.Entries {
+			p := path.Join(prefix, e.Name)
+			te, _ := tt.Entry(e.Name)
+			if e.IsDir() {
+				var toHash plumbing.Hash
+				if te != nil && te.IsDir() {
+					toHash = te.Hash
+				}
+				if err := walk(e.Hash, toHash, p); err != nil {
+					return err
+				}
+				continue
+			}
+			if te == nil && e.Hash == target.Hash && e.Mode == target.Mode {
+				candidates = append(candidates, p)
+			}
+		}
+		return nil
+	}
+	if err := walk(from, to, ""); err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", nil
+	}
+	// prefer the same file name, like git mv between directories
+	for _, c := range candidates {
+		if path.Base(c) == target.Name {
+			return c, nil
+		}
+	}
+	return candidates[0], nil
+}
+
+type historyWalker struct {
+	db     historyDB
+	follow bool
+	queue  historyQueue
+	seen   map[string]bool
+}
+
+func (w *historyWalker) push(c *object.Commit, p string) {
+	key := c.Hash.String() + ":" + p
+	if w.seen[key] {
+		return
+	}
+	w.seen[key] = true
+	heap.Push(&w.queue, &historyItem{commit: c, path: p})
+}
+
+// next examines one commit, returns the commit when it touched the path. History is simplified like git log: when
+// the path is the same in a parent, only that parent is followed; the walk of a path ends at the commit which
+// added it.
+func (w *historyWalker) next(ctx context.Context) (*protocol.HistoryCommit, error) {
+	it := heap.Pop(&w.queue).(*historyItem)
+	c := it.commit
+	entry, err := lookupPath(ctx, w.db, c.Tree, it.path)
+	if err != nil {
+		return nil, err
+	}
+	parents := make([]*object.Commit, 0, len(c.Parents))
+	parentEntries := make([]*object.TreeEntry, 0, len(c.Parents))
+	for _, p := range c.Parents {
+		pc, err := w.db.Commit(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		pe, err := lookupPath(ctx, w.db, pc.Tree, it.path)
+		if err != nil {
+			return nil, err
+		}
+		if sameEntry(entry, pe) {
+			w.push(pc, it.path)
+			return nil, nil
+		}
+		parents = append(parents, pc)
+		parentEntries = append(parentEntries, pe)
+	}
+	if len(parents) == 0 {
+		if entry == nil {
+			return nil, nil
+		}
+		return &protocol.HistoryCommit{Commit: c, Path: it.path, Status: protocol.HISTORY_ADDED}, nil
+	}
+	hc := &protocol.HistoryCommit{Commit: c, Path: it.path, Status: protocol.HISTORY_MODIFIED}
+	switch {
+	case entry == nil:
+		hc.Status = protocol.HISTORY_DELETED
+	case parentEntries[0] == nil:
+		hc.Status = protocol.HISTORY_ADDED
+	}
+	for i, pc := range parents {
+		if parentEntries[i] != nil || entry == nil {
+			w.push(pc, it.path)
+			continue
+		}
+		// added in this commit, history continues in the parent only when renamed
+		if !w.follow || len(it.path) == 0 {
+			continue
+		}
+		oldPath, err := findRename(ctx, w.db, pc.Tree, c.Tree, entry)
+		if err != nil {
+			return nil, err
+		}
+		if len(oldPath) == 0 {
+			continue
+		}
+		if i == 0 {
+			hc.Status = protocol.HISTORY_RENAMED
+			hc.OldPath = oldPath
+		}
+		w.push(pc, oldPath)
+	}
+	return hc, nil
+}
+
+func walkHistory(ctx context.Context, db historyDB, w *historyWalker, limit int) ([]*protocol.HistoryCommit, string, error) {
+	commits := make([]*protocol.HistoryCommit, 0, min(limit, DefaultHistoryLimit))
+	for scanned := 0; w.queue.Len() > 0 && len(commits) < limit && scanned < maxHistoryScan; scanned++ {
+		if err := ctx.Err(); err != nil {
+			return nil, "", err
+		}
+		hc, err := w.next(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		if hc != nil {
+			commits = append(commits, hc)
+		}
+	}
+	if w.queue.Len() == 0 {
+		return commits, "", nil
+	}
+	return commits, encodeHistoryCursor(w.queue), nil
+}
+
+func history(ctx context.Context, db historyDB, start *object.Commit, opts *HistoryOptions) (*protocol.HistoryResponse, error) {
+	p, err := CleanHistoryPath(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	limit = min(limit, MaxHistoryLimit)
+	w := &historyWalker{db: db, follow: opts.Follow, seen: make(map[string]bool)}
+	if len(opts.Cursor) != 0 {
+		items, err := decodeHistoryCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			c, err := db.Commit(ctx, plumbing.NewHash(it.Hash))
+			if err != nil {
+				return nil, err
+			}
+			w.push(c, it.Path)
+		}
+	} else {
+		w.push(start, p)
+	}
+	commits, cursor, err := walkHistory(ctx, db, w, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.HistoryResponse{Path: p, Commits: commits, Cursor: cursor}, nil
+}
+
+// History returns the commits reachable from rev that touched the path, newest first. Pages after the first one
+// are requested with the cursor of the previous response, rev is ignored then.
+func (r *repository) History(ctx context.Context, rev string, opts *HistoryOptions) (*protocol.HistoryResponse, error) {
+	var start *object.Commit
+	if len(opts.Cursor) == 0 {
+		ro, err := r.ParseRev(ctx, rev)
+		if err != nil {
+			return nil, err
+		}
+		if ro.Target == nil {
+			return nil, plumbing.NewErrRevNotFound("rev %s target not commit", rev)
+		}
+		start = ro.Target
+	}
+	return history(ctx, r.odb, start, opts)
+}
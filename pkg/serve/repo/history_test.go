@@ -0,0 +1,150 @@
+package repo
+
+import (
+	"context"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+)
+
+type memoryDB struct {
+	commits map[plumbing.Hash]*object.Commit
+	trees   map[plumbing.Hash]*object.Tree
+	blobs   map[plumbing.Hash]string
+}
+
+func (d *memoryDB) Commit(ctx context.Context, oid plumbing.Hash) (*object.Commit, error) {
+	if c, ok := d.commits[oid]; ok {
+		return c, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func (d *memoryDB) Tree(ctx context.Context, oid plumbing.Hash) (*object.Tree, error) {
+	if t, ok := d.trees[oid]; ok {
+		return t, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func hashString(s string) plumbing.Hash {
+	h := plumbing.NewHasher()
+	_, _ = h.Write([]byte(s))
+	return h.Sum()
+}
+
+// writeTree stores the tree of files (path -> content) and returns its hash.
+func (d *memoryDB) writeTree(files map[string]string) plumbing.Hash {
+	dirs := make(map[string]map[string]string)
+	t := &object.Tree{}
+	var names []string
+	for p := range files {
+		names = append(names, p)
+	}
+	slices.Sort(names)
+	for _, p := range names {
+		dir, rest, ok := strings.Cut(p, "/")
+		if ok {
+			if dirs[dir] == nil {
+				dirs[dir] = make(map[string]string)
+			}
+			dirs[dir][rest] = files[p]
+			continue
+		}
+		oid := hashString(files[p])
+		if d.blobs != nil {
+			d.blobs[oid] = files[p]
+		}
+		t.Entries = append(t.Entries, &object.TreeEntry{Name: p, Mode: filemode.Regular, Hash: oid})
+	}
+	for dir, sub := range dirs {
+		t.Entries = append(t.Entries, &object.TreeEntry{Name: dir, Mode: filemode.Dir, Hash: d.writeTree(sub)})
+	}
+	slices.SortFunc(t.Entries, func(a, b *object.TreeEntry) int { return strings.Compare(a.Name, b.Name) })
+	var b strings.Builder
+	for _, e := range t.Entries {
+		b.WriteString(e.Name + e.Hash.String())
+	}
+	t.Hash = hashString(b.String())
+	d.trees[t.Hash] = t
+	return t.Hash
+}
+
+func (d *memoryDB) commit(message string, files map[string]string, when time.Time, parents ...*object.Commit) *object.Commit {
+	c := &object.Commit{Message: message, Tree: d.writeTree(files), Committer: object.Signature{When: when}}
+	for _, p := range parents {
+		c.Parents = append(c.Parents, p.Hash)
+	}
+	c.Hash = hashString(message + c.Tree.String())
+	d.commits[c.Hash] = c
+	return c
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	d := &memoryDB{commits: make(map[plumbing.Hash]*object.Commit), trees: make(map[plumbing.Hash]*object.Tree)}
+	now := time.Now()
+	c1 := d.commit("add", map[string]string{"a/old.txt": "v1", "README": "r1"}, now)
+	c2 := d.commit("readme", map[string]string{"a/old.txt": "v1", "README": "r2"}, now.Add(time.Minute), c1)
+	c3 := d.commit("modify", map[string]string{"a/old.txt": "v2", "README": "r2"}, now.Add(2*time.Minute), c2)
+	c4 := d.commit("rename", map[string]string{"b/new.txt": "v2", "README": "r2"}, now.Add(3*time.Minute), c3)
+	c5 := d.commit("modify new", map[string]string{"b/new.txt": "v3", "README": "r2"}, now.Add(4*time.Minute), c4)
+
+	resp, err := history(ctx, d, c5, &HistoryOptions{Path: "/b/new.txt", Follow: true})
+	if err != nil {
+		t.Fatalf("history error: %v", err)
+	}
+	var got []string
+	for _, hc := range resp.Commits {
+		got = append(got, hc.Message+":"+hc.Status+":"+hc.Path)
+	}
+	want := []string{"modify new:modified:b/new.txt", "rename:renamed:b/new.txt", "modify:modified:a/old.txt", "add:added:a/old.txt"}
+	if !slices.Equal(got, want) || resp.Cursor != "" {
+		t.Fatalf("history = %q cursor %q; want %q", got, resp.Cursor, want)
+	}
+	if resp.Commits[1].OldPath != "a/old.txt" {
+		t.Fatalf("old path = %q", resp.Commits[1].OldPath)
+	}
+	// without follow the history stops at the rename
+	if resp, err = history(ctx, d, c5, &HistoryOptions{Path: "b/new.txt"}); err != nil || len(resp.Commits) != 2 || resp.Commits[1].Status != protocol.HISTORY_ADDED {
+		t.Fatalf("history without follow: %v %v", resp, err)
+	}
+	// pagination
+	var pages []string
+	opts := &HistoryOptions{Path: "b/new.txt", Follow: true, Limit: 1}
+	for {
+		resp, err := history(ctx, d, c5, opts)
+		if err != nil {
+			t.Fatalf("history page error: %v", err)
+		}
+		for _, hc := range resp.Commits {
+			pages = append(pages, hc.Message+":"+hc.Status+":"+hc.Path)
+		}
+		if resp.Cursor == "" {
+			break
+		}
+		opts.Cursor = resp.Cursor
+	}
+	if !slices.Equal(pages, want) {
+		t.Fatalf("paginated history = %q; want %q", pages, want)
+	}
+	// deleted path
+	if resp, err = history(ctx, d, c5, &HistoryOptions{Path: path.Join("a", "old.txt")}); err != nil || len(resp.Commits) != 3 || resp.Commits[0].Status != protocol.HISTORY_DELETED {
+		t.Fatalf("history of deleted path: %v %v", resp, err)
+	}
+	for _, p := range []string{"a/../b", "a//b"} {
+		if _, err := history(ctx, d, c5, &HistoryOptions{Path: p}); err == nil {
+			t.Errorf("history of %q: expected error", p)
+		}
+	}
+	if _, err := history(ctx, d, c5, &HistoryOptions{Cursor: "!!"}); err == nil {
+		t.Errorf("bad cursor: expected error")
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+)
+
+var (
+	ErrPathPermissionDenied = errors.New("path permission denied")
+)
+
+type permissionChecker struct {
+	historyDB
+	allowed  []string
+	modified []string
+}
+
+func (c *permissionChecker) parentTrees(ctx context.Context, parents []*object.Tree, name string) ([]*object.Tree, error) {
+	trees := make([]*object.Tree, 0, len(parents))
+	for _, p := range parents {
+		e, err := p.Entry(name)
+		if err != nil || e.Type() != object.TreeObject {
+			continue
+		}
+		t, err := c.Tree(ctx, e.Hash)
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, t)
+	}
+	return trees, nil
+}
+
+// unchanged: the entry is taken from one of the parents as it is.
+func unchanged(parents []*object.Tree, e *object.TreeEntry) bool {
+	for _, p := range parents {
+		if pe, err := p.Entry(e.Name); err == nil && pe.Hash == e.Hash && pe.Mode == e.Mode {
+			return true
+		}
+	}
+	return false
+}
+
+// removed: the entry is in every parent and not in the tree.
+func removed(t *object.Tree, parents []*object.Tree, name string) bool {
+	if _, err := t.Entry(name); err == nil {
+		return false
+	}
+	for _, p := range parents {
+		if _, err := p.Entry(name); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// checkTree collects the paths outside the allowed dirs modified by the tree compared to its parents, dirs on the
+// way to an allowed dir are walked, other entries must be unchanged.
+func (c *permissionChecker) checkTree(ctx context.Context, t *object.Tree, parents []*object.Tree, dir string) error {
+	for _, e := range t.Entries {
+		name := path.Join(dir, e.Name)
+		if protocol.PathAllowed(c.allowed, name) || unchanged(parents, e) {
+			continue
+		}
+		if e.Type() == object.TreeObject && c.onTheWay(name) {
+			st, err := c.Tree(ctx, e.Hash)
+			if err != nil {
+				return err
+			}
+			pts, err := c.parentTrees(ctx, parents, e.Name)
+			if err != nil {
+				return err
+			}
+			if err := c.checkTree(ctx, st, pts, name); err != nil {
+				return err
+			}
+			continue
+		}
+		c.modified = append(c.modified, name)
+	}
+	if len(parents) == 0 {
+		return nil
+	}
+	for _, e := range parents[0].Entries {
+		name := path.Join(dir, e.Name)
+		if !protocol.PathAllowed(c.allowed, name) && removed(t, parents, e.Name) {
+			c.modified = append(c.modified, name)
+		}
+	}
+	return nil
+}
+
+// onTheWay reports whether dir is an ancestor of an allowed dir.
+func (c *permissionChecker) onTheWay(dir string) bool {
+	for _, a := range c.allowed {
+		if strings.HasPrefix(a, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPathPermissions rejects the push when a new commit modifies paths outside the dirs the user is restricted
+// to, commits are compared with their parents so merging the work of other users is allowed.
+func (r *QR) checkPathPermissions(ctx context.Context, cmd *Command, rr *reporter) error {
+	return checkPathPermissions(ctx, r, r.commits, cmd, rr)
+}
+
+func checkPathPermissions(ctx context.Context, r historyDB, commits []plumbing.Hash, cmd *Command, rr *reporter) error {
+	if len(cmd.Paths) == 0 || len(commits) == 0 {
+		return nil
+	}
+	c := &permissionChecker{historyDB: r, allowed: cmd.Paths}
+	var offending []string
+	for _, oid := range commits {
+		cc, err := r.Commit(ctx, oid)
+		if err != nil {
+			_ = rr.ng(cmd, "resolve commit '%s' error: %v", oid, err)
+			return err
+		}
+		parents := make([]*object.Tree, 0, len(cc.Parents))
+		for _, p := range cc.Parents {
+			pc, err := r.Commit(ctx, p)
+			if plumbing.IsNoSuchObject(err) {
+				continue
+			}
+			if err != nil {
+				_ = rr.ng(cmd, "resolve commit '%s' error: %v", p, err)
+				return err
+			}
+			root, err := r.Tree(ctx, pc.Tree)
+			if err != nil {
+				_ = rr.ng(cmd, "resolve tree '%s' error: %v", pc.Tree, err)
+				return err
+			}
+			parents = append(parents, root)
+		}
+		root, err := r.Tree(ctx, cc.Tree)
+		if err != nil {
+			_ = rr.ng(cmd, "resolve tree '%s' error: %v", cc.Tree, err)
+			return err
+		}
+		c.modified = c.modified[:0]
+		if err := c.checkTree(ctx, root, parents, ""); err != nil {
+			_ = rr.ng(cmd, "check path permissions of '%s' error: %v", oid, err)
+			return err
+		}
+		if len(c.modified) > pathViolationsMax {
+			c.modified = append(c.modified[:pathViolationsMax], "...")
+		}
+		if len(c.modified) != 0 {
+			offending = append(offending, fmt.Sprintf("  %s %s\n      %s", oid.String()[:12], cc.Subject(), strings.Join(c.modified, "\n      ")))
+		}
+	}
+	if len(offending) == 0 {
+		return nil
+	}
+	_ = rr.status(cmd.W("%d commits modify paths outside your allowed dirs (%s):"), len(offending), strings.Join(cmd.Paths, ", "))
+	for _, s := range offending {
+		_ = rr.status("%s", s)
+	}
+	_ = rr.ng(cmd, "\x1b[31merror\x1b[0m: %s", cmd.W("path permission denied"))
+	return ErrPathPermissionDenied
+}
@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+func TestCheckPathPermissions(t *testing.T) {
+	ctx := context.Background()
+	d := &memoryDB{commits: make(map[plumbing.Hash]*object.Commit), trees: make(map[plumbing.Hash]*object.Tree)}
+	now := time.Now()
+	base := d.commit("base", map[string]string{"docs/a.md": "a1", "src/main.go": "m1", "README": "r1"}, now)
+	docs := d.commit("docs", map[string]string{"docs/a.md": "a2", "src/main.go": "m1", "README": "r1"}, now, base)
+	src := d.commit("src", map[string]string{"docs/a.md": "a1", "src/main.go": "m2", "README": "r1"}, now, base)
+	// src is pushed by another user, the merge takes src/main.go from the second parent
+	merge := d.commit("merge", map[string]string{"docs/a.md": "a2", "src/main.go": "m2", "README": "r1"}, now, docs, src)
+	// src/main.go matches neither parent
+	evilMerge := d.commit("evil merge", map[string]string{"docs/a.md": "a2", "src/main.go": "m3", "README": "r1"}, now, docs, src)
+	// src/main.go is in both parents and removed
+	removeMerge := d.commit("remove merge", map[string]string{"docs/a.md": "a2", "README": "r1"}, now, docs, src)
+
+	for _, c := range []struct {
+		name     string
+		commits  []*object.Commit
+		rejected []string // src is reported as a whole, it is not on the way to docs
+	}{
+		{"allowed dir", []*object.Commit{docs}, nil},
+		{"outside allowed dir", []*object.Commit{src}, []string{"src"}},
+		{"docs then src", []*object.Commit{docs, src}, []string{"src"}},
+		{"merge", []*object.Commit{merge}, nil},
+		{"evil merge", []*object.Commit{evilMerge}, []string{"src"}},
+		{"remove merge", []*object.Commit{removeMerge}, []string{"src"}},
+	} {
+		var b bytes.Buffer
+		rr := newReporter(&b)
+		cmd := &Command{ReferenceName: plumbing.NewBranchReferenceName("mainline"), Paths: []string{"docs/"}}
+		commits := make([]plumbing.Hash, 0, len(c.commits))
+		for _, cc := range c.commits {
+			commits = append(commits, cc.Hash)
+		}
+		err := checkPathPermissions(ctx, d, commits, cmd, rr)
+		_ = rr.close()
+		out := b.String()
+		if len(c.rejected) == 0 {
+			if err != nil || len(cmd.Rejected) != 0 {
+				t.Fatalf("%s: unexpected rejection: %v\n%s", c.name, err, out)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrPathPermissionDenied) {
+			t.Fatalf("%s: expected ErrPathPermissionDenied, got %v\n%s", c.name, err, out)
+		}
+		if !strings.Contains(out, "ng refs/heads/mainline ") {
+			t.Fatalf("%s: expected ng report:\n%s", c.name, out)
+		}
+		for _, p := range c.rejected {
+			if !strings.Contains(out, "\n      "+p) {
+				t.Fatalf("%s: expected %s in report:\n%s", c.name, p, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve"
+)
+
+const (
+	pathActionReject = "reject"
+	pathActionWarn   = "warn"
+
+	pathCheckDotDir  = "dotdir"
+	pathCheckSymlink = "symlink"
+	pathCheckNTFS    = "ntfs"
+	pathCheckCase    = "case"
+
+	// symlink targets longer than this are not valid paths on any supported system
+	symlinkTargetMax = 4096
+	// offending paths reported to the client
+	pathViolationsMax = 20
+)
+
+var (
+	ErrPathPolicyViolation = errors.New("path policy violation")
+)
+
+// dotDirNames: the repository dirs and their 8.3 short name aliases, compared in lower case.
+var dotDirNames = map[string]bool{
+	".zeta":  true,
+	".git":   true,
+	"zeta~1": true,
+	"git~1":  true,
+}
+
+// ntfsReservedNames: device names, reserved with any extension, eg: nul.txt
+var ntfsReservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true, "com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true, "lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+type pathPolicy struct {
+	warn     bool
+	dotDir   bool
+	symlink  bool
+	ntfs     bool
+	caseFold bool
+}
+
+// newPathPolicy: trees received by pushes are always checked for malformed names and dotdir by default.
+func newPathPolicy(p *serve.PathPolicy) (*pathPolicy, error) {
+	pp := &pathPolicy{dotDir: true}
+	if p == nil {
+		return pp, nil
+	}
+	switch strings.ToLower(p.Action) {
+	case "", pathActionReject:
+	case pathActionWarn:
+		pp.warn = true
+	default:
+		return nil, fmt.Errorf("bad path policy action '%s'", p.Action)
+	}
+	if len(p.Checks) != 0 {
+		pp.dotDir = false
+	}
+	for _, c := range p.Checks {
+		switch strings.ToLower(c) {
+		case pathCheckDotDir:
+			pp.dotDir = true
+		case pathCheckSymlink:
+			pp.symlink = true
+		case pathCheckNTFS:
+			pp.ntfs = true
+		case pathCheckCase:
+			pp.caseFold = true
+		default:
+			return nil, fmt.Errorf("bad path policy check '%s'", c)
+		}
+	}
+	return pp, nil
+}
+
+// isDotDir: NTFS ignores trailing dots and spaces and opens the default stream of '.git::$INDEX_ALLOCATION'.
+func isDotDir(name string) bool {
+	name = strings.ToLower(name)
+	if i := strings.IndexByte(name, ':'); i != -1 {
+		name = name[:i]
+	}
+	return dotDirNames[strings.TrimRight(name, ". ")]
+}
+
+// checkName returns the problem of a tree entry name, empty when the name is acceptable.
+func (p *pathPolicy) checkName(name string) string {
+	if len(name) == 0 || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return "malformed name"
+	}
+	if p.dotDir {
+		// backslash is a path separator on Windows
+		for part := range strings.SplitSeq(name, "\\") {
+			if isDotDir(part) {
+				return "reserved repository dir"
+			}
+		}
+	}
+	if p.ntfs {
+		switch {
+		case strings.ContainsRune(name, '\\'):
+			return "backslash in name"
+		case strings.ContainsRune(name, ':'):
+			return "NTFS alternate data stream"
+		case strings.HasSuffix(name, ".") || strings.HasSuffix(name, " "):
+			return "trailing dot or space"
+		}
+		base, _, _ := strings.Cut(name, ".")
+		if ntfsReservedNames[strings.ToLower(strings.TrimRight(base, " "))] {
+			return "reserved device name"
+		}
+	}
+	return ""
+}
+
+// checkSymlink returns the problem of the symlink at dir/name with the target, empty when the target is acceptable.
+func (p *pathPolicy) checkSymlink(dir, target string) string {
+	target = strings.ReplaceAll(target, "\\", "/")
+	if strings.HasPrefix(target, "/") || (len(target) >= 2 && target[1] == ':') {
+		return "absolute symlink target"
+	}
+	resolved := path.Clean(path.Join(dir, target))
+	if resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return "symlink target escapes the worktree"
+	}
+	for part := range strings.SplitSeq(resolved, "/") {
+		if isDotDir(part) {
+			return "symlink target in repository dir"
+		}
+	}
+	return ""
+}
+
+type pathViolation struct {
+	path    string
+	problem string
+	detail  string
+}
+
+type pathDB interface {
+	historyDB
+	Blob(ctx context.Context, oid plumbing.Hash) (*object.Blob, error)
+}
+
+type pathChecker struct {
+	pathDB
+	p          *pathPolicy
+	received   map[plumbing.Hash]bool
+	seen       map[string]bool
+	violations []*pathViolation
+}
+
+func (c *pathChecker) readSymlink(ctx context.Context, oid plumbing.Hash) (string, error) {
+	br, err := c.Blob(ctx, oid)
+	if err != nil {
+		return "", err
+	}
+	defer br.Close() // nolint
+	target, err := io.ReadAll(io.LimitReader(br.Contents, symlinkTargetMax+1))
+	if err != nil {
+		return "", err
+	}
+	if len(target) > symlinkTargetMax {
+		return "", fmt.Errorf("symlink target too long")
+	}
+	return string(target), nil
+}
+
+// checkTree checks the trees received by the push, trees already in the repository were checked when they were pushed.
+// Symlink targets are relative to the dir, an existing tree placed in another dir may escape the worktree and trees
+// pushed before the policy was enabled were never checked, so all trees are checked when symlink is enabled.
+func (c *pathChecker) checkTree(ctx context.Context, oid plumbing.Hash, dir string) error {
+	if !c.received[oid] && !c.p.symlink {
+		return nil
+	}
+	// the same tree is checked once per dir
+	key := oid.String() + ":" + dir
+	if c.seen[key] {
+		return nil
+	}
+	c.seen[key] = true
+	tree, err := c.Tree(ctx, oid)
+	if err != nil {
+		return err
+	}
+	folded := make(map[string]string)
+	for _, e := range tree.Entries {
+		name := path.Join(dir, e.Name)
+		if problem := c.p.checkName(e.Name); len(problem) != 0 {
+			c.violations = append(c.violations, &pathViolation{path: name, problem: problem})
+			continue
+		}
+		if c.p.caseFold {
+			lower := strings.ToLower(e.Name)
+			if other, ok := folded[lower]; ok {
+				c.violations = append(c.violations, &pathViolation{path: name, problem: "case collides with", detail: path.Join(dir, other)})
+			} else {
+				folded[lower] = e.Name
+			}
+		}
+		switch {
+		case e.Mode == filemode.Dir:
+			if err := c.checkTree(ctx, e.Hash, name); err != nil {
+				return err
+			}
+		case e.Mode == filemode.Symlink && c.p.symlink:
+			target, err := c.readSymlink(ctx, e.Hash)
+			if err != nil {
+				c.violations = append(c.violations, &pathViolation{path: name, problem: "unreadable symlink", detail: err.Error()})
+				continue
+			}
+			if problem := c.p.checkSymlink(dir, target); len(problem) != 0 {
+				c.violations = append(c.violations, &pathViolation{path: name, problem: problem, detail: "'" + target + "'"})
+			}
+		}
+	}
+	return nil
+}
+
+// checkPaths checks the trees of the new commits received by this push, all offending paths are reported before
+// rejecting.
+func (r *QR) checkPaths(ctx context.Context, cmd *Command, rr *reporter, p *pathPolicy) error {
+	return checkPaths(ctx, r, r.Objects.Trees, r.commits, cmd, rr, p)
+}
+
+func checkPaths(ctx context.Context, r pathDB, received, commits []plumbing.Hash, cmd *Command, rr *reporter, p *pathPolicy) error {
+	if p == nil || len(commits) == 0 {
+		return nil
+	}
+	c := &pathChecker{pathDB: r, p: p, received: make(map[plumbing.Hash]bool), seen: make(map[string]bool)}
+	for _, oid := range received {
+		c.received[oid] = true
+	}
+	for _, oid := range commits {
+		cc, err := r.Commit(ctx, oid)
+		if err != nil {
+			_ = rr.ng(cmd, "resolve commit '%s' error: %v", oid, err)
+			return err
+		}
+		if err := c.checkTree(ctx, cc.Tree, ""); err != nil {
+			_ = rr.ng(cmd, "check paths of '%s' error: %v", oid, err)
+			return err
+		}
+	}
+	if len(c.violations) == 0 {
+		return nil
+	}
+	prefix := "\x1b[31merror\x1b[0m: "
+	if p.warn {
+		prefix = "\x1b[33mwarning\x1b[0m: "
+	}
+	_ = rr.status("%s%s", prefix, fmt.Sprintf(cmd.W("%d paths do not satisfy the path policy:"), len(c.violations)))
+	for i, v := range c.violations {
+		if i == pathViolationsMax {
+			_ = rr.status("  ...")
+			break
+		}
+		if len(v.detail) != 0 {
+			_ = rr.status("  %s: %s %s", v.path, cmd.W(v.problem), v.detail)
+			continue
+		}
+		_ = rr.status("  %s: %s", v.path, cmd.W(v.problem))
+	}
+	if p.warn {
+		return nil
+	}
+	_ = rr.ng(cmd, "\x1b[31merror\x1b[0m: %s", cmd.W("path policy violation"))
+	return ErrPathPolicyViolation
+}
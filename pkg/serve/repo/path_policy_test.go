@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve"
+)
+
+func TestPathPolicyName(t *testing.T) {
+	p, err := newPathPolicy(&serve.PathPolicy{Checks: []string{"dotdir", "ntfs"}})
+	if err != nil {
+		t.Fatalf("new path policy error: %v", err)
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"README.md", ""},
+		{".gitignore", ""},
+		{"..", "malformed name"},
+		{"a/b", "malformed name"},
+		{".git", "reserved repository dir"},
+		{".ZETA", "reserved repository dir"},
+		{".git. .", "reserved repository dir"},
+		{".git::$INDEX_ALLOCATION", "reserved repository dir"},
+		{"GIT~1", "reserved repository dir"},
+		{"a\\.git", "reserved repository dir"},
+		{"a\\b", "backslash in name"},
+		{"file.txt:stream", "NTFS alternate data stream"},
+		{"notes. ", "trailing dot or space"},
+		{"nul.txt", "reserved device name"},
+		{"Com1", "reserved device name"},
+		{"console", ""},
+	}
+	for _, tt := range tests {
+		if got := p.checkName(tt.name); got != tt.want {
+			t.Errorf("checkName(%q) = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+	def, _ := newPathPolicy(nil)
+	if def.checkName(".git") == "" || def.checkName("nul") != "" || def.warn {
+		t.Errorf("default policy should reject dotdir only")
+	}
+	if _, err := newPathPolicy(&serve.PathPolicy{Action: "ignore"}); err == nil {
+		t.Errorf("bad action should fail")
+	}
+	if _, err := newPathPolicy(&serve.PathPolicy{Checks: []string{"unknown"}}); err == nil {
+		t.Errorf("bad check should fail")
+	}
+}
+
+func TestPathPolicySymlink(t *testing.T) {
+	p, _ := newPathPolicy(&serve.PathPolicy{Checks: []string{"symlink"}})
+	tests := []struct {
+		dir, target string
+		want        string
+	}{
+		{"docs", "../README.md", ""},
+		{"a/b", "../../c", ""},
+		{"a", "../../etc/passwd", "symlink target escapes the worktree"},
+		{"", "..", "symlink target escapes the worktree"},
+		{"a", "b/../../..", "symlink target escapes the worktree"},
+		{"a", "/etc/passwd", "absolute symlink target"},
+		{"", "C:\\Windows", "absolute symlink target"},
+		{"a", "..\\..\\x", "symlink target escapes the worktree"},
+		{"a", "../.zeta/config", "symlink target in repository dir"},
+	}
+	for _, tt := range tests {
+		if got := p.checkSymlink(tt.dir, tt.target); got != tt.want {
+			t.Errorf("checkSymlink(%q, %q) = %q; want %q", tt.dir, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPaths(t *testing.T) {
+	ctx := context.Background()
+	d := &memoryDB{commits: make(map[plumbing.Hash]*object.Commit), trees: make(map[plumbing.Hash]*object.Tree), blobs: make(map[plumbing.Hash]string)}
+	db := &archiveMemoryDB{memoryDB: d}
+	blob := func(content string) plumbing.Hash {
+		oid := hashString(content)
+		d.blobs[oid] = content
+		return oid
+	}
+	tree := func(entries ...*object.TreeEntry) plumbing.Hash {
+		var b strings.Builder
+		for _, e := range entries {
+			b.WriteString(e.Name + e.Hash.String())
+		}
+		oid := hashString(b.String())
+		d.trees[oid] = &object.Tree{Hash: oid, Entries: entries}
+		return oid
+	}
+	commit := func(message string, root plumbing.Hash) plumbing.Hash {
+		oid := hashString(message + root.String())
+		d.commits[oid] = &object.Commit{Hash: oid, Tree: root, Message: message}
+		return oid
+	}
+	// pushed before: lib/link -> ../README resolves to README
+	lib := tree(&object.TreeEntry{Name: "link", Mode: filemode.Symlink, Hash: blob("../README")})
+	// pushed before the path policy was enabled
+	dotDir := tree(&object.TreeEntry{Name: ".git", Mode: filemode.Dir, Hash: tree(&object.TreeEntry{Name: "config", Mode: filemode.Regular, Hash: blob("c")})})
+	readme := &object.TreeEntry{Name: "README", Mode: filemode.Regular, Hash: blob("r")}
+	// the existing lib tree at the root: link -> ../README escapes the worktree
+	reRooted := tree(readme, &object.TreeEntry{Name: "link", Mode: filemode.Symlink, Hash: blob("../README")})
+	moved := tree(readme, &object.TreeEntry{Name: "vendor", Mode: filemode.Dir, Hash: tree(&object.TreeEntry{Name: "lib", Mode: filemode.Dir, Hash: lib})})
+	for _, c := range []struct {
+		name     string
+		checks   []string
+		root     plumbing.Hash
+		received []plumbing.Hash
+		rejected []string
+	}{
+		{"existing tree in the same dir", []string{"symlink"}, tree(&object.TreeEntry{Name: "lib", Mode: filemode.Dir, Hash: lib}, readme), nil, nil},
+		{"existing tree in another dir", []string{"symlink"}, moved, []plumbing.Hash{moved}, nil},
+		{"existing tree at the root", []string{"symlink"}, reRooted, nil, []string{"link: symlink target escapes the worktree"}},
+		{"existing tree moved up", []string{"symlink"}, tree(readme, &object.TreeEntry{Name: "a", Mode: filemode.Dir, Hash: reRooted}), nil, nil},
+		{"tree pushed before the policy", []string{"dotdir", "symlink"}, tree(readme, &object.TreeEntry{Name: "old", Mode: filemode.Dir, Hash: dotDir}), nil, []string{"old/.git: reserved repository dir"}},
+		{"existing trees without symlink check", []string{"dotdir"}, tree(readme, &object.TreeEntry{Name: "old", Mode: filemode.Dir, Hash: dotDir}), nil, nil},
+	} {
+		p, err := newPathPolicy(&serve.PathPolicy{Checks: c.checks})
+		if err != nil {
+			t.Fatalf("new path policy error: %v", err)
+		}
+		var b bytes.Buffer
+		rr := newReporter(&b)
+		cmd := &Command{ReferenceName: plumbing.NewBranchReferenceName("mainline")}
+		err = checkPaths(ctx, db, c.received, []plumbing.Hash{commit(c.name, c.root)}, cmd, rr, p)
+		_ = rr.close()
+		out := b.String()
+		if len(c.rejected) == 0 {
+			if err != nil {
+				t.Fatalf("%s: unexpected rejection: %v\n%s", c.name, err, out)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrPathPolicyViolation) {
+			t.Fatalf("%s: expected ErrPathPolicyViolation, got %v\n%s", c.name, err, out)
+		}
+		for _, s := range c.rejected {
+			if !strings.Contains(out, "  "+s) {
+				t.Fatalf("%s: expected %q in report:\n%s", c.name, s, out)
+			}
+		}
+	}
+}
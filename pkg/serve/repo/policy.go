@@ -0,0 +1,164 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/commitmsg"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve"
+)
+
+const (
+	signedOffBy = "Signed-off-by"
+)
+
+var (
+	ErrPolicyViolation = errors.New("commit policy violation")
+)
+
+type commitPolicy struct {
+	signOff  bool
+	trailers []*regexp.Regexp
+	branches []string
+	rules    *commitmsg.Rules
+}
+
+func newCommitPolicy(p *serve.CommitPolicy) (*commitPolicy, error) {
+	if p == nil || (!p.SignOff && len(p.Trailers) == 0 && len(p.MessagePolicies) == 0) {
+		return nil, nil
+	}
+	rules, err := commitmsg.New(&commitmsg.Options{
+		Policies:      p.MessagePolicies,
+		IssuePattern:  p.IssuePattern,
+		SubjectLength: p.SubjectLength,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("bad commit policy: %w", err)
+	}
+	cp := &commitPolicy{signOff: p.SignOff, branches: p.Branches, rules: rules}
+	for _, b := range p.Branches {
+		if _, err := path.Match(b, ""); err != nil {
+			return nil, fmt.Errorf("bad commit policy branch pattern '%s': %w", b, err)
+		}
+	}
+	for _, s := range p.Trailers {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("bad commit policy trailer pattern '%s': %w", s, err)
+		}
+		cp.trailers = append(cp.trailers, re)
+	}
+	return cp, nil
+}
+
+// match reports whether the policy applies to the command.
+func (p *commitPolicy) match(cmd *Command) bool {
+	if p == nil || !cmd.ReferenceName.IsBranch() {
+		return false
+	}
+	if cmd.Protected {
+		return true
+	}
+	branchName := cmd.ReferenceName.BranchName()
+	for _, b := range p.branches {
+		if ok, _ := path.Match(b, branchName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrailers returns the trailer lines of the last paragraph of the commit message.
+func parseTrailers(message string) []string {
+	return commitmsg.ParseTrailers(message)
+}
+
+func hasSignOff(trailers []string, email string) bool {
+	for _, t := range trailers {
+		token, value, _ := strings.Cut(t, ": ")
+		if !strings.EqualFold(token, signedOffBy) {
+			continue
+		}
+		if l, r := strings.LastIndexByte(value, '<'), strings.LastIndexByte(value, '>'); l != -1 && r > l {
+			if strings.EqualFold(value[l+1:r], email) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// check returns the policy requirements not satisfied by the commit.
+func (p *commitPolicy) check(cc *object.Commit) []string {
+	trailers := parseTrailers(cc.Message)
+	var missing []string
+	if p.signOff && !hasSignOff(trailers, cc.Author.Email) {
+		missing = append(missing, fmt.Sprintf("%s: %s <%s>", signedOffBy, cc.Author.Name, cc.Author.Email))
+	}
+	for _, re := range p.trailers {
+		matched := false
+		for _, t := range trailers {
+			if re.MatchString(t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			missing = append(missing, re.String())
+		}
+	}
+	return missing
+}
+
+// checkPolicy checks the commits in base..NewRev, commits already on the server are checked too: they may have been
+// pushed to a branch the policy does not apply to. All offending commits are reported before rejecting.
+func (r *QR) checkPolicy(ctx context.Context, cmd *Command, rr *reporter, p *commitPolicy, base plumbing.Hash) error {
+	return checkPolicy(ctx, r, base, cmd, rr, p)
+}
+
+func checkPolicy(ctx context.Context, r historyDB, base plumbing.Hash, cmd *Command, rr *reporter, p *commitPolicy) error {
+	if !p.match(cmd) || cmd.NewRev == plumbing.ZERO_OID {
+		return nil
+	}
+	commits, err := revisionRange(ctx, r, base, plumbing.NewHash(cmd.NewRev))
+	if err != nil {
+		_ = rr.ng(cmd, "resolve commits '%s' error: %v", cmd.NewRev, err)
+		return err
+	}
+	var offending []string
+	for _, oid := range commits {
+		cc, err := r.Commit(ctx, oid)
+		if err != nil {
+			_ = rr.ng(cmd, "resolve commit '%s' error: %v", oid, err)
+			return err
+		}
+		var problems []string
+		if missing := p.check(cc); len(missing) != 0 {
+			problems = append(problems, cmd.W("missing trailer: ")+strings.Join(missing, ", "))
+		}
+		for _, v := range p.rules.Check(cc.Message) {
+			problems = append(problems, fmt.Sprintf(cmd.W(v.Format), v.Args...))
+		}
+		if len(problems) != 0 {
+			offending = append(offending, fmt.Sprintf("  %s %s\n      %s", oid.String()[:12], cc.Subject(), strings.Join(problems, "\n      ")))
+		}
+	}
+	if len(offending) == 0 {
+		return nil
+	}
+	_ = rr.status(cmd.W("%d commits do not satisfy the commit policy of '%s':"), len(offending), cmd.ReferenceName)
+	for _, s := range offending {
+		_ = rr.status("%s", s)
+	}
+	_ = rr.ng(cmd, "\x1b[31merror\x1b[0m: %s", cmd.W("commit policy violation"))
+	return ErrPolicyViolation
+}
@@ -0,0 +1,197 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve"
+)
+
+func TestParseTrailers(t *testing.T) {
+	tests := []struct {
+		message string
+		want    []string
+	}{
+		{"subject only", nil},
+		{"subject\n\nSigned-off-by: Jane <jane@example.com>\n", []string{"Signed-off-by: Jane <jane@example.com>"}},
+		{"subject\n\nbody\n\nChange-Id: I1234\nSigned-off-by: Jane <jane@example.com>", []string{"Change-Id: I1234", "Signed-off-by: Jane <jane@example.com>"}},
+		{"subject\n\nbody: not a trailer block\nbecause of this line", nil},
+		{"subject\n\nReviewed-by: a\n  continued", []string{"Reviewed-by: a continued"}},
+	}
+	for _, tt := range tests {
+		if got := parseTrailers(tt.message); !slices.Equal(got, tt.want) {
+			t.Errorf("parseTrailers(%q) = %q; want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestCommitPolicy(t *testing.T) {
+	p, err := newCommitPolicy(&serve.CommitPolicy{
+		SignOff:  true,
+		Trailers: []string{`^Change-Id: I[0-9a-f]+$`},
+		Branches: []string{"release/*"},
+	})
+	if err != nil {
+		t.Fatalf("new commit policy error: %v", err)
+	}
+	if !p.match(&Command{ReferenceName: plumbing.NewBranchReferenceName("release/1.0")}) {
+		t.Errorf("policy should apply to release/1.0")
+	}
+	if p.match(&Command{ReferenceName: plumbing.NewBranchReferenceName("dev")}) {
+		t.Errorf("policy should not apply to dev")
+	}
+	if !p.match(&Command{ReferenceName: plumbing.NewBranchReferenceName("dev"), Protected: true}) {
+		t.Errorf("policy should apply to protected branch")
+	}
+	author := object.Signature{Name: "Jane", Email: "jane@example.com"}
+	good := &object.Commit{Author: author, Message: "fix\n\nChange-Id: Iabc123\nSigned-off-by: Jane <JANE@example.com>\n"}
+	if missing := p.check(good); len(missing) != 0 {
+		t.Errorf("unexpected missing trailers: %v", missing)
+	}
+	bad := &object.Commit{Author: author, Message: "fix\n\nSigned-off-by: Joe <joe@example.com>\n"}
+	if missing := p.check(bad); len(missing) != 2 {
+		t.Errorf("expected 2 missing trailers, got: %v", missing)
+	}
+	if p, _ := newCommitPolicy(&serve.CommitPolicy{Branches: []string{"main"}}); p != nil {
+		t.Errorf("empty policy should be nil")
+	}
+	p, err = newCommitPolicy(&serve.CommitPolicy{MessagePolicies: []string{"issue", "subject-length"}, SubjectLength: 10})
+	if err != nil {
+		t.Fatalf("new message policy error: %v", err)
+	}
+	if v := p.rules.Check("JIRA-1 fix"); len(v) != 0 || len(p.check(good)) != 0 {
+		t.Errorf("unexpected violations: %v", v)
+	}
+	if v := p.rules.Check("fix the crash"); len(v) != 2 {
+		t.Errorf("expected 2 violations, got: %v", v)
+	}
+	if _, err := newCommitPolicy(&serve.CommitPolicy{MessagePolicies: []string{"bad"}}); err == nil {
+		t.Errorf("unknown message policy should fail")
+	}
+}
+
+func TestRevisionRange(t *testing.T) {
+	ctx := context.Background()
+	d := &memoryDB{commits: make(map[plumbing.Hash]*object.Commit), trees: make(map[plumbing.Hash]*object.Tree)}
+	now := time.Now()
+	c1 := d.commit("c1", map[string]string{"a": "1"}, now)
+	c2 := d.commit("c2", map[string]string{"a": "2"}, now.Add(time.Minute), c1)
+	side := d.commit("side", map[string]string{"b": "1"}, now.Add(2*time.Minute), c1)
+	// the committer time of c3 is older than its parent
+	c3 := d.commit("c3", map[string]string{"a": "3"}, now.Add(-time.Hour), c2)
+	merge := d.commit("merge", map[string]string{"a": "3", "b": "1"}, now.Add(3*time.Minute), c3, side)
+	for _, c := range []struct {
+		name    string
+		oldRev  plumbing.Hash
+		newRev  *object.Commit
+		commits []*object.Commit
+	}{
+		{"new branch", plumbing.ZeroHash, c2, []*object.Commit{c2, c1}},
+		{"fast-forward", c1.Hash, c2, []*object.Commit{c2}},
+		{"merge", c2.Hash, merge, []*object.Commit{merge, side, c3}},
+		{"up to date", merge.Hash, merge, nil},
+		{"rewind", merge.Hash, c2, nil},
+		{"force push", side.Hash, c3, []*object.Commit{c3, c2}},
+	} {
+		got, err := revisionRange(ctx, d, c.oldRev, c.newRev.Hash)
+		if err != nil {
+			t.Fatalf("%s: revision range error: %v", c.name, err)
+		}
+		want := make([]plumbing.Hash, 0, len(c.commits))
+		for _, cc := range c.commits {
+			want = append(want, cc.Hash)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: revision range = %v; want %v", c.name, got, want)
+		}
+	}
+}
+
+func TestCheckPolicyExistingCommits(t *testing.T) {
+	ctx := context.Background()
+	d := &memoryDB{commits: make(map[plumbing.Hash]*object.Commit), trees: make(map[plumbing.Hash]*object.Tree)}
+	p, err := newCommitPolicy(&serve.CommitPolicy{SignOff: true, Branches: []string{"release/*"}})
+	if err != nil {
+		t.Fatalf("new commit policy error: %v", err)
+	}
+	now := time.Now()
+	base := d.commit("base\n\nSigned-off-by: Jane <jane@example.com>\n", map[string]string{"a": "1"}, now)
+	// first push: the commit without sign-off goes to dev, the policy does not apply
+	unsigned := d.commit("unsigned", map[string]string{"a": "2"}, now.Add(time.Minute), base)
+	signed := d.commit("signed\n\nSigned-off-by: Jane <jane@example.com>\n", map[string]string{"a": "3"}, now.Add(2*time.Minute), unsigned)
+	for _, cc := range []*object.Commit{base, unsigned, signed} {
+		cc.Author = object.Signature{Name: "Jane", Email: "jane@example.com"}
+	}
+	for _, c := range []struct {
+		name     string
+		oldRev   string
+		newRev   *object.Commit
+		rejected bool
+	}{
+		// second push: release/1.0 is fast-forwarded to commits the server already has
+		{"fast-forward", base.Hash.String(), signed, true},
+		{"new branch", plumbing.ZERO_OID, signed, true},
+		{"signed only", unsigned.Hash.String(), signed, false},
+	} {
+		var b bytes.Buffer
+		rr := newReporter(&b)
+		cmd := &Command{ReferenceName: plumbing.NewBranchReferenceName("release/1.0"), OldRev: c.oldRev, NewRev: c.newRev.Hash.String()}
+		var oldRev plumbing.Hash
+		if c.oldRev != plumbing.ZERO_OID {
+			oldRev = plumbing.NewHash(c.oldRev)
+		}
+		err := checkPolicy(ctx, d, oldRev, cmd, rr, p)
+		_ = rr.close()
+		out := b.String()
+		if !c.rejected {
+			if err != nil {
+				t.Fatalf("%s: unexpected rejection: %v\n%s", c.name, err, out)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrPolicyViolation) {
+			t.Fatalf("%s: expected ErrPolicyViolation, got %v\n%s", c.name, err, out)
+		}
+		if !strings.Contains(out, unsigned.Hash.String()[:12]+" unsigned") || strings.Contains(out, signed.Hash.String()[:12]) {
+			t.Fatalf("%s: expected only the unsigned commit in report:\n%s", c.name, out)
+		}
+	}
+}
+
+func TestCheckMessagePolicyExistingCommits(t *testing.T) {
+	ctx := context.Background()
+	d := &memoryDB{commits: make(map[plumbing.Hash]*object.Commit), trees: make(map[plumbing.Hash]*object.Tree)}
+	p, err := newCommitPolicy(&serve.CommitPolicy{MessagePolicies: []string{"issue"}, Branches: []string{"release/*"}})
+	if err != nil {
+		t.Fatalf("new commit policy error: %v", err)
+	}
+	now := time.Now()
+	base := d.commit("JIRA-1 base", map[string]string{"a": "1"}, now)
+	// pushed to dev first, then release/1.0 is fast-forwarded to it
+	noIssue := d.commit("fix the crash", map[string]string{"a": "2"}, now.Add(time.Minute), base)
+	head := d.commit("JIRA-2 follow up", map[string]string{"a": "3"}, now.Add(2*time.Minute), noIssue)
+	var b bytes.Buffer
+	rr := newReporter(&b)
+	cmd := &Command{ReferenceName: plumbing.NewBranchReferenceName("release/1.0"), OldRev: base.Hash.String(), NewRev: head.Hash.String()}
+	err = checkPolicy(ctx, d, base.Hash, cmd, rr, p)
+	_ = rr.close()
+	out := b.String()
+	if !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("expected ErrPolicyViolation, got %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "1 commits do not satisfy") || !strings.Contains(out, noIssue.Hash.String()[:12]+" fix the crash") {
+		t.Fatalf("expected the commit without issue in report:\n%s", out)
+	}
+	// the same commits pushed to a branch the policy does not apply to
+	cmd = &Command{ReferenceName: plumbing.NewBranchReferenceName("dev"), OldRev: base.Hash.String(), NewRev: head.Hash.String()}
+	if err := checkPolicy(ctx, d, base.Hash, cmd, newReporter(&b), p); err != nil {
+		t.Fatalf("policy should not apply to dev: %v", err)
+	}
+}
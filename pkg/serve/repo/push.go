@@ -19,9 +19,10 @@ import (
 	"github.com/antgroup/hugescm/modules/zeta/object"
 	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/extension"
 	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/emirpasic/gods/trees/binaryheap"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -52,14 +53,26 @@ type Command struct {
 	NewRev        string                 `json:"new_rev"`
 	Language      string                 // language
 	Terminal      string                 // term
+	Protected     bool                   // branch is protected, commit policy is enforced
+	Paths         []string               // dirs the user is restricted to, empty when not restricted
 	M             int
 	B             int
+	Rejected      string // why the push was rejected, the message of the 'ng' report
+	DryRun        bool   // validate the push and report the result without updating the reference
 }
 
 func (c *Command) W(message string) string {
 	return serve.Translate(c.Language, message)
 }
 
+// Reason returns why the push failed, the rejection reported to the user wins over err.
+func (c *Command) Reason(err error) string {
+	if len(c.Rejected) != 0 {
+		return c.Rejected
+	}
+	return err.Error()
+}
+
 func (c *Command) UpdateStats(s string) {
 	kv := strengthen.StrSplitSkipEmpty(s, ';', 2)
 	for _, k := range kv {
@@ -108,6 +121,7 @@ func (r *reporter) ok(cmd *Command, newRev string) error {
 func (r *reporter) ng(cmd *Command, format string, a ...any) error {
 	message := fmt.Sprintf(format, a...)
 	logrus.Errorf("[%s] %s", cmd.ReferenceName, message)
+	cmd.Rejected = message
 	return r.Encodef("ng %s %s", cmd.ReferenceName, message)
 }
 
@@ -116,19 +130,23 @@ type QR struct {
 	seen      map[plumbing.Hash]bool
 	commits   []plumbing.Hash
 	forcePush bool
+	// missing: blobs neither received nor on the server, a dry-run push only sends the metadata so they are
+	// recorded instead of rejecting the push
+	missing map[plumbing.Hash]bool
 }
 
-func NewQR(o *odb.ODB, quarantineDir string) (*QR, error) {
-	d, err := odb.NewQuarantineDB(o, quarantineDir)
-	if err != nil {
-		return nil, err
-	}
-	return &QR{QuarantineDB: d, seen: make(map[plumbing.Hash]bool), forcePush: true}, nil
+func NewQR(q *odb.QuarantineDB) *QR {
+	return &QR{QuarantineDB: q, seen: make(map[plumbing.Hash]bool), forcePush: true}
 }
 
-func (r *QR) Close() error {
-	if r.QuarantineDB != nil {
-		return r.QuarantineDB.Close()
+func (r *QR) checkBlob(ctx context.Context, cmd *Command, rr *reporter, oid plumbing.Hash) error {
+	if err := r.Exists(ctx, oid, false); err != nil {
+		if r.missing != nil {
+			r.missing[oid] = true
+			return nil
+		}
+		_ = rr.ng(cmd, "blob '%s' not exists", oid)
+		return zeta.NewErrNotExist("blob", oid.String())
 	}
 	return nil
 }
@@ -156,15 +174,13 @@ func (r *QR) checkTreeIntegrity(ctx context.Context, cmd *Command, rr *reporter,
 				return err
 			}
 			for _, fe := range ff.Entries {
-				if err := r.Exists(ctx, fe.Hash, false); err != nil {
-					_ = rr.ng(cmd, "blob '%s' not exists", fe.Hash)
-					return zeta.NewErrNotExist("blob", fe.Hash.String())
+				if err := r.checkBlob(ctx, cmd, rr, fe.Hash); err != nil {
+					return err
 				}
 			}
 		case object.BlobObject:
-			if err := r.Exists(ctx, e.Hash, false); err != nil {
-				_ = rr.ng(cmd, "blob '%s' not exists", e.Hash)
-				return zeta.NewErrNotExist("blob", e.Hash.String())
+			if err := r.checkBlob(ctx, cmd, rr, e.Hash); err != nil {
+				return err
 			}
 		default:
 			return fmt.Errorf("unsupported object type %v", e.Type())
@@ -211,6 +227,136 @@ func (r *QR) checkIntegrity(ctx context.Context, cmd *Command, rr *reporter) err
 	return r.checkCommitIntegrity(ctx, cmd, rr, plumbing.NewHash(cmd.NewRev))
 }
 
+// revisionRangeSlop: commits walked after every queued commit is reachable from the old revision, a skewed committer
+// time may hide that a walked commit is reachable too.
+const revisionRangeSlop = 5
+
+// revisionRange returns the commits reachable from newRev but not from oldRev (oldRev..newRev) newest first, whether
+// they are received by this push or already on the server. Commits are walked by committer time like git rev-list,
+// a skewed committer time can only add commits reachable from oldRev, never drop others.
+func revisionRange(ctx context.Context, r historyDB, oldRev, newRev plumbing.Hash) ([]plumbing.Hash, error) {
+	queue := binaryheap.NewWith(func(a, b any) int {
+		if a.(*object.Commit).Committer.When.Before(b.(*object.Commit).Committer.When) {
+			return 1
+		}
+		return -1
+	})
+	uninteresting := make(map[plumbing.Hash]bool)
+	parents := make(map[plumbing.Hash][]plumbing.Hash)
+	queued := make(map[plumbing.Hash]bool)
+	push := func(oid plumbing.Hash) error {
+		if queued[oid] {
+			return nil
+		}
+		cc, err := r.Commit(ctx, oid)
+		if err != nil {
+			return err
+		}
+		queued[oid] = true
+		queue.Push(cc)
+		return nil
+	}
+	// mark: commits reachable from oldRev found after they are walked, e.g. with a skewed committer time
+	mark := func(oid plumbing.Hash) {
+		stack := []plumbing.Hash{oid}
+		for len(stack) != 0 {
+			oid, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			if uninteresting[oid] {
+				continue
+			}
+			uninteresting[oid] = true
+			stack = append(stack, parents[oid]...)
+		}
+	}
+	interesting := func() bool {
+		for _, v := range queue.Values() {
+			if !uninteresting[v.(*object.Commit).Hash] {
+				return true
+			}
+		}
+		return false
+	}
+	if !oldRev.IsZero() {
+		if err := push(oldRev); err != nil {
+			return nil, err
+		}
+		uninteresting[oldRev] = true
+	}
+	if err := push(newRev); err != nil {
+		return nil, err
+	}
+	var walked []plumbing.Hash
+	for slop := revisionRangeSlop; queue.Size() != 0; {
+		if interesting() {
+			slop = revisionRangeSlop
+		} else if slop--; slop < 0 {
+			break
+		}
+		v, _ := queue.Pop()
+		cc := v.(*object.Commit)
+		parents[cc.Hash] = cc.Parents
+		if uninteresting[cc.Hash] {
+			for _, p := range cc.Parents {
+				mark(p)
+			}
+		} else {
+			walked = append(walked, cc.Hash)
+		}
+		for _, p := range cc.Parents {
+			if err := push(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	commits := make([]plumbing.Hash, 0, len(walked))
+	for _, oid := range walked {
+		if !uninteresting[oid] {
+			commits = append(commits, oid)
+		}
+	}
+	return commits, nil
+}
+
+func newPushEvent(cmd *Command, commits []plumbing.Hash) *extension.PushEvent {
+	e := &extension.PushEvent{
+		RID:           cmd.RID,
+		UID:           cmd.UID,
+		ReferenceName: cmd.ReferenceName.String(),
+		OldRev:        cmd.OldRev,
+		NewRev:        cmd.NewRev,
+		DryRun:        cmd.DryRun,
+	}
+	for _, oid := range commits {
+		e.Commits = append(e.Commits, oid.String())
+	}
+	return e
+}
+
+// checkExtensions asks the extensions whether the push is allowed.
+func (r *repository) checkExtensions(ctx context.Context, cmd *Command, rr *reporter, e *extension.PushEvent) error {
+	if err := r.ext.CheckPush(ctx, e); err != nil {
+		if v, ok := errors.AsType[*extension.Veto](err); ok {
+			_ = rr.ng(cmd, "\x1b[31merror\x1b[0m: %s%s: %s", cmd.W("push rejected by extension "), v.Extension, v.Message)
+			return err
+		}
+		_ = rr.ng(cmd, "check push error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// rangeBase returns the commit the pushed history is compared with: the old revision, or the default branch when a
+// new branch is created.
+func (r *repository) rangeBase(ctx context.Context, cmd *Command) plumbing.Hash {
+	if cmd.OldRev != plumbing.ZERO_OID {
+		return plumbing.NewHash(cmd.OldRev)
+	}
+	if b, err := r.mdb.FindBranch(ctx, r.rid, r.defaultBranch); err == nil {
+		return plumbing.NewHash(b.Hash)
+	}
+	return plumbing.ZeroHash
+}
+
 func (r *repository) DoPush(ctx context.Context, cmd *Command, reader io.Reader, w io.Writer) error {
 	ro := newReporter(w)
 	// remove branch or tag
@@ -219,6 +365,15 @@ func (r *repository) DoPush(ctx context.Context, cmd *Command, reader io.Reader,
 			_ = ro.ng(cmd, "\x1b[31merror\x1b[0m: %s%s", cmd.W("refusing to delete the current branch: "), cmd.ReferenceName)
 			return ErrReportStarted
 		}
+		e := newPushEvent(cmd, nil)
+		if err := r.checkExtensions(ctx, cmd, ro, e); err != nil {
+			return ErrReportStarted
+		}
+		if cmd.DryRun {
+			_ = ro.status("%s", cmd.W("dry run: the reference would be deleted")) //nolint:govet
+			_ = ro.ok(cmd, cmd.NewRev)
+			return nil
+		}
 		newReference, err := r.mdb.DoReferenceUpdate(ctx, &database.Command{
 			ReferenceName: cmd.ReferenceName,
 			NewRev:        cmd.NewRev,
@@ -235,73 +390,53 @@ func (r *repository) DoPush(ctx context.Context, cmd *Command, reader io.Reader,
 			return ErrReportStarted
 		}
 		_ = ro.ok(cmd, newReference.Hash)
+		r.ext.NotifyPush(e)
 		return nil
 	}
-	var verified bool
-	recvObjects, err := r.odb.Unpack(ctx, reader, &odb.OStats{M: cmd.M, B: cmd.B}, func(ctx context.Context, quarantineDir string, o *odb.Objects) error {
-		verified = true
-		if err := ro.EncodeString("unpack ok"); err != nil {
-			_ = ro.close()
-			return ErrReportStarted
-		}
-		qr, err := NewQR(r.odb, quarantineDir)
-		if err != nil {
-			_ = ro.ng(cmd, cmd.W("check integrity error: %v"), err)
-			return err
-		}
-		defer qr.Close() // nolint
-
-		if err = qr.checkIntegrity(ctx, cmd, ro); err != nil {
-			_ = ro.close()
-			return ErrReportStarted
-		}
-		if qr.forcePush && cmd.OldRev != plumbing.ZERO_OID {
-			logrus.Infof("Force push, oldRev %s --> newRev %s", cmd.OldRev, cmd.NewRev)
-		}
-		return nil
-	})
+	// objects stay in the quarantine until the reference is updated, a rejected push is dropped with the quarantine
+	q, err := r.odb.Unpack(ctx, reader, &odb.OStats{M: cmd.M, B: cmd.B})
 	if err != nil {
-		if !verified {
-			_ = ro.ng(cmd, "upack error: %v", err)
-		}
+		_ = ro.ng(cmd, "upack error: %v", err)
 		return err
 	}
-	logrus.Infof("objects %d", len(recvObjects.Commits))
+	defer q.Close() // nolint
+	if err := ro.EncodeString("unpack ok"); err != nil {
+		_ = ro.close()
+		return ErrReportStarted
+	}
 	defer ro.close() // nolint
-	if err := r.odb.Reload(); err != nil {
-		_ = ro.ng(cmd, "reload odb error: %v", err)
-		return err
+	qr := NewQR(q)
+	if cmd.DryRun {
+		qr.missing = make(map[plumbing.Hash]bool)
 	}
-	var g errgroup.Group
-	g.Go(func() error {
-		if err := r.odb.BatchObjects(ctx, recvObjects.Objects, 50); err != nil {
-			logrus.Errorf("batch upload blobs error: %v", err)
-			return err
-		}
-		return nil
-	})
-	g.Go(func() error {
-		if err := r.odb.BatchMetaObjects(ctx, recvObjects.MetaObjects); err != nil {
-			logrus.Errorf("batch encode metadata objects error: %v", err)
-			return err
-		}
-		return nil
-	})
-	g.Go(func() error {
-		if err := r.odb.BatchTrees(ctx, recvObjects.Trees); err != nil {
-			logrus.Errorf("batch encode trees error: %v", err)
-			return err
-		}
-		return nil
-	})
-	g.Go(func() error {
-		if err := r.odb.BatchCommits(ctx, recvObjects.Commits); err != nil {
-			logrus.Errorf("batch encode commits error: %v", err)
-			return err
-		}
+	if err = qr.checkIntegrity(ctx, cmd, ro); err != nil {
+		return ErrReportStarted
+	}
+	if err = qr.checkPolicy(ctx, cmd, ro, r.policy, r.rangeBase(ctx, cmd)); err != nil {
+		return ErrReportStarted
+	}
+	if err = qr.checkPaths(ctx, cmd, ro, r.pathPolicy); err != nil {
+		return ErrReportStarted
+	}
+	if err = qr.checkPathPermissions(ctx, cmd, ro); err != nil {
+		return ErrReportStarted
+	}
+	e := newPushEvent(cmd, qr.commits)
+	if err = r.checkExtensions(ctx, cmd, ro, e); err != nil {
+		return ErrReportStarted
+	}
+	if qr.forcePush && cmd.OldRev != plumbing.ZERO_OID {
+		logrus.Infof("Force push, oldRev %s --> newRev %s", cmd.OldRev, cmd.NewRev)
+	}
+	// dry run: all checks passed, the quarantine is dropped and the reference is not updated
+	if cmd.DryRun {
+		_ = ro.status(cmd.W("dry run: %d objects are not on the server and would be uploaded"), len(qr.missing))
+		_ = ro.status("%s", cmd.W("dry run: the reference would be updated")) //nolint:govet
+		_ = ro.ok(cmd, cmd.NewRev)
 		return nil
-	})
-	if err := g.Wait(); err != nil {
+	}
+	logrus.Infof("objects %d", len(q.Objects.Commits))
+	if err := q.Publish(ctx); err != nil {
 		_ = ro.ng(cmd, "store object error: %v", err)
 		return ErrReportStarted
 	}
@@ -314,7 +449,7 @@ func (r *repository) DoPush(ctx context.Context, cmd *Command, reader io.Reader,
 		UID:           cmd.UID,
 	}
 	if cmd.ReferenceName.IsTag() {
-		if to, err := r.odb.Tag(ctx, plumbing.NewHash(cmd.NewRev)); err == nil {
+		if to, err := qr.Tag(ctx, plumbing.NewHash(cmd.NewRev)); err == nil {
 			message, _ := to.Extract()
 			change.Subject, change.Description = messageSplit(message)
 		}
@@ -328,6 +463,11 @@ func (r *repository) DoPush(ctx context.Context, cmd *Command, reader io.Reader,
 		_ = ro.ng(cmd, cmd.W("update reference error: %v"), err)
 		return ErrReportStarted
 	}
+	// objects are already published, the local copies are only a cache
+	if err := q.Migrate(); err != nil {
+		logrus.Errorf("migrate quarantined objects error: %v", err)
+	}
 	_ = ro.ok(cmd, newReference.Hash)
+	r.ext.NotifyPush(e)
 	return nil
 }
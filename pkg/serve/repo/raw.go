@@ -0,0 +1,153 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+// ErrBadRawRequest: bad path, or the path is not a file.
+type ErrBadRawRequest struct {
+	message string
+}
+
+func (e *ErrBadRawRequest) Error() string {
+	return e.message
+}
+
+type rawDB interface {
+	historyDB
+	Fragments(ctx context.Context, oid plumbing.Hash) (*object.Fragments, error)
+	Blob(ctx context.Context, oid plumbing.Hash) (*object.Blob, error)
+}
+
+// RawFile: a file of a commit, see Repository.Raw.
+type RawFile struct {
+	Name   string // base name of the file
+	Commit plumbing.Hash
+	Entry  *object.TreeEntry
+	Size   int64
+	db     rawDB
+	ff     *object.Fragments
+}
+
+// IsSymlink: the content of a symlink is its target.
+func (f *RawFile) IsSymlink() bool {
+	return f.Entry.Mode == filemode.Symlink
+}
+
+type multiReadCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiReadCloser) Close() error {
+	for _, c := range m.closers {
+		_ = c.Close()
+	}
+	return nil
+}
+
+// Open returns the content of the file from offset, fragments before offset are not read.
+func (f *RawFile) Open(ctx context.Context, offset int64) (io.ReadCloser, error) {
+	if offset < 0 || offset > f.Size {
+		return nil, fmt.Errorf("offset %d out of range", offset)
+	}
+	if len(f.Entry.Payload) != 0 {
+		return io.NopCloser(bytes.NewReader(f.Entry.Payload[offset:])), nil
+	}
+	if f.ff == nil {
+		return openBlob(ctx, f.db, f.Entry.Hash, offset)
+	}
+	m := &multiReadCloser{}
+	readers := make([]io.Reader, 0, len(f.ff.Entries))
+	for _, e := range f.ff.Entries {
+		if offset >= int64(e.Size) {
+			offset -= int64(e.Size)
+			continue
+		}
+		rc, err := openBlob(ctx, f.db, e.Hash, offset)
+		if err != nil {
+			_ = m.Close()
+			return nil, err
+		}
+		offset = 0
+		readers = append(readers, rc)
+		m.closers = append(m.closers, rc)
+	}
+	m.Reader = io.MultiReader(readers...)
+	return m, nil
+}
+
+type blobReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func openBlob(ctx context.Context, db rawDB, oid plumbing.Hash, offset int64) (io.ReadCloser, error) {
+	b, err := db.Blob(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		// objects are compressed, the skipped bytes are decompressed and dropped
+		if _, err := io.CopyN(io.Discard, b.Contents, offset); err != nil {
+			_ = b.Close()
+			return nil, err
+		}
+	}
+	return &blobReadCloser{Reader: b.Contents, Closer: b}, nil
+}
+
+func raw(ctx context.Context, db rawDB, start *object.Commit, p string) (*RawFile, error) {
+	p, err := CleanHistoryPath(p)
+	if err != nil {
+		return nil, &ErrBadRawRequest{message: err.Error()}
+	}
+	if len(p) == 0 {
+		return nil, &ErrBadRawRequest{message: "path is required"}
+	}
+	entry, err := lookupPath(ctx, db, start.Tree, p)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, plumbing.NewErrRevNotFound("path '%s' does not exist in commit %s", p, start.Hash)
+	}
+	if !entry.Mode.IsFile() {
+		return nil, &ErrBadRawRequest{message: fmt.Sprintf("'%s' is not a file", p)}
+	}
+	f := &RawFile{Name: path.Base(p), Commit: start.Hash, Entry: entry, Size: entry.Size, db: db}
+	if entry.Mode.IsFragments() {
+		if f.ff, err = db.Fragments(ctx, entry.Hash); err != nil {
+			return nil, err
+		}
+		f.Size = int64(f.ff.Size)
+		return f, nil
+	}
+	if len(entry.Payload) != 0 {
+		f.Size = int64(len(entry.Payload))
+	}
+	return f, nil
+}
+
+// Raw returns the file at path p of rev, symlinks are not followed.
+func (r *repository) Raw(ctx context.Context, rev string, p string) (*RawFile, error) {
+	ro, err := r.ParseRev(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+	if ro.Target == nil {
+		return nil, plumbing.NewErrRevNotFound("rev %s target not commit", rev)
+	}
+	return raw(ctx, r.odb, ro.Target, p)
+}
@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+type rawMemoryDB struct {
+	*archiveMemoryDB
+	fragments map[plumbing.Hash]*object.Fragments
+}
+
+func (d *rawMemoryDB) Fragments(ctx context.Context, oid plumbing.Hash) (*object.Fragments, error) {
+	if ff, ok := d.fragments[oid]; ok {
+		return ff, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func readRaw(t *testing.T, f *RawFile, offset int64) string {
+	t.Helper()
+	rc, err := f.Open(context.Background(), offset)
+	if err != nil {
+		t.Fatalf("open %s at %d error: %v", f.Name, offset, err)
+	}
+	defer rc.Close() // nolint
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read %s error: %v", f.Name, err)
+	}
+	return string(b)
+}
+
+func TestRaw(t *testing.T) {
+	ctx := context.Background()
+	d := &memoryDB{commits: make(map[plumbing.Hash]*object.Commit), trees: make(map[plumbing.Hash]*object.Tree), blobs: make(map[plumbing.Hash]string)}
+	db := &rawMemoryDB{archiveMemoryDB: &archiveMemoryDB{memoryDB: d}, fragments: make(map[plumbing.Hash]*object.Fragments)}
+	c := d.commit("add", map[string]string{"src/a.txt": "hello world\n", "big.bin": "", "link": "src/a.txt"}, time.Unix(1700000000, 0))
+
+	root := d.trees[c.Tree]
+	for _, e := range root.Entries {
+		switch e.Name {
+		case "big.bin":
+			// big.bin: two fragments, "0123" + "456789"
+			first, second := hashString("0123"), hashString("456789")
+			d.blobs[first], d.blobs[second] = "0123", "456789"
+			e.Mode = filemode.Regular | filemode.Fragments
+			e.Hash = hashString("big.bin fragments")
+			db.fragments[e.Hash] = &object.Fragments{Size: 10, Entries: []*object.Fragment{{Index: 0, Size: 4, Hash: first}, {Index: 1, Size: 6, Hash: second}}}
+		case "link":
+			e.Mode = filemode.Symlink
+			e.Size = int64(len("src/a.txt"))
+		case "src":
+			d.trees[e.Hash].Entries[0].Size = int64(len("hello world\n"))
+		}
+	}
+
+	f, err := raw(ctx, db, c, "/src/a.txt")
+	if err != nil {
+		t.Fatalf("raw error: %v", err)
+	}
+	if f.Name != "a.txt" || f.Size != 12 || f.IsSymlink() {
+		t.Fatalf("unexpected file %s size %d", f.Name, f.Size)
+	}
+	if s := readRaw(t, f, 0); s != "hello world\n" {
+		t.Fatalf("unexpected content %q", s)
+	}
+	if s := readRaw(t, f, 6); s != "world\n" {
+		t.Fatalf("unexpected content from offset 6 %q", s)
+	}
+
+	if f, err = raw(ctx, db, c, "big.bin"); err != nil {
+		t.Fatalf("raw fragments error: %v", err)
+	}
+	if f.Size != 10 {
+		t.Fatalf("unexpected fragments size %d", f.Size)
+	}
+	for offset, want := range map[int64]string{0: "0123456789", 4: "456789", 6: "6789", 10: ""} {
+		if s := readRaw(t, f, offset); s != want {
+			t.Fatalf("fragments from offset %d: got %q want %q", offset, s, want)
+		}
+	}
+
+	if f, err = raw(ctx, db, c, "link"); err != nil || !f.IsSymlink() || readRaw(t, f, 0) != "src/a.txt" {
+		t.Fatalf("unexpected symlink %v %v", f, err)
+	}
+
+	for _, p := range []string{"", "src", "../a.txt"} {
+		if _, err := raw(ctx, db, c, p); err == nil {
+			t.Fatalf("raw %q: expected error", p)
+		} else if _, ok := errors.AsType[*ErrBadRawRequest](err); !ok {
+			t.Fatalf("raw %q: unexpected error %v", p, err)
+		}
+	}
+	if _, err := raw(ctx, db, c, "src/b.txt"); !plumbing.IsErrRevNotFound(err) {
+		t.Fatalf("raw missing path: unexpected error %v", err)
+	}
+}
@@ -19,12 +19,18 @@ import (
 	"github.com/antgroup/hugescm/modules/zeta/object"
 	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/extension"
 	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
 )
 
 type Repositories interface {
 	Open(ctx context.Context, rid int64, compressionAlgo, defaultBranch string) (Repository, error)
 	New(ctx context.Context, newRepo *database.Repository, u *database.User, empty bool) (*database.Repository, error)
+	SetDefaultBranch(ctx context.Context, repo *database.Repository, u *database.User, branchName string) (string, error)
+	Templates(ctx context.Context, namespaceID int64) (*protocol.Templates, error)
+	Bucket() oss.Bucket
+	ColdBucket() oss.Bucket
 }
 
 var (
@@ -32,45 +38,87 @@ var (
 )
 
 type repositories struct {
-	root   string
-	cdb    odb.CacheDB
-	mdb    database.DB
-	bucket oss.Bucket
+	root       string
+	cdb        odb.CacheDB
+	mdb        database.DB
+	bucket     oss.Bucket
+	cold       oss.Bucket
+	policy     *commitPolicy
+	pathPolicy *pathPolicy
+	ext        *extension.Set
+	blames     *blameCache
 }
 
-func NewRepositories(root string, ossConfig *serve.OSS, cacheConfig *serve.Cache, mdb database.DB) (Repositories, error) {
+func NewRepositories(root string, ossConfig *serve.OSS, tiering *serve.Tiering, cacheConfig *serve.Cache, policyConfig *serve.CommitPolicy, pathPolicyConfig *serve.PathPolicy, mdb database.DB, ext *extension.Set) (Repositories, error) {
+	policy, err := newCommitPolicy(policyConfig)
+	if err != nil {
+		return nil, err
+	}
+	pathPolicy, err := newPathPolicy(pathPolicyConfig)
+	if err != nil {
+		return nil, err
+	}
 	if err := os.MkdirAll(root, 0755); err != nil {
 		return nil, err
 	}
 
-	bucket, err := oss.NewBucket(&oss.NewBucketOptions{
-		Endpoint:        ossConfig.Endpoint,
-		SharedEndpoint:  ossConfig.SharedEndpoint,
-		AccessKeyID:     ossConfig.AccessKeyID,
-		AccessKeySecret: ossConfig.AccessKeySecret,
-		Bucket:          ossConfig.Bucket,
-	})
+	bucket, err := newBucket(ossConfig)
 	if err != nil {
 		return nil, err
 	}
+	var cold oss.Bucket
+	if tiering != nil && tiering.ColdOSS != nil {
+		if cold, err = newBucket(tiering.ColdOSS); err != nil {
+			return nil, err
+		}
+	}
 	cdb, err := odb.NewCacheDB(cacheConfig.NumCounters, cacheConfig.MaxCost, cacheConfig.BufferItems)
 	if err != nil {
 		return nil, err
 	}
-	return &repositories{root: root, cdb: cdb, mdb: mdb, bucket: bucket}, nil
+	blames, err := newBlameCache()
+	if err != nil {
+		return nil, err
+	}
+	return &repositories{root: root, cdb: cdb, mdb: mdb, bucket: bucket, cold: cold, policy: policy, pathPolicy: pathPolicy, ext: ext, blames: blames}, nil
+}
+
+func newBucket(ossConfig *serve.OSS) (oss.Bucket, error) {
+	return oss.NewBucket(&oss.NewBucketOptions{
+		Endpoint:        ossConfig.Endpoint,
+		SharedEndpoint:  ossConfig.SharedEndpoint,
+		AccessKeyID:     ossConfig.AccessKeyID,
+		AccessKeySecret: ossConfig.AccessKeySecret,
+		Bucket:          ossConfig.Bucket,
+	})
+}
+
+// Bucket returns the oss bucket of the repositories.
+func (r *repositories) Bucket() oss.Bucket {
+	return r.bucket
+}
+
+// ColdBucket returns the oss bucket of the cold tier, nil when tiering is disabled.
+func (r *repositories) ColdBucket() oss.Bucket {
+	return r.cold
+}
+
+// RepositoryPath returns the local storage path of repository rid under root.
+func RepositoryPath(root string, rid int64) string {
+	return fmt.Sprintf("%s/%03d/%d.zeta", root, rid%1000, rid)
 }
 
 func (r *repositories) zetaJoin(rid int64) string {
-	return fmt.Sprintf("%s/%03d/%d.zeta", r.root, rid%1000, rid)
+	return RepositoryPath(r.root, rid)
 }
 
 func (r *repositories) Open(ctx context.Context, rid int64, compressionAlgo, defaultBranch string) (Repository, error) {
 	repoPath := r.zetaJoin(rid)
-	o, err := odb.NewODB(rid, repoPath, compressionAlgo, r.cdb, odb.NewMetadataDB(r.mdb.Database(), rid), r.bucket)
+	o, err := odb.NewODB(rid, repoPath, compressionAlgo, r.cdb, odb.NewMetadataDB(r.mdb.Database(), rid), r.bucket, r.cold)
 	if err != nil {
 		return nil, err
 	}
-	return &repository{odb: o, mdb: r.mdb, rid: rid, defaultBranch: defaultBranch}, nil
+	return &repository{odb: o, mdb: r.mdb, rid: rid, defaultBranch: defaultBranch, policy: r.policy, pathPolicy: r.pathPolicy, ext: r.ext, blames: r.blames}, nil
 }
 
 func (r *repositories) New(ctx context.Context, newRepo *database.Repository, u *database.User, empty bool) (*database.Repository, error) {
@@ -86,24 +134,74 @@ func (r *repositories) New(ctx context.Context, newRepo *database.Repository, u
 	}); err != nil {
 		return nil, err
 	}
-	if empty {
-		return repo, nil
+	if !empty {
+		rr, err := r.Open(ctx, repo.ID, repo.CompressionAlgo, repo.DefaultBranch)
+		if err != nil {
+			return nil, err
+		}
+		defer rr.Close() // nolint
+		if err := rr.Initialize(ctx, u, repo.DefaultBranch); err != nil {
+			return nil, err
+		}
 	}
-	rr, err := r.Open(ctx, repo.ID, repo.CompressionAlgo, repo.DefaultBranch)
+	r.ext.NotifyRepository(&extension.RepositoryEvent{
+		Type:          extension.RepositoryCreated,
+		RID:           repo.ID,
+		NamespaceID:   repo.NamespaceID,
+		Path:          repo.Path,
+		UID:           u.ID,
+		DefaultBranch: repo.DefaultBranch,
+	})
+	return repo, nil
+}
+
+// Templates returns the organization-wide templates which apply to the repositories of the namespace.
+func (r *repositories) Templates(ctx context.Context, namespaceID int64) (*protocol.Templates, error) {
+	items, err := r.mdb.Templates(ctx, namespaceID)
 	if err != nil {
 		return nil, err
 	}
-	defer rr.Close() // nolint
-	if err := rr.Initialize(ctx, u, repo.DefaultBranch); err != nil {
-		return nil, err
+	templates := make([]*protocol.Template, 0, len(items))
+	for _, t := range items {
+		scope := protocol.TEMPLATE_SCOPE_GLOBAL
+		if t.NamespaceID != 0 {
+			scope = t.Namespace
+		}
+		templates = append(templates, &protocol.Template{Kind: t.Kind, Scope: scope, Version: t.Version, Content: t.Content})
 	}
-	return repo, nil
+	return protocol.NewTemplates(templates), nil
+}
+
+// SetDefaultBranch changes the default branch (HEAD) of the repository and returns the old one, observers are
+// notified only when the default branch is changed.
+func (r *repositories) SetDefaultBranch(ctx context.Context, repo *database.Repository, u *database.User, branchName string) (string, error) {
+	oldBranch, err := r.mdb.SetDefaultBranch(ctx, repo.ID, u.ID, branchName)
+	if err != nil {
+		return "", err
+	}
+	if oldBranch != branchName {
+		r.ext.NotifyRepository(&extension.RepositoryEvent{
+			Type:             extension.RepositoryDefaultBranchChanged,
+			RID:              repo.ID,
+			NamespaceID:      repo.NamespaceID,
+			Path:             repo.Path,
+			UID:              u.ID,
+			DefaultBranch:    branchName,
+			OldDefaultBranch: oldBranch,
+		})
+	}
+	return oldBranch, nil
 }
 
 type Repository interface {
 	Initialize(ctx context.Context, u *database.User, initBranch string) error
 	LsTag(ctx context.Context, tagName string) (string, string, error)
+	LsReferences(ctx context.Context) ([]*protocol.ReferenceItem, error)
 	ParseRev(ctx context.Context, rev string) (*RevObjects, error)
+	History(ctx context.Context, rev string, opts *HistoryOptions) (*protocol.HistoryResponse, error)
+	Blame(ctx context.Context, rev string, p string) (*protocol.BlameResponse, error)
+	Archive(ctx context.Context, cc *object.Commit, opts *ArchiveOptions, w io.Writer) error
+	Raw(ctx context.Context, rev string, p string) (*RawFile, error)
 	DoPush(ctx context.Context, cmd *Command, reader io.Reader, w io.Writer) error
 	ODB() odb.DB
 	Close() error
@@ -114,6 +212,10 @@ type repository struct {
 	odb           *odb.ODB
 	rid           int64
 	defaultBranch string
+	policy        *commitPolicy
+	pathPolicy    *pathPolicy
+	ext           *extension.Set
+	blames        *blameCache
 }
 
 func (r *repository) Close() error {
@@ -135,6 +237,25 @@ func (r *repository) LsTag(ctx context.Context, tagName string) (string, string,
 	return tag.Hash, "", nil
 }
 
+// LsReferences: list all references of the repository, annotated tags are peeled.
+func (r *repository) LsReferences(ctx context.Context) ([]*protocol.ReferenceItem, error) {
+	refs, err := r.mdb.ListReferences(ctx, r.rid)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*protocol.ReferenceItem, 0, len(refs))
+	for _, ref := range refs {
+		item := &protocol.ReferenceItem{Name: string(ref.Name), Hash: ref.Hash}
+		if ref.Name.IsTag() {
+			if to, err := r.odb.Tag(ctx, plumbing.NewHash(ref.Hash)); err == nil {
+				item.Peeled = to.Object.String()
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
 func (r *repository) ODB() odb.DB {
 	return r.odb
 }
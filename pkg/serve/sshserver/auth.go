@@ -6,25 +6,35 @@ package sshserver
 import (
 	"database/sql"
 	"errors"
+	"slices"
+	"strings"
 
 	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/extension"
 	"github.com/antgroup/hugescm/pkg/serve/protocol"
 )
 
+// deployKeyAllowed: commands allowed for the deploy key in the repository, empty allows only the download commands.
+func deployKeyAllowed(commands []string, command string) bool {
+	if len(commands) == 0 {
+		return protocol.SSHCommands[command] == protocol.DOWNLOAD
+	}
+	return slices.Contains(commands, command)
+}
+
 func (s *Server) checkAccessForDeployKey(e *Session, repoPath string, operation protocol.Operation) int {
-	switch operation {
-	case protocol.DOWNLOAD:
-		ok, err := s.db.IsDeployKeyEnabled(e.Context(), e.RID, e.KID)
-		if err != nil {
-			e.WriteError("find repo '%s' error: %v", repoPath, err)
-			return 500
-		}
-		if !ok {
-			e.WriteError("Deploy Key not enabled for '%s'", repoPath)
-			return 403
-		}
-	default:
+	dk, err := s.db.FindDeployKeyRepository(e.Context(), e.RID, e.KID)
+	if errors.Is(err, sql.ErrNoRows) {
+		e.WriteError("Deploy Key not enabled for '%s'", repoPath)
+		return 403
+	}
+	if err != nil {
+		e.WriteError("find repo '%s' error: %v", repoPath, err)
+		return 500
+	}
+	if !deployKeyAllowed(dk.Commands, e.SubCommand) || protocol.SSHCommands[e.SubCommand] != operation {
 		e.WriteError("Deploy Key no %s access", operation)
 		return 403
 	}
@@ -54,6 +64,7 @@ func (s *Server) doPermissionCheck(e *Session, repoPath string, operation protoc
 		e.WriteError("find repo '%s' error: %v", repoPath, err)
 		return 500
 	}
+	e.NamespaceID = ns.ID
 	e.NamespacePath = ns.Path
 	e.RepoPath = repo.Path
 	e.RID = repo.ID
@@ -77,6 +88,10 @@ func (s *Server) doPermissionCheck(e *Session, repoPath string, operation protoc
 		return 403
 	}
 	e.IsAdministrator = u.Administrator
+	// the language stored in the user's settings wins over LANG
+	if lang, ok := serve.SupportedLanguage(u.Language); ok {
+		e.language = lang
+	}
 	if u.Administrator {
 		return 0
 	}
@@ -85,20 +100,36 @@ func (s *Server) doPermissionCheck(e *Session, repoPath string, operation protoc
 		e.WriteError("check user's access for repository error: %v", err)
 		return 500
 	}
+	var allowed bool
 	switch operation {
 	case protocol.DOWNLOAD:
-		if !checkRepoReadable(u, repo, accessLevel) {
-			e.WriteError("[DOWNLOAD] access denied, current user: %s", u.UserName)
-			return 403
-		}
+		allowed = checkRepoReadable(u, repo, accessLevel)
 	case protocol.UPLOAD:
-		if !accessLevel.Writeable() {
-			e.WriteError("[UPLOAD] access denied, current user: %s", u.UserName)
-			return 403
-		}
+		allowed = accessLevel.Writeable()
+	case protocol.SUDO:
+		allowed = accessLevel.Sudo()
 	default:
 		e.WriteError("bad operation: %s", operation)
 		return 400
 	}
+	if !s.ext.Authorize(e.Context(), &extension.AccessRequest{
+		RID:         repo.ID,
+		UID:         u.ID,
+		UserName:    u.UserName,
+		Repository:  ns.Path + "/" + repo.Path,
+		Operation:   operation,
+		AccessLevel: int(accessLevel),
+		Allowed:     allowed,
+	}) {
+		e.WriteError("[%s] access denied, current user: %s", strings.ToUpper(string(operation)), u.UserName)
+		return 403
+	}
+	if accessLevel.Sudo() {
+		return 0
+	}
+	if e.Paths, err = s.db.PathPermissions(e.Context(), repo.ID, u.ID); err != nil {
+		e.WriteError("check user's path permissions error: %v", err)
+		return 500
+	}
 	return 0
 }
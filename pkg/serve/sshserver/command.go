@@ -40,6 +40,12 @@ var (
 		"push": func() Command {
 			return &Push{}
 		},
+		"default-branch": func() Command {
+			return &DefaultBranch{}
+		},
+		"templates": func() Command {
+			return &Templates{}
+		},
 	}
 )
 
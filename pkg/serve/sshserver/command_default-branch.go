@@ -0,0 +1,64 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sshserver
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+)
+
+// zeta-serve default-branch "group/mono-zeta" --set "${BRANCH}"
+type DefaultBranch struct {
+	Path   string
+	Branch string
+}
+
+func (c *DefaultBranch) ParseArgs(args []string) error {
+	var p ParseArgs
+	p.Add("set", REQUIRED, 'S')
+	if err := p.Parse(args, func(index rune, nextArg, raw string) error {
+		switch index {
+		case 'S':
+			c.Branch = strings.TrimPrefix(nextArg, protocol.BRANCH_PREFIX)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	var ok bool
+	if c.Path, ok = p.Unresolved(0); !ok {
+		return ErrPathNecessary
+	}
+	if len(c.Branch) == 0 {
+		return errors.New("--set is necessary")
+	}
+	return nil
+}
+
+func (c *DefaultBranch) Exec(ctx *RunCtx) int {
+	return ctx.S.SetDefaultBranch(ctx.Session, c.Path, c.Branch)
+}
+
+func (s *Server) SetDefaultBranch(e *Session, repoPath, branchName string) int {
+	if exitCode := s.doPermissionCheck(e, repoPath, protocol.SUDO); exitCode != 0 {
+		return exitCode
+	}
+	if !plumbing.ValidateBranchName([]byte(branchName)) {
+		return e.ExitFormat(400, e.W("bad branch name '%s'"), branchName)
+	}
+	repo := &database.Repository{ID: e.RID, NamespaceID: e.NamespaceID, Path: e.RepoPath}
+	oldBranch, err := s.hub.SetDefaultBranch(e.Context(), repo, &database.User{ID: e.UID}, branchName)
+	if err != nil {
+		if database.IsErrRevisionNotFound(err) {
+			return e.ExitFormat(404, e.W("branch '%s' not exist"), branchName)
+		}
+		return e.ExitError(err)
+	}
+	ZetaEncodeVND(e, &protocol.DefaultBranchResponse{DefaultBranch: branchName, OldDefaultBranch: oldBranch})
+	return 0
+}
@@ -12,18 +12,23 @@ import (
 )
 
 // zeta-serve ls-remote "group/mono-zeta" --reference "${REFNAME}"
+// zeta-serve ls-remote "group/mono-zeta" --all
 type LsRemote struct {
 	Path      string
 	Reference string
+	All       bool
 }
 
 func (c *LsRemote) ParseArgs(args []string) error {
 	var p ParseArgs
-	p.Add("reference", REQUIRED, 'R')
+	p.Add("reference", REQUIRED, 'R').
+		Add("all", NOARG, 'A')
 	if err := p.Parse(args, func(index rune, nextArg, raw string) error {
 		switch index {
 		case 'R':
 			c.Reference = nextArg
+		case 'A':
+			c.All = true
 		}
 		return nil
 	}); err != nil {
@@ -37,6 +42,9 @@ func (c *LsRemote) ParseArgs(args []string) error {
 }
 
 func (c *LsRemote) Exec(ctx *RunCtx) int {
+	if c.All {
+		return ctx.S.LsReferences(ctx.Session, c.Path)
+	}
 	return ctx.S.LsRemote(ctx.Session, c.Path, c.Reference)
 }
 
@@ -78,6 +86,7 @@ func (s *Server) LsBranchReference(e *Session, branchName string) int {
 		Agent:           s.serverName,
 		HashAlgo:        e.HashAlgo,
 		CompressionAlgo: e.CompressionAlgo,
+		Capabilities:    protocol.Capabilities,
 	}
 	ZetaEncodeVND(e, branch)
 	return 0
@@ -103,6 +112,7 @@ func (s *Server) LsTagReference(e *Session, tagName string) int {
 		Agent:           s.serverName,
 		HashAlgo:        e.HashAlgo,
 		CompressionAlgo: e.CompressionAlgo,
+		Capabilities:    protocol.Capabilities,
 	}
 	ZetaEncodeVND(e, branch)
 	return 0
@@ -127,7 +137,35 @@ func (s *Server) LsOrdinaryReference(e *Session, refname plumbing.ReferenceName)
 		Agent:           s.serverName,
 		HashAlgo:        e.HashAlgo,
 		CompressionAlgo: e.CompressionAlgo,
+		Capabilities:    protocol.Capabilities,
 	}
 	ZetaEncodeVND(e, branch)
 	return 0
 }
+
+func (s *Server) LsReferences(e *Session, repoPath string) int {
+	if exitCode := s.doPermissionCheck(e, repoPath, protocol.DOWNLOAD); exitCode != 0 {
+		return exitCode
+	}
+	rr, err := s.open(e)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	defer rr.Close() // nolint
+	items, err := rr.LsReferences(e.Context())
+	if err != nil {
+		return e.ExitError(err)
+	}
+	refs := &protocol.References{
+		Remote:          e.makeRemoteURL(s.Endpoint),
+		HEAD:            protocol.BRANCH_PREFIX + e.DefaultBranch,
+		Version:         int(protocol.PROTOCOL_VERSION),
+		Agent:           s.serverName,
+		HashAlgo:        e.HashAlgo,
+		CompressionAlgo: e.CompressionAlgo,
+		Capabilities:    protocol.Capabilities,
+		References:      items,
+	}
+	ZetaEncodeVND(e, refs)
+	return 0
+}
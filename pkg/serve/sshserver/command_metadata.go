@@ -9,6 +9,7 @@ import (
 
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/protocol"
 	"github.com/sirupsen/logrus"
 )
@@ -56,6 +57,9 @@ func (c *Metadata) ParseArgs(args []string) error {
 			if err != nil {
 				return fmt.Errorf("parse depth '%s' error: %w", nextArg, err)
 			}
+			if i < 0 {
+				return fmt.Errorf("bad depth value '%s'", nextArg)
+			}
 			c.Depth = i
 		case 'H':
 			if !plumbing.ValidateHashHex(nextArg) {
@@ -67,7 +71,6 @@ func (c *Metadata) ParseArgs(args []string) error {
 				return fmt.Errorf("deepen-from is invalid hash: %s", nextArg)
 			}
 			c.DeepenFrom = plumbing.NewHash(nextArg)
-			c.Deepen = -1
 		case 'D':
 			i, err := strconv.Atoi(nextArg)
 			if err != nil {
@@ -85,6 +88,10 @@ func (c *Metadata) ParseArgs(args []string) error {
 	}); err != nil {
 		return err
 	}
+	// same as HTTP: if deepen-from is set, ignore deepen, clients send both
+	if !c.DeepenFrom.IsZero() {
+		c.Deepen = -1
+	}
 	var ok bool
 	if c.Path, ok = p.Unresolved(0); !ok {
 		return ErrPathNecessary
@@ -105,6 +112,19 @@ func (c *Metadata) Exec(ctx *RunCtx) int {
 	return ctx.S.FetchMetadata(ctx.Session, c)
 }
 
+// metadataLimit: max trees and fragments per response, the rest is returned as cursor, 0 means no limit.
+func metadataLimit(e *Session) int {
+	if len(e.Paths) != 0 {
+		// restricted users receive the allowed dirs only, cursors are based on the full tree
+		return 0
+	}
+	limit, err := strconv.Atoi(e.Getenv("ZETA_METADATA_LIMIT"))
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
 func (s *Server) FetchMetadata(e *Session, c *Metadata) int {
 	rr, err := s.open(e)
 	if err != nil {
@@ -118,7 +138,7 @@ func (s *Server) FetchMetadata(e *Session, c *Metadata) int {
 	if ro.Target == nil {
 		return e.ExitFormat(400, "revision %s target not commit", c.Revision)
 	}
-	p, err := protocol.NewPipePacker(rr.ODB(), e, c.Depth, c.UseZSTD)
+	p, err := protocol.NewPipePacker(rr.ODB(), e, c.Depth, c.UseZSTD, metadataLimit(e))
 	if err != nil {
 		logrus.Errorf("new packer error %v", err)
 		return e.ExitError(err)
@@ -130,7 +150,12 @@ func (s *Server) FetchMetadata(e *Session, c *Metadata) int {
 			return e.ExitError(err)
 		}
 	}
-	if err := p.WriteDeepenMetadata(e.Context(), ro.Target, c.DeepenFrom, c.Have, c.Deepen); err != nil {
+	if len(e.Paths) != 0 {
+		err = p.WriteDeepenSparseMetadata(e.Context(), ro.Target, c.DeepenFrom, c.Have, c.Deepen, e.Paths)
+	} else {
+		err = p.WriteDeepenMetadata(e.Context(), ro.Target, c.DeepenFrom, c.Have, c.Deepen)
+	}
+	if err != nil {
 		logrus.Errorf("write commits error %v", err)
 		return e.ExitError(err)
 	}
@@ -142,9 +167,14 @@ func (s *Server) FetchMetadata(e *Session, c *Metadata) int {
 }
 
 func (s *Server) GetSparseMetadata(e *Session, c *Metadata) int {
-	paths, err := protocol.ReadInputPaths(e)
+	paths, err := protocol.ReadInputPaths(serve.NewLimitedReader(e, s.BodyLimits.SparseMetadata.Size))
 	if err != nil {
-		return e.ExitFormat(400, "bad input paths: %v", err)
+		return e.ExitRequestError(err, "bad input paths: %v")
+	}
+	if len(e.Paths) != 0 {
+		if paths = protocol.RestrictPaths(e.Paths, paths); len(paths) == 0 {
+			return e.ExitFormat(403, "%s", e.W("no access to the requested paths"))
+		}
 	}
 
 	rr, err := s.open(e)
@@ -161,7 +191,7 @@ func (s *Server) GetSparseMetadata(e *Session, c *Metadata) int {
 		return e.ExitFormat(400, "revision %s target not commit", c.Revision)
 	}
 	cc := ro.Target
-	p, err := protocol.NewPipePacker(rr.ODB(), e, c.Depth, c.UseZSTD)
+	p, err := protocol.NewPipePacker(rr.ODB(), e, c.Depth, c.UseZSTD, metadataLimit(e))
 	if err != nil {
 		logrus.Errorf("new packer error %v", err)
 		return e.ExitError(err)
@@ -185,10 +215,13 @@ func (s *Server) GetSparseMetadata(e *Session, c *Metadata) int {
 }
 
 func (s *Server) BatchMetadata(e *Session, depth int, useZSTD bool) int {
-	oids, err := protocol.ReadInputOIDs(e)
+	if len(e.Paths) != 0 {
+		// trees requested by oid cannot be checked against the allowed dirs
+		return e.ExitFormat(403, "%s", e.W("batch metadata is not available to users restricted to some dirs"))
+	}
+	oids, err := protocol.ReadInputOIDs(serve.NewLimitedReader(e, s.BodyLimits.BatchMetadata.Size))
 	if err != nil {
-		e.WriteError("batch-metadata: %v", err)
-		return 400
+		return e.ExitRequestError(err, "batch-metadata: %v")
 	}
 	rr, err := s.open(e)
 	if err != nil {
@@ -204,7 +237,7 @@ func (s *Server) BatchMetadata(e *Session, depth int, useZSTD bool) int {
 		}
 		objects = append(objects, a)
 	}
-	p, err := protocol.NewPipePacker(rr.ODB(), e, depth, useZSTD)
+	p, err := protocol.NewPipePacker(rr.ODB(), e, depth, useZSTD, metadataLimit(e))
 	if err != nil {
 		logrus.Errorf("new packer error %v", err)
 		return e.ExitError(err)
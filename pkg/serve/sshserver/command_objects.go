@@ -4,7 +4,6 @@
 package sshserver
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -13,6 +12,7 @@ import (
 	"github.com/antgroup/hugescm/modules/crc"
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/streamio"
+	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/protocol"
 	"github.com/sirupsen/logrus"
 )
@@ -23,12 +23,15 @@ import (
 
 // zeta-serve objects "group/mono-zeta" --share
 
+// zeta-serve objects "group/mono-zeta" --exists
+
 type Objects struct {
 	Path   string
 	OID    plumbing.Hash
 	Offset int64
 	Batch  bool
 	Share  bool
+	Exists bool
 }
 
 func (c *Objects) ParseArgs(args []string) error {
@@ -36,7 +39,8 @@ func (c *Objects) ParseArgs(args []string) error {
 	p.Add("oid", REQUIRED, 'O').
 		Add("offset", REQUIRED, 'o').
 		Add("share", NOARG, 'S').
-		Add("batch", NOARG, 'B')
+		Add("batch", NOARG, 'B').
+		Add("exists", NOARG, 'E')
 	if err := p.Parse(args, func(index rune, nextArg, raw string) error {
 		switch index {
 		case 'O':
@@ -57,6 +61,8 @@ func (c *Objects) ParseArgs(args []string) error {
 			c.Batch = true
 		case 'S':
 			c.Share = true
+		case 'E':
+			c.Exists = true
 		case 'L':
 
 		}
@@ -81,6 +87,9 @@ func (c *Objects) Exec(ctx *RunCtx) int {
 	if c.Share {
 		return ctx.S.ShareObjects(ctx.Session)
 	}
+	if c.Exists {
+		return ctx.S.ObjectsExists(ctx.Session)
+	}
 	if c.OID.IsZero() {
 		ctx.Session.WriteError("bad oid")
 		return 400
@@ -89,9 +98,9 @@ func (c *Objects) Exec(ctx *RunCtx) int {
 }
 
 func (s *Server) BatchObjects(e *Session) int {
-	oids, err := protocol.ReadInputOIDs(e)
+	oids, err := protocol.ReadInputOIDs(serve.NewLimitedReader(e, s.BodyLimits.BatchObjects.Size))
 	if err != nil {
-		return e.ExitFormat(400, "batch-objects: %v", err)
+		return e.ExitRequestError(err, "batch-objects: %v")
 	}
 	rr, err := s.open(e)
 	if err != nil {
@@ -104,7 +113,13 @@ func (s *Server) BatchObjects(e *Session) int {
 		streamio.PutBufferWriter(buffedWriter)
 	}()
 	cw := crc.NewCrc64Writer(buffedWriter)
-	if err := protocol.WriteBatchObjectsHeader(cw); err != nil {
+	var flags byte
+	writeItem := protocol.WriteObjectsItem
+	if e.Getenv("ZETA_BATCH_INTEGRITY") == protocol.BATCH_INTEGRITY_CRC64 {
+		flags |= protocol.BATCH_FLAG_ITEM_CRC64
+		writeItem = protocol.WriteObjectsItemWithChecksum
+	}
+	if err := protocol.WriteBatchObjectsHeader(cw, flags); err != nil {
 		logrus.Errorf("write blob header error: %v", err)
 		return e.ExitError(err)
 	}
@@ -122,7 +137,7 @@ func (s *Server) BatchObjects(e *Session) int {
 			return nil
 		}
 		defer sr.Close() // nolint
-		return protocol.WriteObjectsItem(cw, sr, oid.String(), sr.Size())
+		return writeItem(cw, sr, oid.String(), sr.Size())
 	}
 	for _, oid := range oids {
 		if err := writeFunc(oid); err != nil {
@@ -137,6 +152,24 @@ func (s *Server) BatchObjects(e *Session) int {
 	return 0
 }
 
+func (s *Server) ObjectsExists(e *Session) int {
+	oids, err := protocol.ReadExistsOIDs(serve.NewLimitedReader(e, s.BodyLimits.BatchObjects.Size))
+	if err != nil {
+		return e.ExitRequestError(err, "exists-oids: %v")
+	}
+	rr, err := s.open(e)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	defer rr.Close() // nolint
+	exists, err := rr.ODB().Exists(e.Context(), oids)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	ZetaEncodeVND(e, protocol.NewObjectsExistsResponse(exists))
+	return 0
+}
+
 func (s *Server) GetObject(e *Session, oid plumbing.Hash, offset int64) int {
 	rr, err := s.open(e)
 	if err != nil {
@@ -144,7 +177,8 @@ func (s *Server) GetObject(e *Session, oid plumbing.Hash, offset int64) int {
 	}
 	defer rr.Close() // nolint
 	o := rr.ODB()
-	sr, err := o.Open(e.Context(), oid, 0)
+	// resume from offset, same as HTTP 'Range: bytes=N-'
+	sr, err := o.Open(e.Context(), oid, offset)
 	if err != nil {
 		return e.ExitError(err)
 	}
@@ -160,9 +194,9 @@ func (s *Server) GetObject(e *Session, oid plumbing.Hash, offset int64) int {
 }
 
 func (s *Server) ShareObjects(e *Session) int {
-	var request protocol.BatchShareObjectsRequest
-	if err := json.NewDecoder(e).Decode(&request); err != nil {
-		return e.ExitFormat(400, "decode request body error: %v", err)
+	request, err := protocol.DecodeBatchShareObjectsRequest(serve.NewLimitedReader(e, s.BodyLimits.ShareObjects.Size))
+	if err != nil {
+		return e.ExitRequestError(err, "decode request body error: %v")
 	}
 	rr, err := s.open(e)
 	if err != nil {
@@ -170,12 +204,15 @@ func (s *Server) ShareObjects(e *Session) int {
 	}
 	defer rr.Close() // nolint
 
+	now := time.Now()
 	response := &protocol.BatchShareObjectsResponse{
-		Objects: make([]*protocol.Representation, 0, len(request.Objects)),
+		Objects:    make([]*protocol.Representation, 0, len(request.Objects)),
+		ServerTime: now,
 	}
 	odb := rr.ODB()
-	ExpiresAt := time.Now().Add(time.Hour * 2)
-	expiresAt := ExpiresAt.Unix()
+	ExpiresAt := now.Add(time.Hour * 2)
+	// links stay valid a little longer than reported, tolerating clients whose clock is behind
+	expiresAt := ExpiresAt.Add(s.ClockSkew.Duration).Unix()
 	for _, o := range request.Objects {
 		if o == nil {
 			return e.ExitFormat(400, "require object is nil")
@@ -4,7 +4,6 @@
 package sshserver
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -14,7 +13,9 @@ import (
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/modules/zeta"
+	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/notify"
 	"github.com/antgroup/hugescm/pkg/serve/protocol"
 	"github.com/antgroup/hugescm/pkg/serve/repo"
 	"github.com/sirupsen/logrus"
@@ -24,7 +25,9 @@ import (
 
 // zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}"
 
-// zeta-serve push "group/mono-zeta" --reference "$REFNAME" --old-rev "$OLD_REV" --new-rev "$NEW_REV"
+// zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}" --delta-base "$BASE"
+
+// zeta-serve push "group/mono-zeta" --reference "$REFNAME" --old-rev "$OLD_REV" --new-rev "$NEW_REV" [--dry-run]
 
 type Push struct {
 	Path       string
@@ -33,7 +36,9 @@ type Push struct {
 	Size       int64
 	OldRev     plumbing.Hash
 	NewRev     plumbing.Hash
+	DeltaBase  plumbing.Hash
 	BatchCheck bool
+	DryRun     bool
 }
 
 func (c *Push) ParseArgs(args []string) error {
@@ -43,7 +48,9 @@ func (c *Push) ParseArgs(args []string) error {
 		Add("batch-check", NOARG, 'B').
 		Add("size", REQUIRED, 'S').
 		Add("old-rev", REQUIRED, 'o').
-		Add("new-rev", REQUIRED, 'n')
+		Add("new-rev", REQUIRED, 'n').
+		Add("delta-base", REQUIRED, 'D').
+		Add("dry-run", NOARG, 'N')
 	if err := p.Parse(args, func(index rune, nextArg, raw string) error {
 		switch index {
 		case 'R':
@@ -74,6 +81,13 @@ func (c *Push) ParseArgs(args []string) error {
 				return fmt.Errorf("old-rev is invalid hash: %s", nextArg)
 			}
 			c.OldRev = plumbing.NewHash(nextArg)
+		case 'D':
+			if !plumbing.ValidateHashHex(nextArg) {
+				return fmt.Errorf("delta-base is invalid hash: %s", nextArg)
+			}
+			c.DeltaBase = plumbing.NewHash(nextArg)
+		case 'N':
+			c.DryRun = true
 		}
 		return nil
 	}); err != nil {
@@ -94,15 +108,18 @@ func (c *Push) Exec(ctx *RunCtx) int {
 		return ctx.S.BatchCheck(ctx.Session, c.Reference)
 	}
 	if c.OID.IsZero() {
-		return ctx.S.Push(ctx.Session, c.Reference, c.OldRev, c.NewRev)
+		return ctx.S.Push(ctx.Session, c.Reference, c.OldRev, c.NewRev, c.DryRun)
+	}
+	if !c.DeltaBase.IsZero() {
+		return ctx.S.PutDelta(ctx.Session, c.Reference, c.OID, c.DeltaBase, c.Size)
 	}
 	return ctx.S.PutObject(ctx.Session, c.Reference, c.OID, c.Size)
 }
 
 func (s *Server) BatchCheck(e *Session, refname string) int {
-	var request protocol.BatchCheckRequest
-	if err := json.NewDecoder(e).Decode(&request); err != nil {
-		return e.ExitFormat(400, "decode request body error: %v", err)
+	request, err := protocol.DecodeBatchCheckRequest(serve.NewLimitedReader(e, s.BodyLimits.BatchCheck.Size))
+	if err != nil {
+		return e.ExitRequestError(err, "decode request body error: %v")
 	}
 	if exitCode := s.updateReferenceDryRun(e, refname); exitCode != 0 {
 		return exitCode
@@ -162,9 +179,28 @@ func (s *Server) PutObject(e *Session, refname string, oid plumbing.Hash, compre
 	return 0
 }
 
-func (s *Server) Push(e *Session, referenceName string, oldRev, newRev plumbing.Hash) int {
+func (s *Server) PutDelta(e *Session, refname string, oid, base plumbing.Hash, deltaSize int64) int {
+	if exitCode := s.updateReferenceDryRun(e, refname); exitCode != 0 {
+		return exitCode
+	}
+	rr, err := s.open(e)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	defer rr.Close() // nolint
+
+	size, err := rr.ODB().WriteDelta(e.Context(), oid, base, e, deltaSize)
+	if err != nil {
+		return e.ExitFormat(409, "upload object '%s' error: %v", oid, err)
+	}
+	logrus.Infof("%s upload large object %s [size: %s, delta: %s] to %s [refname: %s] success", e.UserName, oid, strengthen.FormatSize(size), strengthen.FormatSize(deltaSize), e.makeRemoteURL(s.Endpoint), refname)
+	ZetaEncodeVND(e, &protocol.ErrorCode{Code: 200, Message: "OK"})
+	return 0
+}
+
+func (s *Server) Push(e *Session, referenceName string, oldRev, newRev plumbing.Hash, dryRun bool) int {
 	if referenceName == protocol.HEAD {
-		return s.BranchPush(e, e.DefaultBranch, oldRev, newRev)
+		return s.BranchPush(e, e.DefaultBranch, oldRev, newRev, dryRun)
 	}
 	if !plumbing.ValidateReferenceName([]byte(referenceName)) {
 		return e.ExitFormat(400, e.W("'%s' is not a valid branch name"), referenceName)
@@ -172,16 +208,16 @@ func (s *Server) Push(e *Session, referenceName string, oldRev, newRev plumbing.
 	refname := plumbing.ReferenceName(referenceName)
 	switch {
 	case refname.IsBranch():
-		return s.BranchPush(e, refname.BranchName(), oldRev, newRev)
+		return s.BranchPush(e, refname.BranchName(), oldRev, newRev, dryRun)
 	case refname.IsTag():
-		return s.TagPush(e, refname.TagName(), oldRev, newRev)
+		return s.TagPush(e, refname.TagName(), oldRev, newRev, dryRun)
 	case !strings.HasPrefix(referenceName, plumbing.ReferencePrefix):
-		return s.BranchPush(e, referenceName, oldRev, newRev)
+		return s.BranchPush(e, referenceName, oldRev, newRev, dryRun)
 	}
 	return e.ExitFormat(501, e.W("reference name '%s' is reserved"), referenceName)
 }
 
-func (s *Server) TagPush(e *Session, tagName string, oldRev, newRev plumbing.Hash) int {
+func (s *Server) TagPush(e *Session, tagName string, oldRev, newRev plumbing.Hash, dryRun bool) int {
 	tag, err := s.db.FindTag(e.Context(), e.RID, tagName)
 	if err != nil && !database.IsErrRevisionNotFound(err) {
 		return e.ExitFormat(500, e.W("internal server error: %v"), err)
@@ -194,6 +230,8 @@ func (s *Server) TagPush(e *Session, tagName string, oldRev, newRev plumbing.Has
 		NewRev:        newRev.String(),
 		Terminal:      e.Getenv("TERM"),
 		Language:      e.language,
+		Paths:         e.Paths,
+		DryRun:        dryRun,
 	}
 	if tag != nil && tag.Hash != command.OldRev {
 		return e.ExitFormat(409, "%s", e.W("tag is updated, please update and try again")) //nolint:govet
@@ -213,8 +251,8 @@ func (s *Server) TagPush(e *Session, tagName string, oldRev, newRev plumbing.Has
 	return 0
 }
 
-func (s *Server) BranchPush(e *Session, branchName string, oldRev, newRev plumbing.Hash) int {
-	oldBranch, exitCode := s.checkBranchCanUpdate(e, branchName)
+func (s *Server) BranchPush(e *Session, branchName string, oldRev, newRev plumbing.Hash, dryRun bool) int {
+	oldBranch, exitCode := s.checkBranchCanUpdate(e, branchName, dryRun)
 	if exitCode != 0 {
 		return exitCode
 	}
@@ -226,6 +264,9 @@ func (s *Server) BranchPush(e *Session, branchName string, oldRev, newRev plumbi
 		NewRev:        newRev.String(),
 		Terminal:      e.Getenv("TERM"),
 		Language:      e.language,
+		Paths:         e.Paths,
+		Protected:     oldBranch != nil && oldBranch.ProtectionLevel == ProtectedBranch,
+		DryRun:        dryRun,
 	}
 	if oldBranch != nil && oldBranch.Hash != command.OldRev {
 		return e.ExitFormat(409, "%s", e.W("branch is updated, please update and try again")) //nolint:govet
@@ -237,7 +278,15 @@ func (s *Server) BranchPush(e *Session, branchName string, oldRev, newRev plumbi
 	}
 	defer rr.Close() // nolint
 	if err = rr.DoPush(e.Context(), command, e, e); err != nil {
-		if es, ok := errors.AsType[*zeta.ErrStatusCode](err); ok {
+		es, ok := errors.AsType[*zeta.ErrStatusCode](err)
+		if command.Protected {
+			code := 403
+			if ok {
+				code = es.Code
+			}
+			s.notifyPushFailure(e, command, code, command.Reason(err))
+		}
+		if ok {
 			return e.ExitFormat(es.Code, "reason: %v", err)
 		}
 		return e.ExitError(err)
@@ -245,6 +294,24 @@ func (s *Server) BranchPush(e *Session, branchName string, oldRev, newRev plumbi
 	return 0
 }
 
+// notifyPushFailure notifies the user of a rejected push to a protected branch, see the user's settings. Dry-run
+// pushes are not notified.
+func (s *Server) notifyPushFailure(e *Session, command *repo.Command, code int, message string) {
+	if command.DryRun {
+		return
+	}
+	notify.PushFailed(s.db, &notify.PushFailure{
+		UID:           e.UID,
+		UserName:      e.UserName,
+		Repository:    e.NamespacePath + "/" + e.RepoPath,
+		ReferenceName: string(command.ReferenceName),
+		OldRev:        command.OldRev,
+		NewRev:        command.NewRev,
+		Status:        code,
+		Message:       message,
+	})
+}
+
 const (
 	GeneralBranch      = 0
 	ProtectedBranch    = 10
@@ -252,7 +319,7 @@ const (
 	ConfidentialBranch = 30
 )
 
-func (s *Server) checkBranchCanUpdate(e *Session, branchName string) (*database.Branch, int) {
+func (s *Server) checkBranchCanUpdate(e *Session, branchName string, dryRun bool) (*database.Branch, int) {
 	if !plumbing.ValidateBranchName([]byte(branchName)) {
 		return nil, e.ExitFormat(400, e.W("'%s' is not a valid branch name"), branchName)
 	}
@@ -270,7 +337,9 @@ func (s *Server) checkBranchCanUpdate(e *Session, branchName string) (*database.
 		return nil, e.ExitFormat(403, e.W("'%s' is archived, cannot be modified"), branchName)
 	case ProtectedBranch:
 		if !e.IsAdministrator {
-			return nil, e.ExitFormat(403, e.W("'%s' is protected branch, cannot be modified"), branchName)
+			message := fmt.Sprintf(e.W("'%s' is protected branch, cannot be modified"), branchName)
+			s.notifyPushFailure(e, &repo.Command{ReferenceName: plumbing.NewBranchReferenceName(branchName), DryRun: dryRun}, 403, message)
+			return nil, e.ExitFormat(403, "%s", message)
 		}
 		return branch, 0
 	default:
@@ -282,13 +351,13 @@ func (s *Server) updateReferenceDryRun(e *Session, reference string) int {
 	refname := plumbing.ReferenceName(reference)
 	switch {
 	case refname.IsBranch():
-		_, exitCode := s.checkBranchCanUpdate(e, refname.BranchName())
+		_, exitCode := s.checkBranchCanUpdate(e, refname.BranchName(), false)
 		return exitCode
 	case refname.IsTag():
 		//return s.updateTagDryRun(w, r, refname.TagName())
 		return 0
 	case !strings.HasPrefix(reference, plumbing.ReferencePrefix):
-		_, exitCode := s.checkBranchCanUpdate(e, string(refname))
+		_, exitCode := s.checkBranchCanUpdate(e, string(refname), false)
 		return exitCode
 	}
 	return e.ExitFormat(501, e.W("reference name '%s' is reserved"), refname)
@@ -0,0 +1,53 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sshserver
+
+import (
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+)
+
+// zeta-serve templates "group/mono-zeta" --have "${VERSION}"
+type Templates struct {
+	Path string
+	Have string
+}
+
+func (c *Templates) ParseArgs(args []string) error {
+	var p ParseArgs
+	p.Add("have", REQUIRED, 'H')
+	if err := p.Parse(args, func(index rune, nextArg, raw string) error {
+		switch index {
+		case 'H':
+			c.Have = nextArg
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	var ok bool
+	if c.Path, ok = p.Unresolved(0); !ok {
+		return ErrPathNecessary
+	}
+	return nil
+}
+
+func (c *Templates) Exec(ctx *RunCtx) int {
+	return ctx.S.Templates(ctx.Session, c.Path, c.Have)
+}
+
+func (s *Server) Templates(e *Session, repoPath, have string) int {
+	if exitCode := s.doPermissionCheck(e, repoPath, protocol.DOWNLOAD); exitCode != 0 {
+		return exitCode
+	}
+	templates, err := s.hub.Templates(e.Context(), e.NamespaceID)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	if len(have) != 0 && have == templates.Version {
+		ZetaEncodeVND(e, &protocol.Templates{Version: templates.Version, NotModified: true})
+		return 0
+	}
+	ZetaEncodeVND(e, templates)
+	return 0
+}
@@ -39,3 +39,68 @@ func TestMetadataCommand(t *testing.T) {
 		fmt.Fprintf(os.Stderr, "parse command: %v\n", err)
 	}
 }
+
+func TestDefaultBranchCommand(t *testing.T) {
+	cmd, err := NewCommand([]string{"default-branch", "mono/zeta", "--set=refs/heads/dev"})
+	if err != nil {
+		t.Fatalf("parse command: %v", err)
+	}
+	c, ok := cmd.(*DefaultBranch)
+	if !ok || c.Path != "mono/zeta" || c.Branch != "dev" {
+		t.Errorf("unexpected command: %v", cmd)
+	}
+	if _, err := NewCommand([]string{"default-branch", "mono/zeta"}); err == nil {
+		t.Errorf("--set should be necessary")
+	}
+}
+
+func TestTemplatesCommand(t *testing.T) {
+	cmd, err := NewCommand([]string{"templates", "mono/zeta", "--have", "0123456789abcdef"})
+	if err != nil {
+		t.Fatalf("parse command: %v", err)
+	}
+	c, ok := cmd.(*Templates)
+	if !ok || c.Path != "mono/zeta" || c.Have != "0123456789abcdef" {
+		t.Errorf("unexpected command: %v", cmd)
+	}
+	if _, err := NewCommand([]string{"templates"}); err == nil {
+		t.Errorf("path should be necessary")
+	}
+}
+
+func TestDeployKeyAllowed(t *testing.T) {
+	tests := []struct {
+		commands []string
+		command  string
+		want     bool
+	}{
+		{nil, "ls-remote", true},
+		{nil, "objects", true},
+		{nil, "push", false},
+		{nil, "default-branch", false},
+		{nil, "templates", true},
+		{[]string{"ls-remote", "metadata", "objects", "push"}, "push", true},
+		{[]string{"push"}, "objects", false},
+	}
+	for _, tt := range tests {
+		if got := deployKeyAllowed(tt.commands, tt.command); got != tt.want {
+			t.Errorf("deployKeyAllowed(%v, %s) = %v, want %v", tt.commands, tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestPushDryRunCommand(t *testing.T) {
+	oldRev := "0000000000000000000000000000000000000000000000000000000000000000"
+	newRev := "8f2b5e2ba2c0f4a9a6e3d6c3a5d4f7c2e1b0a9d8c7b6a5f4e3d2c1b0a9f8e7d6"
+	cmd, err := NewCommand([]string{"push", "mono/zeta", "--reference=refs/heads/dev", "--old-rev=" + oldRev, "--new-rev=" + newRev, "--dry-run"})
+	if err != nil {
+		t.Fatalf("parse command: %v", err)
+	}
+	c, ok := cmd.(*Push)
+	if !ok || !c.DryRun || c.Reference != "refs/heads/dev" || c.NewRev.String() != newRev {
+		t.Errorf("unexpected command: %v", cmd)
+	}
+	if cmd, err = NewCommand([]string{"push", "mono/zeta", "--reference=refs/heads/dev", "--old-rev=" + oldRev, "--new-rev=" + newRev}); err != nil || cmd.(*Push).DryRun {
+		t.Errorf("push should not be a dry run: %v %v", cmd, err)
+	}
+}
@@ -0,0 +1,183 @@
+package sshserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+	"github.com/gliderlabs/ssh"
+)
+
+type testContext struct {
+	ssh.Context
+	ctx context.Context
+}
+
+func (c *testContext) Deadline() (deadline time.Time, ok bool) { return c.ctx.Deadline() }
+func (c *testContext) Done() <-chan struct{}                   { return c.ctx.Done() }
+func (c *testContext) Err() error                              { return c.ctx.Err() }
+func (c *testContext) Value(key any) any                       { return c.ctx.Value(key) }
+
+type testSession struct {
+	ssh.Session
+	ctx    *testContext
+	env    []string
+	stdin  io.Reader
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+}
+
+func (s *testSession) Context() ssh.Context                           { return s.ctx }
+func (s *testSession) Environ() []string                              { return s.env }
+func (s *testSession) Read(p []byte) (int, error)                     { return s.stdin.Read(p) }
+func (s *testSession) Write(p []byte) (int, error)                    { return s.stdout.Write(p) }
+func (s *testSession) Stderr() io.ReadWriter                          { return &s.stderr }
+func (s *testSession) CloseWrite() error                              { return nil }
+func (s *testSession) SendRequest(string, bool, []byte) (bool, error) { return true, nil }
+
+type testRepository struct {
+	repo.Repository
+	o *odb.ODB
+}
+
+func (r *testRepository) ODB() odb.DB  { return r.o }
+func (r *testRepository) Close() error { return nil }
+
+type testRepositories struct {
+	repo.Repositories
+	o *odb.ODB
+}
+
+func (r *testRepositories) Open(ctx context.Context, rid int64, compressionAlgo, defaultBranch string) (repo.Repository, error) {
+	return &testRepository{o: r.o}, nil
+}
+
+// newTestServer serves a repository holding one blob, the stored (compressed) bytes of the blob are returned.
+func newTestServer(t *testing.T) (*Server, plumbing.Hash, []byte) {
+	root := filepath.Join(t.TempDir(), "1.zeta")
+	o, err := odb.NewODB(1, root, "zstd", nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("new odb error: %v", err)
+	}
+	t.Cleanup(func() { _ = o.Close() })
+	d, err := backend.NewDatabase(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close() // nolint
+	oid, err := d.HashTo(t.Context(), strings.NewReader(strings.Repeat("resumable blob\n", 100)), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr, err := o.Open(t.Context(), oid, 0)
+	if err != nil {
+		t.Fatalf("open blob error: %v", err)
+	}
+	defer sr.Close() // nolint
+	stored, err := io.ReadAll(sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		ServerConfig: &ServerConfig{BodyLimits: serve.NewBodyLimits()},
+		hub:          &testRepositories{o: o},
+	}
+	return s, oid, stored
+}
+
+func newTestSession(t *testing.T, stdin string, env ...string) (*Session, *testSession) {
+	ts := &testSession{ctx: &testContext{ctx: t.Context()}, env: env, stdin: strings.NewReader(stdin)}
+	e := &Session{
+		Session:    ts,
+		SessionCtx: &SessionCtx{},
+		request:    &request{RID: 1},
+		env:        make(map[string]string),
+	}
+	e.initializeEnv()
+	return e, ts
+}
+
+// TestGetObjectResume: '--offset=N' must behave like HTTP 'Range: bytes=N-'.
+func TestGetObjectResume(t *testing.T) {
+	s, oid, stored := newTestServer(t)
+	for _, offset := range []int64{0, 7, int64(len(stored))} {
+		e, ts := newTestSession(t, "")
+		if code := s.GetObject(e, oid, offset); code != 0 {
+			t.Fatalf("get object offset %d exit %d: %s", offset, code, ts.stderr.String())
+		}
+		var header struct {
+			Magic          [4]byte
+			Version        uint32
+			Length         int64
+			CompressedSize int64
+		}
+		if err := binary.Read(&ts.stdout, binary.BigEndian, &header); err != nil {
+			t.Fatalf("read header error: %v", err)
+		}
+		if header.CompressedSize != int64(len(stored)) || header.Length != int64(len(stored))-offset {
+			t.Fatalf("offset %d: unexpected header %+v, stored %d bytes", offset, header, len(stored))
+		}
+		if !bytes.Equal(ts.stdout.Bytes(), stored[offset:]) {
+			t.Fatalf("offset %d: content does not start at the offset", offset)
+		}
+	}
+}
+
+// TestBatchObjectsIntegrity: 'ZETA_BATCH_INTEGRITY=crc64' must behave like HTTP 'X-Zeta-Batch-Integrity: crc64'.
+func TestBatchObjectsIntegrity(t *testing.T) {
+	s, oid, stored := newTestServer(t)
+	for _, env := range []string{"", "ZETA_BATCH_INTEGRITY=crc64"} {
+		e, ts := newTestSession(t, oid.String()+"\n", env)
+		if code := s.BatchObjects(e); code != 0 {
+			t.Fatalf("batch objects exit %d: %s", code, ts.stderr.String())
+		}
+		var header struct {
+			Magic    [4]byte
+			Version  uint32
+			Reserved [16]byte
+			Size     uint32
+			OID      [plumbing.HASH_HEX_SIZE]byte
+		}
+		if err := binary.Read(&ts.stdout, binary.BigEndian, &header); err != nil {
+			t.Fatalf("read header error: %v", err)
+		}
+		want := byte(0)
+		if len(env) != 0 {
+			want = protocol.BATCH_FLAG_ITEM_CRC64
+		}
+		if header.Reserved[0] != want || string(header.OID[:]) != oid.String() || int(header.Size) != len(stored)+plumbing.HASH_HEX_SIZE {
+			t.Fatalf("%q: unexpected header %+v", env, header)
+		}
+		if !bytes.HasPrefix(ts.stdout.Bytes(), stored) {
+			t.Fatalf("%q: unexpected content", env)
+		}
+	}
+}
+
+func TestObjectsExists(t *testing.T) {
+	s, oid, _ := newTestServer(t)
+	missing := plumbing.NewHash("e5b3a5a9bf8754e338162e69fd6820ed65e0b3f306e651c0f7cb6540e6a8f7a1")
+	e, ts := newTestSession(t, oid.String()+"\n"+missing.String()+"\n"+oid.String()+"\n")
+	if code := s.ObjectsExists(e); code != 0 {
+		t.Fatalf("objects exists exit %d: %s", code, ts.stderr.String())
+	}
+	var response protocol.ObjectsExistsResponse
+	if err := json.NewDecoder(&ts.stdout).Decode(&response); err != nil {
+		t.Fatalf("decode response error: %v", err)
+	}
+	if response.Objects != 3 || !response.Exists(0) || response.Exists(1) || !response.Exists(2) {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+}
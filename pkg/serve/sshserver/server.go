@@ -10,9 +10,11 @@ import (
 	"fmt"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/extension"
 	"github.com/antgroup/hugescm/pkg/serve/repo"
 	"github.com/antgroup/hugescm/pkg/serve/sshserver/rainbow"
 	"github.com/gliderlabs/ssh"
@@ -37,6 +39,7 @@ type Server struct {
 	srv        *ssh.Server
 	db         database.DB
 	hub        repo.Repositories
+	ext        *extension.Set
 	serverName string
 	uniqueID   int64
 }
@@ -50,10 +53,14 @@ func NewServer(sc *ServerConfig) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	if s.db, err = database.NewDB(cfg); err != nil {
+	if s.db, err = database.NewDB(cfg, database.WithReferenceKey(sc.ReferenceSigner)); err != nil {
 		return nil, err
 	}
-	if s.hub, err = repo.NewRepositories(sc.Repositories, sc.PersistentOSS, sc.Cache, s.db); err != nil {
+	if s.ext, err = extension.Load(sc.Extensions); err != nil {
+		_ = s.db.Close()
+		return nil, err
+	}
+	if s.hub, err = repo.NewRepositories(sc.Repositories, sc.PersistentOSS, sc.Tiering, sc.Cache, sc.CommitPolicy, sc.PathPolicy, s.db, s.ext); err != nil {
 		_ = s.db.Close()
 		return nil, err
 	}
@@ -100,6 +107,10 @@ func (s *Server) OnKey(ctx ssh.Context, key ssh.PublicKey) bool {
 		logrus.Errorf("PublicKeyHandle: auth failed for key %s: %v", fingerprint, err)
 		return false
 	}
+	if k.Expired(time.Now()) {
+		logrus.Infof("PublicKeyHandle: key %s expired at %v", fingerprint, k.ExpiresAt)
+		return false
+	}
 	ctx.SetValue(connMetadataKey, &SessionCtx{
 		KID:           k.ID,
 		UID:           k.UID,
@@ -165,6 +176,10 @@ func (s *Server) displayUser(e *Session) int {
 }
 
 func (s *Server) handleSession(e *Session) int {
+	// OnKey also answers unsigned key queries, the key is used only once the session is authenticated
+	if err := s.db.TouchKey(e.Context(), e.KID); err != nil {
+		logrus.Errorf("update last used of key %s: %v", e.Fingerprint, err)
+	}
 	if e.User() != DefaultUser {
 		e.WriteError("supports only username '\x1b[33mzeta\x1b[0m', current '\x1b[31m%s\x1b[0m'\n", e.User())
 		return 1
@@ -183,6 +198,7 @@ func (s *Server) handleSession(e *Session) int {
 		e.WriteError("fatal: \x1b[31m%v\x1b[0m", err)
 		return 1
 	}
+	e.SubCommand = args[1]
 	return cmd.Exec(&RunCtx{
 		S:       s,
 		Session: e,
@@ -15,6 +15,7 @@ import (
 	"unicode"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/modules/zeta/backend"
 	"github.com/antgroup/hugescm/modules/zeta/object"
 	"github.com/antgroup/hugescm/pkg/serve"
@@ -68,12 +69,16 @@ type SessionCtx struct {
 }
 
 type request struct {
+	SubCommand      string // zeta-serve <command>, deploy keys are limited to the commands allowed
 	RID             int64
+	NamespaceID     int64
 	NamespacePath   string
 	RepoPath        string
 	DefaultBranch   string
 	CompressionAlgo string
 	HashAlgo        string
+	// Paths: dirs the user is restricted to, empty when not restricted
+	Paths []string
 }
 
 type Session struct {
@@ -187,3 +192,12 @@ func (e *Session) ExitFormat(code int, format string, a ...any) int {
 	e.WriteError(format, a...)
 	return code
 }
+
+// ExitRequestError exits with 413 when the input exceeds the limit, otherwise 400.
+func (e *Session) ExitRequestError(err error, format string) int {
+	if le, ok := errors.AsType[*serve.ErrBodyTooLarge](err); ok {
+		e.WriteError(e.W("request body exceeds the limit of %s, please split it into smaller batches, eg: lower 'transport.maxEntries'"), strengthen.FormatSize(le.Limit))
+		return 413
+	}
+	return e.ExitFormat(400, format, err)
+}
@@ -0,0 +1,50 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transport
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/antgroup/hugescm/pkg/tr"
+)
+
+const (
+	// ClockSkewThreshold: smaller differences between local and server time are ignored
+	ClockSkewThreshold = 5 * time.Minute
+)
+
+var (
+	clockSkew     atomic.Int64 // server time - local time
+	clockSkewOnce sync.Once
+)
+
+// SyncClock records the skew between local time and the time reported by the server (Date header or server_time).
+// When local time is wildly off, expiry checks of tokens and share links use the server time instead.
+func SyncClock(serverTime time.Time) {
+	if serverTime.IsZero() {
+		return
+	}
+	skew := time.Until(serverTime)
+	if skew.Abs() < ClockSkewThreshold {
+		clockSkew.Store(0)
+		return
+	}
+	clockSkew.Store(int64(skew))
+	clockSkewOnce.Do(func() {
+		_, _ = tr.Fprintf(os.Stderr, "warning: local clock is off by %v from server time, using server time for expiry checks\n", skew.Round(time.Second))
+	})
+}
+
+// ClockSkew returns the detected skew, zero when local time is close to server time.
+func ClockSkew() time.Duration {
+	return time.Duration(clockSkew.Load())
+}
+
+// Now returns local time corrected by the detected skew.
+func Now() time.Time {
+	return time.Now().Add(ClockSkew())
+}
@@ -85,6 +85,8 @@ type Endpoint struct {
 	CredentialEncryptionKey string
 	// CredentialStoragePath specifies the path for encrypted credential file
 	CredentialStoragePath string
+	// CacheDir specifies the directory of the HTTP response cache, disabled when empty
+	CacheDir string
 	// origin endpoint: only scp like url --> zeta@domain.com:namespace/repo
 	origin string
 }
@@ -97,6 +99,8 @@ type Options struct {
 	CredentialStorage       string
 	CredentialEncryptionKey string
 	CredentialStoragePath   string
+	// HTTP response cache directory
+	CacheDir string
 }
 
 func (opts *Options) parseExtraHeader() map[string]string {
@@ -150,6 +154,7 @@ func parseURL(endpoint string, opts *Options) (*Endpoint, error) {
 		e.CredentialStorage = opts.CredentialStorage
 		e.CredentialEncryptionKey = opts.CredentialEncryptionKey
 		e.CredentialStoragePath = opts.CredentialStoragePath
+		e.CacheDir = opts.CacheDir
 	}
 	return e, nil
 }
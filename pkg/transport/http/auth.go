@@ -301,6 +301,7 @@ func (c *client) checkAuth(ctx context.Context, cred *Credentials, operation tra
 		if len(sa.Notice) != 0 {
 			remoteNotify(sa.Notice)
 		}
+		syncClock(resp, sa.ServerTime)
 		c.tokenPayload = &sa
 		return true, nil
 	}
@@ -35,11 +35,14 @@ const (
 	ZETA_PROTOCOL           = "Zeta-Protocol"
 	ZETA_COMMAND_OLDREV     = "X-Zeta-Command-OldRev"
 	ZETA_COMMAND_NEWREV     = "X-Zeta-Command-NewRev"
+	ZETA_COMMAND_DRY_RUN    = "X-Zeta-Command-Dry-Run"
 	ZETA_TERMINAL           = "X-Zeta-Terminal"
 	ZETA_OBJECTS_STATS      = "X-Zeta-Objects-Stats"
 	ZETA_COMPRESSED_SIZE    = "X-Zeta-Compressed-Size"
 	ZETA_PUSH_OPTION_COUNT  = "X-Zeta-Push-Option-Count"
 	ZETA_PUSH_OPTION_PREFIX = "X-Zeta-Push-Option-"
+	ZETA_BATCH_INTEGRITY    = "X-Zeta-Batch-Integrity"
+	ZETA_DELTA_BASE         = "X-Zeta-Delta-Base"
 	// ZETA Protocol Content Type
 	ZETA_MIME_BLOB              = "application/x-zeta-blob"
 	ZETA_MIME_BLOBS             = "application/x-zeta-blobs"
@@ -71,6 +74,7 @@ type client struct {
 	credentialStorage       string
 	credentialEncryptionKey string
 	credentialStoragePath   string
+	cache                   *responseCache
 }
 
 func (c *client) hasAuth() bool {
@@ -124,6 +128,7 @@ func newClient(ctx context.Context, endpoint *transport.Endpoint, operation tran
 		credentialStorage:       endpoint.CredentialStorage,
 		credentialEncryptionKey: endpoint.CredentialEncryptionKey,
 		credentialStoragePath:   endpoint.CredentialStoragePath,
+		cache:                   newResponseCache(endpoint.CacheDir),
 	}
 	if c.extraHeader == nil {
 		c.extraHeader = make(map[string]string)
@@ -233,6 +238,14 @@ func parseError(resp *http.Response) error {
 	return &ErrorCode{status: resp.StatusCode, Message: fmt.Sprintf("%s\n%s", resp.Status, body)}
 }
 
+// syncClock prefers server_time of the response body and falls back to the Date header.
+func syncClock(resp *http.Response, serverTime time.Time) {
+	if serverTime.IsZero() {
+		serverTime, _ = http.ParseTime(resp.Header.Get("Date"))
+	}
+	transport.SyncClock(serverTime)
+}
+
 type sessionReader struct {
 	io.Reader
 	io.Closer
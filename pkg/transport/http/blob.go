@@ -28,6 +28,7 @@ func (c *client) BatchObjects(ctx context.Context, objects []plumbing.Hash) (tra
 	}
 	req.Header.Set("Accept", ZETA_MIME_BLOBS)
 	req.Header.Set("Content-Type", ZETA_MIME_MULTI_OBJECTS)
+	req.Header.Set(ZETA_BATCH_INTEGRITY, transport.BATCH_INTEGRITY_CRC64)
 	resp, err := c.Do(req)
 	if err != nil {
 		return nil, err
@@ -47,6 +48,30 @@ func (c *client) BatchObjects(ctx context.Context, objects []plumbing.Hash) (tra
 	}, nil
 }
 
+func (c *client) ObjectsExists(ctx context.Context, objects []plumbing.Hash) ([]bool, error) {
+	reader := transport.NewObjectsReader(objects)
+	defer reader.Close() // nolint
+	existsURL := c.baseURL.JoinPath("objects", "exists").String()
+	req, err := c.newRequest(ctx, "POST", existsURL, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ZETA_MIME_JSON_METADATA)
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		return nil, parseError(resp)
+	}
+	var response transport.ObjectsExistsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	return response.Exists(len(objects))
+}
+
 func (c *client) Share(ctx context.Context, wantObjects []*transport.WantObject) ([]*transport.Representation, error) {
 	var b bytes.Buffer
 	if err := json.NewEncoder(&b).Encode(&transport.BatchShareObjectsRequest{
@@ -72,6 +97,7 @@ func (c *client) Share(ctx context.Context, wantObjects []*transport.WantObject)
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, err
 	}
+	syncClock(resp, response.ServerTime)
 	return response.Objects, nil
 }
 
@@ -0,0 +1,159 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/trace"
+)
+
+const (
+	IF_NONE_MATCH = "If-None-Match"
+	ETAG          = "ETag"
+	// responses larger than this are never cached
+	maxCachedResponseSize = 4 << 20
+	maxCachedResponses    = 64
+)
+
+// responseCache is a small on-disk cache of ETag validated responses (references and metadata).
+type responseCache struct {
+	dir string
+}
+
+type cachedResponse struct {
+	ETag        string
+	ContentType string
+	Body        []byte
+}
+
+func newResponseCache(dir string) *responseCache {
+	if len(dir) == 0 {
+		return nil
+	}
+	return &responseCache{dir: dir}
+}
+
+func (c *responseCache) key(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		_, _ = h.Write([]byte(p))
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// load: cache file format: etag LF content-type LF body
+func (c *responseCache) load(key string) (*cachedResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+	b, err := os.ReadFile(filepath.Join(c.dir, key))
+	if err != nil {
+		return nil, false
+	}
+	etag, rest, ok := bytes.Cut(b, []byte{'\n'})
+	if !ok {
+		return nil, false
+	}
+	contentType, body, ok := bytes.Cut(rest, []byte{'\n'})
+	if !ok || len(etag) == 0 {
+		return nil, false
+	}
+	return &cachedResponse{ETag: string(etag), ContentType: string(contentType), Body: body}, true
+}
+
+func (c *responseCache) store(key string, cr *cachedResponse) {
+	if c == nil || len(cr.ETag) == 0 || strings.ContainsAny(cr.ETag+cr.ContentType, "\r\n") {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		trace.DbgPrint("create cache dir error: %v", err)
+		return
+	}
+	fd, err := os.CreateTemp(c.dir, "tmp-")
+	if err != nil {
+		trace.DbgPrint("create cache file error: %v", err)
+		return
+	}
+	tempName := fd.Name()
+	w := bufio.NewWriter(fd)
+	_, _ = w.WriteString(cr.ETag + "\n" + cr.ContentType + "\n")
+	_, _ = w.Write(cr.Body)
+	if err := w.Flush(); err != nil {
+		_ = fd.Close()
+		_ = os.Remove(tempName)
+		return
+	}
+	if err := fd.Close(); err != nil {
+		_ = os.Remove(tempName)
+		return
+	}
+	if err := os.Rename(tempName, filepath.Join(c.dir, key)); err != nil {
+		_ = os.Remove(tempName)
+		return
+	}
+	c.prune()
+}
+
+// prune removes the oldest responses beyond maxCachedResponses.
+func (c *responseCache) prune() {
+	dirs, err := os.ReadDir(c.dir)
+	if err != nil || len(dirs) <= maxCachedResponses {
+		return
+	}
+	type entry struct {
+		name    string
+		modTime time.Time
+	}
+	entries := make([]entry, 0, len(dirs))
+	for _, d := range dirs {
+		if si, err := d.Info(); err == nil && si.Mode().IsRegular() {
+			entries = append(entries, entry{name: d.Name(), modTime: si.ModTime()})
+		}
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return a.modTime.Compare(b.modTime)
+	})
+	for _, e := range entries[:max(0, len(entries)-maxCachedResponses)] {
+		_ = os.Remove(filepath.Join(c.dir, e.name))
+	}
+}
+
+// revalidate adds If-None-Match when a cached response exists.
+func (c *responseCache) revalidate(req *http.Request, key string) *cachedResponse {
+	cr, ok := c.load(key)
+	if !ok {
+		return nil
+	}
+	req.Header.Set(IF_NONE_MATCH, cr.ETag)
+	return cr
+}
+
+// readAndStore reads the response body and caches it when it has an ETag and is small enough.
+// The returned body replaces resp.Body, which is consumed when cached.
+func (c *responseCache) readAndStore(resp *http.Response, key string) io.ReadCloser {
+	etag := resp.Header.Get(ETAG)
+	if c == nil || len(etag) == 0 || resp.ContentLength > maxCachedResponseSize {
+		return resp.Body
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedResponseSize+1))
+	if err != nil || len(body) > maxCachedResponseSize {
+		// body is partially consumed, continue reading from the rest
+		return &decompressReader{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), closer: []io.Closer{resp.Body}}
+	}
+	_ = resp.Body.Close()
+	c.store(key, &cachedResponse{ETag: etag, ContentType: resp.Header.Get("Content-Type"), Body: body})
+	return io.NopCloser(bytes.NewReader(body))
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestFetchReferenceETag(t *testing.T) {
+	const etag = `"abc"`
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get(IF_NONE_MATCH) == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set(ETAG, etag)
+		w.Header().Set("Content-Type", ZETA_MIME_JSON_METADATA)
+		_, _ = w.Write([]byte(`{"name":"refs/heads/mainline","hash":"e5b3a5a9bf8754e338162e69fd6820ed65e0b3f306e651c0f7cb6540e6a8f7a1"}`))
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL + "/group/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &client{Client: srv.Client(), baseURL: u, extraHeader: map[string]string{}, cache: newResponseCache(t.TempDir())}
+	for range 2 {
+		ref, err := c.FetchReference(context.Background(), "refs/heads/mainline")
+		if err != nil {
+			t.Fatalf("fetch reference error: %v", err)
+		}
+		if ref.Name != "refs/heads/mainline" {
+			t.Fatalf("unexpected reference name: %s", ref.Name)
+		}
+	}
+	if requests != 2 || notModified != 1 {
+		t.Fatalf("requests: %d, not modified: %d", requests, notModified)
+	}
+}
+
+func TestResponseCachePrune(t *testing.T) {
+	c := newResponseCache(t.TempDir())
+	for i := range maxCachedResponses + 8 {
+		c.store(c.key("key", string(rune('a'+i))), &cachedResponse{ETag: `"x"`, ContentType: ZETA_MIME_METADATA, Body: []byte("body")})
+	}
+	n := 0
+	for i := range maxCachedResponses + 8 {
+		if _, ok := c.load(c.key("key", string(rune('a'+i)))); ok {
+			n++
+		}
+	}
+	if n != maxCachedResponses {
+		t.Fatalf("cached responses: %d, want %d", n, maxCachedResponses)
+	}
+	var nilCache *responseCache
+	if _, ok := nilCache.load("any"); ok {
+		t.Fatalf("nil cache should be empty")
+	}
+}
@@ -4,6 +4,7 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -83,6 +84,9 @@ func (c *client) FetchMetadata(ctx context.Context, target plumbing.Hash, opts *
 	if opts.Depth >= 0 {
 		q.Set("depth", strconv.Itoa(opts.Depth))
 	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
 	if len(q) > 0 {
 		metadataURL.RawQuery = q.Encode()
 	}
@@ -94,15 +98,30 @@ func (c *client) FetchMetadata(ctx context.Context, target plumbing.Hash, opts *
 		req.Header.Set("Content-Type", ZETA_MIME_MULTI_OBJECTS)
 	}
 	req.Header.Set("Accept", ZETA_MIME_COMPRESS_METADATA)
+	cacheKey := c.cache.key(append([]string{metadataURL.String(), ZETA_MIME_COMPRESS_METADATA}, opts.SparseDirs...)...)
+	cached := c.cache.revalidate(req, cacheKey)
 	resp, err := c.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		_ = resp.Body.Close()
+		h := make(http.Header)
+		h.Set("Content-Type", cached.ContentType)
+		rc, err := newDecompressReader(io.NopCloser(bytes.NewReader(cached.Body)), h)
+		if err != nil {
+			return nil, err
+		}
+		return &sessionReader{
+			Reader: rc,
+			Closer: rc,
+		}, nil
+	}
 	if resp.StatusCode > 299 || resp.StatusCode < 200 {
 		defer resp.Body.Close() // nolint
 		return nil, parseError(resp)
 	}
-	rc, err := newDecompressReader(resp.Body, resp.Header)
+	rc, err := newDecompressReader(c.cache.readAndStore(resp, cacheKey), resp.Header)
 	if err != nil {
 		_ = resp.Body.Close()
 		return nil, err
@@ -113,14 +132,19 @@ func (c *client) FetchMetadata(ctx context.Context, target plumbing.Hash, opts *
 	}, nil
 }
 
-func (c *client) BatchMetadata(ctx context.Context, objects []plumbing.Hash, depth int) (transport.SessionReader, error) {
+func (c *client) BatchMetadata(ctx context.Context, objects []plumbing.Hash, depth int, limit int) (transport.SessionReader, error) {
 	reader := transport.NewObjectsReader(objects)
 	defer reader.Close() // nolint
 
 	metadataURL := c.baseURL.JoinPath("metadata", "batch")
+	q := make(url.Values)
 	if depth >= 0 {
-		q := make(url.Values)
 		q.Set("depth", strconv.Itoa(depth))
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	if len(q) > 0 {
 		metadataURL.RawQuery = q.Encode()
 	}
 	req, err := c.newRequest(ctx, http.MethodPost, metadataURL.String(), reader)
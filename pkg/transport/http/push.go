@@ -27,6 +27,9 @@ func (c *client) Push(ctx context.Context, r io.Reader, cmd *transport.Command)
 	req.Header.Set(ZETA_COMMAND_NEWREV, cmd.NewRev)
 	req.Header.Set(ZETA_OBJECTS_STATS, fmt.Sprintf("m-%d;b-%d", cmd.Metadata, cmd.Objects))
 	req.Header.Set("Accept", ZETA_MIME_REPORT_RESULT)
+	if cmd.DryRun {
+		req.Header.Set(ZETA_COMMAND_DRY_RUN, "1")
+	}
 	if len(cmd.PushOptions) != 0 {
 		req.Header.Set(ZETA_PUSH_OPTION_COUNT, strconv.Itoa(len(cmd.PushOptions)))
 		for i, o := range cmd.PushOptions {
@@ -81,6 +84,15 @@ func (c *client) BatchCheck(ctx context.Context, refname plumbing.ReferenceName,
 }
 
 func (c *client) PutObject(ctx context.Context, refname plumbing.ReferenceName, oid plumbing.Hash, r io.Reader, size int64) error {
+	return c.putObject(ctx, refname, oid, plumbing.ZeroHash, r, size)
+}
+
+// PutDelta: the body is the delta, X-Zeta-Delta-Base is the object the delta applies to.
+func (c *client) PutDelta(ctx context.Context, refname plumbing.ReferenceName, oid, base plumbing.Hash, r io.Reader, size int64) error {
+	return c.putObject(ctx, refname, oid, base, r, size)
+}
+
+func (c *client) putObject(ctx context.Context, refname plumbing.ReferenceName, oid, base plumbing.Hash, r io.Reader, size int64) error {
 	req, err := c.newRequest(ctx, "PUT", c.baseURL.JoinPath("reference", string(refname), "objects", oid.String()).String(), r)
 	if err != nil {
 		return fmt.Errorf("new request error: %w", err)
@@ -89,6 +101,9 @@ func (c *client) PutObject(ctx context.Context, refname plumbing.ReferenceName,
 	req.Header.Set("Accept", ZETA_MIME_JSON_METADATA)
 	req.Header.Set("Content-Length", sizeS)
 	req.Header.Set(ZETA_COMPRESSED_SIZE, sizeS)
+	if !base.IsZero() {
+		req.Header.Set(ZETA_DELTA_BASE, base.String())
+	}
 	resp, err := c.Do(req)
 	if err != nil {
 		return fmt.Errorf("do request error: %w", err)
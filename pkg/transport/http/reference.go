@@ -4,9 +4,11 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
@@ -17,27 +19,111 @@ func (c *client) FetchReference(ctx context.Context, refname plumbing.ReferenceN
 	if len(refname) == 0 {
 		refname = plumbing.HEAD
 	}
-	req, err := c.newRequest(ctx, "GET", c.baseURL.JoinPath("reference", string(refname)).String(), nil)
+	referenceURL := c.baseURL.JoinPath("reference", string(refname)).String()
+	req, err := c.newRequest(ctx, "GET", referenceURL, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Accept", ZETA_MIME_JSON_METADATA)
+	cacheKey := c.cache.key(referenceURL, ZETA_MIME_JSON_METADATA)
+	cached := c.cache.revalidate(req, cacheKey)
 	resp, err := c.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close() // nolint
+	var body io.Reader
 	switch resp.StatusCode {
 	case http.StatusOK:
-		break
+		body = c.cache.readAndStore(resp, cacheKey)
+	case http.StatusNotModified:
+		if cached == nil {
+			return nil, parseError(resp)
+		}
+		body = bytes.NewReader(cached.Body)
 	case http.StatusNotFound:
 		return nil, transport.ErrReferenceNotExist
 	default:
 		return nil, parseError(resp)
 	}
 	var ref transport.Reference
-	if err := json.NewDecoder(resp.Body).Decode(&ref); err != nil {
+	if err := json.NewDecoder(body).Decode(&ref); err != nil {
 		return nil, fmt.Errorf("decode reference response error: %w", err)
 	}
 	return &ref, nil
 }
+
+func (c *client) ListReferences(ctx context.Context) (*transport.References, error) {
+	req, err := c.newRequest(ctx, "GET", c.baseURL.JoinPath("references").String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ZETA_MIME_JSON_METADATA)
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseError(resp)
+	}
+	var refs transport.References
+	if err := json.NewDecoder(resp.Body).Decode(&refs); err != nil {
+		return nil, fmt.Errorf("decode references response error: %w", err)
+	}
+	return &refs, nil
+}
+
+func (c *client) SetDefaultBranch(ctx context.Context, branch string) (*transport.DefaultBranchResponse, error) {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(&transport.DefaultBranchRequest{Branch: branch}); err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "PUT", c.baseURL.JoinPath("default-branch").String(), &b)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseError(resp)
+	}
+	var r transport.DefaultBranchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("decode default branch response error: %w", err)
+	}
+	return &r, nil
+}
+
+func (c *client) FetchTemplates(ctx context.Context, have string) (*transport.Templates, error) {
+	req, err := c.newRequest(ctx, "GET", c.baseURL.JoinPath("templates").String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ZETA_MIME_JSON_METADATA)
+	if len(have) != 0 {
+		// the version of the templates is their entity tag
+		req.Header.Set(IF_NONE_MATCH, "\""+have+"\"")
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return &transport.Templates{Version: have, NotModified: true}, nil
+	default:
+		return nil, parseError(resp)
+	}
+	var templates transport.Templates
+	if err := json.NewDecoder(resp.Body).Decode(&templates); err != nil {
+		return nil, fmt.Errorf("decode templates response error: %w", err)
+	}
+	return &templates, nil
+}
@@ -0,0 +1,71 @@
+package ssh
+
+import (
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/shlex"
+	"github.com/antgroup/hugescm/pkg/serve/sshserver"
+	"github.com/antgroup/hugescm/pkg/transport"
+)
+
+// parseServerCommand parses the command line the same way as zeta-serve sshd.
+func parseServerCommand(t *testing.T, commandArgs string) sshserver.Command {
+	args, err := shlex.Split(commandArgs, true)
+	if err != nil {
+		t.Fatalf("split %q error: %v", commandArgs, err)
+	}
+	if len(args) == 0 || args[0] != sshserver.ServeCommand {
+		t.Fatalf("unexpected command %q", commandArgs)
+	}
+	cmd, err := sshserver.NewCommand(args[1:])
+	if err != nil {
+		t.Fatalf("server rejects %q: %v", commandArgs, err)
+	}
+	return cmd
+}
+
+// TestMetadataCommandConformance: the server must read the arguments like the HTTP query, see docs/protocol.md.
+func TestMetadataCommandConformance(t *testing.T) {
+	target := plumbing.NewHash("b3d0d5d7dc3b1ab2c56b5b8e3b6d35ad6b0fa1e8f27eddc0d5f5d5d72bb2f0f0")
+	from := plumbing.NewHash("7d8c3ba6e9f4bb2b46bd2d3a5ae3b9b8ac4c3c5f1a1a9c0c9c3b6bbcb4b6e0f1")
+	tests := []struct {
+		opts       *transport.MetadataOptions
+		deepen     int
+		deepenFrom plumbing.Hash
+		have       plumbing.Hash
+		depth      int
+		sparse     bool
+	}{
+		{&transport.MetadataOptions{Deepen: 1, Depth: transport.AnyDepth}, 1, plumbing.ZeroHash, plumbing.ZeroHash, -1, false},
+		{&transport.MetadataOptions{Deepen: 5, Depth: 0, Have: from}, 5, plumbing.ZeroHash, from, 0, false},
+		// deepen-from wins over deepen
+		{&transport.MetadataOptions{Deepen: 1, DeepenFrom: from, Depth: transport.AnyDepth}, -1, from, plumbing.ZeroHash, -1, false},
+		{&transport.MetadataOptions{Deepen: transport.AnyDeepen, Depth: 1, SparseDirs: []string{"a b/c"}}, -1, plumbing.ZeroHash, plumbing.ZeroHash, 1, true},
+	}
+	for _, tt := range tests {
+		commandArgs := metadataCommand("group/mono zeta", target, tt.opts)
+		c, ok := parseServerCommand(t, commandArgs).(*sshserver.Metadata)
+		if !ok {
+			t.Fatalf("%q is not a metadata command", commandArgs)
+		}
+		if c.Path != "group/mono zeta" || c.Revision != target.String() || !c.UseZSTD || c.Batch {
+			t.Errorf("%q: unexpected command %+v", commandArgs, c)
+		}
+		if c.Deepen != tt.deepen || c.DeepenFrom != tt.deepenFrom || c.Have != tt.have || c.Depth != tt.depth || c.Sparse != tt.sparse {
+			t.Errorf("%q: unexpected options %+v", commandArgs, c)
+		}
+	}
+	c, ok := parseServerCommand(t, batchMetadataCommand("group/mono-zeta", 1)).(*sshserver.Metadata)
+	if !ok || !c.Batch || c.Depth != 1 || !c.UseZSTD {
+		t.Errorf("unexpected batch metadata command %+v", c)
+	}
+}
+
+func TestObjectCommandConformance(t *testing.T) {
+	oid := plumbing.NewHash("b3d0d5d7dc3b1ab2c56b5b8e3b6d35ad6b0fa1e8f27eddc0d5f5d5d72bb2f0f0")
+	c, ok := parseServerCommand(t, objectCommand("group/mono-zeta", oid, 4096)).(*sshserver.Objects)
+	if !ok || c.Path != "group/mono-zeta" || c.OID != oid || c.Offset != 4096 || c.Batch || c.Share {
+		t.Errorf("unexpected objects command %+v", c)
+	}
+}
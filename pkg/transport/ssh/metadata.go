@@ -48,6 +48,32 @@ func (c *client) FetchReference(ctx context.Context, refname plumbing.ReferenceN
 	return &r, nil
 }
 
+func (c *client) ListReferences(ctx context.Context) (*transport.References, error) {
+	commandArgs := fmt.Sprintf("zeta-serve ls-remote '%s' --all", c.Path)
+	cmd, err := c.NewBaseCommand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	if err := cmd.Start(commandArgs); err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	var refs transport.References
+	if err := json.NewDecoder(stdout).Decode(&refs); err != nil {
+		_ = cmd.Close()
+		return nil, cmd.lastError
+	}
+	if err := cmd.Close(); err != nil {
+		return nil, err
+	}
+	return &refs, nil
+}
+
 func sparseDirsGenReader(sparseDirs []string) io.Reader {
 	var b strings.Builder
 	var total int
@@ -80,12 +106,9 @@ func (r *decompressReader) LastError() error {
 	return r.cmd.lastError
 }
 
-// FetchMetadata: support base metadata and sparse metadata.
-//
-//	zeta-serve metadata "group/mono-zeta" --revision "${REVISION}" --depth=1 --deepen-from=${from}
-//	zeta-serve metadata "group/mono-zeta" --revision "${REVISION}" --sparse --depth=1 --deepen-from=${from}
-func (c *client) FetchMetadata(ctx context.Context, target plumbing.Hash, opts *transport.MetadataOptions) (transport.SessionReader, error) {
-	psArgs := []string{"zeta-serve", "metadata", fmt.Sprintf("'%s'", c.Path), "--revision", target.String()}
+// metadataCommand: arguments are the same as the HTTP query, see docs/protocol.md
+func metadataCommand(path string, target plumbing.Hash, opts *transport.MetadataOptions) string {
+	psArgs := []string{"zeta-serve", "metadata", fmt.Sprintf("'%s'", path), "--revision", target.String()}
 	if !opts.Have.IsZero() {
 		psArgs = append(psArgs, "--have="+opts.Have.String())
 	}
@@ -100,11 +123,22 @@ func (c *client) FetchMetadata(ctx context.Context, target plumbing.Hash, opts *
 		psArgs = append(psArgs, "--sparse")
 	}
 	psArgs = append(psArgs, "--zstd")
-	commandArgs := strings.Join(psArgs, " ")
+	return strings.Join(psArgs, " ")
+}
+
+// FetchMetadata: support base metadata and sparse metadata.
+//
+//	zeta-serve metadata "group/mono-zeta" --revision "${REVISION}" --depth=1 --deepen-from=${from}
+//	zeta-serve metadata "group/mono-zeta" --revision "${REVISION}" --sparse --depth=1 --deepen-from=${from}
+func (c *client) FetchMetadata(ctx context.Context, target plumbing.Hash, opts *transport.MetadataOptions) (transport.SessionReader, error) {
+	commandArgs := metadataCommand(c.Path, target, opts)
 	cmd, err := c.NewBaseCommand(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if opts.Limit > 0 {
+		_ = cmd.Setenv("ZETA_METADATA_LIMIT", strconv.Itoa(opts.Limit))
+	}
 	cmd.Stdin = sparseDirsGenReader(opts.SparseDirs)
 	if cmd.Reader, err = cmd.StdoutPipe(); err != nil {
 		_ = cmd.Close()
@@ -122,19 +156,26 @@ func (c *client) FetchMetadata(ctx context.Context, target plumbing.Hash, opts *
 	return &decompressReader{decoder: zr, cmd: cmd}, nil
 }
 
-func (c *client) BatchMetadata(ctx context.Context, objects []plumbing.Hash, depth int) (transport.SessionReader, error) {
-	reader := transport.NewObjectsReader(objects)
-	psArgs := []string{"zeta-serve", "metadata", fmt.Sprintf("'%s'", c.Path), "--batch"}
+func batchMetadataCommand(path string, depth int) string {
+	psArgs := []string{"zeta-serve", "metadata", fmt.Sprintf("'%s'", path), "--batch"}
 	if depth >= 0 {
 		psArgs = append(psArgs, "--depth="+strconv.Itoa(depth))
 	}
 	psArgs = append(psArgs, "--zstd")
-	commandArgs := strings.Join(psArgs, " ")
+	return strings.Join(psArgs, " ")
+}
+
+func (c *client) BatchMetadata(ctx context.Context, objects []plumbing.Hash, depth int, limit int) (transport.SessionReader, error) {
+	reader := transport.NewObjectsReader(objects)
+	commandArgs := batchMetadataCommand(c.Path, depth)
 	cmd, err := c.NewBaseCommand(ctx)
 	if err != nil {
 		_ = reader.Close()
 		return nil, err
 	}
+	if limit > 0 {
+		_ = cmd.Setenv("ZETA_METADATA_LIMIT", strconv.Itoa(limit))
+	}
 	cmd.Stdin = reader
 	cmd.closer = append(cmd.closer, reader)
 	if cmd.Reader, err = cmd.StdoutPipe(); err != nil {
@@ -152,3 +193,60 @@ func (c *client) BatchMetadata(ctx context.Context, objects []plumbing.Hash, dep
 	}
 	return &decompressReader{decoder: zr, cmd: cmd}, nil
 }
+
+// SetDefaultBranch: zeta-serve default-branch "group/mono-zeta" --set "${BRANCH}"
+func (c *client) SetDefaultBranch(ctx context.Context, branch string) (*transport.DefaultBranchResponse, error) {
+	commandArgs := fmt.Sprintf("zeta-serve default-branch '%s' --set='%s'", c.Path, branch)
+	cmd, err := c.NewBaseCommand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	if err := cmd.Start(commandArgs); err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	var r transport.DefaultBranchResponse
+	if err := json.NewDecoder(stdout).Decode(&r); err != nil {
+		_ = cmd.Close()
+		return nil, cmd.lastError
+	}
+	if err := cmd.Close(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// FetchTemplates: zeta-serve templates "group/mono-zeta" --have "${VERSION}"
+func (c *client) FetchTemplates(ctx context.Context, have string) (*transport.Templates, error) {
+	commandArgs := fmt.Sprintf("zeta-serve templates '%s'", c.Path)
+	if len(have) != 0 {
+		commandArgs += fmt.Sprintf(" --have='%s'", have)
+	}
+	cmd, err := c.NewBaseCommand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	if err := cmd.Start(commandArgs); err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	var templates transport.Templates
+	if err := json.NewDecoder(stdout).Decode(&templates); err != nil {
+		_ = cmd.Close()
+		return nil, cmd.lastError
+	}
+	if err := cmd.Close(); err != nil {
+		return nil, err
+	}
+	return &templates, nil
+}
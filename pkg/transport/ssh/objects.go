@@ -30,6 +30,7 @@ func (c *client) BatchObjects(ctx context.Context, objects []plumbing.Hash) (tra
 		_ = reader.Close()
 		return nil, err
 	}
+	_ = cmd.Setenv("ZETA_BATCH_INTEGRITY", transport.BATCH_INTEGRITY_CRC64)
 	cmd.Stdin = reader
 	cmd.closer = append(cmd.closer, reader)
 	if cmd.Reader, err = cmd.StdoutPipe(); err != nil {
@@ -43,10 +44,44 @@ func (c *client) BatchObjects(ctx context.Context, objects []plumbing.Hash) (tra
 	return cmd, nil
 }
 
+// ObjectsExists: zeta-serve objects "group/mono-zeta" --exists
+func (c *client) ObjectsExists(ctx context.Context, objects []plumbing.Hash) ([]bool, error) {
+	reader := transport.NewObjectsReader(objects)
+	defer reader.Close() // nolint
+	commandArgs := fmt.Sprintf("zeta-serve objects '%s' --exists", c.Path)
+	cmd, err := c.NewBaseCommand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = reader
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	if err := cmd.Start(commandArgs); err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	var response transport.ObjectsExistsResponse
+	if err := json.NewDecoder(stdout).Decode(&response); err != nil {
+		_ = cmd.Close()
+		return nil, cmd.lastError
+	}
+	if err := cmd.Close(); err != nil {
+		return nil, cmd.lastError
+	}
+	return response.Exists(len(objects))
+}
+
+// objectCommand: --offset=N is the same as HTTP 'Range: bytes=N-'
+func objectCommand(path string, oid plumbing.Hash, fromByte int64) string {
+	psArgs := []string{"zeta-serve", "objects", fmt.Sprintf("'%s'", path), "--oid=" + oid.String(), fmt.Sprintf("--offset=%d", fromByte)}
+	return strings.Join(psArgs, " ")
+}
+
 // GetObject: zeta-serve objects "group/mono-zeta" --oid "${OID}" --offset=N
 func (c *client) GetObject(ctx context.Context, oid plumbing.Hash, fromByte int64) (transport.SizeReader, error) {
-	psArgs := []string{"zeta-serve", "objects", fmt.Sprintf("'%s'", c.Path), "--oid=" + oid.String(), fmt.Sprintf("--offset=%d", fromByte)}
-	commandArgs := strings.Join(psArgs, " ")
 	cmd, err := c.NewBaseCommand(ctx)
 	if err != nil {
 		return nil, err
@@ -55,7 +90,7 @@ func (c *client) GetObject(ctx context.Context, oid plumbing.Hash, fromByte int6
 		_ = cmd.Close()
 		return nil, err
 	}
-	if err := cmd.Start(commandArgs); err != nil {
+	if err := cmd.Start(objectCommand(c.Path, oid, fromByte)); err != nil {
 		_ = cmd.Close()
 		return nil, err
 	}
@@ -132,5 +167,6 @@ func (c *client) Share(ctx context.Context, wantObjects []*transport.WantObject)
 	if err := cmd.Close(); err != nil {
 		return nil, cmd.lastError
 	}
+	transport.SyncClock(r.ServerTime)
 	return r.Objects, nil
 }
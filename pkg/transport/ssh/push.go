@@ -15,9 +15,12 @@ import (
 	"github.com/antgroup/hugescm/pkg/transport"
 )
 
-// Push: zeta-serve push "group/mono-zeta" --reference "$REFNAME"
+// Push: zeta-serve push "group/mono-zeta" --reference "$REFNAME" [--dry-run]
 func (c *client) Push(ctx context.Context, r io.Reader, command *transport.Command) (rc transport.SessionReader, err error) {
 	commandArgs := fmt.Sprintf("zeta-serve push '%s' --reference=%s --old-rev=%s --new-rev=%s", c.Path, command.Refname, command.OldRev, command.NewRev)
+	if command.DryRun {
+		commandArgs += " --dry-run"
+	}
 	cmd, err := c.NewBaseCommand(ctx)
 	if err != nil {
 		return nil, err
@@ -78,7 +81,15 @@ func (c *client) BatchCheck(ctx context.Context, refname plumbing.ReferenceName,
 
 // PutObject: zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}"
 func (c *client) PutObject(ctx context.Context, refname plumbing.ReferenceName, oid plumbing.Hash, r io.Reader, size int64) error {
-	commandArgs := fmt.Sprintf("zeta-serve push '%s' --reference=%s --oid=%s --size=%d", c.Path, refname, oid, size)
+	return c.putObject(ctx, fmt.Sprintf("zeta-serve push '%s' --reference=%s --oid=%s --size=%d", c.Path, refname, oid, size), r)
+}
+
+// PutDelta: zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}" --delta-base "$BASE"
+func (c *client) PutDelta(ctx context.Context, refname plumbing.ReferenceName, oid, base plumbing.Hash, r io.Reader, size int64) error {
+	return c.putObject(ctx, fmt.Sprintf("zeta-serve push '%s' --reference=%s --oid=%s --size=%d --delta-base=%s", c.Path, refname, oid, size, base), r)
+}
+
+func (c *client) putObject(ctx context.Context, commandArgs string, r io.Reader) error {
 	cmd, err := c.NewBaseCommand(ctx)
 	if err != nil {
 		return err
@@ -4,7 +4,9 @@
 package transport
 
 import (
+	"fmt"
 	"maps"
+	"slices"
 	"time"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
@@ -24,13 +26,14 @@ type SASHandshake struct {
 }
 
 type SASPayload struct {
-	Header    map[string]string `json:"header,omitempty"`
-	Notice    string            `json:"notice,omitempty"`
-	ExpiresAt time.Time         `json:"expires_at,omitzero"`
+	Header     map[string]string `json:"header,omitempty"`
+	Notice     string            `json:"notice,omitempty"`
+	ExpiresAt  time.Time         `json:"expires_at,omitzero"`
+	ServerTime time.Time         `json:"server_time,omitzero"`
 }
 
 func (p *SASPayload) IsExpired() bool {
-	return time.Now().After(p.ExpiresAt)
+	return Now().After(p.ExpiresAt)
 }
 
 type Reference struct {
@@ -46,6 +49,11 @@ type Reference struct {
 	Capabilities    []string               `json:"capabilities"`
 }
 
+// HasCapability reports whether the server advertises the capability.
+func (r *Reference) HasCapability(capability string) bool {
+	return slices.Contains(r.Capabilities, capability)
+}
+
 func (r *Reference) Target() plumbing.Hash {
 	if len(r.Peeled) != 0 {
 		return plumbing.NewHash(r.Peeled)
@@ -53,6 +61,61 @@ func (r *Reference) Target() plumbing.Hash {
 	return plumbing.NewHash(r.Hash)
 }
 
+type ReferenceItem struct {
+	Name   plumbing.ReferenceName `json:"name"`
+	Hash   string                 `json:"hash"`
+	Peeled string                 `json:"peeled,omitempty"`
+}
+
+func (r *ReferenceItem) Target() plumbing.Hash {
+	if len(r.Peeled) != 0 {
+		return plumbing.NewHash(r.Peeled)
+	}
+	return plumbing.NewHash(r.Hash)
+}
+
+// References: all branches, tags and other references of the remote repository.
+type References struct {
+	Remote          string           `json:"remote"`
+	HEAD            string           `json:"head"`
+	Version         int              `json:"version"`
+	Agent           string           `json:"agent"`
+	HashAlgo        string           `json:"hash-algo"`
+	CompressionALGO string           `json:"compression-algo"`
+	Capabilities    []string         `json:"capabilities"`
+	References      []*ReferenceItem `json:"references"`
+}
+
+// DefaultBranchRequest: change the default branch (HEAD) of the repository, the branch must exist.
+type DefaultBranchRequest struct {
+	Branch string `json:"branch"`
+}
+
+type DefaultBranchResponse struct {
+	DefaultBranch    string `json:"default_branch"`
+	OldDefaultBranch string `json:"old_default_branch"`
+}
+
+const (
+	TEMPLATE_IGNORE     = "ignore"
+	TEMPLATE_ATTRIBUTES = "attributes"
+)
+
+// Template: organization-wide ignore or attribute template, scope is global or the namespace path.
+type Template struct {
+	Kind    string `json:"kind"`
+	Scope   string `json:"scope"`
+	Version int64  `json:"version"`
+	Content string `json:"content"`
+}
+
+// Templates: global templates first, then the templates of the namespace.
+type Templates struct {
+	Version     string      `json:"version"`
+	NotModified bool        `json:"not_modified,omitempty"`
+	Templates   []*Template `json:"templates,omitempty"`
+}
+
 type Command struct {
 	Refname     plumbing.ReferenceName `json:"refname"`
 	OldRev      string                 `json:"old_rev"`
@@ -60,6 +123,8 @@ type Command struct {
 	Metadata    int                    `json:"metadata"`
 	Objects     int                    `json:"objects"`
 	PushOptions []string               `json:"push_options,omitempty"`
+	// DryRun: the server validates the push and reports the result without updating the reference, requires CAPABILITY_PUSH_DRY_RUN
+	DryRun bool `json:"dry_run,omitempty"`
 }
 
 type WantObject struct {
@@ -79,7 +144,7 @@ type Representation struct {
 }
 
 func (r *Representation) IsExpired() bool {
-	return time.Now().After(r.ExpiresAt)
+	return Now().After(r.ExpiresAt)
 }
 
 func (r *Representation) Copy() *Representation {
@@ -91,7 +156,8 @@ func (r *Representation) Copy() *Representation {
 }
 
 type BatchShareObjectsResponse struct {
-	Objects []*Representation `json:"objects"`
+	Objects    []*Representation `json:"objects"`
+	ServerTime time.Time         `json:"server_time,omitzero"`
 }
 
 type HaveObject struct {
@@ -107,3 +173,21 @@ type BatchRequest struct {
 type BatchResponse struct {
 	Objects []*HaveObject `json:"objects"`
 }
+
+// ObjectsExistsResponse: the i-th bit of Bitmap, least significant bit first, is set when the i-th object exists.
+type ObjectsExistsResponse struct {
+	Objects int    `json:"objects"`
+	Bitmap  []byte `json:"bitmap"`
+}
+
+// Exists returns the existence of the objects, n is the number of objects of the query.
+func (r *ObjectsExistsResponse) Exists(n int) ([]bool, error) {
+	if r.Objects != n || len(r.Bitmap) < (n+7)/8 {
+		return nil, fmt.Errorf("bad objects exists response: %d objects, expected %d", r.Objects, n)
+	}
+	exists := make([]bool, n)
+	for i := range exists {
+		exists[i] = r.Bitmap[i/8]&(1<<(i%8)) != 0
+	}
+	return exists, nil
+}
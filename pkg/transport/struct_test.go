@@ -68,3 +68,19 @@ func TestEndpoint(t *testing.T) {
 	}
 	fmt.Fprintf(os.Stderr, "endpoint: %v protocol: %s raw: %s\n", e, e.Scheme, raw)
 }
+
+func TestSyncClock(t *testing.T) {
+	defer SyncClock(time.Now())
+	r := &Representation{
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	// local clock is 3 hours behind the server
+	SyncClock(time.Now().Add(3 * time.Hour))
+	if !r.IsExpired() {
+		t.Fatalf("representation should be expired in server time")
+	}
+	SyncClock(time.Now().Add(time.Minute))
+	if ClockSkew() != 0 || r.IsExpired() {
+		t.Fatalf("small skew should be ignored, skew: %v", ClockSkew())
+	}
+}
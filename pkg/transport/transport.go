@@ -30,12 +30,34 @@ const (
 	AnyDeepen = -1
 )
 
+const (
+	// BATCH_INTEGRITY_CRC64: ask the server to append a CRC64 to every batch objects item
+	BATCH_INTEGRITY_CRC64 = "crc64"
+)
+
+const (
+	// CAPABILITY_OBJECTS_EXISTS: the server answers ObjectsExists, push skips the objects the server already has
+	CAPABILITY_OBJECTS_EXISTS = "objects-exists"
+	// CAPABILITY_DELTA_OBJECTS: the server accepts large objects uploaded as a delta against a base object, see PutDelta
+	CAPABILITY_DELTA_OBJECTS = "delta-objects"
+	// CAPABILITY_LIST_REFERENCES: the server lists all references at once, see ListReferences
+	CAPABILITY_LIST_REFERENCES = "list-references"
+	// CAPABILITY_TEMPLATES: the server hosts organization-wide ignore and attribute templates, see FetchTemplates
+	CAPABILITY_TEMPLATES = "templates"
+	// CAPABILITY_PUSH_DRY_RUN: the server validates a dry-run push without updating the reference, see Command.DryRun
+	CAPABILITY_PUSH_DRY_RUN = "push-dry-run"
+	// MAX_DELTA_OBJECT_SIZE: the base and the target of a delta upload are loaded in memory, larger objects are uploaded whole
+	MAX_DELTA_OBJECT_SIZE = 256 << 20
+)
+
 type MetadataOptions struct {
 	SparseDirs []string
 	DeepenFrom plumbing.Hash
 	Have       plumbing.Hash
 	Deepen     int
 	Depth      int
+	// Limit: max trees and fragments per response, the rest is returned as a cursor, 0 means no limit
+	Limit int
 }
 
 type SessionReader interface {
@@ -47,11 +69,13 @@ type SessionReader interface {
 type Transport interface {
 	// FetchReference: discover reference and remote repo info and caps
 	FetchReference(ctx context.Context, refname plumbing.ReferenceName) (*Reference, error)
+	// ListReferences: list all references of remote repo, requires CAPABILITY_LIST_REFERENCES
+	ListReferences(ctx context.Context) (*References, error)
 	// FetchMetadata: support base metadata and sparse metadata.
 	//  target: commit or tag
 	FetchMetadata(ctx context.Context, target plumbing.Hash, opts *MetadataOptions) (SessionReader, error)
-	// BatchMetadata: batch download metadata
-	BatchMetadata(ctx context.Context, oids []plumbing.Hash, depth int) (SessionReader, error)
+	// BatchMetadata: batch download metadata, limit: see MetadataOptions.Limit
+	BatchMetadata(ctx context.Context, oids []plumbing.Hash, depth int, limit int) (SessionReader, error)
 	// BatchObjects: batch download objects AKA blobs
 	BatchObjects(ctx context.Context, oids []plumbing.Hash) (SessionReader, error)
 	// GetObject: get large object, support Range feature
@@ -64,4 +88,13 @@ type Transport interface {
 	BatchCheck(ctx context.Context, refname plumbing.ReferenceName, haveObjects []*HaveObject) ([]*HaveObject, error)
 	// PutObject: upload large object to remote
 	PutObject(ctx context.Context, refname plumbing.ReferenceName, oid plumbing.Hash, r io.Reader, size int64) error
+	// ObjectsExists: check whether the objects (metadata or blobs) exist in remote, requires CAPABILITY_OBJECTS_EXISTS
+	ObjectsExists(ctx context.Context, oids []plumbing.Hash) ([]bool, error)
+	// PutDelta: upload large object as a delta against base, requires CAPABILITY_DELTA_OBJECTS
+	PutDelta(ctx context.Context, refname plumbing.ReferenceName, oid, base plumbing.Hash, r io.Reader, size int64) error
+	// SetDefaultBranch: change the default branch of remote repo, requires SUDO operation
+	SetDefaultBranch(ctx context.Context, branch string) (*DefaultBranchResponse, error)
+	// FetchTemplates: download the organization-wide templates, NotModified when their version is have,
+	// requires CAPABILITY_TEMPLATES
+	FetchTemplates(ctx context.Context, have string) (*Templates, error)
 }
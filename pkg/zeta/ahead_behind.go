@@ -0,0 +1,340 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/emirpasic/gods/trees/binaryheap"
+)
+
+const (
+	// defaultAheadBehindLimit: commits walked before the counts are reported as approximate
+	defaultAheadBehindLimit = 1000
+	aheadBehindCacheName    = "ahead-behind"
+	// aheadBehindCacheEntries: counts of the most recent (local, remote) pairs are kept
+	aheadBehindCacheEntries = 64
+)
+
+const (
+	reachableFromLocal = 1 << iota
+	reachableFromRemote
+	reachableFromBoth = reachableFromLocal | reachableFromRemote
+)
+
+// UpstreamStatus: the current branch compared to its upstream refs/remotes/origin/<branch>.
+type UpstreamStatus struct {
+	Branch   string        `json:"branch"`
+	Upstream string        `json:"upstream"`
+	Local    plumbing.Hash `json:"local"`
+	Remote   plumbing.Hash `json:"remote"`
+	Ahead    int           `json:"ahead"`
+	Behind   int           `json:"behind"`
+	// Approximate: the walk stopped after Limit commits, the counts are lower bounds
+	Approximate bool `json:"approximate,omitempty"`
+	Limit       int  `json:"limit,omitempty"`
+	// Skipped: --no-ahead-behind, only whether the branch and the upstream differ is known
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// Describe returns the git style description of the branch, eg: Your branch is ahead of 'origin/main' by 2 commits.
+func (us *UpstreamStatus) Describe() string {
+	switch {
+	case us.Local == us.Remote:
+		return fmt.Sprintf(W("Your branch is up to date with '%s'."), us.Upstream)
+	case us.Skipped:
+		return fmt.Sprintf(W("Your branch and '%s' refer to different commits."), us.Upstream)
+	case us.Approximate:
+		return fmt.Sprintf(W("Your branch and '%s' have diverged by %d+ commits (ahead %d+, behind %d+)."),
+			us.Upstream, us.Limit, us.Ahead, us.Behind)
+	case us.Behind == 0 && us.Ahead == 1:
+		return fmt.Sprintf(W("Your branch is ahead of '%s' by 1 commit."), us.Upstream)
+	case us.Behind == 0:
+		return fmt.Sprintf(W("Your branch is ahead of '%s' by %d commits."), us.Upstream, us.Ahead)
+	case us.Ahead == 0 && us.Behind == 1:
+		return fmt.Sprintf(W("Your branch is behind '%s' by 1 commit."), us.Upstream)
+	case us.Ahead == 0:
+		return fmt.Sprintf(W("Your branch is behind '%s' by %d commits."), us.Upstream, us.Behind)
+	}
+	return fmt.Sprintf(W("Your branch and '%s' have diverged, and have %d and %d different commits each."), us.Upstream, us.Ahead, us.Behind)
+}
+
+// paintHistory walks the history of the local and the remote tips at once in committer time order, like git. visit is
+// called for each commit reachable from only one side with its flag, the walk ends when every queued commit is
+// reachable from both sides, or when visit returns false, then the walk is incomplete. Missing parents (shallow
+// history) end their line of history.
+func paintHistory(ctx context.Context, b object.Backend, locals, remotes []*object.Commit, visit func(c *object.Commit, flag int) bool) (complete bool, err error) {
+	flags := make(map[plumbing.Hash]int)
+	for _, c := range locals {
+		flags[c.Hash] |= reachableFromLocal
+	}
+	for _, c := range remotes {
+		flags[c.Hash] |= reachableFromRemote
+	}
+	queued := make(map[plumbing.Hash]bool)
+	heap := binaryheap.NewWith(func(a, b any) int {
+		if a.(*object.Commit).Committer.When.Before(b.(*object.Commit).Committer.When) {
+			return 1
+		}
+		return -1
+	})
+	// active: queued commits which are not reachable from both sides
+	var active int
+	push := func(c *object.Commit) {
+		if queued[c.Hash] {
+			return
+		}
+		heap.Push(c)
+		queued[c.Hash] = true
+		if flags[c.Hash] != reachableFromBoth {
+			active++
+		}
+	}
+	for _, c := range locals {
+		push(c)
+	}
+	for _, c := range remotes {
+		push(c)
+	}
+	for active > 0 {
+		v, ok := heap.Pop()
+		if !ok {
+			break
+		}
+		c := v.(*object.Commit)
+		delete(queued, c.Hash)
+		f := flags[c.Hash]
+		if f != reachableFromBoth {
+			active--
+			if !visit(c, f) && active > 0 {
+				return false, nil
+			}
+		}
+		for _, h := range c.Parents {
+			old, seen := flags[h]
+			if seen {
+				if old|f != old {
+					flags[h] = old | f
+					if queued[h] && old|f == reachableFromBoth {
+						active--
+					}
+				}
+				continue
+			}
+			p, err := b.Commit(ctx, h)
+			if plumbing.IsNoSuchObject(err) {
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+			flags[h] = f
+			push(p)
+		}
+	}
+	return true, nil
+}
+
+// countAheadBehind counts the commits reachable from only one tip, once more than limit commits were counted the
+// walk stops and the counts are approximate.
+func countAheadBehind(ctx context.Context, b object.Backend, local, remote *object.Commit, limit int) (ahead, behind int, approximate bool, err error) {
+	complete, err := paintHistory(ctx, b, []*object.Commit{local}, []*object.Commit{remote}, func(c *object.Commit, flag int) bool {
+		if flag == reachableFromLocal {
+			ahead++
+		} else {
+			behind++
+		}
+		return limit <= 0 || ahead+behind <= limit
+	})
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return ahead, behind, !complete, nil
+}
+
+type aheadBehindCount struct {
+	local, remote plumbing.Hash
+	limit         int
+	ahead, behind int
+	approximate   bool
+}
+
+func (e *aheadBehindCount) String() string {
+	approximate := 0
+	if e.approximate {
+		approximate = 1
+	}
+	return fmt.Sprintf("%s %s %d %d %d %d", e.local, e.remote, e.limit, e.ahead, e.behind, approximate)
+}
+
+func parseAheadBehindCount(line string) (*aheadBehindCount, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 6 || !plumbing.ValidateHashHex(fields[0]) || !plumbing.ValidateHashHex(fields[1]) {
+		return nil, false
+	}
+	e := &aheadBehindCount{local: plumbing.NewHash(fields[0]), remote: plumbing.NewHash(fields[1])}
+	var err error
+	if e.limit, err = strconv.Atoi(fields[2]); err != nil {
+		return nil, false
+	}
+	if e.ahead, err = strconv.Atoi(fields[3]); err != nil {
+		return nil, false
+	}
+	if e.behind, err = strconv.Atoi(fields[4]); err != nil {
+		return nil, false
+	}
+	e.approximate = fields[5] == "1"
+	return e, true
+}
+
+// aheadBehindCache: counts keyed by (local tip, remote tip, limit), each line is:
+//
+//	<local> <remote> <limit> <ahead> <behind> <approximate>
+//
+// the commits of both tips never change, so the counts never become stale, only the most recent entries are kept.
+type aheadBehindCache struct {
+	path    string
+	entries []*aheadBehindCount
+}
+
+func (r *Repository) openAheadBehindCache() *aheadBehindCache {
+	c := &aheadBehindCache{path: filepath.Join(r.zetaDir, "cache", aheadBehindCacheName)}
+	fd, err := os.Open(c.path)
+	if err != nil {
+		return c
+	}
+	defer fd.Close() // nolint
+	sr := bufio.NewScanner(fd)
+	for sr.Scan() {
+		if e, ok := parseAheadBehindCount(sr.Text()); ok {
+			c.entries = append(c.entries, e)
+		}
+	}
+	return c
+}
+
+func (c *aheadBehindCache) lookup(local, remote plumbing.Hash, limit int) *aheadBehindCount {
+	for _, e := range c.entries {
+		if e.local == local && e.remote == remote && e.limit == limit {
+			return e
+		}
+	}
+	return nil
+}
+
+// store appends the counts, the cache is only an optimization so errors are ignored.
+func (c *aheadBehindCache) store(e *aheadBehindCount) {
+	c.entries = append(c.entries, e)
+	if len(c.entries) > aheadBehindCacheEntries {
+		c.entries = c.entries[len(c.entries)-aheadBehindCacheEntries:]
+	}
+	var b strings.Builder
+	for _, e := range c.entries {
+		b.WriteString(e.String())
+		b.WriteByte('\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return
+	}
+	// write then rename, concurrent status commands never read a partial cache
+	tmp := c.path + ".lock"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		_ = os.Remove(tmp)
+	}
+}
+
+// aheadBehind: status.aheadBehind, -X status.aheadBehind=false or --no-ahead-behind disables counting.
+func (r *Repository) aheadBehind() bool {
+	if s, ok := getStringFromValues("status.aheadBehind", r.values); ok && len(s) > 0 {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return !r.Status.AheadBehind.False()
+}
+
+func (r *Repository) aheadBehindLimit() int {
+	if s, ok := getStringFromValues("status.aheadBehindLimit", r.values); ok && len(s) > 0 {
+		if limit, err := strconv.Atoi(s); err == nil && limit > 0 {
+			return limit
+		}
+	}
+	if r.Status.AheadBehindLimit > 0 {
+		return r.Status.AheadBehindLimit
+	}
+	return defaultAheadBehindLimit
+}
+
+// UpstreamStatus compares the current branch with refs/remotes/origin/<branch>, nil when HEAD is detached or the
+// branch has no upstream.
+func (r *Repository) UpstreamStatus(ctx context.Context) (*UpstreamStatus, error) {
+	current, err := r.Current()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !current.Name().IsBranch() {
+		return nil, nil
+	}
+	branch := current.Name().BranchName()
+	upstream, err := r.Reference(plumbing.NewRemoteReferenceName(plumbing.Origin, branch))
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	us := &UpstreamStatus{
+		Branch:   branch,
+		Upstream: plumbing.Origin + "/" + branch,
+		Local:    current.Hash(),
+		Remote:   upstream.Hash(),
+	}
+	if us.Local == us.Remote {
+		return us, nil
+	}
+	if !r.aheadBehind() {
+		us.Skipped = true
+		return us, nil
+	}
+	limit := r.aheadBehindLimit()
+	cache := r.openAheadBehindCache()
+	if e := cache.lookup(us.Local, us.Remote, limit); e != nil {
+		us.Ahead, us.Behind, us.Approximate = e.ahead, e.behind, e.approximate
+		if us.Approximate {
+			us.Limit = limit
+		}
+		return us, nil
+	}
+	local, err := r.odb.Commit(ctx, us.Local)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := r.odb.Commit(ctx, us.Remote)
+	if err != nil {
+		return nil, err
+	}
+	if us.Ahead, us.Behind, us.Approximate, err = countAheadBehind(ctx, r.odb, local, remote, limit); err != nil {
+		return nil, err
+	}
+	if us.Approximate {
+		us.Limit = limit
+	}
+	cache.store(&aheadBehindCount{local: us.Local, remote: us.Remote, limit: limit, ahead: us.Ahead, behind: us.Behind, approximate: us.Approximate})
+	return us, nil
+}
@@ -0,0 +1,61 @@
+package zeta
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+func TestCountAheadBehind(t *testing.T) {
+	r, f := newTestRepository(t, "ahead-behind")
+	// initial <- base <- a1 <- a2 <- merge (local), base <- b1 <- b2 <- b3 (remote), merge also merges b1, unrelated root
+	base := f.commit("base\n", nil, f.commit("initial\n", nil))
+	b1 := f.commit("b1\n", nil, base)
+	merge := f.commit("merge\n", nil, f.commit("a2\n", nil, f.commit("a1\n", nil, base)), b1)
+	b3 := f.commit("b3\n", nil, f.commit("b2\n", nil, b1))
+	root := f.commit("root\n", nil)
+	tests := []struct {
+		local, remote plumbing.Hash
+		limit         int
+		want          string
+	}{
+		{merge, base, 0, "4 0 false"},
+		{base, merge, 0, "0 4 false"},
+		{merge, b3, 0, "3 2 false"},
+		{merge, root, 0, "6 1 false"},
+		{merge, b3, 2, "1 2 true"},
+		{merge, b3, 5, "3 2 false"},
+	}
+	for _, tt := range tests {
+		local, remote := f.object(tt.local), f.object(tt.remote)
+		ahead, behind, approximate, err := countAheadBehind(t.Context(), r.odb, local, remote, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(ahead, behind, approximate); got != tt.want {
+			t.Errorf("countAheadBehind(%s, %s, %d) = %s; want %s", local.Message, remote.Message, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestAheadBehindCache(t *testing.T) {
+	r := &Repository{zetaDir: t.TempDir()}
+	local, remote := plumbing.NewHash("1111111111111111111111111111111111111111111111111111111111111111"), plumbing.NewHash("2222222222222222222222222222222222222222222222222222222222222222")
+	c := r.openAheadBehindCache()
+	if e := c.lookup(local, remote, 1000); e != nil {
+		t.Fatalf("unexpected entry %s in empty cache", e)
+	}
+	c.store(&aheadBehindCount{local: local, remote: remote, limit: 1000, ahead: 1000, behind: 0, approximate: true})
+	for i := range aheadBehindCacheEntries - 1 {
+		c.store(&aheadBehindCount{local: local, remote: remote, limit: i + 1})
+	}
+	e := r.openAheadBehindCache().lookup(local, remote, 1000)
+	if e == nil || e.ahead != 1000 || !e.approximate {
+		t.Fatalf("unexpected cached entry %v", e)
+	}
+	c.store(&aheadBehindCount{local: local, remote: remote, limit: 2000})
+	if e := r.openAheadBehindCache().lookup(local, remote, 1000); e != nil {
+		t.Fatalf("oldest entry %s should be dropped", e)
+	}
+}
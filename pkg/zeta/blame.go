@@ -7,11 +7,14 @@ import (
 	"bytes"
 	"container/heap"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 	"unicode/utf8"
 
@@ -571,3 +574,44 @@ func blobHash(ctx context.Context, path string, commit *object.Commit) (plumbing
 	}
 	return file.Hash, nil
 }
+
+type BlameOptions struct {
+	Revision  string
+	Path      string
+	NoMailmap bool
+	JSON      bool
+}
+
+// Blame shows what revision and author last modified each line of a file.
+func (r *Repository) Blame(ctx context.Context, opts *BlameOptions) error {
+	if len(opts.Revision) == 0 {
+		opts.Revision = "HEAD"
+	}
+	oid, err := r.Revision(ctx, opts.Revision)
+	if err != nil {
+		dieln(err)
+		return err
+	}
+	cc, err := r.odb.ParseRevExhaustive(ctx, oid)
+	if err != nil {
+		die_error("open commit '%s' error: %v", opts.Revision, err)
+		return err
+	}
+	result, err := Blame(ctx, cc, opts.Path)
+	if err != nil {
+		die_error("blame '%s' error: %v", opts.Path, err)
+		return err
+	}
+	if !opts.NoMailmap {
+		mailmapBlame(r.LoadMailmap(), result)
+	}
+	if opts.JSON {
+		return json.NewEncoder(os.Stdout).Encode(result)
+	}
+	p := NewPrinter(ctx)
+	defer p.Close() // nolint
+	if _, err := io.WriteString(p, result.String()); err != nil && !errors.Is(err, syscall.EPIPE) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,472 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/tr"
+)
+
+const (
+	cacheStatsName = "blob-stats"
+	// cacheStatsLockTimeout: commands update the stats when they exit, an update waiting longer is dropped
+	cacheStatsLockTimeout = time.Second
+	// cacheStatsLockStale: the lock is held for a read and a write, an older lock was left by a crashed process
+	cacheStatsLockStale = 10 * time.Second
+)
+
+var (
+	// cacheHits, cacheMisses, cacheEvictions: blob cache of this process, exported by the metrics of the command
+	cacheHits      atomic.Int64
+	cacheMisses    atomic.Int64
+	cacheEvictions atomic.Int64
+)
+
+// parseCacheSize: size budget of the loose blobs, 0 is unlimited.
+func parseCacheSize(cfg *config.Config, values map[string]StringArray) int64 {
+	if s, ok := getFromValueOrEnv("core.cacheSize", ENV_ZETA_CORE_CACHE_SIZE, values); ok {
+		if size, err := strengthen.ParseSize(s); err == nil && size >= 0 {
+			return size
+		}
+	}
+	return max(int64(cfg.Core.CacheSize), 0)
+}
+
+func (r *Repository) cacheSize() int64 {
+	return parseCacheSize(r.Config, r.values)
+}
+
+// CacheStats: blob cache metrics accumulated by the commands run in the repository since Since.
+type CacheStats struct {
+	backend.CacheMetrics
+	Since time.Time `json:"since"`
+}
+
+func (r *Repository) cacheStatsPath() string {
+	return filepath.Join(r.zetaDir, "cache", cacheStatsName)
+}
+
+func (r *Repository) loadCacheStats() *CacheStats {
+	s := &CacheStats{}
+	if data, err := os.ReadFile(r.cacheStatsPath()); err == nil {
+		_ = json.Unmarshal(data, s)
+	}
+	if s.Since.IsZero() {
+		s.Since = time.Now()
+	}
+	return s
+}
+
+// lockCacheStats takes the lock of the stats, commands of the repository update the stats concurrently when they
+// exit. Call the returned function to release the lock.
+func (r *Repository) lockCacheStats() (func(), error) {
+	lockName := r.cacheStatsPath() + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockName), 0755); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(cacheStatsLockTimeout)
+	for {
+		fd, err := os.OpenFile(lockName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_ = fd.Close()
+			return func() {
+				_ = os.Remove(lockName)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if si, err := os.Stat(lockName); err == nil && time.Since(si.ModTime()) > cacheStatsLockStale {
+			_ = os.Remove(lockName)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// addCacheStats adds m to the stats of the repository under the lock of the stats.
+func (r *Repository) addCacheStats(m *backend.CacheMetrics) error {
+	unlock, err := r.lockCacheStats()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	s := r.loadCacheStats()
+	s.Add(m)
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	p := r.cacheStatsPath()
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// resetCacheStats removes the stats of the repository, the metrics of this process are not recorded afterwards.
+func (r *Repository) resetCacheStats() error {
+	unlock, err := r.lockCacheStats()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := os.Remove(r.cacheStatsPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	r.cacheStatsReset = true
+	return nil
+}
+
+// recordCacheMetrics adds the metrics of this process to the stats of the repository, the stats are only
+// informational so errors are ignored.
+func (r *Repository) recordCacheMetrics() {
+	m := r.odb.CacheMetrics()
+	if m == nil || *m == (backend.CacheMetrics{}) {
+		return
+	}
+	cacheHits.Add(m.Hits)
+	cacheMisses.Add(m.Misses)
+	cacheEvictions.Add(m.Evictions)
+	if r.cacheStatsReset {
+		return
+	}
+	_ = r.addCacheStats(m)
+}
+
+// cachePins: blobs which are never evicted.
+type cachePins struct {
+	*sharedObjects
+	keep func(oid plumbing.Hash) bool
+}
+
+func (p *cachePins) pinned(oid plumbing.Hash) bool {
+	return p.blobs[oid] || p.keep(oid)
+}
+
+// changes pins the blobs of the tree which are not in the tree of the parent.
+func (p *cachePins) changes(ctx context.Context, oid, parent plumbing.Hash) error {
+	if oid == parent {
+		return nil
+	}
+	if parent.IsZero() {
+		return p.tree(ctx, oid)
+	}
+	t, err := p.o.Tree(ctx, oid)
+	if plumbing.IsNoSuchObject(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	pt, err := p.o.Tree(ctx, parent)
+	if plumbing.IsNoSuchObject(err) {
+		return p.tree(ctx, oid)
+	}
+	if err != nil {
+		return err
+	}
+	entries := make(map[string]*object.TreeEntry, len(pt.Entries))
+	for _, e := range pt.Entries {
+		entries[e.Name] = e
+	}
+	for _, e := range t.Entries {
+		pe := entries[e.Name]
+		if pe != nil && pe.Hash == e.Hash {
+			continue
+		}
+		switch e.Type() {
+		case object.TreeObject:
+			var base plumbing.Hash
+			if pe != nil && pe.Type() == object.TreeObject {
+				base = pe.Hash
+			}
+			if err := p.changes(ctx, e.Hash, base); err != nil {
+				return err
+			}
+		case object.FragmentsObject:
+			if err := p.fragments(ctx, e.Hash); err != nil {
+				return err
+			}
+		default:
+			p.blobs[e.Hash] = true
+		}
+	}
+	return nil
+}
+
+// commitTips appends the commits of oids to tips, tags are peeled and missing commits are skipped.
+func (r *Repository) commitTips(ctx context.Context, tips []*object.Commit, oids ...plumbing.Hash) ([]*object.Commit, error) {
+	for _, oid := range oids {
+		if oid.IsZero() {
+			continue
+		}
+		cc, err := r.odb.ParseRevExhaustive(ctx, oid)
+		if plumbing.IsNoSuchObject(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tips = append(tips, cc)
+	}
+	return tips, nil
+}
+
+// localCommits returns the commits not reachable from any remote reference or tag: commits created locally cannot be
+// fetched again. Local tips are HEAD, the other references and their reflogs.
+func (r *Repository) localCommits(ctx context.Context) ([]*object.Commit, error) {
+	db, err := r.References()
+	if err != nil {
+		return nil, err
+	}
+	var locals, remotes []*object.Commit
+	names := []plumbing.ReferenceName{plumbing.HEAD}
+	for _, ref := range db.References() {
+		if ref.Type() != plumbing.HashReference {
+			continue
+		}
+		if ref.Name().IsRemote() || ref.Name().IsTag() {
+			if remotes, err = r.commitTips(ctx, remotes, ref.Hash()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if locals, err = r.commitTips(ctx, locals, ref.Hash()); err != nil {
+			return nil, err
+		}
+		names = append(names, ref.Name())
+	}
+	if head := db.HEAD(); head != nil && head.Type() == plumbing.HashReference {
+		if locals, err = r.commitTips(ctx, locals, head.Hash()); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range names {
+		if !r.rdb.Exists(name) {
+			continue
+		}
+		o, err := r.rdb.Read(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range o.Entries {
+			if locals, err = r.commitTips(ctx, locals, e.O, e.N); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(locals) == 0 {
+		return nil, nil
+	}
+	commits := make([]*object.Commit, 0, 10)
+	if _, err := paintHistory(ctx, r.odb, locals, remotes, func(c *object.Commit, flag int) bool {
+		if flag == reachableFromLocal {
+			commits = append(commits, c)
+		}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// cachePins returns the blobs retained by eviction: blobs of HEAD and of the index, the keep list, and blobs
+// changed by local commits.
+func (r *Repository) cachePins(ctx context.Context) (*cachePins, error) {
+	var head plumbing.Hash
+	if current, err := r.Current(); err == nil {
+		head = current.Hash()
+	}
+	keep, err := r.keepFunc(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+	p := &cachePins{
+		sharedObjects: &sharedObjects{
+			o:       r.odb,
+			commits: make(map[plumbing.Hash]bool),
+			trees:   make(map[plumbing.Hash]bool),
+			blobs:   make(map[plumbing.Hash]bool),
+		},
+		keep: keep,
+	}
+	if !head.IsZero() {
+		cc, err := r.odb.ParseRevExhaustive(ctx, head)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.tree(ctx, cc.Tree); err != nil {
+			return nil, err
+		}
+	}
+	idx, err := r.odb.Index()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range idx.Entries {
+		if err := p.object(ctx, e.Hash); err != nil {
+			return nil, err
+		}
+	}
+	commits, err := r.localCommits(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range commits {
+		var parent plumbing.Hash
+		if len(c.Parents) != 0 {
+			if pc, err := r.odb.Commit(ctx, c.Parents[0]); err == nil {
+				parent = pc.Tree
+			} else if !plumbing.IsNoSuchObject(err) {
+				return nil, err
+			}
+		}
+		if err := p.changes(ctx, c.Tree, parent); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// evictCache evicts the least recently used unpinned blobs until the loose blobs are not larger than limit.
+func (r *Repository) evictCache(ctx context.Context, limit int64) ([]plumbing.Hash, int64, error) {
+	if len(r.odb.SharingRoot()) != 0 {
+		return nil, 0, backend.ErrSharedCache
+	}
+	p, err := r.cachePins(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return r.odb.EvictBlobs(ctx, limit, p.pinned)
+}
+
+// enforceCacheSize evicts blobs when the loose blobs exceed core.cacheSize, blobs of the sharing root are left to
+// zeta shared-gc.
+func (r *Repository) enforceCacheSize(ctx context.Context) error {
+	budget := r.cacheSize()
+	if budget == 0 || len(r.odb.SharingRoot()) != 0 {
+		return nil
+	}
+	u, err := r.odb.CacheUsage(nil)
+	if err != nil || u.Size <= budget {
+		return err
+	}
+	oids, size, err := r.evictCache(ctx, budget)
+	if err != nil {
+		return err
+	}
+	if len(oids) != 0 && !r.quiet {
+		_, _ = tr.Fprintf(os.Stderr, "cache: evicted %d blobs (%s) to stay within core.cacheSize %s\n", len(oids), strengthen.FormatSize(size), strengthen.FormatSize(budget))
+	}
+	return nil
+}
+
+type CacheStatsOptions struct {
+	JSON bool
+}
+
+// CacheStats shows the budget, the usage and the metrics of the blob cache.
+func (r *Repository) CacheStats(ctx context.Context, opts *CacheStatsOptions) error {
+	p, err := r.cachePins(ctx)
+	if err != nil {
+		die_error("pinned blobs: %v", err)
+		return err
+	}
+	u, err := r.odb.CacheUsage(p.pinned)
+	if err != nil {
+		die_error("cache usage: %v", err)
+		return err
+	}
+	s := r.loadCacheStats()
+	budget := r.cacheSize()
+	if opts.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(&struct {
+			Budget      int64     `json:"budget"`
+			Objects     int       `json:"objects"`
+			Size        int64     `json:"size"`
+			Pinned      int       `json:"pinned"`
+			PinnedSize  int64     `json:"pinned_size"`
+			Shared      bool      `json:"shared,omitempty"`
+			Hits        int64     `json:"hits"`
+			Misses      int64     `json:"misses"`
+			Evictions   int64     `json:"evictions"`
+			EvictedSize int64     `json:"evicted_size"`
+			Since       time.Time `json:"since"`
+		}{
+			Budget: budget, Objects: u.Objects, Size: u.Size, Pinned: u.Pinned, PinnedSize: u.PinnedSize, Shared: len(r.odb.SharingRoot()) != 0,
+			Hits: s.Hits, Misses: s.Misses, Evictions: s.Evictions, EvictedSize: s.EvictedSize, Since: s.Since,
+		})
+	}
+	if budget == 0 {
+		fmt.Fprintf(os.Stdout, "%-12s %s\n", W("budget:"), W("unlimited"))
+	} else {
+		fmt.Fprintf(os.Stdout, "%-12s %s\n", W("budget:"), strengthen.FormatSize(budget))
+	}
+	fmt.Fprintf(os.Stdout, "%-12s %d (%s)\n", W("blobs:"), u.Objects, strengthen.FormatSize(u.Size))
+	fmt.Fprintf(os.Stdout, "%-12s %d (%s)\n", W("pinned:"), u.Pinned, strengthen.FormatSize(u.PinnedSize))
+	fmt.Fprintf(os.Stdout, "%-12s %d\n", W("hits:"), s.Hits)
+	fmt.Fprintf(os.Stdout, "%-12s %d\n", W("misses:"), s.Misses)
+	fmt.Fprintf(os.Stdout, "%-12s %d (%s)\n", W("evictions:"), s.Evictions, strengthen.FormatSize(s.EvictedSize))
+	fmt.Fprintf(os.Stdout, W("Collected since %s\n"), s.Since.Format(time.RFC3339))
+	if len(r.odb.SharingRoot()) != 0 {
+		fmt.Fprintf(os.Stdout, W("Blobs are stored in the sharing root '%s' and are not evicted, use 'zeta shared-gc'\n"), r.odb.SharingRoot())
+	}
+	return nil
+}
+
+type ClearCacheOptions struct {
+	ResetStats bool
+}
+
+// ClearCache evicts every unpinned loose blob, evicted blobs are fetched again when needed.
+func (r *Repository) ClearCache(ctx context.Context, opts *ClearCacheOptions) error {
+	unlock, err := r.lock("gc")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	oids, size, err := r.evictCache(ctx, 0)
+	if errors.Is(err, backend.ErrSharedCache) {
+		die_error("blobs are stored in the sharing root '%s', use 'zeta shared-gc'", r.odb.SharingRoot())
+		return err
+	}
+	if err != nil {
+		die_error("evict blobs: %v", err)
+		return err
+	}
+	if opts.ResetStats {
+		if err := r.resetCacheStats(); err != nil {
+			die_error("reset cache stats: %v", err)
+			return err
+		}
+	}
+	if !r.quiet {
+		fmt.Fprintf(os.Stderr, W("Evicted %d blobs (%s)\n"), len(oids), strengthen.FormatSize(size))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package zeta
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+)
+
+func TestCachePins(t *testing.T) {
+	ctx := t.Context()
+	r, f := newTestRepository(t, "cache")
+	blob := func(content string) plumbing.Hash {
+		oid, err := r.odb.HashTo(ctx, strings.NewReader(content), int64(len(content)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return oid
+	}
+	// base is on the remote, local1 and local2 were not pushed
+	base := f.commit("a1", map[string]string{"a.txt": "a1", "dir/b.txt": "b"})
+	local2 := f.commit("a3", map[string]string{"a.txt": "a3"}, f.commit("a2", map[string]string{"a.txt": "a2"}, base))
+	sig := f.signature()
+	if err := r.UpdateRef(ctx, plumbing.NewRemoteReferenceName(plumbing.Origin, "mainline"), plumbing.ZeroHash, base, &sig, "fetch"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.UpdateRef(ctx, plumbing.NewBranchReferenceName("mainline"), plumbing.ZeroHash, local2, &sig, "commit"); err != nil {
+		t.Fatal(err)
+	}
+	blobs := make(map[string]plumbing.Hash)
+	for _, content := range []string{"a1", "a2", "a3", "b", "orphan"} {
+		blobs[content] = blob(content)
+	}
+	p, err := r.cachePins(ctx)
+	if err != nil {
+		t.Fatalf("cache pins error: %v", err)
+	}
+	for content, pinned := range map[string]bool{"a1": false, "a2": true, "a3": true, "b": true, "orphan": false} {
+		if p.pinned(blobs[content]) != pinned {
+			t.Errorf("blob %q pinned: %v", content, !pinned)
+		}
+	}
+	oids, _, err := r.evictCache(ctx, 0)
+	if err != nil {
+		t.Fatalf("evict error: %v", err)
+	}
+	slices.SortFunc(oids, func(a, b plumbing.Hash) int { return strings.Compare(a.String(), b.String()) })
+	want := []plumbing.Hash{blobs["a1"], blobs["orphan"]}
+	slices.SortFunc(want, func(a, b plumbing.Hash) int { return strings.Compare(a.String(), b.String()) })
+	if !slices.Equal(oids, want) {
+		t.Fatalf("evicted %v, want %v", oids, want)
+	}
+}
+
+func TestCacheStatsRecord(t *testing.T) {
+	ctx := t.Context()
+	worktree := filepath.Join(t.TempDir(), "cache")
+	r, err := Init(ctx, &InitOptions{Worktree: worktree, Branch: "mainline", Quiet: true})
+	if err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	// commands exiting at the same time
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			if err := r.addCacheStats(&backend.CacheMetrics{Hits: 1, Misses: 2}); err != nil {
+				t.Errorf("add cache stats error: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if s := r.loadCacheStats(); s.Hits != 20 || s.Misses != 40 {
+		t.Fatalf("lost updates: hits %d misses %d", s.Hits, s.Misses)
+	}
+	if _, err := os.Stat(r.cacheStatsPath() + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("stats lock not released: %v", err)
+	}
+
+	content := "evicted"
+	if _, err := r.odb.HashTo(ctx, strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ClearCache(ctx, &ClearCacheOptions{ResetStats: true}); err != nil {
+		t.Fatalf("clear cache error: %v", err)
+	}
+	if m := r.odb.CacheMetrics(); m == nil || m.Evictions == 0 {
+		t.Fatalf("expected evictions of clear cache: %v", m)
+	}
+	// the evictions of the clear itself must not undo the reset
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(r.cacheStatsPath()); !os.IsNotExist(err) {
+		t.Fatalf("stats recorded after reset: %v", err)
+	}
+}
@@ -38,6 +38,8 @@ type CatOptions struct {
 	Textconv  bool
 	Direct    bool
 	Output    string
+	// Save: keep the objects resolved from the remote in the local ODB
+	Save bool
 	// Image controls inline image rendering for binary blobs.
 	// Empty string is treated as "auto" (honour detected terminal capability).
 	// See term.ParseImageMode for accepted values.
@@ -85,12 +87,22 @@ func (r *Repository) fetchMissingBlob(ctx context.Context, o *promiseObject) err
 }
 
 func (r *Repository) catMissingObject(ctx context.Context, o *promiseObject) (*object.Blob, error) {
+	if o.remote != nil {
+		return o.remote.Blob(ctx, o.oid)
+	}
 	if err := r.fetchMissingBlob(ctx, o); err != nil {
 		return nil, err
 	}
 	return r.odb.Blob(ctx, o.oid)
 }
 
+func (r *Repository) promisedObject(ctx context.Context, o *promiseObject) (any, error) {
+	if o.remote != nil {
+		return o.remote.Object(ctx, o.oid)
+	}
+	return r.odb.Object(ctx, o.oid)
+}
+
 func objectSize(a object.Encoder) int {
 	var b bytes.Buffer
 	_ = a.Encode(&b)
@@ -100,7 +112,7 @@ func objectSize(a object.Encoder) int {
 func (r *Repository) printSize(ctx context.Context, opts *CatOptions, o *promiseObject) error {
 	var a any
 	var err error
-	if a, err = r.odb.Object(ctx, o.oid); err == nil {
+	if a, err = r.promisedObject(ctx, o); err == nil {
 		if v, ok := a.(object.Encoder); !ok {
 			return opts.Println(objectSize(v))
 		}
@@ -120,8 +132,11 @@ func (r *Repository) printSize(ctx context.Context, opts *CatOptions, o *promise
 }
 
 func (r *Repository) printType(ctx context.Context, opts *CatOptions, o *promiseObject) error {
-	a, err := r.odb.Object(ctx, o.oid)
+	a, err := r.promisedObject(ctx, o)
 	if plumbing.IsNoSuchObject(err) {
+		if o.remote != nil {
+			return opts.Println("blob")
+		}
 		if err := r.fetchMissingBlob(ctx, o); err == nil {
 			return opts.Println("blob")
 		}
@@ -212,7 +227,7 @@ func (r *Repository) catBlob(ctx context.Context, opts *CatOptions, o *promiseOb
 	return nil
 }
 
-func (r *Repository) catFragments(ctx context.Context, opts *CatOptions, ff *object.Fragments) error {
+func (r *Repository) catFragments(ctx context.Context, opts *CatOptions, ff *object.Fragments, remote *remoteObjects) error {
 	objects := make([]*object.Blob, 0, len(ff.Entries))
 	defer func() {
 		for _, o := range objects {
@@ -221,7 +236,7 @@ func (r *Repository) catFragments(ctx context.Context, opts *CatOptions, ff *obj
 	}()
 	readers := make([]io.Reader, 0, len(ff.Entries))
 	for _, e := range ff.Entries {
-		o, err := r.catMissingObject(ctx, &promiseObject{oid: e.Hash, size: int64(e.Size)})
+		o, err := r.catMissingObject(ctx, &promiseObject{oid: e.Hash, size: int64(e.Size), remote: remote})
 		if err != nil {
 			return err
 		}
@@ -262,7 +277,7 @@ func (r *Repository) catObject(ctx context.Context, opts *CatOptions, o *promise
 	if opts.Type {
 		return r.printType(ctx, opts, o)
 	}
-	a, err := r.odb.Object(ctx, o.oid)
+	a, err := r.promisedObject(ctx, o)
 	if plumbing.IsNoSuchObject(err) {
 		return catShowError(o.oid.String(), r.catBlob(ctx, opts, o))
 	}
@@ -288,7 +303,7 @@ func (r *Repository) catObject(ctx context.Context, opts *CatOptions, o *promise
 	if opts.Direct {
 		// only fragments support direct read
 		if ff, ok := a.(*object.Fragments); ok {
-			return r.catFragments(ctx, opts, ff)
+			return r.catFragments(ctx, opts, ff, o.remote)
 		}
 	}
 	fd, termLevel, err := opts.NewFD()
@@ -309,13 +324,16 @@ func (r *Repository) catBranchOrTag(ctx context.Context, opts *CatOptions, branc
 }
 
 func (r *Repository) Cat(ctx context.Context, opts *CatOptions) error {
-	k, v, ok := strings.Cut(opts.Object, ":")
+	k, v, ok := splitRevisionPath(opts.Object)
 	if !ok {
 		return r.catBranchOrTag(ctx, opts, k)
 	}
 	if len(k) == 0 {
 		k = string(plumbing.HEAD) // default --> HEAD
 	}
+	if _, _, err := r.parseObject(ctx, k+":"+v); r.remoteFallback(opts.Object, err) {
+		return r.catRemote(ctx, opts)
+	}
 	oid, err := r.Revision(ctx, k)
 	if err != nil {
 		return catShowError(k, err)
@@ -371,3 +389,16 @@ func (r *Repository) Cat(ctx context.Context, opts *CatOptions) error {
 	}
 	return r.catObject(ctx, opts, &promiseObject{oid: oid})
 }
+
+// catRemote: the revision or the trees on the way are missing locally, resolve the object from the remote.
+func (r *Repository) catRemote(ctx context.Context, opts *CatOptions) error {
+	rs, err := r.newRemoteObjects(ctx, opts.Save)
+	if err != nil {
+		return catShowError(opts.Object, err)
+	}
+	e, err := rs.parseEntry(ctx, opts.Object)
+	if err != nil {
+		return catShowError(opts.Object, err)
+	}
+	return r.catObject(ctx, opts, &promiseObject{oid: e.Hash, size: e.Size, remote: rs})
+}
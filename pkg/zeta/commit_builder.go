@@ -0,0 +1,252 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+// CommitBuilder builds commits without a worktree or index, eg: dependency bumpers and codegen bots. Only the trees on
+// the way to the changed paths are read and written, so the cost does not depend on the size of the repository.
+//
+//	b := r.NewCommitBuilder(parent.Tree)
+//	_, _ = b.WriteBlob(ctx, "go.mod", strings.NewReader(content), int64(len(content)), filemode.Regular)
+//	_ = b.Remove("vendor/old")
+//	newRev, _ := b.Commit(ctx, &CommitTreeOptions{Parents: []plumbing.Hash{parentRev}, Message: "bump deps"})
+//	_ = r.UpdateRef(ctx, refname, parentRev, newRev, nil, "bot: bump deps")
+//
+// The result only depends on the base tree, the changes and the options: the order of the changes does not matter and
+// the same signatures produce the same commit. The builder can be used again to build the next commit.
+type CommitBuilder struct {
+	r     *Repository
+	base  plumbing.Hash
+	edits map[string]*object.TreeEntry // nil removes the path
+}
+
+// NewCommitBuilder returns a builder changing the tree base, zero base starts from an empty tree.
+func (r *Repository) NewCommitBuilder(base plumbing.Hash) *CommitBuilder {
+	return &CommitBuilder{r: r, base: base, edits: make(map[string]*object.TreeEntry)}
+}
+
+// cleanBuilderPath: paths are relative and slash separated, '..' and .zeta are rejected.
+func cleanBuilderPath(p string) (string, error) {
+	for s := range strings.SplitSeq(p, "/") {
+		if s == ".." || s == ".zeta" {
+			return "", fmt.Errorf("bad path '%s'", p)
+		}
+	}
+	cleaned := strings.Trim(path.Clean("/"+p), "/")
+	if len(cleaned) == 0 {
+		return "", fmt.Errorf("bad path '%s'", p)
+	}
+	return cleaned, nil
+}
+
+// WriteBlob stores the contents of reader and adds it at p, large files are stored as fragments.
+func (b *CommitBuilder) WriteBlob(ctx context.Context, p string, reader io.Reader, size int64, mode filemode.FileMode) (plumbing.Hash, error) {
+	p, err := cleanBuilderPath(p)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if mode != filemode.Regular && mode != filemode.Executable {
+		return plumbing.ZeroHash, fmt.Errorf("bad mode %s for blob '%s'", mode, p)
+	}
+	oid, fragments, err := b.r.HashTo(ctx, reader, size)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if fragments {
+		mode |= filemode.Fragments
+	}
+	b.edits[p] = &object.TreeEntry{Name: path.Base(p), Mode: mode, Hash: oid}
+	return oid, nil
+}
+
+// Add adds an existing object at p: a blob, fragments, a symlink or a tree. Trees and fragments must exist locally,
+// blobs may only exist on the remote in a partial clone.
+func (b *CommitBuilder) Add(ctx context.Context, p string, oid plumbing.Hash, mode filemode.FileMode) error {
+	p, err := cleanBuilderPath(p)
+	if err != nil {
+		return err
+	}
+	if mode.IsMalformed() || mode == filemode.Empty || mode == filemode.Submodule {
+		return fmt.Errorf("bad mode %s for '%s'", mode, p)
+	}
+	switch {
+	case mode == filemode.Dir:
+		if _, err := b.r.odb.Tree(ctx, oid); err != nil {
+			return fmt.Errorf("tree %s for '%s': %w", oid, p, err)
+		}
+	case mode.IsFragments():
+		if _, err := b.r.odb.Fragments(ctx, oid); err != nil {
+			return fmt.Errorf("fragments %s for '%s': %w", oid, p, err)
+		}
+	}
+	b.edits[p] = &object.TreeEntry{Name: path.Base(p), Mode: mode, Hash: oid}
+	return nil
+}
+
+// Remove removes the file or dir at p, removing a path that does not exist is not an error.
+func (b *CommitBuilder) Remove(p string) error {
+	p, err := cleanBuilderPath(p)
+	if err != nil {
+		return err
+	}
+	b.edits[p] = nil
+	return nil
+}
+
+type treeEdit struct {
+	path  string
+	entry *object.TreeEntry
+}
+
+// editTree applies the edits sorted by path to the tree base and returns the new tree, zero when the tree is empty.
+func (b *CommitBuilder) editTree(ctx context.Context, base plumbing.Hash, edits []*treeEdit) (plumbing.Hash, error) {
+	entries := make(map[string]*object.TreeEntry)
+	if !base.IsZero() && base != plumbing.EmptyTree {
+		t, err := b.r.odb.Tree(ctx, base)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		for _, e := range t.Entries {
+			entries[e.Name] = e
+		}
+	}
+	files := make(map[string]bool) // files added by the edits
+	for i := 0; i < len(edits); {
+		name, _, ok := strings.Cut(edits[i].path, "/")
+		if !ok {
+			if edits[i].entry == nil {
+				delete(entries, name)
+			} else {
+				entries[name] = edits[i].entry
+				files[name] = edits[i].entry.Mode != filemode.Dir
+			}
+			i++
+			continue
+		}
+		if files[name] {
+			return plumbing.ZeroHash, fmt.Errorf("'%s' is both a file and a dir", name)
+		}
+		var sub []*treeEdit
+		for ; i < len(edits); i++ {
+			d, rest, ok := strings.Cut(edits[i].path, "/")
+			if !ok || d != name {
+				break
+			}
+			sub = append(sub, &treeEdit{path: rest, entry: edits[i].entry})
+		}
+		// a file of the base tree is replaced by the dir
+		var child plumbing.Hash
+		if e, ok := entries[name]; ok && e.Mode == filemode.Dir {
+			child = e.Hash
+		}
+		oid, err := b.editTree(ctx, child, sub)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if oid.IsZero() {
+			delete(entries, name)
+			continue
+		}
+		entries[name] = &object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: oid}
+	}
+	if len(entries) == 0 {
+		return plumbing.ZeroHash, nil
+	}
+	tree := &object.Tree{Entries: make([]*object.TreeEntry, 0, len(entries))}
+	for _, e := range entries {
+		tree.Entries = append(tree.Entries, e)
+	}
+	sort.Sort(object.SubtreeOrder(tree.Entries))
+	return b.r.odb.WriteEncoded(tree)
+}
+
+// WriteTree writes the trees changed by the builder and returns the root tree.
+func (b *CommitBuilder) WriteTree(ctx context.Context) (plumbing.Hash, error) {
+	edits := make([]*treeEdit, 0, len(b.edits))
+	for p, e := range b.edits {
+		edits = append(edits, &treeEdit{path: p, entry: e})
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].path < edits[j].path })
+	oid, err := b.editTree(ctx, b.base, edits)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if oid.IsZero() {
+		return plumbing.EmptyTree, nil
+	}
+	return oid, nil
+}
+
+// Commit writes the tree and a commit of it, opts.Tree is ignored. Set the time of Author and Committer to get
+// reproducible commits.
+func (b *CommitBuilder) Commit(ctx context.Context, opts *CommitTreeOptions) (plumbing.Hash, error) {
+	tree, err := b.WriteTree(ctx)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	o := *opts
+	o.Tree = tree
+	return b.r.CommitTree(ctx, &o)
+}
+
+// UpdateRef moves refname from oldRev to newRev, the update fails when refname is no longer at oldRev. Zero oldRev
+// requires that refname does not exist. The reflog entry is written by committer, nil uses the configured committer.
+func (r *Repository) UpdateRef(ctx context.Context, refname plumbing.ReferenceName, oldRev, newRev plumbing.Hash, committer *object.Signature, message string) error {
+	if refname == plumbing.HEAD || !refname.IsBranch() && !refname.IsTag() && !strings.HasPrefix(string(refname), plumbing.ReferencePrefix) {
+		return fmt.Errorf("bad reference name '%s'", refname)
+	}
+	unlock, err := r.lock("update-ref")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	ref, err := r.Reference(refname)
+	switch {
+	case err == nil:
+		if ref.Hash() != oldRev {
+			return &ErrStaleReference{Name: refname, Expected: oldRev, Actual: ref.Hash()}
+		}
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		if !oldRev.IsZero() {
+			return &ErrStaleReference{Name: refname, Expected: oldRev}
+		}
+	default:
+		return err
+	}
+	if committer == nil {
+		cc := &CommitOptions{}
+		if err := cc.loadConfigAuthorAndCommitter(r); err != nil {
+			return err
+		}
+		committer = &cc.Committer
+	}
+	return r.DoUpdate(ctx, refname, oldRev, newRev, committer, message)
+}
+
+// ErrStaleReference: the reference was updated by someone else, rebuild on top of Actual.
+type ErrStaleReference struct {
+	Name     plumbing.ReferenceName
+	Expected plumbing.Hash
+	Actual   plumbing.Hash // zero when the reference does not exist
+}
+
+func (e *ErrStaleReference) Error() string {
+	if e.Actual.IsZero() {
+		return fmt.Sprintf("reference '%s' does not exist, expected %s", e.Name, e.Expected)
+	}
+	return fmt.Sprintf("reference '%s' is at %s, expected %s", e.Name, e.Actual, e.Expected)
+}
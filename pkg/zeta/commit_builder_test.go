@@ -0,0 +1,93 @@
+package zeta
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+func TestCommitBuilder(t *testing.T) {
+	ctx := t.Context()
+	r, err := Init(ctx, &InitOptions{Worktree: filepath.Join(t.TempDir(), "builder"), Branch: "mainline", Quiet: true})
+	if err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	defer r.Close() // nolint
+
+	sig := object.Signature{Name: "bot", Email: "bot@example.io", When: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	write := func(b *CommitBuilder, p, content string) {
+		if _, err := b.WriteBlob(ctx, p, strings.NewReader(content), int64(len(content)), filemode.Regular); err != nil {
+			t.Fatalf("write %s error: %v", p, err)
+		}
+	}
+	b := r.NewCommitBuilder(plumbing.ZeroHash)
+	write(b, "go.mod", "module example\n")
+	write(b, "vendor/a/a.go", "package a\n")
+	write(b, "vendor/b/b.go", "package b\n")
+	base, err := b.Commit(ctx, &CommitTreeOptions{Author: sig, Committer: sig, Message: "init"})
+	if err != nil {
+		t.Fatalf("commit error: %v", err)
+	}
+	// the same changes in another order produce the same commit
+	b2 := r.NewCommitBuilder(plumbing.ZeroHash)
+	write(b2, "vendor/b/b.go", "package b\n")
+	write(b2, "go.mod", "module example\n")
+	write(b2, "vendor/a/a.go", "package a\n")
+	if oid, err := b2.Commit(ctx, &CommitTreeOptions{Author: sig, Committer: sig, Message: "init"}); err != nil || oid != base {
+		t.Fatalf("commit should be reproducible: %s != %s, %v", oid, base, err)
+	}
+	if err := r.UpdateRef(ctx, plumbing.NewBranchReferenceName("mainline"), plumbing.ZeroHash, base, &sig, "bot: init"); err != nil {
+		t.Fatalf("update-ref error: %v", err)
+	}
+
+	cc, err := r.odb.Commit(ctx, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = r.NewCommitBuilder(cc.Tree)
+	write(b, "go.mod", "module example\n\ngo 1.26\n")
+	if err := b.Remove("vendor/a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Remove("vendor/b/b.go"); err != nil {
+		t.Fatal(err)
+	}
+	next, err := b.Commit(ctx, &CommitTreeOptions{Parents: []plumbing.Hash{base}, Author: sig, Committer: sig, Message: "bump"})
+	if err != nil {
+		t.Fatalf("commit error: %v", err)
+	}
+	nc, err := r.odb.Commit(ctx, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := r.odb.Tree(ctx, nc.Tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// empty dirs are removed
+	if len(tree.Entries) != 1 || tree.Entries[0].Name != "go.mod" {
+		t.Fatalf("unexpected tree: %v", tree.Entries)
+	}
+	if err := r.UpdateRef(ctx, plumbing.NewBranchReferenceName("mainline"), plumbing.ZeroHash, next, &sig, "bot: bump"); !errors.As(err, new(*ErrStaleReference)) {
+		t.Fatalf("stale update should fail: %v", err)
+	}
+	if err := r.UpdateRef(ctx, plumbing.NewBranchReferenceName("mainline"), base, next, &sig, "bot: bump"); err != nil {
+		t.Fatalf("update-ref error: %v", err)
+	}
+
+	b = r.NewCommitBuilder(nc.Tree)
+	write(b, "docs", "file\n")
+	write(b, "docs/a.md", "dir\n")
+	if _, err := b.WriteTree(ctx); err == nil {
+		t.Fatalf("a path cannot be both a file and a dir")
+	}
+	if err := b.Remove("../x"); err == nil {
+		t.Fatalf("bad path should be rejected")
+	}
+}
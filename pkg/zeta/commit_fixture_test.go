@@ -0,0 +1,75 @@
+package zeta
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+// commitFixture writes commits for tests without a worktree. A commit changes the tree of its first parent and is one
+// minute later than the previous one, dates start at 2026-01-01 UTC so the hashes are reproducible.
+type commitFixture struct {
+	t    *testing.T
+	r    *Repository
+	when time.Time
+}
+
+func newCommitFixture(t *testing.T, r *Repository) *commitFixture {
+	return &commitFixture{t: t, r: r, when: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// newTestRepository initializes a repository on the mainline branch, it is closed when the test ends.
+func newTestRepository(t *testing.T, name string) (*Repository, *commitFixture) {
+	r, err := Init(t.Context(), &InitOptions{Worktree: filepath.Join(t.TempDir(), name), Branch: "mainline", Quiet: true})
+	if err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+	return r, newCommitFixture(t, r)
+}
+
+// signature returns the signature of the next commit.
+func (f *commitFixture) signature() object.Signature {
+	return object.Signature{Name: "zeta", Email: "zeta@example.io", When: f.when}
+}
+
+// commit writes the files (path -> content) on top of the first parent.
+func (f *commitFixture) commit(message string, files map[string]string, parents ...plumbing.Hash) plumbing.Hash {
+	return f.commitWith(message, func(b *CommitBuilder) {
+		for p, content := range files {
+			if _, err := b.WriteBlob(f.t.Context(), p, strings.NewReader(content), int64(len(content)), filemode.Regular); err != nil {
+				f.t.Fatalf("write %s error: %v", p, err)
+			}
+		}
+	}, parents...)
+}
+
+// commitWith applies edit to the tree of the first parent, eg: to remove paths or to add existing objects.
+func (f *commitFixture) commitWith(message string, edit func(b *CommitBuilder), parents ...plumbing.Hash) plumbing.Hash {
+	var base plumbing.Hash
+	if len(parents) != 0 {
+		base = f.object(parents[0]).Tree
+	}
+	b := f.r.NewCommitBuilder(base)
+	edit(b)
+	sig := f.signature()
+	f.when = f.when.Add(time.Minute)
+	oid, err := b.Commit(f.t.Context(), &CommitTreeOptions{Author: sig, Committer: sig, Parents: parents, Message: message})
+	if err != nil {
+		f.t.Fatalf("commit error: %v", err)
+	}
+	return oid
+}
+
+func (f *commitFixture) object(oid plumbing.Hash) *object.Commit {
+	cc, err := f.r.odb.Commit(f.t.Context(), oid)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return cc
+}
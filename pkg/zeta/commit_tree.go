@@ -0,0 +1,176 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+)
+
+type BuildCommitOptions struct {
+	Tree      string   // base tree-ish, defaults to the tree of the first parent
+	Parents   []string // parent revisions
+	Message   []string
+	File      string   // read the message from the file, '-' reads stdin
+	CacheInfo []string // <mode>,<object>,<path>
+	Remove    []string
+	IndexInfo bool   // read '<mode> <object>\t<path>' lines from stdin, mode 0 removes the path
+	UpdateRef string // update the reference if it still points to the first parent
+}
+
+func (o *BuildCommitOptions) message() (string, error) {
+	switch {
+	case o.File == "-":
+		b, err := io.ReadAll(os.Stdin)
+		return string(b), err
+	case len(o.File) != 0:
+		b, err := os.ReadFile(o.File)
+		return string(b), err
+	}
+	return genMessage(o.Message), nil
+}
+
+// parseCacheInfo parses '<mode> <object>\t<path>' (sep is ' ') or '<mode>,<object>,<path>' (sep is ',').
+func parseCacheInfo(line string, sep string) (filemode.FileMode, plumbing.Hash, string, error) {
+	var modeText, oidText, p string
+	var ok bool
+	if sep == "," {
+		ss := strings.SplitN(line, ",", 3)
+		if ok = len(ss) == 3; ok {
+			modeText, oidText, p = ss[0], ss[1], ss[2]
+		}
+	} else {
+		var info string
+		if info, p, ok = strings.Cut(line, "\t"); ok {
+			modeText, oidText, ok = strings.Cut(info, " ")
+		}
+	}
+	if !ok {
+		return 0, plumbing.ZeroHash, "", fmt.Errorf("bad cache info '%s'", line)
+	}
+	m, err := strconv.ParseUint(modeText, 8, 32)
+	if err != nil {
+		return 0, plumbing.ZeroHash, "", fmt.Errorf("bad mode '%s'", modeText)
+	}
+	if m != 0 && !plumbing.ValidateHashHex(oidText) {
+		return 0, plumbing.ZeroHash, "", fmt.Errorf("bad object '%s'", oidText)
+	}
+	return filemode.FileMode(m), plumbing.NewHash(oidText), p, nil
+}
+
+func (r *Repository) applyCacheInfo(ctx context.Context, b *CommitBuilder, line string, sep string) error {
+	mode, oid, p, err := parseCacheInfo(line, sep)
+	if err != nil {
+		return err
+	}
+	if mode == filemode.Empty {
+		return b.Remove(p)
+	}
+	return b.Add(ctx, p, oid, mode)
+}
+
+// BuildCommit: zeta commit-tree, creates a commit from the base tree and the changes without touching the index or the
+// worktree, prints the new commit.
+func (r *Repository) BuildCommit(ctx context.Context, opts *BuildCommitOptions) error {
+	if opts.IndexInfo && opts.File == "-" {
+		die("--index-info and -F - cannot be used together")
+		return ErrAborting
+	}
+	parents := make([]plumbing.Hash, 0, len(opts.Parents))
+	for _, p := range opts.Parents {
+		oid, err := r.Revision(ctx, p)
+		if err != nil {
+			die_error("resolve parent '%s': %v", p, err)
+			return err
+		}
+		parents = append(parents, oid)
+	}
+	var base plumbing.Hash
+	switch {
+	case len(opts.Tree) != 0:
+		t, err := r.resolveTree(ctx, opts.Tree)
+		if err != nil {
+			die_error("resolve tree '%s': %v", opts.Tree, err)
+			return err
+		}
+		base = t.Hash
+	case len(parents) != 0:
+		cc, err := r.odb.Commit(ctx, parents[0])
+		if err != nil {
+			die_error("resolve parent '%s': %v", parents[0], err)
+			return err
+		}
+		base = cc.Tree
+	}
+	b := r.NewCommitBuilder(base)
+	for _, c := range opts.CacheInfo {
+		if err := r.applyCacheInfo(ctx, b, c, ","); err != nil {
+			die_error("--cacheinfo: %v", err)
+			return err
+		}
+	}
+	for _, p := range opts.Remove {
+		if err := b.Remove(p); err != nil {
+			die_error("--remove: %v", err)
+			return err
+		}
+	}
+	if opts.IndexInfo {
+		br := bufio.NewScanner(os.Stdin)
+		br.Buffer(make([]byte, 64*1024), 1024*1024)
+		for br.Scan() {
+			line := strings.TrimRight(br.Text(), "\r")
+			if len(line) == 0 {
+				continue
+			}
+			if err := r.applyCacheInfo(ctx, b, line, " "); err != nil {
+				die_error("--index-info: %v", err)
+				return err
+			}
+		}
+		if err := br.Err(); err != nil {
+			die_error("--index-info: %v", err)
+			return err
+		}
+	}
+	message, err := opts.message()
+	if err != nil {
+		die_error("read commit message: %v", err)
+		return err
+	}
+	newRev, err := b.Commit(ctx, &CommitTreeOptions{Parents: parents, Message: message})
+	if err != nil {
+		die_error("commit-tree: %v", err)
+		return err
+	}
+	if len(opts.UpdateRef) != 0 {
+		refname := plumbing.ReferenceName(opts.UpdateRef)
+		if !strings.HasPrefix(opts.UpdateRef, plumbing.ReferencePrefix) {
+			refname = plumbing.NewBranchReferenceName(opts.UpdateRef)
+		}
+		var oldRev plumbing.Hash
+		if len(parents) != 0 {
+			oldRev = parents[0]
+		}
+		cc, err := r.odb.Commit(ctx, newRev)
+		if err != nil {
+			die_error("resolve %s: %v", newRev, err)
+			return err
+		}
+		if err := r.UpdateRef(ctx, refname, oldRev, newRev, &cc.Committer, "commit-tree: "+firstLine(message)); err != nil {
+			die_error("update %s: %v", refname, err)
+			return err
+		}
+	}
+	fmt.Fprintln(os.Stdout, newRev)
+	return nil
+}
@@ -21,13 +21,15 @@ var (
 )
 
 type ListConfigOptions struct {
-	System bool
-	Global bool
-	Local  bool
-	Z      bool
-	JSON   bool
-	CWD    string
-	Values []string
+	System     bool
+	Global     bool
+	Local      bool
+	Z          bool
+	JSON       bool
+	ShowScope  bool
+	ShowOrigin bool
+	CWD        string
+	Values     []string
 }
 
 func (opts *ListConfigOptions) displayInput() {
@@ -52,6 +54,9 @@ func ListConfig(opts *ListConfigOptions) error {
 		die_error("only one config file at a time")
 		return ErrOnlyOneName
 	}
+	if opts.ShowScope || opts.ShowOrigin {
+		return listConfigProvenance(opts)
+	}
 	if opts.JSON {
 		return listConfigJSON(opts)
 	}
@@ -195,15 +200,17 @@ func listConfigJSON(opts *ListConfigOptions) error {
 }
 
 type GetConfigOptions struct {
-	System bool
-	Global bool
-	Local  bool
-	ALL    bool
-	Z      bool
-	JSON   bool
-	Keys   []string
-	CWD    string
-	Values []string
+	System     bool
+	Global     bool
+	Local      bool
+	ALL        bool
+	Z          bool
+	JSON       bool
+	ShowScope  bool
+	ShowOrigin bool
+	Keys       []string
+	CWD        string
+	Values     []string
 }
 
 func (opts *GetConfigOptions) subCommand() string {
@@ -242,6 +249,9 @@ func GetConfig(opts *GetConfigOptions) error {
 		fmt.Fprintf(os.Stderr, "zeta config %s: missing keys\n", opts.subCommand())
 		return ErrMissingKeys
 	}
+	if opts.ShowScope || opts.ShowOrigin {
+		return getConfigProvenance(opts)
+	}
 	if opts.JSON {
 		return getConfigJSON(opts)
 	}
@@ -0,0 +1,246 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/zeta/config"
+)
+
+// configEnvironments: environment variables overriding the config files, see getFromValueOrEnv.
+var configEnvironments = []struct {
+	key  string
+	envs []string
+}{
+	{"core.accelerator", []string{ENV_ZETA_CORE_ACCELERATOR}},
+	{"core.cacheSize", []string{ENV_ZETA_CORE_CACHE_SIZE}},
+	{"core.concurrenttransfers", []string{ENV_ZETA_CORE_CONCURRENT_TRANSFERS}},
+	{"core.editor", []string{ENV_ZETA_EDITOR}},
+	{"core.encryptObjects", []string{ENV_ZETA_CORE_ENCRYPT_OBJECTS}},
+	{"core.ignoreCompat", []string{ENV_ZETA_CORE_IGNORE_COMPAT}},
+	{"core.optimizeStrategy", []string{ENV_ZETA_CORE_OPTIMIZE_STRATEGY}},
+	{"core.orgTemplates", []string{ENV_ZETA_CORE_ORG_TEMPLATES}},
+	{"core.sharingRoot", []string{ENV_ZETA_CORE_SHARING_ROOT}},
+	{"credential.encryptionKey", []string{ENV_ZETA_CREDENTIAL_ENCRYPTION_KEY}},
+	{"credential.storage", []string{ENV_ZETA_CREDENTIAL_STORAGE}},
+	{"credential.storagePath", []string{ENV_ZETA_CREDENTIAL_STORAGE_PATH}},
+	{"metrics.statsd", []string{ENV_ZETA_METRICS_STATSD}},
+	{"telemetry.enabled", []string{ENV_ZETA_TELEMETRY_ENABLED}},
+	{"telemetry.endpoint", []string{ENV_ZETA_TELEMETRY_ENDPOINT}},
+	{"transport.externalProxy", []string{ENV_ZETA_TRANSPORT_EXTERNAL_PROXY}},
+	{"transport.largeSize", []string{ENV_ZETA_TRANSPORT_LARGE_SIZE}},
+	{"transport.maxEntries", []string{ENV_ZETA_TRANSPORT_MAX_ENTRIES}},
+	{"update.endpoint", []string{ENV_ZETA_UPDATE_ENDPOINT}},
+	{"user.email", []string{ENV_ZETA_AUTHOR_EMAIL, ENV_ZETA_COMMITTER_EMAIL}},
+	{"user.name", []string{ENV_ZETA_AUTHOR_NAME, ENV_ZETA_COMMITTER_NAME}},
+}
+
+func environmentEntries() []*config.Entry {
+	var entries []*config.Entry
+	for _, e := range configEnvironments {
+		for _, env := range e.envs {
+			if v, ok := os.LookupEnv(env); ok {
+				entries = append(entries, &config.Entry{Key: e.key, Value: config.NewStringValue(v), Scope: config.ScopeEnv, Origin: "env:" + env})
+			}
+		}
+	}
+	return entries
+}
+
+func commandEntries(values []string) []*config.Entry {
+	entries := make([]*config.Entry, 0, len(values))
+	for _, v := range values {
+		k, s, ok := strings.Cut(v, "=")
+		if !ok {
+			continue
+		}
+		entries = append(entries, &config.Entry{Key: k, Value: config.NewStringValue(s), Scope: config.ScopeCommand, Origin: "command line:-X"})
+	}
+	return entries
+}
+
+// loadConfigEntries returns the config values from the lowest to the highest priority: the system, global and local
+// config files, the environment and -X values. scope selects the values of one config file.
+func loadConfigEntries(cwd string, scope string, values []string) ([]*config.Entry, error) {
+	_, zetaDir, err := FindZetaDir(cwd)
+	if err != nil && (!IsErrNotZetaDir(err) || scope == config.ScopeLocal) {
+		return nil, err
+	}
+	entries, err := config.LoadEntries(zetaDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(scope) != 0 {
+		selected := make([]*config.Entry, 0, len(entries))
+		for _, e := range entries {
+			if e.Scope == scope {
+				selected = append(selected, e)
+			}
+		}
+		return selected, nil
+	}
+	entries = append(entries, environmentEntries()...)
+	return append(entries, commandEntries(values)...), nil
+}
+
+func configScope(system, global, local bool) string {
+	switch {
+	case system:
+		return config.ScopeSystem
+	case global:
+		return config.ScopeGlobal
+	case local:
+		return config.ScopeLocal
+	}
+	return ""
+}
+
+// showEntry writes every value of the entry, with -z the columns and the values end with NUL like git.
+func showEntry(e *config.Entry, key bool, showScope, showOrigin, z bool) {
+	prefix := e.Prefix(showScope, showOrigin)
+	for _, v := range e.Value.All() {
+		switch {
+		case z && key:
+			_, _ = fmt.Fprintf(os.Stdout, "%s%s\n%v%c", strings.ReplaceAll(prefix, "\t", "\x00"), e.Key, v, config.NUL)
+		case z:
+			_, _ = fmt.Fprintf(os.Stdout, "%s%v%c", strings.ReplaceAll(prefix, "\t", "\x00"), v, config.NUL)
+		case key:
+			_, _ = fmt.Fprintf(os.Stdout, "%s%s=%v\n", prefix, e.Key, v)
+		default:
+			_, _ = fmt.Fprintf(os.Stdout, "%s%v\n", prefix, v)
+		}
+	}
+}
+
+// listConfigProvenance: zeta config --list --show-scope --show-origin
+func listConfigProvenance(opts *ListConfigOptions) error {
+	entries, err := loadConfigEntries(opts.CWD, configScope(opts.System, opts.Global, opts.Local), opts.Values)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta config --list error: %v\n", err)
+		return err
+	}
+	if opts.JSON {
+		return json.NewEncoder(os.Stdout).Encode(entries)
+	}
+	for _, e := range entries {
+		showEntry(e, true, opts.ShowScope, opts.ShowOrigin, opts.Z)
+	}
+	return nil
+}
+
+// getConfigProvenance: zeta config --get --show-scope --show-origin, the value which wins is shown first.
+func getConfigProvenance(opts *GetConfigOptions) error {
+	entries, err := loadConfigEntries(opts.CWD, configScope(opts.System, opts.Global, opts.Local), opts.Values)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta config %s error: %v\n", opts.subCommand(), err)
+		return err
+	}
+	matched := make([]*config.Entry, 0, 4)
+	for _, k := range opts.Keys {
+		for i := len(entries) - 1; i >= 0; i-- {
+			if !strings.EqualFold(entries[i].Key, k) {
+				continue
+			}
+			matched = append(matched, entries[i])
+			if !opts.ALL {
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return config.ErrKeyNotFound
+	}
+	if opts.JSON {
+		return json.NewEncoder(os.Stdout).Encode(matched)
+	}
+	for _, e := range matched {
+		if opts.ALL {
+			showEntry(e, false, opts.ShowScope, opts.ShowOrigin, opts.Z)
+			continue
+		}
+		// --get: the first value of the entry, like config.Document.GetFirst
+		first, _ := e.Value.First()
+		showEntry(&config.Entry{Key: e.Key, Value: config.NewStringValue(fmt.Sprint(first)), Scope: e.Scope, Origin: e.Origin}, false, opts.ShowScope, opts.ShowOrigin, opts.Z)
+	}
+	return nil
+}
+
+type AuditConfigOptions struct {
+	JSON   bool
+	CWD    string
+	Values []string
+}
+
+func secretEnvironment(key string) string {
+	for _, e := range configEnvironments {
+		if strings.EqualFold(e.key, key) {
+			return e.envs[0]
+		}
+	}
+	return ""
+}
+
+func formatFinding(f *config.Finding) string {
+	e := f.Entry
+	switch f.Kind {
+	case config.FindingUnknown:
+		if len(f.Suggestion) != 0 {
+			return fmt.Sprintf(W("unknown key '%s', did you mean '%s'?"), e.Key, f.Suggestion)
+		}
+		return fmt.Sprintf(W("unknown key '%s', it has no effect"), e.Key)
+	case config.FindingDeprecated:
+		return fmt.Sprintf(W("'%s' is deprecated and has no effect, use '%s' instead"), e.Key, f.Replacement)
+	case config.FindingInvalid:
+		return fmt.Sprintf(W("invalid value '%v' of '%s': %v"), e.Value.ToAny(), e.Key, f.Err)
+	case config.FindingIgnored:
+		return fmt.Sprintf(W("'%s' is ignored in the repository config, it is only read from the system and global config and the command line"), e.Key)
+	case config.FindingOverridden:
+		return fmt.Sprintf(W("'%s' = '%v' is overridden by '%v' from %s"), e.Key, e.Value.ToAny(), f.Winner.Value.ToAny(), f.Winner.Origin)
+	case config.FindingSecret:
+		return fmt.Sprintf(W("'%s' is stored in plain text, prefer %s or the credential storage"), e.Key, secretEnvironment(e.Key))
+	}
+	return f.Kind
+}
+
+// AuditConfig: zeta config --audit reports unknown, deprecated, invalid, ignored and overridden settings and secrets
+// stored in config files. Overridden settings are informational, the other findings are problems and fail the command.
+func AuditConfig(opts *AuditConfigOptions) error {
+	entries, err := loadConfigEntries(opts.CWD, "", opts.Values)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta config --audit error: %v\n", err)
+		return err
+	}
+	findings := config.Audit(entries)
+	var problems int
+	for _, f := range findings {
+		if f.Kind != config.FindingOverridden {
+			problems++
+		}
+	}
+	if opts.JSON {
+		type finding struct {
+			*config.Finding
+			Message string `json:"message"`
+		}
+		items := make([]*finding, 0, len(findings))
+		for _, f := range findings {
+			items = append(items, &finding{Finding: f, Message: formatFinding(f)})
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(items); err != nil {
+			return err
+		}
+	} else {
+		for _, f := range findings {
+			_, _ = fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", f.Kind, f.Entry.Origin, formatFinding(f))
+		}
+	}
+	if problems != 0 {
+		return &ErrExitCode{ExitCode: 1, Message: fmt.Sprintf("%d problems found", problems)}
+	}
+	return nil
+}
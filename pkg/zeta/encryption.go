@@ -0,0 +1,197 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/command"
+	"github.com/antgroup/hugescm/modules/keyring"
+	"github.com/antgroup/hugescm/modules/shlex"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+)
+
+// Object encryption keys are base64 encoded AES-256 keys, the first key encrypts new objects, the others only decrypt
+// objects not re-encrypted yet. Keys are shared by all repositories of the user, so objects in core.sharingRoot are
+// readable by every repository using it.
+//
+// Keys are printed by core.encryptionKeyCommand one per line (eg: a KMS client), or kept in the credential storage
+// (credential.storage), a key is generated on first use.
+var (
+	objectKeysCred = &keyring.Cred{Protocol: "zeta", Server: "objects", UserName: "encryption-keys"}
+)
+
+func parseEncryptObjects(cfg *config.Config, values map[string]StringArray) bool {
+	if s, ok := getFromValueOrEnv("core.encryptObjects", ENV_ZETA_CORE_ENCRYPT_OBJECTS, values); ok {
+		return strengthen.SimpleAtob(s, false)
+	}
+	return cfg.Core.EncryptObjects.True()
+}
+
+func decodeObjectKeys(text string) ([][]byte, error) {
+	keys := make([][]byte, 0, 2)
+	for _, s := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		if s = strings.TrimSpace(s); len(s) == 0 {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("bad object encryption key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no object encryption key")
+	}
+	return keys, nil
+}
+
+func encodeObjectKeys(keys [][]byte) string {
+	encoded := make([]string, 0, len(keys))
+	for _, k := range keys {
+		encoded = append(encoded, base64.StdEncoding.EncodeToString(k))
+	}
+	return strings.Join(encoded, ",")
+}
+
+func newObjectKey() ([]byte, error) {
+	key := make([]byte, backend.SealedKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+type objectKeyring struct {
+	command string
+	opts    []keyring.Option
+}
+
+func newObjectKeyring(cfg *config.Config, values map[string]StringArray) *objectKeyring {
+	kr := &objectKeyring{}
+	if s, ok := getStringFromValues("core.encryptionKeyCommand", values); ok {
+		kr.command = s
+	} else {
+		kr.command = cfg.Core.EncryptionKeyCommand
+	}
+	storage, encryptionKey, storagePath := parseCredentialConfig(cfg, values)
+	if len(storage) != 0 {
+		kr.opts = append(kr.opts, keyring.WithStorage(storage))
+	}
+	if len(encryptionKey) != 0 {
+		kr.opts = append(kr.opts, keyring.WithEncryptionKey(encryptionKey))
+	}
+	if len(storagePath) != 0 {
+		kr.opts = append(kr.opts, keyring.WithStoragePath(storagePath))
+	}
+	return kr
+}
+
+func (kr *objectKeyring) run(ctx context.Context) ([][]byte, error) {
+	cmdArgs, err := shlex.Split(kr.command, true)
+	if err != nil || len(cmdArgs) == 0 {
+		return nil, fmt.Errorf("bad core.encryptionKeyCommand '%s'", kr.command)
+	}
+	cmd := command.NewFromOptions(ctx, &command.RunOpts{
+		Environ:   os.Environ(),
+		Stderr:    os.Stderr,
+		NoSetpgid: true,
+	}, cmdArgs[0], cmdArgs[1:]...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("run core.encryptionKeyCommand: %w", err)
+	}
+	return decodeObjectKeys(string(out))
+}
+
+func (kr *objectKeyring) store(ctx context.Context, keys [][]byte) error {
+	cred := *objectKeysCred
+	cred.Password = encodeObjectKeys(keys)
+	if err := keyring.Store(ctx, &cred, kr.opts...); err != nil {
+		if errors.Is(err, keyring.ErrStorageDisabled) {
+			return errors.New("object encryption requires credential.storage or core.encryptionKeyCommand")
+		}
+		return fmt.Errorf("store object encryption keys: %w", err)
+	}
+	return nil
+}
+
+// load returns the keys, a key is generated when the credential storage has no key.
+func (kr *objectKeyring) load(ctx context.Context) ([][]byte, error) {
+	if len(kr.command) != 0 {
+		return kr.run(ctx)
+	}
+	cred, err := keyring.Get(ctx, objectKeysCred, kr.opts...)
+	if err == nil {
+		return decodeObjectKeys(cred.Password)
+	}
+	// only generate a key when there is no key, otherwise objects sealed with the lost key become unreadable
+	if !errors.Is(err, keyring.ErrNotFound) {
+		if errors.Is(err, keyring.ErrStorageDisabled) {
+			return nil, errors.New("object encryption requires credential.storage or core.encryptionKeyCommand")
+		}
+		return nil, fmt.Errorf("load object encryption keys: %w", err)
+	}
+	key, err := newObjectKey()
+	if err != nil {
+		return nil, err
+	}
+	keys := [][]byte{key}
+	if err := kr.store(ctx, keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// rotate generates a new current key, previous keys are retained to decrypt the objects of other repositories.
+func (kr *objectKeyring) rotate(ctx context.Context) ([][]byte, error) {
+	if len(kr.command) != 0 {
+		return nil, errors.New("object encryption keys are provided by core.encryptionKeyCommand, rotate them in the key management service")
+	}
+	keys, err := kr.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := newObjectKey()
+	if err != nil {
+		return nil, err
+	}
+	keys = append([][]byte{key}, keys...)
+	if err := kr.store(ctx, keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// parseObjectSealer returns the sealer when core.encryptObjects is enabled, otherwise nil.
+func parseObjectSealer(ctx context.Context, cfg *config.Config, values map[string]StringArray) (*backend.Sealer, error) {
+	if !parseEncryptObjects(cfg, values) {
+		return nil, nil
+	}
+	keys, err := newObjectKeyring(cfg, values).load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return backend.NewSealer(keys...)
+}
+
+// rotateObjectKey generates a new object encryption key, objects are re-encrypted by gc.
+func (r *Repository) rotateObjectKey(ctx context.Context) (*backend.Sealer, error) {
+	if !parseEncryptObjects(r.Config, r.values) {
+		return nil, errors.New("object encryption is not enabled, set core.encryptObjects to true")
+	}
+	keys, err := newObjectKeyring(r.Config, r.values).rotate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return backend.NewSealer(keys...)
+}
@@ -0,0 +1,460 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/antgroup/hugescm/modules/lfs"
+	"github.com/antgroup/hugescm/modules/merkletrie"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+// FastExportOptions: https://git-scm.com/docs/git-fast-export
+type FastExportOptions struct {
+	Revisions    []string // references to export, all branches and tags when empty
+	ImportMarks  string   // marks of a previous export, a missing file is treated as empty
+	ExportMarks  string   // write marks to file when complete
+	LFS          bool     // emit Git LFS pointers for fragments instead of reassembling them
+	LFSThreshold int64    // blobs larger than threshold are also emitted as Git LFS pointers, ignored when < 0
+	LFSObjects   string   // store LFS object contents under this directory (.git/lfs/objects layout)
+	Output       string   // output to file instead of stdout
+}
+
+type fastExporter struct {
+	*Repository
+	opts  *FastExportOptions
+	w     *bufio.Writer
+	marks map[plumbing.Hash]int
+	next  int
+}
+
+func (e *fastExporter) mark(oid plumbing.Hash) int {
+	e.next++
+	e.marks[oid] = e.next
+	return e.next
+}
+
+func (e *fastExporter) importMarks() error {
+	if len(e.opts.ImportMarks) == 0 {
+		return nil
+	}
+	fd, err := os.Open(e.opts.ImportMarks)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer fd.Close() // nolint
+	br := bufio.NewScanner(fd)
+	for br.Scan() {
+		line := strings.TrimSpace(br.Text())
+		if len(line) == 0 {
+			continue
+		}
+		m, s, ok := strings.Cut(line, " ")
+		if !ok || !strings.HasPrefix(m, ":") || !plumbing.ValidateHashHex(s) {
+			return fmt.Errorf("corrupt mark line: %s", line)
+		}
+		n, err := strconv.Atoi(m[1:])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("corrupt mark line: %s", line)
+		}
+		e.marks[plumbing.NewHash(s)] = n
+		e.next = max(e.next, n)
+	}
+	return br.Err()
+}
+
+func (e *fastExporter) exportMarks() error {
+	if len(e.opts.ExportMarks) == 0 {
+		return nil
+	}
+	type markEntry struct {
+		mark int
+		oid  plumbing.Hash
+	}
+	entries := make([]markEntry, 0, len(e.marks))
+	for oid, m := range e.marks {
+		entries = append(entries, markEntry{mark: m, oid: oid})
+	}
+	slices.SortFunc(entries, func(a, b markEntry) int {
+		return a.mark - b.mark
+	})
+	fd, err := os.Create(e.opts.ExportMarks)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fd)
+	for _, m := range entries {
+		_, _ = fmt.Fprintf(w, ":%d %s\n", m.mark, m.oid)
+	}
+	if err := w.Flush(); err != nil {
+		_ = fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+// quotePath quotes path with C-style escapes when fast-import cannot read it verbatim.
+func quotePath(p string) string {
+	if !strings.ContainsAny(p, "\"\\\n") {
+		return p
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString("\\n")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func (e *fastExporter) writeData(data string) {
+	_, _ = fmt.Fprintf(e.w, "data %d\n%s\n", len(data), data)
+}
+
+// contents opens the contents of a blob or fragments entry, missing objects are fetched by promisor.
+func (e *fastExporter) contents(ctx context.Context, entry *object.TreeEntry) (io.ReadCloser, int64, error) {
+	if entry.Hash == backend.BLANK_BLOB_HASH {
+		return io.NopCloser(strings.NewReader("")), 0, nil
+	}
+	if !entry.IsFragments() {
+		b, err := e.catMissingObject(ctx, &promiseObject{oid: entry.Hash, size: entry.Size})
+		if err != nil {
+			return nil, 0, err
+		}
+		return &fragmentsReader{Reader: b.Contents, closers: []io.Closer{b}}, b.Size, nil
+	}
+	ff, err := e.odb.Fragments(ctx, entry.Hash)
+	if err != nil {
+		return nil, 0, err
+	}
+	fr := &fragmentsReader{}
+	readers := make([]io.Reader, 0, len(ff.Entries))
+	for _, fe := range ff.Entries {
+		b, err := e.catMissingObject(ctx, &promiseObject{oid: fe.Hash, size: int64(fe.Size)})
+		if err != nil {
+			_ = fr.Close()
+			return nil, 0, err
+		}
+		fr.closers = append(fr.closers, b)
+		readers = append(readers, b.Contents)
+	}
+	fr.Reader = io.MultiReader(readers...)
+	return fr, int64(ff.Size), nil
+}
+
+type fragmentsReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *fragmentsReader) Close() error {
+	for _, c := range r.closers {
+		_ = c.Close()
+	}
+	return nil
+}
+
+func (e *fastExporter) isLFS(entry *object.TreeEntry) bool {
+	if !e.opts.LFS {
+		return false
+	}
+	return entry.IsFragments() || (e.opts.LFSThreshold >= 0 && entry.Size > e.opts.LFSThreshold)
+}
+
+// lfsPointer hashes the contents with sha256 and returns the Git LFS pointer, contents are saved when --lfs-objects is set.
+func (e *fastExporter) lfsPointer(reader io.Reader, size int64) (string, error) {
+	h := sha256.New()
+	if len(e.opts.LFSObjects) == 0 {
+		if _, err := io.Copy(h, reader); err != nil {
+			return "", err
+		}
+		return lfs.NewPointer(hex.EncodeToString(h.Sum(nil)), size, nil).Encoded(), nil
+	}
+	if err := os.MkdirAll(e.opts.LFSObjects, 0755); err != nil {
+		return "", err
+	}
+	fd, err := os.CreateTemp(e.opts.LFSObjects, "lfs-")
+	if err != nil {
+		return "", err
+	}
+	tempName := fd.Name()
+	defer os.Remove(tempName) // nolint
+	if _, err := io.Copy(io.MultiWriter(fd, h), reader); err != nil {
+		_ = fd.Close()
+		return "", err
+	}
+	if err := fd.Close(); err != nil {
+		return "", err
+	}
+	oid := hex.EncodeToString(h.Sum(nil))
+	saveTo := filepath.Join(e.opts.LFSObjects, oid[0:2], oid[2:4], oid)
+	if err := os.MkdirAll(filepath.Dir(saveTo), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tempName, saveTo); err != nil {
+		return "", err
+	}
+	return lfs.NewPointer(oid, size, nil).Encoded(), nil
+}
+
+func (e *fastExporter) exportBlob(ctx context.Context, entry *object.TreeEntry) (int, error) {
+	if m, ok := e.marks[entry.Hash]; ok {
+		return m, nil
+	}
+	reader, size, err := e.contents(ctx, entry)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close() // nolint
+	if e.isLFS(entry) {
+		pointer, err := e.lfsPointer(reader, size)
+		if err != nil {
+			return 0, err
+		}
+		_, _ = fmt.Fprintf(e.w, "blob\nmark :%d\n", e.mark(entry.Hash))
+		e.writeData(pointer)
+		return e.marks[entry.Hash], nil
+	}
+	_, _ = fmt.Fprintf(e.w, "blob\nmark :%d\ndata %d\n", e.mark(entry.Hash), size)
+	if _, err := io.CopyN(e.w, reader, size); err != nil {
+		return 0, err
+	}
+	if err := e.w.WriteByte('\n'); err != nil {
+		return 0, err
+	}
+	return e.marks[entry.Hash], nil
+}
+
+func fastExportMode(m filemode.FileMode) (string, bool) {
+	switch m.Origin() {
+	case filemode.Regular, filemode.Deprecated:
+		return "100644", true
+	case filemode.Executable:
+		return "100755", true
+	case filemode.Symlink:
+		return "120000", true
+	}
+	return "", false
+}
+
+func (e *fastExporter) exportCommit(ctx context.Context, refname plumbing.ReferenceName, cc *object.Commit) error {
+	if _, ok := e.marks[cc.Hash]; ok {
+		return nil
+	}
+	newTree, err := cc.Root(ctx)
+	if err != nil {
+		return err
+	}
+	var oldTree *object.Tree
+	var from int
+	if len(cc.Parents) != 0 {
+		if m, ok := e.marks[cc.Parents[0]]; ok {
+			parent, err := e.odb.Commit(ctx, cc.Parents[0])
+			if err != nil {
+				return err
+			}
+			if oldTree, err = parent.Root(ctx); err != nil {
+				return err
+			}
+			from = m
+		}
+	}
+	changes, err := object.DiffTreeContext(ctx, oldTree, newTree, nil)
+	if err != nil {
+		return err
+	}
+	var deleted []string
+	var modified []string
+	for _, c := range changes {
+		action, err := c.Action()
+		if err != nil {
+			return err
+		}
+		if action == merkletrie.Delete {
+			deleted = append(deleted, "D "+quotePath(c.From.Name))
+			continue
+		}
+		mode, ok := fastExportMode(c.To.TreeEntry.Mode)
+		if !ok {
+			warn("fast-export: skip '%s' with unsupported mode %s in commit %s", c.To.Name, c.To.TreeEntry.Mode, cc.Hash)
+			continue
+		}
+		m, err := e.exportBlob(ctx, &c.To.TreeEntry)
+		if err != nil {
+			return fmt.Errorf("export '%s' in commit %s: %w", c.To.Name, cc.Hash, err)
+		}
+		modified = append(modified, fmt.Sprintf("M %s :%d %s", mode, m, quotePath(c.To.Name)))
+	}
+	_, _ = fmt.Fprintf(e.w, "commit %s\nmark :%d\n", refname, e.mark(cc.Hash))
+	_, _ = fmt.Fprintf(e.w, "author %s\ncommitter %s\n", cc.Author.String(), cc.Committer.String())
+	e.writeData(cc.Message)
+	if from != 0 {
+		_, _ = fmt.Fprintf(e.w, "from :%d\n", from)
+	}
+	for _, p := range cc.Parents[min(1, len(cc.Parents)):] {
+		if m, ok := e.marks[p]; ok {
+			_, _ = fmt.Fprintf(e.w, "merge :%d\n", m)
+		}
+	}
+	if from == 0 {
+		// root commit or parent is not available (shallow), export the whole tree
+		_, _ = e.w.WriteString("deleteall\n")
+	}
+	for _, s := range deleted {
+		_, _ = e.w.WriteString(s + "\n")
+	}
+	for _, s := range modified {
+		_, _ = e.w.WriteString(s + "\n")
+	}
+	_, err = e.w.WriteString("\n")
+	return err
+}
+
+func (e *fastExporter) exportReference(ctx context.Context, ref *plumbing.Reference) error {
+	refname := ref.Name()
+	o, err := e.odb.Object(ctx, ref.Hash())
+	if err != nil {
+		return err
+	}
+	var tag *object.Tag
+	if t, ok := o.(*object.Tag); ok {
+		if t.ObjectType != object.CommitObject {
+			warn("fast-export: skip tag '%s' which points to a non-commit object", refname.TagName())
+			return nil
+		}
+		tag = t
+	}
+	cc, err := e.odb.ParseRevExhaustive(ctx, ref.Hash())
+	if err != nil {
+		return err
+	}
+	commits, err := e.revList(ctx, cc.Hash, nil, LogOrderTopo, nil)
+	if err != nil {
+		return err
+	}
+	// parents before children
+	slices.Reverse(commits)
+	exportAs := refname
+	if tag != nil || refname.IsTag() {
+		// commits only reachable from tags are exported on the tag ref, like git fast-export
+		exportAs = plumbing.NewTagReferenceName(refname.TagName())
+	}
+	for _, c := range commits {
+		if err := e.exportCommit(ctx, exportAs, c); err != nil {
+			return err
+		}
+	}
+	if tag == nil {
+		_, _ = fmt.Fprintf(e.w, "reset %s\nfrom :%d\n\n", refname, e.marks[cc.Hash])
+		return nil
+	}
+	message, _ := tag.Extract() // signatures are dropped, object names change
+	_, _ = fmt.Fprintf(e.w, "tag %s\nfrom :%d\ntagger %s\n", refname.TagName(), e.marks[cc.Hash], tag.Tagger.String())
+	e.writeData(message)
+	return nil
+}
+
+func (e *fastExporter) references(ctx context.Context) ([]*plumbing.Reference, error) {
+	if len(e.opts.Revisions) == 0 {
+		rdb, err := e.References()
+		if err != nil {
+			return nil, err
+		}
+		refs := make([]*plumbing.Reference, 0, len(rdb.References()))
+		for _, ref := range rdb.References() {
+			if name := ref.Name(); name.IsBranch() || name.IsTag() {
+				refs = append(refs, ref)
+			}
+		}
+		return refs, nil
+	}
+	refs := make([]*plumbing.Reference, 0, len(e.opts.Revisions))
+	for _, rev := range e.opts.Revisions {
+		oid, refname, err := e.RevisionEx(ctx, rev)
+		if err != nil {
+			return nil, err
+		}
+		if len(refname) == 0 {
+			return nil, fmt.Errorf("'%s' is not a reference", rev)
+		}
+		refs = append(refs, plumbing.NewHashReference(refname, oid))
+	}
+	return refs, nil
+}
+
+func (opts *FastExportOptions) newWriter() (io.WriteCloser, error) {
+	if len(opts.Output) == 0 {
+		return &NopWriteCloser{Writer: os.Stdout}, nil
+	}
+	return os.Create(opts.Output)
+}
+
+// FastExport writes a git fast-import stream of the references.
+func (r *Repository) FastExport(ctx context.Context, opts *FastExportOptions) error {
+	e := &fastExporter{Repository: r, opts: opts, marks: make(map[plumbing.Hash]int)}
+	if err := e.importMarks(); err != nil {
+		die_error("fast-export: import marks: %v", err)
+		return err
+	}
+	refs, err := e.references(ctx)
+	if err != nil {
+		die_error("fast-export: %v", err)
+		return err
+	}
+	fd, err := opts.newWriter()
+	if err != nil {
+		die_error("fast-export: %v", err)
+		return err
+	}
+	defer fd.Close() // nolint
+	e.w = bufio.NewWriterSize(fd, 64<<10)
+	// 'done' lets fast-import detect a truncated stream
+	_, _ = e.w.WriteString("feature done\n")
+	for _, ref := range refs {
+		if err := e.exportReference(ctx, ref); err != nil {
+			die_error("fast-export: export '%s': %v", ref.Name(), err)
+			return err
+		}
+	}
+	_, _ = e.w.WriteString("done\n")
+	if err := e.w.Flush(); err != nil {
+		if errors.Is(err, syscall.EPIPE) {
+			return nil
+		}
+		die_error("fast-export: %v", err)
+		return err
+	}
+	if err := e.exportMarks(); err != nil {
+		die_error("fast-export: export marks: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package zeta
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+func TestQuotePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"a/b.txt", "a/b.txt"},
+		{"dir/sp ace.txt", "dir/sp ace.txt"},
+		{"\"quoted\".txt", `"\"quoted\".txt"`},
+		{"new\nline", `"new\nline"`},
+		{`back\slash`, `"back\\slash"`},
+	}
+	for _, tt := range tests {
+		if got := quotePath(tt.path); got != tt.want {
+			t.Errorf("quotePath(%q) = %s; want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFastExport(t *testing.T) {
+	ctx := t.Context()
+	// the fixture dates are fixed, the stream contains the signatures
+	r, f := newTestRepository(t, "fast-export")
+	// a fragmented file of two small fragments, the stream contains the reassembled contents
+	parts := []string{"fragment one\n", "fragment two\n"}
+	h := plumbing.NewHasher()
+	_, _ = h.Write([]byte(strings.Join(parts, "")))
+	ff := &object.Fragments{Size: uint64(len(parts[0]) + len(parts[1])), Origin: h.Sum()}
+	for i, s := range parts {
+		oid, err := r.odb.HashTo(ctx, strings.NewReader(s), int64(len(s)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ff.Entries = append(ff.Entries, &object.Fragment{Index: uint32(i), Hash: oid, Size: uint64(len(s))})
+	}
+	fragments, err := r.odb.WriteEncoded(ff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := f.commitWith("base\n", func(b *CommitBuilder) {
+		for name, content := range map[string]string{"README.md": "readme\n", "src/old.txt": "moved\n"} {
+			if _, err := b.WriteBlob(ctx, name, strings.NewReader(content), int64(len(content)), filemode.Regular); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := b.Add(ctx, "model.bin", fragments, filemode.Regular|filemode.Fragments); err != nil {
+			t.Fatal(err)
+		}
+	})
+	rename := f.commitWith("rename\n", func(b *CommitBuilder) {
+		if _, err := b.WriteBlob(ctx, "src/new.txt", strings.NewReader("moved\n"), 6, filemode.Regular); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Remove("src/old.txt"); err != nil {
+			t.Fatal(err)
+		}
+	}, base)
+	feature := f.commit("feature\n", map[string]string{"README.md": "readme\nfeature\n"}, base)
+	merge := f.commit("merge\n", map[string]string{"README.md": "readme\nfeature\n"}, rename, feature)
+	sig := f.signature()
+	for refname, oid := range map[plumbing.ReferenceName]plumbing.Hash{
+		plumbing.NewBranchReferenceName("mainline"): merge,
+		plumbing.NewBranchReferenceName("feature"):  feature,
+	} {
+		if err := r.UpdateRef(ctx, refname, plumbing.ZeroHash, oid, &sig, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output := filepath.Join(t.TempDir(), "stream")
+	if err := r.FastExport(ctx, &FastExportOptions{Revisions: []string{"feature", "mainline"}, Output: output}); err != nil {
+		t.Fatalf("fast-export error: %v", err)
+	}
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile(filepath.Join("testdata", "fast-export.stream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Fatalf("unexpected fast-export stream:\n%s\nexpected:\n%s", got, expected)
+	}
+}
@@ -37,7 +37,24 @@ type FetchResult struct {
 	FETCH_HEAD plumbing.Hash
 }
 
+const (
+	// maxBatchRetries: re-request objects corrupted in transit at most this many times
+	maxBatchRetries = 3
+)
+
 func (r *Repository) batch(ctx context.Context, t transport.Transport, oids []plumbing.Hash) error {
+	for i := 0; ; i++ {
+		err := r.batchOnce(withTransferRetries(ctx, i), t, oids)
+		var e *odb.ErrCorruptedObjects
+		if !errors.As(err, &e) || i >= maxBatchRetries {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d, %s\n", W("Objects corrupted in transit"), len(e.Objects), W("retrying"))
+		oids = e.Objects
+	}
+}
+
+func (r *Repository) batchOnce(ctx context.Context, t transport.Transport, oids []plumbing.Hash) error {
 	if len(oids) == 0 {
 		return nil
 	}
@@ -47,6 +64,9 @@ func (r *Repository) batch(ctx context.Context, t transport.Transport, oids []pl
 	}
 	if err := r.odb.Unpack(rc, len(oids), r.quiet); err != nil {
 		_ = rc.Close()
+		if odb.IsErrCorruptedObjects(err) {
+			return err
+		}
 		if lastErr := rc.LastError(); lastErr != nil {
 			return lastErr
 		}
@@ -63,27 +83,68 @@ func (r *Repository) fetch(ctx context.Context, t transport.Transport, opts *Fet
 		Deepen:     opts.Deepen,
 		Depth:      opts.Depth,
 	}
-	if r.Core.Snapshot {
+	switch {
+	case len(r.Core.NarrowDirs) != 0:
+		// narrow: the server filters the trees, excluded subtrees are only known by hash
+		metaOpts.SparseDirs = r.Core.NarrowDirs
+	case r.Core.Snapshot:
 		metaOpts.SparseDirs = r.Core.SparseDirs
 	}
+	metaOpts.Limit = r.maxEntries()
 	rc, err := t.FetchMetadata(ctx, opts.Target, metaOpts)
 	if err != nil {
 		return err
 	}
-	if err := r.odb.MetadataUnpack(rc, r.quiet); err != nil {
-		_ = rc.Close()
-		if lastErr := rc.LastError(); lastErr != nil {
-			return lastErr
-		}
+	cursor, err := r.unpackMetadata(rc)
+	if err != nil {
+		return err
+	}
+	if err := r.fetchMetadataCursor(ctx, t, cursor, metaOpts.Limit); err != nil {
 		return err
 	}
-	_ = rc.Close()
 	if err := r.odb.Reload(); err != nil {
 		return err
 	}
 	return r.fetchObjects(ctx, t, opts.Target, opts.SizeLimit, opts.SkipLarges)
 }
 
+func (r *Repository) unpackMetadata(rc transport.SessionReader) ([]plumbing.Hash, error) {
+	cursor, err := r.odb.MetadataUnpackCursor(rc, r.quiet)
+	if err != nil {
+		_ = rc.Close()
+		if lastErr := rc.LastError(); lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, err
+	}
+	_ = rc.Close()
+	return cursor, nil
+}
+
+// fetchMetadataCursor: the server stops at transport.maxEntries trees and fragments and returns a cursor,
+// continue with batch metadata. Only one batch of at most limit objects is in flight, cursors returned
+// by the batch are fetched first, which keeps the pending queue small.
+func (r *Repository) fetchMetadataCursor(ctx context.Context, t transport.Transport, cursor []plumbing.Hash, limit int) error {
+	for len(cursor) != 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		n := min(len(cursor), max(limit, 1))
+		rc, err := t.BatchMetadata(ctx, cursor[:n], transport.AnyDepth, limit)
+		if err != nil {
+			return err
+		}
+		next, err := r.unpackMetadata(rc)
+		if err != nil {
+			return err
+		}
+		cursor = append(next, cursor[n:]...)
+	}
+	return nil
+}
+
 func (r *Repository) fetchAny(ctx context.Context, opts *FetchOptions) error {
 	shallow, err := r.odb.DeepenFrom()
 	if err != nil {
@@ -243,10 +304,18 @@ func (r *Repository) prepareFetch(ctx context.Context, current *plumbing.Referen
 
 // DoFetch: Fetch reference or commit
 func (r *Repository) DoFetch(ctx context.Context, opts *DoFetchOptions) (*FetchResult, error) {
+	unlock, err := r.lock("fetch")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	current, refname, err := r.resolveRef(opts.ReferenceName())
 	if err != nil {
 		return nil, err
 	}
+	if translator := findTranslator(r.Core.Remote); translator != nil {
+		return r.fetchTranslated(ctx, translator, refname, opts)
+	}
 	t, err := r.newTransport(ctx, transport.DOWNLOAD)
 	if err != nil {
 		return nil, err
@@ -265,6 +334,7 @@ func (r *Repository) DoFetch(ctx context.Context, opts *DoFetchOptions) (*FetchR
 		return nil, err
 	} else {
 		want = plumbing.NewHash(ref.Hash)
+		r.syncTemplates(ctx, t, ref)
 	}
 
 	o, err := r.prepareFetch(ctx, current, want, opts)
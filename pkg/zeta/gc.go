@@ -5,6 +5,7 @@ package zeta
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -16,18 +17,51 @@ import (
 
 type GcOptions struct {
 	Prune time.Duration
+	// Reencrypt: encrypt plaintext objects and re-encrypt objects sealed with a retired key
+	Reencrypt bool
+	// RotateKey: generate a new object encryption key and re-encrypt all objects with it
+	RotateKey bool
 }
 
+var (
+	ErrObjectEncryptionDisabled = errors.New("object encryption is not enabled")
+)
+
 func (r *Repository) Gc(ctx context.Context, opts *GcOptions) error {
+	unlock, err := r.lock("gc")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if err := r.Packed(); err != nil {
 		fmt.Fprintf(os.Stderr, "packed refs error: %v\n", err)
 		return err
 	}
+	sealer := r.sealer
+	if opts.RotateKey {
+		var err error
+		if sealer, err = r.rotateObjectKey(ctx); err != nil {
+			die_error("rotate object encryption key: %v", err)
+			return err
+		}
+		opts.Reencrypt = true
+	}
+	if opts.Reencrypt && sealer == nil {
+		die_error("object encryption is not enabled, set core.encryptObjects to true")
+		return ErrObjectEncryptionDisabled
+	}
+	// evict before packing, packed blobs are not evicted
+	if err := r.enforceCacheSize(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "evict blobs error: %v\n", err)
+		return err
+	}
 	packOpts := &backend.PackOptions{
 		ZetaDir:         r.zetaDir,
 		SharingRoot:     r.Core.SharingRoot,
 		Quiet:           r.quiet,
 		CompressionALGO: r.Core.CompressionALGO,
+		Sealer:          sealer,
+		Reseal:          opts.Reencrypt,
 	}
 	if !r.quiet {
 		packOpts.Logger = func(format string, a ...any) {
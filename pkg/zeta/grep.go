@@ -0,0 +1,542 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/streamio"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+const (
+	// grepSniffLen: like git, a file with a NUL byte in the first grepSniffLen bytes is binary
+	grepSniffLen = 8000
+)
+
+// GrepExpr is a boolean combination of line patterns.
+type GrepExpr interface {
+	Match(line string) bool
+}
+
+type grepPattern struct {
+	re *regexp.Regexp
+}
+
+func (e *grepPattern) Match(line string) bool {
+	return e.re.MatchString(line)
+}
+
+type grepAnd struct {
+	left, right GrepExpr
+}
+
+func (e *grepAnd) Match(line string) bool {
+	return e.left.Match(line) && e.right.Match(line)
+}
+
+type grepOr struct {
+	left, right GrepExpr
+}
+
+func (e *grepOr) Match(line string) bool {
+	return e.left.Match(line) || e.right.Match(line)
+}
+
+type grepNot struct {
+	expr GrepExpr
+}
+
+func (e *grepNot) Match(line string) bool {
+	return !e.expr.Match(line)
+}
+
+// grepAny matches the lines matched by any of the patterns, the expression of GrepOptions.Patterns.
+type grepAny []*regexp.Regexp
+
+func (e grepAny) Match(line string) bool {
+	for _, re := range e {
+		if re != nil && re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// GrepTermKind is the kind of a term of a grep expression.
+type GrepTermKind int
+
+const (
+	GrepTermPattern GrepTermKind = iota
+	GrepTermAnd
+	GrepTermOr
+	GrepTermNot
+)
+
+func (k GrepTermKind) String() string {
+	switch k {
+	case GrepTermAnd:
+		return "--and"
+	case GrepTermOr:
+		return "--or"
+	case GrepTermNot:
+		return "--not"
+	}
+	return "-e"
+}
+
+// GrepTerm is a pattern or an operator of a grep expression.
+type GrepTerm struct {
+	Kind    GrepTermKind
+	Pattern string
+}
+
+type GrepPatternOptions struct {
+	// FixedStrings: patterns are fixed strings instead of regular expressions
+	FixedStrings bool
+	IgnoreCase   bool
+}
+
+func (o *GrepPatternOptions) compile(pattern string) (*regexp.Regexp, error) {
+	if o.FixedStrings {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if o.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+var (
+	ErrNoGrepPattern      = errors.New("no pattern given")
+	ErrIncompleteGrepExpr = errors.New("incomplete pattern expression")
+)
+
+type grepParser struct {
+	terms []GrepTerm
+	pos   int
+	opts  *GrepPatternOptions
+}
+
+func (p *grepParser) parseOr() (GrepExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.pos < len(p.terms) {
+		// adjacent patterns are combined with --or
+		if p.terms[p.pos].Kind == GrepTermOr {
+			p.pos++
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &grepOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *grepParser) parseAnd() (GrepExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.pos < len(p.terms) && p.terms[p.pos].Kind == GrepTermAnd {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &grepAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *grepParser) parseNot() (GrepExpr, error) {
+	if p.pos >= len(p.terms) {
+		return nil, ErrIncompleteGrepExpr
+	}
+	t := p.terms[p.pos]
+	p.pos++
+	switch t.Kind {
+	case GrepTermNot:
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &grepNot{expr: e}, nil
+	case GrepTermPattern:
+		re, err := p.opts.compile(t.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return &grepPattern{re: re}, nil
+	}
+	return nil, fmt.Errorf("not a pattern expression: %s", t.Kind)
+}
+
+// CompileGrepExpr compiles the terms given in command line order. Like git grep, --not binds tighter than --and,
+// --and binds tighter than --or, and adjacent patterns are combined with --or.
+func CompileGrepExpr(terms []GrepTerm, opts *GrepPatternOptions) (GrepExpr, error) {
+	if len(terms) == 0 {
+		return nil, ErrNoGrepPattern
+	}
+	if opts == nil {
+		opts = &GrepPatternOptions{}
+	}
+	p := &grepParser{terms: terms, opts: opts}
+	return p.parseOr()
+}
+
+func (o *GrepOptions) expr() GrepExpr {
+	if o.Expr != nil {
+		return o.Expr
+	}
+	return grepAny(o.Patterns)
+}
+
+func (o *GrepOptions) matchPath(m *Matcher, name string) bool {
+	if !m.Match(name) {
+		return false
+	}
+	// When no pathspecs are provided, search all the files.
+	if len(o.PathSpecs) == 0 {
+		return true
+	}
+	for _, pathSpec := range o.PathSpecs {
+		if pathSpec != nil && pathSpec.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// GrepResult is structure of a grep result.
+type GrepResult struct {
+	// FileName is the name of file which contains match.
+	FileName string `json:"file_name"`
+	// LineNumber is the line number of a file at which a match was found.
+	LineNumber int `json:"line_number"`
+	// Content is the content of the file at the matching line.
+	Content string `json:"content"`
+	// TreeName is the name of the tree (reference name/commit hash) at
+	// which the match was performed, empty for the index and the worktree.
+	TreeName string `json:"tree_name,omitempty"`
+}
+
+func (gr GrepResult) String() string {
+	if len(gr.TreeName) == 0 {
+		return fmt.Sprintf("%s:%d:%s", gr.FileName, gr.LineNumber, gr.Content)
+	}
+	return fmt.Sprintf("%s:%s:%d:%s", gr.TreeName, gr.FileName, gr.LineNumber, gr.Content)
+}
+
+// sortGrepResults orders the results by file name in byte order, then by line number, whatever the order of the
+// source is.
+func sortGrepResults(results []GrepResult) {
+	slices.SortStableFunc(results, func(a, b GrepResult) int {
+		return strings.Compare(a.FileName, b.FileName)
+	})
+}
+
+// Grep performs grep on a repository.
+func (r *Repository) Grep(ctx context.Context, opts *GrepOptions) ([]GrepResult, error) {
+	if err := opts.validate(r); err != nil {
+		return nil, err
+	}
+	if opts.Cached || opts.Worktree {
+		return r.Worktree().grepIndex(ctx, opts)
+	}
+
+	// Obtain commit hash from options (CommitHash or ReferenceName).
+	var commitHash plumbing.Hash
+	// treeName contains the value of TreeName in GrepResult.
+	var treeName string
+
+	if opts.ReferenceName != "" {
+		ref, err := r.ReferenceResolve(opts.ReferenceName)
+		if err != nil {
+			return nil, err
+		}
+		commitHash = ref.Hash()
+		treeName = opts.ReferenceName.String()
+	} else if !opts.CommitHash.IsZero() {
+		commitHash = opts.CommitHash
+		treeName = opts.CommitHash.String()
+	}
+
+	// Obtain a tree from the commit hash and get a tracked files iterator from
+	// the tree.
+	tree, err := r.getTreeFromCommitHash(ctx, commitHash)
+	if err != nil {
+		return nil, err
+	}
+	return r.grepTree(ctx, tree, treeName, opts)
+}
+
+// Grep performs grep on a worktree.
+func (w *Worktree) Grep(ctx context.Context, opts *GrepOptions) ([]GrepResult, error) {
+	return w.Repository.Grep(ctx, opts)
+}
+
+// grepTree searches the files of the tree, fragments files are always skipped.
+func (r *Repository) grepTree(ctx context.Context, tree *object.Tree, treeName string, opts *GrepOptions) ([]GrepResult, error) {
+	expr := opts.expr()
+	m := NewMatcher(opts.Paths)
+	var results []GrepResult
+	err := tree.Files().ForEach(ctx, func(file *object.File) error {
+		if !opts.matchPath(m, file.Name) || file.IsFragments() || file.Size > opts.Limit {
+			return nil
+		}
+		rc, _, err := file.OriginReader(ctx)
+		if err != nil {
+			return err
+		}
+		defer rc.Close() // nolint
+		fileResults, err := grepReader(rc, file.Name, treeName, expr, opts)
+		if err != nil {
+			return err
+		}
+		results = append(results, fileResults...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortGrepResults(results)
+	return results, nil
+}
+
+func (w *Worktree) grepBlob(ctx context.Context, oid plumbing.Hash, name string, expr GrepExpr, opts *GrepOptions) ([]GrepResult, error) {
+	br, err := w.odb.Blob(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	defer br.Close() // nolint
+	if br.Size > opts.Limit {
+		return nil, nil
+	}
+	return grepReader(br.Contents, name, "", expr, opts)
+}
+
+func (w *Worktree) grepFile(name string, expr GrepExpr, opts *GrepOptions) ([]GrepResult, error) {
+	si, err := w.fs.Lstat(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !si.Mode().IsRegular() || si.Size() > opts.Limit {
+		return nil, nil
+	}
+	fd, err := w.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close() // nolint
+	return grepReader(fd, name, "", expr, opts)
+}
+
+// grepIndex searches the files of the index, the blobs of the index with opts.Cached, the worktree files otherwise.
+// Unmerged files are searched once.
+func (w *Worktree) grepIndex(ctx context.Context, opts *GrepOptions) ([]GrepResult, error) {
+	idx, err := w.odb.Index()
+	if err != nil {
+		return nil, err
+	}
+	expr := opts.expr()
+	m := NewMatcher(opts.Paths)
+	var results []GrepResult
+	var last string
+	for _, e := range idx.Entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if e.Name == last || !opts.matchPath(m, e.Name) {
+			continue
+		}
+		last = e.Name
+		var fileResults []GrepResult
+		switch {
+		case opts.Cached:
+			if e.IntentToAdd || e.Mode.IsFragments() {
+				continue
+			}
+			fileResults, err = w.grepBlob(ctx, e.Hash, e.Name, expr, opts)
+		default:
+			if e.SkipWorktree {
+				continue
+			}
+			fileResults, err = w.grepFile(e.Name, expr, opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, fileResults...)
+	}
+	sortGrepResults(results)
+	return results, nil
+}
+
+// grepReader matches the lines of r, binary files are skipped unless opts.Text.
+func grepReader(r io.Reader, name, treeName string, expr GrepExpr, opts *GrepOptions) ([]GrepResult, error) {
+	sniffBytes, err := streamio.ReadMax(r, grepSniffLen)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.Text && bytes.IndexByte(sniffBytes, 0) != -1 {
+		return nil, nil
+	}
+	br := bufio.NewReader(io.MultiReader(bytes.NewReader(sniffBytes), r))
+	var results []GrepResult
+	for lineNum := 1; ; lineNum++ {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(line) == 0 {
+			break
+		}
+		content := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		// Normal mode: add to result if matched
+		// Invert mode: add to result if NOT matched
+		if expr.Match(content) != opts.InvertMatch {
+			results = append(results, GrepResult{
+				FileName:   name,
+				LineNumber: lineNum,
+				Content:    content,
+				TreeName:   treeName,
+			})
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return results, nil
+}
+
+// GrepCommandOptions: options of zeta grep.
+type GrepCommandOptions struct {
+	// Revisions: search the trees of the revisions, the worktree when no revision and not Cached
+	Revisions   []string
+	Cached      bool
+	Paths       []string
+	Expr        GrepExpr
+	InvertMatch bool
+	Text        bool
+	LineNumber  bool
+	NameOnly    bool // -l: show only the names of the files
+	Count       bool // -c: show the number of matched lines of the files
+	JSON        bool
+}
+
+type grepPrinter struct {
+	w    *bufio.Writer
+	opts *GrepCommandOptions
+}
+
+func (p *grepPrinter) prefix(treeName, name string) string {
+	if len(treeName) == 0 {
+		return name
+	}
+	return treeName + ":" + name
+}
+
+func (p *grepPrinter) print(results []GrepResult) {
+	for i := 0; i < len(results); {
+		// results of a file are adjacent
+		j := i + 1
+		for j < len(results) && results[j].FileName == results[i].FileName && results[j].TreeName == results[i].TreeName {
+			j++
+		}
+		prefix := p.prefix(results[i].TreeName, results[i].FileName)
+		switch {
+		case p.opts.NameOnly:
+			_, _ = fmt.Fprintf(p.w, "%s\n", prefix)
+		case p.opts.Count:
+			_, _ = fmt.Fprintf(p.w, "%s:%d\n", prefix, j-i)
+		default:
+			for _, result := range results[i:j] {
+				if p.opts.LineNumber {
+					_, _ = fmt.Fprintf(p.w, "%s:%d:%s\n", prefix, result.LineNumber, result.Content)
+					continue
+				}
+				_, _ = fmt.Fprintf(p.w, "%s:%s\n", prefix, result.Content)
+			}
+		}
+		i = j
+	}
+}
+
+// DoGrep prints the lines matching opts.Expr, results are ordered by revision as given, then by file name and line
+// number. Like git grep, it returns exit code 1 when nothing matched.
+func (w *Worktree) DoGrep(ctx context.Context, opts *GrepCommandOptions) error {
+	grepOpts := func() *GrepOptions {
+		return &GrepOptions{Expr: opts.Expr, InvertMatch: opts.InvertMatch, Paths: opts.Paths, Text: opts.Text}
+	}
+	var results []GrepResult
+	switch {
+	case len(opts.Revisions) == 0:
+		o := grepOpts()
+		o.Cached, o.Worktree = opts.Cached, !opts.Cached
+		var err error
+		if results, err = w.Grep(ctx, o); err != nil {
+			return err
+		}
+	case opts.Cached:
+		return errors.New("--cached cannot be used with revisions")
+	default:
+		for _, rev := range opts.Revisions {
+			cc, err := w.parseRevExhaustive(ctx, rev)
+			if err != nil {
+				return err
+			}
+			tree, err := cc.Root(ctx)
+			if err != nil {
+				return err
+			}
+			o := grepOpts()
+			o.CommitHash = cc.Hash
+			if err := o.validate(w.Repository); err != nil {
+				return err
+			}
+			revResults, err := w.grepTree(ctx, tree, rev, o)
+			if err != nil {
+				return err
+			}
+			results = append(results, revResults...)
+		}
+	}
+	if opts.JSON {
+		if results == nil {
+			results = []GrepResult{}
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(results); err != nil {
+			return err
+		}
+	} else {
+		p := &grepPrinter{w: bufio.NewWriter(os.Stdout), opts: opts}
+		p.print(results)
+		if err := p.w.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(results) == 0 {
+		return &ErrExitCode{ExitCode: 1}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package zeta
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCompileGrepExpr(t *testing.T) {
+	pattern := func(p string) GrepTerm {
+		return GrepTerm{Kind: GrepTermPattern, Pattern: p}
+	}
+	and := GrepTerm{Kind: GrepTermAnd}
+	or := GrepTerm{Kind: GrepTermOr}
+	not := GrepTerm{Kind: GrepTermNot}
+	lines := []string{"foo", "bar", "foo bar", "baz", "foo baz", "FOO"}
+	for _, c := range []struct {
+		name  string
+		terms []GrepTerm
+		opts  *GrepPatternOptions
+		want  []string
+	}{
+		{"single", []GrepTerm{pattern("foo")}, nil, []string{"foo", "foo bar", "foo baz"}},
+		{"implicit or", []GrepTerm{pattern("bar"), pattern("baz")}, nil, []string{"bar", "foo bar", "baz", "foo baz"}},
+		{"and", []GrepTerm{pattern("foo"), and, pattern("ba")}, nil, []string{"foo bar", "foo baz"}},
+		{"not", []GrepTerm{pattern("foo"), and, not, pattern("bar")}, nil, []string{"foo", "foo baz"}},
+		// --and binds tighter than --or: bar or (foo and baz)
+		{"precedence", []GrepTerm{pattern("bar"), or, pattern("foo"), and, pattern("baz")}, nil, []string{"bar", "foo bar", "foo baz"}},
+		{"fixed strings", []GrepTerm{pattern("o.b")}, &GrepPatternOptions{FixedStrings: true}, nil},
+		{"regexp", []GrepTerm{pattern("o.b")}, nil, []string{"foo bar", "foo baz"}},
+		{"ignore case", []GrepTerm{pattern("^foo$")}, &GrepPatternOptions{IgnoreCase: true}, []string{"foo", "FOO"}},
+	} {
+		expr, err := CompileGrepExpr(c.terms, c.opts)
+		if err != nil {
+			t.Fatalf("%s: compile error: %v", c.name, err)
+		}
+		var got []string
+		for _, line := range lines {
+			if expr.Match(line) {
+				got = append(got, line)
+			}
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+	for _, terms := range [][]GrepTerm{nil, {pattern("foo"), and}, {not}, {and, pattern("foo")}, {pattern("(")}} {
+		if _, err := CompileGrepExpr(terms, nil); err == nil {
+			t.Errorf("expected error for %v", terms)
+		}
+	}
+}
+
+func TestGrepReader(t *testing.T) {
+	expr, err := CompileGrepExpr([]GrepTerm{{Kind: GrepTermPattern, Pattern: "foo"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := grepReader(strings.NewReader("foo\r\nbar\nfoo bar"), "a.txt", "", expr, &GrepOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Content != "foo" || results[1].LineNumber != 3 || results[1].Content != "foo bar" {
+		t.Fatalf("unexpected results %v", results)
+	}
+	if results, err = grepReader(strings.NewReader("bin\x00foo\n"), "a.bin", "", expr, &GrepOptions{}); err != nil || len(results) != 0 {
+		t.Fatalf("binary file not skipped: %v %v", results, err)
+	}
+	if results, err = grepReader(strings.NewReader("bin\x00foo\n"), "a.bin", "", expr, &GrepOptions{Text: true}); err != nil || len(results) != 1 {
+		t.Fatalf("binary file not searched as text: %v %v", results, err)
+	}
+	if results, err = grepReader(strings.NewReader("foo\nbar\n"), "a.txt", "", expr, &GrepOptions{InvertMatch: true}); err != nil || len(results) != 1 || results[0].Content != "bar" {
+		t.Fatalf("unexpected inverted results: %v %v", results, err)
+	}
+
+	results = []GrepResult{{FileName: "b", LineNumber: 1}, {FileName: "a/c", LineNumber: 2}, {FileName: "a/c", LineNumber: 5}, {FileName: "a.txt", LineNumber: 1}}
+	sortGrepResults(results)
+	var got []string
+	for _, r := range results {
+		got = append(got, r.String())
+	}
+	want := []string{"a.txt:1:", "a/c:2:", "a/c:5:", "b:1:"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected order %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+var (
+	errHealDisabled = errors.New("promisor disabled, corrupt object cannot be downloaded again")
+)
+
+func corruptionLogPath(zetaDir string) string {
+	return filepath.Join(zetaDir, "logs", "corruption.log")
+}
+
+// corruptionIncident: one line of .zeta/logs/corruption.log
+type corruptionIncident struct {
+	Time        time.Time     `json:"time"`
+	OID         plumbing.Hash `json:"oid"`
+	Kind        string        `json:"kind"`
+	Error       string        `json:"error"`
+	Quarantined bool          `json:"quarantined"`
+	Repaired    bool          `json:"repaired"`
+	HealError   string        `json:"heal_error,omitempty"`
+}
+
+// objectHealer downloads corrupt objects from the remote again, r is set once the repository is opened.
+type objectHealer struct {
+	r *Repository
+}
+
+func (h *objectHealer) Heal(ctx context.Context, e *backend.ErrCorruptObject, quarantined bool) error {
+	err := h.heal(ctx, e)
+	if h.r == nil {
+		return err
+	}
+	if err != nil {
+		warn("object %s is corrupt and cannot be repaired: %v", shortHash(e.OID), err)
+	} else {
+		warn("object %s is corrupt, downloaded it again from the remote", shortHash(e.OID))
+	}
+	h.r.logCorruption(e, quarantined, err)
+	return err
+}
+
+func (h *objectHealer) heal(ctx context.Context, e *backend.ErrCorruptObject) error {
+	r := h.r
+	if r == nil || !r.promisorEnabled() || len(r.Core.Remote) == 0 {
+		return errHealDisabled
+	}
+	if !e.Meta {
+		return r.promiseMissingFetch(ctx, &promiseObject{oid: e.OID})
+	}
+	t, err := r.newTransport(ctx, transport.DOWNLOAD)
+	if err != nil {
+		return err
+	}
+	rc, err := t.BatchMetadata(ctx, []plumbing.Hash{e.OID}, 0, 0)
+	if err != nil {
+		return err
+	}
+	objects, err := odb.MetadataDecode(rc, r.odb)
+	if err != nil {
+		_ = rc.Close()
+		if lastErr := rc.LastError(); lastErr != nil {
+			return lastErr
+		}
+		return err
+	}
+	_ = rc.Close()
+	a, ok := objects[e.OID]
+	if !ok {
+		return plumbing.NoSuchObject(e.OID)
+	}
+	encoder, ok := a.(object.Encoder)
+	if !ok {
+		return plumbing.NoSuchObject(e.OID)
+	}
+	_, err = r.odb.WriteEncoded(encoder)
+	return err
+}
+
+// logCorruption appends the incident to .zeta/logs/corruption.log, failures to write the log are ignored.
+func (r *Repository) logCorruption(e *backend.ErrCorruptObject, quarantined bool, healErr error) {
+	kind := "blob"
+	if e.Meta {
+		kind = "metadata"
+	}
+	incident := &corruptionIncident{
+		Time:        time.Now(),
+		OID:         e.OID,
+		Kind:        kind,
+		Error:       e.Err.Error(),
+		Quarantined: quarantined,
+		Repaired:    healErr == nil,
+	}
+	if healErr != nil {
+		incident.HealError = healErr.Error()
+	}
+	line, err := json.Marshal(incident)
+	if err != nil {
+		return
+	}
+	p := corruptionLogPath(r.zetaDir)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return
+	}
+	fd, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	defer fd.Close() // nolint
+	_, _ = fd.Write(append(line, '\n'))
+}
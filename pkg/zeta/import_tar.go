@@ -0,0 +1,359 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+type ImportTarOptions struct {
+	File            string // tarball, '-' reads stdin, gzip compressed tarballs are detected
+	Prefix          string // import into the dir, the rest of the tree is kept; empty replaces the whole tree
+	StripComponents int    // strip leading path components like tar --strip-components
+	Branch          string // branch to commit to, defaults to the current branch
+	Message         string // commit message, defaults to 'Import <file>'
+}
+
+// importEntry: a file of the tarball, path is relative to the prefix.
+type importEntry struct {
+	path string
+	hash plumbing.Hash
+	mode filemode.FileMode
+}
+
+func openTarball(name string) (io.ReadCloser, error) {
+	var fd *os.File
+	if name == "-" {
+		fd = os.Stdin
+	} else {
+		var err error
+		if fd, err = os.Open(name); err != nil {
+			return nil, err
+		}
+	}
+	br := bufio.NewReader(fd)
+	magic, _ := br.Peek(2)
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return &readCloser{Reader: br, closer: fd}, nil
+	}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		_ = fd.Close()
+		return nil, err
+	}
+	return &readCloser{Reader: zr, closer: fd}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (r *readCloser) Close() error {
+	if r.closer == os.Stdin {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// cleanTarPath returns the path of a tarball member after stripping n leading components, the member is skipped when
+// the path is empty. Absolute paths are made relative, '..' and .zeta are rejected.
+func cleanTarPath(name string, n int) (string, bool, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	for p := range strings.SplitSeq(name, "/") {
+		if p == ".." {
+			return "", false, fmt.Errorf("bad path '%s' in tarball", name)
+		}
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if len(name) == 0 {
+		return "", false, nil
+	}
+	for i := 0; i < n; i++ {
+		_, rest, ok := strings.Cut(name, "/")
+		if !ok {
+			return "", false, nil
+		}
+		name = rest
+	}
+	for p := range strings.SplitSeq(name, "/") {
+		if p == ".zeta" {
+			return "", false, fmt.Errorf("bad path '%s' in tarball", name)
+		}
+	}
+	return name, true, nil
+}
+
+// readTarball stores the files of the tarball as blobs (or fragments) and returns them sorted by path.
+func (r *Repository) readTarball(ctx context.Context, reader io.Reader, stripComponents int) ([]*importEntry, error) {
+	entries := make(map[string]*importEntry)
+	tr := tar.NewReader(reader)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name, ok, err := cleanTarPath(hdr.Name, stripComponents)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		e := &importEntry{path: name, mode: filemode.Regular}
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			var fragments bool
+			if e.hash, fragments, err = r.HashTo(ctx, tr, hdr.Size); err != nil {
+				return nil, err
+			}
+			if hdr.Mode&0111 != 0 {
+				e.mode = filemode.Executable
+			}
+			if fragments {
+				e.mode |= filemode.Fragments
+			}
+		case tar.TypeSymlink:
+			if e.hash, err = r.odb.HashTo(ctx, strings.NewReader(hdr.Linkname), int64(len(hdr.Linkname))); err != nil {
+				return nil, err
+			}
+			e.mode = filemode.Symlink
+		case tar.TypeLink:
+			target, ok, err := cleanTarPath(hdr.Linkname, stripComponents)
+			if err != nil {
+				return nil, err
+			}
+			src, found := entries[target]
+			if !ok || !found {
+				return nil, fmt.Errorf("hard link '%s' to unknown file '%s'", hdr.Name, hdr.Linkname)
+			}
+			e.hash, e.mode = src.hash, src.mode
+		default:
+			// dirs are implied by the files, devices and fifos cannot be stored
+			continue
+		}
+		entries[name] = e
+	}
+	files := make([]*importEntry, 0, len(entries))
+	for _, e := range entries {
+		files = append(files, e)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
+
+// writeImportTree writes the trees of the files and returns the hash of the root tree.
+func (r *Repository) writeImportTree(files []*importEntry) (plumbing.Hash, error) {
+	tree := &object.Tree{}
+	for i := 0; i < len(files); {
+		dir, _, ok := strings.Cut(files[i].path, "/")
+		if !ok {
+			tree.Entries = append(tree.Entries, &object.TreeEntry{Name: dir, Mode: files[i].mode, Hash: files[i].hash})
+			i++
+			continue
+		}
+		var sub []*importEntry
+		for ; i < len(files); i++ {
+			d, rest, ok := strings.Cut(files[i].path, "/")
+			if !ok || d != dir {
+				break
+			}
+			sub = append(sub, &importEntry{path: rest, hash: files[i].hash, mode: files[i].mode})
+		}
+		oid, err := r.writeImportTree(sub)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tree.Entries = append(tree.Entries, &object.TreeEntry{Name: dir, Mode: filemode.Dir, Hash: oid})
+	}
+	// a file and a dir of the same name are not adjacent when other names sort between them, eg: a, a-b, a/
+	seen := make(map[string]bool, len(tree.Entries))
+	for _, e := range tree.Entries {
+		if seen[e.Name] {
+			return plumbing.ZeroHash, fmt.Errorf("'%s' is both a file and a dir in tarball", e.Name)
+		}
+		seen[e.Name] = true
+	}
+	sort.Sort(object.SubtreeOrder(tree.Entries))
+	return r.odb.WriteEncoded(tree)
+}
+
+// replaceSubtree returns the tree with the dir at prefix replaced by sub, missing dirs are created.
+func (r *Repository) replaceSubtree(ctx context.Context, root plumbing.Hash, prefix []string, sub plumbing.Hash) (plumbing.Hash, error) {
+	if len(prefix) == 0 {
+		return sub, nil
+	}
+	tree := &object.Tree{}
+	if !root.IsZero() {
+		t, err := r.odb.Tree(ctx, root)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tree.Entries = append(tree.Entries, t.Entries...)
+	}
+	var child plumbing.Hash
+	entries := make([]*object.TreeEntry, 0, len(tree.Entries)+1)
+	for _, e := range tree.Entries {
+		if e.Name != prefix[0] {
+			entries = append(entries, e)
+			continue
+		}
+		if e.Mode == filemode.Dir {
+			child = e.Hash
+		}
+	}
+	oid, err := r.replaceSubtree(ctx, child, prefix[1:], sub)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	entries = append(entries, &object.TreeEntry{Name: prefix[0], Mode: filemode.Dir, Hash: oid})
+	sort.Sort(object.SubtreeOrder(entries))
+	tree.Entries = entries
+	return r.odb.WriteEncoded(tree)
+}
+
+// ImportTar creates a commit from a tarball, eg: vendoring a third-party drop. With Prefix only the dir is replaced
+// by the contents of the tarball. When the branch is checked out the worktree must be clean and is updated.
+func (w *Worktree) ImportTar(ctx context.Context, opts *ImportTarOptions) error {
+	unlock, err := w.lock("import-tar")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	current, _, err := w.current()
+	if err != nil {
+		die_error("resolve HEAD: %v", err)
+		return err
+	}
+	refname := current
+	if len(opts.Branch) != 0 {
+		refname = plumbing.NewBranchReferenceName(opts.Branch)
+	}
+	if !refname.IsBranch() {
+		die_error("reference '%s' not branch", refname)
+		return errors.New("reference not branch")
+	}
+	var oldRev plumbing.Hash
+	ref, err := w.Reference(refname)
+	switch {
+	case err == nil:
+		oldRev = ref.Hash()
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+	default:
+		die_error("resolve %s: %v", refname, err)
+		return err
+	}
+	checkedOut := refname == current
+	if checkedOut {
+		s, err := w.Status(ctx, false)
+		if err != nil {
+			die_error("status: %v", err)
+			return err
+		}
+		for _, fs := range s {
+			if fs.Worktree != Untracked && (fs.Worktree != Unmodified || fs.Staging != Unmodified) {
+				die_error("your local changes would be overwritten by import-tar, commit or stash them first")
+				return ErrAborting
+			}
+		}
+	}
+	var prefix []string
+	if p := strings.Trim(path.Clean("/"+filepath.ToSlash(opts.Prefix)), "/"); len(p) != 0 {
+		prefix = strings.Split(p, "/")
+	}
+	cc := &CommitOptions{}
+	if err := cc.loadConfigAuthorAndCommitter(w.Repository); err != nil {
+		die_error("%v", err)
+		return err
+	}
+
+	fd, err := openTarball(opts.File)
+	if err != nil {
+		die_error("open tarball: %v", err)
+		return err
+	}
+	defer fd.Close() // nolint
+	files, err := w.readTarball(ctx, fd, opts.StripComponents)
+	if err != nil {
+		die_error("read tarball: %v", err)
+		return err
+	}
+	sub, err := w.writeImportTree(files)
+	if err != nil {
+		die_error("write tree: %v", err)
+		return err
+	}
+	var root plumbing.Hash
+	if !oldRev.IsZero() && len(prefix) != 0 {
+		c, err := w.odb.Commit(ctx, oldRev)
+		if err != nil {
+			die_error("resolve %s: %v", refname, err)
+			return err
+		}
+		root = c.Tree
+	}
+	treeOID, err := w.replaceSubtree(ctx, root, prefix, sub)
+	if err != nil {
+		die_error("write tree: %v", err)
+		return err
+	}
+	message := opts.Message
+	if len(message) == 0 {
+		message = fmt.Sprintf("Import %s", filepath.Base(opts.File))
+		if len(prefix) != 0 {
+			message += " into " + strings.Join(prefix, "/")
+		}
+	}
+	var parents []plumbing.Hash
+	if !oldRev.IsZero() {
+		parents = append(parents, oldRev)
+	}
+	newRev, err := w.odb.WriteEncoded(&object.Commit{
+		Author:    cc.Author,
+		Committer: cc.Committer,
+		Parents:   parents,
+		Tree:      treeOID,
+		Message:   strings.TrimRight(message, "\n") + "\n",
+	})
+	if err != nil {
+		die_error("unable encode commit: %v", err)
+		return err
+	}
+	if err := w.DoUpdate(ctx, refname, oldRev, newRev, &cc.Committer, "import-tar: "+message); err != nil {
+		die_error("update %s: %v", refname, err)
+		return err
+	}
+	if checkedOut {
+		if err := w.ResetSparsely(ctx, &ResetOptions{Commit: newRev, Mode: HardReset, Quiet: true}, nil); err != nil {
+			die_error("checkout %s: %v", shortHash(newRev), err)
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, W("Imported %d files into %s as %s\n"), len(files), refname.BranchName(), shortHash(newRev))
+	return nil
+}
@@ -0,0 +1,138 @@
+package zeta
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+)
+
+type tarMember struct {
+	name     string
+	content  string
+	mode     int64
+	typeflag byte
+}
+
+func writeTarball(t *testing.T, name string, compress bool, members ...tarMember) string {
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for _, m := range members {
+		hdr := &tar.Header{Name: m.name, Mode: m.mode, Typeflag: m.typeflag}
+		switch m.typeflag {
+		case tar.TypeReg:
+			hdr.Size = int64(len(m.content))
+		case tar.TypeSymlink, tar.TypeLink:
+			hdr.Linkname = m.content
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if m.typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(m.content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := b.Bytes()
+	if compress {
+		var zb bytes.Buffer
+		zw := gzip.NewWriter(&zb)
+		_, _ = zw.Write(data)
+		_ = zw.Close()
+		data = zb.Bytes()
+	}
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestImportTar(t *testing.T) {
+	ctx := t.Context()
+	worktree := filepath.Join(t.TempDir(), "import")
+	r, err := Init(ctx, &InitOptions{Worktree: worktree, Branch: "mainline", Quiet: true})
+	if err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	defer r.Close() // nolint
+	t.Setenv(ENV_ZETA_AUTHOR_NAME, "zeta")
+	t.Setenv(ENV_ZETA_AUTHOR_EMAIL, "zeta@example.io")
+	t.Setenv(ENV_ZETA_COMMITTER_NAME, "zeta")
+	t.Setenv(ENV_ZETA_COMMITTER_EMAIL, "zeta@example.io")
+	w := r.Worktree()
+
+	app := writeTarball(t, "app-1.0.tar", false,
+		tarMember{name: "app-1.0/", typeflag: tar.TypeDir, mode: 0755},
+		tarMember{name: "app-1.0/README", content: "app\n", typeflag: tar.TypeReg, mode: 0644},
+		tarMember{name: "app-1.0/bin/run", content: "#!/bin/sh\n", typeflag: tar.TypeReg, mode: 0755},
+	)
+	if err := w.ImportTar(ctx, &ImportTarOptions{File: app, StripComponents: 1}); err != nil {
+		t.Fatalf("import error: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(worktree, "README")); err != nil || string(b) != "app\n" {
+		t.Fatalf("worktree not updated: %q %v", b, err)
+	}
+
+	lib := writeTarball(t, "lib-2.1.tar.gz", true,
+		tarMember{name: "lib.h", content: "int f();\n", typeflag: tar.TypeReg, mode: 0644},
+		tarMember{name: "lib.hh", content: "lib.h", typeflag: tar.TypeLink},
+		tarMember{name: "current", content: "lib.h", typeflag: tar.TypeSymlink},
+	)
+	if err := w.ImportTar(ctx, &ImportTarOptions{File: lib, Prefix: "vendor/lib/"}); err != nil {
+		t.Fatalf("import error: %v", err)
+	}
+	current, err := r.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc, err := r.odb.Commit(ctx, current.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cc.Message != "Import lib-2.1.tar.gz into vendor/lib\n" || len(cc.Parents) != 1 {
+		t.Fatalf("unexpected commit: %q parents %v", cc.Message, cc.Parents)
+	}
+	tree, err := r.odb.Tree(ctx, cc.Tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, mode := range map[string]filemode.FileMode{
+		"README":             filemode.Regular,
+		"bin/run":            filemode.Executable,
+		"vendor/lib/lib.h":   filemode.Regular,
+		"vendor/lib/lib.hh":  filemode.Regular,
+		"vendor/lib/current": filemode.Symlink,
+	} {
+		e, err := tree.FindEntry(ctx, p)
+		if err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+		if e.Mode != mode {
+			t.Fatalf("%s: mode %v, expected %v", p, e.Mode, mode)
+		}
+	}
+
+	evil := writeTarball(t, "evil.tar", false, tarMember{name: "../x", content: "x", typeflag: tar.TypeReg, mode: 0644})
+	if err := w.ImportTar(ctx, &ImportTarOptions{File: evil, Branch: "evil"}); err == nil {
+		t.Fatalf("paths outside the tree should be rejected")
+	}
+
+	collision := writeTarball(t, "collision.tar", false,
+		tarMember{name: "a", content: "file\n", typeflag: tar.TypeReg, mode: 0644},
+		tarMember{name: "a-b", content: "between\n", typeflag: tar.TypeReg, mode: 0644},
+		tarMember{name: "a/c", content: "dir\n", typeflag: tar.TypeReg, mode: 0644},
+	)
+	if err := w.ImportTar(ctx, &ImportTarOptions{File: collision, Branch: "collision"}); err == nil || !strings.Contains(err.Error(), "both a file and a dir") {
+		t.Fatalf("a file and a dir of the same name should be rejected: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+const (
+	keepName = "keep"
+)
+
+// KeepList: objects listed in .zeta/keep are never evicted to save space (extreme postflight, one-by-one
+// checkout) and are always fetched, ignoring --limit and --skip-larges. Each line is an object ID, a path or a
+// wildcard pattern relative to the repository root; blank lines and lines starting with '#' are ignored.
+type KeepList struct {
+	oids     map[plumbing.Hash]bool
+	patterns []string
+	m        *Matcher
+}
+
+func ParseKeepList(r io.Reader) (*KeepList, error) {
+	k := &KeepList{oids: make(map[plumbing.Hash]bool)}
+	br := bufio.NewScanner(r)
+	for br.Scan() {
+		line := strings.TrimSpace(br.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if plumbing.ValidateHashHex(line) {
+			k.oids[plumbing.NewHash(line)] = true
+			continue
+		}
+		k.patterns = append(k.patterns, strings.TrimPrefix(filepath.ToSlash(line), "/"))
+	}
+	if err := br.Err(); err != nil {
+		return nil, err
+	}
+	k.m = NewMatcher(k.patterns)
+	return k, nil
+}
+
+// LoadKeepList loads .zeta/keep, a missing file is an empty keep list.
+func LoadKeepList(zetaDir string) (*KeepList, error) {
+	fd, err := os.Open(filepath.Join(zetaDir, keepName))
+	if os.IsNotExist(err) {
+		return &KeepList{oids: make(map[plumbing.Hash]bool), m: NewMatcher(nil)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close() // nolint
+	return ParseKeepList(fd)
+}
+
+func (k *KeepList) Len() int {
+	return len(k.oids) + len(k.patterns)
+}
+
+// Match reports whether the file name with object oid should be kept.
+func (k *KeepList) Match(name string, oid plumbing.Hash) bool {
+	if k.oids[oid] {
+		return true
+	}
+	// an empty matcher matches everything
+	return len(k.patterns) != 0 && k.m.Match(name)
+}
+
+func (r *Repository) keepList() *KeepList {
+	k, err := LoadKeepList(r.zetaDir)
+	if err != nil {
+		warn("read %s: %v", filepath.Join(r.zetaDir, keepName), err)
+		return &KeepList{oids: make(map[plumbing.Hash]bool), m: NewMatcher(nil)}
+	}
+	return k
+}
+
+type keepObjects map[plumbing.Hash]*odb.Entry
+
+func (ko keepObjects) add(ctx context.Context, r *Repository, e *object.TreeEntry) error {
+	if e.Type() != object.FragmentsObject {
+		ko[e.Hash] = &odb.Entry{Hash: e.Hash, Size: e.Size}
+		return nil
+	}
+	ff, err := r.odb.Fragments(ctx, e.Hash)
+	if plumbing.IsNoSuchObject(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, fe := range ff.Entries {
+		ko[fe.Hash] = &odb.Entry{Hash: fe.Hash, Size: int64(fe.Size)}
+	}
+	return nil
+}
+
+func (ko keepObjects) walk(ctx context.Context, r *Repository, k *KeepList, oid plumbing.Hash, parent string) error {
+	t, err := r.odb.Tree(ctx, oid)
+	if plumbing.IsNoSuchObject(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range t.Entries {
+		name := path.Join(parent, e.Name)
+		if e.Type() == object.TreeObject {
+			if err := ko.walk(ctx, r, k, e.Hash, name); err != nil {
+				return err
+			}
+			continue
+		}
+		if k.Match(name, e.Hash) {
+			if err := ko.add(ctx, r, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// keepObjects returns the blobs of commit matched by the keep list, fragments are expanded to their blobs.
+func (r *Repository) keepObjects(ctx context.Context, k *KeepList, commit plumbing.Hash) (keepObjects, error) {
+	ko := make(keepObjects)
+	if k.Len() == 0 {
+		return ko, nil
+	}
+	if !commit.IsZero() {
+		cc, err := r.odb.ParseRevExhaustive(ctx, commit)
+		if err != nil {
+			return nil, err
+		}
+		if err := ko.walk(ctx, r, k, cc.Tree, ""); err != nil {
+			return nil, err
+		}
+	}
+	return ko, nil
+}
+
+// keepFunc reports the objects retained when pruning: listed object IDs and the blobs kept in commit.
+func (r *Repository) keepFunc(ctx context.Context, commit plumbing.Hash) (func(oid plumbing.Hash) bool, error) {
+	k := r.keepList()
+	ko, err := r.keepObjects(ctx, k, commit)
+	if err != nil {
+		return nil, err
+	}
+	return func(oid plumbing.Hash) bool {
+		return k.oids[oid] || ko[oid] != nil
+	}, nil
+}
@@ -0,0 +1,48 @@
+package zeta
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+func TestParseKeepList(t *testing.T) {
+	oid := plumbing.NewHash("4d4a1e8a4a9bf5ab9a5b2a6d1e9d7f0c24d8d8d1a4c6bca4fb1f4b8c9c5ad8e1")
+	k, err := ParseKeepList(strings.NewReader(`# toolchains and models
+` + oid.String() + `
+
+/toolchain/
+models/*.safetensors
+`))
+	if err != nil {
+		t.Fatalf("parse keep list error: %v", err)
+	}
+	if k.Len() != 3 {
+		t.Fatalf("keep list entries: %d", k.Len())
+	}
+	tests := []struct {
+		name string
+		oid  plumbing.Hash
+		want bool
+	}{
+		{"toolchain/bin/gcc", plumbing.ZeroHash, true},
+		{"toolchain", plumbing.ZeroHash, true},
+		{"toolchains/gcc", plumbing.ZeroHash, false},
+		{"models/llm.safetensors", plumbing.ZeroHash, true},
+		{"models/README.md", plumbing.ZeroHash, false},
+		{"data/weights.bin", oid, true},
+	}
+	for _, tt := range tests {
+		if got := k.Match(tt.name, tt.oid); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	empty, err := ParseKeepList(strings.NewReader("# nothing\n"))
+	if err != nil {
+		t.Fatalf("parse keep list error: %v", err)
+	}
+	if empty.Match("README.md", plumbing.ZeroHash) {
+		t.Errorf("empty keep list should not match")
+	}
+}
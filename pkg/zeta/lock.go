@@ -0,0 +1,144 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// lockStaleTimeout: a lock older than this is considered abandoned even if its owner cannot be checked, eg: it
+	// was taken on another host sharing the repository.
+	lockStaleTimeout = 12 * time.Hour
+)
+
+// ErrLocked: another zeta process holds the repository lock.
+type ErrLocked struct {
+	Path string
+	PID  int
+	Host string
+	Op   string
+}
+
+func (e *ErrLocked) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf(W("another zeta process is running in this repository, if it has exited, remove '%s' and try again"), e.Path)
+	}
+	return fmt.Sprintf(W("another zeta process is running in this repository (%s, pid %d on %s), if it has exited, remove '%s' and try again"), e.Op, e.PID, e.Host, e.Path)
+}
+
+func IsErrLocked(err error) bool {
+	var e *ErrLocked
+	return errors.As(err, &e)
+}
+
+// readLockOwner parses the lock file written by acquire: 'pid host operation'.
+func readLockOwner(lockName string) (*ErrLocked, time.Time) {
+	e := &ErrLocked{Path: lockName}
+	si, err := os.Stat(lockName)
+	if err != nil {
+		return e, time.Time{}
+	}
+	b, err := os.ReadFile(lockName)
+	if err != nil {
+		return e, si.ModTime()
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 3 {
+		return e, si.ModTime()
+	}
+	if e.PID, err = strconv.Atoi(fields[0]); err != nil {
+		e.PID = 0
+		return e, si.ModTime()
+	}
+	e.Host, e.Op = fields[1], fields[2]
+	return e, si.ModTime()
+}
+
+// stale: the owner has exited, only processes on this host can be checked.
+func (e *ErrLocked) stale(modTime time.Time) bool {
+	if !modTime.IsZero() && time.Since(modTime) > lockStaleTimeout {
+		return true
+	}
+	if e.PID == 0 {
+		return false
+	}
+	host, err := os.Hostname()
+	if err != nil || host != e.Host {
+		return false
+	}
+	return e.PID != os.Getpid() && !processAlive(e.PID)
+}
+
+// lockManager coordinates zeta processes writing the same repository, gc, fetch, commit, checkout and others that
+// modify the index, references or objects hold the lock. Nested operations in the same process share the lock, eg:
+// pull runs fetch and merge.
+type lockManager struct {
+	mu    sync.Mutex
+	depth int
+}
+
+func (m *lockManager) acquire(lockName, op string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.depth != 0 {
+		m.depth++
+		return nil
+	}
+	host, _ := os.Hostname()
+	if len(host) == 0 {
+		host = "localhost"
+	}
+	for i := 0; ; i++ {
+		fd, err := os.OpenFile(lockName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(fd, "%d %s %s\n", os.Getpid(), host, op)
+			if closeErr := fd.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				_ = os.Remove(lockName)
+				return err
+			}
+			m.depth = 1
+			return nil
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+		owner, modTime := readLockOwner(lockName)
+		if i == 0 && owner.stale(modTime) {
+			_ = os.Remove(lockName)
+			continue
+		}
+		return owner
+	}
+}
+
+func (m *lockManager) release(lockName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.depth--; m.depth == 0 {
+		_ = os.Remove(lockName)
+	}
+}
+
+// lock takes the repository lock for op, call the returned function to release it.
+func (r *Repository) lock(op string) (func(), error) {
+	lockName := filepath.Join(r.zetaDir, "zeta.lock")
+	if err := r.locker.acquire(lockName, op); err != nil {
+		die("%v", err)
+		return nil, err
+	}
+	return func() {
+		r.locker.release(lockName)
+	}, nil
+}
@@ -0,0 +1,67 @@
+package zeta
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockManager(t *testing.T) {
+	lockName := filepath.Join(t.TempDir(), "zeta.lock")
+	var a, b lockManager
+	if err := a.acquire(lockName, "pull"); err != nil {
+		t.Fatalf("acquire error: %v", err)
+	}
+	// nested operations share the lock
+	if err := a.acquire(lockName, "fetch"); err != nil {
+		t.Fatalf("nested acquire error: %v", err)
+	}
+	a.release(lockName)
+	err := b.acquire(lockName, "gc")
+	if e, ok := err.(*ErrLocked); !ok || e.PID != os.Getpid() || e.Op != "pull" {
+		t.Fatalf("acquire locked repository: %v", err)
+	}
+	a.release(lockName)
+	if _, err := os.Stat(lockName); !os.IsNotExist(err) {
+		t.Fatalf("lock not removed: %v", err)
+	}
+	if err := b.acquire(lockName, "gc"); err != nil {
+		t.Fatalf("acquire released lock: %v", err)
+	}
+	b.release(lockName)
+}
+
+func TestLockManagerStale(t *testing.T) {
+	lockName := filepath.Join(t.TempDir(), "zeta.lock")
+	host, _ := os.Hostname()
+	if len(host) == 0 {
+		host = "localhost"
+	}
+	// the pid of an exited process
+	if err := os.WriteFile(lockName, fmt.Appendf(nil, "%d %s commit\n", 1<<22+1, host), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var m lockManager
+	if err := m.acquire(lockName, "gc"); err != nil {
+		t.Fatalf("acquire stale lock: %v", err)
+	}
+	m.release(lockName)
+
+	// another host, the owner cannot be checked
+	if err := os.WriteFile(lockName, []byte("100 other-host commit\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.acquire(lockName, "gc"); !IsErrLocked(err) {
+		t.Fatalf("acquire lock of other host: %v", err)
+	}
+	old := time.Now().Add(-2 * lockStaleTimeout)
+	if err := os.Chtimes(lockName, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.acquire(lockName, "gc"); err != nil {
+		t.Fatalf("acquire expired lock: %v", err)
+	}
+	m.release(lockName)
+}
@@ -258,7 +258,9 @@ func (r *Repository) logFromMergeBase(ctx context.Context, a, b plumbing.Hash, o
 		fmt.Fprintf(os.Stderr, "log commit '%s' error: %v\n", b, err)
 		return err
 	}
+	cg.commits = opts.limitCommits(cg.commits)
 	opts.sort(cg.commits)
+	cg.commits = mailmapCommits(opts.mailmap, cg.commits)
 	if opts.FormatJSON {
 		commits := cg.commits
 		if opts.JSONLimit > 0 && len(commits) > opts.JSONLimit {
@@ -312,6 +314,9 @@ func (r *Repository) logRevFromTo(ctx context.Context, from, to plumbing.Hash, o
 			Order:      opts.Order,
 			PathFilter: newLogPathFilter(opts.Paths),
 			Reverse:    opts.Reverse,
+			Since:      opts.Since,
+			Until:      opts.Until,
+			Mailmap:    opts.mailmap,
 		}, nil, opts.SortFunc(), opts.FormatJSON, opts.JSONLimit)
 	case newRev == nil:
 		return nil
@@ -328,6 +333,9 @@ func (r *Repository) logRevFromTo(ctx context.Context, from, to plumbing.Hash, o
 			Order:      opts.Order,
 			PathFilter: newLogPathFilter(opts.Paths),
 			Reverse:    opts.Reverse,
+			Since:      opts.Since,
+			Until:      opts.Until,
+			Mailmap:    opts.mailmap,
 		}, nil, opts.SortFunc(), opts.FormatJSON, opts.JSONLimit)
 	}
 	ignore := make([]plumbing.Hash, 0, 2)
@@ -343,10 +351,16 @@ func (r *Repository) logRevFromTo(ctx context.Context, from, to plumbing.Hash, o
 		Order:      opts.Order,
 		PathFilter: newLogPathFilter(opts.Paths),
 		Reverse:    opts.Reverse,
+		Since:      opts.Since,
+		Until:      opts.Until,
+		Mailmap:    opts.mailmap,
 	}, ignore, opts.SortFunc(), opts.FormatJSON, opts.JSONLimit)
 }
 
 func (r *Repository) Log(ctx context.Context, opts *LogCommandOptions) error {
+	if !opts.NoMailmap {
+		opts.mailmap = r.LoadMailmap()
+	}
 	if aRev, bRev, ok := strings.Cut(opts.Revision, "..."); ok {
 		a, err := r.Revision(ctx, aRev)
 		if err != nil {
@@ -386,6 +400,9 @@ func (r *Repository) Log(ctx context.Context, opts *LogCommandOptions) error {
 		Order:      opts.Order,
 		PathFilter: newLogPathFilter(opts.Paths),
 		Reverse:    opts.Reverse,
+		Since:      opts.Since,
+		Until:      opts.Until,
+		Mailmap:    opts.mailmap,
 	}, nil, opts.SortFunc(), opts.FormatJSON, opts.JSONLimit)
 }
 
@@ -423,6 +440,10 @@ func (r *Repository) newCommitIter(ctx context.Context, o *LogOptions, ignore []
 		it = r.logWithLimit(it, limitOptions)
 	}
 
+	if o.Mailmap.Len() != 0 {
+		it = newMailmapIter(o.Mailmap, it)
+	}
+
 	return it, nil
 }
 
@@ -129,7 +129,7 @@ func (r *Repository) resolveTree0(ctx context.Context, branchOrTag string) (t *o
 }
 
 func (r *Repository) resolveTree(ctx context.Context, revisionPair string) (*object.Tree, error) {
-	k, v, ok := strings.Cut(revisionPair, ":")
+	k, v, ok := splitRevisionPath(revisionPair)
 	if !ok {
 		return r.resolveTree0(ctx, k)
 	}
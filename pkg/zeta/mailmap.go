@@ -0,0 +1,104 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/antgroup/hugescm/modules/mailmap"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+const (
+	mailmapName = ".mailmap"
+)
+
+func (r *Repository) mailmapFile() string {
+	if s, ok := getStringFromValues("mailmap.file", r.values); ok {
+		return s
+	}
+	return r.Mailmap.File
+}
+
+// LoadMailmap loads the worktree .mailmap and then mailmap.file, entries in mailmap.file take precedence.
+func (r *Repository) LoadMailmap() *mailmap.Mailmap {
+	m := mailmap.New()
+	if len(r.baseDir) != 0 {
+		if err := m.ParseFile(filepath.Join(r.baseDir, mailmapName)); err != nil {
+			warn("read %s: %v", mailmapName, err)
+		}
+	}
+	if p := r.mailmapFile(); len(p) != 0 {
+		p = strengthen.ExpandPath(p)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(r.baseDir, p)
+		}
+		if err := m.ParseFile(p); err != nil {
+			warn("read mailmap.file '%s': %v", p, err)
+		}
+	}
+	return m
+}
+
+func mailmapSignature(m *mailmap.Mailmap, s object.Signature) object.Signature {
+	s.Name, s.Email = m.Lookup(s.Name, s.Email)
+	return s
+}
+
+// mailmapCommit returns a copy of the commit with author and committer rewritten.
+// Commits may be shared with the object cache, so the original is never modified.
+func mailmapCommit(m *mailmap.Mailmap, c *object.Commit) *object.Commit {
+	if m.Len() == 0 {
+		return c
+	}
+	nc := *c
+	nc.Author = mailmapSignature(m, c.Author)
+	nc.Committer = mailmapSignature(m, c.Committer)
+	return &nc
+}
+
+func mailmapCommits(m *mailmap.Mailmap, commits []*object.Commit) []*object.Commit {
+	if m.Len() == 0 {
+		return commits
+	}
+	for i, c := range commits {
+		commits[i] = mailmapCommit(m, c)
+	}
+	return commits
+}
+
+// mailmapBlame rewrites blame line authors in place, BlameResult is owned by the caller.
+func mailmapBlame(m *mailmap.Mailmap, b *BlameResult) {
+	if m.Len() == 0 {
+		return
+	}
+	for _, line := range b.Lines {
+		line.AuthorName, line.Author = m.Lookup(line.AuthorName, line.Author)
+	}
+}
+
+type mailmapIter struct {
+	object.CommitIter
+	m *mailmap.Mailmap
+}
+
+func newMailmapIter(m *mailmap.Mailmap, it object.CommitIter) object.CommitIter {
+	return &mailmapIter{CommitIter: it, m: m}
+}
+
+func (i *mailmapIter) Next(ctx context.Context) (*object.Commit, error) {
+	c, err := i.CommitIter.Next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mailmapCommit(i.m, c), nil
+}
+
+func (i *mailmapIter) ForEach(ctx context.Context, cb func(*object.Commit) error) error {
+	return i.CommitIter.ForEach(ctx, func(c *object.Commit) error {
+		return cb(mailmapCommit(i.m, c))
+	})
+}
@@ -0,0 +1,202 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/antgroup/hugescm/modules/diferenco"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+// MergeLogEntry: a merged commit listed in the merge message.
+type MergeLogEntry struct {
+	Hash    string
+	Short   string
+	Subject string
+}
+
+// MergeMessageData: fields of merge.messageTemplate.
+type MergeMessageData struct {
+	// Branch: the merged revision as given on the command line
+	Branch string
+	// Into: the current branch
+	Into string
+	// Description: branch.<Branch>.description
+	Description string
+	// Commits: merged commits, newest first, at most merge.log commits
+	Commits []*MergeLogEntry
+	// More: more commits were merged than listed
+	More bool
+}
+
+// mergeLog returns merge.log, -X merge.log takes precedence.
+func (w *Worktree) mergeLog() int {
+	if s, ok := getStringFromValues("merge.log", w.values); ok {
+		var n config.MergeLog
+		if err := n.UnmarshalText([]byte(s)); err == nil {
+			return int(n)
+		}
+	}
+	return int(w.Config.Merge.Log)
+}
+
+func (w *Worktree) mergeMessageTemplate() string {
+	if s, ok := getStringFromValues("merge.messageTemplate", w.values); ok {
+		return s
+	}
+	return w.Config.Merge.MessageTemplate
+}
+
+// makeMergeMessage returns the default message merging from into the current branch. Like git fmt-merge-msg the
+// subjects of the merged commits are listed when merge.log is set, the branch description is listed when
+// branch.<name>.description is set.
+func (w *Worktree) makeMergeMessage(ctx context.Context, into, from plumbing.Hash, branch, intoName string) (string, error) {
+	data := &MergeMessageData{
+		Branch:      branch,
+		Into:        intoName,
+		Description: strings.TrimSpace(w.Config.Branch.Description(branch)),
+	}
+	if limit := w.mergeLog(); limit > 0 {
+		commits, err := w.revList(ctx, from, []plumbing.Hash{into}, LogOrderTopo, nil)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range commits {
+			if len(data.Commits) == limit {
+				data.More = true
+				break
+			}
+			data.Commits = append(data.Commits, &MergeLogEntry{Hash: c.Hash.String(), Short: shortHash(c.Hash), Subject: c.Subject()})
+		}
+	}
+	if text := w.mergeMessageTemplate(); len(text) != 0 {
+		tmpl, err := template.New("merge.messageTemplate").Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("bad merge.messageTemplate: %w", err)
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return "", fmt.Errorf("bad merge.messageTemplate: %w", err)
+		}
+		return b.String(), nil
+	}
+	return data.String(), nil
+}
+
+// String formats the message like git fmt-merge-msg:
+//
+//	Merge branch 'topic' into main
+//
+//	* topic:
+//	  : description
+//	  subject of the newest commit
+//	  ...
+func (d *MergeMessageData) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Merge branch '%s' into %s\n", d.Branch, d.Into)
+	if len(d.Description) == 0 && len(d.Commits) == 0 {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "\n* %s:\n", d.Branch)
+	if len(d.Description) != 0 {
+		for line := range strings.SplitSeq(d.Description, "\n") {
+			fmt.Fprintf(&b, "  : %s\n", strings.TrimRight(line, " \t\r"))
+		}
+	}
+	for _, c := range d.Commits {
+		fmt.Fprintf(&b, "  %s\n", c.Subject)
+	}
+	if d.More {
+		b.WriteString("  ...\n")
+	}
+	return b.String()
+}
+
+// conflictPaths returns the paths of the conflicts, sorted.
+func conflictPaths(conflicts []*odb.Conflict) []string {
+	seen := make(map[string]bool)
+	paths := make([]string, 0, len(conflicts))
+	for _, c := range conflicts {
+		for _, p := range []string{c.Our.Path, c.Their.Path, c.Ancestor.Path} {
+			if len(p) != 0 && !seen[p] {
+				seen[p] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// saveMergeMessage writes the message to MERGE_MSG when the merge stops at conflicts, the conflicted paths are listed
+// as comments. zeta merge --continue edits it.
+func (w *Worktree) saveMergeMessage(message string, conflicts []*odb.Conflict) error {
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(message, "\n"))
+	b.WriteString("\n")
+	if paths := conflictPaths(conflicts); len(paths) != 0 {
+		b.WriteString("\n# Conflicts:\n")
+		for _, p := range paths {
+			fmt.Fprintf(&b, "#\t%s\n", p)
+		}
+	}
+	return os.WriteFile(filepath.Join(w.odb.Root(), MERGE_MSG), []byte(b.String()), 0644)
+}
+
+// savedMergeMessage returns the content of MERGE_MSG saved by a conflicting merge.
+func (w *Worktree) savedMergeMessage() (string, bool) {
+	b, err := os.ReadFile(filepath.Join(w.odb.Root(), MERGE_MSG))
+	if err != nil || len(b) == 0 {
+		return "", false
+	}
+	return strings.TrimRight(string(b), "\n"), true
+}
+
+// savedMergeConflicts returns the conflicted paths listed in MERGE_MSG by saveMergeMessage.
+func (w *Worktree) savedMergeConflicts() []string {
+	message, ok := w.savedMergeMessage()
+	if !ok {
+		return nil
+	}
+	_, list, ok := strings.Cut(message, "\n# Conflicts:\n")
+	if !ok {
+		return nil
+	}
+	var paths []string
+	for line := range strings.SplitSeq(list, "\n") {
+		if p, ok := strings.CutPrefix(line, "#\t"); ok {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// hasConflictMarkers reports whether the text still contains a conflict: a line starting with <<<<<<< followed by a
+// line starting with >>>>>>>.
+func hasConflictMarkers(r io.Reader) bool {
+	br := bufio.NewScanner(r)
+	br.Buffer(make([]byte, 64*1024), 1024*1024)
+	var begin bool
+	for br.Scan() {
+		line := br.Text()
+		switch {
+		case strings.HasPrefix(line, diferenco.Sep1):
+			begin = true
+		case begin && strings.HasPrefix(line, diferenco.Sep3):
+			return true
+		}
+	}
+	return false
+}
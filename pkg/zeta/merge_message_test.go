@@ -0,0 +1,125 @@
+package zeta
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+func TestMakeMergeMessage(t *testing.T) {
+	ctx := t.Context()
+	r, f := newTestRepository(t, "merge")
+	base := f.commit("base\n", nil)
+	a := f.commit("feat: first\n", nil, base)
+	b := f.commit("fix: second\n", nil, a)
+	c := f.commit("docs: third\n", nil, b)
+
+	w := r.Worktree()
+	message, err := w.makeMergeMessage(ctx, base, c, "topic", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message != "Merge branch 'topic' into main\n" {
+		t.Fatalf("unexpected default message: %q", message)
+	}
+
+	w.Config.Merge.Log = 2
+	w.Config.Branch = config.Branch{"topic.description": "Resumable downloads\nof large files"}
+	message, err = w.makeMergeMessage(ctx, base, c, "topic", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Merge branch 'topic' into main\n\n* topic:\n  : Resumable downloads\n  : of large files\n  docs: third\n  fix: second\n  ...\n"
+	if message != expected {
+		t.Fatalf("unexpected message:\n%s\nexpected:\n%s", message, expected)
+	}
+
+	w.Config.Merge.MessageTemplate = "Merge {{.Branch}} into {{.Into}}{{range .Commits}}\n- {{.Subject}}{{end}}{{if .More}}\n- ...{{end}}\n"
+	message, err = w.makeMergeMessage(ctx, base, c, "topic", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message != "Merge topic into main\n- docs: third\n- fix: second\n- ...\n" {
+		t.Fatalf("unexpected template message: %q", message)
+	}
+	w.Config.Merge.MessageTemplate = "{{.Unknown}}"
+	if _, err := w.makeMergeMessage(ctx, base, c, "topic", "main"); err == nil {
+		t.Fatalf("bad template should fail")
+	}
+
+	conflicts := []*odb.Conflict{
+		{Our: odb.ConflictEntry{Path: "b.txt"}, Their: odb.ConflictEntry{Path: "b.txt"}},
+		{Ancestor: odb.ConflictEntry{Path: "a.txt"}, Our: odb.ConflictEntry{Path: "a.txt"}},
+	}
+	if err := w.saveMergeMessage("Merge branch 'topic' into main\n", conflicts); err != nil {
+		t.Fatal(err)
+	}
+	saved, ok := w.savedMergeMessage()
+	if !ok || saved != "Merge branch 'topic' into main\n\n# Conflicts:\n#\ta.txt\n#\tb.txt" {
+		t.Fatalf("unexpected MERGE_MSG: %q", saved)
+	}
+	if got, _ := messageReadFrom(strings.NewReader(saved)); got != "Merge branch 'topic' into main\n" {
+		t.Fatalf("conflicts should be comments: %q", got)
+	}
+	if paths := w.savedMergeConflicts(); strings.Join(paths, ",") != "a.txt,b.txt" {
+		t.Fatalf("unexpected conflicts: %v", paths)
+	}
+	_ = os.Remove(filepath.Join(r.ODB().Root(), MERGE_MSG))
+}
+
+func TestMergeConflictLabels(t *testing.T) {
+	ctx := t.Context()
+	r, f := newTestRepository(t, "merge")
+	commit := func(content string, parents ...plumbing.Hash) *object.Commit {
+		return f.object(f.commit(content, map[string]string{"a.txt": content}, parents...))
+	}
+	base := commit("1\n2\n3\n")
+	ours := commit("1\nours\n3\n", base.Hash)
+	theirs := commit("1\ntheirs\n3\n", base.Hash)
+
+	r.Config.Merge.ConflictStyle = "diff3"
+	for _, c := range []struct {
+		labels   *conflictLabels
+		expected string
+	}{
+		{nil, "1\n<<<<<<< main\nours\n||||||| " + shortHash(base.Hash) + "\n2\n=======\ntheirs\n>>>>>>> topic\n3\n"},
+		{&conflictLabels{ours: "HEAD", base: "base"}, "1\n<<<<<<< HEAD\nours\n||||||| base\n2\n=======\ntheirs\n>>>>>>> topic\n3\n"},
+	} {
+		result, err := r.mergeTree(ctx, ours, theirs, nil, "main", "topic", c.labels, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Conflicts) != 1 {
+			t.Fatalf("expected one conflict, got %d", len(result.Conflicts))
+		}
+		tree, err := r.odb.Tree(ctx, result.NewTree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		br, err := r.odb.Blob(ctx, tree.Entries[0].Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(br.Contents)
+		_ = br.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != c.expected {
+			t.Fatalf("unexpected conflict markers:\n%s\nexpected:\n%s", b, c.expected)
+		}
+		if !hasConflictMarkers(strings.NewReader(string(b))) {
+			t.Fatalf("conflict markers not detected")
+		}
+	}
+	if hasConflictMarkers(strings.NewReader("1\n<<<<<<< not a conflict\n3\n")) {
+		t.Fatalf("unexpected conflict markers")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/antgroup/hugescm/modules/diferenco"
@@ -47,17 +48,50 @@ func (r *Repository) readMissingText(ctx context.Context, oid plumbing.Hash, tex
 	return diferenco.ReadUnifiedText(br.Contents, br.Size, textconv)
 }
 
-func (o *MergeTreeOptions) formatJson(result *odb.MergeResult) {
-	if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+type mergeTreeConflict struct {
+	*odb.Conflict
+	Type string `json:"type"` // name of types, eg: modify/delete
+}
+
+// mergeTreeReport: merge-tree --json output
+type mergeTreeReport struct {
+	NewTree    plumbing.Hash        `json:"new-tree"`
+	MergeBases []plumbing.Hash      `json:"merge-bases,omitempty"`
+	Clean      bool                 `json:"clean"`
+	Conflicts  []*mergeTreeConflict `json:"conflicts,omitempty"`
+	Messages   []string             `json:"messages,omitempty"`
+}
+
+func (o *MergeTreeOptions) formatJson(w io.Writer, mr *mergeTreeResult) {
+	report := &mergeTreeReport{
+		NewTree:    mr.NewTree,
+		MergeBases: mr.bases,
+		Clean:      len(mr.Conflicts) == 0,
+		Conflicts:  make([]*mergeTreeConflict, 0, len(mr.Conflicts)),
+		Messages:   mr.Messages,
+	}
+	for _, c := range mr.Conflicts {
+		report.Conflicts = append(report.Conflicts, &mergeTreeConflict{Conflict: c, Type: odb.ConflictTypeName(c.Types)})
+	}
+	b, err := json.Marshal(report)
+	if err != nil {
 		die("format to json error: %v", err)
+		return
+	}
+	// -z: NUL terminated, the document can be read from a stream without parsing
+	b = append(b, '\n')
+	if o.Z {
+		b[len(b)-1] = 0
 	}
+	_, _ = w.Write(b)
 }
 
-func (o *MergeTreeOptions) format(result *odb.MergeResult) {
+func (o *MergeTreeOptions) format(mr *mergeTreeResult) {
 	if o.JSON {
-		o.formatJson(result)
+		o.formatJson(os.Stdout, mr)
 		return
 	}
+	result := mr.MergeResult
 	NewLine := byte('\n')
 	if o.Z {
 		NewLine = '\x00'
@@ -197,7 +231,25 @@ func (r *Repository) resolveAncestorTree(ctx context.Context, into, from, base *
 	return baseOIDs, o, nil
 }
 
-func (r *Repository) mergeTree(ctx context.Context, into, from, base *object.Commit, branch1, branch2 string, allowUnrelatedHistories, textconv bool) (*mergeTreeResult, error) {
+// conflictLabels: labels of the conflict markers, empty labels use the defaults of mergeTree.
+type conflictLabels struct {
+	ours, theirs, base string
+}
+
+// mergeBaseLabel returns the label of the merge base shown by the diff3 and zdiff3 conflict styles.
+func mergeBaseLabel(bases []plumbing.Hash) string {
+	switch len(bases) {
+	case 0:
+		return "empty tree"
+	case 1:
+		return shortHash(bases[0])
+	}
+	return "merged common ancestors"
+}
+
+// mergeTree merges from into into, the conflict markers are labeled with branch1, branch2 and the merge base unless
+// labels overrides them.
+func (r *Repository) mergeTree(ctx context.Context, into, from, base *object.Commit, branch1, branch2 string, labels *conflictLabels, allowUnrelatedHistories, textconv bool) (*mergeTreeResult, error) {
 	mergeDriver := r.resolveMergeDriver()
 	bases, o, err := r.resolveAncestorTree(ctx, into, from, base, mergeDriver, allowUnrelatedHistories, textconv)
 	if err != nil {
@@ -217,14 +269,23 @@ func (r *Repository) mergeTree(ctx context.Context, into, from, base *object.Com
 	if a.Equal(b) {
 		return &mergeTreeResult{MergeResult: &odb.MergeResult{NewTree: a.Hash}, bases: bases}, nil
 	}
-	result, err := r.odb.MergeTree(ctx, o, a, b, &odb.MergeOptions{
+	mo := &odb.MergeOptions{
 		Branch1:       branch1,
 		Branch2:       branch2,
 		DetectRenames: true,
 		Textconv:      textconv,
 		MergeDriver:   mergeDriver,
 		TextResolver:  r.readMissingText,
-	})
+		LabelO:        mergeBaseLabel(bases),
+		LabelA:        branch1,
+		LabelB:        branch2,
+	}
+	if labels != nil {
+		mo.LabelO = odb.LabelOr(labels.base, mo.LabelO)
+		mo.LabelA = odb.LabelOr(labels.ours, mo.LabelA)
+		mo.LabelB = odb.LabelOr(labels.theirs, mo.LabelB)
+	}
+	result, err := r.odb.MergeTree(ctx, o, a, b, mo)
 	if err != nil {
 		die_error("merge-tree: %v", err)
 		return nil, err
@@ -240,25 +301,26 @@ func (r *Repository) MergeTree(ctx context.Context, opts *MergeTreeOptions) erro
 	}
 	c2, err := r.parseRevExhaustive(ctx, opts.Branch2)
 	if err != nil {
-		die_error("parse-rev '%s': %v", opts.Branch1, err)
+		die_error("parse-rev '%s': %v", opts.Branch2, err)
 		return err
 	}
 	var base *object.Commit
 	if len(opts.MergeBase) != 0 {
 		if base, err = r.parseRevExhaustive(ctx, opts.MergeBase); err != nil {
-			die_error("parse-rev '%s': %v", opts.Branch1, err)
+			die_error("parse-rev '%s': %v", opts.MergeBase, err)
 			return err
 		}
 	}
-	result, err := r.mergeTree(ctx, c1, c2, base, opts.Branch1, opts.Branch2, opts.AllowUnrelatedHistories, opts.Textconv)
+	result, err := r.mergeTree(ctx, c1, c2, base, opts.Branch1, opts.Branch2, nil, opts.AllowUnrelatedHistories, opts.Textconv)
 	if err != nil {
 		if mr, ok := errors.AsType[*odb.MergeResult](err); ok {
-			opts.format(mr)
+			// conflicts while merging the merge bases
+			opts.format(&mergeTreeResult{MergeResult: mr})
 			return ErrHasConflicts
 		}
 		return err
 	}
-	opts.format(result.MergeResult)
+	opts.format(result)
 	if len(result.Conflicts) != 0 {
 		return ErrHasConflicts
 	}
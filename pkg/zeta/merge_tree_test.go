@@ -0,0 +1,46 @@
+package zeta
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+func TestMergeTreeJSON(t *testing.T) {
+	ctx := t.Context()
+	// the fixture dates are fixed, the golden files contain the hashes of commits
+	r, f := newTestRepository(t, "merge-tree")
+	base := f.commit("base\n", map[string]string{"a.txt": "1\n2\n3\n", "b.txt": "b\n"})
+	ours := f.object(f.commit("ours\n", map[string]string{"a.txt": "1\nours\n3\n"}, base))
+	theirs := f.object(f.commit("theirs\n", map[string]string{"a.txt": "1\ntheirs\n3\n"}, base))
+	other := f.object(f.commit("other\n", map[string]string{"b.txt": "b\nother\n"}, base))
+
+	for _, c := range []struct {
+		golden        string
+		into, from    *object.Commit
+		expectedClean bool
+	}{
+		{"merge-tree-clean.json", ours, other, true},
+		{"merge-tree-conflict.json", ours, theirs, false},
+	} {
+		result, err := r.mergeTree(ctx, c.into, c.from, nil, "ours", "theirs", nil, false, false)
+		if err != nil {
+			t.Fatalf("%s: merge-tree error: %v", c.golden, err)
+		}
+		if clean := len(result.Conflicts) == 0; clean != c.expectedClean {
+			t.Fatalf("%s: clean %v, expected %v", c.golden, clean, c.expectedClean)
+		}
+		var b bytes.Buffer
+		(&MergeTreeOptions{JSON: true}).formatJson(&b, result)
+		expected, err := os.ReadFile(filepath.Join("testdata", c.golden))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b.Bytes(), expected) {
+			t.Fatalf("%s: unexpected merge-tree --json:\n%s\nexpected:\n%s", c.golden, b.Bytes(), expected)
+		}
+	}
+}
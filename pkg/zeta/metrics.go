@@ -0,0 +1,110 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/statsd"
+)
+
+const (
+	defaultMetricsPrefix = "zeta"
+)
+
+type metricsSettings struct {
+	statsd string
+	prefix string
+	tags   []string
+	repo   string
+}
+
+// metricsRepo: the path of the remote, credentials and host are not exported.
+func metricsRepo(remote string) string {
+	if len(remote) == 0 {
+		return ""
+	}
+	u, err := url.Parse(remote)
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(u.Path, "/")
+}
+
+func resolveMetrics(cwd string, values []string) (*metricsSettings, error) {
+	cfg, err := LoadConfig(cwd, values)
+	if err != nil {
+		return nil, err
+	}
+	m := valuesMapArray(values)
+	s := &metricsSettings{
+		statsd: cfg.Metrics.Statsd,
+		prefix: cfg.Metrics.Prefix,
+		tags:   cfg.Metrics.Tags,
+		repo:   metricsRepo(cfg.Core.Remote),
+	}
+	if v, ok := getFromValueOrEnv("metrics.statsd", ENV_ZETA_METRICS_STATSD, m); ok {
+		s.statsd = v
+	}
+	if v, ok := getStringFromValues("metrics.prefix", m); ok {
+		s.prefix = v
+	}
+	if len(s.prefix) == 0 {
+		s.prefix = defaultMetricsPrefix
+	}
+	if sa, ok := getStringsFromValues("metrics.tags", m); ok {
+		s.tags = sa
+	}
+	// ZETA_METRICS_TAGS=job:build,pool:linux is added to the configured tags, CI systems set it per job
+	if v, ok := os.LookupEnv(ENV_ZETA_METRICS_TAGS); ok {
+		for t := range strings.SplitSeq(v, ",") {
+			if t = strings.TrimSpace(t); len(t) != 0 {
+				s.tags = append(s.tags, t)
+			}
+		}
+	}
+	return s, nil
+}
+
+// RecordMetrics sends the duration, the transferred bytes and the blob cache counts of the command to metrics.statsd,
+// metrics are tagged by command, repository and status. Failures are ignored: metrics must never break a command.
+func RecordMetrics(cwd string, values []string, name string, d time.Duration, err error) {
+	s, e := resolveMetrics(cwd, values)
+	if e != nil || len(s.statsd) == 0 {
+		return
+	}
+	c, e := statsd.New(s.statsd, s.prefix, s.tags)
+	if e != nil {
+		return
+	}
+	defer c.Close() // nolint
+	status := "ok"
+	if err != nil {
+		status = telemetryCategory(err)
+	}
+	tags := []string{statsd.Tag("command", name), statsd.Tag("status", status)}
+	if len(s.repo) != 0 {
+		tags = append(tags, statsd.Tag("repo", s.repo))
+	}
+	c.Timing("command.duration", d, tags...)
+	c.Count("command.count", 1, tags...)
+	if n := transferReceived.Load(); n != 0 {
+		c.Count("transfer.received_bytes", n, tags...)
+	}
+	if n := transferSent.Load(); n != 0 {
+		c.Count("transfer.sent_bytes", n, tags...)
+	}
+	if n := cacheHits.Load(); n != 0 {
+		c.Count("cache.hits", n, tags...)
+	}
+	if n := cacheMisses.Load(); n != 0 {
+		c.Count("cache.misses", n, tags...)
+	}
+	if n := cacheEvictions.Load(); n != 0 {
+		c.Count("cache.evictions", n, tags...)
+	}
+}
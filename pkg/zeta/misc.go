@@ -29,6 +29,15 @@ const (
 	ENV_ZETA_CORE_CONCURRENT_TRANSFERS = "ZETA_CORE_CONCURRENT_TRANSFERS"
 	ENV_ZETA_CORE_SHARING_ROOT         = "ZETA_CORE_SHARING_ROOT"
 	ENV_ZETA_CORE_PROMISOR             = "ZETA_CORE_PROMISOR"
+	ENV_ZETA_CORE_ENCRYPT_OBJECTS      = "ZETA_CORE_ENCRYPT_OBJECTS"
+	ENV_ZETA_CORE_IGNORE_COMPAT        = "ZETA_CORE_IGNORE_COMPAT"
+	ENV_ZETA_CORE_ORG_TEMPLATES        = "ZETA_CORE_ORG_TEMPLATES"
+	ENV_ZETA_CORE_CACHE_SIZE           = "ZETA_CORE_CACHE_SIZE"
+	ENV_ZETA_UPDATE_ENDPOINT           = "ZETA_UPDATE_ENDPOINT"
+	ENV_ZETA_TELEMETRY_ENABLED         = "ZETA_TELEMETRY_ENABLED"
+	ENV_ZETA_TELEMETRY_ENDPOINT        = "ZETA_TELEMETRY_ENDPOINT"
+	ENV_ZETA_METRICS_STATSD            = "ZETA_METRICS_STATSD"
+	ENV_ZETA_METRICS_TAGS              = "ZETA_METRICS_TAGS"
 	ENV_ZETA_AUTHOR_NAME               = "ZETA_AUTHOR_NAME"
 	ENV_ZETA_AUTHOR_EMAIL              = "ZETA_AUTHOR_EMAIL"
 	ENV_ZETA_AUTHOR_DATE               = "ZETA_AUTHOR_DATE"
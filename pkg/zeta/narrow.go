@@ -0,0 +1,216 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/merkletrie/noder"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+// ErrOutsideNarrow: the operation needs a path that was excluded by core.narrow, the client never received the
+// trees below it.
+type ErrOutsideNarrow struct {
+	Path   string
+	Narrow []string
+	Commit plumbing.Hash
+}
+
+func (e *ErrOutsideNarrow) Error() string {
+	if !e.Commit.IsZero() {
+		return fmt.Sprintf(W("commit %s modifies '%s', which is outside the narrow spec (core.narrow: %s)"), e.Commit, e.Path, strings.Join(e.Narrow, " "))
+	}
+	return fmt.Sprintf(W("'%s' is outside the narrow spec (core.narrow: %s)"), e.Path, strings.Join(e.Narrow, " "))
+}
+
+func IsErrOutsideNarrow(err error) bool {
+	var e *ErrOutsideNarrow
+	return errors.As(err, &e)
+}
+
+// narrowContains reports whether dir is one of the narrow dirs or below one of them.
+func narrowContains(narrow []string, dir string) bool {
+	for _, n := range narrow {
+		if dir == n || strings.HasPrefix(dir, n+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveSparse: sparse dirs must stay inside the narrow dirs, without any the whole narrow spec is checked out.
+func effectiveSparse(narrow, sparse []string) ([]string, []string) {
+	if len(narrow) == 0 {
+		return sparse, nil
+	}
+	dirs := make([]string, 0, len(sparse))
+	var outside []string
+	for _, s := range sparse {
+		if narrowContains(narrow, s) {
+			dirs = append(dirs, s)
+			continue
+		}
+		outside = append(outside, s)
+	}
+	if len(dirs) == 0 {
+		return narrow, outside
+	}
+	return dirs, outside
+}
+
+// checkNarrowPath: like sparse checkout, files in the ancestors of narrow dirs are available.
+func (r *Repository) checkNarrowPath(p string) error {
+	if len(r.Core.NarrowDirs) == 0 || len(p) == 0 {
+		return nil
+	}
+	p = path.Clean(strings.TrimPrefix(p, "/"))
+	if noder.NewSparseMatcher(r.Core.NarrowDirs).Match(p) {
+		return nil
+	}
+	return &ErrOutsideNarrow{Path: p, Narrow: r.Core.NarrowDirs}
+}
+
+func (r *Repository) narrowParentTrees(ctx context.Context, parents []*object.Tree, name string) []*object.Tree {
+	trees := make([]*object.Tree, 0, len(parents))
+	for _, p := range parents {
+		e, err := p.Entry(name)
+		if err != nil || e.Type() != object.TreeObject {
+			continue
+		}
+		t, err := r.odb.Tree(ctx, e.Hash)
+		if err != nil {
+			continue
+		}
+		trees = append(trees, t)
+	}
+	return trees
+}
+
+func narrowEntryUnchanged(parents []*object.Tree, e *object.TreeEntry) bool {
+	for _, p := range parents {
+		if pe, err := p.Entry(e.Name); err == nil && pe.Hash == e.Hash && pe.Mode == e.Mode {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNarrowTree: trees outside the narrow spec are grafted from the parents by hash, anything else means the
+// commit changes content the client does not have.
+func (r *Repository) checkNarrowTree(ctx context.Context, m noder.Matcher, t *object.Tree, parents []*object.Tree, parent string) error {
+	for _, e := range t.Entries {
+		name := path.Join(parent, e.Name)
+		sub, ok := m.Match(e.Name)
+		if ok && sub.Len() == 0 {
+			continue
+		}
+		if e.Type() != object.TreeObject {
+			// files in the ancestors of narrow dirs
+			continue
+		}
+		if ok {
+			st, err := r.odb.Tree(ctx, e.Hash)
+			if err != nil {
+				return err
+			}
+			if err := r.checkNarrowTree(ctx, sub, st, r.narrowParentTrees(ctx, parents, e.Name), name); err != nil {
+				return err
+			}
+			continue
+		}
+		if !narrowEntryUnchanged(parents, e) {
+			return &ErrOutsideNarrow{Path: name}
+		}
+	}
+	if len(parents) == 0 {
+		return nil
+	}
+	// excluded trees removed by this commit
+	for _, e := range parents[0].Entries {
+		if e.Type() != object.TreeObject {
+			continue
+		}
+		if _, ok := m.Match(e.Name); ok {
+			continue
+		}
+		if _, err := t.Entry(e.Name); err == nil {
+			continue
+		}
+		removed := true
+		for _, p := range parents[1:] {
+			if _, err := p.Entry(e.Name); err != nil {
+				removed = false
+				break
+			}
+		}
+		if removed {
+			return &ErrOutsideNarrow{Path: path.Join(parent, e.Name)}
+		}
+	}
+	return nil
+}
+
+// checkNarrowPush checks the commits the remote does not have yet, stopping at theirs, the remote-tracking
+// references and the shallow boundary.
+func (r *Repository) checkNarrowPush(ctx context.Context, newRev, theirs plumbing.Hash, ignore []plumbing.Hash) error {
+	if len(r.Core.NarrowDirs) == 0 {
+		return nil
+	}
+	c, err := r.odb.ParseRevExhaustive(ctx, newRev)
+	if err != nil {
+		return err
+	}
+	seen := make(map[plumbing.Hash]bool)
+	if !theirs.IsZero() {
+		seen[theirs] = true
+	}
+	if rdb, err := r.References(); err == nil {
+		for _, ref := range rdb.References() {
+			if ref.Type() == plumbing.HashReference && ref.Name().IsRemote() {
+				seen[ref.Hash()] = true
+			}
+		}
+	}
+	m := noder.NewSparseTreeMatcher(r.Core.NarrowDirs)
+	iter := object.NewCommitIterBFS(c, seen, ignore)
+	return iter.ForEach(ctx, func(cc *object.Commit) error {
+		parents := make([]*object.Tree, 0, len(cc.Parents))
+		for _, h := range cc.Parents {
+			pc, err := r.odb.Commit(ctx, h)
+			if plumbing.IsNoSuchObject(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			root, err := r.odb.Tree(ctx, pc.Tree)
+			if err != nil {
+				return err
+			}
+			parents = append(parents, root)
+		}
+		// commits without local parents come from the server, or only contain what was written in the narrow worktree
+		if len(parents) == 0 {
+			return nil
+		}
+		root, err := r.odb.Tree(ctx, cc.Tree)
+		if err != nil {
+			return err
+		}
+		if err := r.checkNarrowTree(ctx, m, root, parents, ""); err != nil {
+			if e, ok := errors.AsType[*ErrOutsideNarrow](err); ok {
+				e.Commit = cc.Hash
+				e.Narrow = r.Core.NarrowDirs
+			}
+			return err
+		}
+		return nil
+	})
+}
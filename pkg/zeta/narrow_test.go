@@ -0,0 +1,65 @@
+package zeta
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+func TestEffectiveSparse(t *testing.T) {
+	narrow := []string{"team-a", "libs/common"}
+	tests := []struct {
+		sparse  []string
+		want    []string
+		outside []string
+	}{
+		{nil, narrow, nil},
+		{[]string{"team-a/docs"}, []string{"team-a/docs"}, nil},
+		{[]string{"team-a/docs", "team-b", "libs"}, []string{"team-a/docs"}, []string{"team-b", "libs"}},
+		{[]string{"team-ab"}, narrow, []string{"team-ab"}},
+	}
+	for _, tt := range tests {
+		got, outside := effectiveSparse(narrow, tt.sparse)
+		if !slices.Equal(got, tt.want) || !slices.Equal(outside, tt.outside) {
+			t.Errorf("effectiveSparse(%v) = %v %v, want %v %v", tt.sparse, got, outside, tt.want, tt.outside)
+		}
+	}
+}
+
+func TestCheckNarrowPush(t *testing.T) {
+	ctx := t.Context()
+	r, f := newTestRepository(t, "narrow")
+	r.Core.NarrowDirs = []string{"team-a"}
+	if err := r.checkNarrowPath("team-a/src/main.go"); err != nil {
+		t.Errorf("check team-a: %v", err)
+	}
+	if err := r.checkNarrowPath("README.md"); err != nil {
+		t.Errorf("check README.md: %v", err)
+	}
+	if err := r.checkNarrowPath("team-b/main.go"); !IsErrOutsideNarrow(err) {
+		t.Errorf("check team-b: %v", err)
+	}
+
+	base := f.commit("test\n", map[string]string{"README.md": "v1", "team-a/a.txt": "a1", "team-b/b.txt": "b1"})
+	ours := f.commit("test\n", map[string]string{"README.md": "v2", "team-a/a.txt": "a2"}, base)
+	if err := r.checkNarrowPush(ctx, ours, plumbing.ZeroHash, nil); err != nil {
+		t.Fatalf("changes inside narrow: %v", err)
+	}
+	modified := f.commit("test\n", map[string]string{"team-b/b.txt": "b2"}, ours)
+	err := r.checkNarrowPush(ctx, modified, plumbing.ZeroHash, nil)
+	if e, ok := err.(*ErrOutsideNarrow); !ok || e.Path != "team-b" || e.Commit != modified {
+		t.Fatalf("modify team-b: %v", err)
+	}
+	if err := r.checkNarrowPush(ctx, modified, modified, nil); err != nil {
+		t.Fatalf("pushed commits are not checked: %v", err)
+	}
+	removed := f.commitWith("test\n", func(b *CommitBuilder) {
+		if err := b.Remove("team-b"); err != nil {
+			t.Fatal(err)
+		}
+	}, ours)
+	if err := r.checkNarrowPush(ctx, removed, plumbing.ZeroHash, nil); !IsErrOutsideNarrow(err) {
+		t.Fatalf("remove team-b: %v", err)
+	}
+}
@@ -55,31 +55,30 @@ const (
 	CONFLICT_DIR_RENAME_SPLIT
 )
 
-// var (
-// 	mergeDescription = map[int]string{
-// 		/*** "Simple" conflicts and informational messages ***/
-// 		INFO_AUTO_MERGING:       "Auto-merging",
-// 		CONFLICT_CONTENTS:       "CONFLICT (contents)",
-// 		CONFLICT_BINARY:         "CONFLICT (binary)",
-// 		CONFLICT_FILE_DIRECTORY: "CONFLICT (file/directory)",
-// 		CONFLICT_DISTINCT_MODES: "CONFLICT (distinct modes)",
-// 		CONFLICT_MODIFY_DELETE:  "CONFLICT (modify/delete)",
-// 		/*** Regular rename ***/
-// 		CONFLICT_RENAME_RENAME:   "CONFLICT (rename/rename)",
-// 		CONFLICT_RENAME_COLLIDES: "CONFLICT (rename involved in collision)",
-// 		CONFLICT_RENAME_DELETE:   "CONFLICT (rename/delete)",
-
-// 		/*** Basic directory rename ***/
-// 		CONFLICT_DIR_RENAME_SUGGESTED: "CONFLICT (directory rename suggested)",
-// 		INFO_DIR_RENAME_APPLIED:       "Path updated due to directory rename",
-
-// 		/*** Special directory rename cases ***/
-// 		INFO_DIR_RENAME_SKIPPED_DUE_TO_RERENAME: "Directory rename skipped since directory was renamed on both sides",
-// 		CONFLICT_DIR_RENAME_FILE_IN_WAY:         "CONFLICT (file in way of directory rename)",
-// 		CONFLICT_DIR_RENAME_COLLISION:           "CONFLICT(directory rename collision)",
-// 		CONFLICT_DIR_RENAME_SPLIT:               "CONFLICT(directory rename unclear split)",
-// 	}
-// )
+var (
+	conflictTypeNames = map[int]string{
+		CONFLICT_CONTENTS:               "contents",
+		CONFLICT_BINARY:                 "binary",
+		CONFLICT_FILE_DIRECTORY:         "file/directory",
+		CONFLICT_DISTINCT_MODES:         "distinct modes",
+		CONFLICT_MODIFY_DELETE:          "modify/delete",
+		CONFLICT_RENAME_RENAME:          "rename/rename",
+		CONFLICT_RENAME_COLLIDES:        "rename involved in collision",
+		CONFLICT_RENAME_DELETE:          "rename/delete",
+		CONFLICT_DIR_RENAME_SUGGESTED:   "directory rename suggested",
+		CONFLICT_DIR_RENAME_FILE_IN_WAY: "file in way of directory rename",
+		CONFLICT_DIR_RENAME_COLLISION:   "directory rename collision",
+		CONFLICT_DIR_RENAME_SPLIT:       "directory rename unclear split",
+	}
+)
+
+// ConflictTypeName returns the name of conflict type used in 'CONFLICT (<name>)' messages.
+func ConflictTypeName(t int) string {
+	if name, ok := conflictTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
 
 // Conflict represents a merge conflict for a single file.
 type Conflict struct {
@@ -337,6 +336,9 @@ type MergeOptions struct {
 	Textconv      bool
 	MergeDriver   MergeDriver
 	TextResolver  TextResolver
+	// LabelO, LabelA, LabelB: labels of the conflict markers, eg: the merge base, HEAD and the merged branch; empty
+	// labels use the path of the file.
+	LabelO, LabelA, LabelB string
 }
 
 type MergeResult struct {
@@ -349,6 +351,14 @@ func (mr *MergeResult) Error() string {
 	return "conflicts"
 }
 
+// LabelOr returns the conflict marker label, or fallback when it is not set.
+func LabelOr(label, fallback string) string {
+	if len(label) != 0 {
+		return label
+	}
+	return fallback
+}
+
 func (d *ODB) mergeEntry(ctx context.Context, ch *ChangeEntry, opts *MergeOptions, result *MergeResult) (*TreeEntry, error) {
 	// Both sides add
 	if ch.Ancestor == nil {
@@ -369,8 +379,8 @@ func (d *ODB) mergeEntry(ctx context.Context, ch *ChangeEntry, opts *MergeOption
 			A:        ch.Our.Hash,
 			B:        ch.Their.Hash,
 			LabelO:   "",
-			LabelA:   ch.Path,
-			LabelB:   ch.Path,
+			LabelA:   LabelOr(opts.LabelA, ch.Path),
+			LabelB:   LabelOr(opts.LabelB, ch.Path),
 			Textconv: opts.Textconv,
 			M:        opts.MergeDriver,
 			G:        opts.TextResolver,
@@ -416,9 +426,9 @@ func (d *ODB) mergeEntry(ctx context.Context, ch *ChangeEntry, opts *MergeOption
 				O:        ch.Ancestor.Hash,
 				A:        ch.Our.Hash,
 				B:        ch.Their.Hash,
-				LabelO:   ch.Path,
-				LabelA:   ch.Path,
-				LabelB:   ch.Path,
+				LabelO:   LabelOr(opts.LabelO, ch.Path),
+				LabelA:   LabelOr(opts.LabelA, ch.Path),
+				LabelB:   LabelOr(opts.LabelB, ch.Path),
 				Textconv: opts.Textconv,
 				M:        opts.MergeDriver,
 				G:        opts.TextResolver,
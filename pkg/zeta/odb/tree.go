@@ -148,6 +148,66 @@ func (h *treeMaker) makeTrees(entries []*TreeEntry) (plumbing.Hash, error) {
 	return h.copyTreeToStorageRecursive(rootNode, h.trees[rootNode])
 }
 
+// EditTree replaces the entries at the given paths of root, a nil entry removes the path. Only the trees on the way to
+// the edited paths are rewritten, trees left empty are removed.
+func (d *ODB) EditTree(ctx context.Context, root *object.Tree, edits map[string]*object.TreeEntry) (plumbing.Hash, error) {
+	oid, _, err := d.editTree(ctx, root, edits)
+	return oid, err
+}
+
+func (d *ODB) editTree(ctx context.Context, t *object.Tree, edits map[string]*object.TreeEntry) (plumbing.Hash, int, error) {
+	entries := make(map[string]*object.TreeEntry)
+	if t != nil {
+		for _, e := range t.Entries {
+			entries[e.Name] = e
+		}
+	}
+	subEdits := make(map[string]map[string]*object.TreeEntry)
+	for p, e := range edits {
+		name, rest, ok := strings.Cut(p, "/")
+		if ok {
+			if subEdits[name] == nil {
+				subEdits[name] = make(map[string]*object.TreeEntry)
+			}
+			subEdits[name][rest] = e
+			continue
+		}
+		if e == nil {
+			delete(entries, name)
+			continue
+		}
+		entries[name] = &object.TreeEntry{Name: name, Size: e.Size, Mode: e.Mode, Hash: e.Hash}
+	}
+	for name, se := range subEdits {
+		var sub *object.Tree
+		if e, ok := entries[name]; ok && e.Type() == object.TreeObject {
+			var err error
+			if sub, err = d.Tree(ctx, e.Hash); err != nil {
+				return plumbing.ZeroHash, 0, err
+			}
+		}
+		oid, n, err := d.editTree(ctx, sub, se)
+		if err != nil {
+			return plumbing.ZeroHash, 0, err
+		}
+		if n == 0 {
+			delete(entries, name)
+			continue
+		}
+		entries[name] = &object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: oid}
+	}
+	nt := &object.Tree{Entries: make([]*object.TreeEntry, 0, len(entries))}
+	for _, e := range entries {
+		nt.Entries = append(nt.Entries, e)
+	}
+	sort.Sort(object.SubtreeOrder(nt.Entries))
+	if oid := object.Hash(nt); d.Exists(oid, true) {
+		return oid, len(nt.Entries), nil
+	}
+	oid, err := d.WriteEncoded(nt)
+	return oid, len(nt.Entries), err
+}
+
 func (d *ODB) EmptyTree() *object.Tree {
 	return object.NewEmptyTree(d)
 }
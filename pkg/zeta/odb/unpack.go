@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc64"
 	"io"
 	"os"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"github.com/antgroup/hugescm/modules/crc"
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/object"
 	"github.com/antgroup/hugescm/pkg/progress"
 	"github.com/antgroup/hugescm/pkg/tr"
 )
@@ -25,10 +27,17 @@ var (
 )
 
 func (d *ODB) MetadataUnpack(r io.Reader, quiet bool) error {
+	_, err := d.MetadataUnpackCursor(r, quiet)
+	return err
+}
+
+// MetadataUnpackCursor: unpack metadata, return the cursor when the server stops at the limit,
+// the client continues with batch metadata of the cursor.
+func (d *ODB) MetadataUnpackCursor(r io.Reader, quiet bool) ([]plumbing.Hash, error) {
 	start := time.Now()
 	ur, err := d.NewUnpacker(0, true)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer ur.Close() // nolint
 	b := progress.NewUnknownBar(tr.W("Metadata downloading"), quiet)
@@ -37,25 +46,26 @@ func (d *ODB) MetadataUnpack(r io.Reader, quiet bool) error {
 	var reserved [16]byte
 	if _, err := io.ReadFull(cr, magic[:]); err != nil {
 		b.Exit()
-		return err
+		return nil, err
 	}
 	if !bytes.Equal(magic[:], metadataStreamMagic[:]) {
 		b.Exit()
 		err = fmt.Errorf("unexpected metadata '%c' '%c' '%c' '%c'", magic[0], magic[1], magic[2], magic[3])
 		fmt.Fprintln(os.Stderr, err)
-		return err
+		return nil, err
 	}
 	if _, err := io.ReadFull(cr, version[:]); err != nil {
 		b.Exit()
 		fmt.Fprintf(os.Stderr, "unexpected metadata version error: %v\n", err)
-		return err
+		return nil, err
 	}
 	if _, err := io.ReadFull(cr, reserved[:]); err != nil {
 		b.Exit()
 		fmt.Fprintf(os.Stderr, "unexpected reserved, error: %v\n", err)
-		return err
+		return nil, err
 	}
 	var oidBytes [64]byte
+	var cursor []plumbing.Hash
 	var count int
 	var readBytes int64
 	for {
@@ -63,7 +73,7 @@ func (d *ODB) MetadataUnpack(r io.Reader, quiet bool) error {
 		if err := binary.Read(cr, binary.BigEndian, &length); err != nil {
 			b.Exit()
 			fmt.Fprintf(os.Stderr, "unexpected metadata length, error: %v\n", err)
-			return err
+			return nil, err
 		}
 		if length == 0 {
 			break
@@ -73,27 +83,118 @@ func (d *ODB) MetadataUnpack(r io.Reader, quiet bool) error {
 			b.Exit()
 			err := fmt.Errorf("unexpected metadata hash, err: %w", err)
 			fmt.Fprint(os.Stderr, err)
-			return err
+			return nil, err
 		}
 		objectSize := length - plumbing.HASH_HEX_SIZE
 		readBytes += int64(objectSize)
 		if err := ur.Write(plumbing.NewHash(string(oidBytes[:])), objectSize, io.LimitReader(cr, int64(objectSize)), 0); err != nil {
 			b.Exit()
-			return err
+			return nil, err
+		}
+	}
+	if reserved[0]&metadataFlagCursor != 0 {
+		var n uint32
+		if err := binary.Read(cr, binary.BigEndian, &n); err != nil {
+			b.Exit()
+			fmt.Fprintf(os.Stderr, "unexpected metadata cursor length, error: %v\n", err)
+			return nil, err
+		}
+		for range n {
+			if _, err := io.ReadFull(cr, oidBytes[:]); err != nil {
+				b.Exit()
+				fmt.Fprintf(os.Stderr, "unexpected metadata cursor, error: %v\n", err)
+				return nil, err
+			}
+			cursor = append(cursor, plumbing.NewHash(string(oidBytes[:])))
 		}
 	}
 	b.Finish()
 	if err := cr.Verify(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		return err
+		return nil, err
 	}
 	if err := ur.Preserve(); err != nil {
-		return err
+		return nil, err
 	}
 	fmt.Fprintf(os.Stderr, "%s: %d <%s>, %s: %v\n", tr.W("Metadata download completed, total"), count, strengthen.FormatSize(readBytes), tr.W("time spent"), time.Since(start).Truncate(time.Millisecond))
-	return nil
+	return cursor, nil
+}
+
+// MetadataDecode: decode the metadata stream in memory without writing the objects to the database, objects are
+// decoded with b so their subtrees are resolved through it.
+func MetadataDecode(r io.Reader, b object.Backend) (map[plumbing.Hash]any, error) {
+	cr := crc.NewCrc64Reader(r)
+	var magic, version [4]byte
+	var reserved [16]byte
+	if _, err := io.ReadFull(cr, magic[:]); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic[:], metadataStreamMagic[:]) {
+		return nil, fmt.Errorf("unexpected metadata '%c' '%c' '%c' '%c'", magic[0], magic[1], magic[2], magic[3])
+	}
+	if _, err := io.ReadFull(cr, version[:]); err != nil {
+		return nil, fmt.Errorf("unexpected metadata version error: %w", err)
+	}
+	if _, err := io.ReadFull(cr, reserved[:]); err != nil {
+		return nil, fmt.Errorf("unexpected reserved, error: %w", err)
+	}
+	if reserved[0]&metadataFlagCursor != 0 {
+		return nil, errors.New("unexpected metadata cursor")
+	}
+	var oidBytes [64]byte
+	objects := make(map[plumbing.Hash]any)
+	for {
+		var length uint32
+		if err := binary.Read(cr, binary.BigEndian, &length); err != nil {
+			return nil, fmt.Errorf("unexpected metadata length, error: %w", err)
+		}
+		if length == 0 {
+			break
+		}
+		if _, err := io.ReadFull(cr, oidBytes[:]); err != nil {
+			return nil, fmt.Errorf("unexpected metadata hash, err: %w", err)
+		}
+		oid := plumbing.NewHash(string(oidBytes[:]))
+		data := make([]byte, length-plumbing.HASH_HEX_SIZE)
+		if _, err := io.ReadFull(cr, data); err != nil {
+			return nil, fmt.Errorf("unexpected metadata %s, err: %w", oid, err)
+		}
+		a, err := object.Decode(bytes.NewReader(data), oid, b)
+		if err != nil {
+			return nil, fmt.Errorf("decode metadata %s error: %w", oid, err)
+		}
+		if e, ok := a.(object.Encoder); ok && object.Hash(e) != oid {
+			return nil, fmt.Errorf("metadata %s hash mismatch", oid)
+		}
+		objects[oid] = a
+	}
+	if err := cr.Verify(); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// ErrCorruptedObjects: objects that failed the per-item checksum of the batch stream, the remaining objects are preserved.
+type ErrCorruptedObjects struct {
+	Objects []plumbing.Hash
+}
+
+func (e *ErrCorruptedObjects) Error() string {
+	return fmt.Sprintf("%d objects corrupted in transit", len(e.Objects))
+}
+
+func IsErrCorruptedObjects(err error) bool {
+	var e *ErrCorruptedObjects
+	return errors.As(err, &e)
 }
 
+const (
+	// metadataFlagCursor: the metadata header flag, the stream ends with a cursor to continue
+	metadataFlagCursor byte = 0x01
+	// batchFlagItemCRC64: the batch objects header flag, every item is followed by a CRC64 (ISO) of oid and data
+	batchFlagItemCRC64 byte = 0x01
+)
+
 func (d *ODB) Unpack(r io.Reader, expected int, quiet bool) error {
 	start := time.Now()
 	ur, err := d.NewUnpacker(0, false)
@@ -121,7 +222,9 @@ func (d *ODB) Unpack(r io.Reader, expected int, quiet bool) error {
 		fmt.Fprintf(os.Stderr, "unexpected reserved, error: %v\n", err)
 		return err
 	}
-
+	itemCRC := reserved[0]&batchFlagItemCRC64 != 0
+	table := crc64.MakeTable(crc64.ISO)
+	var corrupted []plumbing.Hash
 	var oidBytes [64]byte
 	var count int
 	var readBytes int64
@@ -142,15 +245,41 @@ func (d *ODB) Unpack(r io.Reader, expected int, quiet bool) error {
 			fmt.Fprintf(os.Stderr, "unexpected object hash, error: %v\n", err)
 			return err
 		}
+		oid := plumbing.NewHash(string(oidBytes[:]))
 		objectSize := length - plumbing.HASH_HEX_SIZE
 		readBytes += int64(objectSize)
-		if err := ur.Write(plumbing.NewHash(string(oidBytes[:])), objectSize, io.LimitReader(cr, int64(objectSize)), 0); err != nil {
+		if !itemCRC {
+			if err := ur.Write(oid, objectSize, io.LimitReader(cr, int64(objectSize)), 0); err != nil {
+				b.Exit()
+				return err
+			}
+			b.Add(1)
+			continue
+		}
+		h := crc64.New(table)
+		_, _ = h.Write(oidBytes[:])
+		if err := ur.Write(oid, objectSize, io.TeeReader(io.LimitReader(cr, int64(objectSize)), h), 0); err != nil {
+			b.Exit()
+			return err
+		}
+		var sum uint64
+		if err := binary.Read(cr, binary.BigEndian, &sum); err != nil {
 			b.Exit()
+			fmt.Fprintf(os.Stderr, "unexpected object checksum, error: %v\n", err)
 			return err
 		}
+		if sum != h.Sum64() {
+			// drop the corrupted object, it will be requested again
+			if err := ur.DiscardLast(); err != nil {
+				b.Exit()
+				return err
+			}
+			corrupted = append(corrupted, oid)
+		}
 		b.Add(1)
 	}
-	if err := cr.Verify(); err != nil {
+	// when some items are corrupted, the stream checksum cannot match, the remaining items have been verified one by one.
+	if err := cr.Verify(); err != nil && len(corrupted) == 0 {
 		b.Exit()
 		fmt.Fprintln(os.Stderr, err)
 		return err
@@ -161,5 +290,8 @@ func (d *ODB) Unpack(r io.Reader, expected int, quiet bool) error {
 	}
 	b.Finish()
 	fmt.Fprintf(os.Stderr, "%s: %d <%s>, %s: %v\n", tr.W("Files download completed, total"), count, strengthen.FormatSize(readBytes), tr.W("time spent"), time.Since(start).Truncate(time.Millisecond))
+	if len(corrupted) != 0 {
+		return &ErrCorruptedObjects{Objects: corrupted}
+	}
 	return nil
 }
@@ -1,10 +1,20 @@
 package odb
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/antgroup/hugescm/modules/binary"
+	"github.com/antgroup/hugescm/modules/crc"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
 )
 
 func TestMetadataUnpack(t *testing.T) {
@@ -27,3 +37,116 @@ func TestMetadataUnpack(t *testing.T) {
 		return
 	}
 }
+
+func TestUnpackCorruptedItems(t *testing.T) {
+	odb, err := NewODB(t.TempDir())
+	if err != nil {
+		t.Fatalf("create odb error: %v", err)
+	}
+	defer odb.Close() // nolint
+	oids := []plumbing.Hash{
+		plumbing.NewHash("ccc1bf6d4a1a7c1b0e3fd8e0f1d8c6f5f3bb1b3a58d3a4e6f6f9f0a1b2c3d4e5"),
+		plumbing.NewHash("ddd1bf6d4a1a7c1b0e3fd8e0f1d8c6f5f3bb1b3a58d3a4e6f6f9f0a1b2c3d4e5"),
+	}
+	items := map[plumbing.Hash][]byte{
+		oids[0]: []byte("first object"),
+		oids[1]: []byte("second object"),
+	}
+	var b bytes.Buffer
+	cw := crc.NewCrc64Writer(&b)
+	if err := protocol.WriteBatchObjectsHeader(cw, protocol.BATCH_FLAG_ITEM_CRC64); err != nil {
+		t.Fatal(err)
+	}
+	for _, oid := range oids {
+		if err := protocol.WriteObjectsItemWithChecksum(cw, bytes.NewReader(items[oid]), oid.String(), int64(len(items[oid]))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = protocol.WriteObjectsItemWithChecksum(cw, nil, "", 0)
+	if _, err := cw.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	stream := b.Bytes()
+	// flip a byte of the second object
+	pos := bytes.Index(stream, []byte("second object"))
+	stream[pos] ^= 0xff
+	err = odb.Unpack(bytes.NewReader(stream), len(oids), true)
+	var e *ErrCorruptedObjects
+	if !errors.As(err, &e) {
+		t.Fatalf("expected corrupted objects error, got: %v", err)
+	}
+	if len(e.Objects) != 1 || e.Objects[0] != oids[1] {
+		t.Fatalf("unexpected corrupted objects: %v", e.Objects)
+	}
+	if err := odb.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !odb.Exists(oids[0], false) {
+		t.Errorf("object %s should be preserved", oids[0])
+	}
+	if odb.Exists(oids[1], false) {
+		t.Errorf("corrupted object %s should be discarded", oids[1])
+	}
+}
+
+func TestMetadataUnpackCursor(t *testing.T) {
+	odb, err := NewODB(t.TempDir())
+	if err != nil {
+		t.Fatalf("create odb error: %v", err)
+	}
+	defer odb.Close() // nolint
+	cursor := []plumbing.Hash{
+		plumbing.NewHash("ccc1bf6d4a1a7c1b0e3fd8e0f1d8c6f5f3bb1b3a58d3a4e6f6f9f0a1b2c3d4e5"),
+		plumbing.NewHash("ddd1bf6d4a1a7c1b0e3fd8e0f1d8c6f5f3bb1b3a58d3a4e6f6f9f0a1b2c3d4e5"),
+	}
+	var b bytes.Buffer
+	cw := crc.NewCrc64Writer(&b)
+	var reserved [16]byte
+	reserved[0] = metadataFlagCursor
+	_ = binary.Write(cw, metadataStreamMagic[:], uint32(1), reserved[:], uint32(0), uint32(len(cursor)))
+	for _, oid := range cursor {
+		_ = binary.Write(cw, []byte(oid.String()))
+	}
+	if _, err := cw.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := odb.MetadataUnpackCursor(&b, true)
+	if err != nil {
+		t.Fatalf("unpack metadata error: %v", err)
+	}
+	if !slices.Equal(got, cursor) {
+		t.Fatalf("unexpected cursor: %v", got)
+	}
+}
+
+func TestMetadataDecode(t *testing.T) {
+	tree := &object.Tree{Entries: []*object.TreeEntry{
+		{Name: "spec.md", Mode: filemode.Regular, Hash: plumbing.NewHash("ccc1bf6d4a1a7c1b0e3fd8e0f1d8c6f5f3bb1b3a58d3a4e6f6f9f0a1b2c3d4e5"), Size: 12},
+	}}
+	var data bytes.Buffer
+	if err := tree.Encode(&data); err != nil {
+		t.Fatal(err)
+	}
+	stream := func(oid plumbing.Hash) []byte {
+		var b bytes.Buffer
+		cw := crc.NewCrc64Writer(&b)
+		var reserved [16]byte
+		_ = binary.Write(cw, metadataStreamMagic[:], uint32(1), reserved[:], uint32(data.Len()+plumbing.HASH_HEX_SIZE), []byte(oid.String()), data.Bytes(), uint32(0))
+		if _, err := cw.Finish(); err != nil {
+			t.Fatal(err)
+		}
+		return b.Bytes()
+	}
+	oid := object.Hash(tree)
+	objects, err := MetadataDecode(bytes.NewReader(stream(oid)), nil)
+	if err != nil {
+		t.Fatalf("decode metadata error: %v", err)
+	}
+	got, ok := objects[oid].(*object.Tree)
+	if !ok || len(got.Entries) != 1 || got.Entries[0].Name != "spec.md" {
+		t.Fatalf("unexpected objects: %v", objects)
+	}
+	if _, err := MetadataDecode(bytes.NewReader(stream(plumbing.NewHash("ddd1bf6d4a1a7c1b0e3fd8e0f1d8c6f5f3bb1b3a58d3a4e6f6f9f0a1b2c3d4e5"))), nil); err == nil {
+		t.Fatalf("decode metadata with mismatched hash should fail")
+	}
+}
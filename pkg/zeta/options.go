@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/antgroup/hugescm/modules/mailmap"
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/modules/zeta/object"
@@ -34,10 +35,13 @@ type CheckoutOptions struct {
 	// Create a new branch named Branch and start it at Hash.
 	Create bool
 	Force  bool
-	Merge  bool
-	First  bool
-	One    bool
-	Quiet  bool
+	// Merge local changes with the target commit instead of aborting, base is the current commit.
+	Merge bool
+	// ConflictStyle: merge, diff3 or zdiff3, defaults to merge.conflictStyle.
+	ConflictStyle string
+	First         bool
+	One           bool
+	Quiet         bool
 }
 
 // Validate validates the fields and sets the default values.
@@ -214,6 +218,8 @@ type CommitOptions struct {
 	AllowEmptyMessage bool
 	Message           []string
 	File              string
+	// NoVerify bypasses the commit message policies of commit.policies.
+	NoVerify bool
 }
 
 func genMessage(messages []string) string {
@@ -329,8 +335,8 @@ func (o *CommitOptions) loadConfigAuthorAndCommitter(r *Repository) error {
 	if date, ok := os.LookupEnv(ENV_ZETA_AUTHOR_DATE); ok {
 		o.Author.When = fallbackParseTime(date)
 	}
-	if date, ok := os.LookupEnv(ENV_ZETA_AUTHOR_DATE); ok {
-		o.Author.When = fallbackParseTime(date)
+	if date, ok := os.LookupEnv(ENV_ZETA_COMMITTER_DATE); ok {
+		o.Committer.When = fallbackParseTime(date)
 	}
 	if o.Author.When.IsZero() {
 		o.Author.When = time.Now()
@@ -395,6 +401,9 @@ type LogOptions struct {
 
 	//
 	Reverse bool
+
+	// Mailmap rewrites author and committer identities of the returned commits.
+	Mailmap *mailmap.Mailmap
 }
 
 func newLogPathFilter(paths []string) func(string) bool {
@@ -414,6 +423,10 @@ type LogCommandOptions struct {
 	FormatJSON           bool
 	JSONLimit            int
 	Paths                []string
+	NoMailmap            bool
+	Since                *time.Time // --since/--after: committer date
+	Until                *time.Time // --until/--before: committer date
+	mailmap              *mailmap.Mailmap
 }
 type commitsSortFunc func([]*object.Commit)
 
@@ -424,6 +437,16 @@ func (o *LogCommandOptions) SortFunc() commitsSortFunc {
 	return nil
 }
 
+// limitCommits keeps the commits within --since and --until, like object.NewCommitLimitIterFromIter.
+func (o *LogCommandOptions) limitCommits(commits []*object.Commit) []*object.Commit {
+	if o.Since == nil && o.Until == nil {
+		return commits
+	}
+	return slices.DeleteFunc(commits, func(c *object.Commit) bool {
+		return (o.Since != nil && c.Committer.When.Before(*o.Since)) || (o.Until != nil && c.Committer.When.After(*o.Until))
+	})
+}
+
 func (o *LogCommandOptions) sort(commits []*object.Commit) {
 	if o.OrderByCommitterDate {
 		if o.Reverse {
@@ -477,12 +500,23 @@ type GrepOptions struct {
 	ReferenceName plumbing.ReferenceName
 	// PathSpecs are compiled Regexp objects of pathspec to use in the matching.
 	PathSpecs []*regexp.Regexp
+	// Paths are prefixes or wildcard patterns of the files to search, see NewMatcher.
+	Paths []string
 	// Size Limit
 	Limit int64
+	// Expr combines patterns with AND, OR and NOT, it replaces Patterns when set, see CompileGrepExpr.
+	Expr GrepExpr
+	// Cached searches the files registered in the index instead of a commit.
+	Cached bool
+	// Worktree searches the tracked files of the worktree instead of a commit.
+	Worktree bool
+	// Text searches binary files as text, binary files are skipped by default.
+	Text bool
 }
 
 var (
 	ErrHashOrReference = errors.New("ambiguous options, only one of CommitHash or ReferenceName can be passed")
+	ErrGrepSource      = errors.New("ambiguous options, only one of Cached, Worktree, CommitHash or ReferenceName can be passed")
 )
 
 // Validate validates the fields and sets the default values.
@@ -496,6 +530,15 @@ func (o *GrepOptions) validate(r *Repository) error {
 	if !o.CommitHash.IsZero() && o.ReferenceName != "" {
 		return ErrHashOrReference
 	}
+	if o.Cached || o.Worktree {
+		if o.Cached && o.Worktree || !o.CommitHash.IsZero() || o.ReferenceName != "" {
+			return ErrGrepSource
+		}
+		if o.Limit == 0 {
+			o.Limit = 128 * strengthen.MiByte // limit 128M
+		}
+		return nil
+	}
 
 	// If none of CommitHash and ReferenceName are provided, set commit hash of
 	// the repository's head.
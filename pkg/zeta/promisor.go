@@ -7,6 +7,7 @@ import (
 	"context"
 	"math"
 	"os"
+	"slices"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/zeta/backend"
@@ -20,6 +21,11 @@ func (r *Repository) fetchObjects(ctx context.Context, t transport.Transport, ta
 		sizeLimit = math.MaxInt64
 	}
 	largeSize := r.largeSize()
+	// objects in the keep list are always fetched
+	keep, err := r.keepObjects(ctx, r.keepList(), target)
+	if err != nil {
+		return err
+	}
 	larges := make([]*odb.Entry, 0, 100)
 	seen := make(map[plumbing.Hash]bool)
 	if err := r.odb.CountingSliceObjects(ctx, target, r.Core.SparseDirs, r.maxEntries(), func(ctx context.Context, entries odb.Entries) error {
@@ -28,7 +34,7 @@ func (r *Repository) fetchObjects(ctx context.Context, t transport.Transport, ta
 			if e.Hash == backend.BLANK_BLOB_HASH {
 				continue
 			}
-			if e.Size > sizeLimit {
+			if e.Size > sizeLimit && keep[e.Hash] == nil {
 				continue
 			}
 			if e.Size > largeSize {
@@ -51,13 +57,39 @@ func (r *Repository) fetchObjects(ctx context.Context, t transport.Transport, ta
 	}); err != nil {
 		return err
 	}
+	// kept objects outside of sparse dirs are not counted
+	smalls := make([]plumbing.Hash, 0, len(keep))
+	for oid, e := range keep {
+		if oid == backend.BLANK_BLOB_HASH || seen[oid] || r.odb.Exists(oid, false) {
+			continue
+		}
+		if e.Size > largeSize {
+			larges = append(larges, e)
+			continue
+		}
+		smalls = append(smalls, oid)
+	}
+	if len(smalls) != 0 {
+		if err := r.batch(ctx, t, smalls); err != nil {
+			return err
+		}
+		if err := r.odb.Reload(); err != nil {
+			return err
+		}
+	}
 	if ignoreLarges {
-		return nil
+		larges = slices.DeleteFunc(larges, func(e *odb.Entry) bool {
+			return keep[e.Hash] == nil
+		})
 	}
 	return r.transfer(ctx, t, larges)
 }
 
 func (r *Repository) FetchObjects(ctx context.Context, commit plumbing.Hash, skipLarges bool) error {
+	if IsTranslatedRemote(r.Core.Remote) {
+		// translated remotes write every object on fetch
+		return nil
+	}
 	t, err := r.newTransport(ctx, transport.DOWNLOAD)
 	if err != nil {
 		return err
@@ -158,6 +190,8 @@ func (r *Repository) promiseFetch(ctx context.Context, rev string, fetchMissing
 type promiseObject struct {
 	oid  plumbing.Hash
 	size int64
+	// remote: resolved from the remote, see remoteObjects
+	remote *remoteObjects
 }
 
 func (o *promiseObject) entry() *odb.Entry {
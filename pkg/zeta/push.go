@@ -11,12 +11,15 @@ import (
 	"os"
 	"strings"
 
+	"github.com/antgroup/hugescm/modules/commitmsg"
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/progressbar"
 	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/modules/term"
 	"github.com/antgroup/hugescm/modules/zeta"
+	"github.com/antgroup/hugescm/modules/zeta/object"
 	"github.com/antgroup/hugescm/pkg/progress"
+	"github.com/antgroup/hugescm/pkg/tr"
 	"github.com/antgroup/hugescm/pkg/transport"
 	"github.com/antgroup/hugescm/pkg/zeta/odb"
 )
@@ -32,6 +35,10 @@ type PushOptions struct {
 	PushOptions []string
 	Tag         bool
 	Force       bool
+	// NoVerify bypasses the commit message policies of commit.policies.
+	NoVerify bool
+	// DryRun: the server validates the push without updating the reference, only the metadata is sent.
+	DryRun bool
 }
 
 func (o *PushOptions) Target(name string) plumbing.ReferenceName {
@@ -58,33 +65,39 @@ const (
 		"hint: See the 'Note about fast-forwards' in 'zeta push --help' for details.\x1b[0m\n"
 )
 
+// uploadProgress shows the progress of reading reader unless quiet, done closes the progress bar.
+func (r *Repository) uploadProgress(reader io.Reader, size int64, title string) (io.Reader, func()) {
+	if r.quiet {
+		return reader, func() {}
+	}
+	b := progressbar.NewOptions64(
+		size,
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionUseANSICodes(true),
+		progressbar.OptionSetDescription(title),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetTheme(progress.MakeTheme()))
+	return io.TeeReader(reader, b), func() { _ = b.Close() }
+}
+
 func (r *Repository) putObject(ctx context.Context, t transport.Transport, refname plumbing.ReferenceName, oid plumbing.Hash, title string) error {
 	sr, err := r.odb.SizeReader(oid, false)
 	if err != nil {
 		return err
 	}
 	defer sr.Close() // nolint
-	var reader io.Reader = sr
-	var b *progressbar.ProgressBar
-	if !r.quiet {
-		b = progressbar.NewOptions64(
-			sr.Size(),
-			progressbar.OptionShowBytes(true),
-			progressbar.OptionEnableColorCodes(true),
-			progressbar.OptionUseANSICodes(true),
-			progressbar.OptionSetDescription(title),
-			progressbar.OptionFullWidth(),
-			progressbar.OptionSetTheme(progress.MakeTheme()))
-		reader = io.TeeReader(sr, b)
-		defer b.Close() // nolint
-	}
+	reader, done := r.uploadProgress(sr, sr.Size(), title)
+	defer done()
 	if err = t.PutObject(ctx, refname, oid, reader, sr.Size()); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *Repository) putObjects(ctx context.Context, t transport.Transport, refname plumbing.ReferenceName, haveObjects []*transport.HaveObject) error {
+// putObjects uploads the large objects the remote does not have, objects with a base in bases are uploaded as a
+// delta when it is much smaller.
+func (r *Repository) putObjects(ctx context.Context, t transport.Transport, refname plumbing.ReferenceName, haveObjects []*transport.HaveObject, bases map[plumbing.Hash]plumbing.Hash) error {
 	objects, err := t.BatchCheck(ctx, refname, haveObjects)
 	if err != nil {
 		return err
@@ -98,6 +111,9 @@ func (r *Repository) putObjects(ctx context.Context, t transport.Transport, refn
 	for i, o := range sendObjects {
 		oid := plumbing.NewHash(o.OID)
 		desc := fmt.Sprintf("%s \x1b[38;2;72;198;239m[%d/%d: %s]\x1b[0m", W("Upload Large files"), i+1, len(sendObjects), shortHash(oid))
+		if base, ok := bases[oid]; ok && r.putDelta(ctx, t, refname, oid, base, o.CompressedSize, desc) {
+			continue
+		}
 		if err := r.putObject(ctx, t, refname, oid, desc); err != nil {
 			return err
 		}
@@ -131,6 +147,10 @@ func (r *Repository) doPushRemove(ctx context.Context, target plumbing.Reference
 		error_red("failed to push some refs to '%s'", cleanedRemote)
 		return err
 	}
+	if o.DryRun && !ref.HasCapability(transport.CAPABILITY_PUSH_DRY_RUN) {
+		die_error("remote does not support push --dry-run")
+		return ErrPushDryRunUnsupported
+	}
 	pipeReader, pipeWriter := io.Pipe()
 	go func() {
 		if err := r.odb.PushTo(ctx, pipeWriter, &odb.PushObjects{
@@ -150,6 +170,7 @@ func (r *Repository) doPushRemove(ctx context.Context, target plumbing.Reference
 		Metadata:    0,
 		Objects:     0,
 		PushOptions: o.PushOptions,
+		DryRun:      o.DryRun,
 	}
 	rc, err := t.Push(ctx, pipeReader, cmd)
 	if err != nil {
@@ -177,6 +198,10 @@ func (r *Repository) doPushRemove(ctx context.Context, target plumbing.Reference
 		error_red("failed to push some refs to '%s'", cleanedRemote)
 		return errors.New(result.Reason)
 	}
+	if o.DryRun {
+		_, _ = fmt.Fprintf(os.Stderr, "To: %s\n - [deleted] '%s' (dry run)\n", cleanedRemote, target.Short())
+		return nil
+	}
 	_, _ = fmt.Fprintf(os.Stderr, "To: %s\n - [deleted] '%s'\n", cleanedRemote, target.Short())
 	return nil
 }
@@ -202,11 +227,14 @@ func (r *Repository) doPush(ctx context.Context, ourName plumbing.ReferenceName,
 	}
 	var fastForward, isNewPush bool
 	var theirs, oldRev plumbing.Hash
+	// remoteRef: the capabilities of the remote
+	var remoteRef *transport.Reference
 	ref, err := t.FetchReference(ctx, target)
 	if errors.Is(err, transport.ErrReferenceNotExist) {
 		isNewPush = true
 		if current, err := t.FetchReference(ctx, plumbing.HEAD); err == nil {
 			theirs = plumbing.NewHash(current.Hash)
+			remoteRef = current
 		}
 	} else if err != nil {
 		if !zeta.IsErrExitCode(err) {
@@ -230,19 +258,59 @@ func (r *Repository) doPush(ctx context.Context, ourName plumbing.ReferenceName,
 			return ErrPushRejected
 		}
 		theirs = ref.Target()
+		remoteRef = ref
+	}
+	// servers without dry-run support would update the reference
+	if o.DryRun && (remoteRef == nil || !remoteRef.HasCapability(transport.CAPABILITY_PUSH_DRY_RUN)) {
+		die_error("remote does not support push --dry-run")
+		return ErrPushDryRunUnsupported
 	}
 
+	if err := r.checkNarrowPush(ctx, newRev, theirs, ignoreParents); err != nil {
+		die_error("%v", err)
+		error_red("failed to push some refs to '%s'", r.cleanedRemote())
+		return err
+	}
+	if !o.NoVerify && target.IsBranch() {
+		if err := r.checkPushMessages(ctx, newRev, theirs, ignoreParents); err != nil {
+			error_red("failed to push some refs to '%s'", r.cleanedRemote())
+			return err
+		}
+	}
 	po, err := r.odb.Delta(ctx, newRev, shallow, theirs)
 	if err != nil {
 		die("get objects error: %v", err)
 		return err
 	}
+	if remoteRef != nil && remoteRef.HasCapability(transport.CAPABILITY_OBJECTS_EXISTS) {
+		if err := r.skipRemoteObjects(ctx, t, po); err != nil {
+			die_error("check remote objects error: %v", err)
+			return err
+		}
+	}
+	var plan *pushPlan
+	if o.DryRun {
+		if plan, err = r.newPushPlan(ctx, po); err != nil {
+			die_error("plan push error: %v", err)
+			return err
+		}
+		po = &odb.PushObjects{Metadata: po.Metadata, Objects: plan.symlinks}
+	}
 	if len(po.LargeObjects) != 0 {
 		haveObjects := make([]*transport.HaveObject, 0, len(po.LargeObjects))
+		wanted := make(map[plumbing.Hash]bool, len(po.LargeObjects))
 		for _, o := range po.LargeObjects {
 			haveObjects = append(haveObjects, &transport.HaveObject{OID: o.Hash.String(), CompressedSize: o.Size})
+			wanted[o.Hash] = true
 		}
-		if err := r.putObjects(ctx, t, target, haveObjects); err != nil {
+		var bases map[plumbing.Hash]plumbing.Hash
+		if remoteRef != nil && remoteRef.HasCapability(transport.CAPABILITY_DELTA_OBJECTS) {
+			if bases, err = r.deltaBases(ctx, theirs, newRev, wanted); err != nil {
+				die_error("find delta bases error: %v", err)
+				return err
+			}
+		}
+		if err := r.putObjects(ctx, t, target, haveObjects, bases); err != nil {
 			die_error("upload large objects error: %v", err)
 			return err
 		}
@@ -262,6 +330,7 @@ func (r *Repository) doPush(ctx context.Context, ourName plumbing.ReferenceName,
 		Metadata:    len(po.Metadata),
 		Objects:     len(po.Objects),
 		PushOptions: o.PushOptions,
+		DryRun:      o.DryRun,
 	}
 	if ref != nil {
 		cmd.OldRev = ref.Hash
@@ -284,6 +353,18 @@ func (r *Repository) doPush(ctx context.Context, ourName plumbing.ReferenceName,
 	}
 	_ = rc.Close()
 	cleanedRemote := r.cleanedRemote()
+	var dryRun string
+	if plan != nil {
+		// large objects are checked after the server accepted the push, the check of a rejected push is useless
+		if !result.Rejected {
+			if err := r.checkLargeObjects(ctx, t, target, plan); err != nil {
+				die_error("check large objects error: %v", err)
+				return err
+			}
+		}
+		plan.show(r.verbose)
+		dryRun = " (dry run)"
+	}
 	if result.Rejected {
 		sv := strengthen.StrSplitSkipEmpty(result.Reason, 2, '\n')
 		for _, s := range sv {
@@ -296,21 +377,68 @@ func (r *Repository) doPush(ctx context.Context, ourName plumbing.ReferenceName,
 	fmt.Fprintf(os.Stderr, "To: %s\n", cleanedRemote)
 	if isNewPush {
 		if target.IsBranch() {
-			fmt.Fprintf(os.Stderr, " * [new branch] %s -> %s\n", ourName.Short(), target.BranchName())
+			fmt.Fprintf(os.Stderr, " * [new branch] %s -> %s%s\n", ourName.Short(), target.BranchName(), dryRun)
 			return nil
 		}
 		if target.IsTag() {
-			fmt.Fprintf(os.Stderr, " * [new tag] %s -> %s\n", ourName.Short(), target.TagName())
+			fmt.Fprintf(os.Stderr, " * [new tag] %s -> %s%s\n", ourName.Short(), target.TagName(), dryRun)
 			return nil
 		}
 		// not branch or tag skip
 		return nil
 	}
 	if !fastForward {
-		fmt.Fprintf(os.Stderr, " + %s...%s %s -> %s (forced update)\n", shortHash(oldRev), shortHash(newRev), ourName.Short(), shortReferenceName(target))
+		fmt.Fprintf(os.Stderr, " + %s...%s %s -> %s (forced update)%s\n", shortHash(oldRev), shortHash(newRev), ourName.Short(), shortReferenceName(target), dryRun)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, " + %s...%s %s -> %s%s\n", shortHash(oldRev), shortHash(newRev), ourName.Short(), shortReferenceName(target), dryRun)
+	return nil
+}
+
+// checkPushMessages checks the messages of the commits not on the remote against commit.policies, the same
+// policies are checked by the server on protected branches.
+func (r *Repository) checkPushMessages(ctx context.Context, newRev, theirs plumbing.Hash, ignore []plumbing.Hash) error {
+	rules, err := r.commitMessageRules()
+	if err != nil || rules == nil {
+		return err
+	}
+	c, err := r.odb.ParseRevExhaustive(ctx, newRev)
+	if err != nil {
+		return err
+	}
+	seen := make(map[plumbing.Hash]bool)
+	if !theirs.IsZero() {
+		seen[theirs] = true
+	}
+	if rdb, err := r.References(); err == nil {
+		for _, ref := range rdb.References() {
+			if ref.Type() == plumbing.HashReference && ref.Name().IsRemote() {
+				seen[ref.Hash()] = true
+			}
+		}
+	}
+	var rejected bool
+	iter := object.NewCommitIterBFS(c, seen, ignore)
+	if err := iter.ForEach(ctx, func(cc *object.Commit) error {
+		violations := rules.Check(cc.Message)
+		if len(violations) == 0 {
+			return nil
+		}
+		if !rejected {
+			fmt.Fprintf(os.Stderr, "%s\n", W("The commit message does not satisfy commit.policies:"))
+			rejected = true
+		}
+		for _, v := range violations {
+			fmt.Fprintf(os.Stderr, "  - %s %s: %s\n", shortHash(cc.Hash), commitmsg.Subject(cc.Message), tr.Sprintf(v.Format, v.Args...))
+		}
 		return nil
+	}); err != nil && !plumbing.IsNoSuchObject(err) {
+		return err
+	}
+	if rejected {
+		fmt.Fprintln(os.Stderr, W("Aborting push, use --no-verify to bypass the commit message policies."))
+		return ErrCommitMessagePolicy
 	}
-	fmt.Fprintf(os.Stderr, " + %s...%s %s -> %s\n", shortHash(oldRev), shortHash(newRev), ourName.Short(), shortReferenceName(target))
 	return nil
 }
 
@@ -0,0 +1,133 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+var (
+	ErrPushDryRunUnsupported = errors.New("remote does not support push --dry-run")
+)
+
+// pushPlan: what a push would upload, see zeta push --dry-run. A dry-run push only sends the metadata and the symlink
+// targets, the server validates the push without updating the reference.
+type pushPlan struct {
+	metadata     int
+	metadataSize int64
+	objects      int
+	objectsSize  int64
+	large        []*odb.HaveObject // large objects to upload
+	largeExists  int               // large objects the remote already has
+	checked      bool              // large objects were checked against the remote
+	symlinks     []plumbing.Hash   // symlink targets, checked by the path policy of the server
+}
+
+func (r *Repository) newPushPlan(ctx context.Context, po *odb.PushObjects) (*pushPlan, error) {
+	p := &pushPlan{metadata: len(po.Metadata), large: po.LargeObjects}
+	wanted := make(map[plumbing.Hash]bool, len(po.Objects))
+	for _, oid := range po.Objects {
+		if oid == backend.BLANK_BLOB_HASH {
+			continue
+		}
+		sr, err := r.odb.SizeReader(oid, false)
+		if plumbing.IsNoSuchObject(err) {
+			// same as push: objects not checked out are skipped
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.objects++
+		p.objectsSize += sr.Size()
+		_ = sr.Close()
+		wanted[oid] = true
+	}
+	for _, oid := range po.Metadata {
+		sr, err := r.odb.SizeReader(oid, true)
+		if err != nil {
+			return nil, err
+		}
+		p.metadataSize += sr.Size()
+		_ = sr.Close()
+		a, err := r.odb.Object(ctx, oid)
+		if err != nil {
+			return nil, err
+		}
+		tree, ok := a.(*object.Tree)
+		if !ok {
+			continue
+		}
+		for _, e := range tree.Entries {
+			if e.Mode == filemode.Symlink && wanted[e.Hash] {
+				p.symlinks = append(p.symlinks, e.Hash)
+				delete(wanted, e.Hash)
+			}
+		}
+	}
+	return p, nil
+}
+
+// checkLargeObjects asks the remote which large objects it already has.
+func (r *Repository) checkLargeObjects(ctx context.Context, t transport.Transport, refname plumbing.ReferenceName, p *pushPlan) error {
+	if len(p.large) == 0 {
+		return nil
+	}
+	haveObjects := make([]*transport.HaveObject, 0, len(p.large))
+	sizes := make(map[plumbing.Hash]int64, len(p.large))
+	for _, o := range p.large {
+		haveObjects = append(haveObjects, &transport.HaveObject{OID: o.Hash.String(), CompressedSize: o.Size})
+		sizes[o.Hash] = o.Size
+	}
+	objects, err := t.BatchCheck(ctx, refname, haveObjects)
+	if err != nil {
+		return err
+	}
+	large := make([]*odb.HaveObject, 0, len(objects))
+	for _, o := range objects {
+		if o != nil && o.Action == transport.UPLOAD {
+			oid := plumbing.NewHash(o.OID)
+			large = append(large, &odb.HaveObject{Hash: oid, Size: sizes[oid]})
+		}
+	}
+	p.largeExists = len(p.large) - len(large)
+	p.large = large
+	p.checked = true
+	return nil
+}
+
+func (p *pushPlan) show(verbose bool) {
+	var largeSize int64
+	for _, o := range p.large {
+		largeSize += o.Size
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", W("Dry run, nothing is uploaded and the remote reference is not updated."))
+	fmt.Fprintf(os.Stderr, "  %s %d (%s)\n", W("metadata:"), p.metadata, strengthen.FormatSize(p.metadataSize))
+	fmt.Fprintf(os.Stderr, "  %s %d (%s)\n", W("objects:"), p.objects, strengthen.FormatSize(p.objectsSize))
+	fmt.Fprintf(os.Stderr, "  %s %d (%s)", W("large objects:"), len(p.large), strengthen.FormatSize(largeSize))
+	switch {
+	case !p.checked && len(p.large) != 0:
+		fmt.Fprintf(os.Stderr, ", %s", W("not checked against the remote"))
+	case p.largeExists != 0:
+		fmt.Fprintf(os.Stderr, W(", %d already on the remote"), p.largeExists)
+	}
+	fmt.Fprintln(os.Stderr)
+	if !verbose {
+		return
+	}
+	for _, o := range p.large {
+		fmt.Fprintf(os.Stderr, "    %s %s\n", o.Hash, strengthen.FormatSize(o.Size))
+	}
+}
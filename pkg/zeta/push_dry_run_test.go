@@ -0,0 +1,57 @@
+package zeta
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+func TestPushPlan(t *testing.T) {
+	ctx := t.Context()
+	r, err := Init(ctx, &InitOptions{Worktree: filepath.Join(t.TempDir(), "plan"), Branch: "mainline", Quiet: true})
+	if err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	defer r.Close() // nolint
+
+	sig := object.Signature{Name: "bot", Email: "bot@example.io", When: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := r.NewCommitBuilder(plumbing.ZeroHash)
+	readme := "# plan\n"
+	if _, err := b.WriteBlob(ctx, "README.md", strings.NewReader(readme), int64(len(readme)), filemode.Regular); err != nil {
+		t.Fatal(err)
+	}
+	link, err := b.WriteBlob(ctx, "docs/latest", strings.NewReader("../README.md"), 12, filemode.Regular)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Add(ctx, "docs/latest", link, filemode.Symlink); err != nil {
+		t.Fatal(err)
+	}
+	ours, err := b.Commit(ctx, &CommitTreeOptions{Author: sig, Committer: sig, Message: "init"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	po, err := r.odb.Delta(ctx, ours, plumbing.ZeroHash, plumbing.ZeroHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := r.newPushPlan(ctx, po)
+	if err != nil {
+		t.Fatalf("plan error: %v", err)
+	}
+	if p.metadata != len(po.Metadata) || p.metadataSize == 0 {
+		t.Errorf("metadata %d (%d bytes), expected %d", p.metadata, p.metadataSize, len(po.Metadata))
+	}
+	// sizes are the stored sizes sent by push
+	if p.objects != 2 || p.objectsSize == 0 {
+		t.Errorf("objects %d (%d bytes), expected 2", p.objects, p.objectsSize)
+	}
+	if len(p.symlinks) != 1 || p.symlinks[0] != link {
+		t.Errorf("symlinks %v, expected %s", p.symlinks, link)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/plumbing/format/delta"
+	"github.com/antgroup/hugescm/modules/trace"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+const (
+	// existsBatchSize: objects per exists query, below the server limit
+	existsBatchSize = 50000
+)
+
+// filterRemoteExists returns the objects the remote does not have.
+func filterRemoteExists(ctx context.Context, t transport.Transport, oids []plumbing.Hash) ([]plumbing.Hash, error) {
+	missing := make([]plumbing.Hash, 0, len(oids))
+	for len(oids) != 0 {
+		batch := oids[:min(len(oids), existsBatchSize)]
+		oids = oids[len(batch):]
+		exists, err := t.ObjectsExists(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for i, oid := range batch {
+			if !exists[i] {
+				missing = append(missing, oid)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// skipRemoteObjects removes the metadata and the objects the remote already has from the push, eg: rebased or
+// re-pushed commits share most of their trees and blobs with the commits of other references.
+func (r *Repository) skipRemoteObjects(ctx context.Context, t transport.Transport, po *odb.PushObjects) error {
+	total := len(po.Metadata) + len(po.Objects)
+	if total == 0 {
+		return nil
+	}
+	var err error
+	if po.Metadata, err = filterRemoteExists(ctx, t, po.Metadata); err != nil {
+		return err
+	}
+	if po.Objects, err = filterRemoteExists(ctx, t, po.Objects); err != nil {
+		return err
+	}
+	trace.DbgPrint("skip %d objects the remote already has", total-len(po.Metadata)-len(po.Objects))
+	return nil
+}
+
+// deltaBases returns the bases of the wanted large blobs: the blob at the same path in the tree of theirs, only the
+// trees that differ are read. Missing commits and trees, eg: theirs was never fetched, just have no bases.
+func (r *Repository) deltaBases(ctx context.Context, theirs, ours plumbing.Hash, wanted map[plumbing.Hash]bool) (map[plumbing.Hash]plumbing.Hash, error) {
+	bases := make(map[plumbing.Hash]plumbing.Hash)
+	if theirs.IsZero() || len(wanted) == 0 {
+		return bases, nil
+	}
+	oldCommit, err := r.odb.Commit(ctx, theirs)
+	if plumbing.IsNoSuchObject(err) {
+		return bases, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	newCommit, err := r.odb.Commit(ctx, ours)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.walkDeltaBases(ctx, oldCommit.Tree, newCommit.Tree, wanted, bases); err != nil {
+		return nil, err
+	}
+	return bases, nil
+}
+
+func (r *Repository) walkDeltaBases(ctx context.Context, oldTree, newTree plumbing.Hash, wanted map[plumbing.Hash]bool, bases map[plumbing.Hash]plumbing.Hash) error {
+	if oldTree == newTree {
+		return nil
+	}
+	a, err := r.odb.Tree(ctx, oldTree)
+	if plumbing.IsNoSuchObject(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b, err := r.odb.Tree(ctx, newTree)
+	if plumbing.IsNoSuchObject(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	entries := make(map[string]*object.TreeEntry, len(a.Entries))
+	for _, e := range a.Entries {
+		entries[e.Name] = e
+	}
+	for _, e := range b.Entries {
+		old, ok := entries[e.Name]
+		if !ok || old.Hash == e.Hash {
+			continue
+		}
+		switch {
+		case e.Mode == filemode.Dir && old.Mode == filemode.Dir:
+			if err := r.walkDeltaBases(ctx, old.Hash, e.Hash, wanted, bases); err != nil {
+				return err
+			}
+		case wanted[e.Hash] && e.Type() == object.BlobObject && old.Type() == object.BlobObject:
+			bases[e.Hash] = old.Hash
+		}
+	}
+	return nil
+}
+
+func readDeltaBlob(ctx context.Context, o *odb.ODB, oid plumbing.Hash) ([]byte, error) {
+	b, err := o.Blob(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close() // nolint
+	if b.Size > transport.MAX_DELTA_OBJECT_SIZE {
+		return nil, nil
+	}
+	return io.ReadAll(b.Contents)
+}
+
+// encodeDelta returns the delta of the large object against base, nil when the object should be uploaded whole: the
+// base is missing, either is too large or the delta is not much smaller than the object.
+func (r *Repository) encodeDelta(ctx context.Context, oid, base plumbing.Hash, compressedSize int64) []byte {
+	if !r.odb.Exists(base, false) {
+		return nil
+	}
+	src, err := readDeltaBlob(ctx, r.odb, base)
+	if err != nil || src == nil {
+		return nil
+	}
+	dst, err := readDeltaBlob(ctx, r.odb, oid)
+	if err != nil || dst == nil {
+		return nil
+	}
+	d := delta.Diff(src, dst)
+	if int64(len(d)) > compressedSize/2 {
+		return nil
+	}
+	return d
+}
+
+// putDelta uploads the large object as a delta, false when the object must be uploaded whole.
+func (r *Repository) putDelta(ctx context.Context, t transport.Transport, refname plumbing.ReferenceName, oid, base plumbing.Hash, compressedSize int64, title string) bool {
+	d := r.encodeDelta(ctx, oid, base, compressedSize)
+	if d == nil {
+		return false
+	}
+	reader, done := r.uploadProgress(bytes.NewReader(d), int64(len(d)), title)
+	defer done()
+	if err := t.PutDelta(ctx, refname, oid, base, reader, int64(len(d))); err != nil {
+		trace.DbgPrint("upload %s as delta error: %v", oid, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package zeta
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/plumbing/format/delta"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+type existsTransport struct {
+	transport.Transport
+	have    map[plumbing.Hash]bool
+	queries int
+}
+
+func (t *existsTransport) ObjectsExists(ctx context.Context, oids []plumbing.Hash) ([]bool, error) {
+	t.queries++
+	exists := make([]bool, len(oids))
+	for i, oid := range oids {
+		exists[i] = t.have[oid]
+	}
+	return exists, nil
+}
+
+func TestThinPush(t *testing.T) {
+	ctx := t.Context()
+	r, err := Init(ctx, &InitOptions{Worktree: filepath.Join(t.TempDir(), "thin"), Branch: "mainline", Quiet: true})
+	if err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	defer r.Close() // nolint
+
+	sig := object.Signature{Name: "bot", Email: "bot@example.io", When: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	content := make([]byte, 1<<20)
+	_, _ = rand.New(rand.NewSource(1)).Read(content)
+	b := r.NewCommitBuilder(plumbing.ZeroHash)
+	oldBlob, err := b.WriteBlob(ctx, "assets/model.bin", bytes.NewReader(content), int64(len(content)), filemode.Regular)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirs, err := b.Commit(ctx, &CommitTreeOptions{Author: sig, Committer: sig, Message: "init"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	modified := bytes.Clone(content)
+	copy(modified[1000:], "modified")
+	newBlob, err := b.WriteBlob(ctx, "assets/model.bin", bytes.NewReader(modified), int64(len(modified)), filemode.Regular)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ours, err := b.Commit(ctx, &CommitTreeOptions{Parents: []plumbing.Hash{theirs}, Author: sig, Committer: sig, Message: "update model"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bases, err := r.deltaBases(ctx, theirs, ours, map[plumbing.Hash]bool{newBlob: true})
+	if err != nil {
+		t.Fatalf("delta bases error: %v", err)
+	}
+	if bases[newBlob] != oldBlob {
+		t.Fatalf("base of %s: %s, expected %s", newBlob, bases[newBlob], oldBlob)
+	}
+	d := r.encodeDelta(ctx, newBlob, oldBlob, int64(len(modified)))
+	if d == nil {
+		t.Fatal("modified object should be uploaded as delta")
+	}
+	if got, err := delta.Patch(content, d); err != nil || !bytes.Equal(got, modified) {
+		t.Fatalf("bad delta: %v", err)
+	}
+	if d := r.encodeDelta(ctx, newBlob, plumbing.NewHash("b3d0d5d7dc3b1ab2c56b5b8e3b6d35ad6b0fa1e8f27eddc0d5f5d5d72bb2f0f0"), int64(len(modified))); d != nil {
+		t.Fatal("missing base should upload the object whole")
+	}
+
+	// a re-push of ours to a new branch only sends what the remote does not have
+	po, err := r.odb.Delta(ctx, ours, plumbing.ZeroHash, plumbing.ZeroHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have := map[plumbing.Hash]bool{theirs: true}
+	cc, err := r.odb.Commit(ctx, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have[cc.Tree] = true
+	want := make([]plumbing.Hash, 0, len(po.Metadata))
+	for _, oid := range po.Metadata {
+		if !have[oid] {
+			want = append(want, oid)
+		}
+	}
+	tt := &existsTransport{have: have}
+	if err := r.skipRemoteObjects(ctx, tt, po); err != nil {
+		t.Fatalf("skip remote objects error: %v", err)
+	}
+	if len(po.Metadata) != len(want) || tt.queries != 2 {
+		t.Fatalf("metadata %v, expected %v", po.Metadata, want)
+	}
+	empty := &odb.PushObjects{}
+	if err := r.skipRemoteObjects(ctx, tt, empty); err != nil || tt.queries != 2 {
+		t.Fatalf("empty push should not query the remote")
+	}
+}
@@ -4,9 +4,13 @@
 package zeta
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/transport"
 )
 
 type RemoteInfo struct {
@@ -25,3 +29,28 @@ func (r *Repository) ShowRemote(opts *ShowRemoteOptions) error {
 	_, _ = fmt.Fprintf(os.Stdout, "remote: %s\n", remote)
 	return nil
 }
+
+// SetRemoteDefaultBranch changes the default branch (HEAD) of the remote repository, the branch must exist in the
+// remote. Requires the maintainer access of the remote repository.
+func (r *Repository) SetRemoteDefaultBranch(ctx context.Context, branch string) error {
+	branch = plumbing.ReferenceName(branch).BranchName()
+	if !plumbing.ValidateBranchName([]byte(branch)) {
+		die("'%s' is not a valid branch name", branch)
+		return fmt.Errorf("bad branch name '%s'", branch)
+	}
+	t, err := r.newTransport(ctx, transport.SUDO)
+	if err != nil {
+		return err
+	}
+	resp, err := t.SetDefaultBranch(ctx, branch)
+	if err != nil {
+		die_error("set remote default branch: %v", err)
+		return err
+	}
+	if resp.OldDefaultBranch == resp.DefaultBranch {
+		fmt.Fprintf(os.Stderr, W("Default branch of remote is already '%s'\n"), resp.DefaultBranch)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, W("Default branch of remote changed from '%s' to '%s'\n"), resp.OldDefaultBranch, resp.DefaultBranch)
+	return nil
+}
@@ -0,0 +1,243 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+// remoteObjects resolves 'rev:path' from the remote when the revision or the trees on the way are not in the local
+// repository, eg: files outside the sparse cone or the narrow dirs. Trees are downloaded one at a time and kept in
+// memory, blobs are streamed, nothing is written to the local ODB unless save is set.
+type remoteObjects struct {
+	*Repository
+	t       transport.Transport
+	objects map[plumbing.Hash]any
+	blobs   map[plumbing.Hash]bool
+	save    bool
+}
+
+// remoteFallback reports whether name can be resolved from the remote after the local lookup failed with err.
+func (r *Repository) remoteFallback(name string, err error) bool {
+	if err == nil || !strings.Contains(name, ":") || !r.promisorEnabled() {
+		return false
+	}
+	return plumbing.IsNoSuchObject(err) || IsErrUnknownRevision(err) || IsErrOutsideNarrow(err)
+}
+
+func (r *Repository) newRemoteObjects(ctx context.Context, save bool) (*remoteObjects, error) {
+	t, err := r.newTransport(ctx, transport.DOWNLOAD)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteObjects{
+		Repository: r,
+		t:          t,
+		objects:    make(map[plumbing.Hash]any),
+		blobs:      make(map[plumbing.Hash]bool),
+		save:       save,
+	}, nil
+}
+
+func (o *remoteObjects) fetch(ctx context.Context, oid plumbing.Hash) (any, error) {
+	if a, ok := o.objects[oid]; ok {
+		return a, nil
+	}
+	rc, err := o.t.BatchMetadata(ctx, []plumbing.Hash{oid}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := odb.MetadataDecode(rc, o)
+	if err != nil {
+		_ = rc.Close()
+		if lastErr := rc.LastError(); lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, err
+	}
+	_ = rc.Close()
+	for h, a := range objects {
+		o.objects[h] = a
+		if !o.save {
+			continue
+		}
+		if e, ok := a.(object.Encoder); ok {
+			if _, err := o.odb.WriteEncoded(e); err != nil {
+				return nil, err
+			}
+		}
+	}
+	a, ok := o.objects[oid]
+	if !ok {
+		return nil, plumbing.NoSuchObject(oid)
+	}
+	return a, nil
+}
+
+// Object returns the local object, or downloads it from the remote. Objects are bound to o so the trees below them
+// are resolved the same way.
+func (o *remoteObjects) Object(ctx context.Context, oid plumbing.Hash) (any, error) {
+	a, err := o.odb.Object(ctx, oid)
+	switch {
+	case err == nil:
+		switch v := a.(type) {
+		case *object.Commit:
+			return object.NewSnapshotCommit(v, o), nil
+		case *object.Tree:
+			return object.NewSnapshotTree(v, o), nil
+		}
+		return a, nil
+	case !plumbing.IsNoSuchObject(err):
+		return nil, err
+	case o.blobs[oid] || o.odb.Exists(oid, false):
+		return nil, err
+	}
+	return o.fetch(ctx, oid)
+}
+
+func (o *remoteObjects) Commit(ctx context.Context, oid plumbing.Hash) (*object.Commit, error) {
+	a, err := o.Object(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	if cc, ok := a.(*object.Commit); ok {
+		return cc, nil
+	}
+	return nil, backend.NewErrMismatchedObjectType(oid, "commit")
+}
+
+func (o *remoteObjects) Tree(ctx context.Context, oid plumbing.Hash) (*object.Tree, error) {
+	a, err := o.Object(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := a.(*object.Tree); ok {
+		return t, nil
+	}
+	return nil, backend.NewErrMismatchedObjectType(oid, "tree")
+}
+
+func (o *remoteObjects) Fragments(ctx context.Context, oid plumbing.Hash) (*object.Fragments, error) {
+	a, err := o.Object(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	if ff, ok := a.(*object.Fragments); ok {
+		return ff, nil
+	}
+	return nil, backend.NewErrMismatchedObjectType(oid, "fragments")
+}
+
+func (o *remoteObjects) Tag(ctx context.Context, oid plumbing.Hash) (*object.Tag, error) {
+	a, err := o.Object(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := a.(*object.Tag); ok {
+		return t, nil
+	}
+	return nil, backend.NewErrMismatchedObjectType(oid, "tag")
+}
+
+// Blob returns the local blob, or streams it from the remote, with save the blob is downloaded to the local ODB first.
+func (o *remoteObjects) Blob(ctx context.Context, oid plumbing.Hash) (*object.Blob, error) {
+	if o.odb.Exists(oid, false) {
+		return o.odb.Blob(ctx, oid)
+	}
+	if o.save {
+		if err := o.promiseMissingFetch(ctx, &promiseObject{oid: oid}); err != nil {
+			return nil, err
+		}
+		return o.odb.Blob(ctx, oid)
+	}
+	sr, err := o.t.GetObject(ctx, oid, 0)
+	if err != nil {
+		return nil, err
+	}
+	b, err := object.NewBlob(sr)
+	if err != nil {
+		_ = sr.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// revision resolves rev locally, then as a branch or tag of the remote, 'origin/' is optional.
+func (o *remoteObjects) revision(ctx context.Context, rev string) (plumbing.Hash, error) {
+	oid, err := o.Revision(ctx, rev)
+	if err == nil || !IsErrUnknownRevision(err) {
+		return oid, err
+	}
+	name := strings.TrimPrefix(rev, plumbing.Origin+"/")
+	refnames := []plumbing.ReferenceName{plumbing.NewBranchReferenceName(name), plumbing.NewTagReferenceName(name)}
+	if strings.HasPrefix(name, plumbing.ReferencePrefix) {
+		refnames = []plumbing.ReferenceName{plumbing.ReferenceName(name)}
+	}
+	for _, refname := range refnames {
+		ref, err := o.t.FetchReference(ctx, refname)
+		if errors.Is(err, transport.ErrReferenceNotExist) {
+			continue
+		}
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return ref.Target(), nil
+	}
+	return plumbing.ZeroHash, &ErrUnknownRevision{revision: rev}
+}
+
+// parseEntry resolves 'rev:path', tags are peeled, an empty path is the root tree.
+func (o *remoteObjects) parseEntry(ctx context.Context, name string) (*object.TreeEntry, error) {
+	rev, p, _ := splitRevisionPath(name)
+	if len(rev) == 0 {
+		rev = string(plumbing.HEAD)
+	}
+	oid, err := o.revision(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+	a, err := o.Object(ctx, oid)
+	for err == nil {
+		tag, ok := a.(*object.Tag)
+		if !ok {
+			break
+		}
+		a, err = o.Object(ctx, tag.Object)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var root *object.Tree
+	switch v := a.(type) {
+	case *object.Commit:
+		if root, err = v.Root(ctx); err != nil {
+			return nil, err
+		}
+	case *object.Tree:
+		root = v
+	default:
+		return nil, ErrNotTree
+	}
+	p = strings.Trim(p, "/")
+	if len(p) == 0 {
+		return &object.TreeEntry{Hash: root.Hash, Mode: filemode.Dir, Name: root.Hash.String()}, nil
+	}
+	e, err := root.FindEntry(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if e.Type() == object.BlobObject {
+		o.blobs[e.Hash] = true
+	}
+	return e, nil
+}
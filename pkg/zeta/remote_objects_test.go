@@ -0,0 +1,139 @@
+package zeta
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/binary"
+	"github.com/antgroup/hugescm/modules/crc"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/transport"
+)
+
+type memoryReader struct {
+	*bytes.Reader
+	size int64
+}
+
+func (r *memoryReader) Close() error     { return nil }
+func (r *memoryReader) LastError() error { return nil }
+func (r *memoryReader) Offset() int64    { return 0 }
+func (r *memoryReader) Size() int64      { return r.size }
+
+// objectsTransport serves metadata and blobs from the repository of the remote.
+type objectsTransport struct {
+	transport.Transport
+	remote  *Repository
+	batches int
+}
+
+func (t *objectsTransport) BatchMetadata(ctx context.Context, oids []plumbing.Hash, depth int, limit int) (transport.SessionReader, error) {
+	t.batches++
+	var b bytes.Buffer
+	cw := crc.NewCrc64Writer(&b)
+	var reserved [16]byte
+	_ = binary.Write(cw, []byte{'Z', 'M', '\x00', '\x01'}, uint32(1), reserved[:])
+	for _, oid := range oids {
+		a, err := t.remote.odb.Object(ctx, oid)
+		if err != nil {
+			return nil, err
+		}
+		var data bytes.Buffer
+		if err := a.(object.Encoder).Encode(&data); err != nil {
+			return nil, err
+		}
+		_ = binary.Write(cw, uint32(data.Len()+plumbing.HASH_HEX_SIZE), []byte(oid.String()), data.Bytes())
+	}
+	_ = binary.Write(cw, uint32(0))
+	if _, err := cw.Finish(); err != nil {
+		return nil, err
+	}
+	return &memoryReader{Reader: bytes.NewReader(b.Bytes())}, nil
+}
+
+func (t *objectsTransport) GetObject(ctx context.Context, oid plumbing.Hash, fromByte int64) (transport.SizeReader, error) {
+	rc, err := t.remote.odb.OpenReader(oid, false)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close() // nolint
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryReader{Reader: bytes.NewReader(data), size: int64(len(data))}, nil
+}
+
+func TestRemoteObjects(t *testing.T) {
+	ctx := t.Context()
+	newRepo := func(name string) *Repository {
+		r, err := Init(ctx, &InitOptions{Worktree: filepath.Join(t.TempDir(), name), Quiet: true})
+		if err != nil {
+			t.Fatalf("init error: %v", err)
+		}
+		t.Cleanup(func() { _ = r.Close() })
+		return r
+	}
+	remote, local := newRepo("remote"), newRepo("local")
+	write := func(e object.Encoder, repos ...*Repository) plumbing.Hash {
+		var oid plumbing.Hash
+		for _, r := range repos {
+			var err error
+			if oid, err = r.odb.WriteEncoded(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return oid
+	}
+	content := "remote spec\n"
+	blob, err := remote.odb.HashTo(ctx, strings.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := write(&object.Tree{Entries: []*object.TreeEntry{{Name: "spec.md", Mode: filemode.Regular, Hash: blob, Size: int64(len(content))}}}, remote)
+	root := write(&object.Tree{Entries: []*object.TreeEntry{{Name: "docs", Mode: filemode.Dir, Hash: docs}}}, remote, local)
+	sig := object.Signature{Name: "zeta", Email: "zeta@example.io", When: time.Now()}
+	commit := write(&object.Commit{Author: sig, Committer: sig, Tree: root, Message: "remote\n"}, remote, local)
+
+	name := commit.String() + ":docs/spec.md"
+	if _, _, err := local.parseObject(ctx, name); !local.remoteFallback(name, err) {
+		t.Fatalf("docs is not in the local repository: %v", err)
+	}
+	tr := &objectsTransport{remote: remote}
+	rs := &remoteObjects{Repository: local, t: tr, objects: make(map[plumbing.Hash]any), blobs: make(map[plumbing.Hash]bool)}
+	e, err := rs.parseEntry(ctx, name)
+	if err != nil {
+		t.Fatalf("resolve %s error: %v", name, err)
+	}
+	if e.Hash != blob || tr.batches != 1 {
+		t.Fatalf("unexpected entry %s, batches: %d", e.Hash, tr.batches)
+	}
+	b, err := rs.Blob(ctx, e.Hash)
+	if err != nil {
+		t.Fatalf("open blob error: %v", err)
+	}
+	got, err := io.ReadAll(b.Contents)
+	_ = b.Close()
+	if err != nil || string(got) != content {
+		t.Fatalf("unexpected blob content %q: %v", got, err)
+	}
+	if local.odb.Exists(docs, true) || local.odb.Exists(blob, false) {
+		t.Fatalf("objects resolved from the remote should not be saved")
+	}
+
+	rs.save = true
+	rs.objects = make(map[plumbing.Hash]any)
+	if _, err := rs.parseEntry(ctx, name); err != nil {
+		t.Fatalf("resolve %s error: %v", name, err)
+	}
+	if !local.odb.Exists(docs, true) {
+		t.Fatalf("tree %s should be saved", docs)
+	}
+}
@@ -17,6 +17,7 @@ import (
 
 	"charm.land/lipgloss/v2"
 	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/format/ignore"
 	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/modules/term"
 	"github.com/antgroup/hugescm/modules/trace"
@@ -31,7 +32,6 @@ import (
 	"github.com/antgroup/hugescm/pkg/zeta/odb"
 
 	"github.com/antgroup/hugescm/pkg/transport"
-	"github.com/antgroup/hugescm/pkg/transport/client"
 )
 
 const (
@@ -124,6 +124,7 @@ type NewOptions struct {
 	Destination string
 	Depth       int
 	SparseDirs  []string
+	NarrowDirs  []string
 	Snapshot    bool
 	SizeLimit   int64
 	Values      []string
@@ -138,21 +139,34 @@ const (
 	pathRoot = "/"
 )
 
-func (opts *NewOptions) tidySparse() {
-	if len(opts.SparseDirs) == 0 {
-		return
+func tidyDirs(dirs []string, warning string) []string {
+	if len(dirs) == 0 {
+		return nil
 	}
-	sparseDirs := make([]string, 0, len(opts.SparseDirs))
-	for _, s := range opts.SparseDirs {
+	tidyDirs := make([]string, 0, len(dirs))
+	for _, s := range dirs {
 		if filepath.IsAbs(s) {
-			_, _ = term.Fprintf(os.Stderr, "\x1b[01;33m%s: \x1b[0;33m'%s' %s\x1b[0m\n", W("WARNING"), s, W("is an absolute path and cannot be set as a sparse dir."))
+			_, _ = term.Fprintf(os.Stderr, "\x1b[01;33m%s: \x1b[0;33m'%s' %s\x1b[0m\n", W("WARNING"), s, W(warning))
 			continue
 		}
 		p := path.Clean(s)
 		if p == dot || p == dotDot || p == pathRoot {
 			continue
 		}
-		sparseDirs = append(sparseDirs, p)
+		tidyDirs = append(tidyDirs, p)
+	}
+	return tidyDirs
+}
+
+func (opts *NewOptions) tidySparse() {
+	opts.SparseDirs = tidyDirs(opts.SparseDirs, "is an absolute path and cannot be set as a sparse dir.")
+	opts.NarrowDirs = tidyDirs(opts.NarrowDirs, "is an absolute path and cannot be set as a narrow dir.")
+	if len(opts.NarrowDirs) == 0 {
+		return
+	}
+	sparseDirs, outside := effectiveSparse(opts.NarrowDirs, opts.SparseDirs)
+	for _, s := range outside {
+		warn("sparse dir '%s' is outside the narrow spec, ignored", s)
 	}
 	opts.SparseDirs = sparseDirs
 }
@@ -179,8 +193,12 @@ type Repository struct {
 	zetaDir           string
 	missingNotFailure bool
 	values            map[string]StringArray
+	sealer            *backend.Sealer
 	quiet             bool
 	verbose           bool
+	locker            lockManager
+	// cacheStatsReset: the cache stats were reset by this process, its metrics are not recorded
+	cacheStatsReset bool
 }
 
 func parseInsecureSkipTLS(cfg *config.Config, values map[string]StringArray) bool {
@@ -193,6 +211,18 @@ func parseInsecureSkipTLS(cfg *config.Config, values map[string]StringArray) boo
 	return cfg.HTTP.SSLVerify.False()
 }
 
+// httpCacheDir: HTTP response cache is enabled by default, disable it with http.cache=false
+func (r *Repository) httpCacheDir() string {
+	if cache, ok := getStringFromValues("http.cache", r.values); ok {
+		if !strengthen.SimpleAtob(cache, true) {
+			return ""
+		}
+	} else if r.HTTP.Cache.False() {
+		return ""
+	}
+	return filepath.Join(r.zetaDir, "cache", "http")
+}
+
 func parseExtraHeader(cfg *config.Config, values map[string]StringArray) []string {
 	extraHeader := make([]string, 0, len(cfg.HTTP.ExtraHeader))
 	if sa, ok := getStringsFromValues("http.extraHeader", values); ok {
@@ -219,6 +249,15 @@ func parseCredentialConfig(cfg *config.Config, values map[string]StringArray) (s
 	return
 }
 
+// flushEncryptObjects saves the object encryption settings of the command line, otherwise objects sealed during
+// checkout are unreadable later.
+func flushEncryptObjects(cfg *config.Config, values map[string]StringArray) {
+	cfg.Core.EncryptObjects = config.True
+	if s, ok := getStringFromValues("core.encryptionKeyCommand", values); ok {
+		cfg.Core.EncryptionKeyCommand = s
+	}
+}
+
 func parseSharingRoot(cfg *config.Config, values map[string]StringArray) (string, bool) {
 	if sharingRoot, ok := getStringFromValues("core.sharingRoot", values); ok && len(sharingRoot) > 0 && filepath.IsAbs(sharingRoot) {
 		return sharingRoot, true
@@ -237,6 +276,9 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 	if err := opts.Validate(); err != nil {
 		return nil, err
 	}
+	if t := findTranslator(opts.Remote); t != nil {
+		return newTranslated(ctx, t, opts)
+	}
 
 	// New config from global config
 	cfg, err := config.LoadBaseline()
@@ -277,7 +319,7 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 		_ = os.RemoveAll(destination)
 	}()
 
-	ta, err := client.NewTransport(ctx, endpoint, transport.DOWNLOAD, opts.Verbose)
+	ta, err := newLoggedTransport(ctx, endpoint, transport.DOWNLOAD, zetaDir, opts.Verbose)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "connect remote: %v\n", err)
 		return nil, err
@@ -316,13 +358,22 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 		target = plumbing.NewHash(ref.Hash)
 	}
 
+	healer := &objectHealer{}
 	odbOpts := make([]backend.Option, 0, 2)
-	odbOpts = append(odbOpts, backend.WithCompressionALGO(ref.CompressionALGO), backend.WithEnableLRU(true))
+	odbOpts = append(odbOpts, backend.WithCompressionALGO(ref.CompressionALGO), backend.WithCache(backend.NewCache(parseCacheSize(cfg, values))), backend.WithHealer(healer))
 	var sharingRoot string
 	var sharingSet bool
 	if sharingRoot, sharingSet = parseSharingRoot(cfg, values); sharingSet {
 		odbOpts = append(odbOpts, backend.WithSharingRoot(sharingRoot))
 	}
+	sealer, err := parseObjectSealer(ctx, cfg, values)
+	if err != nil {
+		die_error("object encryption: %v", err)
+		return nil, err
+	}
+	if sealer != nil {
+		odbOpts = append(odbOpts, backend.WithSealer(sealer))
+	}
 	odb, err := odb.NewODB(zetaDir, odbOpts...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "new objects database error: %v\n", err)
@@ -339,6 +390,7 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 		Core: config.Core{
 			Remote:          endpoint.String(),
 			SparseDirs:      opts.SparseDirs,
+			NarrowDirs:      opts.NarrowDirs,
 			Snapshot:        opts.Snapshot,
 			CompressionALGO: ref.CompressionALGO,
 		},
@@ -346,7 +398,12 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 	// Flush sharingRoot
 	if sharingSet {
 		newConfig.Core.SharingRoot = sharingRoot
+		registerSharedClone(sharingRoot, zetaDir)
+	}
+	if sealer != nil {
+		flushEncryptObjects(newConfig, values)
 	}
+	probeFileSystem(zetaDir, newConfig)
 	// Write new config to disk
 	if err := config.Encode(zetaDir, newConfig); err != nil {
 		fmt.Fprintf(os.Stderr, "encode config error: %v\n", err)
@@ -362,9 +419,11 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 		zetaDir: zetaDir,
 		baseDir: destination,
 		values:  values,
+		sealer:  sealer,
 		quiet:   opts.Quiet,
 		verbose: opts.Verbose,
 	}
+	healer.r = r
 	if opts.SizeLimit != -1 {
 		r.missingNotFailure = true
 	}
@@ -385,6 +444,9 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 		}
 	}
 	fmt.Fprintf(os.Stderr, "Checkout from '%s' to %s... sparse-checkout: %v, snapshot: %v\n", r.cleanedRemote(), target.String()[0:8], len(r.Core.SparseDirs) != 0, opts.Snapshot)
+	if len(r.Core.NarrowDirs) != 0 {
+		fmt.Fprintf(os.Stderr, "Narrow clone, only %s are fetched\n", strings.Join(r.Core.NarrowDirs, ", "))
+	}
 	if len(r.Core.SparseDirs) != 0 {
 		fmt.Fprintf(os.Stderr, "")
 	}
@@ -398,6 +460,7 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 		}
 		return nil, err
 	}
+	r.syncTemplates(ctx, ta, ref)
 	commit := target
 	if len(ref.Peeled) != 0 {
 		commit = plumbing.NewHash(ref.Peeled)
@@ -475,23 +538,44 @@ func Open(ctx context.Context, opts *OpenOptions) (*Repository, error) {
 		die_error("%v", err)
 		return nil, err
 	}
-	cfg, err := config.Load(zetaDir)
+	values := valuesMapArray(opts.Values)
+	cfg, err := loadRepositoryConfig(worktree, zetaDir, values)
+	if errors.Is(err, ErrDubiousOwnership) {
+		dubiousOwnership(worktree)
+		return nil, err
+	}
 	if err != nil {
 		die_error("%v", err)
 		return nil, err
 	}
+	healer := &objectHealer{}
 	odbOpts := make([]backend.Option, 0, 2)
-	odbOpts = append(odbOpts, backend.WithCompressionALGO(cfg.Core.CompressionALGO), backend.WithEnableLRU(true))
-	values := valuesMapArray(opts.Values)
+	odbOpts = append(odbOpts, backend.WithCompressionALGO(cfg.Core.CompressionALGO), backend.WithCache(backend.NewCache(parseCacheSize(cfg, values))), backend.WithHealer(healer))
 
 	if sharingRoot, sharingSet := parseSharingRoot(cfg, values); sharingSet {
 		odbOpts = append(odbOpts, backend.WithSharingRoot(sharingRoot))
+		registerSharedClone(sharingRoot, zetaDir)
+	}
+	sealer, err := parseObjectSealer(ctx, cfg, values)
+	if err != nil {
+		die_error("object encryption: %v", err)
+		return nil, err
+	}
+	if sealer != nil {
+		odbOpts = append(odbOpts, backend.WithSealer(sealer))
 	}
 	odb, err := odb.NewODB(zetaDir, odbOpts...)
 	if err != nil {
 		die("open odb: %v", err)
 		return nil, err
 	}
+	if len(cfg.Core.NarrowDirs) != 0 {
+		sparseDirs, outside := effectiveSparse(cfg.Core.NarrowDirs, cfg.Core.SparseDirs)
+		for _, s := range outside {
+			warn("sparse dir '%s' is outside the narrow spec, ignored", s)
+		}
+		cfg.Core.SparseDirs = sparseDirs
+	}
 	r := &Repository{
 		Config:  cfg,
 		zetaDir: zetaDir,
@@ -500,9 +584,11 @@ func Open(ctx context.Context, opts *OpenOptions) (*Repository, error) {
 		Backend: refs.NewBackend(zetaDir),
 		rdb:     reflog.NewDB(zetaDir),
 		values:  values,
+		sealer:  sealer,
 		quiet:   opts.Quiet,
 		verbose: opts.Verbose,
 	}
+	healer.r = r
 	// Warn if the repository is on a network filesystem
 	if ds, err := strengthen.GetDiskFreeSpaceEx(zetaDir); err == nil {
 		if warningFs[strings.ToLower(ds.FS)] {
@@ -538,8 +624,8 @@ func Init(ctx context.Context, opts *InitOptions) (*Repository, error) {
 	cfg.Core.CompressionALGO = odb.DefaultCompressionALGO
 
 	odbOpts := make([]backend.Option, 0, 2)
-	odbOpts = append(odbOpts, backend.WithCompressionALGO(odb.DefaultCompressionALGO), backend.WithEnableLRU(true))
 	values := valuesMapArray(opts.Values)
+	odbOpts = append(odbOpts, backend.WithCompressionALGO(odb.DefaultCompressionALGO), backend.WithCache(backend.NewCache(parseCacheSize(cfg, values))))
 	var sharingRoot string
 	var sharingSet bool
 	if sharingRoot, sharingSet = parseSharingRoot(cfg, values); sharingSet {
@@ -552,7 +638,20 @@ func Init(ctx context.Context, opts *InitOptions) (*Repository, error) {
 	}
 	if sharingSet {
 		newConfig.Core.SharingRoot = sharingRoot
+		registerSharedClone(sharingRoot, zetaDir)
+	}
+	sealer, err := parseObjectSealer(ctx, cfg, values)
+	if err != nil {
+		die_error("object encryption: %v", err)
+		return nil, err
+	}
+	if sealer != nil {
+		odbOpts = append(odbOpts, backend.WithSealer(sealer))
+		flushEncryptObjects(newConfig, values)
 	}
+	probeFileSystem(zetaDir, newConfig)
+	cfg.Core.FileMode.Merge(&newConfig.Core.FileMode)
+	cfg.Core.Symlinks.Merge(&newConfig.Core.Symlinks)
 	// Write new config to disk
 	if err := config.Encode(zetaDir, newConfig); err != nil {
 		die("encode config: %v")
@@ -573,6 +672,7 @@ func Init(ctx context.Context, opts *InitOptions) (*Repository, error) {
 		zetaDir: zetaDir,
 		values:  values,
 		baseDir: destination,
+		sealer:  sealer,
 		quiet:   opts.Quiet,
 		verbose: opts.Verbose,
 	}
@@ -674,6 +774,14 @@ func (r *Repository) promisorEnabled() bool {
 	return strengthen.SimpleAtob(os.Getenv(ENV_ZETA_CORE_PROMISOR), true)
 }
 
+// core.ignoreCompat=git OR ZETA_CORE_IGNORE_COMPAT=git read .gitignore in directories without .zetaignore
+func (r *Repository) ignoreCompat() ignore.Compat {
+	if s, ok := r.getFromValueOrEnv("core.ignoreCompat", ENV_ZETA_CORE_IGNORE_COMPAT); ok {
+		return ignore.ParseCompat(s)
+	}
+	return ignore.ParseCompat(r.Core.IgnoreCompat)
+}
+
 func (r *Repository) maxEntries() int {
 	if maxEntries, ok := r.getIntFromValueOrEnv("transport.maxEntries", ENV_ZETA_TRANSPORT_MAX_ENTRIES); ok && maxEntries > 0 {
 		return maxEntries
@@ -725,10 +833,22 @@ func (r *Repository) mergeConflictStyle() string {
 }
 
 func (r *Repository) Postflight(ctx context.Context) error {
+	// the checkout succeeded, a failed eviction only leaves the blobs over budget until the next one
+	if err := r.enforceCacheSize(ctx); err != nil {
+		warn("cache: evict blobs: %v", err)
+	}
 	if !r.IsExtreme() {
 		return nil
 	}
-	oids, totalSize, err := r.odb.PruneObjects(ctx, extremeSize)
+	var head plumbing.Hash
+	if current, err := r.Current(); err == nil {
+		head = current.Hash()
+	}
+	keep, err := r.keepFunc(ctx, head)
+	if err != nil {
+		return err
+	}
+	oids, totalSize, err := r.odb.PruneObjects(ctx, extremeSize, keep)
 	if err != nil {
 		return err
 	}
@@ -778,16 +898,14 @@ func (r *Repository) cleanedRemote() string {
 	if err != nil {
 		return r.Core.Remote
 	}
-	switch {
-	case u.Scheme == "ssh" && u.User != nil:
-		u.User = url.User(u.User.Username()) // hide ssh password
-	default:
-		u.User = nil
-	}
-	return u.String()
+	return redactedEndpoint(u)
 }
 
 func (r *Repository) newTransport(ctx context.Context, operation transport.Operation) (transport.Transport, error) {
+	if IsTranslatedRemote(r.Core.Remote) {
+		die("%s: %v", r.cleanedRemote(), ErrTranslatedRemote)
+		return nil, ErrTranslatedRemote
+	}
 	credStorage, credEncryptionKey, credStoragePath := parseCredentialConfig(r.Config, r.values)
 	endpoint, err := transport.NewEndpoint(r.Core.Remote, &transport.Options{
 		InsecureSkipTLS:         parseInsecureSkipTLS(r.Config, r.values),
@@ -796,12 +914,13 @@ func (r *Repository) newTransport(ctx context.Context, operation transport.Opera
 		CredentialStorage:       credStorage,
 		CredentialEncryptionKey: credEncryptionKey,
 		CredentialStoragePath:   credStoragePath,
+		CacheDir:                r.httpCacheDir(),
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "bad remote: %v\n", err)
 		return nil, err
 	}
-	t, err := client.NewTransport(ctx, endpoint, operation, r.verbose)
+	t, err := newLoggedTransport(ctx, endpoint, operation, r.zetaDir, r.verbose)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "connect remote: %v\n", err)
 		return nil, err
@@ -813,5 +932,6 @@ func (r *Repository) Close() error {
 	if r.odb == nil {
 		return nil
 	}
+	r.recordCacheMetrics()
 	return r.odb.Close()
 }
@@ -10,9 +10,11 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/modules/zeta/backend"
 	"github.com/antgroup/hugescm/modules/zeta/object"
 )
@@ -32,6 +34,88 @@ func parseReflogRev(rev string) (string, int, error) {
 	return refname, depth, err
 }
 
+// splitReflogRevision: main@{yesterday} --> main, yesterday; @{1} --> "", 1
+func splitReflogRevision(revision string) (string, string, bool) {
+	pos := strings.Index(revision, "@{")
+	if pos == -1 || !strings.HasSuffix(revision, "}") {
+		return "", "", false
+	}
+	return revision[:pos], revision[pos+2 : len(revision)-1], true
+}
+
+// splitRevisionPath: <revision>:<path>, the date of main@{2024-05-01 10:30}:docs may contain ':'
+func splitRevisionPath(s string) (string, string, bool) {
+	offset := 0
+	if pos := strings.Index(s, "@{"); pos != -1 {
+		if end := strings.IndexByte(s[pos:], '}'); end != -1 {
+			offset = pos + end
+		}
+	}
+	before, after, ok := strings.Cut(s[offset:], ":")
+	return s[:offset] + before, after, ok
+}
+
+// reflogReferenceName: empty is the current branch, HEAD, full reference name, branch, origin/<branch>, refs/<name> (stash)
+func (r *Repository) reflogReferenceName(name string) (plumbing.ReferenceName, error) {
+	switch {
+	case len(name) == 0:
+		current, err := r.Current()
+		if err != nil {
+			return "", err
+		}
+		return current.Name(), nil
+	case name == string(plumbing.HEAD):
+		return plumbing.HEAD, nil
+	case strings.HasPrefix(name, plumbing.ReferencePrefix):
+		return plumbing.ReferenceName(name), nil
+	}
+	candidates := []plumbing.ReferenceName{plumbing.NewBranchReferenceName(name)}
+	if branchRemote, ok := strings.CutPrefix(name, plumbing.Origin+"/"); ok {
+		candidates = append(candidates, plumbing.NewRemoteReferenceName(plumbing.Origin, branchRemote))
+	}
+	candidates = append(candidates, plumbing.ReferenceName(plumbing.ReferencePrefix+name))
+	for _, refname := range candidates {
+		if r.rdb.Exists(refname) {
+			return refname, nil
+		}
+	}
+	return "", &ErrUnknownRevision{revision: name}
+}
+
+// resolveReflogRevision: <refname>@{<n>} is the n-th prior value of the reference, <refname>@{<date>} is the value of
+// the reference at the date, both are resolved through the reflog.
+func (r *Repository) resolveReflogRevision(name, selector string) (plumbing.Hash, error) {
+	refname, err := r.reflogReferenceName(name)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if !r.rdb.Exists(refname) {
+		return plumbing.ZeroHash, fmt.Errorf(W("log for '%s' is empty"), refname.Short())
+	}
+	ro, err := r.rdb.Read(refname)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if ro.Empty() {
+		return plumbing.ZeroHash, fmt.Errorf(W("log for '%s' is empty"), refname.Short())
+	}
+	if n, err := strconv.Atoi(selector); err == nil {
+		if n < 0 || n >= len(ro.Entries) {
+			return plumbing.ZeroHash, fmt.Errorf(W("log for '%s' only has %d entries"), refname.Short(), len(ro.Entries))
+		}
+		return ro.Entries[n].N, nil
+	}
+	at, err := strengthen.ParseDate(selector, time.Now())
+	if err != nil {
+		return plumbing.ZeroHash, &ErrUnknownRevision{revision: name + "@{" + selector + "}"}
+	}
+	e, ok := ro.At(at)
+	if !ok {
+		warn("log for '%s' only goes back to %s", refname.Short(), e.Committer.When.Format(time.RFC1123Z))
+	}
+	return e.N, nil
+}
+
 func resolveAncestor(revision string) (string, int, error) {
 	if before, after, ok := strings.Cut(revision, "~"); ok {
 		ns := after
@@ -96,6 +180,9 @@ func (r *Repository) resolveRevision(ctx context.Context, revision string) (plum
 		return plumbing.ZeroHash, ctx.Err()
 	default:
 	}
+	if name, selector, ok := splitReflogRevision(revision); ok {
+		return r.resolveReflogRevision(name, selector)
+	}
 	if revision == string(plumbing.HEAD) {
 		current, err := r.Current()
 		if err != nil {
@@ -141,7 +228,7 @@ func (r *Repository) resolveRevision(ctx context.Context, revision string) (plum
 //	https://git-scm.com/book/en/v2/Git-Tools-Revision-Selection
 //	We are not strictly compatible with Git, do not support combination mode, and do not support finding the second parent
 //
-// eg: HEAD HEAD^^^^ HEAD~2 BRANCH or TAG Long-OID Short-OID
+// eg: HEAD HEAD^^^^ HEAD~2 BRANCH or TAG Long-OID Short-OID main@{1} main@{yesterday} @{2.days.ago}
 func (r *Repository) Revision(ctx context.Context, branchOrTag string) (plumbing.Hash, error) {
 	revision, ancestor, err := resolveAncestor(branchOrTag)
 	if err != nil {
@@ -200,6 +287,9 @@ func (r *Repository) readTree(ctx context.Context, oid plumbing.Hash, p string)
 	}
 	switch a := o.(type) {
 	case *object.Tag:
+		if err := r.checkNarrowPath(p); err != nil {
+			return nil, err
+		}
 		return r.tagTargetTree(ctx, a, p)
 	case *object.Tree:
 		if len(p) == 0 {
@@ -214,6 +304,9 @@ func (r *Repository) readTree(ctx context.Context, oid plumbing.Hash, p string)
 		}
 		return r.odb.Tree(ctx, e.Hash)
 	case *object.Commit:
+		if err := r.checkNarrowPath(p); err != nil {
+			return nil, err
+		}
 		root, err := r.odb.Tree(ctx, a.Tree)
 		if err != nil {
 			return nil, err
@@ -268,6 +361,9 @@ func (r *Repository) parseEntry(ctx context.Context, oid plumbing.Hash, p string
 	}
 	switch a := o.(type) {
 	case *object.Tag:
+		if err := r.checkNarrowPath(p); err != nil {
+			return nil, err
+		}
 		return r.parseTargetEntry(ctx, a, p)
 	case *object.Tree:
 		if len(p) == 0 {
@@ -275,6 +371,9 @@ func (r *Repository) parseEntry(ctx context.Context, oid plumbing.Hash, p string
 		}
 		return a.FindEntry(ctx, p)
 	case *object.Commit:
+		if err := r.checkNarrowPath(p); err != nil {
+			return nil, err
+		}
 		root, err := r.odb.Tree(ctx, a.Tree)
 		if err != nil {
 			return nil, err
@@ -290,7 +389,7 @@ func (r *Repository) parseEntry(ctx context.Context, oid plumbing.Hash, p string
 }
 
 func (r *Repository) parseTreeEntryExhaustive(ctx context.Context, branchOrTag string) (*object.TreeEntry, string, error) {
-	prefix, p, ok := strings.Cut(branchOrTag, ":")
+	prefix, p, ok := splitRevisionPath(branchOrTag)
 	oid, err := r.Revision(ctx, prefix)
 	if err != nil {
 		return nil, "", err
@@ -306,7 +405,7 @@ func (r *Repository) parseTreeEntryExhaustive(ctx context.Context, branchOrTag s
 }
 
 func (r *Repository) parseTreeExhaustive(ctx context.Context, branchOrTag string) (*object.Tree, error) {
-	prefix, p, _ := strings.Cut(branchOrTag, ":")
+	prefix, p, _ := splitRevisionPath(branchOrTag)
 	oid, err := r.Revision(ctx, prefix)
 	if err != nil {
 		return nil, err
@@ -331,6 +430,10 @@ func (r *Repository) IsCurrent(refname plumbing.ReferenceName) bool {
 }
 
 func (r *Repository) RevisionEx(ctx context.Context, revision string) (plumbing.Hash, plumbing.ReferenceName, error) {
+	if name, selector, ok := splitReflogRevision(revision); ok {
+		oid, err := r.resolveReflogRevision(name, selector)
+		return oid, "", err
+	}
 	if revision == string(plumbing.HEAD) {
 		current, err := r.Current()
 		if err != nil {
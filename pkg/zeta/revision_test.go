@@ -121,3 +121,44 @@ func TestParseReflogRev(t *testing.T) {
 		fmt.Fprintf(os.Stderr, "GOOD: [%s %d]\n", n, d)
 	}
 }
+
+func TestSplitReflogRevision(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		selector string
+		ok       bool
+	}{
+		{"mainline@{1}", "mainline", "1", true},
+		{"@{yesterday}", "", "yesterday", true},
+		{"origin/dev@{2024-05-01 10:30}", "origin/dev", "2024-05-01 10:30", true},
+		{"mainline", "", "", false},
+		{"mainline@{1", "", "", false},
+	}
+	for _, tt := range tests {
+		name, selector, ok := splitReflogRevision(tt.input)
+		if name != tt.name || selector != tt.selector || ok != tt.ok {
+			t.Errorf("splitReflogRevision(%q) = %q %q %v", tt.input, name, selector, ok)
+		}
+	}
+}
+
+func TestSplitRevisionPath(t *testing.T) {
+	tests := []struct {
+		input string
+		rev   string
+		p     string
+		ok    bool
+	}{
+		{"HEAD:docs/a.md", "HEAD", "docs/a.md", true},
+		{"mainline@{2024-05-01 10:30}:docs", "mainline@{2024-05-01 10:30}", "docs", true},
+		{"mainline@{2024-05-01 10:30}", "mainline@{2024-05-01 10:30}", "", false},
+		{"HEAD", "HEAD", "", false},
+	}
+	for _, tt := range tests {
+		rev, p, ok := splitRevisionPath(tt.input)
+		if rev != tt.rev || p != tt.p || ok != tt.ok {
+			t.Errorf("splitRevisionPath(%q) = %q %q %v", tt.input, rev, p, ok)
+		}
+	}
+}
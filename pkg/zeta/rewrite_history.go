@@ -0,0 +1,600 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/mailmap"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+const (
+	rewriteHistoryDir = "rewrite-history"
+	// rewriteRemoved: replacement of a message expression without '==>', same as git filter-repo
+	rewriteRemoved = "***REMOVED***"
+)
+
+// RewriteHistoryOptions: options of zeta rewrite-history, like git filter-repo the branches and tags are rewritten.
+type RewriteHistoryOptions struct {
+	RemovePaths      []string // remove the files and dirs matching the paths or wildcards
+	StripBlobsBigger int64    // remove the files larger than the size, ignored when <= 0
+	ReplaceBlobs     []string // '<blob>=<file>' replaces the blob with the contents of file, '<blob>' removes the files
+	ReplaceMessage   string   // file of 'old==>new' expressions applied to commit and tag messages
+	Mailmap          string   // mailmap file used to rewrite authors, committers and taggers
+	PruneEmpty       bool     // drop the commits which become empty
+	DryRun           bool     // write the new objects and the maps, do not update the references
+	Push             bool     // force push the rewritten references
+}
+
+// messageReplacement: an expression of --replace-message.
+type messageReplacement struct {
+	literal string
+	re      *regexp.Regexp
+	to      string
+}
+
+func (m *messageReplacement) replace(s string) string {
+	if m.re != nil {
+		return m.re.ReplaceAllString(s, m.to)
+	}
+	return strings.ReplaceAll(s, m.literal, m.to)
+}
+
+// parseMessageReplacements parses the expressions of git filter-repo --replace-message: 'old==>new' replaces old
+// with new, 'old' replaces old with ***REMOVED***, 'regex:' prefixed expressions are regular expressions, the
+// 'literal:' prefix is optional. Empty lines and lines starting with '#' are skipped.
+func parseMessageReplacements(r io.Reader) ([]*messageReplacement, error) {
+	var replacements []*messageReplacement
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		from, to, ok := strings.Cut(line, "==>")
+		if !ok {
+			to = rewriteRemoved
+		}
+		if expr, ok := strings.CutPrefix(from, "regex:"); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("bad expression '%s': %w", line, err)
+			}
+			replacements = append(replacements, &messageReplacement{re: re, to: to})
+			continue
+		}
+		from = strings.TrimPrefix(from, "literal:")
+		if len(from) == 0 {
+			return nil, fmt.Errorf("bad expression '%s': nothing to replace", line)
+		}
+		replacements = append(replacements, &messageReplacement{literal: from, to: to})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return replacements, nil
+}
+
+// blobReplacement: the new blob of a replaced blob, a zero hash removes the files.
+type blobReplacement struct {
+	hash      plumbing.Hash
+	size      int64
+	fragments bool
+}
+
+type historyRewriter struct {
+	*Repository
+	opts     *RewriteHistoryOptions
+	matcher  *Matcher // nil when no paths are removed
+	blobs    map[plumbing.Hash]*blobReplacement
+	messages []*messageReplacement
+	mailmap  *mailmap.Mailmap
+	trees    map[string]plumbing.Hash        // '<dir>\x00<tree>' -> rewritten tree, zero when empty
+	commits  map[plumbing.Hash]plumbing.Hash // commit -> rewritten commit, zero when pruned to nothing
+	order    []plumbing.Hash                 // commits in the order they were rewritten, parents first
+	changed  int
+	pruned   int
+}
+
+func (r *Repository) newHistoryRewriter(ctx context.Context, opts *RewriteHistoryOptions) (*historyRewriter, error) {
+	h := &historyRewriter{
+		Repository: r,
+		opts:       opts,
+		blobs:      make(map[plumbing.Hash]*blobReplacement),
+		mailmap:    mailmap.New(),
+		trees:      make(map[string]plumbing.Hash),
+		commits:    make(map[plumbing.Hash]plumbing.Hash),
+	}
+	if len(opts.RemovePaths) != 0 {
+		h.matcher = NewMatcher(opts.RemovePaths)
+	}
+	for _, s := range opts.ReplaceBlobs {
+		if err := h.parseBlobReplacement(ctx, s); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.ReplaceMessage) != 0 {
+		fd, err := os.Open(opts.ReplaceMessage)
+		if err != nil {
+			return nil, err
+		}
+		defer fd.Close() // nolint
+		if h.messages, err = parseMessageReplacements(fd); err != nil {
+			return nil, fmt.Errorf("parse '%s': %w", opts.ReplaceMessage, err)
+		}
+	}
+	if len(opts.Mailmap) != 0 {
+		if err := h.mailmap.ParseFile(strengthen.ExpandPath(opts.Mailmap)); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+func (h *historyRewriter) parseBlobReplacement(ctx context.Context, s string) error {
+	oldRev, file, ok := strings.Cut(s, "=")
+	if !plumbing.ValidateHashHex(oldRev) {
+		return fmt.Errorf("bad blob replacement '%s', expected <blob>[=<file>]", s)
+	}
+	if !ok {
+		h.blobs[plumbing.NewHash(oldRev)] = &blobReplacement{}
+		return nil
+	}
+	fd, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer fd.Close() // nolint
+	si, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+	oid, fragments, err := h.HashTo(ctx, fd, si.Size())
+	if err != nil {
+		return fmt.Errorf("write '%s': %w", file, err)
+	}
+	h.blobs[plumbing.NewHash(oldRev)] = &blobReplacement{hash: oid, size: si.Size(), fragments: fragments}
+	return nil
+}
+
+func (h *historyRewriter) rewriteTrees() bool {
+	return h.matcher != nil || h.opts.StripBlobsBigger > 0 || len(h.blobs) != 0
+}
+
+// rewriteEntry returns the rewritten entry of a file, nil when the file is removed.
+func (h *historyRewriter) rewriteEntry(e *object.TreeEntry) *object.TreeEntry {
+	if h.opts.StripBlobsBigger > 0 && e.Size > h.opts.StripBlobsBigger {
+		return nil
+	}
+	b, ok := h.blobs[e.Hash]
+	if !ok {
+		return e
+	}
+	if b.hash.IsZero() {
+		return nil
+	}
+	mode := e.Mode &^ filemode.Fragments
+	if b.fragments {
+		mode |= filemode.Fragments
+	}
+	return &object.TreeEntry{Name: e.Name, Size: b.size, Mode: mode, Hash: b.hash}
+}
+
+// rewriteTree returns the rewritten tree of the dir, zero when the tree becomes empty. Paths are matched from the
+// root, so the trees are memoized by dir and hash.
+func (h *historyRewriter) rewriteTree(ctx context.Context, dir string, oid plumbing.Hash) (plumbing.Hash, error) {
+	key := dir + "\x00" + oid.String()
+	if newRev, ok := h.trees[key]; ok {
+		return newRev, nil
+	}
+	t, err := h.odb.Tree(ctx, oid)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	var changed bool
+	entries := make([]*object.TreeEntry, 0, len(t.Entries))
+	for _, e := range t.Entries {
+		name := path.Join(dir, e.Name)
+		if h.matcher != nil && h.matcher.Match(name) {
+			changed = true
+			continue
+		}
+		switch {
+		case e.Mode == filemode.Dir:
+			sub, err := h.rewriteTree(ctx, name, e.Hash)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			if sub != e.Hash {
+				changed = true
+				if sub.IsZero() {
+					continue
+				}
+				e = &object.TreeEntry{Name: e.Name, Mode: filemode.Dir, Hash: sub}
+			}
+		case e.Mode == filemode.Submodule:
+		default:
+			ne := h.rewriteEntry(e)
+			if ne != e {
+				changed = true
+				if ne == nil {
+					continue
+				}
+				e = ne
+			}
+		}
+		entries = append(entries, e)
+	}
+	newRev := oid
+	switch {
+	case !changed:
+	case len(entries) == 0:
+		newRev = plumbing.ZeroHash
+	default:
+		sort.Sort(object.SubtreeOrder(entries))
+		if newRev, err = h.odb.WriteEncoded(&object.Tree{Entries: entries}); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+	h.trees[key] = newRev
+	return newRev, nil
+}
+
+func (h *historyRewriter) rewriteMessage(message string) string {
+	for _, m := range h.messages {
+		message = m.replace(message)
+	}
+	return message
+}
+
+// commitTree returns the root tree of the commit, the empty tree for a zero commit.
+func (h *historyRewriter) commitTree(ctx context.Context, oid plumbing.Hash) (plumbing.Hash, error) {
+	if oid.IsZero() {
+		return plumbing.EmptyTree, nil
+	}
+	cc, err := h.odb.Commit(ctx, oid)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return cc.Tree, nil
+}
+
+// pruneEmpty reports whether the rewritten commit became empty: it has at most one parent left and the same tree as
+// the parent. Commits which were empty before the rewrite are kept.
+func (h *historyRewriter) pruneEmpty(ctx context.Context, cc *object.Commit, tree plumbing.Hash, parents []plumbing.Hash) (bool, error) {
+	if !h.opts.PruneEmpty || len(parents) > 1 {
+		return false, nil
+	}
+	var parent plumbing.Hash
+	if len(parents) != 0 {
+		parent = parents[0]
+	}
+	parentTree, err := h.commitTree(ctx, parent)
+	if err != nil {
+		return false, err
+	}
+	if tree != parentTree {
+		return false, nil
+	}
+	if len(cc.Parents) > 1 {
+		// a merge whose parents were merged into one
+		return true, nil
+	}
+	var oldParent plumbing.Hash
+	if len(cc.Parents) != 0 {
+		oldParent = cc.Parents[0]
+	}
+	oldParentTree, err := h.commitTree(ctx, oldParent)
+	if err != nil {
+		return false, err
+	}
+	return cc.Tree != oldParentTree, nil
+}
+
+func (h *historyRewriter) rewriteCommit(ctx context.Context, cc *object.Commit) (plumbing.Hash, error) {
+	parents := make([]plumbing.Hash, 0, len(cc.Parents))
+	for _, p := range cc.Parents {
+		np := h.commits[p]
+		if !np.IsZero() && !slices.Contains(parents, np) {
+			parents = append(parents, np)
+		}
+	}
+	tree := cc.Tree
+	if h.rewriteTrees() {
+		var err error
+		if tree, err = h.rewriteTree(ctx, "", cc.Tree); err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("rewrite tree of commit %s: %w", cc.Hash, err)
+		}
+		if tree.IsZero() {
+			tree = plumbing.EmptyTree
+		}
+	}
+	prune, err := h.pruneEmpty(ctx, cc, tree, parents)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if prune {
+		h.pruned++
+		if len(parents) == 0 {
+			return plumbing.ZeroHash, nil
+		}
+		return parents[0], nil
+	}
+	author := mailmapSignature(h.mailmap, cc.Author)
+	committer := mailmapSignature(h.mailmap, cc.Committer)
+	message := h.rewriteMessage(cc.Message)
+	if tree == cc.Tree && slices.Equal(parents, cc.Parents) && message == cc.Message &&
+		author.Name == cc.Author.Name && author.Email == cc.Author.Email &&
+		committer.Name == cc.Committer.Name && committer.Email == cc.Committer.Email {
+		return cc.Hash, nil
+	}
+	nc := &object.Commit{
+		Author:    author,
+		Committer: committer,
+		Parents:   parents,
+		Tree:      tree,
+		Message:   message,
+	}
+	for _, e := range cc.ExtraHeaders {
+		// signatures no longer match the rewritten commit
+		if e.K != "gpgsig" && e.K != "gpgsig-sha256" {
+			nc.ExtraHeaders = append(nc.ExtraHeaders, e)
+		}
+	}
+	newRev, err := h.odb.WriteEncoded(nc)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	h.changed++
+	return newRev, nil
+}
+
+// rewriteCommits rewrites the commits reachable from oid, parents before children.
+func (h *historyRewriter) rewriteCommits(ctx context.Context, oid plumbing.Hash) error {
+	stack := []plumbing.Hash{oid}
+	for len(stack) != 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		current := stack[len(stack)-1]
+		if _, ok := h.commits[current]; ok {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		cc, err := h.odb.Commit(ctx, current)
+		if plumbing.IsNoSuchObject(err) {
+			return fmt.Errorf("commit %s is missing, the history is incomplete, run 'zeta fetch --unshallow' first", current)
+		}
+		if err != nil {
+			return err
+		}
+		var pending bool
+		for _, p := range cc.Parents {
+			if _, ok := h.commits[p]; !ok {
+				stack = append(stack, p)
+				pending = true
+			}
+		}
+		if pending {
+			continue
+		}
+		stack = stack[:len(stack)-1]
+		newRev, err := h.rewriteCommit(ctx, cc)
+		if err != nil {
+			return err
+		}
+		h.commits[current] = newRev
+		h.order = append(h.order, current)
+	}
+	return nil
+}
+
+// rewriteTag rewrites an annotated tag of a commit, the signature of the tag is dropped when the tag changes.
+func (h *historyRewriter) rewriteTag(ctx context.Context, tag *object.Tag) (plumbing.Hash, error) {
+	if err := h.rewriteCommits(ctx, tag.Object); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	target := h.commits[tag.Object]
+	if target.IsZero() {
+		return plumbing.ZeroHash, nil
+	}
+	message, _ := tag.Extract()
+	newMessage := h.rewriteMessage(message)
+	tagger := mailmapSignature(h.mailmap, tag.Tagger)
+	if target == tag.Object && newMessage == message && tagger.Name == tag.Tagger.Name && tagger.Email == tag.Tagger.Email {
+		return tag.Hash, nil
+	}
+	return h.odb.WriteEncoded(&object.Tag{
+		Object:     target,
+		ObjectType: object.CommitObject,
+		Name:       tag.Name,
+		Tagger:     tagger,
+		Content:    newMessage,
+	})
+}
+
+// rewriteReference returns the new target of the reference, zero when nothing of the history is left.
+func (h *historyRewriter) rewriteReference(ctx context.Context, ref *plumbing.Reference) (plumbing.Hash, error) {
+	o, err := h.odb.Object(ctx, ref.Hash())
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	switch v := o.(type) {
+	case *object.Commit:
+		if err := h.rewriteCommits(ctx, v.Hash); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return h.commits[v.Hash], nil
+	case *object.Tag:
+		if v.ObjectType != object.CommitObject {
+			warn("rewrite-history: skip tag '%s' which points to a non-commit object", ref.Name().TagName())
+			return ref.Hash(), nil
+		}
+		return h.rewriteTag(ctx, v)
+	}
+	warn("rewrite-history: skip '%s' which points to a non-commit object", ref.Name())
+	return ref.Hash(), nil
+}
+
+// rewrittenReference: a reference moved by the rewrite.
+type rewrittenReference struct {
+	name   plumbing.ReferenceName
+	oldRev plumbing.Hash
+	newRev plumbing.Hash
+}
+
+// writeMaps writes the commit map and the reference map under .zeta/rewrite-history. A pruned commit is mapped to
+// the rewritten commit which takes its place, the zero hash when nothing of its history is left.
+func (h *historyRewriter) writeMaps(refs []*rewrittenReference) (string, error) {
+	dir := filepath.Join(h.zetaDir, rewriteHistoryDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("old new\n")
+	for _, oid := range h.order {
+		fmt.Fprintf(&b, "%s %s\n", oid, h.commits[oid])
+	}
+	if err := os.WriteFile(filepath.Join(dir, "commit-map"), []byte(b.String()), 0644); err != nil {
+		return "", err
+	}
+	b.Reset()
+	b.WriteString("old new ref\n")
+	for _, ref := range refs {
+		fmt.Fprintf(&b, "%s %s %s\n", ref.oldRev, ref.newRev, ref.name)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ref-map"), []byte(b.String()), 0644); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func (w *Worktree) rewriteHistory(ctx context.Context, opts *RewriteHistoryOptions) ([]*rewrittenReference, error) {
+	unlock, err := w.lock("rewrite-history")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if shallow, err := w.odb.DeepenFrom(); err == nil && !shallow.IsZero() {
+		die_error("rewrite-history requires the complete history, run 'zeta fetch --unshallow' first")
+		return nil, ErrAborting
+	}
+	current, _, err := w.current()
+	if err != nil {
+		die_error("resolve HEAD: %v", err)
+		return nil, err
+	}
+	if !opts.DryRun && current.IsBranch() {
+		s, err := w.Status(ctx, false)
+		if err != nil {
+			die_error("status: %v", err)
+			return nil, err
+		}
+		for _, fs := range s {
+			if fs.Worktree != Untracked && (fs.Worktree != Unmodified || fs.Staging != Unmodified) {
+				die_error("your local changes would be overwritten by rewrite-history, commit or stash them first")
+				return nil, ErrAborting
+			}
+		}
+	}
+	h, err := w.newHistoryRewriter(ctx, opts)
+	if err != nil {
+		die_error("rewrite-history: %v", err)
+		return nil, err
+	}
+	rdb, err := w.References()
+	if err != nil {
+		die_error("rewrite-history: %v", err)
+		return nil, err
+	}
+	var refs []*rewrittenReference
+	for _, ref := range rdb.References() {
+		if name := ref.Name(); !name.IsBranch() && !name.IsTag() {
+			continue
+		}
+		newRev, err := h.rewriteReference(ctx, ref)
+		if err != nil {
+			die_error("rewrite-history: rewrite '%s': %v", ref.Name(), err)
+			return nil, err
+		}
+		if newRev.IsZero() {
+			warn("rewrite-history: nothing of '%s' is left, the reference is not changed", ref.Name())
+			continue
+		}
+		if newRev != ref.Hash() {
+			refs = append(refs, &rewrittenReference{name: ref.Name(), oldRev: ref.Hash(), newRev: newRev})
+		}
+	}
+	dir, err := h.writeMaps(refs)
+	if err != nil {
+		die_error("rewrite-history: write maps: %v", err)
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, W("Rewrote %d of %d commits, pruned %d empty commits, %d references changed\n"), h.changed, len(h.order), h.pruned, len(refs))
+	fmt.Fprintf(os.Stderr, W("The commit map and the reference map are written to '%s'\n"), dir)
+	if opts.DryRun {
+		for _, ref := range refs {
+			fmt.Fprintf(os.Stderr, "%s: %s -> %s\n", ref.name, shortHash(ref.oldRev), shortHash(ref.newRev))
+		}
+		return nil, nil
+	}
+	cc := &CommitOptions{}
+	if err := cc.loadConfigAuthorAndCommitter(w.Repository); err != nil {
+		die_error("%v", err)
+		return nil, err
+	}
+	for _, ref := range refs {
+		if err := w.DoUpdate(ctx, ref.name, ref.oldRev, ref.newRev, &cc.Committer, "rewrite-history"); err != nil {
+			die_error("update %s: %v", ref.name, err)
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "%s: %s -> %s\n", ref.name, shortHash(ref.oldRev), shortHash(ref.newRev))
+		if ref.name == current {
+			if err := w.ResetSparsely(ctx, &ResetOptions{Commit: ref.newRev, Mode: HardReset, Quiet: true}, nil); err != nil {
+				die_error("checkout %s: %v", shortHash(ref.newRev), err)
+				return nil, err
+			}
+		}
+	}
+	return refs, nil
+}
+
+// RewriteHistory rewrites the branches and tags like git filter-repo: removes paths and large files, replaces blobs,
+// rewrites messages and identities. The old objects stay in the local repository and on the remote, with opts.Push
+// the rewritten references are force pushed.
+func (w *Worktree) RewriteHistory(ctx context.Context, opts *RewriteHistoryOptions) error {
+	refs, err := w.rewriteHistory(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if !opts.Push {
+		return nil
+	}
+	var failed bool
+	for _, ref := range refs {
+		// the messages of the old history may not follow the commit message policies
+		if err := w.Push(ctx, &PushOptions{Refspec: string(ref.name), Force: true, NoVerify: true}); err != nil {
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("failed to push some rewritten references")
+	}
+	return nil
+}
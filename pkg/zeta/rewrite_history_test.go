@@ -0,0 +1,74 @@
+package zeta
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMessageReplacements(t *testing.T) {
+	replacements, err := parseMessageReplacements(strings.NewReader("# secrets\n\npassword\nliteral:foo==>bar\nregex:id-[0-9]+==>id-N\n"))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	message := "fix password, foo and id-42\n"
+	for _, m := range replacements {
+		message = m.replace(message)
+	}
+	if want := "fix ***REMOVED***, bar and id-N\n"; message != want {
+		t.Fatalf("got %q, want %q", message, want)
+	}
+	for _, s := range []string{"regex:([==>x", "==>x"} {
+		if _, err := parseMessageReplacements(strings.NewReader(s)); err == nil {
+			t.Errorf("%q should be rejected", s)
+		}
+	}
+}
+
+func TestRewriteHistory(t *testing.T) {
+	ctx := t.Context()
+	r, f := newTestRepository(t, "rewrite")
+	first := f.commit("init", map[string]string{"README": "readme\n", "secrets/key": "token\n"})
+	second := f.commit("rotate key", map[string]string{"secrets/key": "token2\n"}, first)
+	third := f.commit("update readme", map[string]string{"README": "readme v2\n"}, second)
+
+	h, err := r.newHistoryRewriter(ctx, &RewriteHistoryOptions{RemovePaths: []string{"secrets"}, PruneEmpty: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.rewriteCommits(ctx, third); err != nil {
+		t.Fatalf("rewrite error: %v", err)
+	}
+	if h.changed != 2 || h.pruned != 1 || len(h.order) != 3 {
+		t.Fatalf("unexpected rewrite: changed %d, pruned %d, commits %d", h.changed, h.pruned, len(h.order))
+	}
+	// the commit which only changed the removed paths is replaced by its parent
+	if h.commits[second] != h.commits[first] {
+		t.Fatalf("commit %s should be pruned", second)
+	}
+	newThird, err := r.odb.Commit(ctx, h.commits[third])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newThird.Parents) != 1 || newThird.Parents[0] != h.commits[first] {
+		t.Fatalf("unexpected parents %v", newThird.Parents)
+	}
+	root, err := r.odb.Tree(ctx, newThird.Tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Entries) != 1 || root.Entries[0].Name != "README" {
+		t.Fatalf("unexpected tree: %v", root.Entries)
+	}
+
+	// nothing to change keeps the commits
+	h, err = r.newHistoryRewriter(ctx, &RewriteHistoryOptions{RemovePaths: []string{"vendor"}, PruneEmpty: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.rewriteCommits(ctx, third); err != nil {
+		t.Fatalf("rewrite error: %v", err)
+	}
+	if h.changed != 0 || h.commits[third] != third {
+		t.Fatalf("history should not change: %s -> %s", third, h.commits[third])
+	}
+}
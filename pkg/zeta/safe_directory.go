@@ -0,0 +1,101 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+)
+
+var (
+	ErrDubiousOwnership = errors.New("detected dubious ownership in repository")
+)
+
+// safeDirectoryMatch: directories are matched in order, an empty value clears the directories before it.
+func safeDirectoryMatch(directories []string, dir string) bool {
+	dir = filepath.Clean(dir)
+	var allowed bool
+	for _, s := range directories {
+		switch {
+		case len(s) == 0:
+			allowed = false
+		case s == "*":
+			allowed = true
+		case strings.HasSuffix(s, "/*"):
+			prefix := filepath.Clean(strengthen.ExpandPath(strings.TrimSuffix(s, "/*")))
+			if strings.HasPrefix(dir, prefix+string(os.PathSeparator)) || dir == prefix {
+				allowed = true
+			}
+		case filepath.Clean(strengthen.ExpandPath(s)) == dir:
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// safeDirectory reports whether a repository owned by another user may be used, its config and hooks could run
+// commands as the current user. safe.directory is read from the system and global config and the command line, never
+// from the repository config.
+func safeDirectory(worktree, zetaDir string, values map[string]StringArray) (bool, error) {
+	owned := true
+	for _, p := range []string{worktree, zetaDir} {
+		if !isOwnedByCurrentUser(p) {
+			owned = false
+			break
+		}
+	}
+	if owned {
+		return true, nil
+	}
+	cfg, err := config.LoadBaseline()
+	if err != nil {
+		return false, err
+	}
+	directories := cfg.Safe.Directories
+	if sa, ok := getStringsFromValues("safe.directory", values); ok {
+		directories = append(directories, sa...)
+	}
+	return safeDirectoryMatch(directories, worktree), nil
+}
+
+// loadRepositoryConfig loads the config of the repository at zetaDir, every reader of the repository config goes
+// through it so that the config of a repository with dubious ownership is never used.
+func loadRepositoryConfig(worktree, zetaDir string, values map[string]StringArray) (*config.Config, error) {
+	if len(zetaDir) == 0 {
+		return config.LoadBaseline()
+	}
+	safe, err := safeDirectory(worktree, zetaDir, values)
+	if err != nil {
+		return nil, err
+	}
+	if !safe {
+		return nil, ErrDubiousOwnership
+	}
+	return config.Load(zetaDir)
+}
+
+// LoadConfig loads the config for commands that also run outside a repository: the config of the repository at cwd
+// is used only if it passes the safe.directory check, otherwise only the system and global config are loaded.
+func LoadConfig(cwd string, values []string) (*config.Config, error) {
+	worktree, zetaDir, err := FindZetaDir(cwd)
+	if err != nil {
+		return config.LoadBaseline()
+	}
+	cfg, err := loadRepositoryConfig(worktree, zetaDir, valuesMapArray(values))
+	if errors.Is(err, ErrDubiousOwnership) {
+		return config.LoadBaseline()
+	}
+	return cfg, err
+}
+
+func dubiousOwnership(worktree string) {
+	die("%s '%s'", W("detected dubious ownership in repository at"), worktree)
+	fmt.Fprintf(os.Stderr, "%s\n\n\tzeta config --global --add safe.directory '%s'\n\n", W("To add an exception for this directory, call:"), worktree)
+}
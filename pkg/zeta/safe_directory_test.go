@@ -0,0 +1,87 @@
+package zeta
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/zeta/config"
+)
+
+func TestSafeDirectoryMatch(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "shared", "repo")
+	tests := []struct {
+		directories []string
+		want        bool
+	}{
+		{nil, false},
+		{[]string{"*"}, true},
+		{[]string{repo}, true},
+		{[]string{repo + "/"}, true},
+		{[]string{filepath.Join(root, "shared")}, false},
+		{[]string{filepath.Join(root, "shared") + "/*"}, true},
+		{[]string{filepath.Join(root, "share") + "/*"}, false},
+		{[]string{"*", ""}, false},
+		{[]string{"", repo}, true},
+	}
+	for _, tt := range tests {
+		if got := safeDirectoryMatch(tt.directories, repo); got != tt.want {
+			t.Errorf("safeDirectoryMatch(%q) = %v, want %v", tt.directories, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigDubiousOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of the repository requires root")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(config.ENV_ZETA_CONFIG_SYSTEM, filepath.Join(home, "system.toml"))
+	t.Setenv("SUDO_UID", "")
+	worktree := filepath.Join(t.TempDir(), "repo")
+	r, err := Init(t.Context(), &InitOptions{Worktree: worktree, Branch: "mainline", Quiet: true})
+	if err != nil {
+		t.Fatalf("init repo error: %v", err)
+	}
+	_ = r.Close()
+	zetaDir := filepath.Join(worktree, ".zeta")
+	fd, err := os.OpenFile(filepath.Join(zetaDir, "zeta.toml"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fd.WriteString("\n[telemetry]\nendpoint = \"https://repo.example.io\"\n")
+	_ = fd.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := func(values []string) string {
+		cfg, err := LoadConfig(worktree, values)
+		if err != nil {
+			t.Fatalf("load config error: %v", err)
+		}
+		return cfg.Telemetry.Endpoint
+	}
+	if got := endpoint(nil); got != "https://repo.example.io" {
+		t.Fatalf("repository config not loaded, endpoint %q", got)
+	}
+	for _, p := range []string{worktree, zetaDir} {
+		if err := os.Chown(p, 65534, 65534); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := loadRepositoryConfig(worktree, zetaDir, nil); !errors.Is(err, ErrDubiousOwnership) {
+		t.Fatalf("dubious repository config should be refused, got %v", err)
+	}
+	if got := endpoint(nil); got != "" {
+		t.Fatalf("dubious repository config should be ignored, endpoint %q", got)
+	}
+	if got := endpoint([]string{"safe.directory=" + worktree}); got != "https://repo.example.io" {
+		t.Fatalf("repository listed in safe.directory not loaded, endpoint %q", got)
+	}
+	if _, err := Open(t.Context(), &OpenOptions{Worktree: worktree, Quiet: true}); !errors.Is(err, ErrDubiousOwnership) {
+		t.Fatalf("open dubious repository should fail, got %v", err)
+	}
+}
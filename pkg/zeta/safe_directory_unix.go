@@ -0,0 +1,35 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package zeta
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+func isOwnedByCurrentUser(p string) bool {
+	si, err := os.Stat(p)
+	if err != nil {
+		// missing paths are reported when they are opened
+		return true
+	}
+	st, ok := si.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+	euid := os.Geteuid()
+	if int(st.Uid) == euid {
+		return true
+	}
+	// sudo zeta ...: the repository of the user running sudo
+	if euid == 0 {
+		if sudoUID, err := strconv.Atoi(os.Getenv("SUDO_UID")); err == nil && sudoUID == int(st.Uid) {
+			return true
+		}
+	}
+	return false
+}
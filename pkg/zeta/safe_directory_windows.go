@@ -0,0 +1,37 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows
+
+package zeta
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+func isOwnedByCurrentUser(p string) bool {
+	sd, err := windows.GetNamedSecurityInfo(p, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		// missing paths are reported when they are opened
+		return true
+	}
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return true
+	}
+	token := windows.GetCurrentProcessToken()
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return true
+	}
+	if owner.Equals(user.User.Sid) {
+		return true
+	}
+	// repositories created by elevated processes are owned by the administrators group
+	if owner.IsWellKnown(windows.WinBuiltinAdministratorsSid) {
+		if member, err := token.IsMember(owner); err == nil && member {
+			return true
+		}
+	}
+	return false
+}
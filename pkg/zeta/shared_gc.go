@@ -0,0 +1,372 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/modules/zeta/reflog"
+	"github.com/antgroup/hugescm/modules/zeta/refs"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+const (
+	sharedRegistryDir = "registry"
+)
+
+// SharedClone: a repository borrowing objects from the sharing root. Each clone registers itself in
+// <sharingRoot>/registry when it is created or opened, the file name is the hash of its zeta dir.
+type SharedClone struct {
+	ID      string
+	ZetaDir string
+	Stale   string // reason of a stale registration, empty when the clone still uses the sharing root
+}
+
+func sharedRegistrationID(zetaDir string) string {
+	h := plumbing.NewHasher()
+	_, _ = h.Write([]byte(zetaDir))
+	return h.Sum().String()
+}
+
+// registerSharedClone records zetaDir as a borrower of the sharing root.
+func registerSharedClone(sharingRoot, zetaDir string) {
+	zetaDir, err := filepath.Abs(zetaDir)
+	if err != nil {
+		return
+	}
+	p := filepath.Join(sharingRoot, sharedRegistryDir, sharedRegistrationID(zetaDir))
+	if _, err := os.Stat(p); err == nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		warn("register to sharing root: %v", err)
+		return
+	}
+	if err := os.WriteFile(p, []byte(zetaDir+"\n"), 0644); err != nil {
+		warn("register to sharing root: %v", err)
+	}
+}
+
+// staleReason reports why the registered clone no longer borrows from the sharing root.
+func staleReason(sharingRoot, zetaDir string) string {
+	if !odb.IsZetaDir(zetaDir) {
+		return "repository not found"
+	}
+	cfg, err := loadRepositoryConfig(filepath.Dir(zetaDir), zetaDir, nil)
+	if err != nil {
+		// unreadable config or dubious ownership, keep the registration to stay on the safe side
+		return ""
+	}
+	// the sharing root may also come from the environment, only a different root makes the clone stale
+	if len(cfg.Core.SharingRoot) != 0 && filepath.Clean(cfg.Core.SharingRoot) != filepath.Clean(sharingRoot) {
+		return "uses sharing root " + cfg.Core.SharingRoot
+	}
+	return ""
+}
+
+// SharedClones returns the clones registered to the sharing root.
+func SharedClones(sharingRoot string) ([]*SharedClone, error) {
+	dirs, err := os.ReadDir(filepath.Join(sharingRoot, sharedRegistryDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	clones := make([]*SharedClone, 0, len(dirs))
+	for _, d := range dirs {
+		if d.IsDir() || !plumbing.ValidateHashHex(d.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(sharingRoot, sharedRegistryDir, d.Name()))
+		if err != nil {
+			return nil, err
+		}
+		c := &SharedClone{ID: d.Name(), ZetaDir: strings.TrimSpace(string(data))}
+		if c.Stale = staleReason(sharingRoot, c.ZetaDir); len(c.ZetaDir) == 0 {
+			c.Stale = "bad registration"
+		}
+		clones = append(clones, c)
+	}
+	return clones, nil
+}
+
+type sharedObjects struct {
+	o       *odb.ODB
+	commits map[plumbing.Hash]bool
+	trees   map[plumbing.Hash]bool
+	blobs   map[plumbing.Hash]bool
+}
+
+func (s *sharedObjects) tree(ctx context.Context, oid plumbing.Hash) error {
+	if s.trees[oid] {
+		return nil
+	}
+	s.trees[oid] = true
+	t, err := s.o.Tree(ctx, oid)
+	if plumbing.IsNoSuchObject(err) {
+		// sparse or shallow, objects of the missing tree cannot be checked out by this clone
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range t.Entries {
+		switch e.Type() {
+		case object.TreeObject:
+			if err := s.tree(ctx, e.Hash); err != nil {
+				return err
+			}
+		case object.FragmentsObject:
+			if err := s.fragments(ctx, e.Hash); err != nil {
+				return err
+			}
+		default:
+			s.blobs[e.Hash] = true
+		}
+	}
+	return nil
+}
+
+func (s *sharedObjects) fragments(ctx context.Context, oid plumbing.Hash) error {
+	ff, err := s.o.Fragments(ctx, oid)
+	if plumbing.IsNoSuchObject(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range ff.Entries {
+		s.blobs[e.Hash] = true
+	}
+	return nil
+}
+
+func (s *sharedObjects) commit(ctx context.Context, oid plumbing.Hash) error {
+	stack := []plumbing.Hash{oid}
+	for len(stack) != 0 {
+		oid = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if s.commits[oid] {
+			continue
+		}
+		s.commits[oid] = true
+		cc, err := s.o.Commit(ctx, oid)
+		if plumbing.IsNoSuchObject(err) {
+			// shallow boundary
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.tree(ctx, cc.Tree); err != nil {
+			return err
+		}
+		stack = append(stack, cc.Parents...)
+	}
+	return nil
+}
+
+// object marks the objects reachable from oid, oid may be a commit, a tag, a tree, fragments or a blob.
+func (s *sharedObjects) object(ctx context.Context, oid plumbing.Hash) error {
+	if oid.IsZero() {
+		return nil
+	}
+	for range 10 {
+		a, err := s.o.Object(ctx, oid)
+		if plumbing.IsNoSuchObject(err) {
+			// not a metadata object
+			s.blobs[oid] = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch v := a.(type) {
+		case *object.Commit:
+			return s.commit(ctx, oid)
+		case *object.Tree:
+			return s.tree(ctx, oid)
+		case *object.Fragments:
+			return s.fragments(ctx, oid)
+		case *object.Tag:
+			oid = v.Object
+			continue
+		}
+		return nil
+	}
+	return nil
+}
+
+// walk marks the objects used by the clone: references and their reflogs (including stashes) and the index.
+func (s *sharedObjects) walk(ctx context.Context, zetaDir string) error {
+	db, err := refs.ReferencesDB(zetaDir)
+	if err != nil {
+		return err
+	}
+	rdb := reflog.NewDB(zetaDir)
+	names := []plumbing.ReferenceName{plumbing.HEAD}
+	for _, ref := range db.References() {
+		if ref.Type() == plumbing.HashReference {
+			if err := s.object(ctx, ref.Hash()); err != nil {
+				return err
+			}
+		}
+		names = append(names, ref.Name())
+	}
+	if head := db.HEAD(); head != nil && head.Type() == plumbing.HashReference {
+		if err := s.object(ctx, head.Hash()); err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		if !rdb.Exists(name) {
+			continue
+		}
+		o, err := rdb.Read(name)
+		if err != nil {
+			return err
+		}
+		for _, e := range o.Entries {
+			if err := s.object(ctx, e.O); err != nil {
+				return err
+			}
+			if err := s.object(ctx, e.N); err != nil {
+				return err
+			}
+		}
+	}
+	idx, err := s.o.Index()
+	if err != nil {
+		return err
+	}
+	for _, e := range idx.Entries {
+		if err := s.object(ctx, e.Hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sharedBlobs returns the blobs used by the clone.
+func sharedBlobs(ctx context.Context, sharingRoot, zetaDir string, blobs map[plumbing.Hash]bool) error {
+	cfg, err := loadRepositoryConfig(filepath.Dir(zetaDir), zetaDir, nil)
+	if err != nil {
+		return err
+	}
+	odbOpts := []backend.Option{backend.WithCompressionALGO(cfg.Core.CompressionALGO), backend.WithSharingRoot(sharingRoot)}
+	// metadata of clones with object encryption enabled is sealed
+	sealer, err := parseObjectSealer(ctx, cfg, nil)
+	if err != nil {
+		return err
+	}
+	if sealer != nil {
+		odbOpts = append(odbOpts, backend.WithSealer(sealer))
+	}
+	o, err := odb.NewODB(zetaDir, odbOpts...)
+	if err != nil {
+		return err
+	}
+	defer o.Close() // nolint
+	s := &sharedObjects{
+		o:       o,
+		commits: make(map[plumbing.Hash]bool),
+		trees:   make(map[plumbing.Hash]bool),
+		blobs:   blobs,
+	}
+	return s.walk(ctx, zetaDir)
+}
+
+// ResolveSharingRoot returns the sharing root of the repository at cwd, or of the global config when cwd is not
+// in a repository.
+func ResolveSharingRoot(cwd string, values []string) string {
+	cfg, err := LoadConfig(cwd, values)
+	if err != nil {
+		return ""
+	}
+	sharingRoot, _ := parseSharingRoot(cfg, valuesMapArray(values))
+	return sharingRoot
+}
+
+type SharedGCOptions struct {
+	SharingRoot string
+	Prune       time.Duration // objects modified within the duration are retained
+	DryRun      bool
+	Force       bool // remove unreferenced objects even if no clone is registered
+	Quiet       bool
+}
+
+var (
+	ErrNoSharingRoot  = errors.New("sharing root not set")
+	ErrNoSharedClones = errors.New("no clone registered to the sharing root")
+)
+
+// SharedGC removes loose objects of the sharing root that are no longer used by any registered clone. Stale
+// registrations (the clone was removed or uses another sharing root) are dropped. Any clone that cannot be read
+// aborts the collection, so that objects of a clone are never removed by mistake.
+func SharedGC(ctx context.Context, opts *SharedGCOptions) error {
+	if len(opts.SharingRoot) == 0 || !filepath.IsAbs(opts.SharingRoot) {
+		die_error("sharing root not set, use --root or core.sharingRoot")
+		return ErrNoSharingRoot
+	}
+	clones, err := SharedClones(opts.SharingRoot)
+	if err != nil {
+		die_error("read registry: %v", err)
+		return err
+	}
+	blobs := make(map[plumbing.Hash]bool)
+	var live int
+	for _, c := range clones {
+		if len(c.Stale) != 0 {
+			if !opts.Quiet {
+				fmt.Fprintf(os.Stderr, W("stale registration %s: %s\n"), c.ZetaDir, c.Stale)
+			}
+			if !opts.DryRun {
+				if err := os.Remove(filepath.Join(opts.SharingRoot, sharedRegistryDir, c.ID)); err != nil && !os.IsNotExist(err) {
+					die_error("remove registration: %v", err)
+					return err
+				}
+			}
+			continue
+		}
+		if err := sharedBlobs(ctx, opts.SharingRoot, c.ZetaDir, blobs); err != nil {
+			die_error("read clone '%s': %v", c.ZetaDir, err)
+			return err
+		}
+		live++
+	}
+	if live == 0 && !opts.Force {
+		die_error("no clone registered to '%s', use --force to remove all unused objects", opts.SharingRoot)
+		return ErrNoSharedClones
+	}
+	oids, size, err := backend.PruneSharedObjects(ctx, opts.SharingRoot, time.Now().Add(-opts.Prune), opts.DryRun, func(oid plumbing.Hash) bool {
+		return blobs[oid]
+	})
+	if err != nil {
+		die_error("prune shared objects: %v", err)
+		return err
+	}
+	if opts.Quiet {
+		return nil
+	}
+	if opts.DryRun {
+		for _, oid := range oids {
+			fmt.Fprintf(os.Stdout, "%s\n", oid)
+		}
+		fmt.Fprintf(os.Stderr, W("Would remove %d objects (%s), %d clones registered\n"), len(oids), strengthen.FormatSize(size), live)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, W("Removed %d objects (%s), %d clones registered\n"), len(oids), strengthen.FormatSize(size), live)
+	return nil
+}
@@ -0,0 +1,101 @@
+package zeta
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/format/index"
+)
+
+func writeSharedBlob(t *testing.T, sharingRoot, content string, modTime time.Time) plumbing.Hash {
+	h := plumbing.NewHasher()
+	_, _ = h.Write([]byte(content))
+	oid := h.Sum()
+	s := oid.String()
+	p := filepath.Join(sharingRoot, "blob", s[:2], s[2:4], s)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(p, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return oid
+}
+
+func sharedBlobExists(sharingRoot string, oid plumbing.Hash) bool {
+	s := oid.String()
+	_, err := os.Stat(filepath.Join(sharingRoot, "blob", s[:2], s[2:4], s))
+	return err == nil
+}
+
+func TestSharedGC(t *testing.T) {
+	ctx := t.Context()
+	sharingRoot := t.TempDir()
+	values := []string{"core.sharingRoot=" + sharingRoot}
+	live, err := Init(ctx, &InitOptions{Worktree: filepath.Join(t.TempDir(), "live"), Values: values, Quiet: true})
+	if err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	defer live.Close() // nolint
+	removed, err := Init(ctx, &InitOptions{Worktree: filepath.Join(t.TempDir(), "removed"), Values: values, Quiet: true})
+	if err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	_ = removed.Close()
+	if err := os.RemoveAll(removed.BaseDir()); err != nil {
+		t.Fatal(err)
+	}
+	clones, err := SharedClones(sharingRoot)
+	if err != nil || len(clones) != 2 {
+		t.Fatalf("shared clones: %v %v", clones, err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	staged := writeSharedBlob(t, sharingRoot, "staged", old)
+	unused := writeSharedBlob(t, sharingRoot, "unused", old)
+	recent := writeSharedBlob(t, sharingRoot, "recent", time.Now())
+	idx := &index.Index{Version: index.EncodeVersionSupported, Entries: []*index.Entry{{Name: "staged.txt", Hash: staged}}}
+	if err := live.ODB().SetIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &SharedGCOptions{SharingRoot: sharingRoot, Prune: time.Minute, DryRun: true, Quiet: true}
+	if err := SharedGC(ctx, opts); err != nil {
+		t.Fatalf("shared gc dry run: %v", err)
+	}
+	if !sharedBlobExists(sharingRoot, unused) {
+		t.Fatalf("dry run removed objects")
+	}
+	if clones, _ = SharedClones(sharingRoot); len(clones) != 2 {
+		t.Fatalf("dry run removed registrations")
+	}
+	opts.DryRun = false
+	if err := SharedGC(ctx, opts); err != nil {
+		t.Fatalf("shared gc: %v", err)
+	}
+	for oid, want := range map[plumbing.Hash]bool{staged: true, unused: false, recent: true} {
+		if got := sharedBlobExists(sharingRoot, oid); got != want {
+			t.Errorf("object %s exists: %v, want %v", oid.String()[:8], got, want)
+		}
+	}
+	if clones, _ = SharedClones(sharingRoot); len(clones) != 1 || len(clones[0].Stale) != 0 {
+		t.Fatalf("stale registration not removed: %v", clones)
+	}
+
+	// a clone moved to another sharing root no longer protects its objects
+	if err := os.WriteFile(filepath.Join(live.ZetaDir(), "zeta.toml"), []byte("[core]\nsharingRoot = \""+t.TempDir()+"\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if clones, _ = SharedClones(sharingRoot); len(clones) != 1 || len(clones[0].Stale) == 0 {
+		t.Fatalf("moved clone should be stale: %v", clones)
+	}
+	if err := SharedGC(ctx, opts); err != ErrNoSharedClones {
+		t.Fatalf("shared gc without clones: %v", err)
+	}
+}
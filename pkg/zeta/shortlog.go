@@ -0,0 +1,151 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+type ShortlogOptions struct {
+	Revision  string
+	Paths     []string
+	Summary   bool // -s: suppress commit description and provide a commit count summary only
+	Numbered  bool // -n: sort output according to the number of commits per author
+	Email     bool // -e: show the email address of each author
+	Committer bool // -c: collect and show committer identities instead of authors
+	NoMailmap bool
+}
+
+type shortlogGroup struct {
+	name     string
+	subjects []string
+}
+
+func (r *Repository) shortlogRange(ctx context.Context, revision string) (plumbing.Hash, []plumbing.Hash, error) {
+	fromRev, toRev, ok := strings.Cut(revision, "..")
+	if !ok {
+		if len(revision) == 0 {
+			revision = "HEAD"
+		}
+		oid, err := r.Revision(ctx, revision)
+		return oid, nil, err
+	}
+	if len(toRev) == 0 {
+		toRev = "HEAD"
+	}
+	from, err := r.Revision(ctx, fromRev)
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+	to, err := r.Revision(ctx, toRev)
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+	oldRev, err := r.odb.ParseRevExhaustive(ctx, from)
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+	newRev, err := r.odb.ParseRevExhaustive(ctx, to)
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+	bases, err := oldRev.MergeBase(ctx, newRev)
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+	ignore := make([]plumbing.Hash, 0, len(bases))
+	for _, b := range bases {
+		ignore = append(ignore, b.Hash)
+	}
+	return newRev.Hash, ignore, nil
+}
+
+// Shortlog summarizes commits grouped by (mailmap canonicalized) author.
+func (r *Repository) Shortlog(ctx context.Context, opts *ShortlogOptions) error {
+	want, ignore, err := r.shortlogRange(ctx, opts.Revision)
+	if err != nil {
+		dieln(err)
+		return err
+	}
+	if slices.Contains(ignore, want) {
+		return nil
+	}
+	commits, err := r.revList(ctx, want, ignore, LogOrderTopo, opts.Paths)
+	if err != nil {
+		die_error("shortlog: %v", err)
+		return err
+	}
+	if !opts.NoMailmap {
+		commits = mailmapCommits(r.LoadMailmap(), commits)
+	}
+	groups := make(map[string]*shortlogGroup)
+	for _, c := range commits {
+		s := c.Author
+		if opts.Committer {
+			s = c.Committer
+		}
+		name := s.Name
+		if opts.Email {
+			name = fmt.Sprintf("%s <%s>", s.Name, s.Email)
+		}
+		g, ok := groups[name]
+		if !ok {
+			g = &shortlogGroup{name: name}
+			groups[name] = g
+		}
+		g.subjects = append(g.subjects, c.Subject())
+	}
+	sorted := make([]*shortlogGroup, 0, len(groups))
+	for _, g := range groups {
+		// oldest first, matching git shortlog
+		slices.Reverse(g.subjects)
+		sorted = append(sorted, g)
+	}
+	slices.SortFunc(sorted, func(a, b *shortlogGroup) int {
+		if opts.Numbered {
+			if n := cmp.Compare(len(b.subjects), len(a.subjects)); n != 0 {
+				return n
+			}
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	p := NewPrinter(ctx)
+	defer p.Close() // nolint
+	if err := writeShortlog(p, sorted, opts.Summary); err != nil && !errors.Is(err, syscall.EPIPE) {
+		return err
+	}
+	return nil
+}
+
+func writeShortlog(w io.Writer, groups []*shortlogGroup, summary bool) error {
+	for _, g := range groups {
+		if summary {
+			if _, err := fmt.Fprintf(w, "%6d\t%s\n", len(g.subjects), g.name); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s (%d):\n", g.name, len(g.subjects)); err != nil {
+			return err
+		}
+		for _, s := range g.subjects {
+			if _, err := fmt.Fprintf(w, "      %s\n", s); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -27,15 +27,18 @@ type ShowOptions struct {
 	Textconv  bool
 	Algorithm diferenco.Algorithm
 	Limit     int64
+	// Save: keep the objects resolved from the remote in the local ODB
+	Save bool
 }
 
 type showObject struct {
-	name string
-	oid  plumbing.Hash
+	name   string
+	oid    plumbing.Hash
+	remote *remoteObjects
 }
 
 func (r *Repository) parseObject(ctx context.Context, name string) (plumbing.Hash, int64, error) {
-	prefix, p, ok := strings.Cut(name, ":")
+	prefix, p, ok := splitRevisionPath(name)
 	oid, err := r.Revision(ctx, prefix)
 	if !ok || err != nil {
 		return oid, 0, err
@@ -59,8 +62,24 @@ func (r *Repository) showFetch(ctx context.Context, o *promiseObject) error {
 
 func (r *Repository) Show(ctx context.Context, opts *ShowOptions) error {
 	objects := make([]*showObject, 0, len(opts.Objects))
+	var rs *remoteObjects
 	for _, o := range opts.Objects {
 		oid, size, err := r.parseObject(ctx, o)
+		if r.remoteFallback(o, err) {
+			if rs == nil {
+				if rs, err = r.newRemoteObjects(ctx, opts.Save); err != nil {
+					die_error("parse object %s error: %v", o, err)
+					return err
+				}
+			}
+			e, err := rs.parseEntry(ctx, o)
+			if err != nil {
+				die_error("parse object %s error: %v", o, err)
+				return err
+			}
+			objects = append(objects, &showObject{name: o, oid: e.Hash, remote: rs})
+			continue
+		}
 		if err != nil {
 			die_error("parse object %s error: %v", o, err)
 			return err
@@ -90,7 +109,7 @@ func (r *Repository) Show(ctx context.Context, opts *ShowOptions) error {
 func (r *Repository) showOne(ctx context.Context, w *printer, opts *ShowOptions, so *showObject) error {
 	var o any
 	var err error
-	if o, err = r.odb.Object(ctx, so.oid); err != nil {
+	if o, err = r.promisedObject(ctx, &promiseObject{oid: so.oid, remote: so.remote}); err != nil {
 		if plumbing.IsNoSuchObject(err) {
 			return r.showBlob(ctx, w, opts, so)
 		}
@@ -110,7 +129,7 @@ func (r *Repository) showOne(ctx context.Context, w *printer, opts *ShowOptions,
 }
 
 func (r *Repository) showBlob(ctx context.Context, w Printer, opts *ShowOptions, so *showObject) error {
-	b, err := r.catMissingObject(ctx, &promiseObject{oid: so.oid})
+	b, err := r.catMissingObject(ctx, &promiseObject{oid: so.oid, remote: so.remote})
 	if err != nil {
 		return err
 	}
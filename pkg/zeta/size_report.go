@@ -0,0 +1,307 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+const (
+	noExtension = "(none)"
+)
+
+type SizeReportOptions struct {
+	Revision string // tree-ish, supports <rev>:<path>
+	Base     string // compare with base tree-ish when not empty
+	Limit    int    // entries shown in each section, <= 0 means unlimited
+	JSON     bool
+}
+
+type SizeStat struct {
+	Count int   `json:"count"`
+	Size  int64 `json:"size"`
+}
+
+type SizeEntry struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Files     int    `json:"files,omitempty"`     // directory only
+	Fragments bool   `json:"fragments,omitempty"` // file only
+}
+
+type ExtensionSize struct {
+	Extension string `json:"extension"`
+	Count     int    `json:"count"`
+	Size      int64  `json:"size"`
+}
+
+type SizeChange struct {
+	Path    string `json:"path"`
+	OldSize int64  `json:"old_size"`
+	NewSize int64  `json:"new_size"`
+	Delta   int64  `json:"delta"`
+}
+
+type SizeGrowth struct {
+	Base     string        `json:"base"`
+	BaseSize int64         `json:"base_size"`
+	Delta    int64         `json:"delta"`
+	Files    []*SizeChange `json:"files"`
+}
+
+type SizeReport struct {
+	Revision   string           `json:"revision"`
+	Total      SizeStat         `json:"total"`
+	Blobs      SizeStat         `json:"blobs"`
+	Fragments  SizeStat         `json:"fragments"`
+	Files      []*SizeEntry     `json:"largest_files"`
+	Dirs       []*SizeEntry     `json:"largest_dirs"`
+	Extensions []*ExtensionSize `json:"extensions"`
+	Growth     *SizeGrowth      `json:"growth,omitempty"`
+}
+
+type sizeFile struct {
+	path      string
+	size      int64
+	fragments bool
+}
+
+// sizeWalk collects the files of the tree, missing subtrees (sparse or shallow) are skipped.
+func (r *Repository) sizeWalk(ctx context.Context, t *object.Tree, parent string, files []*sizeFile) ([]*sizeFile, error) {
+	for _, e := range t.Entries {
+		name := path.Join(parent, e.Name)
+		switch e.Type() {
+		case object.TreeObject:
+			sub, err := r.odb.Tree(ctx, e.Hash)
+			if plumbing.IsNoSuchObject(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if files, err = r.sizeWalk(ctx, sub, name, files); err != nil {
+				return nil, err
+			}
+		case object.FragmentsObject:
+			files = append(files, &sizeFile{path: name, size: e.Size, fragments: true})
+		case object.BlobObject:
+			files = append(files, &sizeFile{path: name, size: e.Size})
+		}
+	}
+	return files, nil
+}
+
+func (r *Repository) sizeFiles(ctx context.Context, rev string) ([]*sizeFile, error) {
+	t, err := r.resolveTree(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+	return r.sizeWalk(ctx, t, "", make([]*sizeFile, 0, 100))
+}
+
+func fileExtension(name string) string {
+	ext := strings.ToLower(path.Ext(path.Base(name)))
+	// dotfiles such as .gitignore have no extension
+	if len(ext) == 0 || ext == strings.ToLower(path.Base(name)) {
+		return noExtension
+	}
+	return ext
+}
+
+func topN[T any](items []T, limit int, compare func(a, b T) int) []T {
+	slices.SortFunc(items, compare)
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
+
+func newSizeReport(files []*sizeFile, limit int) *SizeReport {
+	sr := &SizeReport{
+		Files:      make([]*SizeEntry, 0, len(files)),
+		Dirs:       make([]*SizeEntry, 0),
+		Extensions: make([]*ExtensionSize, 0),
+	}
+	dirs := make(map[string]*SizeEntry)
+	extensions := make(map[string]*ExtensionSize)
+	for _, f := range files {
+		sr.Total.Count++
+		sr.Total.Size += f.size
+		if f.fragments {
+			sr.Fragments.Count++
+			sr.Fragments.Size += f.size
+		} else {
+			sr.Blobs.Count++
+			sr.Blobs.Size += f.size
+		}
+		sr.Files = append(sr.Files, &SizeEntry{Path: f.path, Size: f.size, Fragments: f.fragments})
+		for dir := path.Dir(f.path); dir != "."; dir = path.Dir(dir) {
+			d, ok := dirs[dir]
+			if !ok {
+				d = &SizeEntry{Path: dir}
+				dirs[dir] = d
+			}
+			d.Size += f.size
+			d.Files++
+		}
+		ext := fileExtension(f.path)
+		e, ok := extensions[ext]
+		if !ok {
+			e = &ExtensionSize{Extension: ext}
+			extensions[ext] = e
+		}
+		e.Count++
+		e.Size += f.size
+	}
+	for _, d := range dirs {
+		sr.Dirs = append(sr.Dirs, d)
+	}
+	for _, e := range extensions {
+		sr.Extensions = append(sr.Extensions, e)
+	}
+	bySize := func(a, b *SizeEntry) int {
+		if n := cmp.Compare(b.Size, a.Size); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Path, b.Path)
+	}
+	sr.Files = topN(sr.Files, limit, bySize)
+	sr.Dirs = topN(sr.Dirs, limit, bySize)
+	sr.Extensions = topN(sr.Extensions, limit, func(a, b *ExtensionSize) int {
+		if n := cmp.Compare(b.Size, a.Size); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Extension, b.Extension)
+	})
+	return sr
+}
+
+// newSizeGrowth compares files with the base files, the largest increases first.
+func newSizeGrowth(files, baseFiles []*sizeFile, limit int) *SizeGrowth {
+	g := &SizeGrowth{Files: make([]*SizeChange, 0)}
+	current := make(map[string]int64, len(files))
+	for _, f := range files {
+		current[f.path] = f.size
+		g.Delta += f.size
+	}
+	base := make(map[string]bool, len(baseFiles))
+	for _, f := range baseFiles {
+		base[f.path] = true
+		g.BaseSize += f.size
+		g.Delta -= f.size
+		newSize, ok := current[f.path]
+		if ok && newSize == f.size {
+			continue
+		}
+		// removed files have a new size of zero
+		g.Files = append(g.Files, &SizeChange{Path: f.path, OldSize: f.size, NewSize: newSize, Delta: newSize - f.size})
+	}
+	for _, f := range files {
+		if !base[f.path] {
+			g.Files = append(g.Files, &SizeChange{Path: f.path, NewSize: f.size, Delta: f.size})
+		}
+	}
+	g.Files = topN(g.Files, limit, func(a, b *SizeChange) int {
+		if n := cmp.Compare(b.Delta, a.Delta); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return g
+}
+
+// SizeReport reports what makes the checkout of a revision large: the largest files and directories, fragments
+// vs regular blobs, per-extension totals and the growth vs a base revision.
+func (r *Repository) SizeReport(ctx context.Context, opts *SizeReportOptions) error {
+	rev := opts.Revision
+	if len(rev) == 0 {
+		rev = string(plumbing.HEAD)
+	}
+	files, err := r.sizeFiles(ctx, rev)
+	if err != nil {
+		die_error("size-report %s: %v", rev, err)
+		return err
+	}
+	sr := newSizeReport(files, opts.Limit)
+	sr.Revision = rev
+	if len(opts.Base) != 0 {
+		baseFiles, err := r.sizeFiles(ctx, opts.Base)
+		if err != nil {
+			die_error("size-report %s: %v", opts.Base, err)
+			return err
+		}
+		sr.Growth = newSizeGrowth(files, baseFiles, opts.Limit)
+		sr.Growth.Base = opts.Base
+	}
+	if opts.JSON {
+		return json.NewEncoder(os.Stdout).Encode(sr)
+	}
+	p := NewPrinter(ctx)
+	defer p.Close() // nolint
+	if err := writeSizeReport(p, sr); err != nil && !errors.Is(err, syscall.EPIPE) {
+		return err
+	}
+	return nil
+}
+
+func formatDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + strengthen.FormatSize(-delta)
+	}
+	return "+" + strengthen.FormatSize(delta)
+}
+
+func writeSizeReport(w io.Writer, sr *SizeReport) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", W("Size report of"), sr.Revision)
+	fmt.Fprintf(&b, "  %-10s %10s %8d %s\n", W("total"), strengthen.FormatSize(sr.Total.Size), sr.Total.Count, W("files"))
+	fmt.Fprintf(&b, "  %-10s %10s %8d %s\n", W("blobs"), strengthen.FormatSize(sr.Blobs.Size), sr.Blobs.Count, W("files"))
+	fmt.Fprintf(&b, "  %-10s %10s %8d %s\n", W("fragments"), strengthen.FormatSize(sr.Fragments.Size), sr.Fragments.Count, W("files"))
+	if len(sr.Files) != 0 {
+		fmt.Fprintf(&b, "\n%s:\n", W("Largest files"))
+		for _, e := range sr.Files {
+			kind := "blob"
+			if e.Fragments {
+				kind = "fragments"
+			}
+			fmt.Fprintf(&b, "  %10s  %-9s  %s\n", strengthen.FormatSize(e.Size), kind, e.Path)
+		}
+	}
+	if len(sr.Dirs) != 0 {
+		fmt.Fprintf(&b, "\n%s:\n", W("Largest directories"))
+		for _, e := range sr.Dirs {
+			fmt.Fprintf(&b, "  %10s  %8d  %s/\n", strengthen.FormatSize(e.Size), e.Files, e.Path)
+		}
+	}
+	if len(sr.Extensions) != 0 {
+		fmt.Fprintf(&b, "\n%s:\n", W("Extensions"))
+		for _, e := range sr.Extensions {
+			fmt.Fprintf(&b, "  %10s  %8d  %s\n", strengthen.FormatSize(e.Size), e.Count, e.Extension)
+		}
+	}
+	if g := sr.Growth; g != nil {
+		fmt.Fprintf(&b, "\n%s %s: %s -> %s (%s)\n", W("Growth since"), g.Base, strengthen.FormatSize(g.BaseSize),
+			strengthen.FormatSize(sr.Total.Size), formatDelta(g.Delta))
+		for _, c := range g.Files {
+			fmt.Fprintf(&b, "  %10s  %10s -> %-10s  %s\n", formatDelta(c.Delta), strengthen.FormatSize(c.OldSize),
+				strengthen.FormatSize(c.NewSize), c.Path)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,61 @@
+package zeta
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSizeReport(t *testing.T) {
+	files := []*sizeFile{
+		{path: "README.md", size: 10},
+		{path: "assets/logo.PNG", size: 300},
+		{path: "assets/models/model.bin", size: 5000, fragments: true},
+		{path: "src/main.go", size: 200},
+		{path: "src/.gitignore", size: 5},
+	}
+	sr := newSizeReport(files, 2)
+	if sr.Total.Count != 5 || sr.Total.Size != 5515 || sr.Fragments.Count != 1 || sr.Fragments.Size != 5000 || sr.Blobs.Size != 515 {
+		t.Fatalf("bad totals: %+v %+v %+v", sr.Total, sr.Blobs, sr.Fragments)
+	}
+	var got []string
+	for _, e := range sr.Files {
+		got = append(got, e.Path)
+	}
+	if !slices.Equal(got, []string{"assets/models/model.bin", "assets/logo.PNG"}) {
+		t.Fatalf("largest files = %q", got)
+	}
+	got = got[:0]
+	for _, e := range sr.Dirs {
+		got = append(got, e.Path)
+	}
+	if !slices.Equal(got, []string{"assets", "assets/models"}) || sr.Dirs[0].Size != 5300 || sr.Dirs[0].Files != 2 {
+		t.Fatalf("largest dirs = %q %+v", got, sr.Dirs[0])
+	}
+	all := newSizeReport(files, 0)
+	exts := make(map[string]int64)
+	for _, e := range all.Extensions {
+		exts[e.Extension] = e.Size
+	}
+	if len(exts) != 5 || exts[".bin"] != 5000 || exts[".png"] != 300 || exts[noExtension] != 5 || exts[".go"] != 200 {
+		t.Fatalf("extensions = %v", exts)
+	}
+
+	base := []*sizeFile{
+		{path: "README.md", size: 10},
+		{path: "assets/logo.PNG", size: 100},
+		{path: "src/main.go", size: 250},
+		{path: "old.txt", size: 40},
+	}
+	g := newSizeGrowth(files, base, 0)
+	if g.BaseSize != 400 || g.Delta != 5115 {
+		t.Fatalf("bad growth: base %d delta %d", g.BaseSize, g.Delta)
+	}
+	got = got[:0]
+	for _, c := range g.Files {
+		got = append(got, c.Path)
+	}
+	want := []string{"assets/models/model.bin", "assets/logo.PNG", "src/.gitignore", "old.txt", "src/main.go"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("growth files = %q; want %q", got, want)
+	}
+}
@@ -5,6 +5,7 @@ package zeta
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -113,6 +114,7 @@ type StatusJSON struct {
 	Staging   []FileStatusJSON `json:"staged,omitempty"`
 	Unstaging []FileStatusJSON `json:"unstaged,omitempty"`
 	Untracked []FileStatusJSON `json:"untracked,omitempty"`
+	Upstream  *UpstreamStatus  `json:"upstream,omitempty"`
 }
 
 // FileStatusJSON is the JSON representation of a FileStatus.
@@ -156,8 +158,13 @@ func newStatusJSON(status Status, root string) *StatusJSON {
 	return sj
 }
 
-func (w *Worktree) ShowStatusJSON(status Status) error {
+func (w *Worktree) ShowStatusJSON(ctx context.Context, status Status) error {
 	sj := newStatusJSON(status, w.baseDir)
+	us, err := w.UpstreamStatus(ctx)
+	if err != nil {
+		return err
+	}
+	sj.Upstream = us
 	return json.NewEncoder(os.Stdout).Encode(sj)
 }
 
@@ -8,10 +8,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/trace"
-	"github.com/antgroup/hugescm/modules/zeta/object"
 )
 
 // TODO:
@@ -22,13 +22,14 @@ import (
 // The operation is aborted however if the operation leads to loss of local changes, unless told otherwise with or .HEAD--discard-changes--merge
 
 type SwitchOptions struct {
-	Force       bool // aka discardChanges
-	Merge       bool
-	ForceCreate bool
-	Remote      bool
-	Limit       int64
-	firstSwitch bool
-	one         bool
+	Force         bool // aka discardChanges
+	Merge         bool
+	ConflictStyle string
+	ForceCreate   bool
+	Remote        bool
+	Limit         int64
+	firstSwitch   bool
+	one           bool
 }
 
 func (so *SwitchOptions) Validate() error {
@@ -50,7 +51,7 @@ func (r *Repository) switchBranchFromRemote(ctx context.Context, branch string,
 	if err != nil {
 		return err
 	}
-	opts := &CheckoutOptions{Merge: so.Merge, Force: so.Force, First: false, One: so.one}
+	opts := &CheckoutOptions{Merge: so.Merge, ConflictStyle: so.ConflictStyle, Force: so.Force, First: false, One: so.one}
 	if fo.Reference != nil && fo.Name.IsBranch() {
 		if err := r.CreateBranch(ctx, branch, fo.FETCH_HEAD.String(), so.ForceCreate, true); err != nil {
 			return err
@@ -71,6 +72,11 @@ func (r *Repository) switchBranchFromRemote(ctx context.Context, branch string,
 }
 
 func (r *Repository) SwitchBranch(ctx context.Context, branch string, so *SwitchOptions) error {
+	unlock, err := r.lock("switch")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	refname := plumbing.NewBranchReferenceName(branch)
 	ref, err := r.Reference(refname)
 	if errors.Is(err, plumbing.ErrReferenceNotFound) {
@@ -91,7 +97,7 @@ func (r *Repository) SwitchBranch(ctx context.Context, branch string, so *Switch
 	}
 	trace.DbgPrint("switch branch from local: %v", branch)
 	w := r.Worktree()
-	if err := w.Checkout(ctx, &CheckoutOptions{Branch: refname, Merge: so.Merge, Force: so.Force, First: so.firstSwitch, One: so.one}); err != nil {
+	if err := w.Checkout(ctx, &CheckoutOptions{Branch: refname, Merge: so.Merge, ConflictStyle: so.ConflictStyle, Force: so.Force, First: so.firstSwitch, One: so.one}); err != nil {
 		switchError(branch, err)
 		return err
 	}
@@ -100,13 +106,18 @@ func (r *Repository) SwitchBranch(ctx context.Context, branch string, so *Switch
 }
 
 func (r *Repository) SwitchDetach(ctx context.Context, basePoint string, so *SwitchOptions) error {
+	unlock, err := r.lock("switch")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	oid, err := r.promiseFetch(ctx, basePoint, true)
 	if err != nil {
 		die_error("resolve %s: %v", basePoint, err)
 		return err
 	}
 	w := r.Worktree()
-	if err := w.Checkout(ctx, &CheckoutOptions{Hash: oid, Merge: so.Merge, Force: so.Force, First: so.firstSwitch, One: so.one}); err != nil {
+	if err := w.Checkout(ctx, &CheckoutOptions{Hash: oid, Merge: so.Merge, ConflictStyle: so.ConflictStyle, Force: so.Force, First: so.firstSwitch, One: so.one}); err != nil {
 		switchError(basePoint, err)
 		return err
 	}
@@ -119,7 +130,14 @@ func (r *Repository) SwitchDetach(ctx context.Context, basePoint string, so *Swi
 	return nil
 }
 
+// SwitchOrphan: switch to a new unborn branch, the next commit starts an unrelated history.
+// All tracked files are removed from the index and the worktree, untracked files are kept.
 func (r *Repository) SwitchOrphan(ctx context.Context, newBranch string, so *SwitchOptions) error {
+	unlock, err := r.lock("switch")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	refname := plumbing.NewBranchReferenceName(newBranch)
 	ref, err := r.ReferencePrefixMatch(refname)
 	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
@@ -130,42 +148,58 @@ func (r *Repository) SwitchOrphan(ctx context.Context, newBranch string, so *Swi
 		die("a branch named '%s' already exists", newBranch)
 		return errors.New("branch already exists")
 	}
-	cc, err := r.parseRevExhaustive(ctx, "HEAD")
-	if err != nil {
-		die_error("zeta switch: parse HEAD: %v", err)
-		return err
-	}
-	orphanCommit := &object.Commit{
-		Author:       cc.Author,
-		Committer:    cc.Committer,
-		Tree:         cc.Tree,
-		ExtraHeaders: cc.ExtraHeaders,
-		Message:      cc.Message,
+	w := r.Worktree()
+	if !so.Force {
+		status, err := w.Status(ctx, false)
+		if err != nil {
+			die_error("zeta switch: status: %v", err)
+			return err
+		}
+		changed := make([]string, 0, len(status))
+		for p, s := range status {
+			if s.Worktree == Untracked || (s.Worktree == Unmodified && s.Staging == Unmodified) {
+				continue
+			}
+			changed = append(changed, p)
+		}
+		if len(changed) != 0 {
+			slices.Sort(changed)
+			die_error("Your local changes to the following files would be removed by switch:")
+			for _, p := range changed {
+				fmt.Fprintf(os.Stderr, "    %s\n", p)
+			}
+			fmt.Fprintf(os.Stderr, "%s\n%s\n", W("Please commit your changes or stash them before you switch branches."), W("Aborting"))
+			return ErrAborting
+		}
 	}
-	newOID, err := r.odb.WriteEncoded(orphanCommit)
-	if err != nil {
-		die("zeta switch: encode new commit: %v", err)
+	if err := w.removeTrackedFiles(); err != nil {
+		switchError(newBranch, err)
 		return err
 	}
-	if err := r.DoUpdate(ctx, refname, plumbing.ZeroHash, newOID, r.NewCommitter(), "branch: Create orphan from"); err != nil {
-		die_error("update-ref '%s': %v", refname, err)
+	originHEAD, err := r.HEAD()
+	if err != nil {
+		die_error("zeta switch: resolve HEAD: %v", err)
 		return err
 	}
-	w := r.Worktree()
-	if err := w.Checkout(ctx, &CheckoutOptions{Branch: refname, Merge: so.Merge, Force: so.Force, First: so.firstSwitch, One: so.one}); err != nil {
-		switchError(newBranch, err)
+	if err := r.Update(plumbing.NewSymbolicReference(plumbing.HEAD, refname), originHEAD); err != nil {
+		die_error("zeta switch: update HEAD: %v", err)
 		return err
 	}
-	fmt.Fprintf(os.Stderr, "%s '%s'\n", W("Switched to a new branch"), newOID.String())
+	fmt.Fprintf(os.Stderr, "%s '%s'\n", W("Switched to a new branch"), newBranch)
 	return nil
 }
 
 func (r *Repository) SwitchNewBranch(ctx context.Context, newBranch string, basePoint string, so *SwitchOptions) error {
+	unlock, err := r.lock("switch")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if err := r.CreateBranch(ctx, newBranch, basePoint, so.ForceCreate, true); err != nil {
 		return err
 	}
 	w := r.Worktree()
-	if err := w.Checkout(ctx, &CheckoutOptions{Branch: plumbing.NewBranchReferenceName(newBranch), Merge: so.Merge, Force: so.Force, First: so.firstSwitch, One: so.one}); err != nil {
+	if err := w.Checkout(ctx, &CheckoutOptions{Branch: plumbing.NewBranchReferenceName(newBranch), Merge: so.Merge, ConflictStyle: so.ConflictStyle, Force: so.Force, First: so.firstSwitch, One: so.one}); err != nil {
 		switchError(newBranch, err)
 		return err
 	}
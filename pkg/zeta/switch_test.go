@@ -1,9 +1,16 @@
 package zeta
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/format/index"
 )
 
 func TestSwitch(t *testing.T) {
@@ -32,3 +39,183 @@ func TestCat(t *testing.T) {
 	defer r.Close() // nolint
 	_ = r.Cat(t.Context(), &CatOptions{Object: "2be5d4418893425e546a6146fbda18eac95ea9a7fbb05faab02096738a974a11"})
 }
+
+func TestSwitchMerge(t *testing.T) {
+	ctx := t.Context()
+	setCommitEnv(t)
+	worktree := filepath.Join(t.TempDir(), "switch")
+	r, err := Init(ctx, &InitOptions{Worktree: worktree, Branch: "mainline", Quiet: true})
+	if err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	defer r.Close() // nolint
+	w := r.Worktree()
+	writeFile := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(worktree, "a.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(message string) {
+		t.Helper()
+		if err := w.Add(ctx, []string{"."}, false); err != nil {
+			t.Fatalf("add error: %v", err)
+		}
+		if _, err := w.Commit(ctx, &CommitOptions{Message: []string{message}}); err != nil {
+			t.Fatalf("commit error: %v", err)
+		}
+	}
+	writeFile("one\ntwo\nthree\n")
+	commit("base")
+	head, err := r.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainline := head.Name().BranchName()
+	if err := r.CreateBranch(ctx, "topic", "HEAD", false, false); err != nil {
+		t.Fatalf("create branch error: %v", err)
+	}
+	if err := r.SwitchBranch(ctx, "topic", &SwitchOptions{}); err != nil {
+		t.Fatalf("switch error: %v", err)
+	}
+	writeFile("ONE\ntwo\nthree\n")
+	commit("topic")
+	if err := r.SwitchBranch(ctx, mainline, &SwitchOptions{}); err != nil {
+		t.Fatalf("switch error: %v", err)
+	}
+
+	// local changes which do not conflict are carried to the target branch
+	writeFile("one\ntwo\nTHREE\n")
+	if err := r.SwitchBranch(ctx, "topic", &SwitchOptions{}); !errors.Is(err, ErrAborting) {
+		t.Fatalf("switch without --merge should abort, got %v", err)
+	}
+	if err := r.SwitchBranch(ctx, "topic", &SwitchOptions{Merge: true}); err != nil {
+		t.Fatalf("switch --merge error: %v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(worktree, "a.txt")); string(b) != "ONE\ntwo\nTHREE\n" {
+		t.Fatalf("unexpected merged content: %q", b)
+	}
+	s, err := w.Status(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs := s.File("a.txt"); fs.Worktree != Modified || fs.Staging != Unmodified {
+		t.Fatalf("merged file should be modified: %v", s)
+	}
+
+	// conflicts are written with markers and recorded as conflict stages
+	writeFile("uno\ntwo\nTHREE\n")
+	if err := r.SwitchBranch(ctx, mainline, &SwitchOptions{Merge: true, ConflictStyle: "diff3"}); err != nil {
+		t.Fatalf("switch --conflict=diff3 error: %v", err)
+	}
+	b, _ := os.ReadFile(filepath.Join(worktree, "a.txt"))
+	if !hasConflictMarkers(strings.NewReader(string(b))) || !strings.Contains(string(b), "\n||||||| ") {
+		t.Fatalf("expected diff3 conflict markers: %q", b)
+	}
+	idx, err := r.ODB().Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stages []index.Stage
+	for _, e := range idx.Entries {
+		if e.Name == "a.txt" {
+			stages = append(stages, e.Stage)
+		}
+	}
+	if !slices.Equal(stages, []index.Stage{index.AncestorMode, index.OurMode, index.TheirMode}) {
+		t.Fatalf("unexpected conflict stages: %v", stages)
+	}
+	if s, err = w.Status(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	if s.File("a.txt").Worktree != UpdatedButUnmerged {
+		t.Fatalf("conflicted file should be unmerged: %v", s)
+	}
+	if _, err := w.Commit(ctx, &CommitOptions{All: true, Message: []string{"markers"}}); !errors.Is(err, ErrHasConflicts) {
+		t.Fatalf("commit -a with unmerged files should fail, got %v", err)
+	}
+	writeFile("uno\ntwo\nthree\n")
+	commit("resolved")
+	if idx, err = r.ODB().Index(); err != nil {
+		t.Fatal(err)
+	}
+	if paths := unmergedIndexPaths(idx); len(paths) != 0 {
+		t.Fatalf("add should resolve the conflict: %v", paths)
+	}
+}
+
+func TestSwitchOrphanAndDetach(t *testing.T) {
+	ctx := t.Context()
+	setCommitEnv(t)
+	worktree := filepath.Join(t.TempDir(), "switch")
+	r, err := Init(ctx, &InitOptions{Worktree: worktree, Branch: "mainline", Quiet: true})
+	if err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	defer r.Close() // nolint
+	w := r.Worktree()
+	if err := os.WriteFile(filepath.Join(worktree, "a.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add(ctx, []string{"."}, false); err != nil {
+		t.Fatalf("add error: %v", err)
+	}
+	oid, err := w.Commit(ctx, &CommitOptions{Message: []string{"base"}})
+	if err != nil {
+		t.Fatalf("commit error: %v", err)
+	}
+
+	if err := r.SwitchDetach(ctx, "HEAD", &SwitchOptions{}); err != nil {
+		t.Fatalf("switch --detach error: %v", err)
+	}
+	head, err := r.HEAD()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Type() != plumbing.HashReference || head.Hash() != oid {
+		t.Fatalf("HEAD should be detached at %s: %v", oid, head)
+	}
+
+	// tracked local changes would be lost by --orphan
+	if err := os.WriteFile(filepath.Join(worktree, "a.txt"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SwitchOrphan(ctx, "fresh", &SwitchOptions{}); !errors.Is(err, ErrAborting) {
+		t.Fatalf("switch --orphan with local changes should abort, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree, "untracked.txt"), []byte("keep\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SwitchOrphan(ctx, "fresh", &SwitchOptions{Force: true}); err != nil {
+		t.Fatalf("switch --orphan error: %v", err)
+	}
+	if head, err = r.HEAD(); err != nil {
+		t.Fatal(err)
+	}
+	if head.Type() != plumbing.SymbolicReference || head.Target() != plumbing.NewBranchReferenceName("fresh") {
+		t.Fatalf("HEAD should point to the unborn branch: %v", head)
+	}
+	if _, err := r.Reference(plumbing.NewBranchReferenceName("fresh")); !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		t.Fatalf("orphan branch should be unborn, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(worktree, "a.txt")); !os.IsNotExist(err) {
+		t.Fatalf("tracked files should be removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(worktree, "untracked.txt")); err != nil {
+		t.Fatalf("untracked files should be kept: %v", err)
+	}
+	idx, err := r.ODB().Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Entries) != 0 {
+		t.Fatalf("index should be empty: %v", idx.Entries)
+	}
+}
+
+func setCommitEnv(t *testing.T) {
+	t.Setenv(ENV_ZETA_AUTHOR_NAME, "zeta")
+	t.Setenv(ENV_ZETA_AUTHOR_EMAIL, "zeta@example.io")
+	t.Setenv(ENV_ZETA_COMMITTER_NAME, "zeta")
+	t.Setenv(ENV_ZETA_COMMITTER_EMAIL, "zeta@example.io")
+}
@@ -0,0 +1,225 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/systemproxy"
+	"github.com/antgroup/hugescm/modules/telemetry"
+	"github.com/antgroup/hugescm/pkg/version"
+)
+
+const (
+	telemetryUploadInterval = 24 * time.Hour
+	telemetryRetryInterval  = time.Hour
+	telemetryUploadTimeout  = 5 * time.Second
+)
+
+var (
+	ErrTelemetryNoEndpoint = errors.New("telemetry.endpoint is not configured, aggregates are only kept locally")
+)
+
+type TelemetryOptions struct {
+	CWD     string
+	Values  []string
+	JSON    bool
+	Verbose bool
+}
+
+type telemetrySettings struct {
+	enabled  bool
+	endpoint string
+	insecure bool
+	proxyURL string
+	path     string
+}
+
+func resolveTelemetry(cwd string, values []string) (*telemetrySettings, error) {
+	cfg, err := LoadConfig(cwd, values)
+	if err != nil {
+		return nil, err
+	}
+	m := valuesMapArray(values)
+	s := &telemetrySettings{
+		enabled:  cfg.Telemetry.Enabled.True(),
+		endpoint: cfg.Telemetry.Endpoint,
+		insecure: parseInsecureSkipTLS(cfg, m),
+		proxyURL: cfg.Transport.ExternalProxy,
+	}
+	if v, ok := getFromValueOrEnv("telemetry.enabled", ENV_ZETA_TELEMETRY_ENABLED, m); ok {
+		s.enabled = strengthen.SimpleAtob(v, false)
+	}
+	if v, ok := getFromValueOrEnv("telemetry.endpoint", ENV_ZETA_TELEMETRY_ENDPOINT, m); ok {
+		s.endpoint = v
+	}
+	if v, ok := getFromValueOrEnv("transport.externalProxy", ENV_ZETA_TRANSPORT_EXTERNAL_PROXY, m); ok && len(v) != 0 {
+		s.proxyURL = v
+	}
+	if s.path, err = telemetry.DefaultPath(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *telemetrySettings) upload(ctx context.Context, stats *telemetry.Stats) error {
+	if len(s.endpoint) == 0 {
+		return ErrTelemetryNoEndpoint
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy: systemproxy.NewSystemProxy(s.proxyURL),
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: s.insecure,
+			},
+		},
+		Timeout: telemetryUploadTimeout,
+	}
+	// the host name is part of version.GetUserAgent() in some builds, never send it here
+	return telemetry.Upload(ctx, client, s.endpoint, "Zeta/"+version.GetVersion(), &telemetry.Report{
+		Version:  version.GetVersion(),
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Since:    stats.Since,
+		Until:    time.Now(),
+		Commands: stats.Commands,
+	})
+}
+
+// telemetryCategory: exit codes are kept since commands such as diff use them to report results
+func telemetryCategory(err error) string {
+	if e, ok := errors.AsType[*ErrExitCode](err); ok {
+		return fmt.Sprintf("exit-%d", e.ExitCode)
+	}
+	return telemetry.Categorize(err)
+}
+
+// RecordTelemetry records a command run when telemetry.enabled is set. Aggregates are uploaded at most once a day
+// when telemetry.endpoint is configured. Failures are ignored: telemetry must never break a command.
+func RecordTelemetry(ctx context.Context, cwd string, values []string, name string, d time.Duration, err error) {
+	s, e := resolveTelemetry(cwd, values)
+	if e != nil || !s.enabled {
+		return
+	}
+	stats := telemetry.Load(s.path)
+	stats.Record(name, d, telemetryCategory(err))
+	now := time.Now()
+	last := stats.Uploaded
+	if last.IsZero() {
+		last = stats.Since
+	}
+	if len(s.endpoint) != 0 && now.Sub(last) >= telemetryUploadInterval && now.Sub(stats.Attempted) >= telemetryRetryInterval {
+		stats.Attempted = now
+		// the command context may already be canceled, the upload is bounded by its own timeout
+		if e := s.upload(context.WithoutCancel(ctx), stats); e == nil {
+			stats.Reset(now)
+		}
+	}
+	_ = stats.Save(s.path)
+}
+
+func formatTelemetryDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+// ShowTelemetry prints the aggregates collected on this machine.
+func ShowTelemetry(ctx context.Context, opts *TelemetryOptions) error {
+	s, err := resolveTelemetry(opts.CWD, opts.Values)
+	if err != nil {
+		die_error("%v", err)
+		return err
+	}
+	stats := telemetry.Load(s.path)
+	if opts.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+	if !s.enabled {
+		fmt.Fprintf(os.Stdout, "%s\n", W("Telemetry is disabled, enable it with 'zeta config --global telemetry.enabled true'"))
+	}
+	if len(s.endpoint) == 0 {
+		fmt.Fprintf(os.Stdout, "%s\n", W("No endpoint is configured, nothing leaves this machine"))
+	} else {
+		fmt.Fprintf(os.Stdout, W("Aggregates are uploaded to '%s' once a day\n"), s.endpoint)
+	}
+	fmt.Fprintf(os.Stdout, W("Collected since %s\n"), stats.Since.Format(time.RFC3339))
+	if len(stats.Commands) == 0 {
+		return nil
+	}
+	names := stats.Names()
+	width := len("command")
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+	fmt.Fprintf(os.Stdout, "\n%-*s %8s %10s %10s %10s %8s  %s\n", width, "command", "runs", "avg", "p90", "max", "failed", "errors")
+	for _, name := range names {
+		c := stats.Commands[name]
+		categories := make([]string, 0, len(c.Errors))
+		for k, v := range c.Errors {
+			categories = append(categories, fmt.Sprintf("%s=%d", k, v))
+		}
+		slices.Sort(categories)
+		fmt.Fprintf(os.Stdout, "%-*s %8d %10s %10s %10s %8d  %s\n", width, name, c.Count,
+			formatTelemetryDuration(c.Average()), formatTelemetryDuration(c.Percentile(90)),
+			formatTelemetryDuration(time.Duration(c.MaxMs)*time.Millisecond), c.Failures(), strings.Join(categories, " "))
+	}
+	return nil
+}
+
+// UploadTelemetry uploads the aggregates now and starts new aggregates.
+func UploadTelemetry(ctx context.Context, opts *TelemetryOptions) error {
+	s, err := resolveTelemetry(opts.CWD, opts.Values)
+	if err != nil {
+		die_error("%v", err)
+		return err
+	}
+	stats := telemetry.Load(s.path)
+	if len(stats.Commands) == 0 {
+		fmt.Fprintf(os.Stderr, "%s\n", W("Nothing to upload"))
+		return nil
+	}
+	now := time.Now()
+	stats.Attempted = now
+	if err := s.upload(ctx, stats); err != nil {
+		_ = stats.Save(s.path)
+		die_error("%v", err)
+		return err
+	}
+	stats.Reset(now)
+	if err := stats.Save(s.path); err != nil {
+		die_error("save telemetry: %v", err)
+		return err
+	}
+	fmt.Fprintf(os.Stderr, W("Uploaded telemetry to '%s'\n"), s.endpoint)
+	return nil
+}
+
+// ClearTelemetry removes the aggregates collected on this machine.
+func ClearTelemetry(ctx context.Context, opts *TelemetryOptions) error {
+	p, err := telemetry.DefaultPath()
+	if err != nil {
+		die_error("%v", err)
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		die_error("remove '%s': %v", p, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing/format/ignore"
+	"github.com/antgroup/hugescm/pkg/transport"
+)
+
+const (
+	// templatesDir: .zeta/templates keeps the organization-wide templates downloaded from the server
+	templatesDir         = "templates"
+	templatesVersionName = "VERSION"
+	orgTemplatesAuto     = "auto"
+)
+
+// orgTemplates: core.orgTemplates=auto OR ZETA_CORE_ORG_TEMPLATES=auto, other values disable the templates.
+func (r *Repository) orgTemplates() bool {
+	if s, ok := r.getFromValueOrEnv("core.orgTemplates", ENV_ZETA_CORE_ORG_TEMPLATES); ok {
+		return strings.EqualFold(s, orgTemplatesAuto)
+	}
+	return strings.EqualFold(r.Core.OrgTemplates, orgTemplatesAuto)
+}
+
+func (r *Repository) templatesJoin(elem ...string) string {
+	return filepath.Join(append([]string{r.zetaDir, templatesDir}, elem...)...)
+}
+
+// templatesVersion returns the version of the downloaded templates, empty when nothing was downloaded.
+func (r *Repository) templatesVersion() string {
+	b, err := os.ReadFile(r.templatesJoin(templatesVersionName))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// encodeTemplates merges the templates of every kind, global templates come first so the templates of the namespace
+// override them. Each template starts with a comment naming its scope and version.
+func encodeTemplates(templates []*transport.Template) map[string][]byte {
+	contents := make(map[string][]byte)
+	for _, t := range templates {
+		if t.Kind != transport.TEMPLATE_IGNORE && t.Kind != transport.TEMPLATE_ATTRIBUTES {
+			continue
+		}
+		var b bytes.Buffer
+		b.Write(contents[t.Kind])
+		fmt.Fprintf(&b, "# %s v%d\n", t.Scope, t.Version)
+		b.WriteString(t.Content)
+		if len(t.Content) != 0 && !strings.HasSuffix(t.Content, "\n") {
+			b.WriteByte('\n')
+		}
+		contents[t.Kind] = b.Bytes()
+	}
+	return contents
+}
+
+func writeTemplateFile(name string, content []byte) error {
+	// write then rename, concurrent commands never read a partial template
+	tmp := name + ".lock"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// storeTemplates replaces the downloaded templates, VERSION is written last so an interrupted update is downloaded again.
+func (r *Repository) storeTemplates(templates *transport.Templates) error {
+	if err := os.MkdirAll(r.templatesJoin(), 0755); err != nil {
+		return err
+	}
+	contents := encodeTemplates(templates.Templates)
+	for _, kind := range []string{transport.TEMPLATE_IGNORE, transport.TEMPLATE_ATTRIBUTES} {
+		name := r.templatesJoin(kind)
+		content, ok := contents[kind]
+		if !ok {
+			if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if err := writeTemplateFile(name, content); err != nil {
+			return err
+		}
+	}
+	return writeTemplateFile(r.templatesJoin(templatesVersionName), []byte(templates.Version+"\n"))
+}
+
+// syncTemplates downloads the organization-wide templates when the server hosts them and they changed since the last
+// download. Templates are a convenience, errors are reported as warnings and never fail checkout or fetch.
+func (r *Repository) syncTemplates(ctx context.Context, t transport.Transport, ref *transport.Reference) {
+	if ref == nil || !ref.HasCapability(transport.CAPABILITY_TEMPLATES) || !r.orgTemplates() {
+		return
+	}
+	have := r.templatesVersion()
+	templates, err := t.FetchTemplates(ctx, have)
+	if err != nil {
+		warn("fetch organization templates error: %v", err)
+		return
+	}
+	if templates.NotModified || templates.Version == have {
+		return
+	}
+	if err := r.storeTemplates(templates); err != nil {
+		warn("store organization templates error: %v", err)
+		return
+	}
+	if !r.quiet {
+		fmt.Fprintf(os.Stderr, W("Organization templates updated to version %s\n"), templates.Version)
+	}
+}
+
+// templateIgnorePatterns returns the patterns of the downloaded ignore template, they have a lower priority than
+// .zeta/info/exclude and .zetaignore.
+func (r *Repository) templateIgnorePatterns() []ignore.Pattern {
+	if !r.orgTemplates() {
+		return nil
+	}
+	fd, err := os.Open(r.templatesJoin(transport.TEMPLATE_IGNORE))
+	if err != nil {
+		return nil
+	}
+	defer fd.Close() // nolint
+	var ps []ignore.Pattern
+	sr := bufio.NewScanner(fd)
+	for sr.Scan() {
+		s := sr.Text()
+		if !strings.HasPrefix(s, "#") && len(strings.TrimSpace(s)) > 0 {
+			ps = append(ps, ignore.ParsePattern(s, nil))
+		}
+	}
+	return ps
+}
@@ -0,0 +1,63 @@
+package zeta
+
+import (
+	"os"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing/format/ignore"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+	"github.com/antgroup/hugescm/pkg/transport"
+)
+
+func TestStoreTemplates(t *testing.T) {
+	r := &Repository{Config: &config.Config{Core: config.Core{OrgTemplates: "auto"}}, zetaDir: t.TempDir()}
+	if v := r.templatesVersion(); v != "" {
+		t.Fatalf("unexpected version %q before download", v)
+	}
+	if err := r.storeTemplates(&transport.Templates{Version: "v1", Templates: []*transport.Template{
+		{Kind: transport.TEMPLATE_IGNORE, Scope: "global", Version: 3, Content: "*.gen\nbuild/"},
+		{Kind: transport.TEMPLATE_ATTRIBUTES, Scope: "global", Version: 1, Content: "*.bin binary\n"},
+		{Kind: transport.TEMPLATE_IGNORE, Scope: "mono", Version: 1, Content: "# keep generated docs\n!docs.gen\n"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if v := r.templatesVersion(); v != "v1" {
+		t.Fatalf("unexpected version %q", v)
+	}
+	b, err := os.ReadFile(r.templatesJoin(transport.TEMPLATE_IGNORE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# global v3\n*.gen\nbuild/\n# mono v1\n# keep generated docs\n!docs.gen\n"; string(b) != want {
+		t.Fatalf("unexpected ignore template %q", b)
+	}
+	m := ignore.NewMatcher(r.templateIgnorePatterns())
+	for _, tt := range []struct {
+		path  []string
+		isDir bool
+		want  bool
+	}{
+		{[]string{"a.gen"}, false, true},
+		{[]string{"docs.gen"}, false, false},
+		{[]string{"src", "build"}, true, true},
+		{[]string{"a.go"}, false, false},
+	} {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("match %v = %v; want %v", tt.path, got, tt.want)
+		}
+	}
+
+	// templates removed from the server are removed locally
+	if err := r.storeTemplates(&transport.Templates{Version: "v2", Templates: []*transport.Template{
+		{Kind: transport.TEMPLATE_IGNORE, Scope: "global", Version: 4, Content: "*.tmp\n"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(r.templatesJoin(transport.TEMPLATE_ATTRIBUTES)); !os.IsNotExist(err) {
+		t.Fatalf("attributes template should be removed: %v", err)
+	}
+	r.Core.OrgTemplates = "off"
+	if ps := r.templateIgnorePatterns(); len(ps) != 0 {
+		t.Fatalf("templates should be disabled, got %d patterns", len(ps))
+	}
+}
@@ -0,0 +1,411 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/systemproxy"
+	"github.com/antgroup/hugescm/modules/telemetry"
+	"github.com/antgroup/hugescm/modules/transferlog"
+	"github.com/antgroup/hugescm/modules/zeta"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/transport/client"
+)
+
+const (
+	// transferErrorMax: error messages of the server may be long, the log keeps the beginning
+	transferErrorMax = 256
+)
+
+var (
+	// transferReceived, transferSent: bytes transferred by this process, exported by the metrics of the command
+	transferReceived atomic.Int64
+	transferSent     atomic.Int64
+)
+
+type transferRetriesKey struct{}
+
+// withTransferRetries marks requests made on ctx as the n-th retry of an earlier request.
+func withTransferRetries(ctx context.Context, n int) context.Context {
+	if n == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, transferRetriesKey{}, n)
+}
+
+func transferRetries(ctx context.Context) int {
+	n, _ := ctx.Value(transferRetriesKey{}).(int)
+	return n
+}
+
+func transferLogPath(zetaDir string) string {
+	return filepath.Join(zetaDir, "logs", "transfer.log")
+}
+
+// redactedEndpoint hides passwords, only the ssh user name is kept.
+func redactedEndpoint(u *url.URL) string {
+	c := *u
+	switch {
+	case c.Scheme == "ssh" && c.User != nil:
+		c.User = url.User(c.User.Username())
+	default:
+		c.User = nil
+	}
+	return c.String()
+}
+
+// transferProxy: the proxy the HTTP transport resolves for the endpoint, ssh uses ProxyCommand of ssh config instead.
+func transferProxy(endpoint *transport.Endpoint) string {
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return ""
+	}
+	req := &http.Request{URL: &endpoint.URL, Header: make(http.Header)}
+	u, err := systemproxy.NewSystemProxy("")(req)
+	if err != nil || u == nil {
+		return "direct"
+	}
+	return u.Redacted()
+}
+
+func transferStatus(err error) (string, string) {
+	if err == nil {
+		return transferlog.StatusOK, ""
+	}
+	message := err.Error()
+	if len(message) > transferErrorMax {
+		message = message[:transferErrorMax]
+	}
+	if e, ok := errors.AsType[interface {
+		error
+		Status() int
+	}](err); ok {
+		return strconv.Itoa(e.Status()), message
+	}
+	if e, ok := errors.AsType[*zeta.ErrExitCode](err); ok {
+		return strconv.Itoa(e.Code), message
+	}
+	return telemetry.Categorize(err), message
+}
+
+// loggedTransport records every request into .zeta/logs/transfer.log, failures to write the log are ignored.
+type loggedTransport struct {
+	transport.Transport
+	log      *transferlog.Logger
+	endpoint string
+	proxy    string
+	mu       sync.Mutex
+	attempts map[plumbing.Hash]int
+}
+
+// newLoggedTransport connects to the remote, the connection (authorization or ssh handshake) is recorded as well.
+func newLoggedTransport(ctx context.Context, endpoint *transport.Endpoint, operation transport.Operation, zetaDir string, verbose bool) (transport.Transport, error) {
+	t := &loggedTransport{
+		log:      transferlog.New(transferLogPath(zetaDir)),
+		endpoint: redactedEndpoint(&endpoint.URL),
+		proxy:    transferProxy(endpoint),
+		attempts: make(map[plumbing.Hash]int),
+	}
+	start := time.Now()
+	ta, err := client.NewTransport(ctx, endpoint, operation, verbose)
+	t.record(ctx, "connect", 0, 0, start, err)
+	if err != nil {
+		return nil, err
+	}
+	t.Transport = ta
+	return t, nil
+}
+
+func (t *loggedTransport) record(ctx context.Context, operation string, objects int, n int64, start time.Time, err error) {
+	switch operation {
+	case "push", "put-object", "put-delta":
+		transferSent.Add(n)
+	default:
+		transferReceived.Add(n)
+	}
+	status, message := transferStatus(err)
+	_ = t.log.Append(&transferlog.Record{
+		Time:       start,
+		Endpoint:   t.endpoint,
+		Operation:  operation,
+		Objects:    objects,
+		Bytes:      n,
+		DurationMs: time.Since(start).Milliseconds(),
+		Retries:    transferRetries(ctx),
+		Status:     status,
+		Error:      message,
+		Proxy:      t.proxy,
+	})
+}
+
+// attempt returns how many times the object was requested before, large objects are resumed on broken connections.
+func (t *loggedTransport) attempt(oid plumbing.Hash) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := t.attempts[oid]
+	t.attempts[oid] = n + 1
+	return n
+}
+
+// loggedReader records the request when the response is closed, so that bytes and duration cover the whole body.
+type loggedReader struct {
+	io.Reader
+	closer    io.Closer
+	lastError func() error
+	done      func(n int64, err error)
+	n         int64
+	err       error
+	once      sync.Once
+}
+
+func (r *loggedReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+func (r *loggedReader) Close() error {
+	err := r.closer.Close()
+	r.once.Do(func() {
+		e := r.err
+		if lastErr := r.lastError(); lastErr != nil {
+			e = lastErr
+		}
+		r.done(r.n, e)
+	})
+	return err
+}
+
+func (r *loggedReader) LastError() error {
+	return r.lastError()
+}
+
+type loggedSizeReader struct {
+	*loggedReader
+	sr transport.SizeReader
+}
+
+func (r *loggedSizeReader) Offset() int64 {
+	return r.sr.Offset()
+}
+
+func (r *loggedSizeReader) Size() int64 {
+	return r.sr.Size()
+}
+
+func (t *loggedTransport) wrap(ctx context.Context, rc transport.SessionReader, operation string, objects int, start time.Time) *loggedReader {
+	return &loggedReader{Reader: rc, closer: rc, lastError: rc.LastError, done: func(n int64, err error) {
+		t.record(ctx, operation, objects, n, start, err)
+	}}
+}
+
+func (t *loggedTransport) FetchReference(ctx context.Context, refname plumbing.ReferenceName) (*transport.Reference, error) {
+	start := time.Now()
+	ref, err := t.Transport.FetchReference(ctx, refname)
+	t.record(ctx, "reference", 0, 0, start, err)
+	return ref, err
+}
+
+func (t *loggedTransport) ListReferences(ctx context.Context) (*transport.References, error) {
+	start := time.Now()
+	refs, err := t.Transport.ListReferences(ctx)
+	t.record(ctx, "references", 0, 0, start, err)
+	return refs, err
+}
+
+func (t *loggedTransport) FetchMetadata(ctx context.Context, target plumbing.Hash, opts *transport.MetadataOptions) (transport.SessionReader, error) {
+	start := time.Now()
+	rc, err := t.Transport.FetchMetadata(ctx, target, opts)
+	if err != nil {
+		t.record(ctx, "metadata", 1, 0, start, err)
+		return nil, err
+	}
+	return t.wrap(ctx, rc, "metadata", 1, start), nil
+}
+
+func (t *loggedTransport) BatchMetadata(ctx context.Context, oids []plumbing.Hash, depth int, limit int) (transport.SessionReader, error) {
+	start := time.Now()
+	rc, err := t.Transport.BatchMetadata(ctx, oids, depth, limit)
+	if err != nil {
+		t.record(ctx, "batch-metadata", len(oids), 0, start, err)
+		return nil, err
+	}
+	return t.wrap(ctx, rc, "batch-metadata", len(oids), start), nil
+}
+
+func (t *loggedTransport) BatchObjects(ctx context.Context, oids []plumbing.Hash) (transport.SessionReader, error) {
+	start := time.Now()
+	rc, err := t.Transport.BatchObjects(ctx, oids)
+	if err != nil {
+		t.record(ctx, "batch-objects", len(oids), 0, start, err)
+		return nil, err
+	}
+	return t.wrap(ctx, rc, "batch-objects", len(oids), start), nil
+}
+
+func (t *loggedTransport) GetObject(ctx context.Context, oid plumbing.Hash, fromByte int64) (transport.SizeReader, error) {
+	ctx = withTransferRetries(ctx, t.attempt(oid))
+	start := time.Now()
+	sr, err := t.Transport.GetObject(ctx, oid, fromByte)
+	if err != nil {
+		t.record(ctx, "get-object", 1, 0, start, err)
+		return nil, err
+	}
+	return &loggedSizeReader{loggedReader: t.wrap(ctx, sr, "get-object", 1, start), sr: sr}, nil
+}
+
+func (t *loggedTransport) Share(ctx context.Context, wantObjects []*transport.WantObject) ([]*transport.Representation, error) {
+	start := time.Now()
+	objects, err := t.Transport.Share(ctx, wantObjects)
+	t.record(ctx, "share", len(wantObjects), 0, start, err)
+	return objects, err
+}
+
+// countingReader counts the bytes sent by uploads.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (t *loggedTransport) Push(ctx context.Context, r io.Reader, cmd *transport.Command) (transport.SessionReader, error) {
+	start := time.Now()
+	cr := &countingReader{Reader: r}
+	rc, err := t.Transport.Push(ctx, cr, cmd)
+	if err != nil {
+		t.record(ctx, "push", cmd.Metadata+cmd.Objects, cr.n, start, err)
+		return nil, err
+	}
+	lr := t.wrap(ctx, rc, "push", cmd.Metadata+cmd.Objects, start)
+	// the report is small, the pushed pack is what matters
+	lr.done = func(_ int64, err error) {
+		t.record(ctx, "push", cmd.Metadata+cmd.Objects, cr.n, start, err)
+	}
+	return lr, nil
+}
+
+func (t *loggedTransport) BatchCheck(ctx context.Context, refname plumbing.ReferenceName, haveObjects []*transport.HaveObject) ([]*transport.HaveObject, error) {
+	start := time.Now()
+	objects, err := t.Transport.BatchCheck(ctx, refname, haveObjects)
+	t.record(ctx, "batch-check", len(haveObjects), 0, start, err)
+	return objects, err
+}
+
+func (t *loggedTransport) PutObject(ctx context.Context, refname plumbing.ReferenceName, oid plumbing.Hash, r io.Reader, size int64) error {
+	start := time.Now()
+	cr := &countingReader{Reader: r}
+	err := t.Transport.PutObject(ctx, refname, oid, cr, size)
+	t.record(ctx, "put-object", 1, cr.n, start, err)
+	return err
+}
+
+func (t *loggedTransport) ObjectsExists(ctx context.Context, oids []plumbing.Hash) ([]bool, error) {
+	start := time.Now()
+	exists, err := t.Transport.ObjectsExists(ctx, oids)
+	t.record(ctx, "objects-exists", len(oids), 0, start, err)
+	return exists, err
+}
+
+func (t *loggedTransport) PutDelta(ctx context.Context, refname plumbing.ReferenceName, oid, base plumbing.Hash, r io.Reader, size int64) error {
+	start := time.Now()
+	cr := &countingReader{Reader: r}
+	err := t.Transport.PutDelta(ctx, refname, oid, base, cr, size)
+	t.record(ctx, "put-delta", 1, cr.n, start, err)
+	return err
+}
+
+func (t *loggedTransport) SetDefaultBranch(ctx context.Context, branch string) (*transport.DefaultBranchResponse, error) {
+	start := time.Now()
+	resp, err := t.Transport.SetDefaultBranch(ctx, branch)
+	t.record(ctx, "set-default-branch", 0, 0, start, err)
+	return resp, err
+}
+
+func (t *loggedTransport) FetchTemplates(ctx context.Context, have string) (*transport.Templates, error) {
+	start := time.Now()
+	templates, err := t.Transport.FetchTemplates(ctx, have)
+	t.record(ctx, "fetch-templates", 0, 0, start, err)
+	return templates, err
+}
+
+type TransferLogOptions struct {
+	Last time.Duration
+	JSON bool
+}
+
+func formatTransferDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+// ShowTransferLog prints the transport requests recorded within opts.Last, all records when it is zero.
+func (r *Repository) ShowTransferLog(ctx context.Context, opts *TransferLogOptions) error {
+	var since time.Time
+	if opts.Last > 0 {
+		since = time.Now().Add(-opts.Last)
+	}
+	p := transferLogPath(r.zetaDir)
+	records, err := transferlog.Read(p, since)
+	if err != nil {
+		die_error("read transfer log: %v", err)
+		return err
+	}
+	if opts.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+	if len(records) == 0 {
+		fmt.Fprintf(os.Stderr, "%s\n", W("No transfers recorded"))
+		return nil
+	}
+	var total, failed, retries int64
+	var elapsed time.Duration
+	endpoints := make(map[string]bool)
+	fmt.Fprintf(os.Stdout, "%-19s %-18s %8s %10s %10s %7s %6s  %s\n", "time", "operation", "objects", "bytes", "duration", "retries", "status", "proxy")
+	for _, e := range records {
+		fmt.Fprintf(os.Stdout, "%-19s %-18s %8d %10s %10s %7d %6s  %s\n", e.Time.Local().Format(time.DateTime), e.Operation, e.Objects,
+			strengthen.FormatSize(e.Bytes), formatTransferDuration(e.Duration()), e.Retries, e.Status, e.Proxy)
+		if len(e.Error) != 0 {
+			fmt.Fprintf(os.Stdout, "    %s\n", e.Error)
+			failed++
+		}
+		total += e.Bytes
+		retries += int64(e.Retries)
+		elapsed += e.Duration()
+		endpoints[e.Endpoint] = true
+	}
+	fmt.Fprintf(os.Stdout, "\n%s %d, %s %s, %s %s, %s %d, %s %d\n", W("requests:"), len(records), W("transferred:"), strengthen.FormatSize(total),
+		W("time spent:"), formatTransferDuration(elapsed), W("retries:"), retries, W("failed:"), failed)
+	for _, e := range slices.Sorted(maps.Keys(endpoints)) {
+		fmt.Fprintf(os.Stdout, "%s %s\n", W("endpoint:"), e)
+	}
+	return nil
+}
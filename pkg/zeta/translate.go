@@ -0,0 +1,283 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/tr"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+// Read-through translation: remotes which do not speak the zeta protocol (eg: git+https://) are fetched by a
+// Translator, the translator downloads the remote and converts the new objects into the repository. Translated
+// remotes are read-only, they support zeta checkout, fetch, pull and switch.
+
+var (
+	ErrTranslatedRemote = errors.New("remote is translated and read-only, only checkout, fetch, pull and switch are supported")
+)
+
+type TranslateOptions struct {
+	Remote  string
+	Quiet   bool
+	Verbose bool
+}
+
+// Translation: the references of a translated remote.
+type Translation struct {
+	// References: branches and tags of the remote, tags are not peeled
+	References map[plumbing.ReferenceName]plumbing.Hash
+	// DefaultBranch: default branch of the remote, empty when unknown
+	DefaultBranch string
+}
+
+type Translator interface {
+	// Translate downloads the remote and writes the objects of every branch and tag into the repository, objects
+	// converted by earlier calls are not converted again.
+	Translate(ctx context.Context, r *Repository, opts *TranslateOptions) (*Translation, error)
+}
+
+var (
+	translatorsMu sync.RWMutex
+	translators   = make(map[string]Translator)
+)
+
+// RegisterTranslator registers t for the remotes using one of the URL schemes.
+func RegisterTranslator(t Translator, schemes ...string) {
+	translatorsMu.Lock()
+	defer translatorsMu.Unlock()
+	for _, scheme := range schemes {
+		translators[strings.ToLower(scheme)] = t
+	}
+}
+
+func findTranslator(remote string) Translator {
+	scheme, _, ok := strings.Cut(remote, "://")
+	if !ok {
+		return nil
+	}
+	translatorsMu.RLock()
+	defer translatorsMu.RUnlock()
+	return translators[strings.ToLower(scheme)]
+}
+
+// IsTranslatedRemote reports whether the remote is fetched through a translator.
+func IsTranslatedRemote(remote string) bool {
+	return findTranslator(remote) != nil
+}
+
+func (r *Repository) peelToCommit(ctx context.Context, oid plumbing.Hash) (plumbing.Hash, error) {
+	for {
+		t, err := r.odb.Tag(ctx, oid)
+		if backend.IsErrMismatchedObjectType(err) {
+			return oid, nil
+		}
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if t.ObjectType != object.CommitObject && t.ObjectType != object.TagObject {
+			return plumbing.ZeroHash, fmt.Errorf("tag '%s' does not point to a commit", t.Name)
+		}
+		oid = t.Object
+	}
+}
+
+// translate converts the remote and updates the remote-tracking branches, tags which do not exist locally are
+// created, existing tags are only moved with force.
+func (r *Repository) translate(ctx context.Context, t Translator, force bool) (*Translation, error) {
+	result, err := t.Translate(ctx, r, &TranslateOptions{Remote: r.Core.Remote, Quiet: r.quiet, Verbose: r.verbose})
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintln(os.Stderr, tr.W("canceled"))
+			return nil, err
+		}
+		die_error("translate remote '%s': %v", r.cleanedRemote(), err)
+		return nil, err
+	}
+	names := make([]plumbing.ReferenceName, 0, len(result.References))
+	for name := range result.References {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		target := result.References[name]
+		switch {
+		case name.IsBranch():
+			originBranch := plumbing.NewRemoteReferenceName(plumbing.Origin, name.BranchName())
+			if err := r.Update(plumbing.NewHashReference(originBranch, target), nil); err != nil {
+				die_error("update-ref '%s' error: %v", originBranch, err)
+				return nil, err
+			}
+		case name.IsTag():
+			if old, err := r.Reference(name); err == nil && old.Hash() == target {
+				continue
+			}
+			if err := r.updateTagReference(ctx, name, target, force); err != nil && !errors.Is(err, ErrAborting) {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// fetchTranslated: DoFetch of a translated remote, every branch and tag is translated, refname selects FETCH_HEAD.
+func (r *Repository) fetchTranslated(ctx context.Context, t Translator, refname plumbing.ReferenceName, opts *DoFetchOptions) (*FetchResult, error) {
+	result, err := r.translate(ctx, t, opts.Force)
+	if err != nil {
+		return nil, err
+	}
+	if refname == plumbing.HEAD && len(result.DefaultBranch) != 0 {
+		refname = plumbing.NewBranchReferenceName(result.DefaultBranch)
+	}
+	want, ok := result.References[refname]
+	if !ok {
+		if !plumbing.ValidateHashHex(opts.Name) || !r.odb.Exists(plumbing.NewHash(opts.Name), true) {
+			die_error("couldn't find remote ref %s", opts.Name)
+			return nil, transport.ErrReferenceNotExist
+		}
+		refname = plumbing.ReferenceName(opts.Name)
+		want = plumbing.NewHash(opts.Name)
+	}
+	ref := &transport.Reference{Remote: r.Core.Remote, Name: refname, Hash: want.String()}
+	if len(result.DefaultBranch) != 0 {
+		ref.HEAD = string(plumbing.NewBranchReferenceName(result.DefaultBranch))
+	}
+	if refname.IsTag() {
+		peeled, err := r.peelToCommit(ctx, want)
+		if err != nil {
+			die_error("peel tag '%s': %v", refname.TagName(), err)
+			return nil, err
+		}
+		if peeled != want {
+			ref.Peeled = peeled.String()
+		}
+	}
+	if err := r.odb.SpecReferenceUpdate(odb.FETCH_HEAD, want); err != nil {
+		die_error("update FETCH_HEAD: %v", err)
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "From: %s\n", r.cleanedRemote())
+	switch {
+	case refname.IsBranch():
+		fmt.Fprintf(os.Stderr, "* branch %s -> FETCH_HEAD\n", refname.BranchName())
+	case refname.IsTag():
+	default:
+		fmt.Fprintf(os.Stderr, "* %s -> FETCH_HEAD\n", refname)
+	}
+	return &FetchResult{Reference: ref, FETCH_HEAD: want}, nil
+}
+
+// newTranslated: zeta checkout of a translated remote. The whole history is converted, --depth is ignored and
+// --narrow is not supported.
+func newTranslated(ctx context.Context, t Translator, opts *NewOptions) (*Repository, error) {
+	if len(opts.NarrowDirs) != 0 {
+		die("narrow checkout is not supported by translated remotes")
+		return nil, ErrTranslatedRemote
+	}
+	repoName := strings.TrimSuffix(path.Base(strings.TrimSuffix(opts.Remote, "/")), ".git")
+	destination, exists, err := checkDestination(repoName, opts.Destination, true)
+	if err != nil {
+		return nil, err
+	}
+	var checkoutSuccess bool
+	defer func() {
+		if checkoutSuccess {
+			return
+		}
+		if exists {
+			_ = os.RemoveAll(filepath.Join(destination, ".zeta"))
+			return
+		}
+		_ = os.RemoveAll(destination)
+	}()
+	r, err := Init(ctx, &InitOptions{Worktree: destination, MustEmpty: true, Values: opts.Values, Quiet: opts.Quiet, Verbose: opts.Verbose})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if checkoutSuccess {
+			return
+		}
+		_ = r.Close()
+	}()
+	values := map[string]any{"core.remote": opts.Remote}
+	if len(opts.SparseDirs) != 0 {
+		values["core.sparse"] = opts.SparseDirs
+	}
+	if err := config.UpdateLocal(r.zetaDir, &config.UpdateOptions{Values: values}); err != nil {
+		fmt.Fprintf(os.Stderr, "encode config error: %v\n", err)
+		return nil, err
+	}
+	r.Core.Remote = opts.Remote
+	r.Core.SparseDirs = opts.SparseDirs
+	fmt.Fprintf(os.Stderr, W("Checkout into '%s'...\n"), filepath.Base(destination))
+	result, err := r.translate(ctx, t, false)
+	if err != nil {
+		return nil, err
+	}
+	refname := plumbing.HEAD
+	switch {
+	case len(opts.TagName) != 0:
+		refname = plumbing.NewTagReferenceName(opts.TagName)
+	case len(opts.Branch) != 0 && len(opts.Commit) == 0:
+		refname = plumbing.NewBranchReferenceName(opts.Branch)
+	case strings.HasPrefix(opts.Refname, plumbing.ReferencePrefix):
+		refname = plumbing.ReferenceName(opts.Refname)
+		switch {
+		case refname.IsBranch():
+			opts.Branch = refname.BranchName()
+		case refname.IsTag():
+			opts.TagName = refname.TagName()
+		}
+	}
+	if refname == plumbing.HEAD && len(result.DefaultBranch) != 0 {
+		refname = plumbing.NewBranchReferenceName(result.DefaultBranch)
+	}
+	target, ok := result.References[refname]
+	if len(opts.Commit) != 0 {
+		target = plumbing.NewHash(opts.Commit)
+		if !r.odb.Exists(target, true) {
+			die_error("commit '%s' not found in remote", opts.Commit)
+			return nil, plumbing.NoSuchObject(target)
+		}
+	} else if !ok {
+		die_error("couldn't find remote ref %s", refname)
+		return nil, transport.ErrReferenceNotExist
+	}
+	commit, err := r.peelToCommit(ctx, target)
+	if err != nil {
+		die_error("peel '%s': %v", refname, err)
+		return nil, err
+	}
+	branchSwitched := opts.Branch
+	if len(opts.Commit) == 0 && len(branchSwitched) == 0 && len(opts.TagName) == 0 && refname.IsBranch() {
+		branchSwitched = refname.BranchName()
+	}
+	so := &SwitchOptions{Force: true, ForceCreate: true, firstSwitch: true, one: opts.One}
+	if len(branchSwitched) != 0 {
+		if err = r.SwitchNewBranch(ctx, branchSwitched, commit.String(), so); err != nil {
+			return nil, err
+		}
+		checkoutSuccess = true
+		return r, nil
+	}
+	if err = r.SwitchDetach(ctx, commit.String(), so); err != nil {
+		return nil, err
+	}
+	checkoutSuccess = true
+	return r, nil
+}
@@ -7,6 +7,9 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/antgroup/hugescm/modules/plumbing"
@@ -16,13 +19,15 @@ import (
 type CommitTreeOptions struct {
 	Tree plumbing.Hash
 	// Author is the author's signature of the commit. If Author is empty the
-	// Name and Email is read from the config, and time.Now it's used as When.
+	// Name and Email is read from the config. If When is zero ZETA_AUTHOR_DATE
+	// or time.Now is used.
 	Author object.Signature
 	// Committer is the committer's signature of the commit. If Committer is
-	// nil the Author signature is used.
+	// empty the Name and Email is read from the config. If When is zero
+	// ZETA_COMMITTER_DATE or the time of the Author is used.
 	Committer object.Signature
-	// Parents are the parents commits for the new commit, by default when
-	// len(Parents) is zero, the hash of HEAD reference is used.
+	// Parents are the parents commits for the new commit, a root commit is
+	// created when len(Parents) is zero.
 	Parents []plumbing.Hash
 	// SignKey denotes a key to sign the commit with. A nil value here means the
 	// commit will not be signed. The private key must be present and already
@@ -34,15 +39,47 @@ type CommitTreeOptions struct {
 }
 
 func (r *Repository) CommitTree(ctx context.Context, opts *CommitTreeOptions) (plumbing.Hash, error) {
-	if !r.odb.Exists(opts.Tree, false) {
+	if opts.Tree != plumbing.EmptyTree && !r.odb.Exists(opts.Tree, true) {
 		return plumbing.ZeroHash, plumbing.NoSuchObject(opts.Tree)
 	}
 	for _, p := range opts.Parents {
 		if p.IsZero() {
 			return plumbing.ZeroHash, errors.New("bad object")
 		}
+		if !r.odb.Exists(p, true) {
+			return plumbing.ZeroHash, plumbing.NoSuchObject(p)
+		}
+	}
+	if len(strings.TrimSpace(opts.Message)) == 0 {
+		return plumbing.ZeroHash, errors.New("empty commit message")
+	}
+	o := *opts
+	if len(o.Author.Name) == 0 || len(o.Committer.Name) == 0 {
+		cc := &CommitOptions{}
+		if err := cc.loadConfigAuthorAndCommitter(r); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if len(o.Author.Name) == 0 {
+			o.Author.Name, o.Author.Email = cc.Author.Name, cc.Author.Email
+		}
+		if len(o.Committer.Name) == 0 {
+			o.Committer.Name, o.Committer.Email = cc.Committer.Name, cc.Committer.Email
+		}
+	}
+	if o.Author.When.IsZero() {
+		o.Author.When = time.Now()
+		if date, ok := os.LookupEnv(ENV_ZETA_AUTHOR_DATE); ok {
+			o.Author.When = fallbackParseTime(date)
+		}
+	}
+	if o.Committer.When.IsZero() {
+		o.Committer.When = o.Author.When
+		if date, ok := os.LookupEnv(ENV_ZETA_COMMITTER_DATE); ok {
+			o.Committer.When = fallbackParseTime(date)
+		}
 	}
-	return r.commitTree(ctx, opts)
+	o.Message = strings.TrimRight(o.Message, "\n") + "\n"
+	return r.commitTree(ctx, &o)
 }
 
 func (r *Repository) commitTree(ctx context.Context, opts *CommitTreeOptions) (plumbing.Hash, error) {
@@ -0,0 +1,417 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/command"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/transport/http"
+	"github.com/antgroup/hugescm/pkg/version"
+	"golang.org/x/crypto/ssh"
+)
+
+// Releases are published to update.endpoint as:
+//
+//	manifest.json      {"version": "0.19.0", "assets": [{"os": "linux", "arch": "amd64", "url": "zeta-linux-amd64", "size": 1, "sha256": "..."}]}
+//	manifest.json.sig  base64 ed25519 signature of manifest.json
+//
+// Asset urls are resolved against the manifest url. The manifest is only trusted when signed by one of update.publicKeys.
+const (
+	releaseManifestName = "manifest.json"
+	releaseManifestMax  = 1 << 20
+	// previous executable, restored by zeta update-self --rollback
+	previousSuffix = ".old"
+)
+
+var (
+	ErrUpdateNotConfigured  = errors.New("update.endpoint and update.publicKeys are required")
+	ErrBadManifestSignature = errors.New("release manifest signature verification failed")
+	ErrNoReleaseAsset       = errors.New("no release for this platform")
+	ErrNoPreviousVersion    = errors.New("no previous version to roll back to")
+)
+
+type releaseAsset struct {
+	OS     string `json:"os"`
+	Arch   string `json:"arch"`
+	URL    string `json:"url"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type releaseManifest struct {
+	Version string          `json:"version"`
+	Assets  []*releaseAsset `json:"assets"`
+}
+
+func (m *releaseManifest) asset(goos, goarch string) (*releaseAsset, error) {
+	for _, a := range m.Assets {
+		if a.OS == goos && a.Arch == goarch {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s/%s", ErrNoReleaseAsset, goos, goarch)
+}
+
+func parseUpdatePublicKeys(keys []string) ([]ed25519.PublicKey, error) {
+	publicKeys := make([]ed25519.PublicKey, 0, len(keys))
+	for _, k := range keys {
+		pk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k))
+		if err != nil {
+			return nil, fmt.Errorf("bad update.publicKeys '%s': %w", k, err)
+		}
+		cpk, ok := pk.(ssh.CryptoPublicKey)
+		if !ok {
+			return nil, fmt.Errorf("update.publicKeys: key type %s not supported", pk.Type())
+		}
+		edk, ok := cpk.CryptoPublicKey().(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("update.publicKeys: key type %s not supported, use ed25519", pk.Type())
+		}
+		publicKeys = append(publicKeys, edk)
+	}
+	return publicKeys, nil
+}
+
+// verifyReleaseManifest checks the signature before the manifest is decoded.
+func verifyReleaseManifest(data, signature []byte, publicKeys []ed25519.PublicKey) (*releaseManifest, error) {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, ErrBadManifestSignature
+	}
+	verified := false
+	for _, k := range publicKeys {
+		if ed25519.Verify(k, data, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrBadManifestSignature
+	}
+	m := &releaseManifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("decode release manifest: %w", err)
+	}
+	if len(m.Version) == 0 {
+		return nil, errors.New("release manifest has no version")
+	}
+	return m, nil
+}
+
+// compareVersion compares dotted versions, a leading 'v' is ignored and a pre-release (1.2.0-rc1) sorts before the
+// release. Unparsable segments are 0, so a development build is older than any release.
+func compareVersion(a, b string) int {
+	parse := func(s string) ([]int, bool) {
+		s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+		s, pre, _ := strings.Cut(s, "-")
+		var n []int
+		for p := range strings.SplitSeq(s, ".") {
+			i, _ := strconv.Atoi(p)
+			n = append(n, i)
+		}
+		return n, len(pre) != 0
+	}
+	na, preA := parse(a)
+	nb, preB := parse(b)
+	for i := range max(len(na), len(nb)) {
+		var x, y int
+		if i < len(na) {
+			x = na[i]
+		}
+		if i < len(nb) {
+			y = nb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case preA && !preB:
+		return -1
+	case !preA && preB:
+		return 1
+	}
+	return 0
+}
+
+// replaceExecutable moves exe to previous and src to exe, exe is restored when the second rename fails. Renaming a
+// running executable is allowed on all platforms, overwriting it is not allowed on Windows.
+func replaceExecutable(exe, src, previous string) error {
+	if err := os.Remove(previous); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(exe, previous); err != nil {
+		return err
+	}
+	if err := os.Rename(src, exe); err != nil {
+		if rollbackErr := os.Rename(previous, exe); rollbackErr != nil {
+			return fmt.Errorf("replace executable: %w, restore '%s' error: %v", err, previous, rollbackErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// rollbackExecutable swaps exe and previous, so a second rollback returns to the updated version.
+func rollbackExecutable(exe, previous string) error {
+	if _, err := os.Stat(previous); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNoPreviousVersion
+		}
+		return err
+	}
+	staged := exe + ".rollback"
+	if err := os.Rename(previous, staged); err != nil {
+		return err
+	}
+	if err := replaceExecutable(exe, staged, previous); err != nil {
+		_ = os.Rename(staged, previous)
+		return err
+	}
+	return nil
+}
+
+type UpdateSelfOptions struct {
+	CWD      string
+	Values   []string
+	Check    bool // only report whether a newer version is available
+	Force    bool // install the release even if it is not newer
+	Rollback bool // restore the executable replaced by the last update
+	Verbose  bool
+}
+
+type selfUpdater struct {
+	*UpdateSelfOptions
+	endpoint   string
+	publicKeys []ed25519.PublicKey
+	downloader http.Downloader
+}
+
+func newSelfUpdater(opts *UpdateSelfOptions) (*selfUpdater, error) {
+	cfg, err := LoadConfig(opts.CWD, opts.Values)
+	if err != nil {
+		return nil, err
+	}
+	// the release endpoint and its signing keys are trust anchors: a cloned
+	// repository must not be able to replace them, so they are only taken
+	// from the system and global config, -c values and the environment.
+	baseline, err := config.LoadBaseline()
+	if err != nil {
+		return nil, err
+	}
+	values := valuesMapArray(opts.Values)
+	u := &selfUpdater{UpdateSelfOptions: opts, endpoint: baseline.SelfUpdate.Endpoint}
+	if s, ok := getFromValueOrEnv("update.endpoint", ENV_ZETA_UPDATE_ENDPOINT, values); ok {
+		u.endpoint = s
+	}
+	keys := []string(baseline.SelfUpdate.PublicKeys)
+	if sa, ok := getStringsFromValues("update.publicKeys", values); ok {
+		keys = sa
+	}
+	if len(u.endpoint) == 0 || len(keys) == 0 {
+		return nil, ErrUpdateNotConfigured
+	}
+	if u.publicKeys, err = parseUpdatePublicKeys(keys); err != nil {
+		return nil, err
+	}
+	proxyURL := cfg.Transport.ExternalProxy
+	if s, ok := getFromValueOrEnv("transport.externalProxy", ENV_ZETA_TRANSPORT_EXTERNAL_PROXY, values); ok && len(s) != 0 {
+		proxyURL = s
+	}
+	u.downloader = http.NewDownloader(opts.Verbose, parseInsecureSkipTLS(cfg, values), proxyURL)
+	return u, nil
+}
+
+func (u *selfUpdater) fetch(ctx context.Context, href string) ([]byte, error) {
+	sr, err := u.downloader.Download(ctx, &transport.Representation{Href: href}, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer sr.Close() // nolint
+	data, err := io.ReadAll(io.LimitReader(sr, releaseManifestMax+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > releaseManifestMax {
+		return nil, fmt.Errorf("'%s' is too large", href)
+	}
+	return data, nil
+}
+
+func (u *selfUpdater) manifest(ctx context.Context) (*releaseManifest, *url.URL, error) {
+	base, err := url.Parse(strings.TrimSuffix(u.endpoint, "/") + "/" + releaseManifestName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad update.endpoint '%s': %w", u.endpoint, err)
+	}
+	data, err := u.fetch(ctx, base.String())
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch release manifest: %w", err)
+	}
+	signature, err := u.fetch(ctx, base.String()+".sig")
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch release manifest signature: %w", err)
+	}
+	m, err := verifyReleaseManifest(data, signature, u.publicKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, base, nil
+}
+
+// download saves the asset next to exe, so it can be renamed to exe, and checks its size and checksum.
+func (u *selfUpdater) download(ctx context.Context, base *url.URL, a *releaseAsset, exe string) (string, error) {
+	ref, err := url.Parse(a.URL)
+	if err != nil {
+		return "", fmt.Errorf("bad asset url '%s': %w", a.URL, err)
+	}
+	want, err := hex.DecodeString(a.SHA256)
+	if err != nil || len(want) != sha256.Size {
+		return "", fmt.Errorf("bad asset sha256 '%s'", a.SHA256)
+	}
+	sr, err := u.downloader.Download(ctx, &transport.Representation{Href: base.ResolveReference(ref).String(), CompressedSize: a.Size}, 0)
+	if err != nil {
+		return "", err
+	}
+	defer sr.Close() // nolint
+	fd, err := os.CreateTemp(filepath.Dir(exe), ".zeta-update-*"+filepath.Ext(exe))
+	if err != nil {
+		return "", err
+	}
+	tempName := fd.Name()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(fd, h), sr)
+	if closeErr := fd.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n != a.Size {
+		err = fmt.Errorf("downloaded %d bytes, expected %d", n, a.Size)
+	}
+	if err == nil && !bytes.Equal(h.Sum(nil), want) {
+		err = errors.New("sha256 checksum mismatch")
+	}
+	if err == nil {
+		err = os.Chmod(tempName, 0755)
+	}
+	if err != nil {
+		_ = os.Remove(tempName)
+		return "", err
+	}
+	return tempName, nil
+}
+
+// verifyExecutable runs the downloaded binary, a binary built for another platform or a truncated binary fails here
+// instead of after it replaced the current executable.
+func verifyExecutable(ctx context.Context, p string, expected string) error {
+	newCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	cmd := command.NewFromOptions(newCtx, &command.RunOpts{
+		Environ:   os.Environ(),
+		Stderr:    os.Stderr,
+		NoSetpgid: true,
+	}, p, "version", "--json")
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("run downloaded executable: %w", err)
+	}
+	var info struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return fmt.Errorf("run downloaded executable: %w", err)
+	}
+	if compareVersion(info.Version, expected) != 0 {
+		return fmt.Errorf("downloaded executable reports version %s, expected %s", info.Version, expected)
+	}
+	return nil
+}
+
+func currentExecutable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// UpdateSelf replaces the running zeta with the release published to update.endpoint.
+func UpdateSelf(ctx context.Context, opts *UpdateSelfOptions) error {
+	exe, err := currentExecutable()
+	if err != nil {
+		die_error("resolve executable: %v", err)
+		return err
+	}
+	previous := exe + previousSuffix
+	if opts.Rollback {
+		if err := rollbackExecutable(exe, previous); err != nil {
+			die_error("rollback: %v", err)
+			return err
+		}
+		fmt.Fprintf(os.Stderr, W("Restored the previous zeta, the replaced version is kept as '%s'\n"), previous)
+		return nil
+	}
+	u, err := newSelfUpdater(opts)
+	if err != nil {
+		die_error("%v", err)
+		return err
+	}
+	m, base, err := u.manifest(ctx)
+	if err != nil {
+		die_error("%v", err)
+		return err
+	}
+	current := version.GetVersion()
+	if compareVersion(m.Version, current) <= 0 && !opts.Force {
+		fmt.Fprintf(os.Stderr, W("zeta %s is up to date\n"), current)
+		return nil
+	}
+	a, err := m.asset(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		die_error("%v", err)
+		return err
+	}
+	if opts.Check {
+		fmt.Fprintf(os.Stdout, W("zeta %s is available (current %s)\n"), m.Version, current)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, W("Downloading zeta %s (%s)\n"), m.Version, strengthen.FormatSize(a.Size))
+	tempName, err := u.download(ctx, base, a, exe)
+	if err != nil {
+		die_error("download zeta %s: %v", m.Version, err)
+		return err
+	}
+	defer os.Remove(tempName) // nolint
+	if err := verifyExecutable(ctx, tempName, m.Version); err != nil {
+		die_error("%v", err)
+		return err
+	}
+	if err := replaceExecutable(exe, tempName, previous); err != nil {
+		die_error("replace '%s': %v", exe, err)
+		return err
+	}
+	fmt.Fprintf(os.Stderr, W("Updated zeta %s -> %s, run 'zeta update-self --rollback' to restore the previous version\n"), current, m.Version)
+	return nil
+}
@@ -0,0 +1,168 @@
+package zeta
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/zeta/config"
+	"github.com/antgroup/hugescm/pkg/transport/http"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCompareVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"0.19.0", "0.18.9", 1},
+		{"v0.18.2", "0.18.2", 0},
+		{"0.18", "0.18.0", 0},
+		{"0.18.10", "0.18.9", 1},
+		{"1.0.0-rc1", "1.0.0", -1},
+		{"1.0.0", "", 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersion(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersion(%q, %q) = %d; want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSelfUpdater(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := parseUpdatePublicKeys([]string{string(ssh.MarshalAuthorizedKey(sshKey))})
+	if err != nil {
+		t.Fatalf("parse public keys error: %v", err)
+	}
+	binary := []byte("#!/bin/sh\necho zeta\n")
+	sum := sha256.Sum256(binary)
+	manifest, _ := json.Marshal(&releaseManifest{
+		Version: "9.0.0",
+		Assets: []*releaseAsset{
+			{OS: "linux", Arch: "amd64", URL: "bin/zeta-linux-amd64", Size: int64(len(binary)), SHA256: hex.EncodeToString(sum[:])},
+			{OS: "linux", Arch: "arm64", URL: "bin/zeta-linux-arm64", Size: int64(len(binary)), SHA256: hex.EncodeToString(make([]byte, sha256.Size))},
+		},
+	})
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, manifest))
+	mux := nethttp.NewServeMux()
+	mux.HandleFunc("/release/manifest.json", func(w nethttp.ResponseWriter, r *nethttp.Request) { _, _ = w.Write(manifest) })
+	mux.HandleFunc("/release/manifest.json.sig", func(w nethttp.ResponseWriter, r *nethttp.Request) { _, _ = w.Write([]byte(signature)) })
+	mux.HandleFunc("/release/bin/", func(w nethttp.ResponseWriter, r *nethttp.Request) { _, _ = w.Write(binary) })
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	u := &selfUpdater{UpdateSelfOptions: &UpdateSelfOptions{}, endpoint: srv.URL + "/release/", publicKeys: keys, downloader: http.NewDownloader(false, false, "")}
+	m, base, err := u.manifest(t.Context())
+	if err != nil {
+		t.Fatalf("fetch manifest error: %v", err)
+	}
+	if _, err := m.asset("windows", "amd64"); !errors.Is(err, ErrNoReleaseAsset) {
+		t.Fatalf("asset of missing platform should fail, got %v", err)
+	}
+	exe := filepath.Join(t.TempDir(), "zeta")
+	if err := os.WriteFile(exe, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := m.asset("linux", "arm64")
+	if _, err := u.download(t.Context(), base, a, exe); err == nil {
+		t.Fatalf("checksum mismatch should fail")
+	}
+	a, _ = m.asset("linux", "amd64")
+	tempName, err := u.download(t.Context(), base, a, exe)
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+	if err := replaceExecutable(exe, tempName, exe+previousSuffix); err != nil {
+		t.Fatalf("replace executable error: %v", err)
+	}
+	readExe := func() string {
+		data, _ := os.ReadFile(exe)
+		return string(data)
+	}
+	if readExe() != string(binary) {
+		t.Fatalf("executable not replaced")
+	}
+	if err := rollbackExecutable(exe, exe+previousSuffix); err != nil || readExe() != "old" {
+		t.Fatalf("rollback error: %v", err)
+	}
+	if err := rollbackExecutable(exe, exe+previousSuffix); err != nil || readExe() != string(binary) {
+		t.Fatalf("second rollback error: %v", err)
+	}
+
+	// a manifest signed by another key is rejected
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	signature = base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, manifest))
+	if _, _, err := u.manifest(t.Context()); !errors.Is(err, ErrBadManifestSignature) {
+		t.Fatalf("bad signature should fail, got %v", err)
+	}
+}
+
+func TestSelfUpdaterIgnoresRepositoryConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(config.ENV_ZETA_CONFIG_SYSTEM, filepath.Join(home, "system.toml"))
+	t.Setenv(ENV_ZETA_UPDATE_ENDPOINT, "")
+	os.Unsetenv(ENV_ZETA_UPDATE_ENDPOINT) // nolint
+	worktree := filepath.Join(t.TempDir(), "repo")
+	r, err := Init(t.Context(), &InitOptions{Worktree: worktree, Branch: "mainline", Quiet: true})
+	if err != nil {
+		t.Fatalf("init repo error: %v", err)
+	}
+	_ = r.Close()
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshKey)))
+	local := "\n[update]\nendpoint = \"https://attacker.example.io/zeta\"\npublicKeys = [\"" + authorizedKey + "\"]\n"
+	fd, err := os.OpenFile(filepath.Join(worktree, ".zeta", "zeta.toml"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fd.WriteString(local)
+	_ = fd.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := config.Load(filepath.Join(worktree, ".zeta")); err != nil || len(cfg.SelfUpdate.PublicKeys) != 1 {
+		t.Fatalf("repository config not written: %v", err)
+	}
+	if _, err := newSelfUpdater(&UpdateSelfOptions{CWD: worktree}); !errors.Is(err, ErrUpdateNotConfigured) {
+		t.Fatalf("repository update config should be ignored, got %v", err)
+	}
+
+	// the same settings in the global config are used
+	global := "[update]\nendpoint = \"https://zeta.example.io/zeta\"\npublicKeys = [\"" + authorizedKey + "\"]\n"
+	if err := os.WriteFile(filepath.Join(home, ".zeta.toml"), []byte(global), 0644); err != nil {
+		t.Fatal(err)
+	}
+	u, err := newSelfUpdater(&UpdateSelfOptions{CWD: worktree})
+	if err != nil {
+		t.Fatalf("new self updater error: %v", err)
+	}
+	if u.endpoint != "https://zeta.example.io/zeta" || len(u.publicKeys) != 1 {
+		t.Fatalf("unexpected updater: endpoint %q, %d keys", u.endpoint, len(u.publicKeys))
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/transport"
+)
+
+const (
+	RemoteUpToDate   = "up-to-date"
+	RemoteAhead      = "ahead"
+	RemoteBehind     = "behind"
+	RemoteDiverged   = "diverged"
+	RemoteNotFetched = "not-fetched" // the remote commit is not in the local repository
+	RemoteLocalOnly  = "local-only"  // never pushed
+	RemoteGone       = "gone"        // deleted from the remote after it was fetched
+	RemoteDiffers    = "differs"     // tags are never expected to move
+	RemoteUnknown    = "unknown"     // incomplete (shallow) history, ahead/behind can't be counted
+
+	TrackingStale       = "stale"        // remote-tracking ref is behind the remote
+	TrackingForcePushed = "force-pushed" // the remote was rewritten since the last fetch
+	TrackingGone        = "gone"         // remote-tracking ref of a deleted remote branch
+)
+
+type VerifyRemoteOptions struct {
+	JSON bool
+}
+
+type RemoteRefCheck struct {
+	Name     plumbing.ReferenceName `json:"name"`
+	Local    plumbing.Hash          `json:"local,omitzero"`
+	Remote   plumbing.Hash          `json:"remote,omitzero"`
+	Tracking plumbing.Hash          `json:"tracking,omitzero"`
+	Status   string                 `json:"status,omitempty"`
+	Ahead    int                    `json:"ahead,omitempty"`
+	Behind   int                    `json:"behind,omitempty"`
+	// TrackingStatus: state of refs/remotes/origin/<branch> compared to the remote
+	TrackingStatus string `json:"tracking_status,omitempty"`
+}
+
+// Problem: whether the ref needs attention. Local-only refs and local commits not pushed yet are normal.
+func (c *RemoteRefCheck) Problem() bool {
+	switch c.Status {
+	case RemoteBehind, RemoteDiverged, RemoteNotFetched, RemoteGone, RemoteDiffers, RemoteUnknown:
+		return true
+	}
+	return len(c.TrackingStatus) != 0
+}
+
+type MissingObject struct {
+	Path string        `json:"path"`
+	Hash plumbing.Hash `json:"hash"`
+}
+
+type VerifyRemoteReport struct {
+	Remote     string            `json:"remote"`
+	References []*RemoteRefCheck `json:"references"`
+	// Missing: objects referenced by HEAD or the index which are not in the local repository
+	Missing []*MissingObject `json:"missing,omitempty"`
+}
+
+func (rr *VerifyRemoteReport) Problems() int {
+	var n int
+	for _, c := range rr.References {
+		if c.Problem() {
+			n++
+		}
+	}
+	if len(rr.Missing) != 0 {
+		n++
+	}
+	return n
+}
+
+// countExclusive returns the number of commits reachable from c but not from the merge bases.
+func countExclusive(ctx context.Context, c *object.Commit, bases []*object.Commit) (int, error) {
+	ignore := make([]plumbing.Hash, 0, len(bases))
+	for _, b := range bases {
+		ignore = append(ignore, b.Hash)
+	}
+	var n int
+	iter := object.NewCommitPreorderIter(c, nil, ignore)
+	defer iter.Close()
+	for {
+		if _, err := iter.Next(ctx); err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			return n, err
+		}
+		n++
+	}
+}
+
+// divergence compares the local commit against the remote commit, both must be in the local repository.
+func divergence(ctx context.Context, local, remote *object.Commit) (status string, ahead, behind int) {
+	bases, err := local.MergeBase(ctx, remote)
+	if err != nil {
+		return RemoteUnknown, 0, 0
+	}
+	if len(bases) == 0 {
+		return RemoteDiverged, 0, 0
+	}
+	if ahead, err = countExclusive(ctx, local, bases); err != nil {
+		return RemoteUnknown, 0, 0
+	}
+	if behind, err = countExclusive(ctx, remote, bases); err != nil {
+		return RemoteUnknown, 0, 0
+	}
+	switch {
+	case ahead == 0 && behind == 0:
+		return RemoteUpToDate, 0, 0
+	case behind == 0:
+		return RemoteAhead, ahead, 0
+	case ahead == 0:
+		return RemoteBehind, 0, behind
+	}
+	return RemoteDiverged, ahead, behind
+}
+
+func (r *Repository) fetchRemoteTarget(ctx context.Context, t transport.Transport, refname plumbing.ReferenceName) (plumbing.Hash, error) {
+	ref, err := t.FetchReference(ctx, refname)
+	if errors.Is(err, transport.ErrReferenceNotExist) {
+		return plumbing.ZeroHash, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return plumbing.NewHash(ref.Hash), nil
+}
+
+// checkTracking compares refs/remotes/origin/<branch> with the current remote commit.
+func (r *Repository) checkTracking(ctx context.Context, c *RemoteRefCheck) {
+	switch {
+	case c.Tracking.IsZero() || c.Tracking == c.Remote:
+		return
+	case c.Remote.IsZero():
+		c.TrackingStatus = TrackingGone
+		return
+	}
+	c.TrackingStatus = TrackingStale
+	tracking, err := r.odb.Commit(ctx, c.Tracking)
+	if err != nil {
+		return
+	}
+	remote, err := r.odb.Commit(ctx, c.Remote)
+	if err != nil {
+		return
+	}
+	if ok, err := tracking.IsAncestor(ctx, remote); err == nil && !ok {
+		c.TrackingStatus = TrackingForcePushed
+	}
+}
+
+func (r *Repository) checkBranch(ctx context.Context, c *RemoteRefCheck) {
+	switch {
+	case c.Remote.IsZero() && !c.Tracking.IsZero():
+		c.Status = RemoteGone
+	case c.Remote.IsZero():
+		if !c.Local.IsZero() {
+			c.Status = RemoteLocalOnly
+		}
+	case c.Local.IsZero():
+		// remote-tracking ref without local branch
+	case c.Local == c.Remote:
+		c.Status = RemoteUpToDate
+	case !r.odb.Exists(c.Remote, true):
+		c.Status = RemoteNotFetched
+	default:
+		local, err := r.odb.Commit(ctx, c.Local)
+		if err != nil {
+			c.Status = RemoteUnknown
+			break
+		}
+		remote, err := r.odb.Commit(ctx, c.Remote)
+		if err != nil {
+			c.Status = RemoteUnknown
+			break
+		}
+		c.Status, c.Ahead, c.Behind = divergence(ctx, local, remote)
+	}
+	r.checkTracking(ctx, c)
+}
+
+// missingObjects checks HEAD, its root tree and the objects of the index which are expected to be present.
+func (r *Repository) missingObjects(ctx context.Context) ([]*MissingObject, error) {
+	missing := make([]*MissingObject, 0, 10)
+	current, err := r.Current()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		// unborn branch
+		return missing, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !r.odb.Exists(current.Hash(), true) {
+		return append(missing, &MissingObject{Path: string(plumbing.HEAD), Hash: current.Hash()}), nil
+	}
+	cc, err := r.odb.Commit(ctx, current.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if cc.Tree != plumbing.EmptyTree && !r.odb.Exists(cc.Tree, true) {
+		missing = append(missing, &MissingObject{Path: "HEAD^{tree}", Hash: cc.Tree})
+	}
+	idx, err := r.odb.Index()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range idx.Entries {
+		// sparse and intent-to-add entries have no local objects by design
+		if e.SkipWorktree || e.IntentToAdd {
+			continue
+		}
+		if !r.odb.Exists(e.Hash, e.Mode.IsFragments()) {
+			missing = append(missing, &MissingObject{Path: e.Name, Hash: e.Hash})
+		}
+	}
+	return missing, nil
+}
+
+func (r *Repository) verifyRemote(ctx context.Context) (*VerifyRemoteReport, error) {
+	rdb, err := r.References()
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.newTransport(ctx, transport.DOWNLOAD)
+	if err != nil {
+		return nil, err
+	}
+	rr := &VerifyRemoteReport{Remote: r.cleanedRemote()}
+	branches := make(map[string]*RemoteRefCheck)
+	var names []string
+	for _, ref := range rdb.References() {
+		if ref.Type() != plumbing.HashReference {
+			continue
+		}
+		name := ref.Name()
+		switch {
+		case name.IsBranch():
+			c := branches[name.BranchName()]
+			if c == nil {
+				c = &RemoteRefCheck{Name: name}
+				branches[name.BranchName()] = c
+				names = append(names, name.BranchName())
+			}
+			c.Local = ref.Hash()
+		case name.IsRemote():
+			branch, ok := strings.CutPrefix(string(name), string(plumbing.NewRemoteReferenceName(plumbing.Origin, "")))
+			if !ok || branch == string(plumbing.HEAD) {
+				continue
+			}
+			c := branches[branch]
+			if c == nil {
+				c = &RemoteRefCheck{Name: plumbing.NewBranchReferenceName(branch)}
+				branches[branch] = c
+				names = append(names, branch)
+			}
+			c.Tracking = ref.Hash()
+		case name.IsTag():
+			c := &RemoteRefCheck{Name: name, Local: ref.Hash()}
+			if c.Remote, err = r.fetchRemoteTarget(ctx, t, name); err != nil {
+				return nil, fmt.Errorf("fetch remote reference '%s': %w", name, err)
+			}
+			switch {
+			case c.Remote.IsZero():
+				c.Status = RemoteLocalOnly
+			case c.Remote == c.Local:
+				c.Status = RemoteUpToDate
+			default:
+				c.Status = RemoteDiffers
+			}
+			rr.References = append(rr.References, c)
+		}
+	}
+	checks := make([]*RemoteRefCheck, 0, len(names))
+	for _, branch := range names {
+		c := branches[branch]
+		if c.Remote, err = r.fetchRemoteTarget(ctx, t, c.Name); err != nil {
+			return nil, fmt.Errorf("fetch remote reference '%s': %w", c.Name, err)
+		}
+		r.checkBranch(ctx, c)
+		checks = append(checks, c)
+	}
+	rr.References = append(checks, rr.References...)
+	if rr.Missing, err = r.missingObjects(ctx); err != nil {
+		return nil, err
+	}
+	return rr, nil
+}
+
+func (c *RemoteRefCheck) describe() string {
+	var s string
+	switch c.Status {
+	case RemoteUpToDate:
+		s = W("up to date")
+	case RemoteAhead:
+		s = fmt.Sprintf(W("ahead %d"), c.Ahead)
+	case RemoteBehind:
+		s = fmt.Sprintf(W("behind %d"), c.Behind)
+	case RemoteDiverged:
+		if c.Ahead == 0 && c.Behind == 0 {
+			s = W("unrelated to the remote")
+		} else {
+			s = fmt.Sprintf(W("diverged, ahead %d, behind %d"), c.Ahead, c.Behind)
+		}
+	case RemoteNotFetched:
+		s = fmt.Sprintf(W("remote moved to %s which is not fetched"), shortHash(c.Remote))
+	case RemoteLocalOnly:
+		s = W("not on the remote")
+	case RemoteGone:
+		s = W("deleted from the remote")
+	case RemoteDiffers:
+		s = fmt.Sprintf(W("differs from the remote %s"), shortHash(c.Remote))
+	case RemoteUnknown:
+		s = W("history is incomplete, unable to compare")
+	}
+	var tracking string
+	switch c.TrackingStatus {
+	case TrackingStale:
+		tracking = W("remote-tracking ref is stale")
+	case TrackingForcePushed:
+		tracking = W("remote was force-pushed since the last fetch")
+	case TrackingGone:
+		tracking = W("remote-tracking ref is stale")
+	}
+	switch {
+	case len(s) == 0:
+		return tracking
+	case len(tracking) == 0:
+		return s
+	}
+	return s + ", " + tracking
+}
+
+// VerifyRemote cross-checks local branches, tags and remote-tracking refs against the remote, and reports objects
+// referenced by HEAD or the index which are missing locally.
+func (r *Repository) VerifyRemote(ctx context.Context, opts *VerifyRemoteOptions) error {
+	rr, err := r.verifyRemote(ctx)
+	if err != nil {
+		die_error("verify-remote: %v", err)
+		return err
+	}
+	if opts.JSON {
+		if err := json.NewEncoder(os.Stdout).Encode(rr); err != nil {
+			return err
+		}
+	} else {
+		_, _ = fmt.Fprintf(os.Stdout, "remote: %s\n", rr.Remote)
+		width := 0
+		for _, c := range rr.References {
+			width = max(width, len(c.Name.Short()))
+		}
+		for _, c := range rr.References {
+			kind := "branch"
+			if c.Name.IsTag() {
+				kind = "tag"
+			}
+			mark := " "
+			if c.Problem() {
+				mark = "!"
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "%s %-6s %-*s  %s\n", mark, kind, width, c.Name.Short(), c.describe())
+		}
+		if len(rr.Missing) != 0 {
+			_, _ = fmt.Fprintf(os.Stdout, W("! %d objects referenced by HEAD or the index are missing, run 'zeta fetch' to download them\n"), len(rr.Missing))
+			for _, m := range rr.Missing {
+				_, _ = fmt.Fprintf(os.Stdout, "    %s %s\n", shortHash(m.Hash), m.Path)
+			}
+		}
+	}
+	if n := rr.Problems(); n != 0 {
+		return &ErrExitCode{ExitCode: 1, Message: fmt.Sprintf("%d problems found", n)}
+	}
+	return nil
+}
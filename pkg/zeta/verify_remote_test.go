@@ -0,0 +1,41 @@
+package zeta
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+func TestDivergence(t *testing.T) {
+	_, f := newTestRepository(t, "divergence")
+	// base <- a1 <- a2 (local), base <- b1 (remote), unrelated root
+	base := f.commit("base\n", nil)
+	a2 := f.commit("a2\n", nil, f.commit("a1\n", nil, base))
+	b1 := f.commit("b1\n", nil, base)
+	root := f.commit("root\n", nil)
+	tests := []struct {
+		local, remote plumbing.Hash
+		status        string
+		ahead, behind int
+	}{
+		{a2, a2, RemoteUpToDate, 0, 0},
+		{a2, base, RemoteAhead, 2, 0},
+		{base, a2, RemoteBehind, 0, 2},
+		{a2, b1, RemoteDiverged, 2, 1},
+		{a2, root, RemoteDiverged, 0, 0},
+	}
+	for _, tt := range tests {
+		local, remote := f.object(tt.local), f.object(tt.remote)
+		status, ahead, behind := divergence(t.Context(), local, remote)
+		if got, want := fmt.Sprint(status, ahead, behind), fmt.Sprint(tt.status, tt.ahead, tt.behind); got != want {
+			t.Errorf("divergence(%s, %s) = %s; want %s", local.Message, remote.Message, got, want)
+		}
+	}
+	if c := (&RemoteRefCheck{Status: RemoteAhead}); c.Problem() {
+		t.Errorf("unpushed commits are not a problem")
+	}
+	if c := (&RemoteRefCheck{Status: RemoteUpToDate, TrackingStatus: TrackingForcePushed}); !c.Problem() {
+		t.Errorf("force-pushed remote should be reported")
+	}
+}
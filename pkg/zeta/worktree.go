@@ -4,7 +4,6 @@
 package zeta
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -253,6 +252,11 @@ func (w *Worktree) Prefetch(ctx context.Context, revision string, limit int64, s
 }
 
 func (w *Worktree) Reset(ctx context.Context, opts *ResetOptions) error {
+	unlock, err := w.lock("reset")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if opts.One {
 		w.missingNotFailure = true
 	}
@@ -362,6 +366,11 @@ func (w *Worktree) ResetSparsely(ctx context.Context, opts *ResetOptions, bar Pr
 }
 
 func (w *Worktree) ResetSpec(ctx context.Context, oid plumbing.Hash, pathSpec []string) error {
+	unlock, err := w.lock("reset")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	root, err := w.getTreeFromHash(ctx, oid)
 	if err != nil {
 		return err
@@ -782,18 +791,20 @@ func (w *Worktree) checkoutSymlink(ctx context.Context, name string, e *object.T
 		}
 		target = b.String()
 	}
-	err = w.fs.Symlink(target, name)
-	if err != nil && isSymlinkWindowsNonAdmin(err) {
-		mode, _ := e.Mode.ToOSFileMode()
-		var to *os.File
-		if to, err = w.fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()); err != nil {
+	if w.trustSymlinks() {
+		if err = w.fs.Symlink(target, name); err == nil || !isSymlinkWindowsNonAdmin(err) {
 			return err
 		}
-		defer to.Close() // nolint
-		_, err = to.WriteString(target)
+	}
+	// core.symlinks=false or no permission to create symlinks: write the target as a plain file
+	mode, _ := e.Mode.ToOSFileMode()
+	var to *os.File
+	if to, err = w.fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()); err != nil {
 		return err
 	}
-	return
+	defer to.Close() // nolint
+	_, err = to.WriteString(target)
+	return err
 }
 
 func (w *Worktree) addIndexFromFile(name string, h plumbing.Hash, mode filemode.FileMode, idx *indexBuilder) error {
@@ -825,6 +836,11 @@ var fillSystemInfo func(e *index.Entry, sys any)
 // Clean the worktree by removing untracked files.
 // An empty dir could be removed - this is what  `zeta clean -f -d .` does.
 func (w *Worktree) Clean(ctx context.Context, opts *CleanOptions) error {
+	unlock, err := w.lock("clean")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	s, err := w.Status(ctx, false)
 	if err != nil {
 		return err
@@ -881,156 +897,6 @@ func (w *Worktree) doClean(status Status, matcher ignore.Matcher, opts *CleanOpt
 	return nil
 }
 
-// GrepResult is structure of a grep result.
-type GrepResult struct {
-	// FileName is the name of file which contains match.
-	FileName string
-	// LineNumber is the line number of a file at which a match was found.
-	LineNumber int
-	// Content is the content of the file at the matching line.
-	Content string
-	// TreeName is the name of the tree (reference name/commit hash) at
-	// which the match was performed.
-	TreeName string
-}
-
-func (gr GrepResult) String() string {
-	return fmt.Sprintf("%s:%s:%d:%s", gr.TreeName, gr.FileName, gr.LineNumber, gr.Content)
-}
-
-// Grep performs grep on a repository.
-func (r *Repository) Grep(ctx context.Context, opts *GrepOptions) ([]GrepResult, error) {
-	if err := opts.validate(r); err != nil {
-		return nil, err
-	}
-
-	// Obtain commit hash from options (CommitHash or ReferenceName).
-	var commitHash plumbing.Hash
-	// treeName contains the value of TreeName in GrepResult.
-	var treeName string
-
-	if opts.ReferenceName != "" {
-		ref, err := r.ReferenceResolve(opts.ReferenceName)
-		if err != nil {
-			return nil, err
-		}
-		commitHash = ref.Hash()
-		treeName = opts.ReferenceName.String()
-	} else if !opts.CommitHash.IsZero() {
-		commitHash = opts.CommitHash
-		treeName = opts.CommitHash.String()
-	}
-
-	// Obtain a tree from the commit hash and get a tracked files iterator from
-	// the tree.
-	tree, err := r.getTreeFromCommitHash(ctx, commitHash)
-	if err != nil {
-		return nil, err
-	}
-	fileiter := tree.Files()
-
-	return findMatchInFiles(ctx, fileiter, treeName, opts)
-}
-
-// Grep performs grep on a worktree.
-func (w *Worktree) Grep(ctx context.Context, opts *GrepOptions) ([]GrepResult, error) {
-	return w.Repository.Grep(ctx, opts)
-}
-
-// findMatchInFiles takes a FileIter, worktree name and GrepOptions, and
-// returns a slice of GrepResult containing the result of regex pattern matching
-// in content of all the files.
-func findMatchInFiles(ctx context.Context, fileiter *object.FileIter, treeName string, opts *GrepOptions) ([]GrepResult, error) {
-	var results []GrepResult
-
-	err := fileiter.ForEach(ctx, func(file *object.File) error {
-		var fileInPathSpec bool
-
-		// When no pathspecs are provided, search all the files.
-		if len(opts.PathSpecs) == 0 {
-			fileInPathSpec = true
-		}
-
-		// Check if the file name matches with the pathspec. Break out of the
-		// loop once a match is found.
-		for _, pathSpec := range opts.PathSpecs {
-			if pathSpec != nil && pathSpec.MatchString(file.Name) {
-				fileInPathSpec = true
-				break
-			}
-		}
-
-		// If the file does not match with any of the pathspec, skip it.
-		if !fileInPathSpec {
-			return nil
-		}
-
-		if file.Size > opts.Limit {
-			// Ignore large file
-			return nil
-		}
-
-		grepResults, err := findMatchInFile(ctx, file, treeName, opts)
-		if err != nil {
-			return err
-		}
-		results = append(results, grepResults...)
-
-		return nil
-	})
-
-	return results, err
-}
-
-// findMatchInFile takes a single File, worktree name and GrepOptions,
-// and returns a slice of GrepResult containing the result of regex pattern
-// matching in the given file.
-func findMatchInFile(ctx context.Context, file *object.File, treeName string, opts *GrepOptions) ([]GrepResult, error) {
-	var grepResults []GrepResult
-
-	rc, _, err := file.OriginReader(ctx)
-	if err != nil {
-		return grepResults, err
-	}
-	defer rc.Close() // nolint
-
-	br := bufio.NewScanner(rc)
-	for lineNum := 0; br.Scan(); lineNum++ {
-		cnt := br.Text()
-		addToResult := false
-
-		// Match the patterns and content. Break out of the loop once a
-		// match is found.
-		for _, pattern := range opts.Patterns {
-			if pattern == nil {
-				continue
-			}
-
-			matched := pattern.MatchString(cnt)
-
-			// Normal mode: add to result if matched
-			// Invert mode: add to result if NOT matched
-			if matched != opts.InvertMatch {
-				addToResult = true
-				break
-			}
-		}
-
-		if addToResult {
-			grepResults = append(grepResults, GrepResult{
-				FileName:   file.Name,
-				LineNumber: lineNum + 1,
-				Content:    cnt,
-				TreeName:   treeName,
-			})
-		}
-	}
-	if err := br.Err(); err != nil {
-		return nil, err
-	}
-	return grepResults, nil
-}
-
 // will walk up the directory tree removing all encountered empty
 // directories, not just the one containing this file
 func rmFileAndDirsIfEmpty(fs vfs.VFS, name string) error {
@@ -1083,29 +949,42 @@ func removeDirIfEmpty(fs vfs.VFS, dir string) (bool, error) {
 
 type indexBuilder struct {
 	entries map[string]*index.Entry
+	// unmerged: conflict stages of paths switched with --merge
+	unmerged map[string][]*index.Entry
 }
 
 func newIndexBuilder(idx *index.Index) *indexBuilder {
-	entries := make(map[string]*index.Entry, len(idx.Entries))
-	for _, e := range idx.Entries {
-		entries[e.Name] = e
+	b := &indexBuilder{
+		entries:  make(map[string]*index.Entry, len(idx.Entries)),
+		unmerged: make(map[string][]*index.Entry),
 	}
-	return &indexBuilder{
-		entries: entries,
+	for _, e := range idx.Entries {
+		b.keep(e)
 	}
+	return b
 }
 
 func newUnlessIndexBuilder(idx *index.Index, m *Matcher) *indexBuilder {
-	entries := make(map[string]*index.Entry, len(idx.Entries))
+	b := &indexBuilder{
+		entries:  make(map[string]*index.Entry, len(idx.Entries)),
+		unmerged: make(map[string][]*index.Entry),
+	}
 	for _, e := range idx.Entries {
 		if m.Match(e.Name) {
 			continue
 		}
-		entries[e.Name] = e
+		b.keep(e)
 	}
-	return &indexBuilder{
-		entries: entries,
+	return b
+}
+
+// keep: conflict stages of a path are kept until the path is added or removed.
+func (b *indexBuilder) keep(e *index.Entry) {
+	if e.Stage != 0 {
+		b.unmerged[e.Name] = append(b.unmerged[e.Name], e)
+		return
 	}
+	b.entries[e.Name] = e
 }
 
 func (b *indexBuilder) Write(idx *index.Index) {
@@ -1113,12 +992,32 @@ func (b *indexBuilder) Write(idx *index.Index) {
 	for _, e := range b.entries {
 		idx.Entries = append(idx.Entries, e)
 	}
+	for _, stages := range b.unmerged {
+		idx.Entries = append(idx.Entries, stages...)
+	}
 }
 
 func (b *indexBuilder) Add(e *index.Entry) {
+	delete(b.unmerged, e.Name)
 	b.entries[e.Name] = e
 }
 
 func (b *indexBuilder) Remove(name string) {
-	delete(b.entries, filepath.ToSlash(name))
+	name = filepath.ToSlash(name)
+	delete(b.unmerged, name)
+	delete(b.entries, name)
+}
+
+// removeTrackedFiles removes all tracked files from the worktree and clears the index.
+func (w *Worktree) removeTrackedFiles() error {
+	idx, err := w.odb.Index()
+	if err != nil {
+		return err
+	}
+	for _, e := range idx.Entries {
+		if err := rmFileAndDirsIfEmpty(w.fs, e.Name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return w.odb.SetIndex(&index.Index{Version: index.EncodeVersionSupported})
 }
@@ -81,6 +81,9 @@ func (w *Worktree) excludeIgnoredChanges(changes merkletrie.Changes) merkletrie.
 				}
 			}
 		}
+		if w.suppressModeChange(&ch) {
+			continue
+		}
 		res = append(res, ch)
 	}
 	return res
@@ -8,9 +8,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/antgroup/hugescm/modules/diferenco"
 	"github.com/antgroup/hugescm/modules/merkletrie"
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/plumbing/filemode"
@@ -18,6 +20,7 @@ import (
 	"github.com/antgroup/hugescm/modules/term"
 	"github.com/antgroup/hugescm/modules/zeta/object"
 	"github.com/antgroup/hugescm/pkg/progress"
+	"github.com/antgroup/hugescm/pkg/tr"
 )
 
 var (
@@ -25,6 +28,11 @@ var (
 )
 
 func (w *Worktree) Checkout(ctx context.Context, opts *CheckoutOptions) error {
+	unlock, err := w.lock("checkout")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if opts.First {
 		return w.checkoutFirstTime(ctx, opts)
 	}
@@ -285,6 +293,12 @@ func (w *Worktree) checkout(ctx context.Context, opts *CheckoutOptions, bar Prog
 		}
 		overwrites = append(overwrites, p)
 	}
+	var merged []*checkoutMerged
+	if len(overwrites) != 0 && opts.Merge {
+		if merged, overwrites, err = w.mergeLocalChanges(ctx, overwrites, oldTree, newTree, current.Name(), opts); err != nil {
+			return err
+		}
+	}
 	if len(overwrites) != 0 {
 		die_error("Your local changes to the following files would be overwritten by checkout:")
 		for _, s := range overwrites {
@@ -309,7 +323,7 @@ func (w *Worktree) checkout(ctx context.Context, opts *CheckoutOptions, bar Prog
 	if err := w.checkoutIgnoreFiles(ctx, newTree, doNotCheckouts, bar); err != nil {
 		return err
 	}
-	return nil
+	return w.writeMerged(ctx, merged)
 }
 
 // Only call zeta checkout or migrate
@@ -589,3 +603,142 @@ func rearrangeChanges(changes merkletrie.Changes) merkletrie.Changes {
 	recs = append(recs, modified...)
 	return recs
 }
+
+type checkoutMerged struct {
+	name     string
+	mode     filemode.FileMode
+	text     string
+	conflict bool
+	// base, local and target are recorded as conflict stages 1, 2 and 3 when the merge conflicts
+	base   *object.TreeEntry
+	local  string
+	target *object.TreeEntry
+}
+
+func (w *Worktree) readWorktreeText(name string) (string, error) {
+	fi, err := w.fs.Lstat(name)
+	if err != nil {
+		return "", err
+	}
+	if !fi.Mode().IsRegular() {
+		return "", fmt.Errorf("'%s' is not a regular file", name)
+	}
+	fd, err := w.fs.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close() // nolint
+	text, _, err := diferenco.ReadUnifiedText(fd, fi.Size(), false)
+	return text, err
+}
+
+// mergeLocalChanges: three-way merge of local changes with the target tree, the base is the current commit.
+// Paths which cannot be merged (binary, symlink, added or deleted on either side) are returned as overwrites.
+func (w *Worktree) mergeLocalChanges(ctx context.Context, paths []string, oldTree, newTree *object.Tree, from plumbing.ReferenceName, opts *CheckoutOptions) ([]*checkoutMerged, []string, error) {
+	var diffAlgorithm diferenco.Algorithm
+	var err error
+	if algorithmName := w.diffAlgorithm(); len(algorithmName) != 0 {
+		if diffAlgorithm, err = diferenco.AlgorithmFromName(algorithmName); err != nil {
+			warn("diff: bad config: diff.algorithm value: %s", algorithmName)
+		}
+	}
+	style := opts.ConflictStyle
+	if len(style) == 0 {
+		style = w.mergeConflictStyle()
+	}
+	labelB := opts.Branch.Short()
+	if len(labelB) == 0 {
+		labelB = shortHash(opts.Hash)
+	}
+	merged := make([]*checkoutMerged, 0, len(paths))
+	overwrites := make([]string, 0, len(paths))
+	for _, p := range paths {
+		a, aErr := oldTree.FindEntry(ctx, p)
+		b, bErr := newTree.FindEntry(ctx, p)
+		if aErr != nil || bErr != nil || a.Type() != object.BlobObject || b.Type() != object.BlobObject || a.Mode == filemode.Symlink || b.Mode == filemode.Symlink {
+			overwrites = append(overwrites, p)
+			continue
+		}
+		textA, err := w.readWorktreeText(p)
+		if err != nil {
+			overwrites = append(overwrites, p)
+			continue
+		}
+		textO, _, err := w.readMissingText(ctx, a.Hash, false)
+		if err != nil {
+			overwrites = append(overwrites, p)
+			continue
+		}
+		textB, _, err := w.readMissingText(ctx, b.Hash, false)
+		if err != nil {
+			overwrites = append(overwrites, p)
+			continue
+		}
+		text, conflict, err := diferenco.Merge(ctx, &diferenco.MergeOptions{
+			TextO:  textO,
+			TextA:  textA,
+			TextB:  textB,
+			LabelO: from.Short(),
+			LabelA: "local",
+			LabelB: labelB,
+			A:      diffAlgorithm,
+			Style:  diferenco.ParseConflictStyle(style),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		merged = append(merged, &checkoutMerged{name: p, mode: b.Mode, text: text, conflict: conflict, base: a, local: textA, target: b})
+	}
+	return merged, overwrites, nil
+}
+
+// writeMerged: the index matches the target tree, merged local changes stay in the worktree. Conflicted paths are
+// recorded as conflict stages like git checkout -m, zeta commit refuses them until they are resolved by zeta add.
+func (w *Worktree) writeMerged(ctx context.Context, merged []*checkoutMerged) error {
+	var conflicts []*checkoutMerged
+	for _, m := range merged {
+		mode, err := m.mode.ToOSFileMode()
+		if err != nil {
+			return err
+		}
+		fd, err := w.fs.OpenFile(m.name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+		if _, err := fd.WriteString(m.text); err != nil {
+			_ = fd.Close()
+			return err
+		}
+		if err := fd.Close(); err != nil {
+			return err
+		}
+		if m.conflict {
+			fmt.Fprintln(os.Stderr, tr.Sprintf("CONFLICT (%s): Merge conflict in %s", W("content"), m.name))
+			conflicts = append(conflicts, m)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "M\t%s\n", m.name)
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	idx, err := w.odb.Index()
+	if err != nil {
+		return err
+	}
+	b := newIndexBuilder(idx)
+	for _, m := range conflicts {
+		local, err := w.odb.HashTo(ctx, strings.NewReader(m.local), int64(len(m.local)))
+		if err != nil {
+			return err
+		}
+		b.Remove(m.name)
+		b.unmerged[m.name] = []*index.Entry{
+			{Name: m.name, Hash: m.base.Hash, Mode: m.base.Mode, Stage: index.AncestorMode},
+			{Name: m.name, Hash: local, Mode: m.base.Mode, Stage: index.OurMode},
+			{Name: m.name, Hash: m.target.Hash, Mode: m.target.Mode, Stage: index.TheirMode},
+		}
+	}
+	b.Write(idx)
+	return w.odb.SetIndex(idx)
+}
@@ -61,7 +61,7 @@ func (w *Worktree) resolveIndexEntry(p noder.Path) (*object.TreeEntry, error) {
 	}, nil
 }
 
-func (w *Worktree) checkoutOne(ctx context.Context, t transport.Transport, name string, e *object.TreeEntry) error {
+func (w *Worktree) checkoutOne(ctx context.Context, t transport.Transport, k *KeepList, name string, e *object.TreeEntry) error {
 	idx, err := w.odb.Index()
 	if err != nil {
 		return err
@@ -101,8 +101,12 @@ func (w *Worktree) checkoutOne(ctx context.Context, t transport.Transport, name
 	if err := w.addIndexFromFile(name, e.Hash, e.Mode, b); err != nil {
 		return err
 	}
-	for _, e := range larges {
-		_ = w.odb.PruneObject(ctx, e.Hash, false)
+	if !k.Match(name, e.Hash) {
+		for _, e := range larges {
+			if !k.oids[e.Hash] {
+				_ = w.odb.PruneObject(ctx, e.Hash, false)
+			}
+		}
 	}
 	b.Write(idx)
 	return w.odb.SetIndex(idx)
@@ -114,8 +118,9 @@ func (w *Worktree) checkoutOneAfterAnother0(ctx context.Context, entries []*odb.
 	if err != nil {
 		return err
 	}
+	k := w.keepList()
 	for _, e := range entries {
-		if err := w.checkoutOne(ctx, t, e.Path, e.TreeEntry); err != nil {
+		if err := w.checkoutOne(ctx, t, k, e.Path, e.TreeEntry); err != nil {
 			return err
 		}
 		_, _ = tr.Fprintf(os.Stderr, "Checkout '%s' success.\n", e.Path)
@@ -213,6 +218,11 @@ func (w *Worktree) resolveBatchObjects(ctx context.Context, root *object.Tree, r
 }
 
 func (w *Worktree) DoBatchCo(ctx context.Context, oneByOne bool, revision string, r io.Reader) error {
+	unlock, err := w.lock("checkout")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	oid, err := w.resolveRevision(ctx, revision)
 	if err != nil {
 		return err
@@ -425,6 +435,11 @@ func (w *Worktree) doPathCheckoutWorktreeOnly(ctx context.Context, patterns []st
 }
 
 func (w *Worktree) DoPathCo(ctx context.Context, worktreeOnly bool, oid plumbing.Hash, pathSpec []string) error {
+	unlock, err := w.lock("checkout")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	cc, err := w.odb.ParseRevExhaustive(ctx, oid)
 	if err != nil {
 		return err
@@ -444,6 +459,12 @@ func (w *Worktree) DoPathCo(ctx context.Context, worktreeOnly bool, oid plumbing
 	if worktreeOnly {
 		return w.doPathCheckoutWorktreeOnly(ctx, pathSpec)
 	}
+	for _, p := range patterns {
+		if err := w.checkNarrowPath(p); err != nil {
+			die_error("%v", err)
+			return err
+		}
+	}
 	entries, err := w.lsTreeRecurseFilter(ctx, root, NewMatcher(patterns))
 	if err != nil {
 		return err
@@ -10,8 +10,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/antgroup/hugescm/modules/commitmsg"
 	"github.com/antgroup/hugescm/modules/env"
 	"github.com/antgroup/hugescm/modules/merkletrie"
 	"github.com/antgroup/hugescm/modules/merkletrie/noder"
@@ -27,8 +29,57 @@ var (
 	ErrNoChanges            = errors.New("clean working tree")
 	ErrNotAllowEmptyMessage = errors.New("not allow empty message")
 	ErrNothingToCommit      = errors.New("nothing to commit")
+	ErrCommitMessagePolicy  = errors.New("commit message policy violation")
 )
 
+// commitMessageRules returns the commit message policies of commit.*, -c values take precedence.
+func (r *Repository) commitMessageRules() (*commitmsg.Rules, error) {
+	c := &r.Config.Commit
+	opts := &commitmsg.Options{
+		Policies:      c.Policies,
+		IssuePattern:  c.IssuePattern,
+		SubjectLength: c.SubjectLength,
+		BranchPattern: c.BranchPattern,
+		Footer:        c.Footer,
+	}
+	if policies, ok := getStringsFromValues("commit.policies", r.values); ok {
+		opts.Policies = policies
+	}
+	if s, ok := getStringFromValues("commit.issuePattern", r.values); ok {
+		opts.IssuePattern = s
+	}
+	if s, ok := getStringFromValues("commit.subjectLength", r.values); ok {
+		opts.SubjectLength, _ = strconv.Atoi(s)
+	}
+	if s, ok := getStringFromValues("commit.branchPattern", r.values); ok {
+		opts.BranchPattern = s
+	}
+	if s, ok := getStringFromValues("commit.footer", r.values); ok {
+		opts.Footer = s
+	}
+	return commitmsg.New(opts)
+}
+
+// applyMessagePolicies appends the branch footer and checks the message, violations are reported to stderr.
+func (w *Worktree) applyMessagePolicies(message string, current plumbing.ReferenceName) (string, error) {
+	rules, err := w.commitMessageRules()
+	if err != nil {
+		return "", err
+	}
+	if current.IsBranch() {
+		message = rules.Enrich(message, current.BranchName())
+	}
+	violations := rules.Check(message)
+	if len(violations) == 0 {
+		return message, nil
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", W("The commit message does not satisfy commit.policies:"))
+	for _, v := range violations {
+		fmt.Fprintf(os.Stderr, "  - %s\n", tr.Sprintf(v.Format, v.Args...))
+	}
+	return "", ErrCommitMessagePolicy
+}
+
 func (w *Worktree) genAmendMessageTemplate(ctx context.Context, p string) error {
 	current, err := w.Current()
 	if err != nil {
@@ -164,6 +215,11 @@ func (w *Worktree) current() (plumbing.ReferenceName, plumbing.Hash, error) {
 // Commit stores the current contents of the index in a new commit along with
 // a log message from the user describing the changes.
 func (w *Worktree) Commit(ctx context.Context, opts *CommitOptions) (plumbing.Hash, error) {
+	unlock, err := w.lock("commit")
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer unlock()
 	if err := opts.Validate(w.Repository); err != nil {
 		return plumbing.ZeroHash, err
 	}
@@ -171,8 +227,31 @@ func (w *Worktree) Commit(ctx context.Context, opts *CommitOptions) (plumbing.Ha
 	if err != nil {
 		return plumbing.ZeroHash, err
 	}
+	// a merge stopped at conflicts: the resolution is committed as the merge commit
+	mergeHEAD, merging := w.mergeHEAD()
+	if merging && opts.Amend {
+		die_error("You are in the middle of a merge -- cannot amend.")
+		return plumbing.ZeroHash, ErrAborting
+	}
+	idx, err := w.odb.Index()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if paths := unmergedIndexPaths(idx); len(paths) != 0 {
+		reportUnmerged(paths)
+		return plumbing.ZeroHash, ErrHasConflicts
+	}
 	var status Status
-	if !opts.Amend {
+	if merging {
+		if status, err = w.status(ctx, oldRev); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if paths := w.unmergedPaths(status, opts.All); len(paths) != 0 {
+			reportUnmerged(paths)
+			return plumbing.ZeroHash, ErrHasConflicts
+		}
+	}
+	if !opts.Amend && !merging {
 		if status, err = w.status(context.Background(), oldRev); err != nil {
 			return plumbing.ZeroHash, err
 		}
@@ -196,6 +275,10 @@ func (w *Worktree) Commit(ctx context.Context, opts *CommitOptions) (plumbing.Ha
 		if message, err = messageReadFromPath(opts.File); err != nil {
 			return plumbing.ZeroHash, err
 		}
+	case len(opts.Message) == 0 && merging:
+		if message, err = w.mergeCommitMessage(ctx, mergeHEAD, current); err != nil {
+			return plumbing.ZeroHash, err
+		}
 	case len(opts.Message) == 0:
 		if message, err = w.messageFromPrompt(ctx, opts, current.BranchName(), status); err != nil {
 			return plumbing.ZeroHash, err
@@ -207,6 +290,11 @@ func (w *Worktree) Commit(ctx context.Context, opts *CommitOptions) (plumbing.Ha
 	if len(message) == 0 && !opts.AllowEmptyMessage {
 		return plumbing.ZeroHash, ErrNotAllowEmptyMessage
 	}
+	if !opts.NoVerify && len(message) != 0 {
+		if message, err = w.applyMessagePolicies(message, current); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
 
 	if opts.All {
 		if err := w.autoAddModifiedAndDeleted(ctx); err != nil {
@@ -231,6 +319,9 @@ func (w *Worktree) Commit(ctx context.Context, opts *CommitOptions) (plumbing.Ha
 			return plumbing.ZeroHash, err
 		}
 	}
+	if merging {
+		opts.Parents = []plumbing.Hash{oldRev, mergeHEAD}
+	}
 
 	commit, err := w.commitTree(ctx, &CommitTreeOptions{
 		Tree:      newTree,
@@ -244,6 +335,9 @@ func (w *Worktree) Commit(ctx context.Context, opts *CommitOptions) (plumbing.Ha
 		return plumbing.ZeroHash, err
 	}
 	reflogMessage := "commit: " + messageSubject(message)
+	if merging {
+		reflogMessage = "commit (merge): " + messageSubject(message)
+	}
 	if current.IsBranch() {
 		_ = w.writeHEADReflog(commit, &opts.Committer, reflogMessage)
 	}
@@ -251,9 +345,25 @@ func (w *Worktree) Commit(ctx context.Context, opts *CommitOptions) (plumbing.Ha
 	if err := w.DoUpdate(ctx, current, oldRev, commit, &opts.Committer, reflogMessage); err != nil {
 		return plumbing.ZeroHash, err
 	}
+	if merging {
+		w.removeMergeState()
+	}
 	return commit, nil
 }
 
+// mergeCommitMessage returns the message of the merge commit concluded by zeta commit: MERGE_MSG saved by the
+// conflicting merge, edited when stdin is a terminal.
+func (w *Worktree) mergeCommitMessage(ctx context.Context, mergeHEAD plumbing.Hash, current plumbing.ReferenceName) (string, error) {
+	savedMessage, ok := w.savedMergeMessage()
+	if !ok {
+		savedMessage = fmt.Sprintf("Merge branch '%s' into %s", mergeHEAD, current.Short())
+	}
+	if term.IsTerminal(os.Stdin.Fd()) && env.ZETA_TERMINAL_PROMPT.SimpleAtob(true) {
+		return w.mergeMessageFromPrompt(ctx, savedMessage)
+	}
+	return messageReadFrom(strings.NewReader(savedMessage))
+}
+
 func (w *Worktree) autoAddModifiedAndDeleted(ctx context.Context) error {
 	s, err := w.Status(ctx, false)
 	if err != nil {
@@ -135,6 +135,10 @@ func (w *Worktree) excludeIgnoredChanges(changes merkletrie.Changes) merkletrie.
 			rmItems[canonicalName(ch.From.String())] = ch
 			continue
 		}
+		// modified
+		if w.suppressModeChange(&ch) {
+			continue
+		}
 		res = append(res, ch)
 	}
 	for _, ch := range newItems {
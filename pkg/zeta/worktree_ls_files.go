@@ -4,36 +4,50 @@
 package zeta
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/antgroup/hugescm/modules/merkletrie"
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/plumbing/format/ignore"
 	"github.com/antgroup/hugescm/modules/plumbing/format/index"
 )
 
 // https://git-scm.com/docs/git-ls-files/zh_HANS-CN
 // Show information about files in the index and the working tree
 
+// ListFilesMode: selection of files, modes can be combined. Files are listed in the order of git: others first,
+// then the index entries (cached/stage), then deleted and modified files.
 type ListFilesMode int
 
 const (
-	ListFilesCached ListFilesMode = iota
+	ListFilesCached ListFilesMode = 1 << iota
 	ListFilesDeleted
 	ListFilesModified
 	ListFilesOthers
-	//ListFilesIgnored
+	// ListFilesIgnored: only show ignored files, must be used with ListFilesOthers or ListFilesCached
+	ListFilesIgnored
 	ListFilesStage
 )
 
+var (
+	ErrIgnoredNeedsSelection = errors.New("ls-files -i must be used with either -o or -c")
+)
+
 type LsFilesOptions struct {
-	Mode  ListFilesMode
-	Z     bool
-	JSON  bool
-	Paths []string
+	Mode ListFilesMode
+	// ExcludeStandard: exclude files matched by .zetaignore from others, implied by ListFilesIgnored
+	ExcludeStandard bool
+	Z               bool
+	JSON            bool
+	Paths           []string
 }
 
 func (opts *LsFilesOptions) newLine() byte {
@@ -43,173 +57,195 @@ func (opts *LsFilesOptions) newLine() byte {
 	return '\n'
 }
 
-func (w *Worktree) lsFilesCached(opts *LsFilesOptions) error {
-	idx, err := w.odb.Index()
-	if err != nil {
-		return err
+type StageItem struct {
+	Name  string            `json:"name"`
+	Mode  filemode.FileMode `json:"mode"`
+	Hash  plumbing.Hash     `json:"hash"`
+	Stage index.Stage       `json:"stage"`
+}
+
+type lsFilesPrinter struct {
+	out     io.Writer
+	w       *bufio.Writer
+	stage   bool
+	json    bool
+	newLine byte
+	names   []string
+	items   []*StageItem
+}
+
+func newLsFilesPrinter(opts *LsFilesOptions, out io.Writer) *lsFilesPrinter {
+	return &lsFilesPrinter{
+		out:     out,
+		w:       bufio.NewWriter(out),
+		stage:   opts.Mode&ListFilesStage != 0,
+		json:    opts.JSON,
+		newLine: opts.newLine(),
+		names:   make([]string, 0, 20),
 	}
-	entries := make([]string, 0, 20)
-	newLine := opts.newLine()
-	m := NewMatcher(opts.Paths)
-	for _, e := range idx.Entries {
-		if !m.Match(e.Name) {
-			continue
-		}
-		if opts.JSON {
-			entries = append(entries, e.Name)
-			continue
+}
+
+// add prints the name, e is the index entry of the file, nil for untracked files.
+func (p *lsFilesPrinter) add(name string, e *index.Entry) {
+	switch {
+	case p.json && p.stage:
+		item := &StageItem{Name: name}
+		if e != nil {
+			item.Mode, item.Hash, item.Stage = e.Mode, e.Hash, e.Stage
 		}
-		_, _ = fmt.Fprintf(os.Stdout, "%s%c", e.Name, newLine)
-	}
-	if opts.JSON {
-		return json.NewEncoder(os.Stdout).Encode(entries)
+		p.items = append(p.items, item)
+	case p.json:
+		p.names = append(p.names, name)
+	case p.stage && e != nil:
+		_, _ = fmt.Fprintf(p.w, "%s %s %d\t%s%c", e.Mode, e.Hash, e.Stage, name, p.newLine)
+	default:
+		_, _ = fmt.Fprintf(p.w, "%s%c", name, p.newLine)
 	}
-	return nil
 }
 
-func (w *Worktree) lsFilesDeleted(ctx context.Context, opts *LsFilesOptions) error {
-	changes, err := w.diffStagingWithWorktree(ctx, false, true)
-	if err != nil {
-		return err
-	}
-	newLine := opts.newLine()
-	m := NewMatcher(opts.Paths)
-	entries := make([]string, 0, 20)
-	for _, e := range changes {
-		action, err := e.Action()
-		if err != nil {
-			return err
-		}
-		if action != merkletrie.Delete {
-			continue
+func (p *lsFilesPrinter) flush() error {
+	switch {
+	case p.json && p.stage:
+		if p.items == nil {
+			p.items = []*StageItem{}
 		}
-		p := nameFromAction(&e)
-		if !m.Match(p) {
-			continue
-		}
-		if opts.JSON {
-			entries = append(entries, p)
-			continue
-		}
-		_, _ = fmt.Fprintf(os.Stdout, "%s%c", p, newLine)
-	}
-	if opts.JSON {
-		return json.NewEncoder(os.Stdout).Encode(entries)
+		return json.NewEncoder(p.out).Encode(p.items)
+	case p.json:
+		return json.NewEncoder(p.out).Encode(p.names)
 	}
-	return nil
+	return p.w.Flush()
+}
+
+func matchIgnore(m ignore.Matcher, name string, isDir bool) bool {
+	return m.Match(strings.Split(name, "/"), isDir)
 }
 
-func (w *Worktree) lsFilesModified(ctx context.Context, opts *LsFilesOptions) error {
-	changes, err := w.diffStagingWithWorktree(ctx, false, true)
+func (w *Worktree) lsFilesOthers(ctx context.Context, opts *LsFilesOptions, m *Matcher, p *lsFilesPrinter) error {
+	changes, err := w.diffStagingWithWorktree(ctx, false, false)
 	if err != nil {
 		return err
 	}
-	newLine := opts.newLine()
-	m := NewMatcher(opts.Paths)
-	entries := make([]string, 0, 20)
-	for _, e := range changes {
-		action, err := e.Action()
-		if err != nil {
+	var im ignore.Matcher
+	if opts.ExcludeStandard || opts.Mode&ListFilesIgnored != 0 {
+		if im, err = w.ignoreMatcher(); err != nil {
 			return err
 		}
-		if action != merkletrie.Delete && action != merkletrie.Modify {
+	}
+	for _, ch := range changes {
+		if len(ch.From) != 0 {
 			continue
 		}
-		p := nameFromAction(&e)
-		if !m.Match(p) {
+		name := nameFromAction(&ch)
+		if !m.Match(name) {
 			continue
 		}
-		if opts.JSON {
-			entries = append(entries, p)
-			continue
+		if im != nil {
+			// show only ignored files with --ignored, otherwise exclude them
+			if matchIgnore(im, name, ch.To.IsDir()) != (opts.Mode&ListFilesIgnored != 0) {
+				continue
+			}
 		}
-		_, _ = fmt.Fprintf(os.Stdout, "%s%c", p, newLine)
-	}
-	if opts.JSON {
-		return json.NewEncoder(os.Stdout).Encode(entries)
+		p.add(name, nil)
 	}
 	return nil
 }
 
-func (w *Worktree) lsFilesOthers(ctx context.Context, opts *LsFilesOptions) error {
-	changes, err := w.diffStagingWithWorktree(ctx, false, false)
-	if err != nil {
-		return err
+func (w *Worktree) lsFilesCached(opts *LsFilesOptions, idx *index.Index, m *Matcher, p *lsFilesPrinter) error {
+	var im ignore.Matcher
+	if opts.Mode&ListFilesIgnored != 0 {
+		var err error
+		if im, err = w.ignoreMatcher(); err != nil {
+			return err
+		}
 	}
-	ignored := w.ignoredChanges(changes)
-	newLine := opts.newLine()
-	m := NewMatcher(opts.Paths)
-	entries := make([]string, 0, 20)
-	for _, e := range ignored {
-		if !m.Match(e) {
+	for _, e := range idx.Entries {
+		if !m.Match(e.Name) {
 			continue
 		}
-		if opts.JSON {
-			entries = append(entries, e)
+		if im != nil && !matchIgnore(im, e.Name, false) {
 			continue
 		}
-		_, _ = fmt.Fprintf(os.Stdout, "%s%c", e, newLine)
-	}
-	if opts.JSON {
-		return json.NewEncoder(os.Stdout).Encode(entries)
+		p.add(e.Name, e)
 	}
 	return nil
 }
 
-type StageItem struct {
-	Name  string            `json:"name"`
-	Mode  filemode.FileMode `json:"mode"`
-	Hash  plumbing.Hash     `json:"hash"`
-	Stage index.Stage       `json:"stage"`
-}
-
-func (w *Worktree) lsFilesStage(opts *LsFilesOptions) error {
-	idx, err := w.odb.Index()
+func (w *Worktree) lsFilesChanged(ctx context.Context, opts *LsFilesOptions, idx *index.Index, m *Matcher, p *lsFilesPrinter) error {
+	changes, err := w.diffStagingWithWorktreeFromIndex(ctx, idx, w.buildWorktreeCache(ctx, idx), false, true)
 	if err != nil {
 		return err
 	}
-	entries := make([]*StageItem, 0, 20)
-	newLine := opts.newLine()
-	m := NewMatcher(opts.Paths)
+	entries := make(map[string]*index.Entry, len(idx.Entries))
 	for _, e := range idx.Entries {
-		if !m.Match(e.Name) {
-			continue
+		entries[e.Name] = e
+	}
+	for _, ch := range changes {
+		action, err := ch.Action()
+		if err != nil {
+			return err
 		}
-		if opts.JSON {
-			entries = append(entries, &StageItem{
-				Name:  e.Name,
-				Mode:  e.Mode,
-				Hash:  e.Hash,
-				Stage: e.Stage,
-			})
+		name := nameFromAction(&ch)
+		if !m.Match(name) {
 			continue
 		}
-		_, _ = fmt.Fprintf(os.Stdout, "%s %s %d\t%s%c", e.Mode, e.Hash, e.Stage, e.Name, newLine)
-	}
-	if opts.JSON {
-		return json.NewEncoder(os.Stdout).Encode(entries)
+		switch action {
+		case merkletrie.Delete:
+			// like git, deleted files are also modified files
+			if opts.Mode&ListFilesDeleted != 0 {
+				p.add(name, entries[name])
+			}
+			if opts.Mode&ListFilesModified != 0 {
+				p.add(name, entries[name])
+			}
+		case merkletrie.Modify:
+			if opts.Mode&ListFilesModified != 0 {
+				p.add(name, entries[name])
+			}
+		}
 	}
 	return nil
 }
 
+// LsFiles shows information about files in the index and the working tree.
 func (w *Worktree) LsFiles(ctx context.Context, opts *LsFilesOptions) error {
+	return w.lsFiles(ctx, opts, os.Stdout)
+}
+
+func (w *Worktree) lsFiles(ctx context.Context, opts *LsFilesOptions, out io.Writer) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
-	switch opts.Mode {
-	case ListFilesCached:
-		return w.lsFilesCached(opts)
-	case ListFilesDeleted:
-		return w.lsFilesDeleted(ctx, opts)
-	case ListFilesModified:
-		return w.lsFilesModified(ctx, opts)
-	case ListFilesOthers:
-		return w.lsFilesOthers(ctx, opts)
-	//case ListFilesIgnored:
-	case ListFilesStage:
-		return w.lsFilesStage(opts)
+	// like git, -i alone is an error rather than -c -i
+	if opts.Mode&ListFilesIgnored != 0 && opts.Mode&(ListFilesOthers|ListFilesCached|ListFilesStage) == 0 {
+		return ErrIgnoredNeedsSelection
 	}
-	return nil
+	if opts.Mode == 0 {
+		opts.Mode = ListFilesCached
+	}
+	m := NewMatcher(opts.Paths)
+	p := newLsFilesPrinter(opts, out)
+	if opts.Mode&ListFilesOthers != 0 {
+		if err := w.lsFilesOthers(ctx, opts, m, p); err != nil {
+			return err
+		}
+	}
+	if opts.Mode&(ListFilesCached|ListFilesStage|ListFilesDeleted|ListFilesModified) != 0 {
+		idx, err := w.odb.Index()
+		if err != nil {
+			return err
+		}
+		if opts.Mode&(ListFilesCached|ListFilesStage) != 0 {
+			if err := w.lsFilesCached(opts, idx, m, p); err != nil {
+				return err
+			}
+		}
+		if opts.Mode&(ListFilesDeleted|ListFilesModified) != 0 {
+			if err := w.lsFilesChanged(ctx, opts, idx, m, p); err != nil {
+				return err
+			}
+		}
+	}
+	return p.flush()
 }
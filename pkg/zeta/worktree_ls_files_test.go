@@ -0,0 +1,66 @@
+package zeta
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLsFiles(t *testing.T) {
+	ctx := t.Context()
+	worktree := filepath.Join(t.TempDir(), "ls-files")
+	r, err := Init(ctx, &InitOptions{Worktree: worktree, Branch: "mainline", Quiet: true})
+	if err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	defer r.Close() // nolint
+	w := r.Worktree()
+	t.Chdir(worktree)
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(worktree, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("a.txt", "a\n")
+	writeFile("b.log", "b\n")
+	if err := w.Add(ctx, []string{"a.txt", "b.log"}, false); err != nil {
+		t.Fatalf("add error: %v", err)
+	}
+	// b.log is tracked before it is ignored
+	writeFile(".zetaignore", "*.log\n")
+	writeFile("c.txt", "c\n")
+	writeFile("d.log", "d\n")
+
+	for _, c := range []struct {
+		name string
+		opts *LsFilesOptions
+		want []string
+	}{
+		{"default", &LsFilesOptions{}, []string{"a.txt", "b.log"}},
+		{"-c -o", &LsFilesOptions{Mode: ListFilesCached | ListFilesOthers}, []string{".zetaignore", "c.txt", "d.log", "a.txt", "b.log"}},
+		{"-o", &LsFilesOptions{Mode: ListFilesOthers}, []string{".zetaignore", "c.txt", "d.log"}},
+		{"-o --exclude-standard", &LsFilesOptions{Mode: ListFilesOthers, ExcludeStandard: true}, []string{".zetaignore", "c.txt"}},
+		{"-o -i", &LsFilesOptions{Mode: ListFilesOthers | ListFilesIgnored}, []string{"d.log"}},
+		{"-c -i", &LsFilesOptions{Mode: ListFilesCached | ListFilesIgnored}, []string{"b.log"}},
+	} {
+		var b bytes.Buffer
+		if err := w.lsFiles(ctx, c.opts, &b); err != nil {
+			t.Fatalf("%s: ls-files error: %v", c.name, err)
+		}
+		if got := strings.Fields(b.String()); strings.Join(got, " ") != strings.Join(c.want, " ") {
+			t.Fatalf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := w.lsFiles(ctx, &LsFilesOptions{Mode: ListFilesIgnored}, &b); !errors.Is(err, ErrIgnoredNeedsSelection) {
+		t.Fatalf("-i: expected ErrIgnoredNeedsSelection, got %v", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("-i: unexpected output %q", b.String())
+	}
+}
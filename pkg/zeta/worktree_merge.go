@@ -16,6 +16,7 @@ import (
 	"github.com/antgroup/hugescm/modules/merkletrie"
 	"github.com/antgroup/hugescm/modules/merkletrie/noder"
 	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/format/index"
 	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/modules/term"
 	"github.com/antgroup/hugescm/modules/trace"
@@ -38,6 +39,11 @@ type MergeOptions struct {
 	Continue                          bool
 	Message                           []string
 	File                              string
+	// NoVerify bypasses the commit message policies of commit.policies.
+	NoVerify bool
+	// LabelOurs, LabelTheirs, LabelBase: labels of the conflict markers, defaults to HEAD, the merged revision and the
+	// merge base.
+	LabelOurs, LabelTheirs, LabelBase string
 }
 
 // 1 Merge branch 'dev-1' into dev-2
@@ -67,19 +73,30 @@ func (w *Worktree) mergeMessageFromPrompt(ctx context.Context, messagePrefix str
 	return messageReadFromPath(p)
 }
 
-func (w *Worktree) mergeMessageGen(ctx context.Context, opts *MergeOptions, branchName string) (string, error) {
+func (w *Worktree) mergeMessageGen(ctx context.Context, opts *MergeOptions, messagePrefix string) (string, error) {
 	switch {
 	case opts.File == "-":
 		return messageReadFrom(os.Stdin)
 	case len(opts.File) != 0:
 		return messageReadFromPath(opts.File)
 	case len(opts.Message) == 0:
-		messagePrefix := fmt.Sprintf("Merge branch '%s' into %s", opts.From, branchName)
 		return w.mergeMessageFromPrompt(ctx, messagePrefix)
 	}
 	return genMessage(opts.Message), nil
 }
 
+// verifyMergeMessage checks the merge message against commit.policies unless noVerify is set.
+func (w *Worktree) verifyMergeMessage(message string, current plumbing.ReferenceName, noVerify bool) (string, error) {
+	if noVerify || len(message) == 0 {
+		return message, nil
+	}
+	message, err := w.applyMessagePolicies(message, current)
+	if errors.Is(err, ErrCommitMessagePolicy) {
+		fmt.Fprintln(os.Stderr, W("Aborting merge, use --no-verify to bypass the commit message policies."))
+	}
+	return message, err
+}
+
 func (w *Worktree) mergeFF(ctx context.Context, parent1, parent2 plumbing.Hash, message string) (plumbing.Hash, error) {
 	select {
 	case <-ctx.Done():
@@ -106,11 +123,16 @@ func (w *Worktree) mergeFF(ctx context.Context, parent1, parent2 plumbing.Hash,
 }
 
 func (w *Worktree) Merge(ctx context.Context, opts *MergeOptions) error {
+	unlock, err := w.lock("merge")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if opts.Abort {
 		return w.mergeAbort(ctx)
 	}
 	if opts.Continue {
-		return w.mergeContinue(ctx)
+		return w.mergeContinue(ctx, opts)
 	}
 	if len(opts.From) == 0 {
 		die_error("zeta merge require revision argument")
@@ -174,21 +196,38 @@ func (w *Worktree) Merge(ctx context.Context, opts *MergeOptions) error {
 		return err
 	}
 
+	messagePrefix := fmt.Sprintf("Merge branch '%s' into %s", opts.From, branchName)
+	defaultMessage, err := w.makeMergeMessage(ctx, current.Hash(), from, opts.From, branchName)
+	if err != nil {
+		die_error("merge message: %v", err)
+		return err
+	}
 	if fastForward {
-		return w.handleFastForwardMerge(ctx, current, from, opts, branchName)
+		return w.handleFastForwardMerge(ctx, current, from, opts, defaultMessage)
 	}
 	if opts.FFOnly {
 		fmt.Fprintln(os.Stderr, W("Not possible to fast-forward, aborting."))
 		return ErrNonFastForwardUpdate
 	}
-	newRev, err := w.mergeInternal(ctx, current.Hash(), from, branchName, opts.From, opts.Squash, opts.AllowUnrelatedHistories, opts.Textconv, opts.Signoff, func() string {
-		message, _ := w.mergeMessageGen(ctx, opts, branchName)
-		return message
+	newRev, err := w.mergeInternal(ctx, current.Hash(), from, &mergeInternalOptions{
+		current:                 currentName,
+		branch1:                 branchName,
+		branch2:                 opts.From,
+		squash:                  opts.Squash,
+		allowUnrelatedHistories: opts.AllowUnrelatedHistories,
+		textconv:                opts.Textconv,
+		signoff:                 opts.Signoff,
+		noVerify:                opts.NoVerify,
+		labels:                  conflictLabels{ours: opts.LabelOurs, theirs: opts.LabelTheirs, base: opts.LabelBase},
+		message:                 defaultMessage,
+		messageFn: func() string {
+			message, _ := w.mergeMessageGen(ctx, opts, defaultMessage)
+			return message
+		},
 	})
 	if err != nil {
 		return err
 	}
-	messagePrefix := fmt.Sprintf("Merge branch '%s' into %s", opts.From, branchName)
 	if err := w.DoUpdate(ctx, current.Name(), current.Hash(), newRev, w.NewCommitter(), "merge: "+messagePrefix); err != nil {
 		die_error("update fast forward: %v", err)
 		return err
@@ -226,8 +265,8 @@ func (w *Worktree) makeSquashMessage(ctx context.Context, from plumbing.Hash, ig
 }
 
 // handleFastForwardMerge handles the fast-forward merge operation
-func (w *Worktree) handleFastForwardMerge(ctx context.Context, current *plumbing.Reference, from plumbing.Hash, opts *MergeOptions, branchName string) error {
-	newRev, err := w.prepareFastForwardRevision(ctx, current.Hash(), from, opts, branchName)
+func (w *Worktree) handleFastForwardMerge(ctx context.Context, current *plumbing.Reference, from plumbing.Hash, opts *MergeOptions, defaultMessage string) error {
+	newRev, err := w.prepareFastForwardRevision(ctx, current, from, opts, defaultMessage)
 	if err != nil {
 		return err
 	}
@@ -249,12 +288,12 @@ func (w *Worktree) handleFastForwardMerge(ctx context.Context, current *plumbing
 }
 
 // prepareFastForwardRevision prepares the new revision for fast-forward merge
-func (w *Worktree) prepareFastForwardRevision(ctx context.Context, currentHash, from plumbing.Hash, opts *MergeOptions, branchName string) (plumbing.Hash, error) {
+func (w *Worktree) prepareFastForwardRevision(ctx context.Context, current *plumbing.Reference, from plumbing.Hash, opts *MergeOptions, defaultMessage string) (plumbing.Hash, error) {
 	if opts.FF {
 		return from, nil
 	}
 
-	message, err := w.mergeMessageGen(ctx, opts, branchName)
+	message, err := w.mergeMessageGen(ctx, opts, defaultMessage)
 	if err != nil {
 		die_error("unable resolve merge message")
 		return plumbing.ZeroHash, err
@@ -263,8 +302,11 @@ func (w *Worktree) prepareFastForwardRevision(ctx context.Context, currentHash,
 	if len(message) == 0 {
 		return plumbing.ZeroHash, ErrAborting
 	}
+	if message, err = w.verifyMergeMessage(message, current.Name(), opts.NoVerify); err != nil {
+		return plumbing.ZeroHash, err
+	}
 
-	newRev, err := w.mergeFF(ctx, from, currentHash, message)
+	newRev, err := w.mergeFF(ctx, from, current.Hash(), message)
 	if err != nil {
 		die_error("merge FF error")
 		return plumbing.ZeroHash, err
@@ -273,7 +315,21 @@ func (w *Worktree) prepareFastForwardRevision(ctx context.Context, currentHash,
 	return newRev, nil
 }
 
-func (w *Worktree) mergeInternal(ctx context.Context, into, from plumbing.Hash, branch1, branch2 string, squash, allowUnrelatedHistories, textconv, signoff bool, messageFn func() string) (plumbing.Hash, error) {
+type mergeInternalOptions struct {
+	current                         plumbing.ReferenceName
+	branch1, branch2                string
+	squash, allowUnrelatedHistories bool
+	textconv, signoff               bool
+	// noVerify bypasses commit.policies
+	noVerify bool
+	// labels: conflict markers, ours defaults to HEAD
+	labels conflictLabels
+	// message: saved to MERGE_MSG when the merge stops at conflicts
+	message   string
+	messageFn func() string
+}
+
+func (w *Worktree) mergeInternal(ctx context.Context, into, from plumbing.Hash, opts *mergeInternalOptions) (plumbing.Hash, error) {
 	c1, err := w.odb.Commit(ctx, into)
 	if err != nil {
 		return plumbing.ZeroHash, err
@@ -282,7 +338,9 @@ func (w *Worktree) mergeInternal(ctx context.Context, into, from plumbing.Hash,
 	if err != nil {
 		return plumbing.ZeroHash, err
 	}
-	result, err := w.mergeTree(ctx, c1, c2, nil, branch1, branch2, allowUnrelatedHistories, textconv)
+	labels := opts.labels
+	labels.ours = odb.LabelOr(labels.ours, "HEAD")
+	result, err := w.mergeTree(ctx, c1, c2, nil, opts.branch1, opts.branch2, &labels, opts.allowUnrelatedHistories, opts.textconv)
 	if err != nil {
 		if mr, ok := errors.AsType[*odb.MergeResult](err); ok {
 			for _, m := range mr.Messages {
@@ -299,12 +357,15 @@ func (w *Worktree) mergeInternal(ctx context.Context, into, from plumbing.Hash,
 		if err := w.checkoutMergeConflicts(ctx, c1, c2, result.MergeResult); err != nil {
 			die_error("checkout conflict tree: %v", err)
 		}
+		if err := w.saveMergeMessage(opts.message, result.Conflicts); err != nil {
+			die_error("save merge message: %v", err)
+		}
 		fmt.Fprintln(os.Stderr, W("Automatic merge failed; fix conflicts and then commit the result."))
 		return plumbing.ZeroHash, ErrHasConflicts
 	}
 	parents := []plumbing.Hash{into}
-	message := messageFn()
-	if squash {
+	message := opts.messageFn()
+	if opts.squash {
 		if message, err = w.makeSquashMessage(ctx, from, result.bases, message); err != nil {
 			return plumbing.ZeroHash, err
 		}
@@ -316,8 +377,11 @@ func (w *Worktree) mergeInternal(ctx context.Context, into, from plumbing.Hash,
 		die_error("No merge message -- not updating HEAD")
 		return plumbing.ZeroHash, ErrAborting
 	}
+	if message, err = w.verifyMergeMessage(message, opts.current, opts.noVerify); err != nil {
+		return plumbing.ZeroHash, err
+	}
 	committer := w.NewCommitter()
-	if signoff {
+	if opts.signoff {
 		message = fmt.Sprintf("%s\n\nSigned-off-by: %s <%s>\n", strings.TrimRightFunc(message, unicode.IsSpace), committer.Name, committer.Email)
 	}
 	cc, err := w.commitTree(ctx, &CommitTreeOptions{
@@ -403,6 +467,64 @@ func (w *Worktree) checkoutMergeConflicts(ctx context.Context, into, from *objec
 	return w.checkoutConflicts(ctx, tree0, root, result.Conflicts)
 }
 
+// mergeHEAD returns MERGE_HEAD when a merge stopped at conflicts, zeta commit or zeta merge --continue concludes it.
+func (w *Worktree) mergeHEAD() (plumbing.Hash, bool) {
+	oid, err := w.odb.ResolveSpecReference(odb.MERGE_HEAD)
+	if err != nil {
+		return plumbing.ZeroHash, false
+	}
+	return oid, true
+}
+
+// unmergedPaths returns the conflicted paths that are not resolved: the file still has conflict markers or the
+// resolution is not added to the index, all: modified files will be added like zeta commit -a.
+func (w *Worktree) unmergedPaths(s Status, all bool) []string {
+	var paths []string
+	for _, p := range w.savedMergeConflicts() {
+		if fs, ok := s[p]; ok && (fs.Worktree == Untracked || !all && (fs.Worktree == Modified || fs.Worktree == Deleted)) {
+			paths = append(paths, p)
+			continue
+		}
+		fd, err := os.Open(filepath.Join(w.baseDir, filepath.FromSlash(p)))
+		if err != nil {
+			// removed, eg: resolved by zeta rm
+			continue
+		}
+		conflicted := hasConflictMarkers(fd)
+		_ = fd.Close()
+		if conflicted {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// unmergedIndexPaths returns the paths with conflict stages in the index, eg: zeta switch --merge stopped at conflicts,
+// zeta add or zeta rm marks the resolution.
+func unmergedIndexPaths(idx *index.Index) []string {
+	var paths []string
+	for _, e := range idx.Entries {
+		if e.Stage != 0 && (len(paths) == 0 || paths[len(paths)-1] != e.Name) {
+			paths = append(paths, e.Name)
+		}
+	}
+	return paths
+}
+
+// removeMergeState removes MERGE_HEAD and MERGE_MSG once the merge is concluded or aborted.
+func (w *Worktree) removeMergeState() {
+	_ = w.odb.SpecReferenceRemove(odb.MERGE_HEAD)
+	_ = os.Remove(filepath.Join(w.odb.Root(), MERGE_MSG))
+}
+
+func reportUnmerged(paths []string) {
+	die_error("committing is not possible because you have unmerged files")
+	for _, p := range paths {
+		fmt.Fprintf(os.Stderr, "\t%s\n", p)
+	}
+	fmt.Fprintln(os.Stderr, W("Fix them up in the worktree, and then use 'zeta add/rm <file>' as appropriate to mark resolution and make a commit."))
+}
+
 func (w *Worktree) mergeAbort(ctx context.Context) error {
 	mergeHEAD, err := w.odb.ResolveSpecReference(odb.MERGE_HEAD)
 	if err != nil {
@@ -428,11 +550,11 @@ func (w *Worktree) mergeAbort(ctx context.Context) error {
 		die_error("zeta merge --abort: reset worktree error: %v", err)
 		return err
 	}
-	_ = w.odb.SpecReferenceRemove(odb.MERGE_HEAD)
+	w.removeMergeState()
 	return nil
 }
 
-func (w *Worktree) mergeContinue(ctx context.Context) error {
+func (w *Worktree) mergeContinue(ctx context.Context, opts *MergeOptions) error {
 	mergeHEAD, err := w.odb.ResolveSpecReference(odb.MERGE_HEAD)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -453,19 +575,35 @@ func (w *Worktree) mergeContinue(ctx context.Context) error {
 		die("resolve current commit: %v", err)
 		return err
 	}
-	mergeTree, err := w.writeIndexAsTree(ctx, cc.Hash, false)
+	s, err := w.status(ctx, cc.Hash)
+	if err != nil {
+		die_error("status: %v", err)
+		return err
+	}
+	if paths := w.unmergedPaths(s, false); len(paths) != 0 {
+		reportUnmerged(paths)
+		return ErrHasConflicts
+	}
+	mergeTree, err := w.writeIndexAsTree(ctx, cc.Tree, false)
 	if err != nil {
 		die_error("write index as tree: %v", err)
 		return err
 	}
 	messagePrefix := fmt.Sprintf("Merge branch '%s' into %s", mergeHEAD, current.Name().Short())
-	message, err := w.mergeMessageFromPrompt(ctx, messagePrefix)
+	savedMessage, ok := w.savedMergeMessage()
+	if !ok {
+		savedMessage = messagePrefix
+	}
+	message, err := w.mergeMessageFromPrompt(ctx, savedMessage)
 	if err != nil {
 		return err
 	}
 	if len(message) == 0 {
 		return ErrAborting
 	}
+	if message, err = w.verifyMergeMessage(message, current.Name(), opts.NoVerify); err != nil {
+		return err
+	}
 	committer := w.NewCommitter()
 	newRev, err := w.commitTree(ctx, &CommitTreeOptions{
 		Tree:      mergeTree,
@@ -482,7 +620,7 @@ func (w *Worktree) mergeContinue(ctx context.Context) error {
 		die_error("update fast forward: %v", err)
 		return err
 	}
-	_ = w.odb.SpecReferenceRemove(odb.MERGE_HEAD)
+	w.removeMergeState()
 	return nil
 }
 
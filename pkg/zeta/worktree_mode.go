@@ -0,0 +1,135 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/antgroup/hugescm/modules/merkletrie"
+	"github.com/antgroup/hugescm/modules/merkletrie/filesystem"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+)
+
+type hasher interface {
+	HashRaw() plumbing.Hash
+	Mode() filemode.FileMode
+}
+
+// core.filemode=false OR -X core.filemode=false: the executable bit of worktree files is not trusted, the mode
+// recorded in the index is kept.
+func (w *Worktree) trustFileMode() bool {
+	if s, ok := getStringFromValues("core.filemode", w.values); ok {
+		return strengthen.SimpleAtob(s, true)
+	}
+	return !w.Core.FileMode.False()
+}
+
+// core.symlinks=false OR -X core.symlinks=false: symlinks are checked out as plain files containing the link target,
+// the plain file is still stored as a symlink.
+func (w *Worktree) trustSymlinks() bool {
+	if s, ok := getStringFromValues("core.symlinks", w.values); ok {
+		return strengthen.SimpleAtob(s, true)
+	}
+	return !w.Core.Symlinks.False()
+}
+
+// resolveIndexMode returns the mode stored in the index for a file whose worktree mode is mode and whose previous
+// index mode is old (zero for new files).
+func (w *Worktree) resolveIndexMode(old, mode filemode.FileMode) filemode.FileMode {
+	if old == filemode.Empty {
+		return mode
+	}
+	old &^= filemode.Fragments
+	if !w.trustFileMode() && isRegularOrExecutable(old) && isRegularOrExecutable(mode) {
+		return old
+	}
+	if !w.trustSymlinks() && old == filemode.Symlink && mode == filemode.Regular {
+		return old
+	}
+	return mode
+}
+
+func isRegularOrExecutable(m filemode.FileMode) bool {
+	m &^= filemode.Fragments
+	return m == filemode.Regular || m == filemode.Executable
+}
+
+// sameModePolicy reports whether a and b are the same mode under core.filemode and core.symlinks.
+func (w *Worktree) sameModePolicy(a, b filemode.FileMode) bool {
+	a &^= filemode.Fragments
+	b &^= filemode.Fragments
+	if a == b {
+		return true
+	}
+	if !w.trustFileMode() && isRegularOrExecutable(a) && isRegularOrExecutable(b) {
+		return true
+	}
+	if !w.trustSymlinks() && (a == filemode.Symlink && b == filemode.Regular || a == filemode.Regular && b == filemode.Symlink) {
+		return true
+	}
+	return false
+}
+
+// suppressModeChange handles changes caused by core.filemode=false and core.symlinks=false: changes where only the
+// mode differs are skipped, when the content changed the worktree mode is unified with the index mode so that no mode
+// change is reported.
+//
+// Returns true if the change should be skipped.
+func (w *Worktree) suppressModeChange(ch *merkletrie.Change) bool {
+	if len(ch.From) == 0 || len(ch.To) == 0 {
+		return false
+	}
+	a, ok := ch.From.Last().(hasher)
+	if !ok {
+		return false
+	}
+	b, ok := ch.To.Last().(hasher)
+	if !ok {
+		return false
+	}
+	modeA := a.Mode()
+	modeB := b.Mode()
+	if modeA == modeB || !w.sameModePolicy(modeA, modeB) {
+		return false
+	}
+	if a.HashRaw() == b.HashRaw() {
+		return true
+	}
+	if fa, ok := ch.From.Last().(*filesystem.Node); ok {
+		fa.UnifyMode(modeB)
+		return false
+	}
+	if fb, ok := ch.To.Last().(*filesystem.Node); ok {
+		fb.UnifyMode(modeA)
+	}
+	return false
+}
+
+// probeFileSystem checks whether the file system of dir keeps the executable bit and supports symlinks, like git init
+// core.filemode=false and core.symlinks=false are recorded when it does not.
+func probeFileSystem(dir string, cfg *config.Config) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	probe := filepath.Join(dir, "probe-filemode")
+	if err := os.WriteFile(probe, nil, 0644); err != nil {
+		return
+	}
+	defer os.Remove(probe) // nolint
+	if err := os.Chmod(probe, 0755); err == nil {
+		if fi, err := os.Lstat(probe); err == nil && fi.Mode().Perm()&0100 == 0 {
+			cfg.Core.FileMode.Set(false)
+		}
+	}
+	link := filepath.Join(dir, "probe-symlink")
+	if err := os.Symlink("probe-filemode", link); err != nil {
+		cfg.Core.Symlinks.Set(false)
+		return
+	}
+	_ = os.Remove(link)
+}
@@ -0,0 +1,86 @@
+//go:build !windows
+
+package zeta
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+)
+
+func TestModePolicyStatus(t *testing.T) {
+	ctx := t.Context()
+	worktree := filepath.Join(t.TempDir(), "mode")
+	r, err := Init(ctx, &InitOptions{Worktree: worktree, Quiet: true})
+	if err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	defer r.Close() // nolint
+	if !r.Core.FileMode.IsUnset() || !r.Core.Symlinks.IsUnset() {
+		t.Skip("file system does not keep the executable bit or support symlinks")
+	}
+	if err := os.WriteFile(filepath.Join(worktree, "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("run.sh", filepath.Join(worktree, "link")); err != nil {
+		t.Fatal(err)
+	}
+	w := r.Worktree()
+	if err := w.Add(ctx, []string{"."}, false); err != nil {
+		t.Fatalf("add error: %v", err)
+	}
+	// simulate a file system without the executable bit and symlinks
+	if err := os.Chmod(filepath.Join(worktree, "run.sh"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(worktree, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree, "link"), []byte("run.sh"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := w.Status(ctx, false)
+	if err != nil {
+		t.Fatalf("status error: %v", err)
+	}
+	if s.File("run.sh").Worktree != Modified || s.File("link").Worktree != Modified {
+		t.Fatalf("mode changes should be reported by default: %v", s)
+	}
+
+	r.Core.FileMode.Set(false)
+	r.Core.Symlinks.Set(false)
+	if s, err = w.Status(ctx, false); err != nil {
+		t.Fatalf("status error: %v", err)
+	}
+	if s.File("run.sh").Worktree != Unmodified || s.File("link").Worktree != Unmodified {
+		t.Fatalf("mode only changes should be suppressed: %v", s)
+	}
+	// content changes are still reported and re-adding keeps the recorded modes
+	if err := os.WriteFile(filepath.Join(worktree, "run.sh"), []byte("#!/bin/sh\necho\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = w.Status(ctx, false); err != nil {
+		t.Fatalf("status error: %v", err)
+	}
+	if s.File("run.sh").Worktree != Modified {
+		t.Fatalf("content change should be reported: %v", s)
+	}
+	if err := w.Add(ctx, []string{"."}, false); err != nil {
+		t.Fatalf("add error: %v", err)
+	}
+	idx, err := r.ODB().Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, mode := range map[string]filemode.FileMode{"run.sh": filemode.Executable, "link": filemode.Symlink} {
+		e, err := idx.Entry(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Mode != mode {
+			t.Fatalf("%s: mode %v, expected %v", name, e.Mode, mode)
+		}
+	}
+}
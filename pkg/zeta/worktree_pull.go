@@ -18,6 +18,11 @@ type PullOptions struct {
 }
 
 func (w *Worktree) Pull(ctx context.Context, opts *PullOptions) error {
+	unlock, err := w.lock("pull")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	current, err := w.Current()
 	if err != nil {
 		die_error("resolve HEAD: %v", err)
@@ -87,7 +92,7 @@ func (w *Worktree) Pull(ctx context.Context, opts *PullOptions) error {
 	remoteRefName := plumbing.NewRemoteReferenceName("origin", branchName)
 	if opts.Rebase {
 		messagePrefix := fmt.Sprintf("Rebase branch '%s' onto %s (branch '%s of %s'))", branchName, branchName, w.cleanedRemote(), fo.FETCH_HEAD)
-		newRev, err := w.rebaseInternal(ctx, current.Hash(), fo.FETCH_HEAD, currentName, remoteRefName, false)
+		newRev, err := w.rebaseInternal(ctx, current.Hash(), fo.FETCH_HEAD, currentName)
 		if err != nil {
 			return err
 		}
@@ -104,9 +109,18 @@ func (w *Worktree) Pull(ctx context.Context, opts *PullOptions) error {
 		return nil
 	}
 	messagePrefix := fmt.Sprintf("Merge branch '%s of %s' into %s", branchName, w.cleanedRemote(), branchName)
-	newRev, err := w.mergeInternal(ctx, current.Hash(), fo.FETCH_HEAD, branchName, string(remoteRefName), opts.Squash, false, false, false, func() string {
-		message, _ := w.mergeMessageFromPrompt(ctx, messagePrefix)
-		return message
+	newRev, err := w.mergeInternal(ctx, current.Hash(), fo.FETCH_HEAD, &mergeInternalOptions{
+		current: current.Name(),
+		branch1: branchName,
+		branch2: string(remoteRefName),
+		squash:  opts.Squash,
+		// the message of pull merges is generated, commit.policies only apply to zeta merge
+		noVerify: true,
+		message:  messagePrefix,
+		messageFn: func() string {
+			message, _ := w.mergeMessageFromPrompt(ctx, messagePrefix)
+			return message
+		},
 	})
 	if err != nil {
 		return err
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
@@ -19,14 +20,85 @@ import (
 )
 
 type RebaseOptions struct {
-	Branch   string
-	Upstream string
-	Onto     string
-	Abort    bool
-	Continue bool
+	Branch                    string
+	Upstream                  string
+	Onto                      string
+	CommitterDateIsAuthorDate bool // committer date of the rebased commits is the author date instead of now
+	RebaseMerges              bool // recreate the merge commits instead of linearizing the history
+	Abort                     bool
+	Continue                  bool
 }
 
+/*
+Rebase replays the commits of the branch which are not in upstream on top of onto, onto defaults to upstream.
+
+First let’s assume your topic is based on branch next. For example, a feature developed
+in topic depends on some functionality which is found in next.
+
+	o---o---o---o---o  master
+		\
+			o---o---o---o---o  next
+							\
+							o---o---o  topic
+
+We want to make topic forked from branch master; for example, because the functionality
+on which topic depends was merged into the more stable master branch. We want our tree to
+look like this:
+
+	o---o---o---o---o  master
+		|            \
+		|             o'--o'--o'  topic
+		\
+			o---o---o---o---o  next
+
+We can get this using the following command:
+
+	zeta rebase --onto master next topic
+
+Another example of --onto option is to rebase part of a branch. If we have the following
+situation:
+
+							H---I---J topicB
+							/
+					E---F---G  topicA
+				/
+	A---B---C---D  master
+
+then the command
+
+	zeta rebase --onto master topicA topicB
+
+would result in:
+
+				H'--I'--J'  topicB
+				/
+				| E---F---G  topicA
+				|/
+	A---B---C---D  master
+
+This is useful when topicB does not depend on topicA.
+
+A range of commits could also be removed with rebase. If we have the following situation:
+
+	E---F---G---H---I---J  topicA
+
+then the command
+
+	zeta rebase --onto topicA~5 topicA~3 topicA
+
+would result in the removal of commits F and G:
+
+	E---H'---I'---J'  topicA
+
+This is useful if F and G were flawed in some way, or should not be part of topicA. Note
+that the argument to --onto and the <upstream> parameter can be any valid commit-ish.
+*/
 func (w *Worktree) Rebase(ctx context.Context, opts *RebaseOptions) error {
+	unlock, err := w.lock("rebase")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if opts.Abort {
 		return w.rebaseAbort(ctx)
 	}
@@ -47,50 +119,46 @@ func (w *Worktree) Rebase(ctx context.Context, opts *RebaseOptions) error {
 		die_error("resolve HEAD: %v", err)
 		return err
 	}
+	if opts.Branch != "HEAD" && plumbing.NewBranchReferenceName(opts.Branch) != current.Name() {
+		if err := w.SwitchBranch(ctx, opts.Branch, &SwitchOptions{Force: false}); err != nil {
+			die_error("can not switch branch %s", err)
+			return err
+		}
+		// rebase the branch switched to
+		if current, err = w.Current(); err != nil {
+			die_error("resolve HEAD: %v", err)
+			return err
+		}
+	}
 	currentName := current.Name()
 	if !currentName.IsBranch() {
 		die_error("reference '%s' not branch", currentName)
 		return errors.New("reference not branch")
 	}
 	branchName := currentName.BranchName()
-
-	if opts.Branch != "HEAD" && opts.Branch != branchName {
-		if err := w.SwitchBranch(ctx, opts.Branch, &SwitchOptions{Force: false}); err != nil {
-			die_error("can not switch branch %s", err)
-			return err
-		}
+	upstream, err := w.Revision(ctx, opts.Upstream)
+	if err != nil {
+		die_error("unable resolve upstream %v", err)
+		return err
 	}
-
-	var newRev plumbing.Hash
-	var messagePrefix string
-	if opts.Onto != "" {
-		ontoRev, err := w.Revision(ctx, opts.Onto)
-		if err != nil {
+	onto := upstream
+	messagePrefix := fmt.Sprintf("Rebase branch '%s' onto %s", branchName, onto)
+	if len(opts.Onto) != 0 {
+		if onto, err = w.Revision(ctx, opts.Onto); err != nil {
 			die_error("unable resolve onto %v", err)
 			return err
 		}
-		upsRev, err := w.Revision(ctx, opts.Upstream)
-		if err != nil {
-			die_error("unable resolve upstream %v", err)
-			return err
-		}
-		messagePrefix = fmt.Sprintf("Rebase branch '%s' with upstream %s onto %s", branchName, opts.Branch, ontoRev)
-		newRev, err = w.rebaseWithUpstream(ctx, current.Hash(), upsRev, ontoRev, currentName, plumbing.ReferenceName(opts.Onto), false)
-		if err != nil {
-			die_error("rebase: %v", err)
-			return err
-		}
-	} else {
-		ontoRev, err := w.Revision(ctx, opts.Upstream)
-		if err != nil {
-			die_error("unable resolve onto %v", err)
-			return err
-		}
-		messagePrefix = fmt.Sprintf("Rebase branch '%s' onto %s", branchName, ontoRev)
-		newRev, err = w.rebaseInternal(ctx, current.Hash(), ontoRev, currentName, plumbing.ReferenceName(opts.Onto), false)
-		if err != nil {
-			return err
-		}
+		messagePrefix = fmt.Sprintf("Rebase branch '%s' with upstream %s onto %s", branchName, upstream, onto)
+	}
+	newRev, err := w.rebaseRange(ctx, &RebaseMD{
+		REBASE_HEAD:                   current.Hash(),
+		ONTO:                          onto,
+		HEAD:                          currentName,
+		REBASE_MERGES:                 opts.RebaseMerges,
+		COMMITTER_DATE_IS_AUTHOR_DATE: opts.CommitterDateIsAuthorDate,
+	}, upstream)
+	if err != nil {
+		return err
 	}
 
 	if err := w.DoUpdate(ctx, current.Name(), current.Hash(), newRev, w.NewCommitter(), "rebase: "+messagePrefix); err != nil {
@@ -116,278 +184,225 @@ func (w *Worktree) Rebase(ctx context.Context, opts *RebaseOptions) error {
 //
 //	 A-->B-->G-->H-->K-->C-->D-->E (our rebased)
 //
-//	merge K & C  merge-base; B, parent K;    A-->B-->G-->H-->K-->C(n)
-//	merge K & D  merge-base: B, parent C(n); A-->B-->G-->H-->K-->C(n)-->D(n)
-//	merge K & E  merge-base: B, parent D(n); A-->B-->G-->H-->K-->C(n)-->D(n)-->E(n)
-func (w *Worktree) rebaseInternal(ctx context.Context, our, onto plumbing.Hash, ourBranch, ontoBranch plumbing.ReferenceName, textconv bool) (plumbing.Hash, error) {
-	oursCommit, err := w.odb.Commit(ctx, our)
+//	pick C  base: B, parent K;    A-->B-->G-->H-->K-->C(n)
+//	pick D  base: C, parent C(n); A-->B-->G-->H-->K-->C(n)-->D(n)
+//	pick E  base: D, parent D(n); A-->B-->G-->H-->K-->C(n)-->D(n)-->E(n)
+func (w *Worktree) rebaseInternal(ctx context.Context, our, onto plumbing.Hash, ourBranch plumbing.ReferenceName) (plumbing.Hash, error) {
+	return w.rebaseRange(ctx, &RebaseMD{REBASE_HEAD: our, ONTO: onto, HEAD: ourBranch}, onto)
+}
+
+// rebaseRange replays the commits of md.REBASE_HEAD which are not in upstream onto md.ONTO.
+func (w *Worktree) rebaseRange(ctx context.Context, md *RebaseMD, upstream plumbing.Hash) (plumbing.Hash, error) {
+	oursCommit, err := w.odb.Commit(ctx, md.REBASE_HEAD)
 	if err != nil {
 		return plumbing.ZeroHash, err
 	}
-	ontoCommit, err := w.odb.Commit(ctx, onto)
+	upsCommit, err := w.odb.Commit(ctx, upstream)
 	if err != nil {
 		return plumbing.ZeroHash, err
 	}
-	bases, err := oursCommit.MergeBase(ctx, ontoCommit)
+	bases, err := oursCommit.MergeBase(ctx, upsCommit)
 	if err != nil {
-		die_error("rebase %s onto %s: %v", our, onto, err)
+		die_error("rebase %s onto %s: %v", md.REBASE_HEAD, upstream, err)
 		return plumbing.ZeroHash, err
 	}
 	if len(bases) == 0 {
 		fmt.Fprintf(os.Stderr, "rebase: %s\n", W("refusing to merge unrelated histories"))
 		return plumbing.ZeroHash, ErrUnrelatedHistories
 	}
-	ignore := make([]plumbing.Hash, 0, 2)
 	for _, c := range bases {
-		ignore = append(ignore, c.Hash)
+		md.UPSTREAM = append(md.UPSTREAM, c.Hash)
 	}
-	baseTree, err := bases[0].Root(ctx)
+	md.LAST = md.ONTO
+	md.REWRITTEN = make(map[string]plumbing.Hash)
+	r, err := w.newRebaser(ctx, md)
 	if err != nil {
-		die_error("unable resolve root tree: %v", err)
 		return plumbing.ZeroHash, err
 	}
-	ontoTree, err := ontoCommit.Root(ctx)
-	if err != nil {
-		die_error("resolve onto tree: %v", err)
-		return plumbing.ZeroHash, err
-	}
-	// TODO: rebase: merge commits should be avoided as much as possible
-	commits, err := w.revList(ctx, our, ignore, LogOrderTopo, nil)
+	return r.replay(ctx)
+}
+
+// rebaser picks the commits of a rebase one by one. Without REBASE_MERGES merge commits are dropped and the other
+// commits are chained on LAST, otherwise the parents of each commit are mapped to their rewritten commits so that
+// the merge topology is recreated, merge commits keep their changes against the first parent.
+type rebaser struct {
+	*Worktree
+	md          *RebaseMD
+	commits     []*object.Commit // topological order, newest first
+	inRange     map[plumbing.Hash]bool
+	committer   *object.Signature
+	mergeDriver odb.MergeDriver
+	continued   bool // REBASE-MD of the stopped rebase is replaced on conflicts
+}
+
+func (w *Worktree) newRebaser(ctx context.Context, md *RebaseMD) (*rebaser, error) {
+	commits, err := w.revList(ctx, md.REBASE_HEAD, md.UPSTREAM, LogOrderTopo, nil)
 	if err != nil {
 		die_error("log range base error: %v", err)
-		return plumbing.ZeroHash, err
+		return nil, err
 	}
-	lastCommitID := onto
-	mergeDriver := w.resolveMergeDriver()
-	for i := len(commits) - 1; i >= 0; i-- {
-		c := commits[i]
-		if len(c.Parents) == 2 {
-			// skip merge commit
-			continue
-		}
-		t, err := c.Root(ctx)
-		if err != nil {
-			die_error("resolve %s tree: %v", c.Hash, err)
-			return plumbing.ZeroHash, err
-		}
-		result, err := w.odb.MergeTree(ctx, baseTree, t, ontoTree, &odb.MergeOptions{
-			Branch1:       ourBranch.BranchName(),
-			Branch2:       ontoBranch.Short(),
-			DetectRenames: true,
-			Textconv:      textconv,
-			MergeDriver:   mergeDriver,
-			TextResolver:  w.readMissingText,
-		})
-		if err != nil {
-			die_error("merge-tree: %v", err)
-			return plumbing.ZeroHash, err
-		}
-		if len(result.Conflicts) != 0 {
-			err = w.checkoutRebaseConflicts(ctx, &RebaseMD{
-				REBASE_HEAD: our,
-				ONTO:        onto,
-				STOPPED:     c.Hash,
-				LAST:        lastCommitID,
-				MERGE_TREE:  result.NewTree,
-				HEAD:        ourBranch,
-			}, result.Conflicts)
-			if err != nil {
-				die_error("unable checkout conflicts: %v", err)
-			}
-			return plumbing.ZeroHash, ErrHasConflicts
-		}
-		cc := &object.Commit{
-			Author:       c.Author,
-			Committer:    c.Committer,
-			Parents:      []plumbing.Hash{lastCommitID},
-			Tree:         result.NewTree,
-			ExtraHeaders: c.ExtraHeaders,
-			Message:      c.Message,
+	inRange := make(map[plumbing.Hash]bool, len(commits))
+	for _, c := range commits {
+		inRange[c.Hash] = true
+	}
+	return &rebaser{
+		Worktree:    w,
+		md:          md,
+		commits:     commits,
+		inRange:     inRange,
+		committer:   w.NewCommitter(),
+		mergeDriver: w.resolveMergeDriver(),
+	}, nil
+}
+
+// parents returns the parents of the rebased c.
+func (r *rebaser) parents(c *object.Commit) []plumbing.Hash {
+	if !r.md.REBASE_MERGES {
+		return []plumbing.Hash{r.md.LAST}
+	}
+	parents := make([]plumbing.Hash, 0, len(c.Parents))
+	seen := make(map[plumbing.Hash]bool)
+	for i, p := range c.Parents {
+		switch newRev, ok := r.md.REWRITTEN[p.String()]; {
+		case ok:
+			p = newRev
+		case i == 0 && !r.inRange[p]:
+			p = r.md.ONTO
 		}
-		newRev, err := w.odb.WriteEncoded(cc)
-		if err != nil {
-			die_error("unable encode commit: %v", err)
-			return plumbing.ZeroHash, err
+		// merges of branches outside the range keep their parents
+		if !seen[p] {
+			seen[p] = true
+			parents = append(parents, p)
 		}
-		lastCommitID = newRev
 	}
-	return lastCommitID, nil
+	if len(parents) == 0 {
+		parents = append(parents, r.md.ONTO)
+	}
+	return parents
 }
 
-/*
-First let’s assume your topic is based on branch next. For example, a feature developed
-in topic depends on some functionality which is found in next.
-
-	o---o---o---o---o  master
-		\
-			o---o---o---o---o  next
-							\
-							o---o---o  topic
-
-We want to make topic forked from branch master; for example, because the functionality
-on which topic depends was merged into the more stable master branch. We want our tree to
-look like this:
-
-	o---o---o---o---o  master
-		|            \
-		|             o'--o'--o'  topic
-		\
-			o---o---o---o---o  next
-
-We can get this using the following command:
-
-	git rebase --onto master next topic
-
-Another example of --onto option is to rebase part of a branch. If we have the following
-situation:
-
-							H---I---J topicB
-							/
-					E---F---G  topicA
-				/
-	A---B---C---D  master
-
-then the command
-
-	git rebase --onto master topicA topicB
-
-would result in:
-
-				H'--I'--J'  topicB
-				/
-				| E---F---G  topicA
-				|/
-	A---B---C---D  master
-
-This is useful when topicB does not depend on topicA.
-
-A range of commits could also be removed with rebase. If we have the following situation:
-
-	E---F---G---H---I---J  topicA
-
-then the command
-
-	git rebase --onto topicA~5 topicA~3 topicA
-
-would result in the removal of commits F and G:
-
-	E---H'---I'---J'  topicA
-
-This is useful if F and G were flawed in some way, or should not be part of topicA. Note
-that the argument to --onto and the <upstream> parameter can be any valid commit-ish.
-*/
-func (w *Worktree) rebaseWithUpstream(ctx context.Context, our, upstream, onto plumbing.Hash, ourBranch, ontoBranch plumbing.ReferenceName, textconv bool) (plumbing.Hash, error) {
-	oursCommit, err := w.ODB().Commit(ctx, our)
-	if err != nil {
-		return plumbing.ZeroHash, err
-	}
-	upsCommit, err := w.ODB().Commit(ctx, upstream)
-	if err != nil {
-		return plumbing.ZeroHash, err
-	}
-	ontoCommit, err := w.ODB().Commit(ctx, onto)
+func (r *rebaser) commitTree(ctx context.Context, oid plumbing.Hash) (*object.Tree, error) {
+	c, err := r.odb.Commit(ctx, oid)
 	if err != nil {
-		return plumbing.ZeroHash, err
+		return nil, err
 	}
+	return c.Root(ctx)
+}
 
-	ourBases, err := oursCommit.MergeBase(ctx, upsCommit)
-	if err != nil {
-		die_error("calc base of upstream with branch error: %v", err)
-		return plumbing.ZeroHash, err
-	}
-	if len(ourBases) == 0 {
-		fmt.Fprintln(os.Stderr, "rebase: refusing to use unrelated histories upstream")
-		return plumbing.ZeroHash, err
+// pick applies the changes of c against its first parent to the tree of parent.
+func (r *rebaser) pick(ctx context.Context, c *object.Commit, parent plumbing.Hash) (*odb.MergeResult, error) {
+	base := r.odb.EmptyTree()
+	if len(c.Parents) != 0 {
+		var err error
+		if base, err = r.commitTree(ctx, c.Parents[0]); err != nil {
+			die_error("resolve %s tree: %v", c.Parents[0], err)
+			return nil, err
+		}
 	}
-	ontoBases, err := ontoCommit.MergeBase(ctx, ourBases[0])
+	ours, err := r.commitTree(ctx, parent)
 	if err != nil {
-		die_error("calc base of branch with onto error: %v", err)
-		return plumbing.ZeroHash, err
-	}
-	if len(ontoBases) == 0 {
-		fmt.Fprintln(os.Stderr, "rebase: refusing to use unrelated histories onto")
-		return plumbing.ZeroHash, err
+		die_error("resolve %s tree: %v", parent, err)
+		return nil, err
 	}
-
-	ontoBaseTree, err := ontoBases[0].Root(ctx)
+	theirs, err := c.Root(ctx)
 	if err != nil {
-		die_error("unable resolve root tree: %v", err)
-		return plumbing.ZeroHash, err
-
+		die_error("resolve %s tree: %v", c.Hash, err)
+		return nil, err
 	}
-	ontoTree, err := ontoCommit.Root(ctx)
+	result, err := r.odb.MergeTree(ctx, base, ours, theirs, &odb.MergeOptions{
+		Branch1:       "HEAD",
+		Branch2:       fmt.Sprintf("%s (%s)", shortHash(c.Hash), c.Subject()),
+		DetectRenames: true,
+		MergeDriver:   r.mergeDriver,
+		TextResolver:  r.readMissingText,
+	})
 	if err != nil {
-		die_error("resolve onto tree: %v", err)
-		return plumbing.ZeroHash, err
+		die_error("merge-tree: %v", err)
+		return nil, err
 	}
+	return result, nil
+}
 
-	ignore := make([]plumbing.Hash, 0, 2)
-	for _, c := range ourBases {
-		ignore = append(ignore, c.Hash)
+// commit writes the rebased c, a pick which became empty because its changes are already in parents[0] is dropped.
+func (r *rebaser) commit(ctx context.Context, c *object.Commit, parents []plumbing.Hash, tree plumbing.Hash) (plumbing.Hash, error) {
+	if len(parents) == 1 && len(c.Parents) != 0 {
+		p, err := r.odb.Commit(ctx, parents[0])
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		origin, err := r.odb.Commit(ctx, c.Parents[0])
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if p.Tree == tree && origin.Tree != c.Tree {
+			fmt.Fprintf(os.Stderr, W("dropping %s %s -- patch contents already upstream\n"), shortHash(c.Hash), c.Subject())
+			return parents[0], nil
+		}
 	}
-	// TODO: rebase: merge commits should be avoided as much as possible
-	commits, err := w.revList(ctx, our, ignore, LogOrderTopo, nil)
+	committer := *r.committer
+	if r.md.COMMITTER_DATE_IS_AUTHOR_DATE {
+		committer.When = c.Author.When
+	}
+	newRev, err := r.odb.WriteEncoded(&object.Commit{
+		Author:       c.Author,
+		Committer:    committer,
+		Parents:      parents,
+		Tree:         tree,
+		ExtraHeaders: c.ExtraHeaders,
+		Message:      c.Message,
+	})
 	if err != nil {
-		die_error("log range base error: %v", err)
+		die_error("unable encode commit: %v", err)
 		return plumbing.ZeroHash, err
 	}
-	lastCommitID := onto
-	mergeDriver := w.resolveMergeDriver()
-	for i := len(commits) - 1; i >= 0; i-- {
-		c := commits[i]
-		if len(c.Parents) == 2 {
-			// skip merge commit
-			continue
-		}
-		t, err := c.Root(ctx)
-		if err != nil {
-			die_error("resolve %s tree: %v", c.Hash, err)
-			return plumbing.ZeroHash, err
+	return newRev, nil
+}
 
+// replay picks the commits which are not rewritten yet, oldest first, and returns the rebased REBASE_HEAD. On
+// conflicts the rebase metadata is saved and the conflicts are checked out.
+func (r *rebaser) replay(ctx context.Context) (plumbing.Hash, error) {
+	md := r.md
+	for i := len(r.commits) - 1; i >= 0; i-- {
+		c := r.commits[i]
+		if _, ok := md.REWRITTEN[c.Hash.String()]; ok {
+			continue
 		}
-		result, err := w.ODB().MergeTree(ctx, ontoBaseTree, t, ontoTree, &odb.MergeOptions{
-			Branch1:       ourBranch.BranchName(),
-			Branch2:       ontoBranch.Short(),
-			DetectRenames: true,
-			Textconv:      textconv,
-			MergeDriver:   mergeDriver,
-			TextResolver:  w.readMissingText,
-		})
-		if err != nil {
-			die_error("merge-tree: %v", err)
-			return plumbing.ZeroHash, err
+		if len(c.Parents) > 1 && !md.REBASE_MERGES {
+			// skip merge commit
+			continue
 		}
-		if len(result.Conflicts) != 0 {
-			err = w.checkoutRebaseConflicts(ctx, &RebaseMD{
-				REBASE_HEAD: our,
-				ONTO:        onto,
-				STOPPED:     c.Hash,
-				LAST:        lastCommitID,
-				MERGE_TREE:  result.NewTree,
-				HEAD:        ourBranch,
-			}, result.Conflicts)
+		parents := r.parents(c)
+		newRev := c.Hash
+		// the parents did not change, the commit is kept as is
+		if !slices.Equal(parents, c.Parents) || md.COMMITTER_DATE_IS_AUTHOR_DATE {
+			result, err := r.pick(ctx, c, parents[0])
 			if err != nil {
-				die_error("unable checkout conflicts: %v", err)
+				return plumbing.ZeroHash, err
+			}
+			if len(result.Conflicts) != 0 {
+				md.STOPPED = c.Hash
+				md.LAST = parents[0]
+				md.MERGE_TREE = result.NewTree
+				if r.continued {
+					_ = os.Remove(filepath.Join(r.odb.Root(), REBASE_MD))
+				}
+				if err := r.checkoutRebaseConflicts(ctx, md, result.Conflicts); err != nil {
+					die_error("unable checkout conflicts: %v", err)
+				}
+				fmt.Fprintln(os.Stderr, W("Automatic merge failed; fix conflicts and then commit the result."))
+				return plumbing.ZeroHash, ErrHasConflicts
+			}
+			if newRev, err = r.commit(ctx, c, parents, result.NewTree); err != nil {
+				return plumbing.ZeroHash, err
 			}
-			fmt.Fprintln(os.Stderr, W("Automatic merge failed; fix conflicts and then commit the result."))
-			return plumbing.ZeroHash, ErrHasConflicts
-		}
-		cc := &object.Commit{
-			Author:       c.Author,
-			Committer:    c.Committer,
-			Parents:      []plumbing.Hash{lastCommitID},
-			Tree:         result.NewTree,
-			ExtraHeaders: c.ExtraHeaders,
-			Message:      c.Message,
-		}
-		newRev, err := w.ODB().WriteEncoded(cc)
-		if err != nil {
-			die_error("unable encode commit: %v", err)
-			return plumbing.ZeroHash, err
 		}
-		lastCommitID = newRev
+		md.REWRITTEN[c.Hash.String()] = newRev
+		md.LAST = newRev
+	}
+	if newRev, ok := md.REWRITTEN[md.REBASE_HEAD.String()]; ok {
+		return newRev, nil
 	}
-	return lastCommitID, nil
+	return md.LAST, nil
 }
 
 type RebaseMD struct {
@@ -397,6 +412,12 @@ type RebaseMD struct {
 	LAST        plumbing.Hash          `toml:"LAST"`        // LAST
 	MERGE_TREE  plumbing.Hash          `toml:"MERGE_TREE"`  // MERGE_TREE
 	HEAD        plumbing.ReferenceName `toml:"HEAD"`        // HEAD aka CURRENT
+	// UPSTREAM: merge bases of REBASE_HEAD and the upstream, commits reachable from them are not replayed
+	UPSTREAM []plumbing.Hash `toml:"UPSTREAM,omitempty"`
+	// REWRITTEN: replayed commits and their rebased commits
+	REWRITTEN                     map[string]plumbing.Hash `toml:"REWRITTEN,omitempty"`
+	REBASE_MERGES                 bool                     `toml:"REBASE_MERGES,omitempty"`
+	COMMITTER_DATE_IS_AUTHOR_DATE bool                     `toml:"COMMITTER_DATE_IS_AUTHOR_DATE,omitempty"`
 }
 
 const (
@@ -497,6 +518,13 @@ func (w *Worktree) rebaseContinue(ctx context.Context) error {
 		return err
 	}
 	trace.DbgPrint("%s", md.REBASE_HEAD)
+	if len(md.UPSTREAM) == 0 {
+		// REBASE-MD without UPSTREAM: continue with the commits after STOPPED
+		md.UPSTREAM = []plumbing.Hash{md.STOPPED}
+	}
+	if md.REWRITTEN == nil {
+		md.REWRITTEN = make(map[string]plumbing.Hash)
+	}
 	last, err := w.odb.Commit(ctx, md.LAST)
 	if err != nil {
 		die_error("unable open last tree: %v", err)
@@ -507,95 +535,27 @@ func (w *Worktree) rebaseContinue(ctx context.Context) error {
 		die_error("unable write resolved tree: %v", err)
 		return err
 	}
-	newBaseTree, err := w.odb.Tree(ctx, resolvedTree)
-	if err != nil {
-		die_error("unable write resolved tree: %v", err)
-		return err
-	}
 	trace.DbgPrint("conflicts resolved: %s", resolvedTree)
 	stoppedCC, err := w.odb.Commit(ctx, md.STOPPED)
 	if err != nil {
 		die_error("unable resolve stopped commit: %v", err)
 		return err
 	}
-	cc := &object.Commit{
-		Author:       stoppedCC.Author,
-		Committer:    stoppedCC.Committer,
-		Parents:      []plumbing.Hash{md.LAST},
-		Tree:         resolvedTree,
-		ExtraHeaders: stoppedCC.ExtraHeaders,
-		Message:      stoppedCC.Message,
-	}
-	lastCommitID, err := w.odb.WriteEncoded(cc)
+	r, err := w.newRebaser(ctx, md)
 	if err != nil {
-		die_error("unable encode commit: %v", err)
 		return err
 	}
-	ontoTree, err := w.getTreeFromCommitHash(ctx, md.ONTO)
+	newRev, err := r.commit(ctx, stoppedCC, r.parents(stoppedCC), resolvedTree)
 	if err != nil {
-		die_error("unable resolve onto: %v", err)
 		return err
 	}
-	mergeDriver := w.resolveMergeDriver()
-	// TODO: rebase: merge commits should be avoided as much as possible
-	commits, err := w.revList(ctx, md.REBASE_HEAD, []plumbing.Hash{md.STOPPED}, LogOrderTopo, nil)
+	md.REWRITTEN[md.STOPPED.String()] = newRev
+	md.LAST = newRev
+	r.continued = true
+	lastCommitID, err := r.replay(ctx)
 	if err != nil {
-		die_error("log range base error: %v", err)
 		return err
 	}
-	for i := len(commits) - 1; i >= 0; i-- {
-		c := commits[i]
-		if len(c.Parents) == 2 {
-			// skip merge commit
-			continue
-		}
-		t, err := c.Root(ctx)
-		if err != nil {
-			die_error("resolve %s tree: %v", c.Hash, err)
-			return err
-		}
-		result, err := w.odb.MergeTree(ctx, newBaseTree, t, ontoTree, &odb.MergeOptions{
-			Branch1:       "rebase-HEAD",
-			Branch2:       "rebase-ONTO",
-			DetectRenames: true,
-			Textconv:      false,
-			MergeDriver:   mergeDriver,
-			TextResolver:  w.readMissingText,
-		})
-		if err != nil {
-			die_error("merge-tree: %v", err)
-			return err
-		}
-		if len(result.Conflicts) != 0 {
-			err = w.checkoutRebaseConflicts(ctx, &RebaseMD{
-				REBASE_HEAD: md.REBASE_HEAD,
-				ONTO:        md.ONTO,
-				STOPPED:     c.Hash,
-				LAST:        lastCommitID,
-				MERGE_TREE:  result.NewTree,
-				HEAD:        md.HEAD,
-			}, result.Conflicts)
-			if err != nil {
-				die_error("unable checkout conflicts: %v", err)
-			}
-			fmt.Fprintln(os.Stderr, W("Automatic merge failed; fix conflicts and then commit the result."))
-			return ErrHasConflicts
-		}
-		cc := &object.Commit{
-			Author:       c.Author,
-			Committer:    c.Committer,
-			Parents:      []plumbing.Hash{lastCommitID},
-			Tree:         result.NewTree,
-			ExtraHeaders: c.ExtraHeaders,
-			Message:      c.Message,
-		}
-		newRev, err := w.odb.WriteEncoded(cc)
-		if err != nil {
-			die_error("unable encode commit: %v", err)
-			return err
-		}
-		lastCommitID = newRev
-	}
 	branchName := md.HEAD.BranchName()
 	messagePrefix := fmt.Sprintf("Rebase branch '%s' onto %s", branchName, md.ONTO)
 	if err := w.DoUpdate(ctx, md.HEAD, md.REBASE_HEAD, lastCommitID, w.NewCommitter(), "rebase: "+messagePrefix); err != nil {
@@ -613,7 +573,6 @@ func (w *Worktree) rebaseContinue(ctx context.Context) error {
 		die_error("reset worktree: %v", err)
 		return err
 	}
-	_ = os.Remove(filepath.Join(w.ODB().Root(), REBASE_MD))
 	fmt.Fprintf(os.Stderr, "%s %s..%s\n", W("Updating"), shortHash(md.REBASE_HEAD), shortHash(lastCommitID))
 	fmt.Fprintf(os.Stderr, W("Successfully rebased and updated %s.\n"), md.HEAD)
 	_ = os.Remove(filepath.Join(w.odb.Root(), REBASE_MD))
@@ -0,0 +1,105 @@
+package zeta
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+func TestRebaseRange(t *testing.T) {
+	ctx := t.Context()
+	r, fixture := newTestRepository(t, "rebase")
+	odb := r.ODB()
+	// E---F---G---H  topic
+	e := fixture.commit("E\n", map[string]string{"e.txt": "e\n"})
+	f := fixture.commit("F\n", map[string]string{"f.txt": "f\n"}, e)
+	g := fixture.commit("G\n", map[string]string{"g.txt": "g\n"}, f)
+	h := fixture.commit("H\n", map[string]string{"h.txt": "h\n"}, g)
+	authored := fixture.object(h).Author.When
+
+	w := r.Worktree()
+	// zeta rebase --onto E G H: drop F and G
+	newRev, err := w.rebaseRange(ctx, &RebaseMD{
+		REBASE_HEAD:                   h,
+		ONTO:                          e,
+		HEAD:                          plumbing.NewBranchReferenceName("topic"),
+		COMMITTER_DATE_IS_AUTHOR_DATE: true,
+	}, g)
+	if err != nil {
+		t.Fatalf("rebase error: %v", err)
+	}
+	cc, err := odb.Commit(ctx, newRev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cc.Message != "H\n" || len(cc.Parents) != 1 || cc.Parents[0] != e {
+		t.Fatalf("unexpected rebased commit: %q %v", cc.Message, cc.Parents)
+	}
+	if !cc.Committer.When.Equal(authored) {
+		t.Fatalf("unexpected committer date: %v", cc.Committer.When)
+	}
+	root, err := cc.Root(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(root.Entries))
+	for _, e := range root.Entries {
+		names = append(names, e.Name)
+	}
+	if strings.Join(names, ",") != "e.txt,h.txt" {
+		t.Fatalf("unexpected rebased tree: %v", names)
+	}
+}
+
+func TestRebaseMerges(t *testing.T) {
+	ctx := t.Context()
+	r, f := newTestRepository(t, "rebase")
+	odb := r.ODB()
+	//	A---B  master
+	//	 \
+	//	  C---M  topic
+	//	   \ /
+	//	    D
+	a := f.commit("A\n", map[string]string{"a.txt": "a\n"})
+	b := f.commit("B\n", map[string]string{"b.txt": "b\n"}, a)
+	c := f.commit("C\n", map[string]string{"c.txt": "c\n"}, a)
+	d := f.commit("D\n", map[string]string{"d.txt": "d\n"}, c)
+	m := f.commit("M\n", map[string]string{"d.txt": "d\n"}, c, d)
+
+	w := r.Worktree()
+	newRev, err := w.rebaseRange(ctx, &RebaseMD{
+		REBASE_HEAD:   m,
+		ONTO:          b,
+		HEAD:          plumbing.NewBranchReferenceName("topic"),
+		REBASE_MERGES: true,
+	}, b)
+	if err != nil {
+		t.Fatalf("rebase error: %v", err)
+	}
+	merge, err := odb.Commit(ctx, newRev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merge.Message != "M\n" || len(merge.Parents) != 2 {
+		t.Fatalf("merge topology not recreated: %q %v", merge.Message, merge.Parents)
+	}
+	newC, err := odb.Commit(ctx, merge.Parents[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	newD, err := odb.Commit(ctx, merge.Parents[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newC.Message != "C\n" || newC.Parents[0] != b || newD.Message != "D\n" || newD.Parents[0] != newC.Hash {
+		t.Fatalf("unexpected rebased parents: %v %v", newC.Parents, newD.Parents)
+	}
+	root, err := merge.Root(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Entries) != 4 {
+		t.Fatalf("unexpected merge tree entries: %d", len(root.Entries))
+	}
+}
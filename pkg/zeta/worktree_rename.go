@@ -158,6 +158,11 @@ func (w *Worktree) renameConflict(ctx context.Context, source, destination strin
 zeta rename [-v] [-f] [-n] [-k] <source> <destination>
 */
 func (w *Worktree) Rename(ctx context.Context, source, destination string, opts *RenameOptions) error {
+	unlock, err := w.lock("mv")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	newSource, newDestination, err := w.validateRenameArgs(source, destination)
 	if err != nil {
 		return err
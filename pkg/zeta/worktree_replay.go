@@ -58,6 +58,11 @@ func (w *Worktree) replayMD() (*ReplayMD, error) {
 }
 
 func (w *Worktree) CherryPick(ctx context.Context, opts *CherryPickOptions) error {
+	unlock, err := w.lock("cherry-pick")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if opts.Abort {
 		return w.cherryPickAbort(ctx)
 	}
@@ -261,6 +266,11 @@ func (w *Worktree) cherryPickContinue(ctx context.Context) error {
 }
 
 func (w *Worktree) Revert(ctx context.Context, opts *RevertOptions) error {
+	unlock, err := w.lock("revert")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if opts.Abort {
 		return w.revertAbort(ctx)
 	}
@@ -138,6 +138,11 @@ func (w *Worktree) restoreIndexMatch(ctx context.Context, entries []*odb.TreeEnt
 }
 
 func (w *Worktree) Restore(ctx context.Context, opts *RestoreOptions) error {
+	unlock, err := w.lock("restore")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	entries, err := w.lsRestoreEntries(ctx, opts)
 	if err != nil {
 		die("zeta restore error: %v", err)
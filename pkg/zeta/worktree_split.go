@@ -0,0 +1,503 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/diferenco"
+	"github.com/antgroup/hugescm/modules/env"
+	"github.com/antgroup/hugescm/modules/merkletrie"
+	"github.com/antgroup/hugescm/modules/merkletrie/noder"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/term"
+	"github.com/antgroup/hugescm/modules/tui"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+type SplitCommitOptions struct {
+	Commit  string   // commit to split
+	Paths   []string // changes under the paths go to the first commit, nothing is asked
+	Message []string // message of the first commit, required with Paths
+}
+
+// splitPart is a commit carved out of the commit being split.
+type splitPart struct {
+	tree    plumbing.Hash
+	message string
+}
+
+// SplitCommit splits an unpushed commit of the current branch into several commits: the changes are picked hunk by
+// hunk (or by paths) into new commits on top of the parent, the rest stays in the last commit which keeps the
+// original message. The commits after it are replayed with their trees unchanged, so the index and the worktree are
+// left alone.
+func (w *Worktree) SplitCommit(ctx context.Context, opts *SplitCommitOptions) error {
+	unlock, err := w.lock("split-commit")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	for _, md := range []string{REBASE_MD, REPLAY_MD} {
+		if _, err := os.Stat(filepath.Join(w.odb.Root(), md)); err == nil {
+			die_error("'%s' exists, finish or abort the rebase, cherry-pick or revert in progress first", md)
+			return ErrAborting
+		}
+	}
+	if len(opts.Paths) == 0 && (!term.IsTerminal(os.Stdin.Fd()) || !env.ZETA_TERMINAL_PROMPT.SimpleAtob(true)) {
+		die_error("picking hunks requires a terminal, use --path to split by paths")
+		return ErrAborting
+	}
+	current, oldRev, err := w.current()
+	if err != nil {
+		die_error("resolve HEAD: %v", err)
+		return err
+	}
+	if !current.IsBranch() {
+		die_error("reference '%s' not branch", current)
+		return errors.New("reference not branch")
+	}
+	rev, err := w.Revision(ctx, opts.Commit)
+	if err != nil {
+		die_error("unable resolve %s: %v", opts.Commit, err)
+		return err
+	}
+	cc, err := w.odb.Commit(ctx, rev)
+	if err != nil {
+		die_error("zeta split-commit resolve '%s' error: %v", rev, err)
+		return err
+	}
+	if len(cc.Parents) > 1 {
+		die_error("commit %s is a merge commit", shortHash(rev))
+		return ErrAborting
+	}
+	descendants, err := w.splitDescendants(ctx, cc, current, oldRev)
+	if err != nil {
+		return err
+	}
+	parts, err := w.splitParts(ctx, cc, opts)
+	if err != nil {
+		return err
+	}
+	if len(parts) == 0 {
+		fmt.Fprintf(os.Stderr, W("No changes selected, commit %s is unchanged.\n"), shortHash(rev))
+		return nil
+	}
+	committer := w.NewCommitter()
+	parents := cc.Parents
+	for _, p := range parts {
+		newRev, err := w.odb.WriteEncoded(&object.Commit{
+			Author:    cc.Author,
+			Committer: *committer,
+			Parents:   parents,
+			Tree:      p.tree,
+			Message:   p.message,
+		})
+		if err != nil {
+			die_error("unable encode commit: %v", err)
+			return err
+		}
+		parents = []plumbing.Hash{newRev}
+	}
+	// the last commit keeps the tree and the message of the commit being split
+	lastCommitID, err := w.odb.WriteEncoded(&object.Commit{
+		Author:       cc.Author,
+		Committer:    *committer,
+		Parents:      parents,
+		Tree:         cc.Tree,
+		ExtraHeaders: cc.ExtraHeaders,
+		Message:      cc.Message,
+	})
+	if err != nil {
+		die_error("unable encode commit: %v", err)
+		return err
+	}
+	for i := len(descendants) - 1; i >= 0; i-- {
+		c := descendants[i]
+		cc := &object.Commit{
+			Author:       c.Author,
+			Committer:    c.Committer,
+			Parents:      []plumbing.Hash{lastCommitID},
+			Tree:         c.Tree,
+			ExtraHeaders: c.ExtraHeaders,
+			Message:      c.Message,
+		}
+		if lastCommitID, err = w.odb.WriteEncoded(cc); err != nil {
+			die_error("unable encode commit: %v", err)
+			return err
+		}
+	}
+	messagePrefix := fmt.Sprintf("Split '%s' into %d commits", rev, len(parts)+1)
+	if err := w.DoUpdate(ctx, current, oldRev, lastCommitID, committer, "split-commit: "+messagePrefix); err != nil {
+		die_error("update split-commit: %v", err)
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s %s..%s\n", W("Updating"), shortHash(oldRev), shortHash(lastCommitID))
+	fmt.Fprintf(os.Stderr, W("Successfully split %s into %d commits and updated %s.\n"), shortHash(rev), len(parts)+1, current)
+	return nil
+}
+
+// splitDescendants returns the commits after cc on the branch, newest first. cc must not be reachable from the
+// remote branch, and the commits to replay must not be merges.
+func (w *Worktree) splitDescendants(ctx context.Context, cc *object.Commit, current plumbing.ReferenceName, head plumbing.Hash) ([]*object.Commit, error) {
+	ignore := slices.Clone(cc.Parents)
+	upstream, err := w.Reference(plumbing.NewRemoteReferenceName(plumbing.Origin, current.BranchName()))
+	switch {
+	case err == nil:
+		ignore = append(ignore, upstream.Hash())
+	case !errors.Is(err, plumbing.ErrReferenceNotFound):
+		die_error("resolve upstream: %v", err)
+		return nil, err
+	}
+	commits, err := w.revList(ctx, head, ignore, LogOrderTopo, nil)
+	if err != nil {
+		die_error("log range base error: %v", err)
+		return nil, err
+	}
+	i := slices.IndexFunc(commits, func(c *object.Commit) bool {
+		return c.Hash == cc.Hash
+	})
+	if i == -1 {
+		die_error("commit %s is not an unpushed commit of branch '%s'", shortHash(cc.Hash), current.BranchName())
+		return nil, ErrAborting
+	}
+	for _, c := range commits[:i] {
+		if len(c.Parents) != 1 {
+			die_error("unable replay merge commit %s", shortHash(c.Hash))
+			return nil, ErrAborting
+		}
+	}
+	return commits[:i], nil
+}
+
+// splitParts picks the changes of cc into commits until nothing is picked or the rest of the changes are picked.
+func (w *Worktree) splitParts(ctx context.Context, cc *object.Commit, opts *SplitCommitOptions) ([]*splitPart, error) {
+	base := w.odb.EmptyTree()
+	if len(cc.Parents) != 0 {
+		pc, err := w.odb.Commit(ctx, cc.Parents[0])
+		if err != nil {
+			die_error("zeta split-commit resolve parent error: %v", err)
+			return nil, err
+		}
+		if base, err = pc.Root(ctx); err != nil {
+			die_error("zeta split-commit resolve parent tree error: %v", err)
+			return nil, err
+		}
+	}
+	target, err := cc.Root(ctx)
+	if err != nil {
+		die_error("zeta split-commit resolve tree error: %v", err)
+		return nil, err
+	}
+	parts := make([]*splitPart, 0, 2)
+	for {
+		changes, err := object.DiffTreeWithOptions(ctx, base, target, nil, noder.NewSparseTreeMatcher(w.Core.SparseDirs))
+		if err != nil {
+			die_error("diff tree error: %v", err)
+			return nil, err
+		}
+		if len(changes) == 0 {
+			return parts, nil
+		}
+		var edits map[string]*object.TreeEntry
+		var message string
+		if len(opts.Paths) != 0 {
+			edits = splitPathEdits(changes, opts.Paths)
+			message = genMessage(opts.Message)
+		} else {
+			p := &splitPicker{Worktree: w, n: len(parts) + 1}
+			if edits, err = p.pick(ctx, changes); err != nil {
+				return nil, err
+			}
+			if len(edits) != 0 && !p.all {
+				if message, err = p.askMessage(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(edits) == 0 {
+			return parts, nil
+		}
+		newTree, err := w.odb.EditTree(ctx, base, edits)
+		if err != nil {
+			die_error("unable write tree: %v", err)
+			return nil, err
+		}
+		if newTree == target.Hash {
+			// the rest of the changes stay in the last commit
+			return parts, nil
+		}
+		parts = append(parts, &splitPart{tree: newTree, message: message})
+		if len(opts.Paths) != 0 {
+			return parts, nil
+		}
+		if base, err = w.odb.Tree(ctx, newTree); err != nil {
+			die_error("unable open tree: %v", err)
+			return nil, err
+		}
+	}
+}
+
+// splitPathEdits picks the changes under paths.
+func splitPathEdits(changes object.Changes, paths []string) map[string]*object.TreeEntry {
+	edits := make(map[string]*object.TreeEntry)
+	for _, ch := range changes {
+		for _, e := range []*object.ChangeEntry{&ch.From, &ch.To} {
+			if len(e.Name) == 0 || !splitPathMatch(e.Name, paths) {
+				continue
+			}
+			edits[e.Name] = nil
+			if e == &ch.To {
+				edits[e.Name] = &ch.To.TreeEntry
+			}
+		}
+	}
+	return edits
+}
+
+func splitPathMatch(name string, paths []string) bool {
+	for _, p := range paths {
+		p = strings.Trim(filepath.ToSlash(p), "/")
+		if len(p) == 0 || name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPicker asks which changes go to the commit n.
+type splitPicker struct {
+	*Worktree
+	n    int
+	all  bool // every change is picked
+	quit bool
+}
+
+const (
+	splitPickHelp = `y - include this change in the commit
+n - leave this change to the following commits
+a - include this hunk and the remaining hunks of the file
+d - leave this hunk and the remaining hunks of the file
+q - leave this change and all the remaining changes`
+)
+
+func (p *splitPicker) ask(choices string, format string, a ...any) (byte, error) {
+	title := fmt.Sprintf(W(format), a...)
+	for {
+		var input string
+		if err := tui.AskInput(&input, "%s [%s,?]? ", title, strings.Join(strings.Split(choices, ""), ",")); err != nil {
+			return 0, err
+		}
+		input = strings.ToLower(strings.TrimSpace(input))
+		if len(input) == 1 && strings.Contains(choices, input) {
+			return input[0], nil
+		}
+		fmt.Fprintln(os.Stderr, W(splitPickHelp))
+	}
+}
+
+func (p *splitPicker) askMessage() (string, error) {
+	for {
+		var input string
+		if err := tui.AskInput(&input, "%s: ", fmt.Sprintf(W("Message of commit #%d"), p.n)); err != nil {
+			return "", err
+		}
+		if message := genMessage([]string{strings.TrimSpace(input)}); len(message) != 0 {
+			return message, nil
+		}
+	}
+}
+
+func (p *splitPicker) pick(ctx context.Context, changes object.Changes) (map[string]*object.TreeEntry, error) {
+	edits := make(map[string]*object.TreeEntry)
+	p.all = true
+	for _, ch := range changes {
+		if p.quit {
+			p.all = false
+			break
+		}
+		action, err := ch.Action()
+		if err != nil {
+			die_error("change action error: %v", err)
+			return nil, err
+		}
+		if action == merkletrie.Modify {
+			e, picked, err := p.pickHunks(ctx, ch)
+			if err != nil {
+				return nil, err
+			}
+			if e != nil {
+				edits[ch.To.Name] = e
+			}
+			p.all = p.all && picked
+			continue
+		}
+		name, status := ch.To.Name, W("new file:")
+		if action == merkletrie.Delete {
+			name, status = ch.From.Name, W("deleted:")
+		}
+		fmt.Fprintf(os.Stderr, "%s %s\n", status, name)
+		c, err := p.ask("ynq", "Include this change in commit #%d", p.n)
+		if err != nil {
+			return nil, err
+		}
+		switch c {
+		case 'y':
+			edits[name] = nil
+			if action == merkletrie.Insert {
+				edits[name] = &ch.To.TreeEntry
+			}
+		case 'q':
+			p.quit = true
+			fallthrough
+		default:
+			p.all = false
+		}
+	}
+	return edits, nil
+}
+
+// pickHunks asks for each hunk of a modified text file, other files are picked as a whole. It returns the entry of
+// the picked content, nil if nothing is picked, and whether all the hunks are picked.
+func (p *splitPicker) pickHunks(ctx context.Context, ch *object.Change) (*object.TreeEntry, bool, error) {
+	from, to := &ch.From.TreeEntry, &ch.To.TreeEntry
+	name := ch.To.Name
+	fmt.Fprintf(os.Stderr, "%s %s\n", W("modified:"), name)
+	var a, b string
+	var err error
+	textual := from.Mode == to.Mode && from.Mode.IsFile() && !from.Mode.IsFragments() &&
+		max(from.Size, to.Size) <= diferenco.MAX_DIFF_SIZE
+	if textual {
+		if a, _, err = p.readMissingText(ctx, from.Hash, false); err == nil {
+			b, _, err = p.readMissingText(ctx, to.Hash, false)
+		}
+		switch {
+		case errors.Is(err, diferenco.ErrNonText):
+			textual = false
+		case err != nil:
+			die_error("read %s: %v", name, err)
+			return nil, false, err
+		}
+	}
+	if !textual {
+		c, err := p.ask("ynq", "Include this change in commit #%d", p.n)
+		if err != nil {
+			return nil, false, err
+		}
+		switch c {
+		case 'y':
+			return to, true, nil
+		case 'q':
+			p.quit = true
+		}
+		return nil, false, nil
+	}
+	sink := diferenco.NewSink(diferenco.NEWLINE_RAW)
+	L1, L2 := sink.SplitRawLines(a), sink.SplitRawLines(b)
+	hunks, err := diferenco.DiffSlices(ctx, L1, L2, diferenco.Unspecified)
+	if err != nil {
+		die_error("diff %s: %v", name, err)
+		return nil, false, err
+	}
+	picked := make([]bool, len(hunks))
+	var rest byte // a: pick the remaining hunks, d: leave them
+	for i, h := range hunks {
+		if rest == 0 && !p.quit {
+			printSplitHunk(sink.Lines, L1, L2, h)
+			c, err := p.ask("ynadq", "Include this hunk in commit #%d", p.n)
+			if err != nil {
+				return nil, false, err
+			}
+			switch c {
+			case 'a', 'd':
+				rest = c
+			case 'q':
+				p.quit = true
+			}
+			picked[i] = c == 'y' || c == 'a'
+			continue
+		}
+		picked[i] = rest == 'a'
+	}
+	switch n := countPicked(picked); {
+	case n == 0:
+		return nil, false, nil
+	case n == len(picked):
+		return to, true, nil
+	}
+	content := applyPickedHunks(sink.Lines, L1, L2, hunks, picked)
+	oid, err := p.odb.HashTo(ctx, strings.NewReader(content), int64(len(content)))
+	if err != nil {
+		die_error("unable write blob: %v", err)
+		return nil, false, err
+	}
+	return &object.TreeEntry{Name: to.Name, Size: int64(len(content)), Mode: to.Mode, Hash: oid}, false, nil
+}
+
+func countPicked(picked []bool) int {
+	var n int
+	for _, ok := range picked {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// applyPickedHunks applies the picked hunks of the diff from L1 to L2, the lines keep their line endings.
+func applyPickedHunks(lines []string, L1, L2 []int, hunks []diferenco.Change, picked []bool) string {
+	var b strings.Builder
+	write := func(indexes []int) {
+		for _, i := range indexes {
+			_, _ = b.WriteString(lines[i])
+		}
+	}
+	var pos int
+	for i, h := range hunks {
+		write(L1[pos:h.P1])
+		if picked[i] {
+			write(L2[h.P2 : h.P2+h.Ins])
+		} else {
+			write(L1[h.P1 : h.P1+h.Del])
+		}
+		pos = h.P1 + h.Del
+	}
+	write(L1[pos:])
+	return b.String()
+}
+
+func printSplitHunk(lines []string, L1, L2 []int, h diferenco.Change) {
+	const contextLines = 3
+	colored := term.StderrLevel != term.LevelNone
+	var b strings.Builder
+	write := func(color string, prefix byte, indexes []int) {
+		for _, i := range indexes {
+			if colored && len(color) != 0 {
+				_, _ = b.WriteString(color)
+			}
+			_ = b.WriteByte(prefix)
+			_, _ = b.WriteString(strings.TrimRight(lines[i], "\r\n"))
+			if colored && len(color) != 0 {
+				_, _ = b.WriteString("\x1b[0m")
+			}
+			_ = b.WriteByte('\n')
+		}
+	}
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.P1+1, h.Del, h.P2+1, h.Ins)
+	if colored {
+		header = "\x1b[36m" + header + "\x1b[0m"
+	}
+	_, _ = b.WriteString(header)
+	_ = b.WriteByte('\n')
+	write("", ' ', L1[max(h.P1-contextLines, 0):h.P1])
+	write("\x1b[31m", '-', L1[h.P1:h.P1+h.Del])
+	write("\x1b[32m", '+', L2[h.P2:h.P2+h.Ins])
+	write("", ' ', L1[h.P1+h.Del:min(h.P1+h.Del+contextLines, len(L1))])
+	_, _ = os.Stderr.WriteString(b.String())
+}
@@ -0,0 +1,109 @@
+package zeta
+
+import (
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/diferenco"
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+func TestApplyPickedHunks(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\n"
+	b := "one\n2\nthree\nfour\nfive\nsix"
+	sink := diferenco.NewSink(diferenco.NEWLINE_RAW)
+	L1, L2 := sink.SplitRawLines(a), sink.SplitRawLines(b)
+	hunks, err := diferenco.DiffSlices(t.Context(), L1, L2, diferenco.Unspecified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hunks) != 2 {
+		t.Fatalf("unexpected hunks: %v", hunks)
+	}
+	tests := []struct {
+		picked []bool
+		want   string
+	}{
+		{[]bool{false, false}, a},
+		{[]bool{true, true}, b},
+		{[]bool{true, false}, "one\n2\nthree\nfour\nfive\n"},
+		{[]bool{false, true}, "one\ntwo\nthree\nfour\nfive\nsix"},
+	}
+	for _, tt := range tests {
+		if got := applyPickedHunks(sink.Lines, L1, L2, hunks, tt.picked); got != tt.want {
+			t.Errorf("applyPickedHunks(%v) = %q, want %q", tt.picked, got, tt.want)
+		}
+	}
+}
+
+func TestSplitCommitPaths(t *testing.T) {
+	ctx := t.Context()
+	r, f := newTestRepository(t, "split")
+	odb := r.ODB()
+	base := f.commit("base\n", map[string]string{"README.md": "readme\n"})
+	split := f.commit("docs and readme\n", map[string]string{"README.md": "readme v2\n", "docs/spec.md": "spec\n"}, base)
+	after := f.commit("main\n", map[string]string{"main.go": "package main\n"}, split)
+	splitTree, afterTree := f.object(split).Tree, f.object(after).Tree
+
+	branch := plumbing.NewBranchReferenceName("mainline")
+	if err := r.Update(plumbing.NewSymbolicReference(plumbing.HEAD, branch), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Update(plumbing.NewHashReference(branch, after), nil); err != nil {
+		t.Fatal(err)
+	}
+	w := r.Worktree()
+	if err := w.SplitCommit(ctx, &SplitCommitOptions{Commit: split.String(), Paths: []string{"docs"}, Message: []string{"docs"}}); err != nil {
+		t.Fatalf("split commit error: %v", err)
+	}
+	ref, err := r.Reference(branch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c3, err := odb.Commit(ctx, ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c3.Tree != afterTree || c3.Message != "main\n" {
+		t.Fatalf("unexpected last commit: %s %q", c3.Tree, c3.Message)
+	}
+	c2, err := odb.Commit(ctx, c3.Parents[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c2.Tree != splitTree || c2.Message != "docs and readme\n" {
+		t.Fatalf("unexpected split commit: %s %q", c2.Tree, c2.Message)
+	}
+	c1, err := odb.Commit(ctx, c2.Parents[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1.Parents[0] != base || c1.Message != "docs\n" {
+		t.Fatalf("unexpected first commit: %v %q", c1.Parents, c1.Message)
+	}
+	root, err := c1.Root(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseRoot, err := f.object(base).Root(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readme, err := baseRoot.FindEntry(ctx, "README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, err := root.FindEntry(ctx, "README.md"); err != nil || e.Hash != readme.Hash {
+		t.Fatalf("README.md should be unchanged in the first commit: %v", err)
+	}
+	if _, err := root.FindEntry(ctx, "docs/spec.md"); err != nil {
+		t.Fatalf("docs/spec.md should be in the first commit: %v", err)
+	}
+
+	// pushed commits are not split
+	if err := r.Update(plumbing.NewHashReference(plumbing.NewRemoteReferenceName(plumbing.Origin, branch.BranchName()), ref.Hash()), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SplitCommit(ctx, &SplitCommitOptions{Commit: c2.Hash.String(), Paths: []string{"README.md"}, Message: []string{"readme"}}); err == nil {
+		t.Fatalf("split pushed commit should fail")
+	}
+}
@@ -143,6 +143,11 @@ func (w *Worktree) stashStore(ctx context.Context, base *object.Commit, committe
 }
 
 func (w *Worktree) StashPush(ctx context.Context, opts *StashPushOptions) error {
+	unlock, err := w.lock("stash")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	status, err := w.Status(context.Background(), false)
 	if err != nil {
 		die_error("status: %v", err)
@@ -470,6 +475,11 @@ func (w *Worktree) stashApply(ctx context.Context, e *reflog.Entry) error {
 }
 
 func (w *Worktree) StashApply(ctx context.Context, stashRev string) error {
+	unlock, err := w.lock("stash")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	e, err := w.readStashRev(stashRev)
 	if err != nil {
 		return err
@@ -479,6 +489,11 @@ func (w *Worktree) StashApply(ctx context.Context, stashRev string) error {
 }
 
 func (w *Worktree) StashPop(ctx context.Context, stashRev string) error {
+	unlock, err := w.lock("stash")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	index, err := w.checkStashRev(stashRev)
 	if err != nil {
 		return err
@@ -508,6 +523,11 @@ func (w *Worktree) StashPop(ctx context.Context, stashRev string) error {
 }
 
 func (w *Worktree) StashClear(ctx context.Context) error {
+	unlock, err := w.lock("stash")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if !w.rdb.Exists(StashName) {
 		return nil
 	}
@@ -525,6 +545,11 @@ func (w *Worktree) StashClear(ctx context.Context) error {
 }
 
 func (w *Worktree) StashDrop(ctx context.Context, stashRev string) error {
+	unlock, err := w.lock("stash")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	index, err := w.checkStashRev(stashRev)
 	if err != nil {
 		return err
@@ -14,6 +14,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/antgroup/hugescm/modules/fnmatch"
@@ -94,15 +95,41 @@ func (w *Worktree) Status(ctx context.Context, verbose bool) (Status, error) {
 		if verbose {
 			if ref.Name().IsBranch() {
 				fmt.Fprintf(os.Stderr, "%s %s\n", W("On branch"), ref.Name().BranchName())
+				w.showUpstreamStatus(ctx)
 			} else {
 				fmt.Fprintf(os.Stderr, "%s %s\n", W("HEAD detached at"), ref.Hash())
 			}
+			if _, merging := w.mergeHEAD(); merging {
+				fmt.Fprintf(os.Stderr, "%s\n  (%s)\n  (%s)\n", W("You have unmerged paths."),
+					W("fix conflicts and run \"zeta commit\""), W("use \"zeta merge --abort\" to abort the merge"))
+			}
 		}
 	}
 
 	return w.status(ctx, hash)
 }
 
+func (w *Worktree) showUpstreamStatus(ctx context.Context) {
+	us, err := w.UpstreamStatus(ctx)
+	if err != nil {
+		warn("compare with upstream: %v", err)
+		return
+	}
+	if us == nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", us.Describe())
+	switch {
+	case us.Local == us.Remote || us.Skipped:
+	case us.Behind == 0:
+		fmt.Fprintf(os.Stderr, "  (%s)\n", W("use \"zeta push\" to publish your local commits"))
+	case us.Ahead == 0:
+		fmt.Fprintf(os.Stderr, "  (%s)\n", W("use \"zeta pull\" to update your local branch"))
+	default:
+		fmt.Fprintf(os.Stderr, "  (%s)\n", W("use \"zeta pull\" to merge the remote branch into yours"))
+	}
+}
+
 func (w *Worktree) status(ctx context.Context, commit plumbing.Hash) (Status, error) {
 	s := make(Status)
 
@@ -174,6 +201,10 @@ func (w *Worktree) status(ctx context.Context, commit plumbing.Hash) (Status, er
 			fs.Worktree = Modified
 		}
 	}
+	for _, p := range unmergedIndexPaths(idx) {
+		fs := s.File(p)
+		fs.Staging, fs.Worktree = Unmodified, UpdatedButUnmerged
+	}
 
 	return s, nil
 }
@@ -425,10 +456,12 @@ func buildCacheFromIndex(ctx context.Context, idx *index.Index, resolve fragment
 }
 
 func (w *Worktree) ignoreMatcher() (ignore.Matcher, error) {
-	patterns, err := ignore.ReadPatterns(w.fs, nil)
+	patterns, err := ignore.ReadPatterns(w.fs, nil, w.ignoreCompat())
 	if err != nil {
 		return nil, err
 	}
+	// organization-wide templates have the lowest priority
+	patterns = append(w.templateIgnorePatterns(), patterns...)
 	patterns = append(patterns, w.Excludes...)
 	return ignore.NewMatcher(patterns), nil
 }
@@ -504,6 +537,11 @@ func isPathInDirectory(path, directory string) bool {
 // made to the working tree files applied, or remove paths that do not exist in
 // the working tree anymore.
 func (w *Worktree) AddWithOptions(ctx context.Context, opts *AddOptions) error {
+	unlock, err := w.lock("add")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if err := opts.Validate(w.Repository); err != nil {
 		return err
 	}
@@ -517,7 +555,7 @@ func (w *Worktree) AddWithOptions(ctx context.Context, opts *AddOptions) error {
 		return w.AddGlob(ctx, opts.Glob, opts.DryRun)
 	}
 
-	_, err := w.doAdd(ctx, opts.Path, make([]ignore.Pattern, 0), opts.SkipStatus, opts.DryRun)
+	_, err = w.doAdd(ctx, opts.Path, make([]ignore.Pattern, 0), opts.SkipStatus, opts.DryRun)
 	return err
 }
 
@@ -547,6 +585,11 @@ func (w *Worktree) cleanPatterns(paths []string) ([]string, bool, error) {
 }
 
 func (w *Worktree) Add(ctx context.Context, pathSpec []string, dryRun bool) error {
+	unlock, err := w.lock("add")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if len(pathSpec) == 1 && pathSpec[0] == "." {
 		return w.AddWithOptions(ctx, &AddOptions{All: true, DryRun: dryRun})
 	}
@@ -581,6 +624,11 @@ func (w *Worktree) Add(ctx context.Context, pathSpec []string, dryRun bool) erro
 }
 
 func (w *Worktree) AddTracked(ctx context.Context, pathSpec []string, dryRun bool) error {
+	unlock, err := w.lock("add")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	idx, err := w.odb.Index()
 	if err != nil {
 		return err
@@ -650,6 +698,11 @@ func (w *Worktree) chmod(ctx context.Context, paths []string, mask bool) error {
 }
 
 func (w *Worktree) Chmod(ctx context.Context, paths []string, mask bool, dryRun bool) error {
+	unlock, err := w.lock("add")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if dryRun {
 		return nil
 	}
@@ -707,6 +760,11 @@ func (w *Worktree) doAdd(ctx context.Context, path string, ignorePattern []ignor
 // directory path, all directory contents are added to the index recursively. No
 // error is returned if all matching paths are already staged in index.
 func (w *Worktree) AddGlob(ctx context.Context, pattern string, dryRun bool) error {
+	unlock, err := w.lock("add")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	files, err := vfs.Glob(w.fs, pattern)
 	if err != nil {
 		return err
@@ -819,6 +877,7 @@ func (w *Worktree) copyFileToStorage(ctx context.Context, path string) (plumbing
 }
 
 func (w *Worktree) addOrUpdateFileToIndex(idx *index.Index, filename string, h plumbing.Hash, asFragments bool) error {
+	removeConflictStages(idx, filename)
 	e, err := idx.Entry(filename)
 	if err != nil && !errors.Is(err, index.ErrEntryNotFound) {
 		return err
@@ -844,10 +903,12 @@ func (w *Worktree) doUpdateFileToIndex(e *index.Entry, filename string, h plumbi
 	e.Size = uint64(info.Size())
 	e.Hash = h
 	e.ModifiedAt = info.ModTime()
-	e.Mode, err = filemode.NewFromOS(info.Mode())
+	mode, err := filemode.NewFromOS(info.Mode())
 	if err != nil {
 		return err
 	}
+	// core.filemode=false and core.symlinks=false keep the mode recorded in the index
+	e.Mode = w.resolveIndexMode(e.Mode, mode)
 	// check object is fragments
 	if asFragments {
 		e.Mode |= filemode.Fragments
@@ -939,10 +1000,18 @@ func (w *Worktree) deleteFromIndex(idx *index.Index, path string) (plumbing.Hash
 	if err != nil {
 		return plumbing.ZeroHash, err
 	}
-
+	removeConflictStages(idx, path)
 	return e.Hash, nil
 }
 
+// removeConflictStages: adding or removing a conflicted path marks the resolution.
+func removeConflictStages(idx *index.Index, path string) {
+	path = filepath.ToSlash(path)
+	idx.Entries = slices.DeleteFunc(idx.Entries, func(e *index.Entry) bool {
+		return e.Stage != 0 && e.Name == path
+	})
+}
+
 func (w *Worktree) deleteFromFilesystem(path string) error {
 	err := w.fs.Remove(path)
 	if os.IsNotExist(err) {
@@ -955,6 +1024,11 @@ func (w *Worktree) deleteFromFilesystem(path string) error {
 // matches a directory path, all directory contents are removed from the index
 // recursively.
 func (w *Worktree) RemoveGlob(pattern string) error {
+	unlock, err := w.lock("rm")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	idx, err := w.odb.Index()
 	if err != nil {
 		return err
@@ -1021,6 +1095,11 @@ func matchEx(idx *index.Index, pattern string, recurse bool) (matches []*index.E
 }
 
 func (w *Worktree) Remove(ctx context.Context, patterns []string, opts *RemoveOptions) error {
+	unlock, err := w.lock("rm")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if len(patterns) == 0 {
 		return nil
 	}
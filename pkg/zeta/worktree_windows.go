@@ -14,7 +14,6 @@ import (
 
 	"github.com/antgroup/hugescm/modules/merkletrie"
 	"github.com/antgroup/hugescm/modules/merkletrie/filesystem"
-	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/plumbing/filemode"
 	"github.com/antgroup/hugescm/modules/plumbing/format/index"
 )
@@ -61,11 +60,6 @@ func systemCaseEqual(a, b string) bool {
 	return strings.EqualFold(a, b)
 }
 
-type hasher interface {
-	HashRaw() plumbing.Hash
-	Mode() filemode.FileMode
-}
-
 // unifyChangeFileMode handles file mode changes on Windows.
 //
 // Windows does not use the POSIX file permission model (rwx permissions) and instead
@@ -186,7 +180,7 @@ func (w *Worktree) excludeIgnoredChanges(changes merkletrie.Changes) merkletrie.
 			continue
 		}
 		// modified
-		if w.unifyChangeFileMode(&ch) {
+		if w.unifyChangeFileMode(&ch) || w.suppressModeChange(&ch) {
 			continue
 		}
 		res = append(res, ch)